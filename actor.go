@@ -0,0 +1,58 @@
+package adapter
+
+import (
+	"context"
+	"fmt"
+	"slices"
+)
+
+// WithActorColumn records the acting identity of each rule inserted in
+// column of the policy table, like created_by. extractor returns the
+// identity carried by the context of the call, the one of NewAdapter or
+// Transaction for the methods of the casbin interfaces, or an empty string
+// when there is none. The column is added to the table by EnsureTable, and
+// its values are returned in the CreatedBy field of the rules of
+// GetAllPolicies, GetFilteredPolicies and ListPolicies. The rules of an
+// update are inserted again, with the identity of the update.
+func WithActorColumn(column string, extractor func(ctx context.Context) string) AdapterOption {
+	return AdapterOption{apply: func(a *Adapter) {
+		a.actorColumn, a.actorExtractor = column, extractor
+	}}
+}
+
+// validateActorColumn checks that the column of WithActorColumn is a
+// column of its own.
+func (a *Adapter) validateActorColumn() error {
+	column := a.actorColumn
+	if column == "" {
+		return nil
+	}
+	if !isValidIdentifier(column) || slices.Contains(a.expectedColumns(), column) || column == "id" ||
+		column == createdAtColumn || column == ruleHashColumn || column == deletedAtColumn || column == a.tenantColumn {
+		return fmt.Errorf("invalid actor column name: %q", column)
+	}
+	return nil
+}
+
+// actor returns the identity of WithActorColumn carried by ctx.
+func (a *Adapter) actor(ctx context.Context) string {
+	if a.actorExtractor == nil {
+		return ""
+	}
+	return a.actorExtractor(ctx)
+}
+
+// ensureActorColumn adds the column of WithActorColumn to table, the policy
+// table or the one replacing it, when it is missing.
+func (a *Adapter) ensureActorColumn(ctx context.Context, table string) error {
+	statements, err := a.missingColumnsSql(ctx, table, []string{a.actorColumn})
+	if err != nil {
+		return err
+	}
+	for _, sql := range statements {
+		if _, err := a.db.Exec(ctx, sql); err != nil && !isDuplicateColumnError(err) {
+			return fmt.Errorf("failed to add actor column: %w", err)
+		}
+	}
+	return a.clearTableFields(ctx, table)
+}
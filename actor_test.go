@@ -0,0 +1,150 @@
+package adapter
+
+import (
+	"context"
+	"reflect"
+	"testing"
+)
+
+// actorKey is the context key of the identity of the tests.
+type actorKey struct{}
+
+func withActor(ctx context.Context, actor string) context.Context {
+	return context.WithValue(ctx, actorKey{}, actor)
+}
+
+func actorOf(ctx context.Context) string {
+	actor, _ := ctx.Value(actorKey{}).(string)
+	return actor
+}
+
+// storedActors returns the values of the created_by column, ordered by id.
+func storedActors(t *testing.T, a *Adapter) []string {
+	t.Helper()
+	values, err := a.db.Model(a.tableName).Fields("created_by").OrderAsc("id").Array()
+	if err != nil {
+		t.Fatalf("failed to read actor column: %v", err)
+	}
+	actors := make([]string, len(values))
+	for i, value := range values {
+		actors[i] = value.String()
+	}
+	return actors
+}
+
+func TestActorColumn(t *testing.T) {
+	ctx := context.Background()
+	db := newSqliteDB(t)
+	a, err := NewAdapter(withActor(ctx, "admin"), "", "", db, WithActorColumn("created_by", actorOf))
+	if err != nil {
+		t.Fatalf("NewAdapter failed: %v", err)
+	}
+	initPolicy(t, a)
+
+	// The methods taking a context record its identity, or none.
+	if _, err := a.ImportPolicies(withActor(ctx, "importer"), [][]string{{"carol", "data3", "read"}}, "p", ImportOptions{}); err != nil {
+		t.Fatalf("ImportPolicies failed: %v", err)
+	}
+	if _, err := a.ImportPolicies(ctx, [][]string{{"dave", "data3", "read"}}, "p", ImportOptions{}); err != nil {
+		t.Fatalf("ImportPolicies failed: %v", err)
+	}
+	err = a.Transaction(withActor(ctx, "operator"), func(tx *Adapter) error {
+		return tx.AddPolicy("p", "p", []string{"erin", "data3", "read"})
+	})
+	if err != nil {
+		t.Fatalf("Transaction failed: %v", err)
+	}
+	want := []string{"admin", "admin", "admin", "admin", "admin", "importer", "", "operator"}
+	if actors := storedActors(t, a); !reflect.DeepEqual(actors, want) {
+		t.Errorf("stored actors %q, supposed to be %q", actors, want)
+	}
+
+	rules, err := a.GetAllPolicies(ctx, nil)
+	if err != nil {
+		t.Fatalf("GetAllPolicies failed: %v", err)
+	}
+	for i, rule := range rules {
+		if rule.CreatedBy != want[i] {
+			t.Errorf("rule %+v created by %q, supposed to be %q", rule, rule.CreatedBy, want[i])
+		}
+	}
+	rows, total, err := a.ListPolicies(ctx, Filter{V1: []string{"data3"}}, 1, 10, "id", true)
+	if err != nil {
+		t.Fatalf("ListPolicies failed: %v", err)
+	}
+	wantRows := []Rule{
+		{PType: "p", V0: "erin", V1: "data3", V2: "read", CreatedBy: "operator"},
+		{PType: "p", V0: "dave", V1: "data3", V2: "read"},
+		{PType: "p", V0: "carol", V1: "data3", V2: "read", CreatedBy: "importer"},
+	}
+	if total != 3 || !reflect.DeepEqual(rows, wantRows) {
+		t.Errorf("ListPolicies = %+v, %d, supposed to be %+v, 3", rows, total, wantRows)
+	}
+
+	// An updated rule is inserted again by the updater, the removals match
+	// the rules whoever added them.
+	b, err := NewAdapter(withActor(ctx, "editor"), "", "", db, WithActorColumn("created_by", actorOf))
+	if err != nil {
+		t.Fatalf("NewAdapter failed: %v", err)
+	}
+	if err := b.UpdatePolicy("p", "p", []string{"dave", "data3", "read"}, []string{"dave", "data3", "write"}); err != nil {
+		t.Fatalf("UpdatePolicy failed: %v", err)
+	}
+	if err := b.RemovePolicy("p", "p", []string{"carol", "data3", "read"}); err != nil {
+		t.Fatalf("RemovePolicy failed: %v", err)
+	}
+	rules, err = b.GetFilteredPolicies(ctx, Filter{V1: []string{"data3"}})
+	if err != nil {
+		t.Fatalf("GetFilteredPolicies failed: %v", err)
+	}
+	wantRows = []Rule{
+		{PType: "p", V0: "erin", V1: "data3", V2: "read", CreatedBy: "operator"},
+		{PType: "p", V0: "dave", V1: "data3", V2: "write", CreatedBy: "editor"},
+	}
+	if !reflect.DeepEqual(rules, wantRows) {
+		t.Errorf("GetFilteredPolicies = %+v, supposed to be %+v", rules, wantRows)
+	}
+}
+
+func TestActorColumnOptions(t *testing.T) {
+	// The column is added to an existing table, the rules stored before
+	// have no actor.
+	db := newSqliteAdapter(t).db
+	ctx := context.Background()
+	if _, err := db.Exec(ctx, "INSERT INTO casbin_rule (p_type, v0, v1, v2) VALUES ('p', 'alice', 'data1', 'read')"); err != nil {
+		t.Fatalf("failed to insert rule: %v", err)
+	}
+	a, err := NewAdapter(ctx, "", "", db, WithActorColumn("created_by", nil))
+	if err != nil {
+		t.Fatalf("NewAdapter failed: %v", err)
+	}
+	if err := a.AddPolicy("p", "p", []string{"bob", "data2", "write"}); err != nil {
+		t.Fatalf("AddPolicy failed: %v", err)
+	}
+	if actors := storedActors(t, a); !reflect.DeepEqual(actors, []string{"", ""}) {
+		t.Errorf("stored actors %q, supposed to be empty", actors)
+	}
+
+	// The table replacing the policy table in a split save has the column.
+	b, err := NewAdapter(withActor(ctx, "admin"), "", "", newSqliteDB(t), WithActorColumn("created_by", actorOf), WithMaxTxRows(2))
+	if err != nil {
+		t.Fatalf("NewAdapter failed: %v", err)
+	}
+	initPolicy(t, b)
+	if actors := storedActors(t, b); !reflect.DeepEqual(actors, []string{"admin", "admin", "admin", "admin", "admin"}) {
+		t.Errorf("stored actors %q, supposed to be admin", actors)
+	}
+
+	for name, opts := range map[string][]AdapterOption{
+		"invalid column":     {WithActorColumn("created_by;", actorOf)},
+		"rule column":        {WithActorColumn("v0", actorOf)},
+		"created_at":         {WithActorColumn("created_at", actorOf)},
+		"tenant column":      {WithActorColumn("tenant_id", actorOf), WithTenantColumn("tenant_id")},
+		"JSON storage":       {WithActorColumn("created_by", actorOf), WithJSONStorage()},
+		"single rule column": {WithActorColumn("created_by", actorOf), WithSingleRuleColumn("rule")},
+	} {
+		if _, err := NewAdapter(ctx, "", "", newSqliteDB(t), opts...); err == nil {
+			t.Errorf("%s: expected an error", name)
+		}
+	}
+}
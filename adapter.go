@@ -4,38 +4,44 @@ import (
 	"context"
 	"errors"
 	"fmt"
+	"slices"
+	"sort"
+	"strings"
+	"sync"
+	"time"
 
 	"github.com/casbin/casbin/v2/model"
+	"github.com/casbin/casbin/v2/persist"
 	"github.com/gogf/gf/v2/database/gdb"
 	"github.com/gogf/gf/v2/frame/g"
+	"github.com/gogf/gf/v2/os/gcache"
+	"github.com/gogf/gf/v2/os/glog"
 )
 
 const (
 	defaultTableName = "casbin_rule"
 	dropTableSql     = `DROP TABLE IF EXISTS %s`
-	createTableSql   = `
-CREATE TABLE IF NOT EXISTS %s (
-  id bigint NOT NULL AUTO_INCREMENT,
-  p_type varchar(10) CHARACTER SET utf8mb4 COLLATE utf8mb4_general_ci DEFAULT NULL,
-  v0 varchar(256) COLLATE utf8mb4_general_ci DEFAULT NULL,
-  v1 varchar(256) COLLATE utf8mb4_general_ci DEFAULT NULL,
-  v2 varchar(256) COLLATE utf8mb4_general_ci DEFAULT NULL,
-  v3 varchar(256) COLLATE utf8mb4_general_ci DEFAULT NULL,
-  v4 varchar(256) COLLATE utf8mb4_general_ci DEFAULT NULL,
-  v5 varchar(256) COLLATE utf8mb4_general_ci DEFAULT NULL,
-  created_at datetime DEFAULT CURRENT_TIMESTAMP,
-  PRIMARY KEY (id)
-) ENGINE=InnoDB DEFAULT CHARSET=utf8mb4 COLLATE=utf8mb4_bin;
-`
-	truncateTableSql = `TRUNCATE TABLE %s`
 
 	// defaultBatchSize is the default size for batch operations
 	defaultBatchSize = 1000
 
+	// defaultPageSize is the default number of rows read per query
+	defaultPageSize = 10000
+
+	// defaultFilterChunkSize is the default number of values of a filter field per query
+	defaultFilterChunkSize = 1000
+
 	// maxFieldIndex is the maximum field index for policy rules
 	maxFieldIndex = 5
+
+	// policyKeySep joins the values of a rule into the keys of the policy
+	// maps of casbin models.
+	policyKeySep = model.DefaultSep
 )
 
+// valueColumns are the columns of the rule values in order.
+var valueColumns = []string{Columns.V0, Columns.V1, Columns.V2, Columns.V3, Columns.V4, Columns.V5}
+
 type (
 	Adapter struct {
 		ctx         context.Context
@@ -44,10 +50,164 @@ type (
 		db          gdb.DB
 		isFiltered  bool
 		batchSize   int
+		pageSize    int
+
+		// filterChunkSize caps the number of values of a filter field per query.
+		filterChunkSize int
+		dialect         dialect
+
+		// pTypeColumn is the policy type column, "ptype" on tables created by
+		// gorm-adapter. Rows are scanned into Rule by gconv's fuzzy matching
+		// of column names, so both conventions scan alike.
+		pTypeColumn string
+
+		// tenantColumn scopes every query and insert to tenant when set.
+		tenantColumn string
+		tenant       string
+		// scope is the tenant of the scope column set by WithScope.
+		scope string
+		// routes maps the policy types and the sections routed to tables
+		// of their own to their tables.
+		routes map[string]string
+		// ruleColumn is the column holding the whole rules, see
+		// WithSingleRuleColumn, and jsonStorage stores the values of the
+		// rules in a JSON array, see WithJSONStorage.
+		ruleColumn  string
+		jsonStorage bool
+		// partitioned partitions the policy tables by tenant, partitions
+		// holds the partitions ensured, by table and tenant.
+		partitioned bool
+		partitions  *sync.Map
+		// tombstones writes the removals as tombstone rows merged away by
+		// the database, on ClickHouse.
+		tombstones bool
+		// loadHint is the table hint of the loads set by WithReadPast.
+		loadHint string
+
+		// pDomainIndex and gDomainIndex locate the domain in p and g rules.
+		pDomainIndex int
+		gDomainIndex int
+
+		history      bool
+		historyTable string
+
+		// versionTable holds the version counter bumped by every change.
+		versioned    bool
+		versionTable string
+
+		// tx is the external transaction the adapter is bound to, if any.
+		tx gdb.TX
+
+		allowDestructive bool
+		allowEmptySave   bool
+		// utc writes the times in UTC, see WithUTC. utcChosen is set once
+		// it is chosen, by WithUTC or by EnsureTable.
+		utc       bool
+		utcChosen bool
+		// actorColumn and actorExtractor record the identity inserting each
+		// rule, see WithActorColumn.
+		actorColumn    string
+		actorExtractor func(ctx context.Context) string
+		// progress is the callback of WithProgress.
+		progress         func(op string, done, total int64)
+		destructiveGuard destructiveGuard
+		autoCreate       bool
+		autoMigrate      bool
+		syncSave         bool
+		stableSave       bool
+		strictUpdate     bool
+		noTransactions   bool
+		// loadOrder is the order of the loads set by WithLoadOrder.
+		loadOrder      filterPage
+		uniqueIndex    bool
+		changeTracking bool
+		// softDelete is set when the policy table has a deleted_at column.
+		softDelete     bool
+		conflictPolicy ConflictPolicy
+
+		// cache holds the rules of the last loads when caching is enabled.
+		cache    *gcache.Cache
+		cacheTTL time.Duration
+
+		// watcher is notified of the changes written through the adapter.
+		watcher persist.Watcher
+		// dispatcher broadcasts the rule changes, after writing them or
+		// instead of it depending on dispatchMode.
+		dispatcher   persist.Dispatcher
+		dispatchMode DispatchMode
+
+		// logger logs the loads and changes of the stored rules, those
+		// taking longer than slowThreshold are logged as slow.
+		logger        *glog.Logger
+		slowThreshold time.Duration
+		stats         *adapterStats
+		// tracerName names the tracer of the operation spans.
+		tracerName string
+		metrics    *adapterMetrics
+
+		// hooks are called around the changes of the stored rules.
+		hooks *hookList
+		// events delivers the changes of the stored rules to Events, the
+		// changes made in Transaction are queued in pendingEvents until it
+		// committed.
+		events        *eventFeed
+		pendingEvents *[]PolicyEvent
+
+		// plan records the statements of a dry run.
+		plan *dryRunPlan
+		// autoReload holds the reloads started by NewEnforcer.
+		autoReload *AutoReload
+
+		// deadlockRetries is the number of times a deadlocked transaction
+		// is retried.
+		deadlockRetries int
+		// purgeRetention is how long PurgeExpired keeps the soft deleted
+		// rows.
+		purgeRetention time.Duration
+		// maxRules is the quota of stored rules, unlimited when 0.
+		maxRules int64
+		// reloadInterval is the configured autoReload interval.
+		reloadInterval time.Duration
+		// tableComment and columnComments describe the created policy table.
+		tableComment   string
+		columnComments map[string]string
+		// columnTypes are the SQL types of the value columns by field index,
+		// valueLengths the resulting lengths of the value columns, 0 when
+		// unbounded. ruleHash is set when the unique index covers the
+		// rule_hash column.
+		columnTypes  map[int]string
+		valueLengths []int
+		ruleHash     bool
+
+		// normalization normalizes the values written and looked up.
+		normalization *NormalizeOptions
+		// hasher hashes the values of hashedFields written and looked up.
+		hasher       func(string) string
+		hashedFields []int
+		// codec encodes the values stored in the rule tables.
+		codec ValueCodec
+
+		// writers is the number of goroutines writing the batches of
+		// SavePolicy, the writes are serial when it is below 2.
+		writers int
+		// maxTxRows is the maximum number of rows written by a transaction
+		// of SavePolicy and AddPolicies, 0 when it is unlimited.
+		maxTxRows int
+		// autoIncrementStep is the step between the ids MySQL gives the rows
+		// of one insert, 0 when they needn't be consecutive.
+		autoIncrementStep int64
+
+		// now returns the current time, it is replaced in tests.
+		now func() time.Time
+		// insertBatch inserts records into table, it is replaced in tests.
+		insertBatch func(ctx context.Context, table string, records g.List) error
 	}
 
 	AdapterOption struct {
 		BatchSize int
+
+		// apply configures the adapter, it is set by the With* option helpers.
+		apply func(a *Adapter)
 	}
 
 	Rule struct {
@@ -58,6 +218,10 @@ type (
 		V3    string `orm:"v3" json:"v3"`
 		V4    string `orm:"v4" json:"v4"`
 		V5    string `orm:"v5" json:"v5"`
+
+		// CreatedBy is the identity recorded by WithActorColumn. The rules
+		// are compared by their values, whoever created them.
+		CreatedBy string `json:"created_by,omitempty"`
 	}
 
 	Filter struct {
@@ -68,6 +232,50 @@ type (
 		V3    []string
 		V4    []string
 		V5    []string
+
+		// NotPType and NotV0 to NotV5 exclude the rules having one of their
+		// values.
+		NotPType []string
+		NotV0    []string
+		NotV1    []string
+		NotV2    []string
+		NotV3    []string
+		NotV4    []string
+		NotV5    []string
+
+		// V0Prefix to V5Prefix keep the rules whose value starts with one
+		// of their prefixes. The case sensitivity is the one of the column
+		// collation. Prefixes can't match encrypted or hashed values.
+		V0Prefix []string
+		V1Prefix []string
+		V2Prefix []string
+		V3Prefix []string
+		V4Prefix []string
+		V5Prefix []string
+
+		// CreatedAfter and CreatedBefore keep the rules stored from
+		// CreatedAfter on and before CreatedBefore. The zero time leaves
+		// the window open on its side.
+		CreatedAfter  time.Time
+		CreatedBefore time.Time
+
+		// Limit and Offset page the rules ordered by OrderBy: id, the
+		// default, or created_at, optionally followed by asc or desc. They
+		// are honored by LoadFilteredPolicy, GetFilteredPolicies and
+		// CountPolicies, and only set by the first of several filters,
+		// paging the rules matching any of them.
+		Limit   int
+		Offset  int
+		OrderBy string
+
+		// AllowEmpty lets Validate accept a filter without conditions,
+		// matching all the rules.
+		AllowEmpty bool
+
+		// raw is the condition of a FilterRaw.
+		raw FilterRaw
+		// domains are the domains of FilterByDomain.
+		domains []string
 	}
 )
 
@@ -94,29 +302,62 @@ func NewAdapter(ctx context.Context, dbGroupName, tableName string, db gdb.DB, o
 		return nil, fmt.Errorf("invalid context: %w", err)
 	}
 
+	adp := newAdapter(ctx, dbGroupName, tableName, db, opts)
+	if err := adp.open(); err != nil {
+		return nil, fmt.Errorf("failed to open %s: %w", adp, err)
+	}
+
+	return adp, nil
+}
+
+// newAdapter creates an adapter with the defaults and the options applied.
+func newAdapter(ctx context.Context, dbGroupName, tableName string, db gdb.DB, opts []AdapterOption) *Adapter {
 	adp := &Adapter{
 		ctx:         ctx,
 		dbGroupName: dbGroupName,
 		tableName:   tableName,
 		db:          db,
 		batchSize:   defaultBatchSize,
+		pageSize:    defaultPageSize,
+
+		filterChunkSize: defaultFilterChunkSize,
+		pTypeColumn:     Columns.PType,
+		autoCreate:      true,
+		now:             time.Now,
+		purgeRetention:  defaultPurgeRetention,
+
+		pDomainIndex: defaultPDomainIndex,
+		gDomainIndex: defaultGDomainIndex,
+		hooks:        &hookList{},
+		stats:        &adapterStats{},
+		events:       &eventFeed{size: defaultEventBuffer, done: make(chan struct{})},
 	}
 
+	adp.insertBatch = adp.insertRecords
+
 	// Apply options
 	for _, opt := range opts {
 		if opt.BatchSize > 0 {
 			adp.batchSize = opt.BatchSize
 		}
+		if opt.apply != nil {
+			opt.apply(adp)
+		}
 	}
+	return adp
+}
 
-	if err := adp.open(); err != nil {
-		return nil, fmt.Errorf("failed to open adapter: %w", err)
+func (a *Adapter) open() error {
+	if err := a.configure(); err != nil {
+		return err
 	}
-
-	return adp, nil
+	a.detectAutoIncrement(a.ctx)
+	return a.initTables()
 }
 
-func (a *Adapter) open() error {
+// configure resolves the database, the table names and the dialect, and
+// validates the options.
+func (a *Adapter) configure() error {
 	if a.db == nil {
 		if a.dbGroupName == "" {
 			return errors.New("database group name cannot be empty when db is nil")
@@ -134,11 +375,279 @@ func (a *Adapter) open() error {
 	// Get database prefix and validate connection
 	prefix := a.db.GetPrefix()
 	a.tableName = fmt.Sprintf("%s%s", prefix, a.tableName)
-	return a.createTable()
+	if a.routed() {
+		routes := make(map[string]string, len(a.routes))
+		for pType, table := range a.routes {
+			routes[pType] = prefix + table
+		}
+		a.routes = routes
+	}
+	a.dialect = dialectFor(a.db)
+	if err := a.configureScope(); err != nil {
+		return err
+	}
+	if a.tenantColumn != "" && !isValidIdentifier(a.tenantColumn) {
+		return fmt.Errorf("invalid tenant column name: %q", a.tenantColumn)
+	}
+	if err := a.validateComments(); err != nil {
+		return err
+	}
+	if err := a.configureColumnTypes(); err != nil {
+		return err
+	}
+	if err := a.validateSplit(); err != nil {
+		return err
+	}
+	if err := a.validatePartitioning(); err != nil {
+		return err
+	}
+	if err := a.configureTombstones(); err != nil {
+		return err
+	}
+	if err := a.validatePacked(); err != nil {
+		return err
+	}
+	if err := a.validateActorColumn(); err != nil {
+		return err
+	}
+	if err := a.validateNoTransactions(); err != nil {
+		return err
+	}
+	if err := a.validateReadPast(); err != nil {
+		return err
+	}
+	if !isValidFieldIndex(a.pDomainIndex) || !isValidFieldIndex(a.gDomainIndex) {
+		return fmt.Errorf("invalid domain field index: p=%d, g=%d", a.pDomainIndex, a.gDomainIndex)
+	}
+	if a.changeTracking && a.uniqueIndex {
+		return errors.New("change tracking can't be combined with a unique index")
+	}
+	if a.history {
+		a.historyTable = a.tableName + historyTableSuffix
+	}
+	if a.versioned {
+		a.versionTable = a.tableName + versionTableSuffix
+	}
+	return nil
+}
+
+// initTables checks and migrates the existing tables of the adapter,
+// creates them when auto-create is enabled and prepares the history table.
+// The schema changes are made holding the advisory lock of the table.
+func (a *Adapter) initTables() error {
+	err := a.withDDLLock(a.ctx, func() error {
+		if a.autoMigrate {
+			if err := a.migrateSchema(a.ctx); err != nil {
+				return err
+			}
+		}
+		if err := a.detectColumns(a.ctx); err != nil {
+			return err
+		}
+		if a.autoCreate {
+			if err := a.EnsureTable(a.ctx); err != nil {
+				return err
+			}
+		}
+		return a.validateLoadOrder(a.ctx)
+	})
+	if err != nil {
+		return err
+	}
+
+	if a.historyTable != "" {
+		return a.openHistory()
+	}
+	return nil
 }
 
 func (a *Adapter) model() *gdb.Model {
-	return a.db.Model(a.tableName).Safe().Ctx(a.ctx)
+	return a.modelCtx(a.ctx)
+}
+
+// modelCtx returns a model of the policy table bound to ctx. Inside a
+// transaction callback ctx carries the transaction, so the model joins it.
+func (a *Adapter) modelCtx(ctx context.Context) *gdb.Model {
+	return a.hintedModelCtx(ctx, "")
+}
+
+// loadModelCtx is modelCtx for the loads, which read the policy table with
+// the table hint of WithReadPast.
+func (a *Adapter) loadModelCtx(ctx context.Context) *gdb.Model {
+	return a.hintedModelCtx(ctx, a.loadHint)
+}
+
+// hintedModelCtx is modelCtx selecting from the policy table with hint
+// when it is set. On ClickHouse the table is always read FINAL.
+func (a *Adapter) hintedModelCtx(ctx context.Context, hint string) *gdb.Model {
+	var m *gdb.Model
+	if a.tx != nil {
+		m = a.tx.Model(a.tableName).Safe().Ctx(ctx)
+	} else {
+		m = a.db.Model(a.tableName).Safe().Ctx(ctx)
+	}
+	var hook gdb.HookHandler
+	switch {
+	case a.packed():
+		hook = a.packedHook(a.measured())
+	case a.codec != nil:
+		hook = a.codecHook(a.measured())
+	case a.measured():
+		hook = affectedHook
+	}
+	if a.tombstones {
+		m, hint = m.Where(tombstoneColumn, 0), "FINAL"
+	}
+	if hint != "" {
+		hook = a.readHintHook(hook, hint)
+	}
+	return a.scoped(a.planned(m, hook))
+}
+
+// readHintHook adds the select statements of hook appending hint to the
+// policy table they read.
+func (a *Adapter) readHintHook(hook gdb.HookHandler, hint string) gdb.HookHandler {
+	charLeft, charRight := a.db.GetChars()
+	from := fmt.Sprintf(" FROM %s%s%s", charLeft, a.tableName, charRight)
+	next := hook.Select
+	hook.Select = func(ctx context.Context, in *gdb.HookSelectInput) (gdb.Result, error) {
+		in.Sql = strings.Replace(in.Sql, from, from+" "+hint, 1)
+		if next != nil {
+			return next(ctx, in)
+		}
+		return in.Next(ctx)
+	}
+	return hook
+}
+
+// scoped restricts m to the rows visible to the adapter.
+func (a *Adapter) scoped(m *gdb.Model) *gdb.Model {
+	if a.tenantColumn != "" {
+		m = m.Where(a.tenantColumn, a.tenant)
+	}
+	return m
+}
+
+// schema returns the optional columns of the policy tables.
+func (a *Adapter) schema() tableSchema {
+	return tableSchema{
+		tenantColumn:   a.tenantColumn,
+		tableComment:   a.tableComment,
+		columnComments: a.columnComments,
+		columnTypes:    a.columnTypeSchema(),
+		ruleHash:       a.ruleHash,
+		partitioned:    a.partitioned,
+		ruleColumn:     a.ruleColumn,
+		jsonStorage:    a.jsonStorage,
+		actorColumn:    a.actorColumn,
+	}
+}
+
+// ruleRecord converts rule into the row written to the policy table.
+func (a *Adapter) ruleRecord(ctx context.Context, rule Rule) g.Map {
+	if a.ruleColumn != "" {
+		return a.stamped(a.ruleLineRecord(rule))
+	}
+	if a.jsonStorage {
+		return a.stamped(a.jsonValuesRecord(rule))
+	}
+	record := g.Map{
+		a.pTypeColumn: rule.PType,
+		Columns.V0:    rule.V0,
+		Columns.V1:    rule.V1,
+		Columns.V2:    rule.V2,
+		Columns.V3:    rule.V3,
+		Columns.V4:    rule.V4,
+		Columns.V5:    rule.V5,
+	}
+	if a.tenantColumn != "" {
+		record[a.tenantColumn] = a.tenant
+	}
+	if a.ruleHash {
+		record[ruleHashColumn] = ruleHash(rule)
+	}
+	if a.actorColumn != "" {
+		record[a.actorColumn] = a.actor(ctx)
+	}
+	if a.tombstones {
+		stamp := nextRowStamp()
+		record["id"], record[versionColumn] = stamp, stamp
+	}
+	return a.stamped(record)
+}
+
+// atomic runs fn in a transaction when a single rule change writes to more
+// than one table, otherwise fn runs directly.
+func (a *Adapter) atomic(ctx context.Context, fn func(ctx context.Context) error) error {
+	if a.historyTable == "" && a.versionTable == "" {
+		if err := fn(ctx); err != nil {
+			return err
+		}
+		return a.committed(ctx)
+	}
+	return a.transaction(ctx, func(ctx context.Context, tx gdb.TX) error {
+		return fn(ctx)
+	})
+}
+
+// insertRules inserts rules in batches for better performance.
+func (a *Adapter) insertRules(ctx context.Context, rules []Rule) error {
+	defer a.invalidateCache()
+	size := a.insertSize()
+	for i := 0; i < len(rules); i += size {
+		if err := ctx.Err(); err != nil {
+			return err
+		}
+		end := i + size
+		if end > len(rules) {
+			end = len(rules)
+		}
+		batch := make(g.List, 0, end-i)
+		for _, rule := range rules[i:end] {
+			batch = append(batch, a.ruleRecord(ctx, rule))
+		}
+		if _, err := a.modelCtx(ctx).Insert(batch); err != nil {
+			return fmt.Errorf("failed to insert rules batch: %w", err)
+		}
+		reportProgress(ctx, len(batch))
+	}
+	return a.recordHistory(ctx, historyOpAdd, rules)
+}
+
+// deleteRules deletes the rows selected by query. When history is enabled
+// the rows are read first so that their removal can be recorded.
+func (a *Adapter) deleteRules(ctx context.Context, query *gdb.Model) error {
+	defer a.invalidateCache()
+	if a.tombstones {
+		return a.writeTombstones(ctx, query)
+	}
+	if a.historyTable == "" {
+		_, err := query.Ctx(ctx).Delete()
+		return err
+	}
+
+	var removed []Rule
+	if err := query.Ctx(ctx).OrderAsc("id").Scan(&removed); err != nil {
+		return err
+	}
+	if len(removed) == 0 {
+		return nil
+	}
+	if _, err := query.Ctx(ctx).Delete(); err != nil {
+		return err
+	}
+	return a.recordHistory(ctx, historyOpRemove, removed)
+}
+
+// deleteRows deletes the rows selected by query like deleteRules, writing
+// tombstones on ClickHouse rather than a mutation, without recording their
+// history.
+func (a *Adapter) deleteRows(ctx context.Context, query *gdb.Model) error {
+	if a.tombstones {
+		return a.writeTombstones(ctx, query)
+	}
+	_, err := query.Ctx(ctx).Delete()
+	return err
 }
 
 // IsFiltered returns true if the loaded policy has been filtered.
@@ -147,150 +656,740 @@ func (a *Adapter) IsFiltered() bool {
 }
 
 // create a policy table when it doesn't exist.
-func (a *Adapter) createTable() error {
+func (a *Adapter) createTable(ctx context.Context) error {
 	if a.tableName == "" {
 		return errors.New("table name cannot be empty")
 	}
 
-	_, err := a.db.Exec(a.ctx, fmt.Sprintf(createTableSql, a.tableName))
-	if err != nil {
-		return fmt.Errorf("failed to create table: %w", err)
+	statements := a.dialect.createTableSql(a.tableName, a.schema())
+	if a.packed() {
+		statements = a.packedTableSql(a.tableName)
+	}
+	for _, sql := range statements {
+		if _, err := a.db.Exec(ctx, sql); err != nil {
+			return fmt.Errorf("failed to create table: %w", err)
+		}
+	}
+	return a.clearTableFields(ctx, a.tableName)
+}
+
+// clearTableFields drops the columns of table cached by gdb, which filters
+// the data of inserts by them, after the table was created or altered.
+func (a *Adapter) clearTableFields(ctx context.Context, table string) error {
+	if err := a.db.GetCore().ClearTableFields(ctx, table); err != nil {
+		return fmt.Errorf("failed to clear cached columns of %s: %w", table, err)
 	}
 	return nil
 }
 
+// drop the policy table from the storage.
+func (a *Adapter) dropTable(ctx context.Context) error {
+	if a.tableName == "" {
+		return errors.New("table name cannot be empty")
+	}
+
+	defer a.invalidateCache()
+	_, err := a.db.Exec(ctx, fmt.Sprintf(dropTableSql, a.tableName))
+	if err != nil {
+		return fmt.Errorf("failed to drop table: %w", err)
+	}
+	return a.clearTableFields(ctx, a.tableName)
+}
+
 // truncate policy table in the storage.
-func (a *Adapter) truncateTable() error {
+func (a *Adapter) truncateTable(ctx context.Context) error {
 	if a.tableName == "" {
 		return errors.New("table name cannot be empty")
 	}
 
-	_, err := a.db.Exec(a.ctx, fmt.Sprintf(truncateTableSql, a.tableName))
+	defer a.invalidateCache()
+	_, err := a.db.Exec(ctx, a.dialect.truncateTableSql(a.tableName))
 	if err != nil {
 		return fmt.Errorf("failed to truncate table: %w", err)
 	}
 	return nil
 }
 
-// SavePolicy saves all policy rules to the storage.
+// SavePolicy saves all policy rules to the storage. It returns
+// ErrUnknownSection when the model holds rules outside the p and g sections,
+// and ErrRefusingEmptySave when it holds no rules while the storage does,
+// unless the adapter was created with WithAllowEmptySave.
 func (a *Adapter) SavePolicy(model model.Model) error {
 	if model == nil {
 		return errors.New("model cannot be nil")
 	}
-
-	if err := a.truncateTable(); err != nil {
-		return fmt.Errorf("failed to truncate table: %w", err)
+	if err := checkSections(model); err != nil {
+		return fmt.Errorf("failed to save policy: %w", err)
 	}
+	op := Operation{Method: "SavePolicy", Model: model}
+	return a.mutate(a.ctx, op, func(ctx context.Context) error {
+		if err := a.checkEmptySave(ctx, model); err != nil {
+			return fmt.Errorf("failed to save policy: %w", err)
+		}
+		if err := a.confirmSave(ctx, model); err != nil {
+			return fmt.Errorf("failed to save policy: %w", err)
+		}
+		return a.savePolicy(ctx, model)
+	}, nil)
+}
 
-	var rules []Rule
-
-	// Convert policy rules to database records
-	for pType, ast := range model["p"] {
-		for _, rule := range ast.Policy {
-			rules = append(rules, a.buildRule(pType, rule))
+func (a *Adapter) savePolicy(ctx context.Context, model model.Model) error {
+	if a.routed() {
+		return a.saveSplit(ctx, model, (*Adapter).savePolicy)
+	}
+	if err := a.checkQuota(ctx, modelRuleCount(model), true); err != nil {
+		return fmt.Errorf("failed to save policy: %w", err)
+	}
+	if a.syncSave {
+		return a.syncPolicy(ctx, model)
+	}
+	if a.stableSave {
+		return a.stableSavePolicy(ctx, model)
+	}
+	ctx = withWatcherMessage(ctx, &WatcherMessage{Method: WatcherSavePolicy}, model)
+	rules := a.modelRules(model)
+	if a.parallelWrites() || a.splitsSave(ctx, len(rules)) {
+		return a.parallelSavePolicy(ctx, rules)
+	}
+	if a.noTransactions && a.tx == nil {
+		return a.sequentialSavePolicy(ctx, rules)
+	}
+
+	// A tenant scoped adapter only replaces the rows of its tenant, by
+	// truncating its partition when partitioned, and an adapter bound to a
+	// transaction can't truncate as it commits implicitly on some
+	// databases, as does one saving in the transaction of ctx. Their rows
+	// are deleted in the transaction below, as are soft deleted ones. The
+	// databases rolling back the truncation truncate in the transaction, so
+	// that a failed or canceled save keeps the stored rules. The others
+	// truncate before it, unless ctx can be canceled: the rows are then
+	// deleted in the transaction, so that a canceled save doesn't leave the
+	// table empty.
+	truncate := (a.tenantColumn == "" || a.partitioned) && a.tx == nil && !a.softDelete && gdb.TXFromCtx(ctx, a.db.GetGroup()) == nil &&
+		(ctx.Done() == nil || !a.partitioned && a.dialect.transactionalTruncate())
+	inTx := truncate && !a.partitioned && a.dialect.transactionalTruncate()
+	if truncate && a.partitioned {
+		if err := a.truncatePartition(ctx); err != nil {
+			return err
 		}
-	}
-	for pType, ast := range model["g"] {
-		for _, rule := range ast.Policy {
-			rules = append(rules, a.buildRule(pType, rule))
+	} else if truncate && !inTx {
+		if err := a.truncateTable(ctx); err != nil {
+			return fmt.Errorf("failed to truncate table: %w", err)
 		}
 	}
 
-	if len(rules) == 0 {
-		return nil
+	if len(rules) == 0 && a.historyTable == "" && a.versionTable == "" && truncate && !inTx {
+		return a.committed(ctx)
 	}
 
 	// Use transaction for better reliability
-	err := a.model().Transaction(a.ctx, func(ctx context.Context, tx gdb.TX) error {
-		// Insert rules in batches for better performance
-		for i := 0; i < len(rules); i += defaultBatchSize {
-			end := i + defaultBatchSize
-			if end > len(rules) {
-				end = len(rules)
+	err := a.transaction(ctx, func(ctx context.Context, tx gdb.TX) error {
+		switch {
+		case inTx:
+			if _, err := a.exec(ctx, tx, a.dialect.truncateTableSql(a.tableName)); err != nil {
+				return fmt.Errorf("failed to truncate table: %w", err)
 			}
-			batch := rules[i:end]
-			if _, err := tx.Model(a.tableName).Ctx(ctx).Insert(batch); err != nil {
-				return fmt.Errorf("failed to insert rules batch: %w", err)
+		case !truncate:
+			if err := a.deleteRows(ctx, a.modelCtx(ctx).Where("1=1")); err != nil {
+				return fmt.Errorf("failed to delete rules: %w", err)
 			}
 		}
-		return nil
+		if err := a.recordHistory(ctx, historyOpReset, nil); err != nil {
+			return err
+		}
+		return a.insertRules(ctx, rules)
 	})
 
 	return err
 }
 
+// modelRules converts the policy rules of model to database records.
+func (a *Adapter) modelRules(model model.Model) []Rule {
+	var rules []Rule
+	for _, sec := range policySections {
+		for _, pType := range sectionTypes(model, sec) {
+			for _, rule := range model[sec][pType].Policy {
+				rules = append(rules, a.buildRule(pType, rule))
+			}
+		}
+	}
+	return rules
+}
+
 // LoadPolicy loads all policy rules from the storage.
 func (a *Adapter) LoadPolicy(model model.Model) error {
 	if model == nil {
 		return errors.New("model cannot be nil")
 	}
+	ctx := a.startProgress(a.ctx, "LoadPolicy", -1)
+	return a.measureLoad(ctx, "LoadPolicy", nil, model, func(ctx context.Context) error {
+		return a.loadPolicy(ctx, model)
+	})
+}
 
-	var rules []Rule
-	err := a.model().
-		OrderAsc("id").
-		Scan(&rules)
+func (a *Adapter) loadPolicy(ctx context.Context, model model.Model) error {
+	if a.routed() {
+		for _, table := range a.splitTables() {
+			if err := table.loadPolicy(ctx, model); err != nil {
+				return err
+			}
+		}
+		a.isFiltered = false
+		return nil
+	}
+
+	key := a.cacheKey(nil)
+	if cached, err := a.loadCached(key, model); err != nil || cached {
+		if cached {
+			a.isFiltered = false
+		}
+		return err
+	}
+	if a.packed() {
+		return a.loadPacked(ctx, model, key)
+	}
+	if err := a.reservePolicy(ctx, model); err != nil {
+		return err
+	}
+	policy := a.newCachedPolicy()
+
+	// The records are read without scanning them into rules, the values of
+	// each page share one backing array.
+	query := a.loadModelCtx(ctx).Fields(append([]string{"id", a.pTypeColumn}, valueColumns...))
+	err := a.scanLoadPages(query, func(records gdb.Result) error {
+		arena := make([]string, 0, len(records)*len(valueColumns))
+		for _, record := range records {
+			start := len(arena)
+			for _, column := range valueColumns {
+				if value := record[column].String(); value != "" {
+					arena = append(arena, value)
+				}
+			}
+			pType, values := record[a.pTypeColumn].String(), arena[start:len(arena):len(arena)]
+			loadPolicyValues(pType, values, model)
+			policy.add(pType, values)
+		}
+		reportProgress(ctx, len(records))
+		return nil
+	})
 	if err != nil {
 		return fmt.Errorf("failed to scan policy rules: %w", err)
 	}
 
-	for _, rule := range rules {
-		a.loadPolicyRule(rule, model)
+	a.isFiltered = false
+	return a.storeCache(key, policy)
+}
+
+// reservePolicy grows the policy slices and maps of model by the number of
+// stored rules of each policy type.
+func (a *Adapter) reservePolicy(ctx context.Context, model model.Model) error {
+	records, err := a.modelCtx(ctx).
+		Fields(a.pTypeColumn, "COUNT(1) AS rules").
+		Group(a.pTypeColumn).
+		All()
+	if err != nil {
+		return fmt.Errorf("failed to count policy rules: %w", err)
 	}
 
+	for _, record := range records {
+		pType, rules := record[a.pTypeColumn].String(), record["rules"].Int()
+		if pType == "" {
+			continue
+		}
+		ast := model[pType[:1]][pType]
+		if ast == nil {
+			continue
+		}
+		if cap(ast.Policy)-len(ast.Policy) < rules {
+			policy := make([][]string, len(ast.Policy), len(ast.Policy)+rules)
+			copy(policy, ast.Policy)
+			ast.Policy = policy
+		}
+		if len(ast.PolicyMap) == 0 {
+			ast.PolicyMap = make(map[string]int, rules)
+		}
+	}
 	return nil
 }
 
-// LoadFilteredPolicy loads only policy rules that match the filter.
+// LoadFilteredPolicy loads only policy rules that match the filter. The
+// filter is a Filter or *Filter, or a []Filter or []*Filter loading the
+// rules matching any of its filters, each rule once. A FilterRaw filters by
+// a condition of its own. A nil filter loads the whole policy like
+// LoadPolicy.
+//
+// The filter can also be a map[string][]string of the values by column
+// name, p_type and v0 to v5, or a [][]string of rule patterns, each being a
+// policy type followed by the values by field index, with empty strings
+// matching any value. The rules matching any pattern are loaded.
 func (a *Adapter) LoadFilteredPolicy(model model.Model, filter interface{}) error {
+	return a.loadFiltered("LoadFilteredPolicy", model, filter)
+}
+
+// LoadIncrementalFilteredPolicy adds the rules matching filter, any filter
+// of LoadFilteredPolicy, to the rules already loaded into model, skipping
+// those loaded already. Unlike the enforcer's LoadFilteredPolicy the model
+// isn't cleared first, so that rules can be loaded lazily, one filter at a
+// time, on top of a first load.
+func (a *Adapter) LoadIncrementalFilteredPolicy(model model.Model, filter interface{}) error {
+	return a.loadFiltered("LoadIncrementalFilteredPolicy", model, filter)
+}
+
+// loadFiltered loads the rules matching filter into model, measured as
+// method. The rules loaded already are skipped.
+func (a *Adapter) loadFiltered(method string, model model.Model, filter interface{}) error {
 	if model == nil {
 		return errors.New("model cannot be nil")
 	}
 
-	filterRule, ok := filter.(Filter)
-	if !ok {
-		return errors.New("invalid filter type")
+	filters, err := toFilters(filter)
+	if err != nil {
+		return err
+	}
+	if filters == nil {
+		return a.LoadPolicy(model)
+	}
+	for i := range filters {
+		if err := filters[i].validate(a.valueLengths); err != nil {
+			return err
+		}
 	}
+	return a.measureLoad(a.ctx, method, filters, model, func(ctx context.Context) error {
+		return a.loadFilteredPolicy(ctx, model, filters)
+	})
+}
 
-	query := a.model()
+// toFilters returns the filters of a filter passed to LoadFilteredPolicy,
+// none for a nil filter.
+func toFilters(filter interface{}) ([]Filter, error) {
+	var filters []Filter
+	switch filter := filter.(type) {
+	case nil:
+		return nil, nil
+	case Filter:
+		return []Filter{filter}, nil
+	case *Filter:
+		if filter == nil {
+			return nil, nil
+		}
+		return []Filter{*filter}, nil
+	case []Filter:
+		filters = filter
+	case []*Filter:
+		filters = make([]Filter, 0, len(filter))
+		for _, f := range filter {
+			if f == nil {
+				return nil, fmt.Errorf("%w: nil filter", ErrInvalidFilter)
+			}
+			filters = append(filters, *f)
+		}
+	case map[string][]string:
+		f, err := mapFilter(filter)
+		if err != nil {
+			return nil, err
+		}
+		return []Filter{f}, nil
+	case FilterRaw:
+		if err := filter.validate(); err != nil {
+			return nil, err
+		}
+		return []Filter{{raw: filter}}, nil
+	case *FilterRaw:
+		if filter == nil {
+			return nil, nil
+		}
+		return toFilters(*filter)
+	case [][]string:
+		filters = make([]Filter, 0, len(filter))
+		for _, pattern := range filter {
+			f, err := patternFilter(pattern)
+			if err != nil {
+				return nil, err
+			}
+			filters = append(filters, f)
+		}
+	default:
+		return nil, fmt.Errorf("%w type %T, supposed to be a Filter, *Filter, []Filter, []*Filter, FilterRaw, map[string][]string or [][]string", ErrInvalidFilter, filter)
+	}
+	if len(filters) == 0 {
+		return nil, fmt.Errorf("%w: no filters", ErrInvalidFilter)
+	}
+	return filters, nil
+}
+
+// filterColumns names the columns of the fields of a Filter, in the order of
+// filterFields.
+var filterColumns = []string{Columns.PType, Columns.V0, Columns.V1, Columns.V2, Columns.V3, Columns.V4, Columns.V5}
 
-	if len(filterRule.PType) > 0 {
-		query = query.WhereIn(Columns.PType, filterRule.PType)
+// filterFields returns the fields of filter.
+func filterFields(filter *Filter) []*[]string {
+	return []*[]string{&filter.PType, &filter.V0, &filter.V1, &filter.V2, &filter.V3, &filter.V4, &filter.V5}
+}
+
+// mapFilter returns the filter of the values by column name of m.
+func mapFilter(m map[string][]string) (Filter, error) {
+	var filter Filter
+	fields := filterFields(&filter)
+	for key, values := range m {
+		i := slices.Index(filterColumns, key)
+		if i < 0 {
+			return Filter{}, fmt.Errorf("%w key %q, supposed to be one of %s", ErrInvalidFilter, key, strings.Join(filterColumns, ", "))
+		}
+		*fields[i] = values
 	}
-	if len(filterRule.V0) > 0 {
-		query = query.WhereIn(Columns.V0, filterRule.V0)
+	return filter, nil
+}
+
+// patternFilter returns the filter of a rule pattern, a policy type followed
+// by the values by field index, where empty strings match any value.
+func patternFilter(pattern []string) (Filter, error) {
+	if len(pattern) > len(filterColumns) {
+		return Filter{}, fmt.Errorf("%w pattern %q: more than %d fields", ErrInvalidFilter, pattern, len(filterColumns))
 	}
-	if len(filterRule.V1) > 0 {
-		query = query.WhereIn(Columns.V1, filterRule.V1)
+
+	var filter Filter
+	fields := filterFields(&filter)
+	for i, value := range pattern {
+		if value != "" {
+			*fields[i] = []string{value}
+		}
 	}
-	if len(filterRule.V2) > 0 {
-		query = query.WhereIn(Columns.V2, filterRule.V2)
+	return filter, nil
+}
+
+func (a *Adapter) loadFilteredPolicy(ctx context.Context, model model.Model, filters []Filter) error {
+
+	key := a.cacheKey(filters)
+	if cached, err := a.loadCached(key, model); err != nil || cached {
+		if cached {
+			a.isFiltered = true
+		}
+		return err
 	}
-	if len(filterRule.V3) > 0 {
-		query = query.WhereIn(Columns.V3, filterRule.V3)
+
+	rows, err := a.filteredRows(ctx, filters)
+	if err != nil {
+		return err
+	}
+	policy := a.newCachedPolicy()
+	for _, row := range rows {
+		a.loadPolicyRule(row.Rule, model)
+		policy.add(row.PType, row.toSlice())
+	}
+
+	a.isFiltered = true
+	return a.storeCache(key, policy)
+}
+
+// filteredRows returns the rows matching any of filters, in the order and
+// page of the first filter. Every filter, and long value lists, are queried
+// separately, a row matching several filters is returned once.
+func (a *Adapter) filteredRows(ctx context.Context, filters []Filter) ([]ruleRow, error) {
+	if a.routed() {
+		return a.splitRows(ctx, filters)
+	}
+	if a.packed() {
+		return a.packedRows(ctx, filters)
 	}
-	if len(filterRule.V4) > 0 {
-		query = query.WhereIn(Columns.V4, filterRule.V4)
+	page, err := a.pageOf(filters)
+	if err != nil {
+		return nil, err
 	}
-	if len(filterRule.V5) > 0 {
-		query = query.WhereIn(Columns.V5, filterRule.V5)
+	var chunks []Filter
+	for _, filter := range filters {
+		chunks = append(chunks, a.filterChunks(filter)...)
 	}
 
-	var rules []Rule
-	if err := query.Scan(&rules); err != nil {
-		return fmt.Errorf("failed to scan filtered policy rules: %w", err)
+	var rows []pagedRow
+	for _, chunk := range chunks {
+		var chunkRows []pagedRow
+		query, err := a.filterQuery(a.loadModelCtx(ctx), chunk)
+		if err != nil {
+			return nil, err
+		}
+		records, err := page.query(query, len(chunks) == 1).All()
+		if err == nil {
+			err = records.Structs(&chunkRows)
+		}
+		if err != nil {
+			return nil, fmt.Errorf("failed to scan filtered policy rules: %w", err)
+		}
+		if page.column != "id" && page.column != createdAtColumn {
+			for i, record := range records {
+				chunkRows[i].order, chunkRows[i].orderNull = record[page.column].String(), record[page.column].IsNil()
+			}
+		}
+		if a.actorColumn != "" {
+			for i, record := range records {
+				chunkRows[i].CreatedBy = record[a.actorColumn].String()
+			}
+		}
+		rows = append(rows, chunkRows...)
+	}
+	if len(chunks) > 1 {
+		sort.Slice(rows, func(i, j int) bool {
+			return page.less(rows, i, j)
+		})
+		unique := rows[:0]
+		for i, row := range rows {
+			if i == 0 || row.Id != rows[i-1].Id {
+				unique = append(unique, row)
+			}
+		}
+		rows = page.slice(unique)
 	}
 
-	for _, rule := range rules {
-		a.loadPolicyRule(rule, model)
+	result := make([]ruleRow, len(rows))
+	for i, row := range rows {
+		result[i] = ruleRow{Id: row.Id, Rule: row.Rule}
 	}
+	return result, nil
+}
 
-	a.isFiltered = true
-	return nil
+// WithFilterChunkSize sets the maximum number of values of a Filter field
+// sent in one query by LoadFilteredPolicy, 1000 by default. Longer value
+// lists are split over several queries to stay below the limits of the
+// databases on query size and parameters.
+func WithFilterChunkSize(size int) AdapterOption {
+	return AdapterOption{apply: func(a *Adapter) {
+		if size > 0 {
+			a.filterChunkSize = size
+		}
+	}}
+}
+
+// filterChunks splits filter into filters whose value lists have at most
+// the filter chunk size values. Together they match the rules of filter,
+// and no rule is matched by two of them. The negated lists aren't split.
+func (a *Adapter) filterChunks(filter Filter) []Filter {
+	chunks := []Filter{filter}
+	fields := []func(f *Filter) *[]string{
+		func(f *Filter) *[]string { return &f.PType },
+		func(f *Filter) *[]string { return &f.V0 },
+		func(f *Filter) *[]string { return &f.V1 },
+		func(f *Filter) *[]string { return &f.V2 },
+		func(f *Filter) *[]string { return &f.V3 },
+		func(f *Filter) *[]string { return &f.V4 },
+		func(f *Filter) *[]string { return &f.V5 },
+	}
+	for _, field := range fields {
+		values := *field(&filter)
+		if len(values) <= a.filterChunkSize {
+			continue
+		}
+
+		split := make([]Filter, 0, len(chunks)*(len(values)/a.filterChunkSize+1))
+		for _, chunk := range chunks {
+			for i := 0; i < len(values); i += a.filterChunkSize {
+				end := i + a.filterChunkSize
+				if end > len(values) {
+					end = len(values)
+				}
+				*field(&chunk) = values[i:end]
+				split = append(split, chunk)
+			}
+		}
+		chunks = split
+	}
+	return chunks
+}
+
+// filterQuery restricts query to the rules matching filter, whose values
+// are normalized and encoded first.
+func (a *Adapter) filterQuery(query *gdb.Model, filter Filter) (*gdb.Model, error) {
+	filter = a.normalizeFilter(filter)
+	for i, prefixes := range filterPrefixes(filter) {
+		if len(prefixes) == 0 {
+			continue
+		}
+		if a.codec != nil || a.hasher != nil && slices.Contains(a.hashedFields, i) {
+			return nil, fmt.Errorf("%w: prefixes can't match the encoded values of %s", ErrInvalidFilter, valueColumns[i])
+		}
+		conditions := make([]string, len(prefixes))
+		args := make([]interface{}, len(prefixes))
+		for j, prefix := range prefixes {
+			conditions[j] = valueColumns[i] + " LIKE ? ESCAPE '" + likeEscape + "'"
+			args[j] = likePrefix(prefix)
+		}
+		query = query.Where("("+strings.Join(conditions, " OR ")+")", args...)
+	}
+
+	filter, err := a.encodeFilter(filter)
+	if err != nil {
+		return nil, err
+	}
+	if len(filter.PType) > 0 {
+		query = query.WhereIn(a.pTypeColumn, filter.PType)
+	}
+	if len(filter.V0) > 0 {
+		query = query.WhereIn(Columns.V0, filter.V0)
+	}
+	if len(filter.V1) > 0 {
+		query = query.WhereIn(Columns.V1, filter.V1)
+	}
+	if len(filter.V2) > 0 {
+		query = query.WhereIn(Columns.V2, filter.V2)
+	}
+	if len(filter.V3) > 0 {
+		query = query.WhereIn(Columns.V3, filter.V3)
+	}
+	if len(filter.V4) > 0 {
+		query = query.WhereIn(Columns.V4, filter.V4)
+	}
+	if len(filter.V5) > 0 {
+		query = query.WhereIn(Columns.V5, filter.V5)
+	}
+	for i, values := range [][]string{filter.NotPType, filter.NotV0, filter.NotV1, filter.NotV2, filter.NotV3, filter.NotV4, filter.NotV5} {
+		if len(values) > 0 {
+			column := a.pTypeColumn
+			if i > 0 {
+				column = valueColumns[i-1]
+			}
+			query = query.WhereNotIn(column, values)
+		}
+	}
+	if !filter.CreatedAfter.IsZero() {
+		query = query.WhereGTE(createdAtColumn, a.dbTime(filter.CreatedAfter))
+	}
+	if !filter.CreatedBefore.IsZero() {
+		query = query.WhereLT(createdAtColumn, a.dbTime(filter.CreatedBefore))
+	}
+	if filter.raw.Where != "" {
+		query = query.Where("("+filter.raw.Where+")", filter.raw.Args...)
+	}
+	if len(filter.domains) > 0 {
+		condition, args, err := a.domainCondition(filter.domains)
+		if err != nil {
+			return nil, err
+		}
+		query = query.Where(condition, args...)
+	}
+	return query, nil
+}
+
+// likeEscape escapes the wildcards of the LIKE patterns of likePrefix. It
+// isn't a backslash, which needs escaping in the MySQL string literals.
+const likeEscape = "!"
+
+// likePrefix returns the LIKE pattern matching the values starting with
+// prefix.
+func likePrefix(prefix string) string {
+	return likeEscaped(prefix) + "%"
+}
+
+// likeEscaped escapes the wildcards of value in a LIKE pattern.
+func likeEscaped(value string) string {
+	return strings.NewReplacer(likeEscape, likeEscape+likeEscape, "%", likeEscape+"%", "_", likeEscape+"_").Replace(value)
+}
+
+// filterPrefixes returns the prefix lists of filter by field index.
+func filterPrefixes(filter Filter) [][]string {
+	return [][]string{filter.V0Prefix, filter.V1Prefix, filter.V2Prefix, filter.V3Prefix, filter.V4Prefix, filter.V5Prefix}
 }
 
-// toQuery gets query string and args from Rule.
-func (c *Rule) toQuery() (interface{}, []interface{}) {
-	where := "p_type=?"
+// ruleRow is a stored rule together with its id.
+type ruleRow struct {
+	Id int64 `orm:"id"`
+	Rule
+}
+
+// WithBatchSize sets the number of rules written per statement, 1000 by
+// default. It is the same as setting AdapterOption.BatchSize. The
+// statements hold fewer rules when they would bind more parameters than
+// the database allows, like the 2100 of SQL Server.
+func WithBatchSize(size int) AdapterOption {
+	return AdapterOption{BatchSize: size}
+}
+
+// WithStrictUpdate makes UpdatePolicy and UpdatePolicies fail, without
+// changing any rule, when an old rule is ambiguous: it matches several
+// stored rules, or shares the stored rule it matches with another old rule.
+// Without it every stored rule matched by an old rule is replaced.
+func WithStrictUpdate() AdapterOption {
+	return AdapterOption{apply: func(a *Adapter) {
+		a.strictUpdate = true
+	}}
+}
+
+// AmbiguousRuleError is returned by UpdatePolicy and UpdatePolicies in
+// strict mode when an old rule is ambiguous.
+type AmbiguousRuleError struct {
+	Rule Rule
+	// Matches is the number of stored rules matched by Rule, Shared is set
+	// when its match is also matched by another old rule.
+	Matches int
+	Shared  bool
+}
+
+func (e *AmbiguousRuleError) Error() string {
+	values := append([]string{e.Rule.PType}, e.Rule.toSlice()...)
+	if e.Shared {
+		return fmt.Sprintf("ambiguous old rule %v: its stored rule is matched by another old rule", values)
+	}
+	return fmt.Sprintf("ambiguous old rule %v: matches %d stored rules", values, e.Matches)
+}
+
+// WithPageSize sets the number of rows read per query by LoadPolicy and the
+// other operations reading the whole table, 10000 by default.
+func WithPageSize(size int) AdapterOption {
+	return AdapterOption{apply: func(a *Adapter) {
+		if size > 0 {
+			a.pageSize = size
+		}
+	}}
+}
+
+// scanPages reads the rows selected by query in pages of the page size,
+// ordered by id, and calls fn with each page. Pages are read with keyset
+// pagination so that large tables are never held in memory at once.
+func (a *Adapter) scanPages(query *gdb.Model, fn func(rows []ruleRow) error) error {
+	return a.scanRecordPages(query, func(records gdb.Result) error {
+		var rows []ruleRow
+		if err := records.Structs(&rows); err != nil {
+			return fmt.Errorf("failed to scan rules page: %w", err)
+		}
+		if a.actorColumn != "" {
+			for i, record := range records {
+				rows[i].CreatedBy = record[a.actorColumn].String()
+			}
+		}
+		return fn(rows)
+	})
+}
+
+// scanRecordPages is scanPages reading the pages as records, which must
+// include the id column.
+func (a *Adapter) scanRecordPages(query *gdb.Model, fn func(records gdb.Result) error) error {
+	var lastID int64
+	for {
+		if err := query.GetCtx().Err(); err != nil {
+			return err
+		}
+		records, err := query.
+			WhereGT("id", lastID).
+			OrderAsc("id").
+			Limit(a.pageSize).
+			All()
+		if err != nil {
+			return fmt.Errorf("failed to read rules page: %w", err)
+		}
+		if len(records) == 0 {
+			return nil
+		}
+		if err := fn(records); err != nil {
+			return err
+		}
+		if len(records) < a.pageSize {
+			return nil
+		}
+		lastID = records[len(records)-1]["id"].Int64()
+	}
+}
+
+// toQuery gets query string and args from Rule, pTypeColumn is the policy
+// type column of the table.
+func (c *Rule) toQuery(pTypeColumn string) (interface{}, []interface{}) {
+	where := pTypeColumn + "=?"
 	args := []interface{}{c.PType}
 
 	if c.V0 != "" {
@@ -321,6 +1420,24 @@ func (c *Rule) toQuery() (interface{}, []interface{}) {
 	return where, args
 }
 
+// matches reports whether the stored rule is matched by the conditions of
+// toQuery, which ignore the empty values of c.
+func (c *Rule) matches(stored Rule) bool {
+	values := [...][2]string{
+		{c.V0, stored.V0}, {c.V1, stored.V1}, {c.V2, stored.V2},
+		{c.V3, stored.V3}, {c.V4, stored.V4}, {c.V5, stored.V5},
+	}
+	if c.PType != stored.PType {
+		return false
+	}
+	for _, v := range values {
+		if v[0] != "" && v[0] != v[1] {
+			return false
+		}
+	}
+	return true
+}
+
 // toSlice converts Rule to string slice.
 func (c *Rule) toSlice() []string {
 	if c == nil {
@@ -350,8 +1467,31 @@ func (c *Rule) toSlice() []string {
 	return res
 }
 
-// buildRule builds Rule from string slice.
+// fieldColumn returns the column storing the value at fieldIndex.
+func fieldColumn(fieldIndex int) string {
+	return fmt.Sprintf("v%d", fieldIndex)
+}
+
+// isValidFieldIndex reports whether fieldIndex addresses a value column.
+func isValidFieldIndex(fieldIndex int) bool {
+	return fieldIndex >= 0 && fieldIndex <= maxFieldIndex
+}
+
+// ruleKey identifies a rule by its policy type and values.
+type ruleKey [7]string
+
+// key returns the identity of the rule.
+func (c *Rule) key() ruleKey {
+	return ruleKey{c.PType, c.V0, c.V1, c.V2, c.V3, c.V4, c.V5}
+}
+
+// buildRule builds Rule from string slice, normalizing its values.
 func (a *Adapter) buildRule(pType string, data []string) Rule {
+	return a.normalizeRule(newRule(pType, data))
+}
+
+// newRule builds Rule from string slice.
+func newRule(pType string, data []string) Rule {
 	rule := Rule{
 		PType: pType,
 	}
@@ -380,20 +1520,52 @@ func (a *Adapter) buildRule(pType string, data []string) Rule {
 
 // loadPolicyRule loads a policy rule into the model.
 func (a *Adapter) loadPolicyRule(rule Rule, model model.Model) {
-	ruleText := rule.toSlice()
-	if len(ruleText) == 0 {
+	loadPolicyValues(rule.PType, rule.toSlice(), model)
+}
+
+// loadPolicyValues loads the values of a policy rule into the model and
+// indexes them in its policy map, skipping the rules already loaded like
+// persist.LoadPolicyArray. Rules of policy types missing from the model
+// are skipped too.
+func loadPolicyValues(pType string, values []string, model model.Model) {
+	if len(values) == 0 || pType == "" {
 		return
 	}
 
-	key := rule.PType
-	sec := key[:1]
-	model[sec][key].Policy = append(model[sec][key].Policy, ruleText)
+	ast := model[pType[:1]][pType]
+	if ast == nil {
+		return
+	}
+	if ast.PolicyMap == nil {
+		ast.PolicyMap = make(map[string]int)
+	}
+	key := strings.Join(values, policyKeySep)
+	if _, ok := ast.PolicyMap[key]; ok {
+		return
+	}
+	ast.PolicyMap[key] = len(ast.Policy)
+	ast.Policy = append(ast.Policy, values)
 }
 
 // AddPolicy adds a policy rule to the storage.
 func (a *Adapter) AddPolicy(sec string, pType string, rule []string) error {
+	if g := a.groupingFor(pType); g != nil {
+		return g.AddPolicy(sec, pType, rule)
+	}
+	op := Operation{Method: "AddPolicy", Sec: sec, PType: pType, Rules: a.opRules(pType, rule)}
+	write := func(ctx context.Context) error { return a.addPolicy(ctx, sec, pType, rule) }
+	return a.mutate(a.ctx, op, write, func(d persist.Dispatcher) error {
+		return d.AddPolicies(sec, pType, [][]string{rule})
+	})
+}
+
+func (a *Adapter) addPolicy(ctx context.Context, sec string, pType string, rule []string) error {
 	dbRule := a.buildRule(pType, rule)
-	_, err := a.model().Insert(dbRule)
+	ctx = withWatcherMessage(ctx, &WatcherMessage{Method: WatcherAddPolicy, Sec: sec, PType: pType, Rules: [][]string{rule}}, nil)
+	err := a.atomic(ctx, func(ctx context.Context) error {
+		_, err := a.insertRulesOnConflict(ctx, []Rule{dbRule}, a.conflictPolicy)
+		return err
+	})
 	if err != nil {
 		return fmt.Errorf("failed to add policy: %w", err)
 	}
@@ -402,6 +1574,17 @@ func (a *Adapter) AddPolicy(sec string, pType string, rule []string) error {
 
 // AddPolicies adds policy rules to the storage.
 func (a *Adapter) AddPolicies(sec string, pType string, rules [][]string) error {
+	if g := a.groupingFor(pType); g != nil {
+		return g.AddPolicies(sec, pType, rules)
+	}
+	op := Operation{Method: "AddPolicies", Sec: sec, PType: pType, Rules: a.opRules(pType, rules...)}
+	write := func(ctx context.Context) error { return a.addPolicies(ctx, sec, pType, rules) }
+	return a.mutate(a.ctx, op, write, func(d persist.Dispatcher) error {
+		return d.AddPolicies(sec, pType, rules)
+	})
+}
+
+func (a *Adapter) addPolicies(ctx context.Context, sec string, pType string, rules [][]string) error {
 	if len(rules) == 0 {
 		return nil
 	}
@@ -411,19 +1594,13 @@ func (a *Adapter) AddPolicies(sec string, pType string, rules [][]string) error
 		dbRules = append(dbRules, a.buildRule(pType, rule))
 	}
 
-	err := a.model().Transaction(a.ctx, func(ctx context.Context, tx gdb.TX) error {
-		// Insert rules in batches for better performance
-		for i := 0; i < len(dbRules); i += defaultBatchSize {
-			end := i + defaultBatchSize
-			if end > len(dbRules) {
-				end = len(dbRules)
-			}
-			batch := dbRules[i:end]
-			if _, err := tx.Model(a.tableName).Ctx(ctx).Insert(batch); err != nil {
-				return fmt.Errorf("failed to insert rules batch: %w", err)
-			}
-		}
-		return nil
+	ctx = withWatcherMessage(ctx, &WatcherMessage{Method: WatcherAddPolicies, Sec: sec, PType: pType, Rules: rules}, nil)
+	if a.splitsWrite(ctx, len(dbRules)) {
+		return a.splitAddPolicies(ctx, dbRules)
+	}
+	err := a.transaction(ctx, func(ctx context.Context, tx gdb.TX) error {
+		_, err := a.insertRulesOnConflict(ctx, dbRules, a.conflictPolicy)
+		return err
 	})
 
 	return err
@@ -431,9 +1608,26 @@ func (a *Adapter) AddPolicies(sec string, pType string, rules [][]string) error
 
 // RemovePolicy removes a policy rule from the storage.
 func (a *Adapter) RemovePolicy(sec string, pType string, rule []string) error {
+	if g := a.groupingFor(pType); g != nil {
+		return g.RemovePolicy(sec, pType, rule)
+	}
+	op := Operation{Method: "RemovePolicy", Sec: sec, PType: pType, Rules: a.opRules(pType, rule)}
+	write := func(ctx context.Context) error { return a.removePolicy(ctx, sec, pType, rule) }
+	return a.mutate(a.ctx, op, write, func(d persist.Dispatcher) error {
+		return d.RemovePolicies(sec, pType, [][]string{rule})
+	})
+}
+
+func (a *Adapter) removePolicy(ctx context.Context, sec string, pType string, rule []string) error {
 	dbRule := a.buildRule(pType, rule)
-	query, args := dbRule.toQuery()
-	_, err := a.model().Where(query, args...).Delete()
+	query, args, err := a.rulesCondition([]Rule{dbRule})
+	if err != nil {
+		return fmt.Errorf("failed to delete policy: %w", err)
+	}
+	ctx = withWatcherMessage(ctx, &WatcherMessage{Method: WatcherRemovePolicy, Sec: sec, PType: pType, Rules: [][]string{rule}}, nil)
+	err = a.atomic(ctx, func(ctx context.Context) error {
+		return a.deleteRules(ctx, a.modelCtx(ctx).Where(query, args...))
+	})
 	if err != nil {
 		return fmt.Errorf("failed to delete policy: %w", err)
 	}
@@ -442,41 +1636,231 @@ func (a *Adapter) RemovePolicy(sec string, pType string, rule []string) error {
 
 // RemovePolicies removes policy rules from the storage.
 func (a *Adapter) RemovePolicies(sec string, pType string, rules [][]string) error {
+	if g := a.groupingFor(pType); g != nil {
+		return g.RemovePolicies(sec, pType, rules)
+	}
+	op := Operation{Method: "RemovePolicies", Sec: sec, PType: pType, Rules: a.opRules(pType, rules...)}
+	write := func(ctx context.Context) error { return a.removePolicies(ctx, sec, pType, rules) }
+	return a.mutate(a.ctx, op, write, func(d persist.Dispatcher) error {
+		return d.RemovePolicies(sec, pType, rules)
+	})
+}
+
+func (a *Adapter) removePolicies(ctx context.Context, sec string, pType string, rules [][]string) error {
 	if len(rules) == 0 {
 		return nil
 	}
 
-	err := a.model().Transaction(a.ctx, func(ctx context.Context, tx gdb.TX) error {
-		for _, rule := range rules {
-			dbRule := a.buildRule(pType, rule)
-			query, args := dbRule.toQuery()
-			if _, err := tx.Model(a.tableName).Ctx(ctx).Where(query, args...).Delete(); err != nil {
-				return fmt.Errorf("failed to delete rule: %w", err)
-			}
-		}
-		return nil
+	dbRules := make([]Rule, 0, len(rules))
+	for _, rule := range rules {
+		dbRules = append(dbRules, a.buildRule(pType, rule))
+	}
+
+	ctx = withWatcherMessage(ctx, &WatcherMessage{Method: WatcherRemovePolicies, Sec: sec, PType: pType, Rules: rules}, nil)
+	err := a.transaction(ctx, func(ctx context.Context, tx gdb.TX) error {
+		return a.removeRules(ctx, dbRules)
 	})
 
 	return err
 }
 
-// RemoveFilteredPolicy removes policy rules that match the filter from the storage.
-func (a *Adapter) RemoveFilteredPolicy(sec string, pType string, fieldIndex int, fieldValues ...string) error {
-	if fieldIndex < 0 || fieldIndex > maxFieldIndex {
-		return fmt.Errorf("invalid field index: %d", fieldIndex)
+// removeRules deletes the stored rules matched by rules, each rule matching
+// the rows RemovePolicy would delete for it. The conditions of up to the
+// batch size rules are combined with OR into one statement, fewer when the
+// dialect limits the size of the conditions.
+func (a *Adapter) removeRules(ctx context.Context, rules []Rule) error {
+	size := a.groupSize()
+	for i := 0; i < len(rules); i += size {
+		if err := ctx.Err(); err != nil {
+			return err
+		}
+		end := i + size
+		if end > len(rules) {
+			end = len(rules)
+		}
+		query, args, err := a.rulesCondition(rules[i:end])
+		if err != nil {
+			return fmt.Errorf("failed to delete rules: %w", err)
+		}
+		if err := a.deleteRules(ctx, a.modelCtx(ctx).Where(query, args...)); err != nil {
+			return fmt.Errorf("failed to delete rules: %w", err)
+		}
 	}
+	return nil
+}
 
-	query := a.model().Where(Columns.PType, pType)
+// insertSize returns the number of rules inserted by one statement.
+func (a *Adapter) insertSize() int {
+	return a.rowsPerInsert(a.insertColumns())
+}
 
+// rowsPerInsert returns the number of rows of columns values inserted by
+// one statement: the batch size, fewer when the dialect limits the rows or
+// the parameters of a statement, or WithMaxTxRows the rows of a
+// transaction.
+func (a *Adapter) rowsPerInsert(columns int) int {
+	size := min(a.batchSize, a.paramsLimit(columns))
+	if limit := a.dialect.maxInsertRows(); limit > 0 && limit < size {
+		size = limit
+	}
+	if a.maxTxRows > 0 && a.maxTxRows < size {
+		size = a.maxTxRows
+	}
+	return size
+}
+
+// groupSize returns the number of rules whose conditions are combined into
+// one statement.
+func (a *Adapter) groupSize() int {
+	size := min(a.batchSize, a.paramsLimit(a.conditionParams()))
+	if limit := a.dialect.maxGroupedConditions(); limit > 0 && limit < size {
+		return limit
+	}
+	return size
+}
+
+// idsSize returns the number of ids selected by one statement.
+func (a *Adapter) idsSize() int {
+	return min(a.batchSize, a.paramsLimit(1))
+}
+
+// reservedParams is the number of parameters left to the conditions
+// outside of a batch, like the one of the tenant.
+const reservedParams = 8
+
+// paramsLimit returns the number of items of params parameters each a
+// statement binds within the parameters of the dialect, at least 1.
+func (a *Adapter) paramsLimit(params int) int {
+	limit := a.dialect.maxParams()
+	if limit <= 0 {
+		return a.batchSize
+	}
+	return max((limit-reservedParams)/params, 1)
+}
+
+// insertColumns returns the number of columns of the rows inserted into
+// the policy table, the ones of ruleRecord and the tombstone column.
+func (a *Adapter) insertColumns() int {
+	switch {
+	case a.ruleColumn != "":
+		return 1
+	case a.jsonStorage:
+		return 2
+	}
+	columns := 1 + len(valueColumns)
+	if a.tenantColumn != "" {
+		columns++
+	}
+	if a.ruleHash {
+		columns++
+	}
+	if a.actorColumn != "" {
+		columns++
+	}
+	if a.tombstones {
+		columns += 3
+	}
+	return columns
+}
+
+// conditionParams returns the number of parameters of the condition of a
+// rule of rulesCondition.
+func (a *Adapter) conditionParams() int {
+	switch {
+	case a.ruleColumn != "":
+		return 1
+	case a.jsonStorage:
+		return 2
+	}
+	return 1 + len(valueColumns)
+}
+
+// rulesQuery combines the conditions of rules with OR, a row matches when it
+// matches any of the rules.
+func rulesQuery(rules []Rule, pTypeColumn string) (string, []interface{}) {
+	if len(rules) == 1 {
+		query, args := rules[0].toQuery(pTypeColumn)
+		return query.(string), args
+	}
+
+	conditions := make([]string, 0, len(rules))
+	args := make([]interface{}, 0, len(rules)*(maxFieldIndex+2))
+	for _, rule := range rules {
+		query, ruleArgs := rule.toQuery(pTypeColumn)
+		conditions = append(conditions, "("+query.(string)+")")
+		args = append(args, ruleArgs...)
+	}
+	return "(" + strings.Join(conditions, " OR ") + ")", args
+}
+
+// policyFieldsQuery returns the query of the stored rules of pType holding
+// the non-empty values of fieldValues from fieldIndex on.
+func (a *Adapter) policyFieldsQuery(ctx context.Context, pType string, fieldIndex int, fieldValues []string) (*gdb.Model, error) {
+	if a.packed() {
+		return a.packedFieldsQuery(ctx, pType, fieldIndex, fieldValues)
+	}
+	return a.fieldsQuery(a.modelCtx(ctx).Where(a.pTypeColumn, pType), fieldIndex, fieldValues)
+}
+
+// fieldsQuery restricts query to the rules holding the non-empty values of
+// fieldValues from fieldIndex on.
+func (a *Adapter) fieldsQuery(query *gdb.Model, fieldIndex int, fieldValues []string) (*gdb.Model, error) {
 	idx := fieldIndex
 	for _, fieldValue := range fieldValues {
-		if fieldValue != "" {
-			query = query.Where(fmt.Sprintf("v%d", idx), fieldValue)
+		if fieldValue = a.normalizeValue(idx, fieldValue); fieldValue != "" {
+			var err error
+			if query, err = a.fieldQuery(query, idx, fieldValue); err != nil {
+				return nil, err
+			}
 		}
 		idx++
 	}
+	return query, nil
+}
 
-	_, err := query.Delete()
+// fieldQuery restricts query to the rules holding value, normalized and
+// encoded, at fieldIndex.
+func (a *Adapter) fieldQuery(query *gdb.Model, fieldIndex int, value string) (*gdb.Model, error) {
+	encoded, err := a.encodeValue(a.normalizeValue(fieldIndex, value))
+	if err != nil {
+		return nil, err
+	}
+	return query.Where(fieldColumn(fieldIndex), encoded), nil
+}
+
+// RemoveFilteredPolicy removes policy rules that match the filter from the storage.
+func (a *Adapter) RemoveFilteredPolicy(sec string, pType string, fieldIndex int, fieldValues ...string) error {
+	if g := a.groupingFor(pType); g != nil {
+		return g.RemoveFilteredPolicy(sec, pType, fieldIndex, fieldValues...)
+	}
+	op := Operation{Method: "RemoveFilteredPolicy", Sec: sec, PType: pType, FieldIndex: fieldIndex, FieldValues: fieldValues}
+	write := func(ctx context.Context) error {
+		return a.removeFilteredPolicy(ctx, sec, pType, fieldIndex, fieldValues...)
+	}
+	return a.mutate(a.ctx, op, write, func(d persist.Dispatcher) error {
+		return d.RemoveFilteredPolicy(sec, pType, fieldIndex, fieldValues...)
+	})
+}
+
+func (a *Adapter) removeFilteredPolicy(ctx context.Context, sec string, pType string, fieldIndex int, fieldValues ...string) error {
+	if !isValidFieldIndex(fieldIndex) {
+		return fmt.Errorf("invalid field index: %d", fieldIndex)
+	}
+
+	query, err := a.policyFieldsQuery(ctx, pType, fieldIndex, fieldValues)
+	if err != nil {
+		return fmt.Errorf("failed to delete filtered policies: %w", err)
+	}
+	if err := a.confirmQuery(ctx, "RemoveFilteredPolicy", query); err != nil {
+		return fmt.Errorf("failed to delete filtered policies: %w", err)
+	}
+
+	ctx = withWatcherMessage(ctx, &WatcherMessage{
+		Method: WatcherRemoveFilteredPolicy, Sec: sec, PType: pType, FieldIndex: fieldIndex, FieldValues: fieldValues,
+	}, nil)
+	err = a.atomic(ctx, func(ctx context.Context) error {
+		return a.deleteRules(ctx, query)
+	})
 	if err != nil {
 		return fmt.Errorf("failed to delete filtered policies: %w", err)
 	}
@@ -486,63 +1870,160 @@ func (a *Adapter) RemoveFilteredPolicy(sec string, pType string, fieldIndex int,
 
 // UpdatePolicy updates a policy rule from storage.
 func (a *Adapter) UpdatePolicy(sec string, pType string, oldRule, newRule []string) error {
-	err := a.model().Transaction(a.ctx, func(ctx context.Context, tx gdb.TX) error {
-		oldData := a.buildRule(pType, oldRule)
-		query, args := oldData.toQuery()
-
-		// Delete old rule
-		if _, err := tx.Model(a.tableName).Ctx(ctx).Where(query, args...).Delete(); err != nil {
-			return fmt.Errorf("failed to delete old rule: %w", err)
-		}
-
-		// Insert new rule
-		newData := a.buildRule(pType, newRule)
-		if _, err := tx.Model(a.tableName).Ctx(ctx).Insert(newData); err != nil {
-			return fmt.Errorf("failed to insert new rule: %w", err)
-		}
+	if g := a.groupingFor(pType); g != nil {
+		return g.UpdatePolicy(sec, pType, oldRule, newRule)
+	}
+	op := Operation{Method: "UpdatePolicy", Sec: sec, PType: pType, Rules: a.opRules(pType, oldRule), NewRules: a.opRules(pType, newRule)}
+	write := func(ctx context.Context) error { return a.updatePolicy(ctx, sec, pType, oldRule, newRule) }
+	return a.mutate(a.ctx, op, write, func(d persist.Dispatcher) error {
+		return d.UpdatePolicy(sec, pType, oldRule, newRule)
+	})
+}
 
-		return nil
+func (a *Adapter) updatePolicy(ctx context.Context, sec string, pType string, oldRule, newRule []string) error {
+	ctx = withWatcherMessage(ctx, &WatcherMessage{
+		Method: WatcherUpdatePolicy, Sec: sec, PType: pType, Rules: [][]string{oldRule}, NewRules: [][]string{newRule},
+	}, nil)
+	err := a.transaction(ctx, func(ctx context.Context, tx gdb.TX) error {
+		return a.updateRules(ctx, []Rule{a.buildRule(pType, oldRule)}, []Rule{a.buildRule(pType, newRule)})
 	})
 
 	return err
 }
 
-// UpdatePolicies updates multiple policy rules in the storage.
+// UpdatePolicies updates multiple policy rules in the storage, oldRules[i]
+// is replaced by newRules[i]. The old rules are deleted and the new ones
+// inserted in batches, so a new rule is never deleted as the old rule of
+// another pair.
 func (a *Adapter) UpdatePolicies(sec string, pType string, oldRules, newRules [][]string) error {
+	if g := a.groupingFor(pType); g != nil {
+		return g.UpdatePolicies(sec, pType, oldRules, newRules)
+	}
 	if len(oldRules) != len(newRules) {
 		return errors.New("old rules and new rules have different length")
 	}
+	op := Operation{
+		Method: "UpdatePolicies", Sec: sec, PType: pType, Rules: a.opRules(pType, oldRules...), NewRules: a.opRules(pType, newRules...),
+	}
+	write := func(ctx context.Context) error { return a.updatePolicies(ctx, sec, pType, oldRules, newRules) }
+	return a.mutate(a.ctx, op, write, func(d persist.Dispatcher) error {
+		return d.UpdatePolicies(sec, pType, oldRules, newRules)
+	})
+}
+
+func (a *Adapter) updatePolicies(ctx context.Context, sec string, pType string, oldRules, newRules [][]string) error {
 
 	if len(oldRules) == 0 {
 		return nil
 	}
 
-	err := a.model().Transaction(a.ctx, func(ctx context.Context, tx gdb.TX) error {
-		for i := 0; i < len(oldRules); i++ {
-			oldRule := a.buildRule(pType, oldRules[i])
-			query, args := oldRule.toQuery()
+	oldData := make([]Rule, 0, len(oldRules))
+	newData := make([]Rule, 0, len(newRules))
+	for i := range oldRules {
+		oldData = append(oldData, a.buildRule(pType, oldRules[i]))
+		newData = append(newData, a.buildRule(pType, newRules[i]))
+	}
 
-			// Delete old rule
-			if _, err := tx.Model(a.tableName).Ctx(ctx).Where(query, args...).Delete(); err != nil {
-				return fmt.Errorf("failed to delete old rule: %w", err)
-			}
+	ctx = withWatcherMessage(ctx, &WatcherMessage{
+		Method: WatcherUpdatePolicies, Sec: sec, PType: pType, Rules: oldRules, NewRules: newRules,
+	}, nil)
+	err := a.transaction(ctx, func(ctx context.Context, tx gdb.TX) error {
+		return a.updateRules(ctx, oldData, newData)
+	})
+
+	return err
+}
 
-			// Insert new rule
-			newRule := a.buildRule(pType, newRules[i])
-			if _, err := tx.Model(a.tableName).Ctx(ctx).Insert(newRule); err != nil {
-				return fmt.Errorf("failed to insert new rule: %w", err)
+// updateRules replaces the stored rules matched by oldRules with newRules.
+// In strict mode it first checks that every old rule matches a stored rule
+// of its own.
+func (a *Adapter) updateRules(ctx context.Context, oldRules, newRules []Rule) error {
+	if a.strictUpdate {
+		if err := a.checkUnambiguous(ctx, oldRules); err != nil {
+			return err
+		}
+	}
+
+	if err := a.removeRules(ctx, oldRules); err != nil {
+		return fmt.Errorf("failed to delete old rules: %w", err)
+	}
+	if err := a.insertRules(ctx, newRules); err != nil {
+		return fmt.Errorf("failed to insert new rules: %w", err)
+	}
+	return nil
+}
+
+// checkUnambiguous returns an *AmbiguousRuleError when one of rules matches
+// several stored rules, or a stored rule is matched by several of rules.
+func (a *Adapter) checkUnambiguous(ctx context.Context, rules []Rule) error {
+	// A row is read once per chunk of rules matching it.
+	var rows []ruleRow
+	read := make(map[int64]bool)
+	size := a.groupSize()
+	for i := 0; i < len(rules); i += size {
+		if err := ctx.Err(); err != nil {
+			return err
+		}
+		end := i + size
+		if end > len(rules) {
+			end = len(rules)
+		}
+		var chunkRows []ruleRow
+		query, args, err := a.rulesCondition(rules[i:end])
+		if err != nil {
+			return fmt.Errorf("failed to scan old rules: %w", err)
+		}
+		if err := a.modelCtx(ctx).Where(query, args...).OrderAsc("id").Scan(&chunkRows); err != nil {
+			return fmt.Errorf("failed to scan old rules: %w", err)
+		}
+		for _, row := range chunkRows {
+			if !read[row.Id] {
+				read[row.Id] = true
+				rows = append(rows, row)
 			}
 		}
-		return nil
-	})
+	}
 
-	return err
+	matched := make(map[int64]bool, len(rows))
+	for _, rule := range rules {
+		matches := 0
+		for _, row := range rows {
+			if !rule.matches(row.Rule) {
+				continue
+			}
+			if matched[row.Id] {
+				return &AmbiguousRuleError{Rule: rule, Matches: 1, Shared: true}
+			}
+			matched[row.Id] = true
+			matches++
+		}
+		if matches > 1 {
+			return &AmbiguousRuleError{Rule: rule, Matches: matches}
+		}
+	}
+	return nil
 }
 
 // UpdateFilteredPolicies deletes old rules and adds new rules.
 func (a *Adapter) UpdateFilteredPolicies(sec string, pType string, newPolicies [][]string, fieldIndex int, fieldValues ...string) ([][]string, error) {
+	if g := a.groupingFor(pType); g != nil {
+		return g.UpdateFilteredPolicies(sec, pType, newPolicies, fieldIndex, fieldValues...)
+	}
+	op := Operation{
+		Method: "UpdateFilteredPolicies", Sec: sec, PType: pType, NewRules: a.opRules(pType, newPolicies...),
+		FieldIndex: fieldIndex, FieldValues: fieldValues,
+	}
+	var oldPolicies [][]string
+	err := a.mutate(a.ctx, op, func(ctx context.Context) (err error) {
+		oldPolicies, err = a.updateFilteredPolicies(ctx, sec, pType, newPolicies, fieldIndex, fieldValues...)
+		return err
+	}, nil)
+	return oldPolicies, err
+}
+
+func (a *Adapter) updateFilteredPolicies(ctx context.Context, sec string, pType string, newPolicies [][]string, fieldIndex int, fieldValues ...string) ([][]string, error) {
 	// Validate parameters
-	if fieldIndex < 0 || fieldIndex > maxFieldIndex {
+	if !isValidFieldIndex(fieldIndex) {
 		return nil, fmt.Errorf("invalid field index: %d", fieldIndex)
 	}
 
@@ -552,17 +2033,12 @@ func (a *Adapter) UpdateFilteredPolicies(sec string, pType string, newPolicies [
 
 	// Get old rules
 	var oldRules []Rule
-	query := a.model().Where(Columns.PType, pType)
-
-	idx := fieldIndex
-	for _, fieldValue := range fieldValues {
-		if fieldValue != "" {
-			query = query.Where(fmt.Sprintf("v%d", idx), fieldValue)
-		}
-		idx++
+	query, err := a.policyFieldsQuery(ctx, pType, fieldIndex, fieldValues)
+	if err != nil {
+		return nil, fmt.Errorf("failed to scan old rules: %w", err)
 	}
 
-	if err := query.Scan(&oldRules); err != nil {
+	if err := query.OrderAsc("id").Scan(&oldRules); err != nil {
 		return nil, fmt.Errorf("failed to scan old rules: %w", err)
 	}
 
@@ -571,31 +2047,29 @@ func (a *Adapter) UpdateFilteredPolicies(sec string, pType string, newPolicies [
 	for _, rule := range oldRules {
 		oldPolicies = append(oldPolicies, rule.toSlice())
 	}
+	dispatch := func(d persist.Dispatcher) error {
+		return d.UpdateFilteredPolicies(sec, pType, oldPolicies, newPolicies)
+	}
+	if a.dispatchOnly() {
+		if err := a.dispatch(dispatch); err != nil {
+			return nil, err
+		}
+		return oldPolicies, nil
+	}
 
-	err := a.model().Transaction(a.ctx, func(ctx context.Context, tx gdb.TX) error {
+	err = a.transaction(ctx, func(ctx context.Context, tx gdb.TX) error {
 		// Delete old rules
-		if _, err := query.Ctx(ctx).Delete(); err != nil {
+		if err := a.deleteRules(ctx, query); err != nil {
 			return fmt.Errorf("failed to delete old rules: %w", err)
 		}
 
 		// Insert new rules
-		if len(newPolicies) > 0 {
-			dbRules := make([]Rule, 0, len(newPolicies))
-			for _, policy := range newPolicies {
-				dbRules = append(dbRules, a.buildRule(pType, policy))
-			}
-
-			// Insert rules in batches for better performance
-			for i := 0; i < len(dbRules); i += defaultBatchSize {
-				end := i + defaultBatchSize
-				if end > len(dbRules) {
-					end = len(dbRules)
-				}
-				batch := dbRules[i:end]
-				if _, err := tx.Model(a.tableName).Ctx(ctx).Insert(batch); err != nil {
-					return fmt.Errorf("failed to insert new rules batch: %w", err)
-				}
-			}
+		dbRules := make([]Rule, 0, len(newPolicies))
+		for _, policy := range newPolicies {
+			dbRules = append(dbRules, a.buildRule(pType, policy))
+		}
+		if err := a.insertRules(ctx, dbRules); err != nil {
+			return fmt.Errorf("failed to insert new rules: %w", err)
 		}
 
 		return nil
@@ -604,6 +2078,9 @@ func (a *Adapter) UpdateFilteredPolicies(sec string, pType string, newPolicies [
 	if err != nil {
 		return nil, err
 	}
+	if err := a.dispatch(dispatch); err != nil {
+		return nil, err
+	}
 
 	return oldPolicies, nil
 }
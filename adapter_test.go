@@ -3,6 +3,7 @@ package adapter
 import (
 	"context"
 	"log"
+	"path/filepath"
 	"strings"
 	"testing"
 
@@ -360,6 +361,33 @@ func arrayEqualsWithoutOrder(a [][]string, b [][]string) bool {
 	return true
 }
 
+// newSqliteDB opens a sqlite database in a temporary directory, so the test
+// doesn't need a database server.
+func newSqliteDB(t testing.TB) gdb.DB {
+	t.Helper()
+	db, err := gdb.New(gdb.ConfigNode{
+		Type: "sqlite",
+		Name: filepath.Join(t.TempDir(), "casbin.db"),
+	})
+	if err != nil {
+		t.Fatalf("failed to create database connection: %v", err)
+	}
+	t.Cleanup(func() {
+		_ = db.Close(context.Background())
+	})
+	return db
+}
+
+// newSqliteAdapter creates an adapter backed by a new sqlite database.
+func newSqliteAdapter(t *testing.T, opts ...AdapterOption) *Adapter {
+	t.Helper()
+	a, err := NewAdapter(context.Background(), "", "", newSqliteDB(t), opts...)
+	if err != nil {
+		t.Fatalf("failed to create adapter: %v", err)
+	}
+	return a
+}
+
 func TestAdapters(t *testing.T) {
 	db, err := gdb.New(gdb.ConfigNode{
 		Type:     "mysql",
@@ -382,7 +410,58 @@ func TestAdapters(t *testing.T) {
 		t.Fatalf("failed to create adapter: %v", err)
 	}
 
-	// Run all test cases
+	runAdapterSuite(t, a)
+	t.Run("ConcurrentCreate", func(t *testing.T) {
+		testConcurrentCreate(t, db)
+	})
+	t.Run("ConcurrentSaves", func(t *testing.T) {
+		testConcurrentSaves(t, db)
+	})
+	t.Run("ReturningIDs", func(t *testing.T) {
+		returning, err := NewAdapter(context.Background(), "", "casbin_rule_ids", db, WithBatchSize(2))
+		if err != nil {
+			t.Fatalf("failed to create adapter: %v", err)
+		}
+		if err := returning.truncateTable(context.Background()); err != nil {
+			t.Fatalf("failed to truncate table: %v", err)
+		}
+		testReturningIDs(t, returning)
+	})
+	t.Run("MaxTxRows", func(t *testing.T) {
+		split, err := NewAdapter(context.Background(), "", "casbin_rule_split", db, WithMaxTxRows(2))
+		if err != nil {
+			t.Fatalf("failed to create adapter: %v", err)
+		}
+		if err := split.truncateTable(context.Background()); err != nil {
+			t.Fatalf("failed to truncate table: %v", err)
+		}
+		testMaxTxRows(t, split)
+	})
+	t.Run("JSONStorage", func(t *testing.T) {
+		stored, err := NewAdapter(context.Background(), "", "casbin_rule_json", db, WithJSONStorage())
+		if err != nil {
+			t.Fatalf("failed to create adapter: %v", err)
+		}
+		if err := stored.truncateTable(context.Background()); err != nil {
+			t.Fatalf("failed to truncate table: %v", err)
+		}
+		runAdapterSuite(t, stored)
+	})
+	// MySQL commits a truncation at once, a canceled save must not leave
+	// the table empty.
+	t.Run("SavePolicyCanceled", func(t *testing.T) {
+		testSavePolicyCanceled(t, db, "casbin_rule_cancel")
+	})
+}
+
+// TestSqliteAdapters runs the test cases of TestAdapters against sqlite, so
+// that they run without a MySQL server.
+func TestSqliteAdapters(t *testing.T) {
+	runAdapterSuite(t, newSqliteAdapter(t))
+}
+
+// runAdapterSuite runs the test cases shared by the databases against a.
+func runAdapterSuite(t *testing.T, a *Adapter) {
 	t.Run("SaveLoad", func(t *testing.T) {
 		testSaveLoad(t, a)
 	})
@@ -0,0 +1,48 @@
+// Package adaptertest provides a casbin adapter backed by a throwaway
+// sqlite database, for the tests of the code using the adapter without a
+// database server.
+package adaptertest
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"path/filepath"
+
+	"github.com/gogf/gf/v2/database/gdb"
+
+	adapter "github.com/zcyc/gf-adapter/v2"
+
+	_ "github.com/gogf/gf/contrib/drivers/sqlite/v2"
+)
+
+// NewTestAdapter returns an adapter storing its rules in a new sqlite
+// database in a temporary directory, created with opts. The database is a
+// file rather than in memory, so that the connections of the pool share
+// it. Call the returned function to close the database and remove the
+// directory.
+func NewTestAdapter(ctx context.Context, opts ...adapter.AdapterOption) (*adapter.Adapter, func(), error) {
+	dir, err := os.MkdirTemp("", "casbin-adapter-")
+	if err != nil {
+		return nil, nil, fmt.Errorf("failed to create database directory: %w", err)
+	}
+	db, err := gdb.New(gdb.ConfigNode{
+		Type: "sqlite",
+		Name: filepath.Join(dir, "casbin.db"),
+	})
+	if err != nil {
+		_ = os.RemoveAll(dir)
+		return nil, nil, fmt.Errorf("failed to create database connection: %w", err)
+	}
+	cleanup := func() {
+		_ = db.Close(context.Background())
+		_ = os.RemoveAll(dir)
+	}
+
+	a, err := adapter.NewAdapter(ctx, "", "", db, opts...)
+	if err != nil {
+		cleanup()
+		return nil, nil, err
+	}
+	return a, cleanup, nil
+}
@@ -0,0 +1,73 @@
+package adaptertest
+
+import (
+	"context"
+	"testing"
+
+	"github.com/casbin/casbin/v2"
+	"github.com/casbin/casbin/v2/model"
+)
+
+const rbacModel = `
+[request_definition]
+r = sub, obj, act
+
+[policy_definition]
+p = sub, obj, act
+
+[role_definition]
+g = _, _
+
+[policy_effect]
+e = some(where (p.eft == allow))
+
+[matchers]
+m = g(r.sub, p.sub) && r.obj == p.obj && r.act == p.act
+`
+
+func TestNewTestAdapter(t *testing.T) {
+	a, cleanup, err := NewTestAdapter(context.Background())
+	if err != nil {
+		t.Fatalf("NewTestAdapter failed: %v", err)
+	}
+	defer cleanup()
+
+	m, _ := model.NewModelFromString(rbacModel)
+	e, err := casbin.NewEnforcer(m, a)
+	if err != nil {
+		t.Fatalf("failed to create enforcer: %v", err)
+	}
+	if _, err := e.AddPolicy("admin", "data1", "read"); err != nil {
+		t.Fatalf("AddPolicy failed: %v", err)
+	}
+	if _, err := e.AddGroupingPolicy("alice", "admin"); err != nil {
+		t.Fatalf("AddGroupingPolicy failed: %v", err)
+	}
+
+	// A second enforcer reads the stored rules.
+	m, _ = model.NewModelFromString(rbacModel)
+	e, _ = casbin.NewEnforcer(m, a)
+	if ok, _ := e.Enforce("alice", "data1", "read"); !ok {
+		t.Error("alice denied, supposed to read data1 as admin")
+	}
+}
+
+func TestNewTestAdapterIsolated(t *testing.T) {
+	a1, cleanup1, err := NewTestAdapter(context.Background())
+	if err != nil {
+		t.Fatalf("NewTestAdapter failed: %v", err)
+	}
+	defer cleanup1()
+	a2, cleanup2, err := NewTestAdapter(context.Background())
+	if err != nil {
+		t.Fatalf("NewTestAdapter failed: %v", err)
+	}
+	defer cleanup2()
+
+	if err := a1.AddPolicy("p", "p", []string{"alice", "data1", "read"}); err != nil {
+		t.Fatalf("AddPolicy failed: %v", err)
+	}
+	if count, err := a2.CountPolicies(context.Background(), nil); err != nil || count != 0 {
+		t.Errorf("count %d, err: %v, supposed to be 0 in another test database", count, err)
+	}
+}
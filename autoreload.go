@@ -0,0 +1,113 @@
+package adapter
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sync"
+	"time"
+
+	"github.com/gogf/gf/v2/os/gtimer"
+)
+
+// AutoReload calls a reload function periodically, see StartAutoReload.
+type AutoReload struct {
+	adapter *Adapter
+	reload  func() error
+	entry   *gtimer.Entry
+
+	// mu is held while a reload runs.
+	mu     sync.Mutex
+	closed bool
+	// version is the version of the last reload, -1 when it is unknown.
+	version int64
+
+	stop      chan struct{}
+	closeOnce sync.Once
+}
+
+// StartAutoReload calls reload, e.g. the LoadPolicy method of an enforcer,
+// every interval on a gtimer until ctx is canceled or the returned
+// AutoReload is closed. A tick is skipped while the previous reload is
+// still running. Reload errors are logged with the logger of the database
+// and the next tick tries again. With WithVersionTable the reload is
+// skipped as long as the version is the one of the last reload or of the
+// start, so the policy is expected to be loaded already.
+func (a *Adapter) StartAutoReload(ctx context.Context, interval time.Duration, reload func() error) (*AutoReload, error) {
+	if reload == nil {
+		return nil, errors.New("reload function cannot be nil")
+	}
+	if interval <= 0 {
+		return nil, fmt.Errorf("invalid reload interval: %s", interval)
+	}
+
+	r := &AutoReload{
+		adapter: a,
+		reload:  reload,
+		version: -1,
+		stop:    make(chan struct{}),
+	}
+	if a.versionTable != "" {
+		version, err := readVersion(ctx, a.db, a.versionTable)
+		if err != nil {
+			return nil, err
+		}
+		r.version = version
+	}
+
+	r.entry = gtimer.AddSingleton(ctx, interval, r.tick)
+	go func() {
+		select {
+		case <-ctx.Done():
+			r.Close()
+		case <-r.stop:
+		}
+	}()
+	return r, nil
+}
+
+// tick runs a reload unless the version is unchanged.
+func (r *AutoReload) tick(ctx context.Context) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if r.closed {
+		return
+	}
+
+	a := r.adapter
+	version := int64(-1)
+	if a.versionTable != "" {
+		// A version that can't be read doesn't prevent the reload.
+		var err error
+		if version, err = readVersion(ctx, a.db, a.versionTable); err != nil {
+			r.logError(ctx, err)
+			version = -1
+		} else if version == r.version {
+			return
+		}
+	}
+
+	if err := r.reload(); err != nil {
+		r.logError(ctx, fmt.Errorf("failed to reload policy: %w", err))
+		return
+	}
+	r.version = version
+}
+
+// logError logs err with the logger of the database.
+func (r *AutoReload) logError(ctx context.Context, err error) {
+	if logger := r.adapter.db.GetLogger(); logger != nil {
+		logger.Errorf(ctx, "casbin adapter: auto reload: %v", err)
+	}
+}
+
+// Close stops the reloads and waits for the running one to return.
+func (r *AutoReload) Close() {
+	r.closeOnce.Do(func() {
+		r.entry.Close()
+		close(r.stop)
+	})
+	r.mu.Lock()
+	r.closed = true
+	r.mu.Unlock()
+}
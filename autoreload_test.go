@@ -0,0 +1,104 @@
+package adapter
+
+import (
+	"context"
+	"errors"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+// waitCount waits until count reaches at least n.
+func waitCount(t *testing.T, count *atomic.Int32, n int32) {
+	t.Helper()
+	deadline := time.Now().Add(5 * time.Second)
+	for count.Load() < n {
+		if time.Now().After(deadline) {
+			t.Fatalf("%d reloads, supposed to be at least %d", count.Load(), n)
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+}
+
+func TestAutoReload(t *testing.T) {
+	a := newSqliteAdapter(t)
+
+	// Failing reloads are retried on the next tick, and a slow reload makes
+	// the ticks meanwhile skip.
+	var reloads, running, overlaps atomic.Int32
+	r, err := a.StartAutoReload(context.Background(), 100*time.Millisecond, func() error {
+		if running.Add(1) > 1 {
+			overlaps.Add(1)
+		}
+		defer running.Add(-1)
+		reloads.Add(1)
+		time.Sleep(150 * time.Millisecond)
+		return errors.New("database unavailable")
+	})
+	if err != nil {
+		t.Fatalf("StartAutoReload failed: %v", err)
+	}
+	waitCount(t, &reloads, 3)
+	r.Close()
+	if n := overlaps.Load(); n != 0 {
+		t.Errorf("%d overlapping reloads, supposed to be 0", n)
+	}
+
+	stopped := reloads.Load()
+	time.Sleep(300 * time.Millisecond)
+	if n := reloads.Load(); n != stopped {
+		t.Errorf("%d reloads after Close, supposed to be %d", n, stopped)
+	}
+	r.Close()
+}
+
+func TestAutoReloadCanceled(t *testing.T) {
+	a := newSqliteAdapter(t)
+	ctx, cancel := context.WithCancel(context.Background())
+	var reloads atomic.Int32
+	_, err := a.StartAutoReload(ctx, 100*time.Millisecond, func() error {
+		reloads.Add(1)
+		return nil
+	})
+	if err != nil {
+		t.Fatalf("StartAutoReload failed: %v", err)
+	}
+	waitCount(t, &reloads, 1)
+	cancel()
+
+	// A reload may have been running while ctx was canceled.
+	time.Sleep(150 * time.Millisecond)
+	stopped := reloads.Load()
+	time.Sleep(300 * time.Millisecond)
+	if n := reloads.Load(); n != stopped {
+		t.Errorf("%d reloads after the cancellation, supposed to be %d", n, stopped)
+	}
+}
+
+func TestAutoReloadVersion(t *testing.T) {
+	a := newSqliteAdapter(t, WithVersionTable())
+	var reloads atomic.Int32
+	r, err := a.StartAutoReload(context.Background(), 100*time.Millisecond, func() error {
+		reloads.Add(1)
+		return nil
+	})
+	if err != nil {
+		t.Fatalf("StartAutoReload failed: %v", err)
+	}
+	defer r.Close()
+
+	// Nothing changed since the start.
+	time.Sleep(350 * time.Millisecond)
+	if n := reloads.Load(); n != 0 {
+		t.Errorf("%d reloads of an unchanged version, supposed to be 0", n)
+	}
+
+	if err := a.AddPolicy("p", "p", []string{"alice", "data1", "read"}); err != nil {
+		t.Fatalf("AddPolicy failed: %v", err)
+	}
+	waitCount(t, &reloads, 1)
+	time.Sleep(350 * time.Millisecond)
+	if n := reloads.Load(); n != 1 {
+		t.Errorf("%d reloads after one change, supposed to be 1", n)
+	}
+}
@@ -0,0 +1,140 @@
+package adapter
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"slices"
+	"strings"
+
+	"github.com/gogf/gf/v2/database/gdb"
+)
+
+// backupTableInfix separates the policy table name from the suffix of its
+// backup tables.
+const backupTableInfix = "_backup_"
+
+// errTenantBackup is returned by Backup and Restore on the adapters of a
+// tenant column or a scope, whose backups would hold the rules of the other
+// tenants.
+var errTenantBackup = errors.New("backups aren't supported with a tenant column or a scope")
+
+// Backup copies the stored rules into a new table with the layout of the
+// policy table, named after the policy table and suffix, like
+// casbin_rule_backup_<suffix>, and returns its name. suffix must be a valid
+// identifier, and the backup table must not exist yet. It isn't supported
+// with WithTenantColumn or WithScope.
+func (a *Adapter) Backup(ctx context.Context, suffix string) (backupTable string, err error) {
+	if a.routed() {
+		return "", errors.New("backups aren't supported with routed tables")
+	}
+	if err := a.checkDryRun(); err != nil {
+		return "", fmt.Errorf("failed to back up policy: %w", err)
+	}
+	if a.tenantColumn != "" {
+		return "", errTenantBackup
+	}
+	if !isValidIdentifier(suffix) {
+		return "", fmt.Errorf("invalid backup suffix: %q", suffix)
+	}
+	backupTable = a.tableName + backupTableInfix + suffix
+	tables, err := a.db.Tables(ctx)
+	if err != nil {
+		return "", fmt.Errorf("failed to list tables: %w", err)
+	}
+	if slices.Contains(tables, backupTable) {
+		return "", fmt.Errorf("backup table %s already exists", backupTable)
+	}
+
+	for _, sql := range a.dialect.createTableLikeSql(backupTable, a.tableName, a.schema()) {
+		if _, err := a.db.Exec(ctx, sql); err != nil {
+			return "", fmt.Errorf("failed to create table %s: %w", backupTable, err)
+		}
+	}
+	if err := a.clearTableFields(ctx, backupTable); err != nil {
+		return "", err
+	}
+	if err := a.copyRows(ctx, a.tableName, backupTable); err != nil {
+		return "", a.abandonTable(ctx, backupTable, err)
+	}
+	return backupTable, nil
+}
+
+// ListBackups returns the names of the backup tables of the policy table
+// made by Backup, sorted.
+func (a *Adapter) ListBackups(ctx context.Context) ([]string, error) {
+	tables, err := a.db.Tables(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list tables: %w", err)
+	}
+	var backups []string
+	for _, table := range tables {
+		if strings.HasPrefix(table, a.tableName+backupTableInfix) {
+			backups = append(backups, table)
+		}
+	}
+	slices.Sort(backups)
+	return backups, nil
+}
+
+// Restore replaces the stored rules by the ones of backupTable, one of the
+// tables listed by ListBackups, in one transaction. It requires the
+// adapter to be created with WithAllowDestructive, and isn't supported with
+// WithTenantColumn or WithScope.
+func (a *Adapter) Restore(ctx context.Context, backupTable string) error {
+	if !a.allowDestructive {
+		return fmt.Errorf("failed to restore policy: %w", ErrDestructiveNotAllowed)
+	}
+	if a.tenantColumn != "" {
+		return fmt.Errorf("failed to restore policy: %w", errTenantBackup)
+	}
+	if err := a.checkDryRun(); err != nil {
+		return fmt.Errorf("failed to restore policy: %w", err)
+	}
+	backups, err := a.ListBackups(ctx)
+	if err != nil {
+		return err
+	}
+	if !slices.Contains(backups, backupTable) {
+		return fmt.Errorf("unknown backup table: %q", backupTable)
+	}
+	return a.mutate(ctx, Operation{Method: "Restore"}, func(ctx context.Context) error {
+		return a.restore(ctx, backupTable)
+	}, nil)
+}
+
+func (a *Adapter) restore(ctx context.Context, backupTable string) error {
+	if err := a.confirmTables(ctx, "Restore", []string{a.tableName}); err != nil {
+		return fmt.Errorf("failed to restore policy: %w", err)
+	}
+	ctx = withWatcherMessage(ctx, &WatcherMessage{Method: WatcherSavePolicy}, nil)
+	err := a.transaction(ctx, func(ctx context.Context, tx gdb.TX) error {
+		// The tombstones can't be written in bulk, the table is emptied.
+		clearSql := fmt.Sprintf("DELETE FROM %s", a.tableName)
+		if a.tombstones {
+			clearSql = a.dialect.truncateTableSql(a.tableName)
+		}
+		if _, err := a.db.Exec(ctx, clearSql); err != nil {
+			return fmt.Errorf("failed to delete rules: %w", err)
+		}
+		if err := a.copyRows(ctx, backupTable, a.tableName); err != nil {
+			return err
+		}
+		if a.historyTable == "" {
+			return nil
+		}
+
+		var restored []Rule
+		if err := a.db.Model(backupTable).Ctx(ctx).OrderAsc("id").Scan(&restored); err != nil {
+			return fmt.Errorf("failed to read rules: %w", err)
+		}
+		if err := a.recordHistory(ctx, historyOpReset, nil); err != nil {
+			return err
+		}
+		return a.recordHistory(ctx, historyOpAdd, restored)
+	})
+	if err != nil {
+		return fmt.Errorf("failed to restore policy: %w", err)
+	}
+	return nil
+}
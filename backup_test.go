@@ -0,0 +1,103 @@
+package adapter
+
+import (
+	"context"
+	"errors"
+	"reflect"
+	"testing"
+
+	"github.com/casbin/casbin/v2"
+)
+
+func TestBackupRestore(t *testing.T) {
+	ctx := context.Background()
+	a := newSqliteAdapter(t, WithAllowDestructive())
+	initPolicy(t, a)
+	e, err := casbin.NewEnforcer("examples/rbac_model.conf", a)
+	if err != nil {
+		t.Fatalf("NewEnforcer failed: %v", err)
+	}
+	original, _ := e.GetPolicy()
+
+	backup, err := a.Backup(ctx, "before_change")
+	if err != nil {
+		t.Fatalf("Backup failed: %v", err)
+	}
+	if backup != "casbin_rule_backup_before_change" {
+		t.Errorf("backup table %q", backup)
+	}
+	if _, err := a.Backup(ctx, "before_change"); err == nil {
+		t.Error("expected an existing backup table to fail the backup")
+	}
+	if _, err := a.Backup(ctx, "x; DROP TABLE casbin_rule"); err == nil {
+		t.Error("expected an invalid suffix to fail the backup")
+	}
+	backups, err := a.ListBackups(ctx)
+	if err != nil {
+		t.Fatalf("ListBackups failed: %v", err)
+	}
+	if !reflect.DeepEqual(backups, []string{backup}) {
+		t.Errorf("backups %v, supposed to be [%s]", backups, backup)
+	}
+
+	// Corrupt the live table.
+	if _, err := a.db.Exec(ctx, "UPDATE casbin_rule SET v1 = 'corrupt' WHERE v0 = 'alice'"); err != nil {
+		t.Fatalf("failed to corrupt table: %v", err)
+	}
+	if err := a.RemovePolicy("p", "p", []string{"bob", "data2", "write"}); err != nil {
+		t.Fatalf("RemovePolicy failed: %v", err)
+	}
+	if err := a.AddPolicy("p", "p", []string{"mallory", "data1", "write"}); err != nil {
+		t.Fatalf("AddPolicy failed: %v", err)
+	}
+
+	if err := a.Restore(ctx, "casbin_rule"); err == nil {
+		t.Error("expected the policy table to fail as a backup")
+	}
+	if err := a.Restore(ctx, backup); err != nil {
+		t.Fatalf("Restore failed: %v", err)
+	}
+	if err := e.LoadPolicy(); err != nil {
+		t.Fatalf("LoadPolicy failed: %v", err)
+	}
+	testGetPolicy(t, e, original)
+	if ids := storedIDs(t, a); !reflect.DeepEqual(ids, []int64{1, 2, 3, 4, 5}) {
+		t.Errorf("stored ids %v, supposed to be the ones of the backup", ids)
+	}
+}
+
+func TestRestoreRequiresDestructive(t *testing.T) {
+	ctx := context.Background()
+	a := newSqliteAdapter(t)
+	initPolicy(t, a)
+	backup, err := a.Backup(ctx, "nightly")
+	if err != nil {
+		t.Fatalf("Backup failed: %v", err)
+	}
+	if err := a.Restore(ctx, backup); !errors.Is(err, ErrDestructiveNotAllowed) {
+		t.Errorf("Restore err: %v, supposed to be ErrDestructiveNotAllowed", err)
+	}
+}
+
+func TestBackupOfTenant(t *testing.T) {
+	ctx := context.Background()
+	for name, opts := range map[string][]AdapterOption{
+		"tenant": {WithTenantColumn("tenant_id"), WithAllowDestructive()},
+		"scope":  {WithScope("api"), WithAllowDestructive()},
+	} {
+		a := newSqliteAdapter(t, opts...)
+		if a.tenantColumn == "tenant_id" {
+			a = a.ForTenant("t1")
+		}
+		initPolicy(t, a)
+		if _, err := a.Backup(ctx, "nightly"); !errors.Is(err, errTenantBackup) {
+			t.Errorf("%s: Backup err: %v, supposed to refuse the tenant", name, err)
+		}
+		if err := a.Restore(ctx, a.tableName+backupTableInfix+"nightly"); !errors.Is(err, errTenantBackup) {
+			t.Errorf("%s: Restore err: %v, supposed to refuse the tenant", name, err)
+		}
+		if count, err := a.CountPolicies(ctx, nil); err != nil || count != 5 {
+			t.Errorf("%s: CountPolicies = %d, err: %v, supposed to keep the 5 rules", name, count, err)
+		}
+	}
+}
@@ -0,0 +1,79 @@
+package adapter
+
+import (
+	"context"
+	"fmt"
+	"testing"
+
+	"github.com/casbin/casbin/v2"
+)
+
+// paramsDialect binds at most 999 parameters per statement, like the
+// sqlite builds before 3.32.
+type paramsDialect struct {
+	dialect
+}
+
+func (paramsDialect) maxParams() int {
+	return 999
+}
+
+func TestBatchSizes(t *testing.T) {
+	a := newSqliteAdapter(t)
+	if size, group := a.insertSize(), a.groupSize(); size != defaultBatchSize || group != sqliteMaxGroupedConditions {
+		t.Errorf("insert size %d and group size %d, supposed to be the batch size and the sqlite depth limit", size, group)
+	}
+	a.dialect = paramsDialect{a.dialect}
+	if size, group, ids := a.insertSize(), a.groupSize(), a.idsSize(); size != 141 || group != 141 || ids != 991 {
+		t.Errorf("insert size %d, group size %d and ids size %d, supposed to be 141, 141 and 991", size, group, ids)
+	}
+
+	tenant := newSqliteAdapter(t, WithTenantColumn("tenant_id"), WithBatchSize(100))
+	tenant.dialect = paramsDialect{tenant.dialect}
+	if size := tenant.insertSize(); size != 100 {
+		t.Errorf("insert size %d, supposed to be the smaller batch size", size)
+	}
+	tenant.batchSize = defaultBatchSize
+	// A row binds the policy type, the values and the tenant.
+	if size := tenant.insertSize(); size != 123 {
+		t.Errorf("insert size %d, supposed to be 123", size)
+	}
+	json := *a
+	json.jsonStorage = true
+	if size := json.insertSize(); size != 495 {
+		t.Errorf("JSON storage insert size %d, supposed to be 495", size)
+	}
+}
+
+func TestBatchSizesManyRules(t *testing.T) {
+	ctx := context.Background()
+	for name, limited := range map[string]bool{"sqlite": false, "999 parameters": true} {
+		a := newSqliteAdapter(t)
+		if limited {
+			a.dialect = paramsDialect{a.dialect}
+		}
+		rules := make([][]string, 5000)
+		for i := range rules {
+			rules[i] = []string{fmt.Sprintf("user%d", i), "data1", "read"}
+		}
+		if err := a.AddPolicies("p", "p", rules); err != nil {
+			t.Fatalf("%s: AddPolicies failed: %v", name, err)
+		}
+		e, err := casbin.NewEnforcer("examples/rbac_model.conf", a)
+		if err != nil {
+			t.Fatalf("%s: NewEnforcer failed: %v", name, err)
+		}
+		if err := e.SavePolicy(); err != nil {
+			t.Fatalf("%s: SavePolicy failed: %v", name, err)
+		}
+		if _, err := a.UpdateFilteredPolicies("p", "p", rules[:4000], 1, "data1"); err != nil {
+			t.Fatalf("%s: UpdateFilteredPolicies failed: %v", name, err)
+		}
+		if err := a.RemovePolicies("p", "p", rules[:2500]); err != nil {
+			t.Fatalf("%s: RemovePolicies failed: %v", name, err)
+		}
+		if count, err := a.CountPolicies(ctx, nil); err != nil || count != 1500 {
+			t.Errorf("%s: %d rules stored, err: %v, supposed to be 1500", name, count, err)
+		}
+	}
+}
@@ -0,0 +1,118 @@
+package adapter
+
+import (
+	"fmt"
+	"hash/fnv"
+	"time"
+
+	"github.com/casbin/casbin/v2/model"
+	"github.com/gogf/gf/v2/os/gcache"
+)
+
+// cachedPolicy holds the values of the loaded rules of each policy type in
+// load order. The value slices are shared with the models loaded from the
+// cache and must not be modified.
+type cachedPolicy map[string][][]string
+
+// add records the values of a loaded rule.
+func (p cachedPolicy) add(pType string, values []string) {
+	if p != nil && len(values) > 0 {
+		p[pType] = append(p[pType], values)
+	}
+}
+
+// WithCache keeps the rules read by LoadPolicy and LoadFilteredPolicy in an
+// in-memory gcache for ttl, so that loading them again, e.g. by short-lived
+// enforcers, doesn't query the database. Filtered loads are cached per
+// filter. Every change written through the adapter, its tenant and table
+// copies included, empties the cache. Changes written by other processes
+// are only seen once the rules expire, unless InvalidateCache is called,
+// e.g. from the callback of a watcher. Adapters bound to a transaction
+// bypass the cache, the changes of WithTx empty it before the caller
+// commits, call InvalidateCache once committed.
+func WithCache(ttl time.Duration) AdapterOption {
+	return AdapterOption{apply: func(a *Adapter) {
+		a.cache = gcache.New()
+		a.cacheTTL = ttl
+	}}
+}
+
+// InvalidateCache empties the rule cache of WithCache, the next loads read
+// the rules from the database. It does nothing without a cache.
+func (a *Adapter) InvalidateCache() error {
+	if a.cache == nil {
+		return nil
+	}
+	if err := a.cache.Clear(a.ctx); err != nil {
+		return fmt.Errorf("failed to invalidate cache: %w", err)
+	}
+	return nil
+}
+
+// invalidateCache empties the cache before and after a change, errors of
+// the in-memory cache are not expected.
+func (a *Adapter) invalidateCache() {
+	_ = a.InvalidateCache()
+}
+
+// cacheKey returns the cache key of the rules matching any of filters, all
+// the rules when there are no filters.
+func (a *Adapter) cacheKey(filters []Filter) string {
+	key := a.tableName + "/" + a.tenant
+	if len(filters) == 0 {
+		return key
+	}
+	h := fnv.New64a()
+	for _, filter := range filters {
+		_, _ = fmt.Fprintf(h, "%#v", filter)
+	}
+	return fmt.Sprintf("%s/%x", key, h.Sum64())
+}
+
+// usesCache reports whether loads go through the cache.
+func (a *Adapter) usesCache() bool {
+	return a.cache != nil && a.tx == nil
+}
+
+// loadCached loads the cached rules of key into model and reports whether
+// they were cached.
+func (a *Adapter) loadCached(key string, model model.Model) (bool, error) {
+	if !a.usesCache() {
+		return false, nil
+	}
+
+	value, err := a.cache.Get(a.ctx, key)
+	if err != nil {
+		return false, fmt.Errorf("failed to read cache: %w", err)
+	}
+	policy, ok := value.Val().(cachedPolicy)
+	if !ok {
+		return false, nil
+	}
+	for pType, rules := range policy {
+		for _, values := range rules {
+			loadPolicyValues(pType, values, model)
+		}
+	}
+	return true, nil
+}
+
+// newCachedPolicy returns the policy recording the rules of a load, nil
+// when the cache isn't used.
+func (a *Adapter) newCachedPolicy() cachedPolicy {
+	if !a.usesCache() {
+		return nil
+	}
+	return make(cachedPolicy)
+}
+
+// storeCache caches the rules of a load under key.
+func (a *Adapter) storeCache(key string, policy cachedPolicy) error {
+	if policy == nil {
+		return nil
+	}
+	if err := a.cache.Set(a.ctx, key, policy, a.cacheTTL); err != nil {
+		return fmt.Errorf("failed to write cache: %w", err)
+	}
+	return nil
+}
@@ -0,0 +1,131 @@
+package adapter
+
+import (
+	"context"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/casbin/casbin/v2"
+	"github.com/gogf/gf/v2/os/glog"
+)
+
+// countStatements counts the statements db runs from now on.
+func countStatements(t *testing.T, a *Adapter) *atomic.Int32 {
+	t.Helper()
+	var statements atomic.Int32
+	logger := glog.New()
+	logger.SetHandlers(func(ctx context.Context, in *glog.HandlerInput) {
+		statements.Add(1)
+	})
+	a.db.SetLogger(logger)
+	a.db.SetDebug(true)
+	return &statements
+}
+
+func TestCache(t *testing.T) {
+	a := newSqliteAdapter(t, WithCache(time.Minute))
+	initPolicy(t, a)
+	e, err := casbin.NewEnforcer("examples/rbac_model.conf", a)
+	if err != nil {
+		t.Fatalf("NewEnforcer failed: %v", err)
+	}
+
+	// The second load is served from the cache.
+	statements := countStatements(t, a)
+	if err := e.LoadPolicy(); err != nil {
+		t.Fatalf("LoadPolicy failed: %v", err)
+	}
+	if n := statements.Load(); n != 0 {
+		t.Errorf("LoadPolicy ran %d statements, supposed to be served from the cache", n)
+	}
+	testGetPolicy(t, e, [][]string{{"alice", "data1", "read"}, {"bob", "data2", "write"}, {"data2_admin", "data2", "read"}, {"data2_admin", "data2", "write"}})
+
+	// Filtered loads are cached per filter.
+	filter := Filter{PType: []string{"p"}, V0: []string{"bob"}}
+	for i := 0; i < 2; i++ {
+		if err := e.LoadFilteredPolicy(filter); err != nil {
+			t.Fatalf("LoadFilteredPolicy failed: %v", err)
+		}
+		testGetPolicy(t, e, [][]string{{"bob", "data2", "write"}})
+	}
+	if n := statements.Load(); n != 1 {
+		t.Errorf("filtered loads ran %d statements, supposed to be 1", n)
+	}
+
+	// A change through the adapter invalidates the cache.
+	if err := a.AddPolicy("p", "p", []string{"carol", "data3", "read"}); err != nil {
+		t.Fatalf("AddPolicy failed: %v", err)
+	}
+	if err := e.LoadPolicy(); err != nil {
+		t.Fatalf("LoadPolicy failed: %v", err)
+	}
+	testGetPolicy(t, e, [][]string{{"alice", "data1", "read"}, {"bob", "data2", "write"}, {"data2_admin", "data2", "read"}, {"data2_admin", "data2", "write"}, {"carol", "data3", "read"}})
+
+	// Changes made behind the adapter's back need an explicit invalidation.
+	if _, err := a.db.Model(a.tableName).Where("v0", "carol").Delete(); err != nil {
+		t.Fatalf("failed to delete rule: %v", err)
+	}
+	if err := e.LoadPolicy(); err != nil {
+		t.Fatalf("LoadPolicy failed: %v", err)
+	}
+	if ok, _ := e.HasPolicy("carol", "data3", "read"); !ok {
+		t.Error("the cached rules are supposed to include carol's rule")
+	}
+	if err := a.InvalidateCache(); err != nil {
+		t.Fatalf("InvalidateCache failed: %v", err)
+	}
+	if err := e.LoadPolicy(); err != nil {
+		t.Fatalf("LoadPolicy failed: %v", err)
+	}
+	if ok, _ := e.HasPolicy("carol", "data3", "read"); ok {
+		t.Error("carol's rule was deleted")
+	}
+}
+
+func TestCacheExpires(t *testing.T) {
+	a := newSqliteAdapter(t, WithCache(50*time.Millisecond))
+	initPolicy(t, a)
+	e, err := casbin.NewEnforcer("examples/rbac_model.conf", a)
+	if err != nil {
+		t.Fatalf("NewEnforcer failed: %v", err)
+	}
+
+	if _, err := a.db.Model(a.tableName).Where("v0", "bob").Delete(); err != nil {
+		t.Fatalf("failed to delete rule: %v", err)
+	}
+	time.Sleep(100 * time.Millisecond)
+	if err := e.LoadPolicy(); err != nil {
+		t.Fatalf("LoadPolicy failed: %v", err)
+	}
+	if ok, _ := e.HasPolicy("bob", "data2", "write"); ok {
+		t.Error("the expired rules are supposed to be read again")
+	}
+}
+
+func TestCacheTransaction(t *testing.T) {
+	a := newSqliteAdapter(t, WithCache(time.Minute))
+	initPolicy(t, a)
+	e, err := casbin.NewEnforcer("examples/rbac_model.conf", a)
+	if err != nil {
+		t.Fatalf("NewEnforcer failed: %v", err)
+	}
+
+	// A load while the transaction is open caches the old rules, the
+	// commit empties the cache again.
+	err = a.Transaction(context.Background(), func(tx *Adapter) error {
+		if err := tx.AddPolicy("p", "p", []string{"carol", "data3", "read"}); err != nil {
+			return err
+		}
+		return e.LoadPolicy()
+	})
+	if err != nil {
+		t.Fatalf("Transaction failed: %v", err)
+	}
+	if err := e.LoadPolicy(); err != nil {
+		t.Fatalf("LoadPolicy failed: %v", err)
+	}
+	if ok, _ := e.HasPolicy("carol", "data3", "read"); !ok {
+		t.Error("the committed rule is supposed to be loaded")
+	}
+}
@@ -0,0 +1,100 @@
+package adapter
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+	"sync/atomic"
+	"testing"
+
+	"github.com/casbin/casbin/v2/model"
+	"github.com/gogf/gf/v2/database/gdb"
+	"github.com/gogf/gf/v2/os/glog"
+)
+
+// cancelAfterInserts cancels ctx once the database of a ran n inserts,
+// returning the number of inserts run.
+func cancelAfterInserts(a *Adapter, n int32, cancel context.CancelFunc) *atomic.Int32 {
+	var inserts atomic.Int32
+	logger := glog.New()
+	logger.SetHandlers(func(ctx context.Context, in *glog.HandlerInput) {
+		if strings.Contains(in.ValuesContent(), "INSERT INTO") && inserts.Add(1) == n {
+			cancel()
+		}
+	})
+	a.db.SetLogger(logger)
+	a.db.SetDebug(true)
+	return &inserts
+}
+
+// manyRules returns n rules of the p section.
+func manyRules(n int) [][]string {
+	rules := make([][]string, n)
+	for i := range rules {
+		rules[i] = []string{fmt.Sprintf("user%d", i), "data1", "read"}
+	}
+	return rules
+}
+
+func TestSavePolicyCanceled(t *testing.T) {
+	testSavePolicyCanceled(t, newSqliteDB(t), "")
+}
+
+// testSavePolicyCanceled checks that a save canceled half way keeps the
+// rules stored in table of db.
+func testSavePolicyCanceled(t *testing.T, db gdb.DB, table string) {
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	a, err := NewAdapter(ctx, "", table, db, WithBatchSize(10))
+	if err != nil {
+		t.Fatalf("NewAdapter failed: %v", err)
+	}
+	initPolicy(t, a)
+
+	m, _ := model.NewModelFromFile("examples/rbac_model.conf")
+	for _, rule := range manyRules(100) {
+		m.AddPolicy("p", "p", rule)
+	}
+	inserts := cancelAfterInserts(a, 3, cancel)
+	if err := a.SavePolicy(m); !errors.Is(err, context.Canceled) {
+		t.Fatalf("SavePolicy err: %v, supposed to be canceled", err)
+	}
+	if n := inserts.Load(); n != 3 {
+		t.Errorf("%d inserts, supposed to stop after the 3rd batch", n)
+	}
+	// The save is rolled back, keeping the stored rules.
+	b, err := NewAdapter(context.Background(), "", table, db)
+	if err != nil {
+		t.Fatalf("NewAdapter failed: %v", err)
+	}
+	rules, err := b.GetAllPolicies(context.Background(), nil)
+	if err != nil {
+		t.Fatalf("GetAllPolicies failed: %v", err)
+	}
+	if len(rules) != 5 || rules[0] != (Rule{PType: "p", V0: "alice", V1: "data1", V2: "read"}) {
+		t.Errorf("stored rules %v, supposed to be the 5 rules saved first", rules)
+	}
+
+	// A canceled load stops between pages.
+	if err := a.LoadPolicy(m); !errors.Is(err, context.Canceled) {
+		t.Errorf("LoadPolicy err: %v, supposed to be canceled", err)
+	}
+}
+
+func TestImportPoliciesCanceled(t *testing.T) {
+	a := newSqliteAdapter(t, WithBatchSize(10))
+	initPolicy(t, a)
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	inserts := cancelAfterInserts(a, 2, cancel)
+	if _, err := a.ImportPolicies(ctx, manyRules(100), "p", ImportOptions{}); !errors.Is(err, context.Canceled) {
+		t.Fatalf("ImportPolicies err: %v, supposed to be canceled", err)
+	}
+	if n := inserts.Load(); n != 2 {
+		t.Errorf("%d inserts, supposed to stop after the 2nd batch", n)
+	}
+	if count, err := a.CountPolicies(context.Background(), nil); err != nil || count != 5 {
+		t.Errorf("CountPolicies = %d, err: %v, supposed to keep the 5 rules", count, err)
+	}
+}
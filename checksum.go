@@ -0,0 +1,115 @@
+package adapter
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"slices"
+
+	"github.com/casbin/casbin/v2/model"
+)
+
+// Checksum returns a hex SHA-256 of the stored rules visible to the
+// adapter, comparable with the ones of other adapters and of
+// ChecksumOfModel. The checksum only depends on the set of distinct rules:
+// the order of the rows and the duplicate rows don't change it, and empty
+// values hash the same whether they are stored as NULL or as empty strings.
+// The rules are hashed like the unique index does, one SHA-256 of the
+// policy type and the values of each rule, and the sorted distinct rule
+// hashes are hashed in turn. With the rule_hash column, which
+// WithUniqueIndex adds over TEXT columns, the rule hashes are read from the
+// database, otherwise the rules are read in pages and hashed. The rules of
+// all the tables of WithSplitTables and WithTableRouting are hashed in one
+// set.
+func (a *Adapter) Checksum(ctx context.Context) (string, error) {
+	seen := make(map[string]struct{})
+	for _, table := range a.splitTables() {
+		if err := table.addRuleHashes(ctx, seen); err != nil {
+			return "", err
+		}
+	}
+	return checksumOfSet(seen), nil
+}
+
+// addRuleHashes adds the rule hashes of the stored rules of the table of
+// the adapter to seen.
+func (a *Adapter) addRuleHashes(ctx context.Context, seen map[string]struct{}) error {
+	if a.ruleHash {
+		hashes, complete, err := a.storedRuleHashes(ctx)
+		if err != nil {
+			return err
+		}
+		if complete {
+			for _, hash := range hashes {
+				seen[hash] = struct{}{}
+			}
+			return nil
+		}
+	}
+
+	err := a.scanPages(a.modelCtx(ctx), func(rows []ruleRow) error {
+		for _, row := range rows {
+			seen[ruleHash(row.Rule)] = struct{}{}
+		}
+		return nil
+	})
+	if err != nil {
+		return fmt.Errorf("failed to compute checksum: %w", err)
+	}
+	return nil
+}
+
+// storedRuleHashes returns the sorted distinct values of the rule_hash
+// column, complete is false when some rows have no hash.
+func (a *Adapter) storedRuleHashes(ctx context.Context) (hashes []string, complete bool, err error) {
+	values, err := a.modelCtx(ctx).Distinct().Fields(ruleHashColumn).OrderAsc(ruleHashColumn).Array()
+	if err != nil {
+		return nil, false, fmt.Errorf("failed to compute checksum: %w", err)
+	}
+	hashes = make([]string, 0, len(values))
+	for _, value := range values {
+		if value.IsNil() {
+			return nil, false, nil
+		}
+		hashes = append(hashes, value.String())
+	}
+	return hashes, true, nil
+}
+
+// ChecksumOfModel returns the checksum of the p and g rules of m the way
+// Checksum does, after normalizing them like the writes of the adapter do.
+func (a *Adapter) ChecksumOfModel(m model.Model) (string, error) {
+	if m == nil {
+		return "", errors.New("model cannot be nil")
+	}
+	if err := checkSections(m); err != nil {
+		return "", fmt.Errorf("failed to compute checksum: %w", err)
+	}
+	seen := make(map[string]struct{})
+	for _, rule := range a.modelRules(m) {
+		seen[ruleHash(rule)] = struct{}{}
+	}
+	return checksumOfSet(seen), nil
+}
+
+// checksumOfSet returns the checksum of the rule hashes of seen.
+func checksumOfSet(seen map[string]struct{}) string {
+	hashes := make([]string, 0, len(seen))
+	for hash := range seen {
+		hashes = append(hashes, hash)
+	}
+	slices.Sort(hashes)
+	return checksumOf(hashes)
+}
+
+// checksumOf hashes the sorted distinct rule hashes, one per line.
+func checksumOf(hashes []string) string {
+	digest := sha256.New()
+	for _, hash := range hashes {
+		digest.Write([]byte(hash))
+		digest.Write([]byte{'\n'})
+	}
+	return hex.EncodeToString(digest.Sum(nil))
+}
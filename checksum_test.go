@@ -0,0 +1,63 @@
+package adapter
+
+import (
+	"context"
+	"testing"
+
+	"github.com/casbin/casbin/v2"
+)
+
+func TestChecksum(t *testing.T) {
+	for name, opts := range map[string][]AdapterOption{
+		"scanned": nil,
+		// The rule hashes are read from the rule_hash column.
+		"rule hash": {WithUniqueIndex(), WithColumnType(1, "TEXT")},
+		// The rules of both tables are hashed.
+		"split tables":           {WithSplitTables("casbin_p", "casbin_g")},
+		"split tables rule hash": {WithSplitTables("casbin_p", "casbin_g"), WithUniqueIndex(), WithColumnType(1, "TEXT")},
+	} {
+		t.Run(name, func(t *testing.T) {
+			testChecksum(t, opts)
+		})
+	}
+}
+
+func testChecksum(t *testing.T, opts []AdapterOption) {
+	ctx := context.Background()
+	a := newSqliteAdapter(t, opts...)
+	initPolicy(t, a)
+	e, err := casbin.NewEnforcer("examples/rbac_model.conf", "examples/rbac_policy.csv")
+	if err != nil {
+		t.Fatalf("NewEnforcer failed: %v", err)
+	}
+	want, err := a.ChecksumOfModel(e.GetModel())
+	if err != nil {
+		t.Fatalf("ChecksumOfModel failed: %v", err)
+	}
+	if sum, err := a.Checksum(ctx); err != nil || sum != want {
+		t.Fatalf("checksum %s, err: %v, supposed to be the one of the model %s", sum, err, want)
+	}
+
+	// The same rules in another order, stored twice and with NULL values.
+	b := newSqliteAdapter(t)
+	rules := [][]string{{"data2_admin", "data2", "write"}, {"bob", "data2", "write"}, {"alice", "data1", "read"}, {"data2_admin", "data2", "read"}}
+	if err := b.AddPolicies("p", "p", rules); err != nil {
+		t.Fatalf("AddPolicies failed: %v", err)
+	}
+	if err := b.AddPolicies("p", "p", rules[:2]); err != nil {
+		t.Fatalf("AddPolicies failed: %v", err)
+	}
+	if _, err := b.db.Exec(ctx, "INSERT INTO casbin_rule (p_type, v0, v1) VALUES ('g', 'alice', 'data2_admin')"); err != nil {
+		t.Fatalf("failed to insert rule: %v", err)
+	}
+	if sum, err := b.Checksum(ctx); err != nil || sum != want {
+		t.Errorf("checksum %s, err: %v, supposed to be %s", sum, err, want)
+	}
+
+	if err := a.RemovePolicy("p", "p", []string{"alice", "data1", "read"}); err != nil {
+		t.Fatalf("RemovePolicy failed: %v", err)
+	}
+	if sum, err := a.Checksum(ctx); err != nil || sum == want {
+		t.Errorf("checksum %s, err: %v, supposed to change with the rules", sum, err)
+	}
+}
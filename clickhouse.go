@@ -0,0 +1,356 @@
+package adapter
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+	"sync/atomic"
+	"time"
+
+	"github.com/gogf/gf/v2/database/gdb"
+	"github.com/gogf/gf/v2/frame/g"
+)
+
+const (
+	// tombstoneColumn and versionColumn are the columns of the
+	// ReplacingMergeTree policy table on ClickHouse: the row of a rule with
+	// the highest version replaces the others, and is dropped when it is a
+	// tombstone.
+	tombstoneColumn = "is_deleted"
+	versionColumn   = "version"
+
+	clickhouseCreateTableSql = `
+CREATE TABLE IF NOT EXISTS %s (
+  id Int64,
+  p_type String DEFAULT '',
+  v0 String DEFAULT '',
+  v1 String DEFAULT '',
+  v2 String DEFAULT '',
+  v3 String DEFAULT '',
+  v4 String DEFAULT '',
+  v5 String DEFAULT '',
+%s  version UInt64,
+  is_deleted UInt8 DEFAULT 0,
+  created_at DateTime DEFAULT now()
+) ENGINE = ReplacingMergeTree(version, is_deleted)
+ORDER BY (%s)%s
+`
+	clickhouseTenantColumnSql  = "  %s String DEFAULT '',\n"
+	clickhouseActorColumnSql   = "%s String DEFAULT ''"
+	clickhouseAddTenantSql     = `ALTER TABLE %s ADD COLUMN %s, MODIFY ORDER BY (%s)`
+	clickhouseTruncateTableSql = `TRUNCATE TABLE %s`
+	clickhouseCreateLikeSql    = `CREATE TABLE %s AS %s`
+	clickhouseSwapTablesSql    = `RENAME TABLE %s TO %s, %s TO %s`
+	clickhouseOptimizeTableSql = `OPTIMIZE TABLE %s FINAL`
+	clickhouseMutationsSql     = "SELECT mutation_id, `table`, latest_fail_reason FROM system.mutations WHERE `database` = ? AND `table` IN (?) AND is_done = 0 ORDER BY create_time"
+
+	// mutationPollInterval is the delay between the polls of
+	// WaitForMutations.
+	mutationPollInterval = 100 * time.Millisecond
+)
+
+// clickhouseColumnSql defines the columns of the create statement, they are
+// used to add missing columns to existing tables.
+var clickhouseColumnSql = map[string]string{
+	"p_type":     "p_type String DEFAULT ''",
+	"v0":         "v0 String DEFAULT ''",
+	"v1":         "v1 String DEFAULT ''",
+	"v2":         "v2 String DEFAULT ''",
+	"v3":         "v3 String DEFAULT ''",
+	"v4":         "v4 String DEFAULT ''",
+	"v5":         "v5 String DEFAULT ''",
+	"created_at": "created_at DateTime DEFAULT now()",
+}
+
+// clickhouseDialect stores the rules in a ReplacingMergeTree table, which
+// needs ClickHouse 23.2 or later. The rules are its sorting key, so the
+// rows of a rule are merged into the latest one in the background, and
+// the removals are written as tombstone rows, see configureTombstones.
+type clickhouseDialect struct{}
+
+// createTableSql skips the column types of schema, the strings of
+// ClickHouse have no length. The comments are written by the COMMENT
+// clauses of the columns and the table, quoted like the ones of MySQL.
+func (clickhouseDialect) createTableSql(table string, schema tableSchema) []string {
+	var columns, comment string
+	if schema.tenantColumn != "" {
+		columns = fmt.Sprintf(clickhouseTenantColumnSql, schema.tenantColumn)
+	}
+	if schema.tableComment != "" {
+		comment = "\nCOMMENT " + mysqlQuote(schema.tableComment)
+	}
+	createSql := fmt.Sprintf(clickhouseCreateTableSql, table, columns, clickhouseSortingKey(schema.tenantColumn), comment)
+	return []string{mysqlColumnComments(createSql, schema)}
+}
+
+// clickhouseSortingKey returns the sorting key of the policy table, which
+// identifies the rules. The tenant column comes last, as a column added
+// later can only be appended to the key.
+func clickhouseSortingKey(tenantColumn string) string {
+	key := stagingColumns
+	if tenantColumn != "" {
+		key = append(key[:len(key):len(key)], tenantColumn)
+	}
+	return strings.Join(key, ", ")
+}
+
+// createHistoryTableSql, createVersionTableSql and the staging table
+// statements are empty, history, polling and stable saves are rejected on
+// ClickHouse by configureTombstones, as are createRuleColumnTableSql and the
+// JSON statements by validatePacked.
+func (clickhouseDialect) createHistoryTableSql(table string, schema tableSchema) []string {
+	return nil
+}
+
+func (clickhouseDialect) createVersionTableSql(table string) string {
+	return ""
+}
+
+func (clickhouseDialect) createRuleColumnTableSql(table, column string) string {
+	return ""
+}
+
+func (clickhouseDialect) createJSONTableSql(table string) []string {
+	return nil
+}
+
+func (clickhouseDialect) jsonValueSql(column string, index int) string {
+	return ""
+}
+
+func (clickhouseDialect) jsonEqualSql(column string) string {
+	return ""
+}
+
+func (clickhouseDialect) createStagingTableSql(table string, schema tableSchema) string {
+	return ""
+}
+
+func (clickhouseDialect) dropStagingTableSql(table string) string {
+	return ""
+}
+
+func (clickhouseDialect) truncateTableSql(table string) string {
+	return fmt.Sprintf(clickhouseTruncateTableSql, table)
+}
+
+func (clickhouseDialect) transactionalTruncate() bool {
+	return false
+}
+
+// nullSafeEqualSql compares the columns plainly, they aren't nullable on
+// ClickHouse.
+func (clickhouseDialect) nullSafeEqualSql(left, right string) string {
+	return fmt.Sprintf("%s = %s", left, right)
+}
+
+func (clickhouseDialect) createTableLikeSql(table, like string, schema tableSchema) []string {
+	return []string{fmt.Sprintf(clickhouseCreateLikeSql, table, like)}
+}
+
+func (clickhouseDialect) swapTablesSql(table, staging, old string) []string {
+	return []string{fmt.Sprintf(clickhouseSwapTablesSql, table, old, staging, table)}
+}
+
+// createUniqueIndexSql is empty, the sorting key already merges the rows
+// of a rule.
+func (clickhouseDialect) createUniqueIndexSql(table string, columns []string) string {
+	return ""
+}
+
+func (clickhouseDialect) maxWriters() int {
+	return 0
+}
+
+func (clickhouseDialect) maxGroupedConditions() int {
+	return 0
+}
+
+// lockSql is empty, ClickHouse has no advisory locks.
+func (clickhouseDialect) lockSql() (lock, unlock string) {
+	return "", ""
+}
+
+// addPartitionSql and truncatePartitionSql are empty, the partitions of
+// ClickHouse are created by the database.
+func (clickhouseDialect) addPartitionSql(table, partition, tenant string) string {
+	return ""
+}
+
+func (clickhouseDialect) truncatePartitionSql(table, partition string) string {
+	return ""
+}
+
+func (clickhouseDialect) optimizeTableSql(table string) string {
+	return fmt.Sprintf(clickhouseOptimizeTableSql, table)
+}
+
+func (clickhouseDialect) readPastHint() string {
+	return ""
+}
+
+func (clickhouseDialect) maxInsertRows() int {
+	return 0
+}
+
+// maxParams is 0, the driver renders the parameters into the statement.
+func (clickhouseDialect) maxParams() int {
+	return 0
+}
+
+// resolvesConflicts is false, the driver has neither INSERT IGNORE nor
+// REPLACE.
+func (clickhouseDialect) resolvesConflicts() bool {
+	return false
+}
+
+func (clickhouseDialect) conflictSql(policy ConflictPolicy, unique, columns []string) string {
+	return ""
+}
+
+// insertedIds is nil, the ids of the rows are set by ruleRecord.
+func (clickhouseDialect) insertedIds(last int64, n int) []int64 {
+	return nil
+}
+
+func (clickhouseDialect) returningSql() (output, returning string) {
+	return "", ""
+}
+
+// addColumnSql adds the tenant column to the sorting key along with the
+// column, so that the rules of different tenants aren't merged.
+func (clickhouseDialect) addColumnSql(table, column string, schema tableSchema) []string {
+	if column == schema.tenantColumn {
+		definition := strings.TrimSuffix(strings.TrimSpace(fmt.Sprintf(clickhouseTenantColumnSql, column)), ",")
+		return []string{fmt.Sprintf(clickhouseAddTenantSql, table, mysqlColumnComments(definition, schema), clickhouseSortingKey(column))}
+	}
+	if column == schema.actorColumn {
+		return []string{fmt.Sprintf(addColumnSql, table, mysqlColumnComments(fmt.Sprintf(clickhouseActorColumnSql, column), schema))}
+	}
+	return []string{fmt.Sprintf(addColumnSql, table, mysqlColumnComments(clickhouseColumnSql[column], schema))}
+}
+
+// configureTombstones enables the tombstone rows when the database merges
+// the rows itself. A removal then inserts a tombstone row of every rule it
+// matches instead of deleting the rows, and the queries of the policy
+// table read it FINAL, merged, without the tombstones. The database has no
+// transactions, the statements of a change apply one by one. History,
+// change tracking, unique indexes, polling, stable saves and codecs aren't
+// supported.
+func (a *Adapter) configureTombstones() error {
+	a.tombstones = a.dialect.optimizeTableSql(a.tableName) != ""
+	if !a.tombstones {
+		return nil
+	}
+	switch {
+	case a.history:
+		return errors.New("history isn't supported on ClickHouse")
+	case a.changeTracking:
+		return errors.New("change tracking isn't supported on ClickHouse")
+	case a.uniqueIndex:
+		return errors.New("unique indexes aren't supported on ClickHouse")
+	case a.versioned:
+		return errors.New("polling isn't supported on ClickHouse")
+	case a.stableSave:
+		return errors.New("stable saves aren't supported on ClickHouse")
+	case a.codec != nil:
+		return errors.New("codecs aren't supported on ClickHouse")
+	}
+	return nil
+}
+
+// rowStamp is the last id handed out by nextRowStamp.
+var rowStamp atomic.Int64
+
+// nextRowStamp returns the id and version of a row written with tombstones,
+// the nanoseconds since the epoch, increasing within the process. The
+// version of a rule written by several processes follows their clocks.
+func nextRowStamp() int64 {
+	for {
+		last, now := rowStamp.Load(), time.Now().UnixNano()
+		if now <= last {
+			now = last + 1
+		}
+		if rowStamp.CompareAndSwap(last, now) {
+			return now
+		}
+	}
+}
+
+// writeTombstones inserts a tombstone row of every rule selected by query.
+func (a *Adapter) writeTombstones(ctx context.Context, query *gdb.Model) error {
+	var removed []Rule
+	if err := query.Ctx(ctx).OrderAsc("id").Scan(&removed); err != nil {
+		return err
+	}
+	size := a.insertSize()
+	for i := 0; i < len(removed); i += size {
+		if err := ctx.Err(); err != nil {
+			return err
+		}
+		end := min(i+size, len(removed))
+		batch := make(g.List, 0, end-i)
+		for _, rule := range removed[i:end] {
+			record := a.ruleRecord(ctx, rule)
+			record[tombstoneColumn] = 1
+			batch = append(batch, record)
+		}
+		if _, err := a.modelCtx(ctx).Insert(batch); err != nil {
+			return fmt.Errorf("failed to insert tombstones: %w", err)
+		}
+	}
+	return nil
+}
+
+// OptimizeNow merges the rows of the policy tables on ClickHouse right
+// away, dropping the replaced rows and the tombstones the background
+// merges would drop later. The loads don't need it, they merge the rows
+// they read.
+func (a *Adapter) OptimizeNow(ctx context.Context) error {
+	if !a.tombstones {
+		return errors.New("optimizing requires ClickHouse")
+	}
+	if err := a.checkDryRun(); err != nil {
+		return fmt.Errorf("failed to optimize table: %w", err)
+	}
+	for _, table := range a.routedTableNames() {
+		if _, err := a.db.Exec(ctx, a.dialect.optimizeTableSql(table)); err != nil {
+			return fmt.Errorf("failed to optimize table %s: %w", table, err)
+		}
+	}
+	return nil
+}
+
+// WaitForMutations waits until the mutations of the policy tables on
+// ClickHouse are done, the ALTER TABLE DELETE and UPDATE statements it
+// applies in the background, polling system.mutations. A mutation that
+// failed is returned as an error, as is the error of ctx when it is done
+// first. The removals of the adapter, ClearPolicy, SavePolicy and the
+// imports replacing the rules included, write tombstones rather than
+// mutations, the loads see them at once: it is meant for the mutations run
+// on the tables directly, e.g. through Model.
+func (a *Adapter) WaitForMutations(ctx context.Context) error {
+	if !a.tombstones {
+		return errors.New("waiting for mutations requires ClickHouse")
+	}
+	for {
+		pending, err := a.db.GetAll(ctx, clickhouseMutationsSql, a.db.GetSchema(), a.routedTableNames())
+		if err != nil {
+			return fmt.Errorf("failed to get mutations: %w", err)
+		}
+		if pending.IsEmpty() {
+			return nil
+		}
+		for _, mutation := range pending {
+			if reason := mutation["latest_fail_reason"].String(); reason != "" {
+				return fmt.Errorf("mutation %s of table %s failed: %s", mutation["mutation_id"], mutation["table"], reason)
+			}
+		}
+		select {
+		case <-ctx.Done():
+			return fmt.Errorf("failed to wait for mutation %s: %w", pending[0]["mutation_id"], ctx.Err())
+		case <-time.After(mutationPollInterval):
+		}
+	}
+}
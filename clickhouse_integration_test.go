@@ -0,0 +1,48 @@
+//go:build clickhouse
+
+package adapter
+
+import (
+	"context"
+	"fmt"
+	"testing"
+)
+
+// TestClickHouseAdapters needs a ClickHouse server, it runs with go test
+// -tags clickhouse.
+func TestClickHouseAdapters(t *testing.T) {
+	a, err := NewAdapter(context.Background(), "", "", newClickHouseDB(t))
+	if err != nil {
+		t.Fatalf("failed to create adapter: %v", err)
+	}
+
+	runAdapterSuite(t, a)
+	t.Run("OptimizeNow", func(t *testing.T) {
+		if err := a.OptimizeNow(context.Background()); err != nil {
+			t.Fatalf("OptimizeNow failed: %v", err)
+		}
+		rules, err := a.CountPolicies(context.Background(), nil)
+		if err != nil {
+			t.Fatalf("CountPolicies failed: %v", err)
+		}
+		count, err := a.db.Model(a.tableName).Count()
+		if err != nil || int64(count) != rules {
+			t.Errorf("%d rows after merging, err: %v, supposed to be %d, one per rule", count, err, rules)
+		}
+	})
+	t.Run("WaitForMutations", func(t *testing.T) {
+		ctx := context.Background()
+		if err := a.AddPolicy("p", "p", []string{"mutated", "data1", "read"}); err != nil {
+			t.Fatalf("AddPolicy failed: %v", err)
+		}
+		if _, err := a.db.Exec(ctx, fmt.Sprintf("ALTER TABLE %s DELETE WHERE v0 = 'mutated'", a.tableName)); err != nil {
+			t.Fatalf("failed to run mutation: %v", err)
+		}
+		if err := a.WaitForMutations(ctx); err != nil {
+			t.Fatalf("WaitForMutations failed: %v", err)
+		}
+		if count, err := a.db.Model(a.tableName).Where("v0", "mutated").Count(); err != nil || count != 0 {
+			t.Errorf("%d rows of the deleted rule, err: %v, supposed to be none", count, err)
+		}
+	})
+}
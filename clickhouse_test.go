@@ -0,0 +1,203 @@
+package adapter
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/casbin/casbin/v2"
+	"github.com/gogf/gf/v2/database/gdb"
+)
+
+// newClickHouseDB returns a connection to the ClickHouse server of
+// TestClickHouseAdapters, it connects at the first statement.
+func newClickHouseDB(t *testing.T) gdb.DB {
+	t.Helper()
+	db, err := gdb.New(gdb.ConfigNode{
+		Type:  "clickhouse",
+		Host:  "127.0.0.1",
+		Port:  "9000",
+		User:  "default",
+		Name:  "casbin",
+		Debug: true,
+	})
+	if err != nil {
+		t.Fatalf("failed to create database connection: %v", err)
+	}
+	return db
+}
+
+func TestClickHouseDialect(t *testing.T) {
+	d := clickhouseDialect{}
+	ddl := d.createTableSql("casbin_rule", tableSchema{tenantColumn: "tenant_id"})[0]
+	for _, want := range []string{
+		"  tenant_id String DEFAULT '',\n  version UInt64,",
+		"ENGINE = ReplacingMergeTree(version, is_deleted)\nORDER BY (p_type, v0, v1, v2, v3, v4, v5, tenant_id)",
+	} {
+		if !strings.Contains(ddl, want) {
+			t.Errorf("create statement lacks %q:\n%s", want, ddl)
+		}
+	}
+
+	want := "ALTER TABLE casbin_rule ADD COLUMN tenant_id String DEFAULT '', MODIFY ORDER BY (p_type, v0, v1, v2, v3, v4, v5, tenant_id)"
+	if sql := d.addColumnSql("casbin_rule", "tenant_id", tableSchema{tenantColumn: "tenant_id"}); len(sql) != 1 || sql[0] != want {
+		t.Errorf("add tenant statements %q, supposed to be %q", sql, want)
+	}
+	if key := clickhouseSortingKey(""); key != "p_type, v0, v1, v2, v3, v4, v5" {
+		t.Errorf("sorting key %q without tenant", key)
+	}
+}
+
+func TestClickHouseOptions(t *testing.T) {
+	ctx := context.Background()
+	db := newClickHouseDB(t)
+	a := newAdapter(ctx, "", "", db, nil)
+	if err := a.configure(); err != nil || !a.tombstones {
+		t.Fatalf("tombstones %v, err: %v, supposed to be enabled on ClickHouse", a.tombstones, err)
+	}
+	for name, opt := range map[string]AdapterOption{
+		"history":         WithHistory(),
+		"change tracking": WithChangeTracking(),
+		"unique index":    WithUniqueIndex(),
+	} {
+		if err := newAdapter(ctx, "", "", db, []AdapterOption{opt}).configure(); err == nil {
+			t.Errorf("%s: expected an error", name)
+		}
+	}
+	if a := newSqliteAdapter(t); a.tombstones {
+		t.Error("tombstones enabled on sqlite")
+	}
+}
+
+// TestTombstones runs the tombstones against sqlite, which reads FINAL as
+// a table alias and doesn't merge the rows, so the removed rules stay
+// loaded.
+func TestTombstones(t *testing.T) {
+	ctx := context.Background()
+	a := newSqliteAdapter(t)
+	for _, sql := range []string{
+		"ALTER TABLE casbin_rule ADD COLUMN version INTEGER",
+		"ALTER TABLE casbin_rule ADD COLUMN is_deleted INTEGER NOT NULL DEFAULT 0",
+	} {
+		if _, err := a.db.Exec(ctx, sql); err != nil {
+			t.Fatalf("failed to add tombstone columns: %v", err)
+		}
+	}
+	if err := a.clearTableFields(ctx, a.tableName); err != nil {
+		t.Fatal(err)
+	}
+	a.tombstones = true
+
+	e, err := casbin.NewEnforcer("examples/rbac_model.conf", a)
+	if err != nil {
+		t.Fatalf("NewEnforcer failed: %v", err)
+	}
+	for _, rule := range [][]string{{"alice", "data1", "read"}, {"bob", "data2", "write"}} {
+		if _, err := e.AddPolicy(rule); err != nil {
+			t.Fatalf("AddPolicy failed: %v", err)
+		}
+	}
+	if _, err := e.RemovePolicy("alice", "data1", "read"); err != nil {
+		t.Fatalf("RemovePolicy failed: %v", err)
+	}
+
+	rows, err := a.db.Model(a.tableName).OrderAsc("id").All()
+	if err != nil || len(rows) != 3 {
+		t.Fatalf("stored %d rows, err: %v, supposed to be 3", len(rows), err)
+	}
+	added, tombstone := rows[0], rows[2]
+	if tombstone["v0"].String() != "alice" || tombstone[tombstoneColumn].Int() != 1 || added[tombstoneColumn].Int() != 0 {
+		t.Errorf("rows %v, supposed to end with the tombstone of alice's rule", rows)
+	}
+	if tombstone["id"].Int64() != tombstone[versionColumn].Int64() || tombstone[versionColumn].Int64() <= added[versionColumn].Int64() {
+		t.Errorf("tombstone version %v isn't after the added version %v", tombstone[versionColumn], added[versionColumn])
+	}
+
+	// The tombstones are never loaded.
+	if err := e.LoadPolicy(); err != nil {
+		t.Fatalf("LoadPolicy failed: %v", err)
+	}
+	testGetPolicy(t, e, [][]string{{"alice", "data1", "read"}, {"bob", "data2", "write"}})
+	if removed, err := a.Deduplicate(ctx); err != nil || removed != 0 {
+		t.Errorf("deduplicated %d rows, err: %v, supposed to leave them to the merges", removed, err)
+	}
+
+	// The bulk removals write tombstones too, never a mutation.
+	var buf bytes.Buffer
+	logStatements(a, &buf)
+	a.allowDestructive = true
+	if _, err := a.ImportPolicies(ctx, [][]string{{"carol", "data3", "read"}}, "p", ImportOptions{Replace: true}); err != nil {
+		t.Fatalf("ImportPolicies failed: %v", err)
+	}
+	if err := a.ClearPolicy(ctx); err != nil {
+		t.Fatalf("ClearPolicy failed: %v", err)
+	}
+	if strings.Contains(buf.String(), "DELETE") {
+		t.Errorf("statements %s, supposed to write tombstones rather than deleting", buf.String())
+	}
+	count, err := a.db.Model(a.tableName).Where(tombstoneColumn, 1).Count()
+	if err != nil || count != 6 {
+		t.Errorf("stored %d tombstones, err: %v, supposed to be 6", count, err)
+	}
+}
+
+func TestWaitForMutations(t *testing.T) {
+	ctx := context.Background()
+	if err := newSqliteAdapter(t).WaitForMutations(ctx); err == nil {
+		t.Error("WaitForMutations supposed to require ClickHouse")
+	}
+
+	// The mutations are read from a system database attached to the single
+	// connection.
+	dir := t.TempDir()
+	db, err := gdb.New(gdb.ConfigNode{Type: "sqlite", Name: filepath.Join(dir, "casbin.db"), MaxOpenConnCount: 1})
+	if err != nil {
+		t.Fatalf("failed to create database connection: %v", err)
+	}
+	defer db.Close(ctx)
+	a, err := NewAdapter(ctx, "", "", db)
+	if err != nil {
+		t.Fatalf("NewAdapter failed: %v", err)
+	}
+	a.tombstones = true
+	for _, sql := range []string{
+		fmt.Sprintf("ATTACH DATABASE '%s' AS system", filepath.Join(dir, "system.db")),
+		"CREATE TABLE system.mutations (`database` TEXT, `table` TEXT, mutation_id TEXT, create_time INTEGER, is_done INTEGER, latest_fail_reason TEXT DEFAULT '')",
+	} {
+		if _, err := db.Exec(ctx, sql); err != nil {
+			t.Fatalf("failed to create mutations table: %v", err)
+		}
+	}
+	if err := a.WaitForMutations(ctx); err != nil {
+		t.Fatalf("WaitForMutations failed: %v", err)
+	}
+	if _, err := db.Exec(ctx, "INSERT INTO system.mutations VALUES (?, ?, 'mutation_1.txt', 1, 0, '')", db.GetSchema(), a.tableName); err != nil {
+		t.Fatalf("failed to insert mutation: %v", err)
+	}
+
+	// A pending mutation is waited for until ctx is done.
+	timeout, cancel := context.WithTimeout(ctx, 3*mutationPollInterval/2)
+	defer cancel()
+	if err := a.WaitForMutations(timeout); !errors.Is(err, context.DeadlineExceeded) || !strings.Contains(err.Error(), "mutation_1.txt") {
+		t.Errorf("WaitForMutations err: %v, supposed to time out on mutation_1.txt", err)
+	}
+	go func() {
+		time.Sleep(mutationPollInterval / 2)
+		_, _ = db.Exec(ctx, "UPDATE system.mutations SET is_done = 1")
+	}()
+	if err := a.WaitForMutations(ctx); err != nil {
+		t.Errorf("WaitForMutations failed: %v", err)
+	}
+
+	if _, err := db.Exec(ctx, "INSERT INTO system.mutations VALUES (?, ?, 'mutation_2.txt', 2, 0, 'Code: 8. Cannot find column')", db.GetSchema(), a.tableName); err != nil {
+		t.Fatalf("failed to insert mutation: %v", err)
+	}
+	if err := a.WaitForMutations(ctx); err == nil || !strings.Contains(err.Error(), "Cannot find column") {
+		t.Errorf("WaitForMutations err: %v, supposed to report the failed mutation", err)
+	}
+}
@@ -0,0 +1,358 @@
+package adapter
+
+import (
+	"context"
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/hmac"
+	"crypto/sha256"
+	"database/sql"
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"strings"
+	"unicode/utf8"
+
+	"github.com/gogf/gf/v2/container/gvar"
+	"github.com/gogf/gf/v2/database/gdb"
+	"github.com/gogf/gf/v2/frame/g"
+)
+
+// ErrEncodedValueTooLong is returned by the writes of WithEncryption when an
+// encoded value is longer than its column.
+var ErrEncodedValueTooLong = errors.New("encoded value too long for its column")
+
+// ValueCodec encodes the rule values stored in the database and decodes
+// them back. Encode must be deterministic, the filters and removals find
+// the stored rules by the encoded values.
+type ValueCodec interface {
+	Encode(value string) (string, error)
+	Decode(value string) (string, error)
+}
+
+// WithEncryption stores the values of the rules encoded with codec, e.g. an
+// AESCodec. The values are decoded again by the loads and the queries, and
+// the values of the filters and of the rules removed or updated are encoded
+// to find the stored ones. Empty values are stored as they are. The rules
+// already stored aren't encoded, and the distinct values are sorted after
+// decoding. The encoded values must fit the value columns, the writes of
+// longer ones fail with ErrEncodedValueTooLong: the values of AESCodec
+// grow by a third plus about 40 characters, values longer than about 150
+// bytes need wider columns, see WithColumnType and WithTextColumns.
+func WithEncryption(codec ValueCodec) AdapterOption {
+	return AdapterOption{apply: func(a *Adapter) {
+		a.codec = codec
+	}}
+}
+
+// encodeValue encodes a value to store.
+func (a *Adapter) encodeValue(value string) (string, error) {
+	if a.codec == nil || value == "" {
+		return value, nil
+	}
+	encoded, err := a.codec.Encode(value)
+	if err != nil {
+		return "", fmt.Errorf("failed to encode value: %w", err)
+	}
+	return encoded, nil
+}
+
+// decodeValue decodes a stored value.
+func (a *Adapter) decodeValue(value string) (string, error) {
+	if a.codec == nil || value == "" {
+		return value, nil
+	}
+	decoded, err := a.codec.Decode(value)
+	if err != nil {
+		return "", fmt.Errorf("failed to decode value: %w", err)
+	}
+	return decoded, nil
+}
+
+// encodeValues returns values encoded.
+func (a *Adapter) encodeValues(values []string) ([]string, error) {
+	if a.codec == nil {
+		return values, nil
+	}
+	encoded := make([]string, len(values))
+	for i, value := range values {
+		var err error
+		if encoded[i], err = a.encodeValue(value); err != nil {
+			return nil, err
+		}
+	}
+	return encoded, nil
+}
+
+// encodeRules returns rules with their values encoded.
+func (a *Adapter) encodeRules(rules []Rule) ([]Rule, error) {
+	if a.codec == nil {
+		return rules, nil
+	}
+	encoded := make([]Rule, len(rules))
+	for i, rule := range rules {
+		for _, value := range []*string{&rule.V0, &rule.V1, &rule.V2, &rule.V3, &rule.V4, &rule.V5} {
+			var err error
+			if *value, err = a.encodeValue(*value); err != nil {
+				return nil, err
+			}
+		}
+		encoded[i] = rule
+	}
+	return encoded, nil
+}
+
+// encodeFilter returns filter with its values encoded.
+func (a *Adapter) encodeFilter(filter Filter) (Filter, error) {
+	if a.codec == nil {
+		return filter, nil
+	}
+	for _, values := range []*[]string{
+		&filter.V0, &filter.V1, &filter.V2, &filter.V3, &filter.V4, &filter.V5,
+		&filter.NotV0, &filter.NotV1, &filter.NotV2, &filter.NotV3, &filter.NotV4, &filter.NotV5,
+	} {
+		var err error
+		if *values, err = a.encodeValues(*values); err != nil {
+			return Filter{}, err
+		}
+	}
+	return filter, nil
+}
+
+// rulesCondition returns the condition of rulesQuery matching the stored
+// rules.
+func (a *Adapter) rulesCondition(rules []Rule) (string, []interface{}, error) {
+	if a.ruleColumn != "" {
+		query, args := a.ruleLinesCondition(rules)
+		return query, args, nil
+	}
+	if a.jsonStorage {
+		query, args := a.jsonValuesCondition(rules)
+		return query, args, nil
+	}
+	encoded, err := a.encodeRules(rules)
+	if err != nil {
+		return "", nil, err
+	}
+	query, args := rulesQuery(encoded, a.pTypeColumn)
+	return query, args, nil
+}
+
+// encodeRecord returns a copy of the row record with its values encoded.
+// The encoded values must fit their columns, which some databases truncate
+// silently.
+func (a *Adapter) encodeRecord(record g.Map) (g.Map, error) {
+	encoded := make(g.Map, len(record))
+	for column, value := range record {
+		if s, ok := value.(string); ok && valueColumn(column) {
+			e, err := a.encodeValue(s)
+			if err != nil {
+				return nil, err
+			}
+			if err := a.checkEncodedLength(column, s, e); err != nil {
+				return nil, err
+			}
+			value = e
+		}
+		encoded[column] = value
+	}
+	return encoded, nil
+}
+
+// checkEncodedLength returns ErrEncodedValueTooLong when the value encoded
+// from value is longer than the value column.
+func (a *Adapter) checkEncodedLength(column, value, encoded string) error {
+	if a.codec == nil || len(a.valueLengths) != len(valueColumns) {
+		return nil
+	}
+	maxLength := a.valueLengths[column[1]-'0']
+	if maxLength > 0 && utf8.RuneCountInString(encoded) > maxLength {
+		return fmt.Errorf("%w: value %.20q... of %s encodes to %d characters, the column holds %d", ErrEncodedValueTooLong, value, column, utf8.RuneCountInString(encoded), maxLength)
+	}
+	return nil
+}
+
+// decodeRecords decodes the values of the rows records in place.
+func (a *Adapter) decodeRecords(records gdb.Result) error {
+	if a.codec == nil {
+		return nil
+	}
+	for _, record := range records {
+		for column, value := range record {
+			if !valueColumn(column) || value.IsNil() {
+				continue
+			}
+			decoded, err := a.decodeValue(value.String())
+			if err != nil {
+				return err
+			}
+			record[column] = gvar.New(decoded)
+		}
+	}
+	return nil
+}
+
+// codecHook encodes the values of the rows written by a model and decodes
+// those of the rows it reads. When counted is set it also counts the
+// affected rows like affectedHook.
+func (a *Adapter) codecHook(counted bool) gdb.HookHandler {
+	count := func(ctx context.Context, result sql.Result, err error) (sql.Result, error) {
+		if counted {
+			return countAffected(ctx, result, err)
+		}
+		return result, err
+	}
+	hook := gdb.HookHandler{
+		Select: func(ctx context.Context, in *gdb.HookSelectInput) (gdb.Result, error) {
+			result, err := in.Next(ctx)
+			if err != nil {
+				return result, err
+			}
+			return result, a.decodeRecords(result)
+		},
+		Insert: func(ctx context.Context, in *gdb.HookInsertInput) (sql.Result, error) {
+			for i, record := range in.Data {
+				encoded, err := a.encodeRecord(record)
+				if err != nil {
+					return nil, err
+				}
+				in.Data[i] = encoded
+			}
+			result, err := in.Next(ctx)
+			return count(ctx, result, err)
+		},
+		Update: func(ctx context.Context, in *gdb.HookUpdateInput) (sql.Result, error) {
+			if data, ok := in.Data.(map[string]interface{}); ok {
+				encoded, err := a.encodeRecord(data)
+				if err != nil {
+					return nil, err
+				}
+				in.Data = map[string]interface{}(encoded)
+			}
+			result, err := in.Next(ctx)
+			return count(ctx, result, err)
+		},
+	}
+	if counted {
+		hook.Delete = affectedHook.Delete
+	}
+	return hook
+}
+
+// encoded returns m of a table of rules other than the policy table,
+// encoding and decoding their values, and recording its writes in a dry
+// run.
+func (a *Adapter) encoded(m *gdb.Model) *gdb.Model {
+	var hook gdb.HookHandler
+	switch {
+	case a.codec != nil:
+		hook = a.codecHook(false)
+	case a.plan == nil:
+		return m
+	}
+	return a.planned(m, hook)
+}
+
+const (
+	// aesKeyIDSeparator separates the key id from the ciphertext of the
+	// values encoded by AESCodec.
+	aesKeyIDSeparator = ":"
+	// aesNonceLabel and aesKeyLabel derive the nonce key and the cipher
+	// key from a key of AESCodec.
+	aesNonceLabel = "casbin adapter nonce"
+	aesKeyLabel   = "casbin adapter key"
+)
+
+var _ ValueCodec = (*AESCodec)(nil)
+
+// AESCodec is a ValueCodec encrypting the values with AES-GCM. The encoded
+// values start with the id of their key, so that the keys can be rotated:
+// the values are encoded with the current key and decoded with any key
+// given. The nonce is derived from the value, so that equal values encode
+// equally as filters need, which reveals the equal stored values. The
+// values written with a former key aren't found by filters and removals
+// until the policy is saved again.
+type AESCodec struct {
+	current string
+	keys    map[string]aesKey
+}
+
+// aesKey holds the cipher of a key and the key deriving the nonces.
+type aesKey struct {
+	aead  cipher.AEAD
+	nonce []byte
+}
+
+// NewAESCodec returns an AESCodec encoding with the key currentKeyID of
+// keys, which are AES keys of 16, 24 or 32 bytes by their id. The ids can't
+// contain a colon.
+func NewAESCodec(currentKeyID string, keys map[string][]byte) (*AESCodec, error) {
+	if _, ok := keys[currentKeyID]; !ok {
+		return nil, fmt.Errorf("unknown current key id: %q", currentKeyID)
+	}
+
+	c := &AESCodec{current: currentKeyID, keys: make(map[string]aesKey, len(keys))}
+	for id, key := range keys {
+		if id == "" || strings.Contains(id, aesKeyIDSeparator) {
+			return nil, fmt.Errorf("invalid key id: %q", id)
+		}
+		switch len(key) {
+		case 16, 24, 32:
+		default:
+			return nil, fmt.Errorf("invalid length of key %q: %d bytes", id, len(key))
+		}
+		block, err := aes.NewCipher(deriveKey(key, aesKeyLabel)[:len(key)])
+		if err != nil {
+			return nil, fmt.Errorf("failed to create cipher of key %q: %w", id, err)
+		}
+		aead, err := cipher.NewGCM(block)
+		if err != nil {
+			return nil, fmt.Errorf("failed to create cipher of key %q: %w", id, err)
+		}
+		c.keys[id] = aesKey{aead: aead, nonce: deriveKey(key, aesNonceLabel)}
+	}
+	return c, nil
+}
+
+// deriveKey derives the key of label from key.
+func deriveKey(key []byte, label string) []byte {
+	mac := hmac.New(sha256.New, key)
+	mac.Write([]byte(label))
+	return mac.Sum(nil)
+}
+
+// Encode encrypts value with the current key.
+func (c *AESCodec) Encode(value string) (string, error) {
+	key := c.keys[c.current]
+	mac := hmac.New(sha256.New, key.nonce)
+	mac.Write([]byte(value))
+	nonce := mac.Sum(nil)[:key.aead.NonceSize()]
+
+	sealed := key.aead.Seal(nonce, nonce, []byte(value), nil)
+	return c.current + aesKeyIDSeparator + base64.RawURLEncoding.EncodeToString(sealed), nil
+}
+
+// Decode decrypts value with the key it was encoded with.
+func (c *AESCodec) Decode(value string) (string, error) {
+	id, encoded, ok := strings.Cut(value, aesKeyIDSeparator)
+	if !ok {
+		return "", errors.New("missing key id")
+	}
+	key, ok := c.keys[id]
+	if !ok {
+		return "", fmt.Errorf("unknown key id: %q", id)
+	}
+	sealed, err := base64.RawURLEncoding.DecodeString(encoded)
+	if err != nil {
+		return "", fmt.Errorf("invalid ciphertext: %w", err)
+	}
+	if len(sealed) < key.aead.NonceSize() {
+		return "", errors.New("invalid ciphertext: too short")
+	}
+	nonce, ciphertext := sealed[:key.aead.NonceSize()], sealed[key.aead.NonceSize():]
+	plain, err := key.aead.Open(nil, nonce, ciphertext, nil)
+	if err != nil {
+		return "", err
+	}
+	return string(plain), nil
+}
@@ -0,0 +1,184 @@
+package adapter
+
+import (
+	"context"
+	"errors"
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/casbin/casbin/v2"
+	"github.com/casbin/casbin/v2/model"
+)
+
+// testKeys are AES keys by their id.
+var testKeys = map[string][]byte{
+	"k1": []byte("0123456789abcdef0123456789abcdef"),
+	"k2": []byte("fedcba9876543210"),
+}
+
+// newTestCodec returns an AESCodec encoding with the key current of keys.
+func newTestCodec(t *testing.T, current string, keys map[string][]byte) *AESCodec {
+	t.Helper()
+	codec, err := NewAESCodec(current, keys)
+	if err != nil {
+		t.Fatalf("NewAESCodec failed: %v", err)
+	}
+	return codec
+}
+
+func TestAESCodec(t *testing.T) {
+	c1 := newTestCodec(t, "k1", map[string][]byte{"k1": testKeys["k1"]})
+	encoded, err := c1.Encode("alice")
+	if err != nil {
+		t.Fatalf("Encode failed: %v", err)
+	}
+	if !strings.HasPrefix(encoded, "k1:") || strings.Contains(encoded, "alice") {
+		t.Errorf("encoded value %q, supposed to be k1: followed by the ciphertext", encoded)
+	}
+	if again, _ := c1.Encode("alice"); again != encoded {
+		t.Errorf("alice encoded as %q and %q, supposed to be deterministic", encoded, again)
+	}
+	if other, _ := c1.Encode("bob"); other == encoded {
+		t.Error("alice and bob encoded equally")
+	}
+	if decoded, err := c1.Decode(encoded); err != nil || decoded != "alice" {
+		t.Errorf("decoded %q, err: %v, supposed to be alice", decoded, err)
+	}
+
+	// The rotated codec encodes with the new key and decodes the old values.
+	c2 := newTestCodec(t, "k2", testKeys)
+	if rotated, _ := c2.Encode("alice"); !strings.HasPrefix(rotated, "k2:") {
+		t.Errorf("rotated value %q, supposed to start with k2:", rotated)
+	}
+	if decoded, err := c2.Decode(encoded); err != nil || decoded != "alice" {
+		t.Errorf("decoded %q with the rotated codec, err: %v, supposed to be alice", decoded, err)
+	}
+
+	// A wrong key fails to decode.
+	wrong := newTestCodec(t, "k1", map[string][]byte{"k1": testKeys["k2"]})
+	if _, err := wrong.Decode(encoded); err == nil {
+		t.Error("expected the wrong key to fail")
+	}
+	for _, value := range []string{"alice", "k3:abc", "k1:!!!", "k1:YQ"} {
+		if _, err := c2.Decode(value); err == nil {
+			t.Errorf("expected %q to fail", value)
+		}
+	}
+
+	for _, keys := range []map[string][]byte{
+		{"k1": []byte("short")},
+		{"k:1": testKeys["k1"]},
+		{"k2": testKeys["k2"]},
+	} {
+		if _, err := NewAESCodec("k1", keys); err == nil {
+			t.Errorf("expected the keys %v to fail", keys)
+		}
+	}
+}
+
+func TestEncryption(t *testing.T) {
+	ctx := context.Background()
+	db := newSqliteDB(t)
+	a, err := NewAdapter(ctx, "", "", db, WithEncryption(newTestCodec(t, "k1", testKeys)))
+	if err != nil {
+		t.Fatalf("failed to create adapter: %v", err)
+	}
+	initPolicy(t, a)
+
+	// The values are stored encrypted, the policy types and empty values
+	// as they are.
+	records, err := db.Model(a.tableName).Ctx(ctx).All()
+	if err != nil {
+		t.Fatalf("failed to read rows: %v", err)
+	}
+	for _, record := range records {
+		if !strings.HasPrefix(record["v0"].String(), "k1:") || record["v3"].String() != "" {
+			t.Errorf("stored row %v, supposed to be encrypted", record.Map())
+		}
+		if p := record["p_type"].String(); p != "p" && p != "g" {
+			t.Errorf("stored policy type %q", p)
+		}
+	}
+
+	e, _ := casbin.NewEnforcer("examples/rbac_model.conf", a)
+	testGetPolicy(t, e, [][]string{{"alice", "data1", "read"}, {"bob", "data2", "write"}, {"data2_admin", "data2", "read"}, {"data2_admin", "data2", "write"}})
+
+	// The filters find the encrypted values.
+	if err := e.LoadFilteredPolicy(Filter{V0: []string{"alice", "bob"}}); err != nil {
+		t.Fatalf("LoadFilteredPolicy failed: %v", err)
+	}
+	testGetPolicyWithoutOrder(t, e, [][]string{{"alice", "data1", "read"}, {"bob", "data2", "write"}})
+	if roles, err := a.GetRolesForUser(ctx, "alice"); err != nil || !reflect.DeepEqual(roles, []string{"data2_admin"}) {
+		t.Errorf("roles %v, err: %v, supposed to be [data2_admin]", roles, err)
+	}
+	if subjects, err := a.GetAllSubjects(ctx); err != nil || !reflect.DeepEqual(subjects, []string{"alice", "bob", "data2_admin"}) {
+		t.Errorf("subjects %v, err: %v, supposed to be [alice bob data2_admin]", subjects, err)
+	}
+
+	old, err := a.UpdateFilteredPolicies("p", "p", [][]string{{"bob", "data3", "write"}}, 0, "bob")
+	if err != nil {
+		t.Fatalf("UpdateFilteredPolicies failed: %v", err)
+	}
+	if !reflect.DeepEqual(old, [][]string{{"bob", "data2", "write"}}) {
+		t.Errorf("old policies %v, supposed to be [[bob data2 write]]", old)
+	}
+	if err := a.RemovePolicy("p", "p", []string{"alice", "data1", "read"}); err != nil {
+		t.Fatalf("RemovePolicy failed: %v", err)
+	}
+	if err := a.RemoveFilteredPolicy("p", "p", 0, "data2_admin"); err != nil {
+		t.Fatalf("RemoveFilteredPolicy failed: %v", err)
+	}
+	_ = e.LoadPolicy()
+	testGetPolicyWithoutOrder(t, e, [][]string{{"bob", "data3", "write"}})
+
+	// A stable save keeps the rows of the unchanged rules.
+	stable, err := NewAdapter(ctx, "", "", db, WithEncryption(newTestCodec(t, "k1", testKeys)), WithStableSave())
+	if err != nil {
+		t.Fatalf("failed to create adapter: %v", err)
+	}
+	before, _ := db.Model(a.tableName).Ctx(ctx).Where("p_type", "p").Value("id")
+	m, _ := model.NewModelFromFile("examples/rbac_model.conf")
+	m.AddPolicy("p", "p", []string{"bob", "data3", "write"})
+	m.AddPolicy("p", "p", []string{"carol", "data1", "read"})
+	if err := stable.SavePolicy(m); err != nil {
+		t.Fatalf("SavePolicy failed: %v", err)
+	}
+	if after, _ := db.Model(a.tableName).Ctx(ctx).Where("p_type", "p").OrderAsc("id").Value("id"); after.Int() != before.Int() {
+		t.Errorf("the row of the unchanged rule moved from id %d to %d", before.Int(), after.Int())
+	}
+	_ = e.LoadPolicy()
+	testGetPolicy(t, e, [][]string{{"bob", "data3", "write"}, {"carol", "data1", "read"}})
+
+	// An adapter with the wrong key fails to load.
+	wrong, err := NewAdapter(ctx, "", "", db, WithEncryption(newTestCodec(t, "k1", map[string][]byte{"k1": testKeys["k2"]})))
+	if err != nil {
+		t.Fatalf("failed to create adapter: %v", err)
+	}
+	if err := wrong.LoadPolicy(model.NewModel()); err == nil {
+		t.Error("expected loading with the wrong key to fail")
+	}
+}
+
+func TestEncryptionValueLength(t *testing.T) {
+	long := strings.Repeat("x", 200)
+
+	// The encoded value doesn't fit the column, sqlite wouldn't truncate it
+	// but MySQL may.
+	a := newSqliteAdapter(t, WithEncryption(newTestCodec(t, "k1", testKeys)))
+	if err := a.AddPolicy("p", "p", []string{long, "data1", "read"}); !errors.Is(err, ErrEncodedValueTooLong) {
+		t.Errorf("AddPolicy err: %v, supposed to be %v", err, ErrEncodedValueTooLong)
+	}
+	if err := a.AddPolicy("p", "p", []string{strings.Repeat("x", 100), "data1", "read"}); err != nil {
+		t.Errorf("AddPolicy failed: %v", err)
+	}
+
+	// TEXT columns hold the long values.
+	text := newSqliteAdapter(t, WithEncryption(newTestCodec(t, "k1", testKeys)), WithTextColumns())
+	if err := text.AddPolicy("p", "p", []string{long, "data1", "read"}); err != nil {
+		t.Fatalf("AddPolicy failed: %v", err)
+	}
+	if count, err := text.CountPolicies(context.Background(), Filter{V0: []string{long}}); err != nil || count != 1 {
+		t.Errorf("CountPolicies = %d, err: %v, supposed to find the long value", count, err)
+	}
+}
@@ -0,0 +1,136 @@
+package adapter
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"regexp"
+	"strconv"
+	"strings"
+)
+
+// ruleHashColumn holds the hash of the rule indexed by WithUniqueIndex when
+// a value column is TEXT.
+const ruleHashColumn = "rule_hash"
+
+// sqlTypeRegex matches the column types accepted by WithColumnType, e.g.
+// TEXT, MEDIUMTEXT or varchar(2048).
+var sqlTypeRegex = regexp.MustCompile(`^[A-Za-z][A-Za-z ]*(\([0-9]+\))?$`)
+
+// WithColumnType sets the SQL type of the value column of fieldIndex, e.g.
+// TEXT or varchar(1024), in place of its varchar(256). The type must be a
+// character type of the database. It applies to the tables created by the
+// adapter and bounds the values accepted by the filters. With
+// WithUniqueIndex and a TEXT column, the unique index covers the hash of
+// every rule kept in a rule_hash column instead of the value columns.
+func WithColumnType(fieldIndex int, sqlType string) AdapterOption {
+	return AdapterOption{apply: func(a *Adapter) {
+		if a.columnTypes == nil {
+			a.columnTypes = make(map[int]string)
+		}
+		a.columnTypes[fieldIndex] = sqlType
+	}}
+}
+
+// WithTextColumns makes all the value columns TEXT, like WithColumnType.
+func WithTextColumns() AdapterOption {
+	return AdapterOption{apply: func(a *Adapter) {
+		a.columnTypes = make(map[int]string, len(valueColumns))
+		for i := range valueColumns {
+			a.columnTypes[i] = "TEXT"
+		}
+	}}
+}
+
+// configureColumnTypes validates the column types and derives the lengths
+// of the value columns from them.
+func (a *Adapter) configureColumnTypes() error {
+	a.valueLengths = make([]int, len(valueColumns))
+	for i := range a.valueLengths {
+		a.valueLengths[i] = maxValueLength
+	}
+	for fieldIndex, sqlType := range a.columnTypes {
+		if !isValidFieldIndex(fieldIndex) {
+			return fmt.Errorf("invalid column type field index: %d", fieldIndex)
+		}
+		if !sqlTypeRegex.MatchString(sqlType) {
+			return fmt.Errorf("invalid column type of v%d: %q", fieldIndex, sqlType)
+		}
+		a.valueLengths[fieldIndex] = typeLength(sqlType)
+		if a.uniqueIndex && isTextType(sqlType) {
+			a.ruleHash = true
+		}
+	}
+	return nil
+}
+
+// isTextType reports whether sqlType is a TEXT type, which can't be indexed
+// as a whole.
+func isTextType(sqlType string) bool {
+	upper := strings.ToUpper(sqlType)
+	return strings.Contains(upper, "TEXT") || strings.Contains(upper, "CLOB")
+}
+
+// typeLength returns the number of characters of a char or varchar type, 0
+// for the other types whose length isn't checked.
+func typeLength(sqlType string) int {
+	name, length, ok := strings.Cut(strings.ToLower(sqlType), "(")
+	if !ok || (strings.TrimSpace(name) != "varchar" && strings.TrimSpace(name) != "char") {
+		return 0
+	}
+	n, err := strconv.Atoi(strings.TrimSuffix(length, ")"))
+	if err != nil {
+		return 0
+	}
+	return n
+}
+
+// columnTypeSchema returns the column types by column name.
+func (a *Adapter) columnTypeSchema() map[string]string {
+	if len(a.columnTypes) == 0 {
+		return nil
+	}
+	types := make(map[string]string, len(a.columnTypes))
+	for fieldIndex, sqlType := range a.columnTypes {
+		types[valueColumns[fieldIndex]] = sqlType
+	}
+	return types
+}
+
+// ruleHash returns the hex SHA-256 of the policy type and the values of
+// rule.
+func ruleHash(rule Rule) string {
+	key := rule.key()
+	sum := sha256.Sum256([]byte(strings.Join(key[:], "\x00")))
+	return hex.EncodeToString(sum[:])
+}
+
+// ensureRuleHashColumn adds the rule_hash column to an existing policy
+// table and hashes the rules stored without hash.
+func (a *Adapter) ensureRuleHashColumn(ctx context.Context) error {
+	statements, err := a.missingColumnsSql(ctx, a.tableName, []string{ruleHashColumn})
+	if err != nil {
+		return err
+	}
+	for _, sql := range statements {
+		if _, err := a.db.Exec(ctx, sql); err != nil && !isDuplicateColumnError(err) {
+			return fmt.Errorf("failed to add rule hash column: %w", err)
+		}
+	}
+	if err := a.clearTableFields(ctx, a.tableName); err != nil {
+		return err
+	}
+
+	var rows []ruleRow
+	if err := a.modelCtx(ctx).WhereNull(ruleHashColumn).Scan(&rows); err != nil {
+		return fmt.Errorf("failed to scan unhashed rules: %w", err)
+	}
+	for _, row := range rows {
+		_, err := a.db.Model(a.tableName).Ctx(ctx).Data(ruleHashColumn, ruleHash(row.Rule)).Where("id", row.Id).Update()
+		if err != nil {
+			return fmt.Errorf("failed to hash rule %d: %w", row.Id, err)
+		}
+	}
+	return nil
+}
@@ -0,0 +1,118 @@
+package adapter
+
+import (
+	"context"
+	"strings"
+	"testing"
+
+	"github.com/casbin/casbin/v2"
+)
+
+func TestTextColumns(t *testing.T) {
+	a := newSqliteAdapter(t, WithTextColumns(), WithUniqueIndex(), WithConflictPolicy(ConflictSkip), WithHistory())
+	if !a.ruleHash {
+		t.Fatal("the unique index of TEXT columns doesn't use the rule hash")
+	}
+
+	// The objects only differ after their first 10KB.
+	long := strings.Repeat("https://example.com/search?q=", 350)
+	rules := [][]string{{"alice", long + "a", "read"}, {"alice", long + "b", "read"}, {"alice", long + "a", "read"}}
+	inserted, err := a.AddPoliciesOnConflict("p", "p", rules, ConflictSkip)
+	if err != nil {
+		t.Fatalf("AddPoliciesOnConflict failed: %v", err)
+	}
+	if inserted != 2 {
+		t.Errorf("inserted %d rules, supposed to be 2", inserted)
+	}
+	if _, err := a.AddPoliciesOnConflict("p", "p", rules[:1], ConflictError); err == nil {
+		t.Error("expected the unique index to reject the duplicate rule")
+	}
+
+	e, _ := casbin.NewEnforcer("examples/rbac_model.conf", a)
+	testGetPolicyWithoutOrder(t, e, [][]string{{"alice", long + "a", "read"}, {"alice", long + "b", "read"}})
+	if err := e.LoadFilteredPolicy(Filter{V1: []string{long + "b"}}); err != nil {
+		t.Fatalf("LoadFilteredPolicy failed: %v", err)
+	}
+	testGetPolicy(t, e, [][]string{{"alice", long + "b", "read"}})
+}
+
+func TestRuleHashBackfill(t *testing.T) {
+	ctx := context.Background()
+	db := newSqliteDB(t)
+	a, err := NewAdapter(ctx, "", "", db)
+	if err != nil {
+		t.Fatalf("NewAdapter failed: %v", err)
+	}
+	initPolicy(t, a)
+
+	a, err = NewAdapter(ctx, "", "", db, WithColumnType(1, "TEXT"), WithUniqueIndex())
+	if err != nil {
+		t.Fatalf("NewAdapter failed: %v", err)
+	}
+	count, err := db.Model(a.tableName).WhereNull(ruleHashColumn).Count()
+	if err != nil || count != 0 {
+		t.Errorf("%d stored rules without hash, err: %v, supposed to be 0", count, err)
+	}
+	if err := a.AddPolicy("p", "p", []string{"alice", "data1", "read"}); err == nil {
+		t.Error("expected the unique index to reject the stored rule")
+	}
+}
+
+func TestColumnTypeDDL(t *testing.T) {
+	schema := tableSchema{
+		columnTypes: map[string]string{"v1": "TEXT", "v2": "varchar(1024)"},
+		ruleHash:    true,
+	}
+	for name, ddl := range map[string]string{
+		"create":  mysqlDialect{}.createTableSql("casbin_rule", schema)[0],
+		"history": mysqlDialect{}.createHistoryTableSql("casbin_rule_history", schema)[0],
+		"staging": mysqlDialect{}.createStagingTableSql("casbin_rule_staging", schema),
+	} {
+		for _, want := range []string{
+			"v0 varchar(256) COLLATE utf8mb4_general_ci DEFAULT NULL,",
+			"v1 TEXT COLLATE utf8mb4_general_ci DEFAULT NULL,",
+			"v2 varchar(1024) COLLATE utf8mb4_general_ci DEFAULT NULL,",
+		} {
+			if !strings.Contains(ddl, want) {
+				t.Errorf("%s statement lacks %q:\n%s", name, want, ddl)
+			}
+		}
+		if hashed := strings.Contains(ddl, "rule_hash char(64)"); hashed != (name == "create") {
+			t.Errorf("%s statement has the rule hash column: %t:\n%s", name, hashed, ddl)
+		}
+	}
+}
+
+func TestColumnTypeFilterLength(t *testing.T) {
+	a := newSqliteAdapter(t, WithColumnType(1, "varchar(1024)"), WithColumnType(2, "TEXT"))
+	e, _ := casbin.NewEnforcer("examples/rbac_model.conf", a)
+	for _, tt := range []struct {
+		filter Filter
+		valid  bool
+	}{
+		{Filter{V0: []string{strings.Repeat("x", 300)}}, false},
+		{Filter{V1: []string{strings.Repeat("x", 300)}}, true},
+		{Filter{V1Prefix: []string{strings.Repeat("x", 1025)}}, false},
+		{Filter{V2: []string{strings.Repeat("x", 10000)}}, true},
+	} {
+		if err := e.LoadFilteredPolicy(tt.filter); (err == nil) != tt.valid {
+			t.Errorf("LoadFilteredPolicy(%.40v...) err: %v, supposed to be valid: %t", tt.filter, err, tt.valid)
+		}
+	}
+	// Filter.Validate checks the lengths of the default tables.
+	f := Filter{V1: []string{strings.Repeat("x", 300)}}
+	if err := f.Validate(); err == nil {
+		t.Error("expected Validate to reject a value longer than the default column")
+	}
+}
+
+func TestInvalidColumnTypes(t *testing.T) {
+	for name, opt := range map[string]AdapterOption{
+		"field index": WithColumnType(6, "TEXT"),
+		"injection":   WithColumnType(1, "TEXT, evil int"),
+	} {
+		if _, err := NewAdapter(context.Background(), "", "", newSqliteDB(t), opt); err == nil {
+			t.Errorf("%s: expected an error", name)
+		}
+	}
+}
@@ -0,0 +1,74 @@
+package adapter
+
+import (
+	"fmt"
+	"maps"
+	"strings"
+	"unicode/utf8"
+)
+
+const (
+	// maxTableCommentLength and maxColumnCommentLength are the longest
+	// comments MySQL accepts, in characters.
+	maxTableCommentLength  = 2048
+	maxColumnCommentLength = 1024
+)
+
+// WithTableComment sets the comment of the policy table created by the
+// adapter. Comments are written on MySQL, PostgreSQL and ClickHouse and
+// skipped on the other databases. They don't change existing tables, except
+// on PostgreSQL, which sets them by COMMENT statements run along with the
+// create statement.
+func WithTableComment(comment string) AdapterOption {
+	return AdapterOption{apply: func(a *Adapter) {
+		a.tableComment = comment
+	}}
+}
+
+// WithColumnComments sets the comments of the columns of the policy table
+// by column name, e.g. {"v0": "subject"}. The comments are written when the
+// adapter creates the table or adds a column to it, on the databases of
+// WithTableComment.
+func WithColumnComments(comments map[string]string) AdapterOption {
+	return AdapterOption{apply: func(a *Adapter) {
+		a.columnComments = maps.Clone(comments)
+	}}
+}
+
+// validateComments checks that the comments fit and name the columns of
+// the policy table.
+func (a *Adapter) validateComments() error {
+	if utf8.RuneCountInString(a.tableComment) > maxTableCommentLength {
+		return fmt.Errorf("table comment longer than %d characters", maxTableCommentLength)
+	}
+	for column, comment := range a.columnComments {
+		if _, ok := mysqlColumnSql[column]; !ok && column != "id" && column != a.tenantColumn {
+			return fmt.Errorf("comment of unknown column: %q", column)
+		}
+		if utf8.RuneCountInString(comment) > maxColumnCommentLength {
+			return fmt.Errorf("comment of column %s longer than %d characters", column, maxColumnCommentLength)
+		}
+	}
+	return nil
+}
+
+// mysqlQuote returns s as a MySQL string literal, which ClickHouse reads
+// alike.
+func mysqlQuote(s string) string {
+	s = strings.ReplaceAll(s, `\`, `\\`)
+	return "'" + strings.ReplaceAll(s, "'", "''") + "'"
+}
+
+// mysqlColumnComments adds the comments of schema to the column definitions
+// of ddl.
+func mysqlColumnComments(ddl string, schema tableSchema) string {
+	if len(schema.columnComments) == 0 {
+		return ddl
+	}
+	return columnLines(ddl, func(column, definition string) string {
+		if comment := schema.columnComments[column]; comment != "" {
+			return definition + " COMMENT " + mysqlQuote(comment)
+		}
+		return definition
+	})
+}
@@ -0,0 +1,124 @@
+package adapter
+
+import (
+	"context"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestMySQLComments(t *testing.T) {
+	schema := tableSchema{
+		tenantColumn: "tenant_id",
+		tableComment: "Casbin rules of the 'api'",
+		columnComments: map[string]string{
+			"v0":         `subject, e.g. alice\admin`,
+			"created_at": "creation time",
+			"tenant_id":  "tenant",
+			"updated_at": "last update",
+		},
+	}
+	d := mysqlDialect{}
+	ddl := d.createTableSql("casbin_rule", schema)[0]
+	for _, want := range []string{
+		"v0 varchar(256) COLLATE utf8mb4_general_ci DEFAULT NULL COMMENT 'subject, e.g. alice\\\\admin',\n",
+		"created_at datetime DEFAULT CURRENT_TIMESTAMP COMMENT 'creation time',\n",
+		"tenant_id varchar(64) COLLATE utf8mb4_general_ci NOT NULL DEFAULT '' COMMENT 'tenant',\n",
+		"COLLATE=utf8mb4_bin COMMENT='Casbin rules of the ''api''';",
+	} {
+		if !strings.Contains(ddl, want) {
+			t.Errorf("create statement lacks %q:\n%s", want, ddl)
+		}
+	}
+	if strings.Contains(ddl, "v1 varchar(256) COLLATE utf8mb4_general_ci DEFAULT NULL COMMENT") {
+		t.Errorf("create statement comments v1:\n%s", ddl)
+	}
+	if history := d.createHistoryTableSql("casbin_rule_history", schema)[0]; strings.Contains(history, "COMMENT") {
+		t.Errorf("history table has comments:\n%s", history)
+	}
+
+	statements := d.addColumnSql("casbin_rule", "updated_at", schema)
+	if want := "ALTER TABLE casbin_rule ADD COLUMN updated_at datetime DEFAULT NULL COMMENT 'last update'"; statements[0] != want {
+		t.Errorf("add column statement %q, supposed to be %q", statements[0], want)
+	}
+	statements = d.addColumnSql("casbin_rule", "tenant_id", schema)
+	if !strings.HasSuffix(statements[0], "NOT NULL DEFAULT '' COMMENT 'tenant'") {
+		t.Errorf("add column statement %q lacks the comment", statements[0])
+	}
+}
+
+func TestSqliteComments(t *testing.T) {
+	a := newSqliteAdapter(t, WithTableComment("Casbin rules"), WithColumnComments(map[string]string{"v0": "subject"}))
+	if err := a.AddPolicy("p", "p", []string{"alice", "data1", "read"}); err != nil {
+		t.Fatalf("AddPolicy failed: %v", err)
+	}
+	if ddl := a.dialect.createTableSql(a.tableName, a.schema())[0]; strings.Contains(ddl, "subject") {
+		t.Errorf("sqlite create statement has comments:\n%s", ddl)
+	}
+}
+
+func TestInvalidComments(t *testing.T) {
+	for name, opt := range map[string]AdapterOption{
+		"unknown column": WithColumnComments(map[string]string{"v6": "extra"}),
+		"long table":     WithTableComment(strings.Repeat("x", maxTableCommentLength+1)),
+		"long column":    WithColumnComments(map[string]string{"v0": strings.Repeat("é", maxColumnCommentLength+1)}),
+	} {
+		if _, err := NewAdapter(context.Background(), "", "", newSqliteDB(t), opt); err == nil {
+			t.Errorf("%s: expected an error", name)
+		}
+	}
+}
+
+func TestPgsqlComments(t *testing.T) {
+	schema := tableSchema{
+		tenantColumn: "tenant_id",
+		tableComment: "Casbin rules of the 'api'",
+		columnComments: map[string]string{
+			"v0":         `subject, e.g. alice\admin`,
+			"tenant_id":  "tenant",
+			"updated_at": "last update",
+		},
+	}
+	d := pgsqlDialect{}
+	statements := d.createTableSql("casbin_rule", schema)
+	want := []string{
+		"COMMENT ON TABLE casbin_rule IS 'Casbin rules of the ''api'''",
+		`COMMENT ON COLUMN casbin_rule.v0 IS 'subject, e.g. alice\admin'`,
+		"COMMENT ON COLUMN casbin_rule.tenant_id IS 'tenant'",
+	}
+	// The comment of updated_at waits for the column to be added.
+	if len(statements) != 2+len(want) || !reflect.DeepEqual(statements[2:], want) {
+		t.Errorf("create statements %q, supposed to end with %q", statements, want)
+	}
+	if strings.Contains(statements[0], "COMMENT") {
+		t.Errorf("create statement has comments:\n%s", statements[0])
+	}
+
+	statements = d.addColumnSql("casbin_rule", "updated_at", schema)
+	if want := "COMMENT ON COLUMN casbin_rule.updated_at IS 'last update'"; statements[len(statements)-1] != want {
+		t.Errorf("add column statements %q, supposed to end with %q", statements, want)
+	}
+}
+
+func TestClickHouseComments(t *testing.T) {
+	schema := tableSchema{
+		tenantColumn:   "tenant_id",
+		tableComment:   "Casbin rules",
+		columnComments: map[string]string{"v0": "subject's", "tenant_id": "tenant"},
+	}
+	d := clickhouseDialect{}
+	ddl := d.createTableSql("casbin_rule", schema)[0]
+	for _, want := range []string{
+		"  v0 String DEFAULT '' COMMENT 'subject''s',\n",
+		"  tenant_id String DEFAULT '' COMMENT 'tenant',\n",
+		"ORDER BY (p_type, v0, v1, v2, v3, v4, v5, tenant_id)\nCOMMENT 'Casbin rules'",
+	} {
+		if !strings.Contains(ddl, want) {
+			t.Errorf("create statement lacks %q:\n%s", want, ddl)
+		}
+	}
+	want := "ALTER TABLE casbin_rule ADD COLUMN tenant_id String DEFAULT '' COMMENT 'tenant', MODIFY ORDER BY (p_type, v0, v1, v2, v3, v4, v5, tenant_id)"
+	if sql := d.addColumnSql("casbin_rule", "tenant_id", schema)[0]; sql != want {
+		t.Errorf("add tenant statement %q, supposed to be %q", sql, want)
+	}
+}
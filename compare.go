@@ -0,0 +1,107 @@
+package adapter
+
+import (
+	"context"
+	"errors"
+	"fmt"
+)
+
+// DiffReport is the difference between the rules of two adapters returned
+// by CompareWith. Rules are compared by policy type and values, the order
+// of the rows doesn't matter.
+type DiffReport struct {
+	// OnlyInA holds the rules stored by the compared adapter only, OnlyInB
+	// the rules stored by the other adapter only.
+	OnlyInA []Rule `json:"only_in_a"`
+	OnlyInB []Rule `json:"only_in_b"`
+	// DuplicatesA and DuplicatesB hold the rules stored more than once on
+	// each side.
+	DuplicatesA []DuplicateRule `json:"duplicates_a"`
+	DuplicatesB []DuplicateRule `json:"duplicates_b"`
+}
+
+// DuplicateRule is a rule stored Count times.
+type DuplicateRule struct {
+	Rule
+	Count int `json:"count"`
+}
+
+// Equal reports whether both adapters store the same set of rules,
+// regardless of duplicates.
+func (r *DiffReport) Equal() bool {
+	return len(r.OnlyInA) == 0 && len(r.OnlyInB) == 0
+}
+
+// ruleCounts counts the rows of each distinct rule, in the order the rules
+// were first added.
+type ruleCounts struct {
+	rules  []Rule
+	counts map[ruleKey]int
+}
+
+// CompareWith compares the stored rules of the adapter, side A, with the
+// ones of other, side B, e.g. to verify a new table before cutting over to
+// it. The rules of both sides are read in pages, only the distinct rules
+// are held in memory.
+func (a *Adapter) CompareWith(ctx context.Context, other *Adapter) (*DiffReport, error) {
+	if other == nil {
+		return nil, errors.New("other adapter cannot be nil")
+	}
+
+	countsA, err := a.countRules(ctx)
+	if err != nil {
+		return nil, err
+	}
+	countsB, err := other.countRules(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	report := &DiffReport{
+		OnlyInA:     []Rule{},
+		OnlyInB:     []Rule{},
+		DuplicatesA: countsA.duplicates(),
+		DuplicatesB: countsB.duplicates(),
+	}
+	for _, rule := range countsA.rules {
+		if countsB.counts[rule.key()] == 0 {
+			report.OnlyInA = append(report.OnlyInA, rule)
+		}
+	}
+	for _, rule := range countsB.rules {
+		if countsA.counts[rule.key()] == 0 {
+			report.OnlyInB = append(report.OnlyInB, rule)
+		}
+	}
+	return report, nil
+}
+
+// countRules counts the stored rows of each distinct rule.
+func (a *Adapter) countRules(ctx context.Context) (*ruleCounts, error) {
+	counts := &ruleCounts{counts: make(map[ruleKey]int)}
+	err := a.scanPages(a.modelCtx(ctx), func(rows []ruleRow) error {
+		for _, row := range rows {
+			key := row.key()
+			if counts.counts[key] == 0 {
+				counts.rules = append(counts.rules, row.Rule)
+			}
+			counts.counts[key]++
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to scan rules of %s: %w", a.tableName, err)
+	}
+	return counts, nil
+}
+
+// duplicates returns the rules counted more than once.
+func (c *ruleCounts) duplicates() []DuplicateRule {
+	duplicates := []DuplicateRule{}
+	for _, rule := range c.rules {
+		if count := c.counts[rule.key()]; count > 1 {
+			duplicates = append(duplicates, DuplicateRule{Rule: rule, Count: count})
+		}
+	}
+	return duplicates
+}
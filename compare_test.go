@@ -0,0 +1,73 @@
+package adapter
+
+import (
+	"context"
+	"encoding/json"
+	"reflect"
+	"testing"
+)
+
+func TestCompareWith(t *testing.T) {
+	ctx := context.Background()
+	a := newSqliteAdapter(t)
+	initPolicy(t, a)
+
+	b, err := a.WithTable("casbin_rule_shadow")
+	if err != nil {
+		t.Fatalf("WithTable failed: %v", err)
+	}
+	if _, err := a.CopyTo(ctx, b, nil, true); err != nil {
+		t.Fatalf("CopyTo failed: %v", err)
+	}
+
+	report, err := a.CompareWith(ctx, b)
+	if err != nil {
+		t.Fatalf("CompareWith failed: %v", err)
+	}
+	if !report.Equal() || len(report.DuplicatesA) != 0 || len(report.DuplicatesB) != 0 {
+		t.Errorf("identical tables reported as %+v", report)
+	}
+
+	// An addition and a duplicate on A, a removal and a duplicate on B.
+	if err := a.AddPolicies("p", "p", [][]string{{"carol", "data3", "read"}, {"alice", "data1", "read"}}); err != nil {
+		t.Fatalf("AddPolicies failed: %v", err)
+	}
+	if err := b.RemovePolicy("p", "p", []string{"bob", "data2", "write"}); err != nil {
+		t.Fatalf("RemovePolicy failed: %v", err)
+	}
+	if err := b.AddPolicies("g", "g", [][]string{{"alice", "data2_admin"}, {"alice", "data2_admin"}}); err != nil {
+		t.Fatalf("AddPolicies failed: %v", err)
+	}
+
+	report, err = a.CompareWith(ctx, b)
+	if err != nil {
+		t.Fatalf("CompareWith failed: %v", err)
+	}
+	want := &DiffReport{
+		OnlyInA:     []Rule{{PType: "p", V0: "bob", V1: "data2", V2: "write"}, {PType: "p", V0: "carol", V1: "data3", V2: "read"}},
+		OnlyInB:     []Rule{},
+		DuplicatesA: []DuplicateRule{{Rule: Rule{PType: "p", V0: "alice", V1: "data1", V2: "read"}, Count: 2}},
+		DuplicatesB: []DuplicateRule{{Rule: Rule{PType: "g", V0: "alice", V1: "data2_admin"}, Count: 3}},
+	}
+	if !reflect.DeepEqual(report, want) {
+		t.Errorf("report %+v, supposed to be %+v", report, want)
+	}
+	if report.Equal() {
+		t.Error("different tables reported as equal")
+	}
+
+	// The other way round the sides are swapped.
+	report, _ = b.CompareWith(ctx, a)
+	if !reflect.DeepEqual(report.OnlyInB, want.OnlyInA) || len(report.OnlyInA) != 0 {
+		t.Errorf("swapped report %+v", report)
+	}
+
+	data, err := json.Marshal(report)
+	if err != nil {
+		t.Fatalf("failed to encode report: %v", err)
+	}
+	var decoded DiffReport
+	if err := json.Unmarshal(data, &decoded); err != nil || !reflect.DeepEqual(&decoded, report) {
+		t.Errorf("report doesn't survive JSON: %s, err: %v", data, err)
+	}
+}
@@ -0,0 +1,104 @@
+package adapter
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"slices"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/gogf/gf/v2/os/gcfg"
+)
+
+// configKeys are the keys of the configuration section of
+// NewAdapterFromConfig.
+var configKeys = []string{"group", "table", "batchSize", "pageSize", "filterChunkSize", "autoReload"}
+
+// NewAdapterFromConfig creates an adapter configured by the section key of
+// cfg, e.g. "casbin" for:
+//
+//	casbin:
+//	  group: default       # database group, required
+//	  table: casbin_rule   # policy table, casbin_rule by default
+//	  batchSize: 1000      # see WithBatchSize
+//	  pageSize: 1000       # see WithPageSize
+//	  filterChunkSize: 500 # see WithFilterChunkSize
+//	  autoReload: 30s      # see AutoReloadInterval
+//
+// The options of the section are applied before opts. Unknown keys are
+// logged as warnings, with the logger of WithLogger or of the database.
+func NewAdapterFromConfig(ctx context.Context, cfg *gcfg.Config, key string, opts ...AdapterOption) (*Adapter, error) {
+	if cfg == nil {
+		return nil, errors.New("config cannot be nil")
+	}
+	value, err := cfg.Get(ctx, key)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read config %s: %w", key, err)
+	}
+	section := value.Map()
+	if len(section) == 0 {
+		return nil, fmt.Errorf("missing config section %s", key)
+	}
+
+	group, _ := section["group"].(string)
+	if group == "" {
+		return nil, fmt.Errorf("missing required config key %s.group", key)
+	}
+	table, _ := section["table"].(string)
+
+	var configured []AdapterOption
+	for _, field := range []struct {
+		name   string
+		option func(int) AdapterOption
+	}{
+		{"batchSize", WithBatchSize},
+		{"pageSize", WithPageSize},
+		{"filterChunkSize", WithFilterChunkSize},
+	} {
+		raw, ok := section[field.name]
+		if !ok {
+			continue
+		}
+		n, err := strconv.Atoi(fmt.Sprint(raw))
+		if err != nil || n <= 0 {
+			return nil, fmt.Errorf("invalid config key %s.%s: %v, supposed to be a positive integer", key, field.name, raw)
+		}
+		configured = append(configured, field.option(n))
+	}
+	if raw, ok := section["autoReload"]; ok {
+		interval, err := time.ParseDuration(fmt.Sprint(raw))
+		if err != nil || interval <= 0 {
+			return nil, fmt.Errorf("invalid config key %s.autoReload: %v, supposed to be a positive duration such as 30s", key, raw)
+		}
+		configured = append(configured, AdapterOption{apply: func(a *Adapter) {
+			a.reloadInterval = interval
+		}})
+	}
+
+	a, err := NewAdapter(ctx, group, table, nil, append(configured, opts...)...)
+	if err != nil {
+		return nil, err
+	}
+
+	var unknown []string
+	for name := range section {
+		if !slices.Contains(configKeys, name) {
+			unknown = append(unknown, name)
+		}
+	}
+	if logger := a.warnLogger(); len(unknown) > 0 && logger != nil {
+		sort.Strings(unknown)
+		logger.Warningf(ctx, "casbin adapter: config %s: unknown keys %s, supposed to be among %s",
+			key, strings.Join(unknown, ", "), strings.Join(configKeys, ", "))
+	}
+	return a, nil
+}
+
+// AutoReloadInterval returns the autoReload interval of the configuration of
+// NewAdapterFromConfig, to pass to StartAutoReload, or 0.
+func (a *Adapter) AutoReloadInterval() time.Duration {
+	return a.reloadInterval
+}
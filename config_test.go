@@ -0,0 +1,72 @@
+package adapter
+
+import (
+	"bytes"
+	"context"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/gogf/gf/v2/database/gdb"
+	"github.com/gogf/gf/v2/os/gcfg"
+)
+
+// newContentConfig returns a config of the YAML content.
+func newContentConfig(t *testing.T, content string) *gcfg.Config {
+	t.Helper()
+	adapter, err := gcfg.NewAdapterContent(content)
+	if err != nil {
+		t.Fatalf("failed to create config adapter: %v", err)
+	}
+	return gcfg.NewWithAdapter(adapter)
+}
+
+func TestNewAdapterFromConfig(t *testing.T) {
+	ctx := context.Background()
+	group := "casbin_config_" + strings.ReplaceAll(t.Name(), "/", "_")
+	gdb.AddConfigNode(group, gdb.ConfigNode{Type: "sqlite", Name: filepath.Join(t.TempDir(), "casbin.db")})
+
+	var buf bytes.Buffer
+	cfg := newContentConfig(t, `
+casbin:
+  group: `+group+`
+  table: rules
+  batchSize: 50
+  pageSize: 20
+  autoReload: 30s
+  batchsize: 10
+`)
+	a, err := NewAdapterFromConfig(ctx, cfg, "casbin", WithLogger(newBufferLogger(&buf)))
+	if err != nil {
+		t.Fatalf("NewAdapterFromConfig failed: %v", err)
+	}
+	if a.tableName != "rules" || a.batchSize != 50 || a.pageSize != 20 || a.AutoReloadInterval() != 30*time.Second {
+		t.Errorf("table %s, batch size %d, page size %d, reload %s, supposed to be rules, 50, 20 and 30s",
+			a.tableName, a.batchSize, a.pageSize, a.AutoReloadInterval())
+	}
+	if !strings.Contains(buf.String(), "unknown keys batchsize") {
+		t.Errorf("log %q, supposed to warn about batchsize", buf.String())
+	}
+	if err := a.AddPolicy("p", "p", []string{"alice", "data1", "read"}); err != nil {
+		t.Fatalf("AddPolicy failed: %v", err)
+	}
+
+	tests := []struct {
+		name    string
+		content string
+		want    string
+	}{
+		{"no section", "other:\n  group: " + group, "missing config section casbin"},
+		{"no group", "casbin:\n  table: rules", "missing required config key casbin.group"},
+		{"bad batch size", "casbin:\n  group: " + group + "\n  batchSize: many", "invalid config key casbin.batchSize"},
+		{"negative page size", "casbin:\n  group: " + group + "\n  pageSize: -1", "invalid config key casbin.pageSize"},
+		{"bad reload", "casbin:\n  group: " + group + "\n  autoReload: 30", "invalid config key casbin.autoReload"},
+	}
+	for _, tt := range tests {
+		_, err := NewAdapterFromConfig(ctx, newContentConfig(t, tt.content), "casbin")
+		if err == nil || !strings.Contains(err.Error(), tt.want) {
+			t.Errorf("%s: err: %v, supposed to contain %q", tt.name, err, tt.want)
+		}
+	}
+}
@@ -0,0 +1,325 @@
+package adapter
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"sort"
+	"strings"
+
+	"github.com/gogf/gf/v2/database/gdb"
+	"github.com/gogf/gf/v2/frame/g"
+)
+
+// mysqlIndexPrefixLength is the number of leading characters of each value
+// column covered by the unique index on MySQL, whose keys are limited to
+// 3072 bytes.
+const mysqlIndexPrefixLength = 100
+
+// ConflictPolicy decides what happens when an added rule is already stored.
+type ConflictPolicy int
+
+const (
+	// ConflictError adds the rule anyway, which fails the whole add when
+	// the unique index of WithUniqueIndex rejects it. It is the default.
+	ConflictError ConflictPolicy = iota
+	// ConflictSkip keeps the stored rule and skips the added one.
+	ConflictSkip
+	// ConflictReplace replaces the stored rule by the added one, which gets
+	// a new row.
+	ConflictReplace
+)
+
+// WithUniqueIndex adds a unique index over the policy type, the values and
+// the tenant column to the policy table when it is ensured, so that the
+// database rejects duplicate rules. Creating the index fails when the table
+// already holds duplicates, remove them with Deduplicate first. On MySQL
+// the index covers the first 100 characters of each value. When a value
+// column is TEXT, see WithColumnType, it covers a hash of the rule instead.
+func WithUniqueIndex() AdapterOption {
+	return AdapterOption{apply: func(a *Adapter) {
+		a.uniqueIndex = true
+	}}
+}
+
+// WithConflictPolicy sets the conflict policy of AddPolicy and AddPolicies.
+func WithConflictPolicy(policy ConflictPolicy) AdapterOption {
+	return AdapterOption{apply: func(a *Adapter) {
+		a.conflictPolicy = policy
+	}}
+}
+
+// AddPoliciesOnConflict adds policy rules to the storage like AddPolicies,
+// handling the rules that are already stored according to policy, and
+// returns the number of rules actually inserted. With the unique index the
+// conflicts are resolved by the database, using INSERT IGNORE or REPLACE
+// and their equivalents, otherwise, when history is enabled and on the
+// databases without them, the stored rules are looked up first.
+func (a *Adapter) AddPoliciesOnConflict(sec string, pType string, rules [][]string, policy ConflictPolicy) (int64, error) {
+	if g := a.groupingFor(pType); g != nil {
+		return g.AddPoliciesOnConflict(sec, pType, rules, policy)
+	}
+	if len(rules) == 0 {
+		return 0, nil
+	}
+
+	dbRules := a.opRules(pType, rules...)
+	op := Operation{Method: "AddPoliciesOnConflict", Sec: sec, PType: pType, Rules: dbRules}
+
+	var inserted int64
+	err := a.mutate(a.ctx, op, func(ctx context.Context) error {
+		return a.transaction(ctx, func(ctx context.Context, tx gdb.TX) error {
+			var err error
+			inserted, err = a.insertRulesOnConflict(ctx, dbRules, policy)
+			return err
+		})
+	}, nil)
+	if err != nil {
+		return 0, fmt.Errorf("failed to add policies: %w", err)
+	}
+	return inserted, nil
+}
+
+// insertRulesOnConflict inserts rules resolving the conflicts with the
+// stored rules according to policy.
+func (a *Adapter) insertRulesOnConflict(ctx context.Context, rules []Rule, policy ConflictPolicy) (int64, error) {
+	if err := a.checkQuota(ctx, len(rules), false); err != nil {
+		return 0, err
+	}
+	switch {
+	case policy == ConflictError:
+		return int64(len(rules)), a.insertRules(ctx, rules)
+	case a.uniqueIndex && a.historyTable == "" && a.dialect.resolvesConflicts():
+		return a.insertRulesResolved(ctx, rules, policy)
+	}
+
+	fresh, ids, err := a.resolveConflicts(ctx, rules, policy)
+	if err != nil {
+		return 0, err
+	}
+	size := a.idsSize()
+	for i := 0; i < len(ids); i += size {
+		if err := ctx.Err(); err != nil {
+			return 0, err
+		}
+		end := i + size
+		if end > len(ids) {
+			end = len(ids)
+		}
+		if err := a.deleteRules(ctx, a.modelCtx(ctx).WhereIn("id", ids[i:end])); err != nil {
+			return 0, fmt.Errorf("failed to delete replaced rules: %w", err)
+		}
+	}
+	return int64(len(fresh)), a.insertRules(ctx, fresh)
+}
+
+// resolveConflicts looks up the stored rows of rules and returns the rules
+// to insert under policy, without the repeated ones and, unless replaced,
+// the stored ones, along with the ids of the rows they replace.
+func (a *Adapter) resolveConflicts(ctx context.Context, rules []Rule, policy ConflictPolicy) ([]Rule, []int64, error) {
+	stored, err := a.storedRows(ctx, rules)
+	if err != nil {
+		return nil, nil, err
+	}
+	exists := make(map[ruleKey]bool, len(rules))
+	var replaced []int64
+	for _, row := range stored {
+		if policy == ConflictReplace {
+			replaced = append(replaced, row.Id)
+		} else {
+			exists[row.key()] = true
+		}
+	}
+
+	fresh := make([]Rule, 0, len(rules))
+	for _, rule := range rules {
+		if !exists[rule.key()] {
+			exists[rule.key()] = true
+			fresh = append(fresh, rule)
+		}
+	}
+	return fresh, replaced, nil
+}
+
+// insertRulesResolved inserts rules in batches and lets the unique index
+// resolve the conflicts.
+func (a *Adapter) insertRulesResolved(ctx context.Context, rules []Rule, policy ConflictPolicy) (int64, error) {
+	defer a.invalidateCache()
+	// An ON CONFLICT clause can't update a row twice, the repeated rules of
+	// a replace are dropped first.
+	if policy == ConflictReplace && a.dialect.conflictSql(policy, nil, nil) != "" {
+		rules = distinctRules(rules)
+	}
+	var inserted int64
+	size := a.insertSize()
+	for i := 0; i < len(rules); i += size {
+		if err := ctx.Err(); err != nil {
+			return inserted, err
+		}
+		end := i + size
+		if end > len(rules) {
+			end = len(rules)
+		}
+		batch := make(g.List, 0, end-i)
+		for _, rule := range rules[i:end] {
+			batch = append(batch, a.ruleRecord(ctx, rule))
+		}
+
+		var (
+			result sql.Result
+			err    error
+		)
+		switch {
+		case a.dialect.conflictSql(policy, nil, nil) != "":
+			result, err = a.insertClause(ctx, batch, policy)
+		case policy == ConflictSkip:
+			result, err = a.modelCtx(ctx).Data(batch).InsertIgnore()
+		default:
+			result, err = a.modelCtx(ctx).Data(batch).Replace()
+		}
+		if err != nil {
+			return 0, fmt.Errorf("failed to insert rules batch: %w", err)
+		}
+		reportProgress(ctx, len(batch))
+
+		// A replaced rule counts twice on MySQL, every rule is written.
+		if policy == ConflictReplace {
+			inserted += int64(len(batch))
+			continue
+		}
+		affected, err := result.RowsAffected()
+		if err != nil {
+			return 0, fmt.Errorf("failed to count inserted rules: %w", err)
+		}
+		inserted += affected
+	}
+	return inserted, nil
+}
+
+// distinctRules returns rules without the repeated ones.
+func distinctRules(rules []Rule) []Rule {
+	seen := make(map[ruleKey]bool, len(rules))
+	distinct := make([]Rule, 0, len(rules))
+	for _, rule := range rules {
+		if !seen[rule.key()] {
+			seen[rule.key()] = true
+			distinct = append(distinct, rule)
+		}
+	}
+	return distinct
+}
+
+// insertClause inserts batch by a statement ending with the conflict clause
+// of the dialect. The records are encoded and the statement counted like
+// the inserts of modelCtx.
+func (a *Adapter) insertClause(ctx context.Context, batch g.List, policy ConflictPolicy) (sql.Result, error) {
+	columns, values, args, err := a.insertValuesSql(batch)
+	if err != nil {
+		return nil, err
+	}
+	query := fmt.Sprintf("INSERT INTO %s (%s) VALUES %s %s", a.tableName, strings.Join(columns, ", "), values,
+		a.dialect.conflictSql(policy, a.uniqueColumns(), columns))
+	result, err := a.exec(ctx, a.tx, query, args...)
+	if a.measured() {
+		return countAffected(ctx, result, err)
+	}
+	return result, err
+}
+
+// insertValuesSql returns the sorted columns of the records of batch, which
+// share their columns, the VALUES rows binding them and their arguments.
+// The values are encoded by the codec of the adapter.
+func (a *Adapter) insertValuesSql(batch g.List) (columns []string, values string, args []interface{}, err error) {
+	if len(batch) == 0 {
+		return nil, "", nil, errors.New("no records to insert")
+	}
+	columns = make([]string, 0, len(batch[0]))
+	for column := range batch[0] {
+		columns = append(columns, column)
+	}
+	sort.Strings(columns)
+	placeholders := "(" + strings.TrimSuffix(strings.Repeat("?, ", len(columns)), ", ") + ")"
+	rows := make([]string, 0, len(batch))
+	args = make([]interface{}, 0, len(batch)*len(columns))
+	for _, record := range batch {
+		if a.codec != nil {
+			encoded, err := a.encodeRecord(record)
+			if err != nil {
+				return nil, "", nil, err
+			}
+			record = encoded
+		}
+		for _, column := range columns {
+			args = append(args, record[column])
+		}
+		rows = append(rows, placeholders)
+	}
+	return columns, strings.Join(rows, ", "), args, nil
+}
+
+// storedRows returns the stored rows holding one of rules.
+func (a *Adapter) storedRows(ctx context.Context, rules []Rule) ([]ruleRow, error) {
+	wanted := make(map[ruleKey]bool, len(rules))
+	for _, rule := range rules {
+		wanted[rule.key()] = true
+	}
+
+	var (
+		stored []ruleRow
+		read   = make(map[int64]bool)
+		size   = a.groupSize()
+	)
+	for i := 0; i < len(rules); i += size {
+		if err := ctx.Err(); err != nil {
+			return nil, err
+		}
+		end := i + size
+		if end > len(rules) {
+			end = len(rules)
+		}
+		// The conditions also match the rules extending one of rules.
+		var rows []ruleRow
+		query, args, err := a.rulesCondition(rules[i:end])
+		if err != nil {
+			return nil, fmt.Errorf("failed to scan stored rules: %w", err)
+		}
+		if err := a.modelCtx(ctx).Where(query, args...).OrderAsc("id").Scan(&rows); err != nil {
+			return nil, fmt.Errorf("failed to scan stored rules: %w", err)
+		}
+		for _, row := range rows {
+			if wanted[row.key()] && !read[row.Id] {
+				read[row.Id] = true
+				stored = append(stored, row)
+			}
+		}
+	}
+	return stored, nil
+}
+
+// createUniqueIndex creates the unique index of WithUniqueIndex when it
+// doesn't exist.
+func (a *Adapter) createUniqueIndex(ctx context.Context) error {
+	_, err := a.db.Exec(ctx, a.dialect.createUniqueIndexSql(a.tableName, a.uniqueColumns()))
+	if err != nil && !isDuplicateColumnError(err) {
+		return fmt.Errorf("failed to create unique index, remove duplicate rules with Deduplicate first: %w", err)
+	}
+	return nil
+}
+
+// uniqueColumns returns the columns of the unique index of WithUniqueIndex.
+func (a *Adapter) uniqueColumns() []string {
+	columns := []string{a.pTypeColumn, Columns.V0, Columns.V1, Columns.V2, Columns.V3, Columns.V4, Columns.V5}
+	if a.ruleHash {
+		columns = []string{ruleHashColumn}
+	}
+	if a.tenantColumn != "" {
+		columns = append(columns, a.tenantColumn)
+	}
+	return columns
+}
+
+// valueColumn reports whether column is one of v0 to v5.
+func valueColumn(column string) bool {
+	return len(column) == 2 && column[0] == 'v' && strings.ContainsRune("012345", rune(column[1]))
+}
@@ -0,0 +1,97 @@
+package adapter
+
+import (
+	"context"
+	"reflect"
+	"testing"
+)
+
+func TestAddPoliciesOnConflictSkip(t *testing.T) {
+	rules := [][]string{{"alice", "data1", "read"}, {"bob", "data2", "write"}, {"bob", "data2", "write"}}
+
+	for name, opts := range map[string][]AdapterOption{
+		"unique index": {WithUniqueIndex(), WithConflictPolicy(ConflictSkip)},
+		// History looks up the stored rules instead of relying on the index.
+		"history":  {WithUniqueIndex(), WithConflictPolicy(ConflictSkip), WithHistory()},
+		"no index": {WithConflictPolicy(ConflictSkip)},
+	} {
+		t.Run(name, func(t *testing.T) {
+			a := newSqliteAdapter(t, opts...)
+			for run, want := range []int64{2, 0} {
+				inserted, err := a.AddPoliciesOnConflict("p", "p", rules, ConflictSkip)
+				if err != nil {
+					t.Fatalf("run %d: AddPoliciesOnConflict failed: %v", run, err)
+				}
+				if inserted != want {
+					t.Errorf("run %d: inserted %d rules, supposed to be %d", run, inserted, want)
+				}
+			}
+			// AddPolicies uses the conflict policy of the adapter.
+			if err := a.AddPolicies("p", "p", rules); err != nil {
+				t.Fatalf("AddPolicies failed: %v", err)
+			}
+			if ids := storedIDs(t, a); len(ids) != 2 {
+				t.Errorf("stored ids %v, supposed to be 2 rows", ids)
+			}
+		})
+	}
+}
+
+func TestAddPoliciesOnConflictError(t *testing.T) {
+	a := newSqliteAdapter(t, WithUniqueIndex())
+	initPolicy(t, a)
+
+	err := a.AddPolicies("p", "p", [][]string{{"carol", "data3", "read"}, {"alice", "data1", "read"}})
+	if err == nil {
+		t.Fatal("expected the duplicate rule to fail the add")
+	}
+	if ids := storedIDs(t, a); !reflect.DeepEqual(ids, []int64{1, 2, 3, 4, 5}) {
+		t.Errorf("stored ids %v, supposed to be [1 2 3 4 5]", ids)
+	}
+}
+
+func TestAddPoliciesOnConflictReplace(t *testing.T) {
+	for name, opts := range map[string][]AdapterOption{
+		"unique index": {WithUniqueIndex()},
+		"no index":     nil,
+	} {
+		t.Run(name, func(t *testing.T) {
+			a := newSqliteAdapter(t, opts...)
+			initPolicy(t, a)
+
+			inserted, err := a.AddPoliciesOnConflict("p", "p", [][]string{{"alice", "data1", "read"}, {"carol", "data3", "read"}}, ConflictReplace)
+			if err != nil {
+				t.Fatalf("AddPoliciesOnConflict failed: %v", err)
+			}
+			if inserted != 2 {
+				t.Errorf("inserted %d rules, supposed to be 2", inserted)
+			}
+			// alice's rule moved to a new row.
+			if ids := storedIDs(t, a); !reflect.DeepEqual(ids, []int64{2, 3, 4, 5, 6, 7}) {
+				t.Errorf("stored ids %v, supposed to be [2 3 4 5 6 7]", ids)
+			}
+		})
+	}
+}
+
+func TestUniqueIndexRequiresDistinctRules(t *testing.T) {
+	db := newSqliteDB(t)
+	a, err := NewAdapter(context.Background(), "", "", db)
+	if err != nil {
+		t.Fatalf("NewAdapter failed: %v", err)
+	}
+	initPolicy(t, a)
+	if err := a.AddPolicy("p", "p", []string{"alice", "data1", "read"}); err != nil {
+		t.Fatalf("AddPolicy failed: %v", err)
+	}
+
+	if _, err := NewAdapter(context.Background(), "", "", db, WithUniqueIndex()); err == nil {
+		t.Error("expected the unique index to fail on duplicate rules")
+	}
+	if _, err := a.Deduplicate(a.ctx); err != nil {
+		t.Fatalf("Deduplicate failed: %v", err)
+	}
+	if _, err := NewAdapter(context.Background(), "", "", db, WithUniqueIndex()); err != nil {
+		t.Errorf("NewAdapter failed: %v", err)
+	}
+}
@@ -0,0 +1,72 @@
+package adapter
+
+import (
+	"context"
+	"errors"
+	"fmt"
+
+	"github.com/gogf/gf/v2/database/gdb"
+)
+
+// CopyTo copies the stored rules to the table of dest and returns the number
+// of copied rules. dest may use another table, database group or dialect.
+// The rules are read in pages of the source page size and inserted in
+// batches of the destination batch size, in one transaction of dest. Values
+// are copied verbatim including empty ones, ids are not preserved. When
+// replace is true the rules visible to dest are deleted first. A non-nil
+// filter restricts the copied rules like LoadFilteredPolicy does.
+func (a *Adapter) CopyTo(ctx context.Context, dest *Adapter, filter *Filter, replace bool) (copied int, err error) {
+	if dest == nil {
+		return 0, errors.New("destination adapter cannot be nil")
+	}
+	ctx = a.startProgress(ctx, "CopyTo", -1)
+	err = dest.mutate(ctx, Operation{Method: "CopyTo"}, func(ctx context.Context) (err error) {
+		copied, err = a.copyTo(ctx, dest, filter, replace)
+		return err
+	}, nil)
+	return copied, err
+}
+
+func (a *Adapter) copyTo(ctx context.Context, dest *Adapter, filter *Filter, replace bool) (copied int, err error) {
+
+	err = dest.transaction(ctx, func(txCtx context.Context, tx gdb.TX) error {
+		if replace {
+			if err := dest.deleteRows(txCtx, dest.modelCtx(txCtx).Where("1=1")); err != nil {
+				return fmt.Errorf("failed to delete rules: %w", err)
+			}
+			if err := dest.recordHistory(txCtx, historyOpReset, nil); err != nil {
+				return err
+			}
+		}
+
+		// Transactions are bound to the context by database group, so the
+		// source is only read in the transaction when it shares the database.
+		sourceCtx := ctx
+		if a.db == dest.db {
+			sourceCtx = txCtx
+		}
+		query := a.modelCtx(sourceCtx)
+		if filter != nil {
+			var err error
+			if query, err = a.filterQuery(query, *filter); err != nil {
+				return err
+			}
+		}
+
+		return a.scanPages(query, func(rows []ruleRow) error {
+			rules := make([]Rule, 0, len(rows))
+			for _, row := range rows {
+				rules = append(rules, row.Rule)
+			}
+			if err := dest.insertRules(txCtx, rules); err != nil {
+				return err
+			}
+			copied += len(rules)
+			return nil
+		})
+	})
+	if err != nil {
+		return 0, fmt.Errorf("failed to copy rules: %w", err)
+	}
+	return copied, nil
+}
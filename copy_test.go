@@ -0,0 +1,72 @@
+package adapter
+
+import (
+	"context"
+	"reflect"
+	"testing"
+)
+
+func TestCopyTo(t *testing.T) {
+	ctx := context.Background()
+	src := newSqliteAdapter(t, WithPageSize(2))
+	initPolicy(t, src)
+	// Empty values are copied verbatim.
+	if err := src.AddPolicy("p", "p", []string{"carol", "", "read"}); err != nil {
+		t.Fatalf("AddPolicy failed: %v", err)
+	}
+
+	dst := newSqliteAdapter(t, WithTenantColumn("tenant_id")).ForTenant("staging")
+	if err := dst.AddPolicy("p", "p", []string{"stale", "data9", "read"}); err != nil {
+		t.Fatalf("AddPolicy failed: %v", err)
+	}
+
+	copied, err := src.CopyTo(ctx, dst, nil, true)
+	if err != nil {
+		t.Fatalf("CopyTo failed: %v", err)
+	}
+	if copied != 6 {
+		t.Errorf("copied %d rules, supposed to be 6", copied)
+	}
+
+	want, err := src.GetAllPolicies(ctx, nil)
+	if err != nil {
+		t.Fatalf("GetAllPolicies failed: %v", err)
+	}
+	got, err := dst.GetAllPolicies(ctx, nil)
+	if err != nil {
+		t.Fatalf("GetAllPolicies failed: %v", err)
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("copied rules %v, supposed to be %v", got, want)
+	}
+
+	// Appending a filtered copy keeps the copied rules.
+	filter := &Filter{PType: []string{"g"}}
+	if copied, err := src.CopyTo(ctx, dst, filter, false); err != nil || copied != 1 {
+		t.Errorf("copied %d rules, err: %v, supposed to be 1", copied, err)
+	}
+	got, _ = dst.GetAllPolicies(ctx, filter)
+	if len(got) != 2 {
+		t.Errorf("copied grouping rules %v, supposed to be 2 copies", got)
+	}
+}
+
+func TestCopyToSameDatabase(t *testing.T) {
+	ctx := context.Background()
+	src := newSqliteAdapter(t)
+	initPolicy(t, src)
+
+	dst, err := src.WithTable("casbin_rule_copy")
+	if err != nil {
+		t.Fatalf("WithTable failed: %v", err)
+	}
+	if _, err := src.CopyTo(ctx, dst, nil, true); err != nil {
+		t.Fatalf("CopyTo failed: %v", err)
+	}
+
+	want, _ := src.GetAllPolicies(ctx, nil)
+	got, _ := dst.GetAllPolicies(ctx, nil)
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("copied rules %v, supposed to be %v", got, want)
+	}
+}
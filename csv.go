@@ -0,0 +1,258 @@
+package adapter
+
+import (
+	"bufio"
+	"context"
+	"encoding/csv"
+	"errors"
+	"fmt"
+	"io"
+	"strings"
+
+	"github.com/gogf/gf/v2/database/gdb"
+)
+
+// ImportOptions configures ImportCSV and ImportPolicies.
+type ImportOptions struct {
+	// Replace deletes the stored rules visible to the adapter before the
+	// import, otherwise the imported rules are appended. The deletion is
+	// confirmed by the hook of WithDestructiveGuard, and importing no rules
+	// is refused with ErrRefusingEmptySave unless WithAllowEmptySave is set.
+	Replace bool
+	// SkipDuplicates skips the rules that are repeated in the input or, when
+	// appending, already stored.
+	SkipDuplicates bool
+	// DryRun validates the input and counts the rules that would be inserted
+	// without writing anything.
+	DryRun bool
+
+	// BestEffort makes ImportPolicies insert the valid rows when others are
+	// rejected, instead of writing nothing.
+	BestEffort bool
+	// Validator rejects the rows of ImportPolicies it returns an error for.
+	Validator func(values []string) error
+}
+
+// ImportLineError reports a malformed line of an imported policy file.
+type ImportLineError struct {
+	Line int
+	Err  error
+}
+
+func (e *ImportLineError) Error() string {
+	return fmt.Sprintf("line %d: %v", e.Line, e.Err)
+}
+
+func (e *ImportLineError) Unwrap() error {
+	return e.Err
+}
+
+// ExportCSV writes the stored rules to w in the format of casbin's file
+// adapter, one "ptype, v0, v1, ..." line per rule, so that the output can be
+// loaded by the file adapter or committed as a policy file. The p rules are
+// written before the g rules, each in the order they were added. Values
+// containing commas, quotes or surrounding spaces are quoted. A non-nil
+// filter restricts the exported rules like LoadFilteredPolicy does. The
+// rules are read in pages of the page size, so large tables are streamed.
+func (a *Adapter) ExportCSV(ctx context.Context, w io.Writer, filter *Filter) error {
+	bw := bufio.NewWriter(w)
+
+	for _, sec := range policySections {
+		query := a.modelCtx(ctx).WhereLike(a.pTypeColumn, sec+"%")
+		if filter != nil {
+			var err error
+			if query, err = a.filterQuery(query, *filter); err != nil {
+				return err
+			}
+		}
+
+		err := a.scanPages(query, func(rows []ruleRow) error {
+			for _, row := range rows {
+				if err := writeCSVLine(bw, row.Rule); err != nil {
+					return fmt.Errorf("failed to write rule: %w", err)
+				}
+			}
+			return nil
+		})
+		if err != nil {
+			return fmt.Errorf("failed to export %s rules: %w", sec, err)
+		}
+	}
+
+	if err := bw.Flush(); err != nil {
+		return fmt.Errorf("failed to write rules: %w", err)
+	}
+	return nil
+}
+
+// writeCSVLine writes rule as a line of a casbin policy file.
+func writeCSVLine(w *bufio.Writer, rule Rule) error {
+	values := rule.toSlice()
+	if len(values) == 0 {
+		return nil
+	}
+
+	fields := make([]string, 0, len(values)+1)
+	fields = append(fields, csvField(rule.PType))
+	for _, value := range values {
+		fields = append(fields, csvField(value))
+	}
+
+	_, err := w.WriteString(strings.Join(fields, ", ") + "\n")
+	return err
+}
+
+// csvField quotes value when casbin's policy file parser would otherwise
+// split or trim it.
+func csvField(value string) string {
+	if strings.ContainsAny(value, ",\"\r\n") || strings.TrimSpace(value) != value || strings.HasPrefix(value, "#") {
+		return `"` + strings.ReplaceAll(value, `"`, `""`) + `"`
+	}
+	return value
+}
+
+// ImportCSV reads rules in the format of casbin's file adapter from r and
+// inserts them in batches of the batch size, returning the number of
+// inserted rules. Blank lines and lines starting with "#" are ignored. The
+// whole input is validated first, and if any line is malformed nothing is
+// written and the returned error joins an *ImportLineError per bad line.
+// The import runs in one transaction.
+func (a *Adapter) ImportCSV(ctx context.Context, r io.Reader, opts ImportOptions) (inserted int, err error) {
+	rules, err := parseCSV(r)
+	if err != nil {
+		return 0, err
+	}
+
+	return a.importHooked(ctx, "ImportCSV", rules, opts)
+}
+
+// importHooked imports rules like importRules, calling the hooks around it.
+func (a *Adapter) importHooked(ctx context.Context, method string, rules []Rule, opts ImportOptions) (inserted int, err error) {
+	for i := range rules {
+		rules[i] = a.normalizeRule(rules[i])
+	}
+	err = a.mutate(ctx, Operation{Method: method, Rules: rules}, func(ctx context.Context) (err error) {
+		inserted, err = a.importRules(ctx, method, rules, opts)
+		return err
+	}, nil)
+	return inserted, err
+}
+
+// importRules writes imported rules according to opts and returns the
+// number of inserted rules. The replaced rules are confirmed by the hook of
+// WithDestructiveGuard as deleted by method.
+func (a *Adapter) importRules(ctx context.Context, method string, rules []Rule, opts ImportOptions) (int, error) {
+	if opts.SkipDuplicates {
+		seen := make(map[ruleKey]struct{}, len(rules))
+		if !opts.Replace {
+			err := a.scanPages(a.modelCtx(ctx), func(rows []ruleRow) error {
+				for _, row := range rows {
+					seen[row.key()] = struct{}{}
+				}
+				return nil
+			})
+			if err != nil {
+				return 0, fmt.Errorf("failed to read stored rules: %w", err)
+			}
+		}
+
+		unique := rules[:0]
+		for _, rule := range rules {
+			if _, ok := seen[rule.key()]; ok {
+				continue
+			}
+			seen[rule.key()] = struct{}{}
+			unique = append(unique, rule)
+		}
+		rules = unique
+		setProgressTotal(ctx, len(rules))
+	}
+
+	if opts.DryRun {
+		return len(rules), nil
+	}
+	if opts.Replace {
+		if err := a.checkEmptyReplace(ctx, rules); err != nil {
+			return 0, fmt.Errorf("failed to import rules: %w", err)
+		}
+		if err := a.confirmQuery(ctx, method, a.modelCtx(ctx)); err != nil {
+			return 0, fmt.Errorf("failed to import rules: %w", err)
+		}
+	}
+	if err := a.checkQuota(ctx, len(rules), opts.Replace); err != nil {
+		return 0, fmt.Errorf("failed to import rules: %w", err)
+	}
+
+	err := a.transaction(ctx, func(ctx context.Context, tx gdb.TX) error {
+		if opts.Replace {
+			if err := a.deleteRows(ctx, a.modelCtx(ctx).Where("1=1")); err != nil {
+				return fmt.Errorf("failed to delete rules: %w", err)
+			}
+			if err := a.recordHistory(ctx, historyOpReset, nil); err != nil {
+				return err
+			}
+		}
+		return a.insertRules(ctx, rules)
+	})
+	if err != nil {
+		return 0, fmt.Errorf("failed to import rules: %w", err)
+	}
+	return len(rules), nil
+}
+
+// parseCSV parses the lines of a casbin policy file the same way as the
+// file adapter does.
+func parseCSV(r io.Reader) ([]Rule, error) {
+	var (
+		rules []Rule
+		errs  []error
+	)
+
+	scanner := bufio.NewScanner(r)
+	for line := 1; scanner.Scan(); line++ {
+		text := strings.TrimSpace(scanner.Text())
+		if text == "" || strings.HasPrefix(text, "#") {
+			continue
+		}
+
+		rule, err := parseCSVLine(text)
+		if err != nil {
+			errs = append(errs, &ImportLineError{Line: line, Err: err})
+			continue
+		}
+		rules = append(rules, rule)
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, fmt.Errorf("failed to read rules: %w", err)
+	}
+
+	if len(errs) > 0 {
+		return nil, errors.Join(errs...)
+	}
+	return rules, nil
+}
+
+// parseCSVLine parses a single "ptype, v0, v1, ..." line.
+func parseCSVLine(line string) (Rule, error) {
+	reader := csv.NewReader(strings.NewReader(line))
+	reader.Comma = ','
+	reader.TrimLeadingSpace = true
+
+	tokens, err := reader.Read()
+	if err != nil {
+		return Rule{}, err
+	}
+
+	return importedRule(tokens[0], tokens[1:])
+}
+
+// importedRule validates an imported rule and builds it.
+func importedRule(pType string, values []string) (Rule, error) {
+	if !strings.HasPrefix(pType, "p") && !strings.HasPrefix(pType, "g") {
+		return Rule{}, fmt.Errorf("invalid policy type: %q", pType)
+	}
+	if len(values) == 0 || len(values) > maxFieldIndex+1 {
+		return Rule{}, fmt.Errorf("rule should have 1 to %d values, got %d", maxFieldIndex+1, len(values))
+	}
+	return newRule(pType, values), nil
+}
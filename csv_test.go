@@ -0,0 +1,195 @@
+package adapter
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/casbin/casbin/v2"
+)
+
+// exportToFile exports the rules matching filter to a policy file.
+func exportToFile(t *testing.T, a *Adapter, filter *Filter) string {
+	t.Helper()
+	var buf bytes.Buffer
+	if err := a.ExportCSV(context.Background(), &buf, filter); err != nil {
+		t.Fatalf("ExportCSV failed: %v", err)
+	}
+	path := filepath.Join(t.TempDir(), "policy.csv")
+	if err := os.WriteFile(path, buf.Bytes(), 0o644); err != nil {
+		t.Fatalf("failed to write policy file: %v", err)
+	}
+	return path
+}
+
+func TestExportCSVRoundTrip(t *testing.T) {
+	// A small page size makes the export read several pages.
+	a := newSqliteAdapter(t, WithPageSize(2))
+	initPolicy(t, a)
+	err := a.AddPolicies("p", "p", [][]string{
+		{"carol", "data,3", "read"},
+		{"dave", `say "hi"`, " write "},
+		{"erin", "#data4", "read"},
+	})
+	if err != nil {
+		t.Fatalf("AddPolicies failed: %v", err)
+	}
+
+	e, err := casbin.NewEnforcer("examples/rbac_model.conf", exportToFile(t, a, nil))
+	if err != nil {
+		t.Fatalf("failed to load exported policy: %v", err)
+	}
+	testGetPolicy(t, e, [][]string{
+		{"alice", "data1", "read"},
+		{"bob", "data2", "write"},
+		{"data2_admin", "data2", "read"},
+		{"data2_admin", "data2", "write"},
+		{"carol", "data,3", "read"},
+		{"dave", `say "hi"`, " write "},
+		{"erin", "#data4", "read"},
+	})
+	if res, _ := e.GetGroupingPolicy(); !arrayEqualsWithoutOrder(res, [][]string{{"alice", "data2_admin"}}) {
+		t.Errorf("grouping policy: %v, supposed to be [[alice data2_admin]]", res)
+	}
+}
+
+func TestExportCSVSectionOrder(t *testing.T) {
+	a := newSqliteAdapter(t)
+	if err := a.AddPolicy("g", "g", []string{"alice", "admin"}); err != nil {
+		t.Fatalf("AddPolicy failed: %v", err)
+	}
+	if err := a.AddPolicy("p", "p", []string{"admin", "data1", "read"}); err != nil {
+		t.Fatalf("AddPolicy failed: %v", err)
+	}
+
+	var buf bytes.Buffer
+	if err := a.ExportCSV(context.Background(), &buf, nil); err != nil {
+		t.Fatalf("ExportCSV failed: %v", err)
+	}
+	want := "p, admin, data1, read\ng, alice, admin\n"
+	if buf.String() != want {
+		t.Errorf("exported %q, supposed to be %q", buf.String(), want)
+	}
+}
+
+func TestExportCSVFiltered(t *testing.T) {
+	a := newSqliteAdapter(t)
+	initPolicy(t, a)
+
+	path := exportToFile(t, a, &Filter{PType: []string{"p"}, V1: []string{"data2"}})
+	e, err := casbin.NewEnforcer("examples/rbac_model.conf", path)
+	if err != nil {
+		t.Fatalf("failed to load exported policy: %v", err)
+	}
+	testGetPolicyWithoutOrder(t, e, [][]string{{"bob", "data2", "write"}, {"data2_admin", "data2", "read"}, {"data2_admin", "data2", "write"}})
+	if res, _ := e.GetGroupingPolicy(); len(res) != 0 {
+		t.Errorf("grouping policy: %v, supposed to be empty", res)
+	}
+}
+
+// importFile imports a policy file into the adapter.
+func importFile(t *testing.T, a *Adapter, path string, opts ImportOptions) int {
+	t.Helper()
+	f, err := os.Open(path)
+	if err != nil {
+		t.Fatalf("failed to open policy file: %v", err)
+	}
+	defer f.Close()
+
+	inserted, err := a.ImportCSV(context.Background(), f, opts)
+	if err != nil {
+		t.Fatalf("ImportCSV failed: %v", err)
+	}
+	return inserted
+}
+
+func TestImportCSV(t *testing.T) {
+	a := newSqliteAdapter(t, AdapterOption{BatchSize: 2})
+
+	if inserted := importFile(t, a, "examples/rbac_policy.csv", ImportOptions{}); inserted != 5 {
+		t.Errorf("inserted %d rules, supposed to be 5", inserted)
+	}
+
+	e, err := casbin.NewEnforcer("examples/rbac_model.conf", a)
+	if err != nil {
+		t.Fatalf("NewEnforcer failed: %v", err)
+	}
+	testGetPolicy(t, e, [][]string{{"alice", "data1", "read"}, {"bob", "data2", "write"}, {"data2_admin", "data2", "read"}, {"data2_admin", "data2", "write"}})
+	if res, _ := e.GetGroupingPolicy(); !arrayEqualsWithoutOrder(res, [][]string{{"alice", "data2_admin"}}) {
+		t.Errorf("grouping policy: %v, supposed to be [[alice data2_admin]]", res)
+	}
+
+	// Appending again only inserts the rules that aren't stored yet.
+	in := "p, alice, data1, read\np, carol, \"data,3\", read\np, carol, \"data,3\", read\n"
+	inserted, err := a.ImportCSV(context.Background(), strings.NewReader(in), ImportOptions{SkipDuplicates: true})
+	if err != nil {
+		t.Fatalf("ImportCSV failed: %v", err)
+	}
+	if inserted != 1 {
+		t.Errorf("inserted %d rules, supposed to be 1", inserted)
+	}
+
+	// Replacing drops the stored rules first.
+	if inserted := importFile(t, a, "examples/rbac_with_domains_policy.csv", ImportOptions{Replace: true}); inserted != 8 {
+		t.Errorf("inserted %d rules, supposed to be 8", inserted)
+	}
+	e, err = casbin.NewEnforcer("examples/rbac_with_domains_model.conf", a)
+	if err != nil {
+		t.Fatalf("NewEnforcer failed: %v", err)
+	}
+	file, err := casbin.NewEnforcer("examples/rbac_with_domains_model.conf", "examples/rbac_with_domains_policy.csv")
+	if err != nil {
+		t.Fatalf("failed to load policy file: %v", err)
+	}
+	want, _ := file.GetPolicy()
+	testGetPolicyWithoutOrder(t, e, want)
+}
+
+func TestImportCSVDryRun(t *testing.T) {
+	a := newSqliteAdapter(t)
+	initPolicy(t, a)
+
+	in := strings.Join([]string{
+		"# seed policy",
+		"p, alice, data1, read",
+		"",
+		"x, alice, data1, read",
+		"p, erin, data3, read",
+		"p",
+		"g, \"bob, data2_admin",
+	}, "\n")
+
+	_, err := a.ImportCSV(context.Background(), strings.NewReader(in), ImportOptions{DryRun: true})
+	if err == nil {
+		t.Fatal("expected an error for the malformed lines")
+	}
+	var lines []int
+	for _, err := range err.(interface{ Unwrap() []error }).Unwrap() {
+		var lineErr *ImportLineError
+		if !errors.As(err, &lineErr) {
+			t.Fatalf("unexpected error type %T", err)
+		}
+		lines = append(lines, lineErr.Line)
+	}
+	if !reflect.DeepEqual(lines, []int{4, 6, 7}) {
+		t.Errorf("malformed lines %v, supposed to be [4 6 7]", lines)
+	}
+
+	// A valid input is counted but not written.
+	valid := "p, alice, data1, read\np, erin, data3, read\n"
+	inserted, err := a.ImportCSV(context.Background(), strings.NewReader(valid), ImportOptions{DryRun: true, SkipDuplicates: true})
+	if err != nil {
+		t.Fatalf("ImportCSV failed: %v", err)
+	}
+	if inserted != 1 {
+		t.Errorf("would insert %d rules, supposed to be 1", inserted)
+	}
+
+	e, _ := casbin.NewEnforcer("examples/rbac_model.conf", a)
+	testGetPolicy(t, e, [][]string{{"alice", "data1", "read"}, {"bob", "data2", "write"}, {"data2_admin", "data2", "read"}, {"data2_admin", "data2", "write"}})
+}
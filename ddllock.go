@@ -0,0 +1,69 @@
+package adapter
+
+import (
+	"context"
+	"crypto/sha1"
+	"encoding/hex"
+	"fmt"
+	"time"
+)
+
+const (
+	// ddlLockPrefix starts the names of the advisory locks guarding the
+	// creation of the tables.
+	ddlLockPrefix = "casbin:"
+	// maxLockNameLength is the longest lock name MySQL accepts.
+	maxLockNameLength = 64
+	// ddlLockTimeout is how long an adapter waits for another one creating
+	// or replacing the tables.
+	ddlLockTimeout = time.Minute
+)
+
+// ddlLockName returns the name of the advisory lock of table, names too
+// long for MySQL are shortened with a hash.
+func ddlLockName(table string) string {
+	name := ddlLockPrefix + table
+	if len(name) <= maxLockNameLength {
+		return name
+	}
+	sum := sha1.Sum([]byte(table))
+	return ddlLockPrefix + hex.EncodeToString(sum[:])
+}
+
+// withDDLLock runs fn holding the advisory lock of the policy table, so that
+// of the adapters starting at once only one creates or migrates the tables
+// while the others wait, and then find them ready. It is held as well while
+// the policy table is replaced, see replaceTable. fn runs without lock when
+// the database has no advisory locks.
+func (a *Adapter) withDDLLock(ctx context.Context, fn func() error) error {
+	lockSql, unlockSql := a.dialect.lockSql()
+	if lockSql == "" {
+		return fn()
+	}
+
+	// The lock belongs to the connection, so it is taken and released on
+	// the same one.
+	sqlDB, err := a.db.GetCore().Master()
+	if err != nil {
+		return fmt.Errorf("failed to get connection: %w", err)
+	}
+	conn, err := sqlDB.Conn(ctx)
+	if err != nil {
+		return fmt.Errorf("failed to get connection: %w", err)
+	}
+	defer conn.Close()
+
+	name := ddlLockName(a.tableName)
+	var locked *int
+	if err := conn.QueryRowContext(ctx, lockSql, name, int(ddlLockTimeout.Seconds())).Scan(&locked); err != nil {
+		return fmt.Errorf("failed to lock table %s: %w", a.tableName, err)
+	}
+	if locked == nil || *locked != 1 {
+		return fmt.Errorf("failed to lock table %s: timed out after %s", a.tableName, ddlLockTimeout)
+	}
+	defer func() {
+		var released *int
+		_ = conn.QueryRowContext(context.WithoutCancel(ctx), unlockSql, name).Scan(&released)
+	}()
+	return fn()
+}
@@ -0,0 +1,59 @@
+package adapter
+
+import (
+	"context"
+	"strings"
+	"sync"
+	"testing"
+
+	"github.com/casbin/casbin/v2"
+	"github.com/casbin/casbin/v2/model"
+	"github.com/gogf/gf/v2/database/gdb"
+)
+
+func TestDDLLockName(t *testing.T) {
+	if name := ddlLockName("casbin_rule"); name != "casbin:casbin_rule" {
+		t.Errorf("lock name %q, supposed to be casbin:casbin_rule", name)
+	}
+	long := strings.Repeat("t", 80)
+	name := ddlLockName(long)
+	if len(name) > maxLockNameLength || name == ddlLockName(long+"x") {
+		t.Errorf("lock name %q of a long table isn't a short unique name", name)
+	}
+}
+
+func TestConcurrentCreate(t *testing.T) {
+	testConcurrentCreate(t, newSqliteDB(t))
+}
+
+// testConcurrentCreate starts many adapters on db at once.
+func testConcurrentCreate(t *testing.T, db gdb.DB) {
+	ctx := context.Background()
+
+	// The adapters starting at once all find a ready table.
+	const replicas = 20
+	adapters := make([]*Adapter, replicas)
+	errs := make([]error, replicas)
+	var wg sync.WaitGroup
+	for i := range adapters {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			adapters[i], errs[i] = NewAdapter(ctx, "", "", db, WithUniqueIndex(), WithVersionTable())
+		}()
+	}
+	wg.Wait()
+	for i, err := range errs {
+		if err != nil {
+			t.Fatalf("adapter %d failed: %v", i, err)
+		}
+	}
+
+	m, _ := model.NewModelFromFile("examples/rbac_model.conf")
+	m.AddPolicy("p", "p", []string{"alice", "data1", "read"})
+	if err := adapters[0].SavePolicy(m); err != nil {
+		t.Fatalf("SavePolicy failed: %v", err)
+	}
+	e, _ := casbin.NewEnforcer("examples/rbac_model.conf", adapters[replicas-1])
+	testGetPolicyWithoutOrder(t, e, [][]string{{"alice", "data1", "read"}})
+}
@@ -0,0 +1,70 @@
+package adapter
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"math/rand/v2"
+	"strings"
+	"time"
+
+	"github.com/gogf/gf/v2/database/gdb"
+)
+
+// deadlockRetryDelay is the base pause before retrying a transaction, the
+// pause grows with the attempts and is jittered.
+const deadlockRetryDelay = 20 * time.Millisecond
+
+// WithDeadlockRetry retries the transactions of the changes, e.g. of
+// SavePolicy, AddPolicies, RemovePolicies and the updates, up to retries
+// times when they failed on a deadlock or a lock wait timeout. A
+// transaction holds the whole change, so it is safe to run it again. The
+// transactions of an adapter bound to an external transaction aren't
+// retried, the caller owns them.
+func WithDeadlockRetry(retries int) AdapterOption {
+	return AdapterOption{apply: func(a *Adapter) {
+		a.deadlockRetries = max(retries, 0)
+	}}
+}
+
+// runTransaction runs fn in a new transaction, retrying it up to the
+// configured times when it deadlocked.
+func (a *Adapter) runTransaction(ctx context.Context, fn func(ctx context.Context, tx gdb.TX) error) error {
+	// ClickHouse has no transactions, its statements apply one by one, as
+	// do the ones of WithoutTransactions.
+	if a.tombstones || a.noTransactions {
+		resetAffected(ctx)
+		return fn(ctx, nil)
+	}
+	for attempt := 0; ; attempt++ {
+		resetAffected(ctx)
+		err := a.db.Transaction(ctx, fn)
+		if err == nil || !isDeadlockError(err) || a.deadlockRetries == 0 {
+			return err
+		}
+		if attempt == a.deadlockRetries {
+			return fmt.Errorf("transaction failed after %d retries: %w", attempt, err)
+		}
+
+		delay := deadlockRetryDelay * time.Duration(attempt+1)
+		delay = delay/2 + rand.N(delay/2+1)
+		select {
+		case <-ctx.Done():
+			return errors.Join(err, ctx.Err())
+		case <-time.After(delay):
+		}
+	}
+}
+
+// isDeadlockError reports whether err was caused by a deadlock or a lock
+// wait timeout: the MySQL errors 1213 and 1205 and the PostgreSQL deadlock
+// and serialization failures.
+func isDeadlockError(err error) bool {
+	msg := strings.ToLower(err.Error())
+	for _, s := range []string{"error 1213", "error 1205", "deadlock", "lock wait timeout", "sqlstate 40p01", "sqlstate 40001"} {
+		if strings.Contains(msg, s) {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,81 @@
+package adapter
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+	"testing"
+
+	"github.com/casbin/casbin/v2"
+	"github.com/gogf/gf/v2/database/gdb"
+)
+
+func TestIsDeadlockError(t *testing.T) {
+	tests := []struct {
+		err  error
+		want bool
+	}{
+		{errors.New("Error 1213 (40001): Deadlock found when trying to get lock; try restarting transaction"), true},
+		{errors.New("Error 1205 (HY000): Lock wait timeout exceeded; try restarting transaction"), true},
+		{errors.New("ERROR: deadlock detected (SQLSTATE 40P01)"), true},
+		{errors.New("Error 1062 (23000): Duplicate entry 'p' for key 'idx'"), false},
+		{errors.New("no such table: casbin_rule"), false},
+	}
+	for _, tt := range tests {
+		if got := isDeadlockError(tt.err); got != tt.want {
+			t.Errorf("isDeadlockError(%q) = %v, supposed to be %v", tt.err, got, tt.want)
+		}
+	}
+}
+
+func TestDeadlockRetry(t *testing.T) {
+	ctx := context.Background()
+	a := newSqliteAdapter(t, WithDeadlockRetry(2))
+
+	// The transaction deadlocks twice and then succeeds.
+	deadlock := errors.New("Error 1213 (40001): Deadlock found when trying to get lock; try restarting transaction")
+	attempts := 0
+	err := a.transaction(ctx, func(ctx context.Context, tx gdb.TX) error {
+		attempts++
+		if err := a.insertRules(ctx, []Rule{{PType: "p", V0: "alice", V1: "data1", V2: "read"}}); err != nil {
+			return err
+		}
+		if attempts < 3 {
+			return deadlock
+		}
+		return nil
+	})
+	if err != nil {
+		t.Fatalf("transaction failed: %v", err)
+	}
+	if attempts != 3 {
+		t.Errorf("%d attempts, supposed to be 3", attempts)
+	}
+	e, _ := casbin.NewEnforcer("examples/rbac_model.conf", a)
+	testGetPolicyWithoutOrder(t, e, [][]string{{"alice", "data1", "read"}})
+
+	// Other errors aren't retried.
+	attempts = 0
+	failure := errors.New("failure")
+	err = a.transaction(ctx, func(ctx context.Context, tx gdb.TX) error {
+		attempts++
+		return failure
+	})
+	if !errors.Is(err, failure) || attempts != 1 {
+		t.Errorf("%d attempts, err: %v, supposed to be 1 attempt failing with %v", attempts, err, failure)
+	}
+
+	// A change deadlocking every time fails with the number of retries.
+	trigger := fmt.Sprintf(`CREATE TRIGGER deadlock BEFORE INSERT ON %s WHEN NEW.v0 = 'bob'
+BEGIN SELECT RAISE(ABORT, 'Deadlock found when trying to get lock; try restarting transaction'); END`, a.tableName)
+	if _, err := a.db.Exec(ctx, trigger); err != nil {
+		t.Fatalf("failed to create trigger: %v", err)
+	}
+	err = a.AddPolicies("p", "p", [][]string{{"carol", "data1", "read"}, {"bob", "data2", "write"}})
+	if err == nil || !strings.Contains(err.Error(), "after 2 retries") {
+		t.Errorf("AddPolicies returned %v, supposed to fail after 2 retries", err)
+	}
+	_ = e.LoadPolicy()
+	testGetPolicyWithoutOrder(t, e, [][]string{{"alice", "data1", "read"}})
+}
@@ -0,0 +1,142 @@
+package adapter
+
+import (
+	"fmt"
+	"maps"
+	"strings"
+	"time"
+)
+
+// AdapterInfo summarizes the configuration of an adapter, as returned by
+// DebugInfo. It holds no credentials, nor the keys of codecs, salts of
+// hashers or addresses of the database.
+type AdapterInfo struct {
+	// Table is the resolved policy table, Routes the tables of the routed
+	// rules of WithTableRouting and HistoryTable the one of WithHistory.
+	Table        string
+	Routes       map[string]string
+	HistoryTable string
+	// Group is the database group, Driver the type of the database driver.
+	Group  string
+	Driver string
+	// TenantColumn and Tenant scope the adapter to a tenant.
+	TenantColumn string
+	Tenant       string
+
+	BatchSize       int
+	PageSize        int
+	Writers         int
+	MaxTxRows       int
+	MaxRules        int64
+	DeadlockRetries int
+	CacheTTL        time.Duration
+	// Modes lists the enabled modes, such as "history" or "unique-index".
+	Modes []string
+
+	// Filtered is set when the last load was filtered, LastLoad is the
+	// last successful load, nil before the first one.
+	Filtered bool
+	LastLoad *LoadInfo
+}
+
+// DebugInfo returns the configuration of the adapter and its last load.
+func (a *Adapter) DebugInfo() AdapterInfo {
+	info := AdapterInfo{
+		Table:           a.tableName,
+		Routes:          maps.Clone(a.routes),
+		HistoryTable:    a.historyTable,
+		Group:           a.dbGroupName,
+		Driver:          a.driver(),
+		TenantColumn:    a.tenantColumn,
+		Tenant:          a.tenant,
+		BatchSize:       a.batchSize,
+		PageSize:        a.pageSize,
+		Writers:         a.writers,
+		MaxTxRows:       a.maxTxRows,
+		MaxRules:        a.maxRules,
+		DeadlockRetries: a.deadlockRetries,
+		CacheTTL:        a.cacheTTL,
+		Modes:           a.modes(),
+		Filtered:        a.isFiltered,
+	}
+	if last, ok := a.LastLoadInfo(); ok {
+		info.LastLoad = &last
+	}
+	return info
+}
+
+// String returns a single line naming the table and the driver of the
+// adapter, along with the database group and the tenant when set, like
+// adapter[table=casbin_rule driver=mysql].
+func (a *Adapter) String() string {
+	fields := []string{"table=" + a.tableName}
+	if a.dbGroupName != "" {
+		fields = append(fields, "group="+a.dbGroupName)
+	}
+	fields = append(fields, "driver="+a.driver())
+	if a.tenantColumn != "" {
+		fields = append(fields, "tenant="+a.tenant)
+	}
+	return "adapter[" + strings.Join(fields, " ") + "]"
+}
+
+// withContext prefixes err with the summary of the adapter.
+func (a *Adapter) withContext(err error) error {
+	if err == nil {
+		return nil
+	}
+	return fmt.Errorf("%s: %w", a, err)
+}
+
+// driver returns the type of the database driver.
+func (a *Adapter) driver() string {
+	if a.db == nil {
+		return ""
+	}
+	if config := a.db.GetConfig(); config != nil {
+		return config.Type
+	}
+	return ""
+}
+
+// modes returns the names of the enabled modes.
+func (a *Adapter) modes() []string {
+	var modes []string
+	for _, mode := range []struct {
+		name    string
+		enabled bool
+	}{
+		{"allow-destructive", a.allowDestructive},
+		{"allow-empty-save", a.allowEmptySave},
+		{"destructive-guard", a.guarded()},
+		{"actor-column", a.actorColumn != ""},
+		{"auto-migrate", a.autoMigrate},
+		{"history", a.historyTable != ""},
+		{"polling", a.versionTable != ""},
+		{"unique-index", a.uniqueIndex},
+		{"soft-delete", a.softDelete},
+		{"change-tracking", a.changeTracking},
+		{"sync-save", a.syncSave},
+		{"stable-save", a.stableSave},
+		{"strict-update", a.strictUpdate},
+		{"no-transactions", a.noTransactions},
+		{"cache", a.cache != nil},
+		{"watcher", a.watcher != nil},
+		{"dispatcher", a.dispatcher != nil},
+		{"codec", a.codec != nil},
+		{"field-hashing", a.hasher != nil},
+		{"normalization", a.normalization != nil},
+		{"partitioned", a.partitioned},
+		{"tombstones", a.tombstones},
+		{"single-rule-column", a.ruleColumn != ""},
+		{"json-storage", a.jsonStorage},
+		{"read-past", a.loadHint != ""},
+		{"transaction", a.tx != nil},
+		{"dry-run", a.plan != nil},
+	} {
+		if mode.enabled {
+			modes = append(modes, mode.name)
+		}
+	}
+	return modes
+}
@@ -0,0 +1,79 @@
+package adapter
+
+import (
+	"context"
+	"fmt"
+	"path/filepath"
+	"slices"
+	"strings"
+	"testing"
+
+	"github.com/casbin/casbin/v2"
+	"github.com/gogf/gf/v2/database/gdb"
+)
+
+func TestDebugInfo(t *testing.T) {
+	db, err := gdb.New(gdb.ConfigNode{
+		Type: "sqlite",
+		Name: filepath.Join(t.TempDir(), "casbin.db"),
+		User: "casbin_admin",
+		Pass: "s3cret-password",
+		Host: "db.internal.example",
+	})
+	if err != nil {
+		t.Fatalf("failed to create database connection: %v", err)
+	}
+	t.Cleanup(func() { _ = db.Close(context.Background()) })
+	a, err := NewAdapter(context.Background(), "", "", db, WithHistory(), WithUniqueIndex(),
+		WithFieldHashing([]int{0}, SaltedHasher([]byte("pepper-salt"))))
+	if err != nil {
+		t.Fatalf("failed to create adapter: %v", err)
+	}
+
+	if s := a.String(); s != "adapter[table=casbin_rule driver=sqlite]" {
+		t.Errorf("String() %q", s)
+	}
+	info := a.DebugInfo()
+	if info.Table != "casbin_rule" || info.Driver != "sqlite" || info.BatchSize != defaultBatchSize || info.LastLoad != nil {
+		t.Errorf("info %+v", info)
+	}
+	for _, mode := range []string{"history", "unique-index", "field-hashing"} {
+		if !slices.Contains(info.Modes, mode) {
+			t.Errorf("modes %v lack %s", info.Modes, mode)
+		}
+	}
+
+	e, err := casbin.NewEnforcer("examples/rbac_model.conf", a)
+	if err != nil {
+		t.Fatalf("NewEnforcer failed: %v", err)
+	}
+	if err := e.LoadPolicy(); err != nil {
+		t.Fatalf("LoadPolicy failed: %v", err)
+	}
+	if info := a.DebugInfo(); info.LastLoad == nil || info.Filtered {
+		t.Errorf("info %+v, supposed to hold the unfiltered load", info)
+	}
+
+	// The errors name the adapter.
+	if _, err := a.db.Exec(a.ctx, "DROP TABLE casbin_rule"); err != nil {
+		t.Fatalf("failed to drop table: %v", err)
+	}
+	err = a.AddPolicy("p", "p", []string{"alice", "data1", "read"})
+	if err == nil || !strings.HasPrefix(err.Error(), "adapter[table=casbin_rule driver=sqlite]: ") {
+		t.Errorf("AddPolicy err: %v, supposed to name the adapter", err)
+	}
+
+	dump := fmt.Sprintf("%s %v %+v %#v", a, a.DebugInfo(), a.DebugInfo(), a.DebugInfo())
+	for _, secret := range []string{"casbin_admin", "s3cret-password", "db.internal.example", "pepper-salt"} {
+		if strings.Contains(dump, secret) || strings.Contains(err.Error(), secret) {
+			t.Errorf("%q leaked into %s", secret, dump)
+		}
+	}
+}
+
+func TestStringTenant(t *testing.T) {
+	a := newSqliteAdapter(t, WithTenantColumn("tenant_id"))
+	if s := a.ForTenant("t1").String(); s != "adapter[table=casbin_rule driver=sqlite tenant=t1]" {
+		t.Errorf("String() %q", s)
+	}
+}
@@ -0,0 +1,148 @@
+package adapter
+
+import (
+	"context"
+	"fmt"
+	"strings"
+
+	"github.com/gogf/gf/v2/database/gdb"
+)
+
+// Deduplicate deletes the rows holding the same rule as a row with a lower
+// id, so that only the oldest row of each rule remains, and returns the
+// number of deleted rows. The rows are matched and deleted by the database
+// with a self-join in one transaction. A tenant scoped adapter only
+// deduplicates the rows of its tenant, and the tables of WithSplitTables and
+// WithTableRouting are deduplicated one by one. The rules of a single rule
+// column or of the JSON storage are matched by their stored text. On
+// ClickHouse, which merges the rows of a rule itself, nothing is deleted.
+func (a *Adapter) Deduplicate(ctx context.Context) (removed int64, err error) {
+	err = a.mutate(ctx, Operation{Method: "Deduplicate"}, func(ctx context.Context) (err error) {
+		removed, err = a.deduplicate(ctx)
+		return err
+	}, nil)
+	return removed, err
+}
+
+func (a *Adapter) deduplicate(ctx context.Context) (removed int64, err error) {
+	// The rows of a rule are merged on ClickHouse, their queries read one.
+	if a.tombstones {
+		return 0, nil
+	}
+	if a.routed() {
+		for _, table := range a.splitTables() {
+			n, err := table.deduplicate(ctx)
+			if err != nil {
+				return removed, err
+			}
+			removed += n
+		}
+		return removed, nil
+	}
+	query, args := a.duplicateIdsSql()
+
+	err = a.transaction(ctx, func(ctx context.Context, tx gdb.TX) error {
+		if a.historyTable != "" {
+			records, err := a.getAll(ctx, tx, query, args...)
+			if err != nil {
+				return fmt.Errorf("failed to find duplicate rules: %w", err)
+			}
+			if records.IsEmpty() {
+				return nil
+			}
+			var rules []Rule
+			if err := a.modelCtx(ctx).WhereIn("id", records.Array("id")).OrderAsc("id").Scan(&rules); err != nil {
+				return fmt.Errorf("failed to scan duplicate rules: %w", err)
+			}
+			if err := a.recordHistory(ctx, historyOpRemove, rules); err != nil {
+				return err
+			}
+		}
+
+		// The derived table lets MySQL delete from the table it selects from.
+		sql := fmt.Sprintf("DELETE FROM %s WHERE id IN (SELECT id FROM (%s) dup)", a.tableName, query)
+		res, err := a.exec(ctx, tx, sql, args...)
+		if err != nil {
+			return fmt.Errorf("failed to delete duplicate rules: %w", err)
+		}
+		removed, err = res.RowsAffected()
+		addAffected(ctx, removed)
+		return err
+	})
+	if err != nil {
+		return 0, fmt.Errorf("failed to deduplicate rules: %w", err)
+	}
+	return removed, nil
+}
+
+// PlanDeduplicate returns the ids of the rows Deduplicate would delete, in
+// ascending order, without deleting them. The ids of routed tables follow
+// the ones of the default table, table by table.
+func (a *Adapter) PlanDeduplicate(ctx context.Context) ([]int64, error) {
+	if a.tombstones {
+		return nil, nil
+	}
+	if a.routed() {
+		var ids []int64
+		for _, table := range a.splitTables() {
+			tableIds, err := table.PlanDeduplicate(ctx)
+			if err != nil {
+				return nil, err
+			}
+			ids = append(ids, tableIds...)
+		}
+		return ids, nil
+	}
+	query, args := a.duplicateIdsSql()
+	query += " ORDER BY id"
+
+	var (
+		records gdb.Result
+		err     error
+	)
+	if a.tx != nil {
+		records, err = a.tx.GetAll(query, args...)
+	} else {
+		records, err = a.db.GetAll(ctx, query, args...)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to find duplicate rules: %w", err)
+	}
+
+	ids := make([]int64, 0, len(records))
+	for _, record := range records {
+		ids = append(ids, record["id"].Int64())
+	}
+	return ids, nil
+}
+
+// duplicateIdsSql returns the query selecting the ids of the rows holding
+// the same rule as a row with a lower id.
+func (a *Adapter) duplicateIdsSql() (string, []interface{}) {
+	columns := []string{a.pTypeColumn, Columns.V0, Columns.V1, Columns.V2, Columns.V3, Columns.V4, Columns.V5}
+	switch {
+	case a.ruleColumn != "":
+		columns = []string{a.ruleColumn}
+	case a.jsonStorage:
+		columns = []string{a.pTypeColumn, jsonValuesColumn}
+	}
+	if a.tenantColumn != "" {
+		columns = append(columns, a.tenantColumn)
+	}
+
+	conditions := make([]string, 0, len(columns)+1)
+	for _, column := range columns {
+		conditions = append(conditions, a.dialect.nullSafeEqualSql("t1."+column, "t2."+column))
+	}
+	conditions = append(conditions, "t1.id > t2.id")
+	if live := a.liveSql("t1"); live != "" {
+		conditions = append(conditions, live, a.liveSql("t2"))
+	}
+
+	query := fmt.Sprintf("SELECT DISTINCT t1.id AS id FROM %s t1 JOIN %s t2 ON %s",
+		a.tableName, a.tableName, strings.Join(conditions, " AND "))
+	if a.tenantColumn == "" {
+		return query, nil
+	}
+	return query + fmt.Sprintf(" WHERE t1.%s = ?", a.tenantColumn), []interface{}{a.tenant}
+}
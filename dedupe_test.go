@@ -0,0 +1,111 @@
+package adapter
+
+import (
+	"context"
+	"reflect"
+	"testing"
+
+	"github.com/casbin/casbin/v2"
+)
+
+func TestDeduplicate(t *testing.T) {
+	ctx := context.Background()
+	a := newSqliteAdapter(t, WithHistory())
+	initPolicy(t, a)
+	// Rows 6 to 9, of which 6, 8 and 9 duplicate rows 1, 7 and 5.
+	err := a.AddPolicies("p", "p", [][]string{{"alice", "data1", "read"}, {"carol", "data3", "read"}, {"carol", "data3", "read"}})
+	if err != nil {
+		t.Fatalf("AddPolicies failed: %v", err)
+	}
+	if err := a.AddPolicy("g", "g", []string{"alice", "data2_admin"}); err != nil {
+		t.Fatalf("AddPolicy failed: %v", err)
+	}
+
+	ids, err := a.PlanDeduplicate(ctx)
+	if err != nil {
+		t.Fatalf("PlanDeduplicate failed: %v", err)
+	}
+	if !reflect.DeepEqual(ids, []int64{6, 8, 9}) {
+		t.Errorf("planned ids %v, supposed to be [6 8 9]", ids)
+	}
+
+	removed, err := a.Deduplicate(ctx)
+	if err != nil {
+		t.Fatalf("Deduplicate failed: %v", err)
+	}
+	if removed != 3 {
+		t.Errorf("removed %d rows, supposed to be 3", removed)
+	}
+
+	var survivors []int64
+	values, err := a.model().Fields("id").OrderAsc("id").Array()
+	if err != nil {
+		t.Fatalf("failed to query ids: %v", err)
+	}
+	for _, value := range values {
+		survivors = append(survivors, value.Int64())
+	}
+	if !reflect.DeepEqual(survivors, []int64{1, 2, 3, 4, 5, 7}) {
+		t.Errorf("surviving ids %v, supposed to be [1 2 3 4 5 7]", survivors)
+	}
+
+	e, _ := casbin.NewEnforcer("examples/rbac_model.conf", a)
+	testGetPolicy(t, e, [][]string{{"alice", "data1", "read"}, {"bob", "data2", "write"}, {"data2_admin", "data2", "read"}, {"data2_admin", "data2", "write"}, {"carol", "data3", "read"}})
+
+	if removed, err := a.Deduplicate(ctx); err != nil || removed != 0 {
+		t.Errorf("removed %d rows from a deduplicated table, err: %v", removed, err)
+	}
+}
+
+func TestDeduplicateForTenant(t *testing.T) {
+	ctx := context.Background()
+	a := newSqliteAdapter(t, WithTenantColumn("tenant_id"))
+	t1, t2 := a.ForTenant("t1"), a.ForTenant("t2")
+	rules := [][]string{{"alice", "data1", "read"}, {"alice", "data1", "read"}}
+	if err := t1.AddPolicies("p", "p", rules); err != nil {
+		t.Fatalf("AddPolicies failed: %v", err)
+	}
+	if err := t2.AddPolicies("p", "p", rules); err != nil {
+		t.Fatalf("AddPolicies failed: %v", err)
+	}
+
+	// The same rule of another tenant is not a duplicate.
+	if removed, err := t1.Deduplicate(ctx); err != nil || removed != 1 {
+		t.Errorf("removed %d rows, err: %v, supposed to be 1", removed, err)
+	}
+	if count, _ := t2.model().Count(); count != 2 {
+		t.Errorf("tenant t2 has %d rows, supposed to be 2", count)
+	}
+}
+
+func TestDeduplicateStorage(t *testing.T) {
+	for name, opts := range map[string][]AdapterOption{
+		// The duplicates of both tables are deleted.
+		"split tables": {WithSplitTables("casbin_p", "casbin_g")},
+		// The rules are matched by their stored text.
+		"single rule column": {WithSingleRuleColumn("rule")},
+		"JSON storage":       {WithJSONStorage()},
+	} {
+		t.Run(name, func(t *testing.T) {
+			ctx := context.Background()
+			a := newSqliteAdapter(t, opts...)
+			initPolicy(t, a)
+			if err := a.AddPolicies("p", "p", [][]string{{"alice", "data1", "read"}, {"alice", "data1"}}); err != nil {
+				t.Fatalf("AddPolicies failed: %v", err)
+			}
+			if err := a.AddPolicy("g", "g", []string{"alice", "data2_admin"}); err != nil {
+				t.Fatalf("AddPolicy failed: %v", err)
+			}
+
+			if ids, err := a.PlanDeduplicate(ctx); err != nil || len(ids) != 2 {
+				t.Errorf("planned ids %v, err: %v, supposed to be 2 ids", ids, err)
+			}
+			if removed, err := a.Deduplicate(ctx); err != nil || removed != 2 {
+				t.Errorf("removed %d rows, err: %v, supposed to be 2", removed, err)
+			}
+
+			e, _ := casbin.NewEnforcer("examples/rbac_model.conf", a)
+			testGetPolicy(t, e, [][]string{{"alice", "data1", "read"}, {"bob", "data2", "write"}, {"data2_admin", "data2", "read"}, {"data2_admin", "data2", "write"}, {"alice", "data1"}})
+		})
+	}
+}
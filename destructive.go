@@ -0,0 +1,142 @@
+package adapter
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/casbin/casbin/v2/model"
+	"github.com/gogf/gf/v2/database/gdb"
+)
+
+// destructiveGuard holds the confirmation hook of WithDestructiveGuard and
+// the thresholds of WithDestructiveThreshold.
+type destructiveGuard struct {
+	confirm  func(op string, rowCount int64) error
+	rows     int64
+	fraction float64
+}
+
+// WithDestructiveGuard sets a hook confirming the operations deleting
+// stored rules in bulk: SavePolicy, ClearPolicy, DropTable, Restore,
+// RemoveFilteredPolicy, and ImportCSV, ImportJSON and ImportPolicies with
+// ImportOptions.Replace. Before deleting more rules than the thresholds of
+// WithDestructiveThreshold, every rule by default, they call confirm with
+// their name and the number of rules they would delete, and abort with
+// its error, if any. confirm may check a feature flag or an environment
+// variable, or only log the operation.
+//
+// The rules deleted by SavePolicy are the stored ones the model doesn't
+// hold. Those of DropTable and Restore are all the stored rules, and
+// those of the imports all the rules of the table they import into.
+func WithDestructiveGuard(confirm func(op string, rowCount int64) error) AdapterOption {
+	return AdapterOption{apply: func(a *Adapter) {
+		a.destructiveGuard.confirm = confirm
+	}}
+}
+
+// WithDestructiveThreshold sets the operations confirmed by the hook of
+// WithDestructiveGuard to those deleting more than rows rules, or more than
+// fraction of the stored rules, e.g. 0.5 for half of them. A zero rows or
+// fraction is no threshold.
+func WithDestructiveThreshold(rows int64, fraction float64) AdapterOption {
+	return AdapterOption{apply: func(a *Adapter) {
+		a.destructiveGuard.rows, a.destructiveGuard.fraction = rows, fraction
+	}}
+}
+
+// guarded reports whether the adapter has a hook of WithDestructiveGuard.
+func (a *Adapter) guarded() bool {
+	return a.destructiveGuard.confirm != nil
+}
+
+// confirmDestructive calls the hook of WithDestructiveGuard when op deleting
+// deleted of the total stored rules exceeds its thresholds.
+func (a *Adapter) confirmDestructive(op string, deleted, total int64) error {
+	guard := a.destructiveGuard
+	if guard.confirm == nil || deleted == 0 {
+		return nil
+	}
+	if guard.rows > 0 || guard.fraction > 0 {
+		exceeded := guard.rows > 0 && deleted > guard.rows ||
+			guard.fraction > 0 && float64(deleted) > guard.fraction*float64(total)
+		if !exceeded {
+			return nil
+		}
+	}
+	if err := guard.confirm(op, deleted); err != nil {
+		return fmt.Errorf("%s deleting %d of %d rules not confirmed: %w", op, deleted, total, err)
+	}
+	return nil
+}
+
+// confirmTables calls the hook of WithDestructiveGuard for op deleting all
+// the rules of tables, of every tenant.
+func (a *Adapter) confirmTables(ctx context.Context, op string, tables []string) error {
+	if !a.guarded() {
+		return nil
+	}
+	var total int64
+	for _, table := range tables {
+		count, err := a.db.Model(table).Ctx(ctx).Count()
+		if err != nil {
+			return fmt.Errorf("failed to count rules: %w", err)
+		}
+		total += int64(count)
+	}
+	return a.confirmDestructive(op, total, total)
+}
+
+// confirmQuery calls the hook of WithDestructiveGuard for op deleting the
+// rules selected by query, all the stored rules when query is nil.
+func (a *Adapter) confirmQuery(ctx context.Context, op string, query *gdb.Model) error {
+	if !a.guarded() {
+		return nil
+	}
+	var total int64
+	for _, table := range a.splitTables() {
+		count, err := table.modelCtx(ctx).Count()
+		if err != nil {
+			return fmt.Errorf("failed to count rules: %w", err)
+		}
+		total += int64(count)
+	}
+	deleted := total
+	if query != nil {
+		count, err := query.Ctx(ctx).Count()
+		if err != nil {
+			return fmt.Errorf("failed to count rules: %w", err)
+		}
+		deleted = int64(count)
+	}
+	return a.confirmDestructive(op, deleted, total)
+}
+
+// confirmSave calls the hook of WithDestructiveGuard for SavePolicy deleting
+// the stored rules m doesn't hold.
+func (a *Adapter) confirmSave(ctx context.Context, m model.Model) error {
+	if !a.guarded() {
+		return nil
+	}
+	kept := make(map[ruleKey]int)
+	for _, rule := range a.modelRules(m) {
+		kept[syncKey(rule)]++
+	}
+	var deleted, total int64
+	for _, table := range a.splitTables() {
+		err := table.scanPages(table.modelCtx(ctx), func(rows []ruleRow) error {
+			for _, row := range rows {
+				total++
+				if key := syncKey(row.Rule); kept[key] > 0 {
+					kept[key]--
+					continue
+				}
+				deleted++
+			}
+			return nil
+		})
+		if err != nil {
+			return fmt.Errorf("failed to read rules: %w", err)
+		}
+	}
+	return a.confirmDestructive("SavePolicy", deleted, total)
+}
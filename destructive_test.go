@@ -0,0 +1,124 @@
+package adapter
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"testing"
+
+	"github.com/casbin/casbin/v2"
+)
+
+// guardCalls records the calls of a destructive guard, returning err.
+type guardCalls struct {
+	ops  []string
+	rows []int64
+	err  error
+}
+
+func (c *guardCalls) confirm(op string, rowCount int64) error {
+	c.ops = append(c.ops, op)
+	c.rows = append(c.rows, rowCount)
+	return c.err
+}
+
+func TestDestructiveGuard(t *testing.T) {
+	ctx := context.Background()
+	denied := errors.New("denied")
+	calls := &guardCalls{err: denied}
+	a := newSqliteAdapter(t, WithAllowDestructive(), WithDestructiveGuard(calls.confirm))
+	initPolicy(t, a)
+
+	e, _ := casbin.NewEnforcer("examples/rbac_model.conf", a)
+	if _, err := e.RemovePolicy("alice", "data1", "read"); err != nil {
+		t.Fatalf("RemovePolicy failed: %v", err)
+	}
+	if _, err := e.RemoveFilteredPolicy(1, "data2"); !errors.Is(err, denied) {
+		t.Errorf("RemoveFilteredPolicy err: %v, supposed to be denied", err)
+	}
+	if err := a.ClearPolicy(ctx); !errors.Is(err, denied) {
+		t.Errorf("ClearPolicy err: %v, supposed to be denied", err)
+	}
+	if err := a.DropTable(ctx); !errors.Is(err, denied) {
+		t.Errorf("DropTable err: %v, supposed to be denied", err)
+	}
+	// Saving the loaded rules deletes none, adding a rule neither.
+	if _, err := e.AddPolicy("erin", "data3", "read"); err != nil {
+		t.Fatalf("AddPolicy failed: %v", err)
+	}
+	if err := e.SavePolicy(); err != nil {
+		t.Fatalf("SavePolicy failed: %v", err)
+	}
+	e.EnableAutoSave(false)
+	if _, err := e.RemovePolicy("erin", "data3", "read"); err != nil {
+		t.Fatalf("RemovePolicy failed: %v", err)
+	}
+	if err := e.SavePolicy(); !errors.Is(err, denied) {
+		t.Errorf("SavePolicy err: %v, supposed to be denied", err)
+	}
+	// The imports replacing the rules delete all of them.
+	if _, err := a.ImportPolicies(ctx, [][]string{{"zoe", "data1", "read"}}, "p", ImportOptions{Replace: true}); !errors.Is(err, denied) {
+		t.Errorf("ImportPolicies err: %v, supposed to be denied", err)
+	}
+	if _, err := a.ImportCSV(ctx, strings.NewReader("p, zoe, data1, read\n"), ImportOptions{Replace: true}); !errors.Is(err, denied) {
+		t.Errorf("ImportCSV err: %v, supposed to be denied", err)
+	}
+	if count, err := a.CountPolicies(ctx, nil); err != nil || count != 5 {
+		t.Errorf("CountPolicies = %d, err: %v, supposed to keep the 5 rules", count, err)
+	}
+	wantOps := []string{"RemoveFilteredPolicy", "ClearPolicy", "DropTable", "SavePolicy", "ImportPolicies", "ImportCSV"}
+	wantRows := []int64{3, 4, 4, 1, 5, 5}
+	if len(calls.ops) != len(wantOps) {
+		t.Fatalf("guard calls %v, supposed to be %v", calls.ops, wantOps)
+	}
+	for i := range wantOps {
+		if calls.ops[i] != wantOps[i] || calls.rows[i] != wantRows[i] {
+			t.Errorf("guard call %d: %s of %d rules, supposed to be %s of %d", i, calls.ops[i], calls.rows[i], wantOps[i], wantRows[i])
+		}
+	}
+
+	// The operations confirmed by the hook go on.
+	calls.err = nil
+	backup, err := a.Backup(ctx, "guard")
+	if err != nil {
+		t.Fatalf("Backup failed: %v", err)
+	}
+	if err := e.SavePolicy(); err != nil {
+		t.Fatalf("SavePolicy failed: %v", err)
+	}
+	if err := a.Restore(ctx, backup); err != nil {
+		t.Fatalf("Restore failed: %v", err)
+	}
+	if err := a.ClearPolicy(ctx); err != nil {
+		t.Fatalf("ClearPolicy failed: %v", err)
+	}
+	if count, err := a.CountPolicies(ctx, nil); err != nil || count != 0 {
+		t.Errorf("CountPolicies = %d, err: %v, supposed to be 0", count, err)
+	}
+	if got := calls.ops[len(wantOps):]; len(got) != 3 || got[0] != "SavePolicy" || got[1] != "Restore" || got[2] != "ClearPolicy" {
+		t.Errorf("guard calls %v, supposed to be SavePolicy, Restore and ClearPolicy", got)
+	}
+}
+
+func TestDestructiveThreshold(t *testing.T) {
+	calls := &guardCalls{err: errors.New("denied")}
+	a := newSqliteAdapter(t, WithDestructiveGuard(calls.confirm), WithDestructiveThreshold(2, 0.5))
+	initPolicy(t, a)
+
+	// Deleting 2 of the 5 rules is under both thresholds.
+	if err := a.RemoveFilteredPolicy("p", "p", 0, "data2_admin"); err != nil {
+		t.Fatalf("RemoveFilteredPolicy failed: %v", err)
+	}
+	// Deleting the 2 p rules of the 3 left is more than half of them.
+	if err := a.RemoveFilteredPolicy("p", "p", 0); err == nil {
+		t.Error("RemoveFilteredPolicy: expected an error")
+	}
+	b := newSqliteAdapter(t, WithDestructiveGuard(calls.confirm), WithDestructiveThreshold(0, 0.9))
+	initPolicy(t, b)
+	if err := b.RemoveFilteredPolicy("p", "p", 0, "data2_admin"); err != nil {
+		t.Fatalf("RemoveFilteredPolicy failed: %v", err)
+	}
+	if len(calls.ops) != 1 {
+		t.Errorf("guard calls %v, supposed to be the one over the thresholds", calls.ops)
+	}
+}
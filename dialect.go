@@ -0,0 +1,704 @@
+package adapter
+
+import (
+	"fmt"
+	"regexp"
+	"strings"
+
+	"github.com/gogf/gf/v2/database/gdb"
+)
+
+const (
+	mysqlCreateTableSql = `
+CREATE TABLE IF NOT EXISTS %s (
+  id bigint NOT NULL AUTO_INCREMENT,
+  p_type varchar(10) CHARACTER SET utf8mb4 COLLATE utf8mb4_general_ci DEFAULT NULL,
+  v0 varchar(256) COLLATE utf8mb4_general_ci DEFAULT NULL,
+  v1 varchar(256) COLLATE utf8mb4_general_ci DEFAULT NULL,
+  v2 varchar(256) COLLATE utf8mb4_general_ci DEFAULT NULL,
+  v3 varchar(256) COLLATE utf8mb4_general_ci DEFAULT NULL,
+  v4 varchar(256) COLLATE utf8mb4_general_ci DEFAULT NULL,
+  v5 varchar(256) COLLATE utf8mb4_general_ci DEFAULT NULL,
+%s  created_at datetime DEFAULT CURRENT_TIMESTAMP,
+  PRIMARY KEY (id)%s
+) ENGINE=InnoDB DEFAULT CHARSET=utf8mb4 COLLATE=utf8mb4_bin%s;
+`
+	mysqlCreateHistoryTableSql = `
+CREATE TABLE IF NOT EXISTS %s (
+  id bigint NOT NULL AUTO_INCREMENT,
+  op varchar(10) NOT NULL,
+  p_type varchar(10) CHARACTER SET utf8mb4 COLLATE utf8mb4_general_ci DEFAULT NULL,
+  v0 varchar(256) COLLATE utf8mb4_general_ci DEFAULT NULL,
+  v1 varchar(256) COLLATE utf8mb4_general_ci DEFAULT NULL,
+  v2 varchar(256) COLLATE utf8mb4_general_ci DEFAULT NULL,
+  v3 varchar(256) COLLATE utf8mb4_general_ci DEFAULT NULL,
+  v4 varchar(256) COLLATE utf8mb4_general_ci DEFAULT NULL,
+  v5 varchar(256) COLLATE utf8mb4_general_ci DEFAULT NULL,
+%s  changed_at datetime NOT NULL,
+  PRIMARY KEY (id),
+  KEY idx_changed_at (changed_at)%s
+) ENGINE=InnoDB DEFAULT CHARSET=utf8mb4 COLLATE=utf8mb4_bin;
+`
+	mysqlTenantColumnSql  = "  %s varchar(64) COLLATE utf8mb4_general_ci NOT NULL DEFAULT '',\n"
+	mysqlActorColumnSql   = "%s varchar(255) COLLATE utf8mb4_general_ci NOT NULL DEFAULT ''"
+	mysqlTypedColumnSql   = "%s %s COLLATE utf8mb4_general_ci DEFAULT NULL"
+	mysqlTenantKeySql     = ",\n  KEY idx_%s (%s)"
+	mysqlTruncateTableSql = `TRUNCATE TABLE %s`
+	mysqlCreateIndexSql   = `ALTER TABLE %s ADD KEY idx_%s (%s)`
+	mysqlCreateLikeSql    = `CREATE TABLE %s LIKE %s`
+	mysqlUniqueIndexSql   = `ALTER TABLE %s ADD UNIQUE KEY uniq_rule (%s)`
+	mysqlSwapTablesSql    = `RENAME TABLE %s TO %s, %s TO %s`
+	mysqlVersionTableSql  = `CREATE TABLE IF NOT EXISTS %s (id int NOT NULL, version bigint NOT NULL DEFAULT 0, PRIMARY KEY (id)) ENGINE=InnoDB`
+	mysqlRuleColumnSql    = `CREATE TABLE IF NOT EXISTS %s (id bigint NOT NULL AUTO_INCREMENT, %s text DEFAULT NULL, created_at datetime DEFAULT CURRENT_TIMESTAMP, PRIMARY KEY (id)) ENGINE=InnoDB DEFAULT CHARSET=utf8mb4 COLLATE=utf8mb4_bin`
+	mysqlJSONTableSql     = `CREATE TABLE IF NOT EXISTS %s (id bigint NOT NULL AUTO_INCREMENT, p_type varchar(32) NOT NULL DEFAULT '', vals json DEFAULT NULL, created_at datetime DEFAULT CURRENT_TIMESTAMP, PRIMARY KEY (id), KEY idx_p_type (p_type)) ENGINE=InnoDB DEFAULT CHARSET=utf8mb4 COLLATE=utf8mb4_bin`
+	mysqlPartitionSql     = "\nPARTITION BY LIST COLUMNS(%s) (PARTITION %s VALUES IN (''))"
+	mysqlAddPartitionSql  = `ALTER TABLE %s ADD PARTITION (PARTITION %s VALUES IN (%s))`
+	mysqlTruncatePartSql  = `ALTER TABLE %s TRUNCATE PARTITION %s`
+
+	sqliteCreateTableSql = `
+CREATE TABLE IF NOT EXISTS %s (
+  id INTEGER PRIMARY KEY AUTOINCREMENT,
+  p_type varchar(10) DEFAULT NULL,
+  v0 varchar(256) DEFAULT NULL,
+  v1 varchar(256) DEFAULT NULL,
+  v2 varchar(256) DEFAULT NULL,
+  v3 varchar(256) DEFAULT NULL,
+  v4 varchar(256) DEFAULT NULL,
+  v5 varchar(256) DEFAULT NULL,
+%s  created_at datetime DEFAULT CURRENT_TIMESTAMP
+);
+`
+	sqliteCreateHistoryTableSql = `
+CREATE TABLE IF NOT EXISTS %s (
+  id INTEGER PRIMARY KEY AUTOINCREMENT,
+  op varchar(10) NOT NULL,
+  p_type varchar(10) DEFAULT NULL,
+  v0 varchar(256) DEFAULT NULL,
+  v1 varchar(256) DEFAULT NULL,
+  v2 varchar(256) DEFAULT NULL,
+  v3 varchar(256) DEFAULT NULL,
+  v4 varchar(256) DEFAULT NULL,
+  v5 varchar(256) DEFAULT NULL,
+%s  changed_at datetime NOT NULL
+);
+`
+	sqliteTenantColumnSql  = "  %s varchar(64) NOT NULL DEFAULT '',\n"
+	sqliteActorColumnSql   = "%s varchar(255) NOT NULL DEFAULT ''"
+	sqliteTypedColumnSql   = "%s %s DEFAULT NULL"
+	sqliteCreateIndexSql   = `CREATE INDEX IF NOT EXISTS idx_%s_%s ON %s (%s)`
+	sqliteTruncateTableSql = `DELETE FROM %s`
+	sqliteRenameTableSql   = `ALTER TABLE %s RENAME TO %s`
+	sqliteUniqueIndexSql   = `CREATE UNIQUE INDEX IF NOT EXISTS uniq_%s_rule ON %s (%s)`
+	sqliteVersionTableSql  = `CREATE TABLE IF NOT EXISTS %s (id INTEGER PRIMARY KEY, version INTEGER NOT NULL DEFAULT 0)`
+	sqliteRuleColumnSql    = `CREATE TABLE IF NOT EXISTS %s (id INTEGER PRIMARY KEY AUTOINCREMENT, %s TEXT DEFAULT NULL, created_at datetime DEFAULT CURRENT_TIMESTAMP)`
+	sqliteJSONTableSql     = `CREATE TABLE IF NOT EXISTS %s (id INTEGER PRIMARY KEY AUTOINCREMENT, p_type TEXT NOT NULL DEFAULT '', vals TEXT DEFAULT NULL, created_at datetime DEFAULT CURRENT_TIMESTAMP)`
+
+	sqliteMaxGroupedConditions = 500
+	// sqliteMaxParams is the default limit of the host parameters of sqlite
+	// since 3.32.
+	sqliteMaxParams = 32766
+	// mysqlMaxParams is the limit of the placeholders of a prepared
+	// statement of MySQL.
+	mysqlMaxParams = 65535
+
+	addColumnSql = `ALTER TABLE %s ADD COLUMN %s`
+
+	// orNullEqualSql compares two columns equal or both NULL, on the
+	// databases without a null-safe operator.
+	orNullEqualSql = `(%s = %s OR (%s IS NULL AND %s IS NULL))`
+
+	mysqlCreateStagingTableSql  = "CREATE TEMPORARY TABLE IF NOT EXISTS %s (\n  id bigint NOT NULL AUTO_INCREMENT PRIMARY KEY,\n  %s\n)"
+	mysqlDropStagingTableSql    = `DROP TEMPORARY TABLE IF EXISTS %s`
+	sqliteCreateStagingTableSql = "CREATE TEMP TABLE IF NOT EXISTS %s (\n  id INTEGER PRIMARY KEY AUTOINCREMENT,\n  %s\n)"
+	sqliteDropStagingTableSql   = `DROP TABLE IF EXISTS temp.%s`
+
+	mysqlLockSql   = `SELECT GET_LOCK(?, ?)`
+	mysqlUnlockSql = `SELECT RELEASE_LOCK(?)`
+)
+
+// stagingColumns are the rule columns of the staging table.
+var stagingColumns = []string{"p_type", "v0", "v1", "v2", "v3", "v4", "v5"}
+
+// stagingColumnsSql joins the definitions of the staging columns, the
+// typed columns of schema are defined by typedSql.
+func stagingColumnsSql(definitions map[string]string, schema tableSchema, typedSql string) string {
+	columns := make([]string, 0, len(stagingColumns))
+	for _, column := range stagingColumns {
+		if sqlType, ok := schema.columnTypes[column]; ok {
+			columns = append(columns, fmt.Sprintf(typedSql, column, sqlType))
+			continue
+		}
+		columns = append(columns, definitions[column])
+	}
+	return strings.Join(columns, ",\n  ")
+}
+
+// columnLines replaces the definition of every line of ddl by the result of
+// fn, which gets the first word of the line as column. The lines holding no
+// column definition are left alone by fn.
+func columnLines(ddl string, fn func(column, definition string) string) string {
+	lines := strings.Split(ddl, "\n")
+	for i, line := range lines {
+		trimmed := strings.TrimSpace(line)
+		definition, comma := strings.CutSuffix(trimmed, ",")
+		column, _, _ := strings.Cut(definition, " ")
+		if replaced := fn(column, definition); replaced != definition {
+			lines[i] = strings.TrimSuffix(line, trimmed) + replaced
+			if comma {
+				lines[i] += ","
+			}
+		}
+	}
+	return strings.Join(lines, "\n")
+}
+
+// withColumnTypes defines the typed columns of schema in ddl by typedSql.
+func withColumnTypes(ddl string, schema tableSchema, typedSql string) string {
+	if len(schema.columnTypes) == 0 {
+		return ddl
+	}
+	return columnLines(ddl, func(column, definition string) string {
+		if sqlType, ok := schema.columnTypes[column]; ok {
+			return fmt.Sprintf(typedSql, column, sqlType)
+		}
+		return definition
+	})
+}
+
+var (
+	// mysqlColumnSql and sqliteColumnSql define the columns of the create
+	// statements, they are used to add missing columns to existing tables.
+	mysqlColumnSql = map[string]string{
+		"p_type":     "p_type varchar(10) CHARACTER SET utf8mb4 COLLATE utf8mb4_general_ci DEFAULT NULL",
+		"v0":         "v0 varchar(256) COLLATE utf8mb4_general_ci DEFAULT NULL",
+		"v1":         "v1 varchar(256) COLLATE utf8mb4_general_ci DEFAULT NULL",
+		"v2":         "v2 varchar(256) COLLATE utf8mb4_general_ci DEFAULT NULL",
+		"v3":         "v3 varchar(256) COLLATE utf8mb4_general_ci DEFAULT NULL",
+		"v4":         "v4 varchar(256) COLLATE utf8mb4_general_ci DEFAULT NULL",
+		"v5":         "v5 varchar(256) COLLATE utf8mb4_general_ci DEFAULT NULL",
+		"created_at": "created_at datetime DEFAULT CURRENT_TIMESTAMP",
+		"updated_at": "updated_at datetime DEFAULT NULL",
+		"deleted_at": "deleted_at datetime DEFAULT NULL",
+		"rule_hash":  "rule_hash char(64) DEFAULT NULL",
+	}
+	// sqliteColumnSql has no CURRENT_TIMESTAMP default for created_at, as
+	// sqlite can't add a column with a non-constant default.
+	sqliteColumnSql = map[string]string{
+		"p_type":     "p_type varchar(10) DEFAULT NULL",
+		"v0":         "v0 varchar(256) DEFAULT NULL",
+		"v1":         "v1 varchar(256) DEFAULT NULL",
+		"v2":         "v2 varchar(256) DEFAULT NULL",
+		"v3":         "v3 varchar(256) DEFAULT NULL",
+		"v4":         "v4 varchar(256) DEFAULT NULL",
+		"v5":         "v5 varchar(256) DEFAULT NULL",
+		"created_at": "created_at datetime DEFAULT NULL",
+		"updated_at": "updated_at datetime DEFAULT NULL",
+		"deleted_at": "deleted_at datetime DEFAULT NULL",
+		"rule_hash":  "rule_hash char(64) DEFAULT NULL",
+	}
+
+	// indexedColumns are the optional columns added with an index.
+	indexedColumns = map[string]bool{
+		updatedAtColumn: true,
+		deletedAtColumn: true,
+	}
+)
+
+// identifierRegex matches the column names accepted from options.
+var identifierRegex = regexp.MustCompile(`^[A-Za-z_][A-Za-z0-9_]*$`)
+
+// isValidIdentifier reports whether name can be used as a column name
+// without quoting.
+func isValidIdentifier(name string) bool {
+	return identifierRegex.MatchString(name)
+}
+
+// tableSchema describes the optional columns of the policy tables.
+type tableSchema struct {
+	// tenantColumn is the name of the tenant column, empty when disabled.
+	tenantColumn string
+	// tableComment and columnComments, by column name, are the comments of
+	// the policy table. The history table has none.
+	tableComment   string
+	columnComments map[string]string
+	// columnTypes are the SQL types of the value columns replacing their
+	// varchar, by column name.
+	columnTypes map[string]string
+	// ruleHash adds the rule_hash column to the policy table.
+	ruleHash bool
+	// partitioned partitions the policy table by the tenant column.
+	partitioned bool
+	// ruleColumn is the column of WithSingleRuleColumn, empty when the
+	// rules are stored in the value columns.
+	ruleColumn string
+	// jsonStorage stores the values in the JSON column of WithJSONStorage.
+	jsonStorage bool
+	// actorColumn is the column of WithActorColumn, empty when disabled.
+	actorColumn string
+}
+
+// dialect generates the database specific statements used by the adapter.
+type dialect interface {
+	createTableSql(table string, schema tableSchema) []string
+	createHistoryTableSql(table string, schema tableSchema) []string
+	// createVersionTableSql returns the statement creating the table holding
+	// the version counter of the policy.
+	createVersionTableSql(table string) string
+	// createRuleColumnTableSql returns the statement creating the policy
+	// table of WithSingleRuleColumn, storing the rules in the text column.
+	createRuleColumnTableSql(table, column string) string
+	// createJSONTableSql returns the statements creating the policy table of
+	// WithJSONStorage, storing the values of the rules in the vals column.
+	createJSONTableSql(table string) []string
+	// jsonValueSql returns the expression of the value at index of the JSON
+	// array of column, as text and NULL when the array is shorter. It is
+	// empty when the database can't extract it, the values are then matched
+	// once read.
+	jsonValueSql(column string, index int) string
+	// jsonEqualSql returns the condition comparing the JSON array of column
+	// with the bound one, which every placeholder of the condition binds.
+	jsonEqualSql(column string) string
+	truncateTableSql(table string) string
+	// transactionalTruncate reports whether the statement of
+	// truncateTableSql is rolled back with the transaction it runs in.
+	transactionalTruncate() bool
+	// addColumnSql returns the statements adding column, one of the columns
+	// of the create statements, to an existing table.
+	addColumnSql(table, column string, schema tableSchema) []string
+	// nullSafeEqualSql returns a condition comparing two columns that holds
+	// when both are NULL.
+	nullSafeEqualSql(left, right string) string
+	// createStagingTableSql and dropStagingTableSql manage a temporary table
+	// of the connection holding rules to compare with the policy table.
+	createStagingTableSql(table string, schema tableSchema) string
+	dropStagingTableSql(table string) string
+	// createTableLikeSql returns the statements creating table with the
+	// layout of the policy table like.
+	createTableLikeSql(table, like string, schema tableSchema) []string
+	// swapTablesSql returns the statements, run in one transaction, renaming
+	// table to old and staging to table.
+	swapTablesSql(table, staging, old string) []string
+	// createUniqueIndexSql returns the statement creating the unique index
+	// of table over columns.
+	createUniqueIndexSql(table string, columns []string) string
+	// maxWriters returns the maximum number of connections inserting into a
+	// table at once, 0 when it is unlimited.
+	maxWriters() int
+	// maxGroupedConditions returns the maximum number of rule conditions
+	// combined with OR into one statement, 0 when only the batch size limits
+	// it. 1 deletes the rules one statement each.
+	maxGroupedConditions() int
+	// lockSql and unlockSql return the statements taking and releasing an
+	// advisory lock of the connection, both taking the lock name, the lock
+	// statement also the seconds to wait for it. The lock statement selects
+	// 1 once the lock is taken. They are empty when the database has no
+	// advisory locks.
+	lockSql() (lock, unlock string)
+	// addPartitionSql returns the statement adding partition, holding the
+	// rows of tenant, to a table partitioned by the tenant column, and
+	// truncatePartitionSql the one emptying it. They are empty when the
+	// database has no partitions.
+	addPartitionSql(table, partition, tenant string) string
+	truncatePartitionSql(table, partition string) string
+	// optimizeTableSql returns the statement forcing the merges of a table
+	// that removes rules by writing tombstone rows. It is empty when the
+	// database deletes the rows in place.
+	optimizeTableSql(table string) string
+	// insertedIds returns the ids of the n rows inserted by one statement
+	// whose last insert id is last, nil when the database can't tell them.
+	insertedIds(last int64, n int) []int64
+	// returningSql returns the clauses of an insert selecting the ids of
+	// its rows, output going before VALUES and returning after them. Both
+	// are empty when the ids are told by the last insert id.
+	returningSql() (output, returning string)
+	// readPastHint returns the table hint of the loads skipping the rows
+	// locked by writers, empty when the database has none.
+	readPastHint() string
+	// maxInsertRows returns the maximum number of rows inserted by one
+	// statement, 0 when only the batch size limits it.
+	maxInsertRows() int
+	// maxParams returns the maximum number of parameters bound by one
+	// statement, 0 when it is unlimited. The batches of rows, conditions
+	// and ids are sized to stay below it.
+	maxParams() int
+	// resolvesConflicts reports whether the database resolves the conflicts
+	// of the inserts with the unique index, by INSERT IGNORE and REPLACE or
+	// their equivalents.
+	resolvesConflicts() bool
+	// conflictSql returns the clause appended to the insert of columns that
+	// resolves its conflicts with the unique index over unique according to
+	// policy. It is empty when the database resolves them by INSERT IGNORE
+	// and REPLACE.
+	conflictSql(policy ConflictPolicy, unique, columns []string) string
+}
+
+// dialectFor returns the dialect matching the driver type of db.
+// MySQL is used when the type is unknown.
+func dialectFor(db gdb.DB) dialect {
+	if config := db.GetConfig(); config != nil {
+		switch config.Type {
+		case "sqlite":
+			return sqliteDialect{}
+		case "clickhouse":
+			return clickhouseDialect{}
+		case "mssql":
+			return mssqlDialect{}
+		case "dm":
+			return dmDialect{}
+		case "pgsql":
+			return pgsqlDialect{}
+		}
+	}
+	return mysqlDialect{}
+}
+
+type mysqlDialect struct{}
+
+func (d mysqlDialect) createTableSql(table string, schema tableSchema) []string {
+	columns, keys := d.tenantSql(schema)
+	if schema.ruleHash {
+		columns += "  " + mysqlColumnSql[ruleHashColumn] + ",\n"
+	}
+	var comment string
+	if schema.tableComment != "" {
+		comment = " COMMENT=" + mysqlQuote(schema.tableComment)
+	}
+	if schema.partitioned {
+		comment += fmt.Sprintf(mysqlPartitionSql, schema.tenantColumn, defaultPartition)
+	}
+	createSql := withColumnTypes(fmt.Sprintf(mysqlCreateTableSql, table, columns, keys, comment), schema, mysqlTypedColumnSql)
+	// The partitioning column must be part of the primary key.
+	if schema.partitioned {
+		createSql = strings.Replace(createSql, "PRIMARY KEY (id)", fmt.Sprintf("PRIMARY KEY (id, %s)", schema.tenantColumn), 1)
+	}
+	return []string{mysqlColumnComments(createSql, schema)}
+}
+
+func (d mysqlDialect) createHistoryTableSql(table string, schema tableSchema) []string {
+	columns, keys := d.tenantSql(schema)
+	createSql := fmt.Sprintf(mysqlCreateHistoryTableSql, table, columns, keys)
+	return []string{withColumnTypes(createSql, schema, mysqlTypedColumnSql)}
+}
+
+func (mysqlDialect) createVersionTableSql(table string) string {
+	return fmt.Sprintf(mysqlVersionTableSql, table)
+}
+
+func (mysqlDialect) createRuleColumnTableSql(table, column string) string {
+	return fmt.Sprintf(mysqlRuleColumnSql, table, column)
+}
+
+func (mysqlDialect) createJSONTableSql(table string) []string {
+	return []string{fmt.Sprintf(mysqlJSONTableSql, table)}
+}
+
+func (mysqlDialect) jsonValueSql(column string, index int) string {
+	return fmt.Sprintf("JSON_UNQUOTE(JSON_EXTRACT(%s, '$[%d]'))", column, index)
+}
+
+// jsonEqualSql casts the bound array, MySQL doesn't compare a JSON column
+// with a string as JSON.
+func (mysqlDialect) jsonEqualSql(column string) string {
+	return column + " = CAST(? AS JSON)"
+}
+
+func (mysqlDialect) tenantSql(schema tableSchema) (columns, keys string) {
+	if schema.tenantColumn == "" {
+		return "", ""
+	}
+	columns = fmt.Sprintf(mysqlTenantColumnSql, schema.tenantColumn)
+	keys = fmt.Sprintf(mysqlTenantKeySql, schema.tenantColumn, schema.tenantColumn)
+	return columns, keys
+}
+
+func (mysqlDialect) truncateTableSql(table string) string {
+	return fmt.Sprintf(mysqlTruncateTableSql, table)
+}
+
+func (mysqlDialect) transactionalTruncate() bool {
+	return false
+}
+
+func (mysqlDialect) nullSafeEqualSql(left, right string) string {
+	return fmt.Sprintf("%s <=> %s", left, right)
+}
+
+func (mysqlDialect) createStagingTableSql(table string, schema tableSchema) string {
+	return fmt.Sprintf(mysqlCreateStagingTableSql, table, stagingColumnsSql(mysqlColumnSql, schema, mysqlTypedColumnSql))
+}
+
+func (mysqlDialect) dropStagingTableSql(table string) string {
+	return fmt.Sprintf(mysqlDropStagingTableSql, table)
+}
+
+func (mysqlDialect) createTableLikeSql(table, like string, schema tableSchema) []string {
+	return []string{fmt.Sprintf(mysqlCreateLikeSql, table, like)}
+}
+
+// swapTablesSql renames both tables in one statement, which is atomic.
+func (mysqlDialect) swapTablesSql(table, staging, old string) []string {
+	return []string{fmt.Sprintf(mysqlSwapTablesSql, table, old, staging, table)}
+}
+
+// createUniqueIndexSql indexes a prefix of every value column, the index
+// of the full columns would exceed the maximum key length.
+func (mysqlDialect) createUniqueIndexSql(table string, columns []string) string {
+	keys := make([]string, 0, len(columns))
+	for _, column := range columns {
+		if valueColumn(column) {
+			column = fmt.Sprintf("%s(%d)", column, mysqlIndexPrefixLength)
+		}
+		keys = append(keys, column)
+	}
+	return fmt.Sprintf(mysqlUniqueIndexSql, table, strings.Join(keys, ", "))
+}
+
+func (mysqlDialect) maxWriters() int {
+	return 0
+}
+
+func (mysqlDialect) maxGroupedConditions() int {
+	return 0
+}
+
+func (mysqlDialect) lockSql() (lock, unlock string) {
+	return mysqlLockSql, mysqlUnlockSql
+}
+
+func (mysqlDialect) addPartitionSql(table, partition, tenant string) string {
+	return fmt.Sprintf(mysqlAddPartitionSql, table, partition, mysqlQuote(tenant))
+}
+
+func (mysqlDialect) truncatePartitionSql(table, partition string) string {
+	return fmt.Sprintf(mysqlTruncatePartSql, table, partition)
+}
+
+func (mysqlDialect) optimizeTableSql(table string) string {
+	return ""
+}
+
+func (mysqlDialect) readPastHint() string {
+	return ""
+}
+
+func (mysqlDialect) maxInsertRows() int {
+	return 0
+}
+
+func (mysqlDialect) maxParams() int {
+	return mysqlMaxParams
+}
+
+func (mysqlDialect) resolvesConflicts() bool {
+	return true
+}
+
+func (mysqlDialect) conflictSql(policy ConflictPolicy, unique, columns []string) string {
+	return ""
+}
+
+// insertedIds only tells the id of a single row, the last insert id is the
+// one of the first row and the ids of a statement needn't be consecutive
+// with concurrent inserts.
+func (mysqlDialect) insertedIds(last int64, n int) []int64 {
+	if n != 1 {
+		return nil
+	}
+	return []int64{last}
+}
+
+func (mysqlDialect) returningSql() (output, returning string) {
+	return "", ""
+}
+
+func (mysqlDialect) addColumnSql(table, column string, schema tableSchema) []string {
+	if column == schema.tenantColumn {
+		definition := strings.TrimSuffix(strings.TrimSpace(fmt.Sprintf(mysqlTenantColumnSql, column)), ",")
+		return []string{
+			fmt.Sprintf(addColumnSql, table, mysqlColumnComments(definition, schema)),
+			fmt.Sprintf(mysqlCreateIndexSql, table, column, column),
+		}
+	}
+	if column == schema.actorColumn {
+		return []string{fmt.Sprintf(addColumnSql, table, mysqlColumnComments(fmt.Sprintf(mysqlActorColumnSql, column), schema))}
+	}
+	definition := mysqlColumnComments(mysqlColumnSql[column], schema)
+	statements := []string{fmt.Sprintf(addColumnSql, table, definition)}
+	if indexedColumns[column] {
+		statements = append(statements, fmt.Sprintf(mysqlCreateIndexSql, table, column, column))
+	}
+	return statements
+}
+
+type sqliteDialect struct{}
+
+// createTableSql skips the comments of schema, sqlite has no comments.
+func (d sqliteDialect) createTableSql(table string, schema tableSchema) []string {
+	var columns string
+	if schema.ruleHash {
+		columns = "  " + sqliteColumnSql[ruleHashColumn] + ",\n"
+	}
+	return d.withTenant(sqliteCreateTableSql, table, columns, schema)
+}
+
+func (d sqliteDialect) createHistoryTableSql(table string, schema tableSchema) []string {
+	return d.withTenant(sqliteCreateHistoryTableSql, table, "", schema)
+}
+
+func (sqliteDialect) createVersionTableSql(table string) string {
+	return fmt.Sprintf(sqliteVersionTableSql, table)
+}
+
+func (sqliteDialect) createRuleColumnTableSql(table, column string) string {
+	return fmt.Sprintf(sqliteRuleColumnSql, table, column)
+}
+
+func (sqliteDialect) createJSONTableSql(table string) []string {
+	return []string{fmt.Sprintf(sqliteJSONTableSql, table)}
+}
+
+func (sqliteDialect) jsonValueSql(column string, index int) string {
+	return fmt.Sprintf("json_extract(%s, '$[%d]')", column, index)
+}
+
+func (sqliteDialect) jsonEqualSql(column string) string {
+	return column + " = ?"
+}
+
+// withTenant renders the create statement with the optional columns,
+// sqlite needs a separate statement for the tenant index.
+func (sqliteDialect) withTenant(createSql, table, columns string, schema tableSchema) []string {
+	if schema.tenantColumn == "" {
+		return []string{withColumnTypes(fmt.Sprintf(createSql, table, columns), schema, sqliteTypedColumnSql)}
+	}
+	columns = fmt.Sprintf(sqliteTenantColumnSql, schema.tenantColumn) + columns
+	return []string{
+		withColumnTypes(fmt.Sprintf(createSql, table, columns), schema, sqliteTypedColumnSql),
+		fmt.Sprintf(sqliteCreateIndexSql, table, schema.tenantColumn, table, schema.tenantColumn),
+	}
+}
+
+func (sqliteDialect) truncateTableSql(table string) string {
+	return fmt.Sprintf(sqliteTruncateTableSql, table)
+}
+
+func (sqliteDialect) transactionalTruncate() bool {
+	return true
+}
+
+func (sqliteDialect) nullSafeEqualSql(left, right string) string {
+	return fmt.Sprintf("%s IS %s", left, right)
+}
+
+func (sqliteDialect) createStagingTableSql(table string, schema tableSchema) string {
+	return fmt.Sprintf(sqliteCreateStagingTableSql, table, stagingColumnsSql(sqliteColumnSql, schema, sqliteTypedColumnSql))
+}
+
+func (sqliteDialect) dropStagingTableSql(table string) string {
+	return fmt.Sprintf(sqliteDropStagingTableSql, table)
+}
+
+// createTableLikeSql creates table with the adapter's schema, sqlite can
+// only copy the layout of a table by its create statement.
+func (d sqliteDialect) createTableLikeSql(table, like string, schema tableSchema) []string {
+	if schema.ruleColumn != "" {
+		return []string{d.createRuleColumnTableSql(table, schema.ruleColumn)}
+	}
+	if schema.jsonStorage {
+		return d.createJSONTableSql(table)
+	}
+	return d.createTableSql(table, schema)
+}
+
+func (sqliteDialect) swapTablesSql(table, staging, old string) []string {
+	return []string{
+		fmt.Sprintf(sqliteRenameTableSql, table, old),
+		fmt.Sprintf(sqliteRenameTableSql, staging, table),
+	}
+}
+
+func (sqliteDialect) createUniqueIndexSql(table string, columns []string) string {
+	return fmt.Sprintf(sqliteUniqueIndexSql, table, table, strings.Join(columns, ", "))
+}
+
+// maxWriters is 1 as sqlite locks the whole database for every write.
+func (sqliteDialect) maxWriters() int {
+	return 1
+}
+
+// maxGroupedConditions stays well below the expression depth limit of
+// sqlite, 1000 by default, which a chain of OR conditions counts against.
+func (sqliteDialect) maxGroupedConditions() int {
+	return sqliteMaxGroupedConditions
+}
+
+// lockSql is empty, sqlite serializes the schema changes itself.
+func (sqliteDialect) lockSql() (lock, unlock string) {
+	return "", ""
+}
+
+// addPartitionSql and truncatePartitionSql are empty, sqlite has no
+// partitions.
+func (sqliteDialect) addPartitionSql(table, partition, tenant string) string {
+	return ""
+}
+
+func (sqliteDialect) truncatePartitionSql(table, partition string) string {
+	return ""
+}
+
+func (sqliteDialect) optimizeTableSql(table string) string {
+	return ""
+}
+
+func (sqliteDialect) readPastHint() string {
+	return ""
+}
+
+func (sqliteDialect) maxInsertRows() int {
+	return 0
+}
+
+func (sqliteDialect) maxParams() int {
+	return sqliteMaxParams
+}
+
+func (sqliteDialect) resolvesConflicts() bool {
+	return true
+}
+
+func (sqliteDialect) conflictSql(policy ConflictPolicy, unique, columns []string) string {
+	return ""
+}
+
+// insertedIds counts back from the last row, sqlite writes one statement
+// at a time and numbers its rows consecutively.
+func (sqliteDialect) insertedIds(last int64, n int) []int64 {
+	ids := make([]int64, n)
+	for i := range ids {
+		ids[i] = last - int64(n-1-i)
+	}
+	return ids
+}
+
+func (sqliteDialect) returningSql() (output, returning string) {
+	return "", ""
+}
+
+func (sqliteDialect) addColumnSql(table, column string, schema tableSchema) []string {
+	if column == schema.tenantColumn {
+		definition := strings.TrimSuffix(strings.TrimSpace(fmt.Sprintf(sqliteTenantColumnSql, column)), ",")
+		return []string{
+			fmt.Sprintf(addColumnSql, table, definition),
+			fmt.Sprintf(sqliteCreateIndexSql, table, column, table, column),
+		}
+	}
+	if column == schema.actorColumn {
+		return []string{fmt.Sprintf(addColumnSql, table, fmt.Sprintf(sqliteActorColumnSql, column))}
+	}
+	statements := []string{fmt.Sprintf(addColumnSql, table, sqliteColumnSql[column])}
+	if indexedColumns[column] {
+		statements = append(statements, fmt.Sprintf(sqliteCreateIndexSql, table, column, table, column))
+	}
+	return statements
+}
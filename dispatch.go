@@ -0,0 +1,85 @@
+package adapter
+
+import (
+	"fmt"
+
+	"github.com/casbin/casbin/v2/persist"
+)
+
+// DispatchMode tells whether the adapter writes the changes it hands to its
+// dispatcher.
+type DispatchMode int
+
+const (
+	// DispatchAfterWrite writes a change and hands it to the dispatcher once
+	// it is stored.
+	DispatchAfterWrite DispatchMode = iota
+	// DispatchOnly hands a change to the dispatcher instead of writing it,
+	// the dispatcher applies it on every node, e.g. through the adapter
+	// returned by Local.
+	DispatchOnly
+)
+
+var _ persist.Dispatcher = NopDispatcher{}
+
+// NopDispatcher is a persist.Dispatcher that ignores every change. Embed it
+// to implement only the methods of interest.
+type NopDispatcher struct{}
+
+func (NopDispatcher) AddPolicies(sec string, ptype string, rules [][]string) error    { return nil }
+func (NopDispatcher) RemovePolicies(sec string, ptype string, rules [][]string) error { return nil }
+func (NopDispatcher) RemoveFilteredPolicy(sec string, ptype string, fieldIndex int, fieldValues ...string) error {
+	return nil
+}
+func (NopDispatcher) ClearPolicy() error { return nil }
+func (NopDispatcher) UpdatePolicy(sec string, ptype string, oldRule, newRule []string) error {
+	return nil
+}
+func (NopDispatcher) UpdatePolicies(sec string, ptype string, oldRules, newRules [][]string) error {
+	return nil
+}
+func (NopDispatcher) UpdateFilteredPolicies(sec string, ptype string, oldRules [][]string, newRules [][]string) error {
+	return nil
+}
+
+// WithDispatcher hands the rule changes of the adapter methods casbin
+// dispatches, AddPolicy, RemovePolicy, UpdatePolicy and their variants as
+// well as ClearPolicy, to d, so that they are broadcast to all the nodes
+// even when the adapter is called without an enforcer. mode selects
+// whether the changes are written locally too. The other writes, such as
+// SavePolicy and imports, are only written locally. An adapter bound to a
+// transaction dispatches its changes as they are written, before the
+// commit.
+func WithDispatcher(d persist.Dispatcher, mode DispatchMode) AdapterOption {
+	return AdapterOption{apply: func(a *Adapter) {
+		a.dispatcher = d
+		a.dispatchMode = mode
+	}}
+}
+
+// Local returns a copy of the adapter that writes its changes without
+// handing them to the dispatcher, for the dispatcher to apply the changes
+// it received.
+func (a *Adapter) Local() *Adapter {
+	clone := *a
+	clone.dispatcher = nil
+	clone.isFiltered = false
+	return &clone
+}
+
+// dispatchOnly reports whether the changes are dispatched instead of
+// written.
+func (a *Adapter) dispatchOnly() bool {
+	return a.dispatcher != nil && a.dispatchMode == DispatchOnly
+}
+
+// dispatch hands a change to the dispatcher, if any.
+func (a *Adapter) dispatch(fn func(d persist.Dispatcher) error) error {
+	if a.dispatcher == nil {
+		return nil
+	}
+	if err := fn(a.dispatcher); err != nil {
+		return fmt.Errorf("failed to dispatch change: %w", err)
+	}
+	return nil
+}
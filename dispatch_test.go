@@ -0,0 +1,130 @@
+package adapter
+
+import (
+	"context"
+	"fmt"
+	"reflect"
+	"testing"
+
+	"github.com/casbin/casbin/v2"
+)
+
+// recordingDispatcher records the dispatched changes and applies them to
+// the local adapters of its nodes, like a replicated log would.
+type recordingDispatcher struct {
+	NopDispatcher
+	calls []string
+	nodes []*Adapter
+}
+
+func (d *recordingDispatcher) apply(call string, fn func(a *Adapter) error) error {
+	d.calls = append(d.calls, call)
+	for _, node := range d.nodes {
+		if err := fn(node.Local()); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (d *recordingDispatcher) AddPolicies(sec string, ptype string, rules [][]string) error {
+	return d.apply(fmt.Sprintf("AddPolicies %s %s %v", sec, ptype, rules), func(a *Adapter) error {
+		return a.AddPolicies(sec, ptype, rules)
+	})
+}
+
+func (d *recordingDispatcher) RemovePolicies(sec string, ptype string, rules [][]string) error {
+	return d.apply(fmt.Sprintf("RemovePolicies %s %s %v", sec, ptype, rules), func(a *Adapter) error {
+		return a.RemovePolicies(sec, ptype, rules)
+	})
+}
+
+func (d *recordingDispatcher) RemoveFilteredPolicy(sec string, ptype string, fieldIndex int, fieldValues ...string) error {
+	return d.apply(fmt.Sprintf("RemoveFilteredPolicy %s %s %d %v", sec, ptype, fieldIndex, fieldValues), func(a *Adapter) error {
+		return a.RemoveFilteredPolicy(sec, ptype, fieldIndex, fieldValues...)
+	})
+}
+
+func (d *recordingDispatcher) UpdatePolicy(sec string, ptype string, oldRule, newRule []string) error {
+	return d.apply(fmt.Sprintf("UpdatePolicy %s %s %v %v", sec, ptype, oldRule, newRule), func(a *Adapter) error {
+		return a.UpdatePolicy(sec, ptype, oldRule, newRule)
+	})
+}
+
+func (d *recordingDispatcher) UpdateFilteredPolicies(sec string, ptype string, oldRules [][]string, newRules [][]string) error {
+	return d.apply(fmt.Sprintf("UpdateFilteredPolicies %s %s %v %v", sec, ptype, oldRules, newRules), func(a *Adapter) error {
+		return a.UpdatePolicies(sec, ptype, oldRules, newRules)
+	})
+}
+
+func (d *recordingDispatcher) ClearPolicy() error {
+	return d.apply("ClearPolicy", func(a *Adapter) error {
+		return a.ClearPolicy(context.Background())
+	})
+}
+
+func TestDispatcher(t *testing.T) {
+	changes := func(a *Adapter) {
+		t.Helper()
+		steps := []error{
+			a.AddPolicy("p", "p", []string{"alice", "data1", "read"}),
+			a.AddPolicies("p", "p", [][]string{{"bob", "data2", "write"}, {"carol", "data3", "read"}}),
+			a.RemovePolicy("p", "p", []string{"carol", "data3", "read"}),
+			a.UpdatePolicy("p", "p", []string{"bob", "data2", "write"}, []string{"bob", "data2", "read"}),
+			a.RemoveFilteredPolicy("p", "p", 1, "data9"),
+		}
+		_, err := a.UpdateFilteredPolicies("p", "p", [][]string{{"alice", "data1", "write"}}, 0, "alice")
+		steps = append(steps, err)
+		for i, err := range steps {
+			if err != nil {
+				t.Fatalf("change %d failed: %v", i, err)
+			}
+		}
+	}
+	wantCalls := []string{
+		"AddPolicies p p [[alice data1 read]]",
+		"AddPolicies p p [[bob data2 write] [carol data3 read]]",
+		"RemovePolicies p p [[carol data3 read]]",
+		"UpdatePolicy p p [bob data2 write] [bob data2 read]",
+		"RemoveFilteredPolicy p p 1 [data9]",
+		"UpdateFilteredPolicies p p [[alice data1 read]] [[alice data1 write]]",
+	}
+	wantPolicy := [][]string{{"bob", "data2", "read"}, {"alice", "data1", "write"}}
+
+	t.Run("after write", func(t *testing.T) {
+		d := &recordingDispatcher{}
+		a := newSqliteAdapter(t, WithDispatcher(d, DispatchAfterWrite))
+		changes(a)
+		if !reflect.DeepEqual(d.calls, wantCalls) {
+			t.Errorf("dispatched %q, supposed to be %q", d.calls, wantCalls)
+		}
+		e, _ := casbin.NewEnforcer("examples/rbac_model.conf", a)
+		testGetPolicyWithoutOrder(t, e, wantPolicy)
+	})
+
+	t.Run("dispatch only", func(t *testing.T) {
+		// The changes are only stored by the nodes applying them.
+		d := &recordingDispatcher{}
+		nodes := []*Adapter{
+			newSqliteAdapter(t, WithDispatcher(d, DispatchOnly), WithAllowDestructive()),
+			newSqliteAdapter(t, WithDispatcher(d, DispatchOnly), WithAllowDestructive()),
+		}
+		d.nodes = nodes
+		changes(nodes[0])
+		if !reflect.DeepEqual(d.calls, wantCalls) {
+			t.Errorf("dispatched %q, supposed to be %q", d.calls, wantCalls)
+		}
+		for _, node := range nodes {
+			e, _ := casbin.NewEnforcer("examples/rbac_model.conf", node)
+			testGetPolicyWithoutOrder(t, e, wantPolicy)
+		}
+
+		if err := nodes[1].ClearPolicy(context.Background()); err != nil {
+			t.Fatalf("ClearPolicy failed: %v", err)
+		}
+		for _, node := range nodes {
+			e, _ := casbin.NewEnforcer("examples/rbac_model.conf", node)
+			testGetPolicyWithoutOrder(t, e, [][]string{})
+		}
+	})
+}
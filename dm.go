@@ -0,0 +1,237 @@
+package adapter
+
+import (
+	"fmt"
+	"strings"
+)
+
+const (
+	dmCreateTableSql = `
+CREATE TABLE IF NOT EXISTS %s (
+  id BIGINT IDENTITY(1,1) NOT NULL,
+  p_type VARCHAR(10) DEFAULT NULL,
+  v0 VARCHAR(256) DEFAULT NULL,
+  v1 VARCHAR(256) DEFAULT NULL,
+  v2 VARCHAR(256) DEFAULT NULL,
+  v3 VARCHAR(256) DEFAULT NULL,
+  v4 VARCHAR(256) DEFAULT NULL,
+  v5 VARCHAR(256) DEFAULT NULL,
+%s  created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+  PRIMARY KEY (id)
+)
+`
+	dmCreateHistoryTableSql = `
+CREATE TABLE IF NOT EXISTS %s (
+  id BIGINT IDENTITY(1,1) NOT NULL,
+  op VARCHAR(10) NOT NULL,
+  p_type VARCHAR(10) DEFAULT NULL,
+  v0 VARCHAR(256) DEFAULT NULL,
+  v1 VARCHAR(256) DEFAULT NULL,
+  v2 VARCHAR(256) DEFAULT NULL,
+  v3 VARCHAR(256) DEFAULT NULL,
+  v4 VARCHAR(256) DEFAULT NULL,
+  v5 VARCHAR(256) DEFAULT NULL,
+%s  changed_at TIMESTAMP NOT NULL,
+  PRIMARY KEY (id)
+)
+`
+	dmCreateStagingTableSql = "CREATE GLOBAL TEMPORARY TABLE IF NOT EXISTS %s (\n  id BIGINT IDENTITY(1,1) NOT NULL PRIMARY KEY,\n  %s\n) ON COMMIT PRESERVE ROWS"
+	dmTenantColumnSql       = "  %s VARCHAR(64) DEFAULT '' NOT NULL,\n"
+	dmActorColumnSql        = "%s VARCHAR(255) DEFAULT '' NOT NULL"
+	dmTypedColumnSql        = "%s %s DEFAULT NULL"
+	dmCreateIndexSql        = `CREATE INDEX IF NOT EXISTS idx_%s_%s ON %s (%s)`
+	dmUniqueIndexSql        = `CREATE UNIQUE INDEX IF NOT EXISTS uniq_%s_rule ON %s (%s)`
+	dmTruncateTableSql      = `TRUNCATE TABLE %s`
+	dmCreateLikeSql         = `CREATE TABLE %s LIKE %s`
+	dmRenameTableSql        = `ALTER TABLE %s RENAME TO %s`
+	dmVersionTableSql       = `CREATE TABLE IF NOT EXISTS %s (id INT NOT NULL PRIMARY KEY, version BIGINT DEFAULT 0 NOT NULL)`
+	dmRuleColumnSql         = `CREATE TABLE IF NOT EXISTS %s (id BIGINT IDENTITY(1,1) NOT NULL, %s TEXT DEFAULT NULL, created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP, PRIMARY KEY (id))`
+	dmJSONTableSql          = `CREATE TABLE IF NOT EXISTS %s (id BIGINT IDENTITY(1,1) NOT NULL, p_type VARCHAR(32) DEFAULT '' NOT NULL, vals TEXT DEFAULT NULL, created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP, PRIMARY KEY (id))`
+
+	// dmMaxParams is the limit of the parameters bound by a statement.
+	dmMaxParams = 2048
+)
+
+// dmColumnSql defines the columns of the create statements, they are used
+// to add missing columns to existing tables.
+var dmColumnSql = map[string]string{
+	"p_type":     "p_type VARCHAR(10) DEFAULT NULL",
+	"v0":         "v0 VARCHAR(256) DEFAULT NULL",
+	"v1":         "v1 VARCHAR(256) DEFAULT NULL",
+	"v2":         "v2 VARCHAR(256) DEFAULT NULL",
+	"v3":         "v3 VARCHAR(256) DEFAULT NULL",
+	"v4":         "v4 VARCHAR(256) DEFAULT NULL",
+	"v5":         "v5 VARCHAR(256) DEFAULT NULL",
+	"created_at": "created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP",
+	"updated_at": "updated_at TIMESTAMP DEFAULT NULL",
+	"deleted_at": "deleted_at TIMESTAMP DEFAULT NULL",
+	"rule_hash":  "rule_hash CHAR(64) DEFAULT NULL",
+}
+
+// dmDialect generates the statements of Dameng DM8. The identifiers are
+// left unquoted, as the gdb driver strips the quotes of the statements,
+// and folded to upper case by the database.
+type dmDialect struct{}
+
+// createTableSql skips the comments of schema, DM keeps them by separate
+// COMMENT statements.
+func (d dmDialect) createTableSql(table string, schema tableSchema) []string {
+	var columns string
+	if schema.ruleHash {
+		columns = "  " + dmColumnSql[ruleHashColumn] + ",\n"
+	}
+	return d.withTenant(dmCreateTableSql, table, columns, schema)
+}
+
+func (d dmDialect) createHistoryTableSql(table string, schema tableSchema) []string {
+	statements := d.withTenant(dmCreateHistoryTableSql, table, "", schema)
+	return append(statements, fmt.Sprintf(dmCreateIndexSql, table, "changed_at", table, "changed_at"))
+}
+
+func (dmDialect) createVersionTableSql(table string) string {
+	return fmt.Sprintf(dmVersionTableSql, table)
+}
+
+func (dmDialect) createRuleColumnTableSql(table, column string) string {
+	return fmt.Sprintf(dmRuleColumnSql, table, column)
+}
+
+func (dmDialect) createJSONTableSql(table string) []string {
+	return []string{fmt.Sprintf(dmJSONTableSql, table)}
+}
+
+// jsonValueSql is empty, the values of the arrays are matched once read.
+func (dmDialect) jsonValueSql(column string, index int) string {
+	return ""
+}
+
+func (dmDialect) jsonEqualSql(column string) string {
+	return column + " = ?"
+}
+
+// withTenant renders the create statement with the optional columns, the
+// index of the tenant column is created by a separate statement.
+func (dmDialect) withTenant(createSql, table, columns string, schema tableSchema) []string {
+	if schema.tenantColumn == "" {
+		return []string{withColumnTypes(fmt.Sprintf(createSql, table, columns), schema, dmTypedColumnSql)}
+	}
+	columns = fmt.Sprintf(dmTenantColumnSql, schema.tenantColumn) + columns
+	return []string{
+		withColumnTypes(fmt.Sprintf(createSql, table, columns), schema, dmTypedColumnSql),
+		fmt.Sprintf(dmCreateIndexSql, table, schema.tenantColumn, table, schema.tenantColumn),
+	}
+}
+
+func (dmDialect) truncateTableSql(table string) string {
+	return fmt.Sprintf(dmTruncateTableSql, table)
+}
+
+func (dmDialect) transactionalTruncate() bool {
+	return false
+}
+
+func (dmDialect) nullSafeEqualSql(left, right string) string {
+	return fmt.Sprintf(orNullEqualSql, left, right, left, right)
+}
+
+// createStagingTableSql creates a global temporary table, whose rows are
+// private to the session. It is kept once created, dropStagingTableSql
+// only empties it, as the DDL of DM commits the transaction of the save.
+func (dmDialect) createStagingTableSql(table string, schema tableSchema) string {
+	return fmt.Sprintf(dmCreateStagingTableSql, table, stagingColumnsSql(dmColumnSql, schema, dmTypedColumnSql))
+}
+
+func (dmDialect) dropStagingTableSql(table string) string {
+	return fmt.Sprintf("DELETE FROM %s", table)
+}
+
+func (dmDialect) createTableLikeSql(table, like string, schema tableSchema) []string {
+	return []string{fmt.Sprintf(dmCreateLikeSql, table, like)}
+}
+
+func (dmDialect) swapTablesSql(table, staging, old string) []string {
+	return []string{
+		fmt.Sprintf(dmRenameTableSql, table, old),
+		fmt.Sprintf(dmRenameTableSql, staging, table),
+	}
+}
+
+func (dmDialect) createUniqueIndexSql(table string, columns []string) string {
+	return fmt.Sprintf(dmUniqueIndexSql, table, table, strings.Join(columns, ", "))
+}
+
+func (dmDialect) maxWriters() int {
+	return 0
+}
+
+func (dmDialect) maxGroupedConditions() int {
+	return 0
+}
+
+// lockSql is empty, the locks of DM are bound to transactions.
+func (dmDialect) lockSql() (lock, unlock string) {
+	return "", ""
+}
+
+// addPartitionSql and truncatePartitionSql are empty, the adapter doesn't
+// manage the partitions of DM.
+func (dmDialect) addPartitionSql(table, partition, tenant string) string {
+	return ""
+}
+
+func (dmDialect) truncatePartitionSql(table, partition string) string {
+	return ""
+}
+
+func (dmDialect) optimizeTableSql(table string) string {
+	return ""
+}
+
+// insertedIds is nil, the driver doesn't tell the last insert id.
+func (dmDialect) insertedIds(last int64, n int) []int64 {
+	return nil
+}
+
+func (dmDialect) returningSql() (output, returning string) {
+	return "", ""
+}
+
+func (dmDialect) readPastHint() string {
+	return ""
+}
+
+func (dmDialect) maxInsertRows() int {
+	return 0
+}
+
+func (dmDialect) maxParams() int {
+	return dmMaxParams
+}
+
+// resolvesConflicts is false, the driver has neither INSERT IGNORE nor
+// REPLACE, the conflicts are resolved by looking up the stored rules.
+func (dmDialect) resolvesConflicts() bool {
+	return false
+}
+
+func (dmDialect) conflictSql(policy ConflictPolicy, unique, columns []string) string {
+	return ""
+}
+
+func (dmDialect) addColumnSql(table, column string, schema tableSchema) []string {
+	if column == schema.tenantColumn {
+		definition := strings.TrimSuffix(strings.TrimSpace(fmt.Sprintf(dmTenantColumnSql, column)), ",")
+		return []string{
+			fmt.Sprintf(addColumnSql, table, definition),
+			fmt.Sprintf(dmCreateIndexSql, table, column, table, column),
+		}
+	}
+	if column == schema.actorColumn {
+		return []string{fmt.Sprintf(addColumnSql, table, fmt.Sprintf(dmActorColumnSql, column))}
+	}
+	statements := []string{fmt.Sprintf(addColumnSql, table, dmColumnSql[column])}
+	if indexedColumns[column] {
+		statements = append(statements, fmt.Sprintf(dmCreateIndexSql, table, column, table, column))
+	}
+	return statements
+}
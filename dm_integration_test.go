@@ -0,0 +1,35 @@
+//go:build dm
+
+package adapter
+
+import (
+	"context"
+	"testing"
+
+	_ "github.com/gogf/gf/contrib/drivers/dm/v2"
+	"github.com/gogf/gf/v2/database/gdb"
+)
+
+// TestDMAdapters needs a DM8 server and the dm driver module, it runs with
+// go test -tags dm.
+func TestDMAdapters(t *testing.T) {
+	db, err := gdb.New(gdb.ConfigNode{
+		Type:  "dm",
+		Host:  "127.0.0.1",
+		Port:  "5236",
+		User:  "SYSDBA",
+		Pass:  "SYSDBA001",
+		Name:  "CASBIN",
+		Debug: true,
+	})
+	if err != nil {
+		t.Fatalf("failed to create database connection: %v", err)
+	}
+
+	a, err := NewAdapter(context.Background(), "", "", db)
+	if err != nil {
+		t.Fatalf("failed to create adapter: %v", err)
+	}
+
+	runAdapterSuite(t, a)
+}
@@ -0,0 +1,73 @@
+package adapter
+
+import (
+	"strings"
+	"testing"
+)
+
+// dmLimitedDialect has the limits of DM over sqlite.
+type dmLimitedDialect struct {
+	dialect
+}
+
+func (dmLimitedDialect) maxInsertRows() int {
+	return 2
+}
+
+func (dmLimitedDialect) resolvesConflicts() bool {
+	return false
+}
+
+func TestDMDialect(t *testing.T) {
+	d := dmDialect{}
+	schema := tableSchema{tenantColumn: "tenant_id"}
+	statements := d.createTableSql("casbin_rule", schema)
+	if len(statements) != 2 {
+		t.Fatalf("create statements %q, supposed to be the table and the tenant index", statements)
+	}
+	for _, want := range []string{
+		"CREATE TABLE IF NOT EXISTS casbin_rule (\n  id BIGINT IDENTITY(1,1) NOT NULL,",
+		"  tenant_id VARCHAR(64) DEFAULT '' NOT NULL,\n  created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,\n  PRIMARY KEY (id)",
+	} {
+		if !strings.Contains(statements[0], want) {
+			t.Errorf("create statement lacks %q:\n%s", want, statements[0])
+		}
+	}
+	if want := "CREATE INDEX IF NOT EXISTS idx_casbin_rule_tenant_id ON casbin_rule (tenant_id)"; statements[1] != want {
+		t.Errorf("tenant index statement %q, supposed to be %q", statements[1], want)
+	}
+	if sql := d.truncateTableSql("casbin_rule"); sql != "TRUNCATE TABLE casbin_rule" {
+		t.Errorf("truncate statement %q", sql)
+	}
+	if sql := d.addColumnSql("casbin_rule", "tenant_id", schema)[0]; sql != "ALTER TABLE casbin_rule ADD COLUMN tenant_id VARCHAR(64) DEFAULT '' NOT NULL" {
+		t.Errorf("add tenant statement %q", sql)
+	}
+	if sql := d.createStagingTableSql("casbin_rule_staging", schema); !strings.HasPrefix(sql, "CREATE GLOBAL TEMPORARY TABLE") {
+		t.Errorf("staging statement %q, supposed to create a temporary table", sql)
+	}
+	if d.resolvesConflicts() {
+		t.Error("DM is supposed to look up the conflicts")
+	}
+}
+
+func TestInsertLimits(t *testing.T) {
+	a := newSqliteAdapter(t, WithUniqueIndex(), WithConflictPolicy(ConflictSkip))
+	a.dialect = dmLimitedDialect{a.dialect}
+	if size := a.insertSize(); size != 2 {
+		t.Fatalf("insert size %d, supposed to be the limit of the dialect", size)
+	}
+
+	rules := [][]string{{"alice", "data1", "read"}, {"bob", "data2", "write"}, {"carol", "data3", "read"}, {"bob", "data2", "write"}}
+	for run, want := range []int64{3, 0} {
+		inserted, err := a.AddPoliciesOnConflict("p", "p", rules, ConflictSkip)
+		if err != nil {
+			t.Fatalf("run %d: AddPoliciesOnConflict failed: %v", run, err)
+		}
+		if inserted != want {
+			t.Errorf("run %d: inserted %d rules, supposed to be %d", run, inserted, want)
+		}
+	}
+	if ids := storedIDs(t, a); len(ids) != 3 {
+		t.Errorf("stored ids %v, supposed to be 3 rows", ids)
+	}
+}
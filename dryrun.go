@@ -0,0 +1,256 @@
+package adapter
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"sort"
+	"strings"
+
+	"github.com/gogf/gf/v2/database/gdb"
+	"github.com/gogf/gf/v2/util/gconv"
+)
+
+// ErrDryRunUnsupported is returned in a dry run by the methods running
+// statements it can't plan, such as DDL, or needing their results.
+var ErrDryRunUnsupported = errors.New("not supported in a dry run")
+
+// errDryRun rolls back the transaction of a dry run.
+var errDryRun = errors.New("dry run")
+
+// PlannedStatement is a statement a change would run, recorded by DryRun.
+type PlannedStatement struct {
+	// Op is the name of the adapter method running the statement, e.g.
+	// AddPolicies.
+	Op string
+	// SQL is the statement with the placeholders of the driver, and Args
+	// the arguments bound to them.
+	SQL  string
+	Args []any
+}
+
+// dryRunPlan collects the statements of a dry run.
+type dryRunPlan struct {
+	db         gdb.DB
+	statements []PlannedStatement
+}
+
+// plannedOpKey is the context key of the operation a dry run records the
+// statements of.
+type plannedOpKey struct{}
+
+// DryRun runs fn with a copy of the adapter bound to a transaction that is
+// always rolled back, and returns the write statements its changes would
+// run, in order. The writes are recorded instead of being sent, while the
+// reads run, so later changes and loads of fn see the stored rules without
+// the earlier changes. Methods running other statements, e.g. DropTable,
+// Backup or AddPoliciesReturningIDs, and the stable saves fail with
+// ErrDryRunUnsupported. The copy calls no hooks, delivers no events and
+// notifies no watcher or dispatcher, and SavePolicy deletes the rows
+// instead of truncating the table. The returned error is the one of fn.
+func (a *Adapter) DryRun(ctx context.Context, fn func(a *Adapter) error) ([]PlannedStatement, error) {
+	plan := &dryRunPlan{db: a.db}
+	err := a.db.Transaction(ctx, func(ctx context.Context, tx gdb.TX) error {
+		dry := a.WithTx(tx)
+		dry.ctx = ctx
+		dry.plan = plan
+		dry.hooks = &hookList{}
+		dry.pendingEvents = &[]PolicyEvent{}
+		dry.dispatcher = nil
+		if err := fn(dry); err != nil {
+			return err
+		}
+		return errDryRun
+	})
+	if err != nil && !errors.Is(err, errDryRun) {
+		return plan.statements, err
+	}
+	return plan.statements, nil
+}
+
+// checkDryRun returns ErrDryRunUnsupported in a dry run.
+func (a *Adapter) checkDryRun() error {
+	if a.plan != nil {
+		return ErrDryRunUnsupported
+	}
+	return nil
+}
+
+// record returns write recording its statements for the operation method.
+func (p *dryRunPlan) record(method string, write func(ctx context.Context) error) func(ctx context.Context) error {
+	return func(ctx context.Context) error {
+		return write(context.WithValue(ctx, plannedOpKey{}, method))
+	}
+}
+
+// add records the statement query with args, formatted for the driver.
+func (p *dryRunPlan) add(ctx context.Context, query string, args []any) error {
+	query, args = p.db.GetCore().FormatSqlBeforeExecuting(query, args)
+	query, args, err := p.db.DoFilter(ctx, nil, query, args)
+	if err != nil {
+		return fmt.Errorf("failed to plan statement: %w", err)
+	}
+	method, _ := ctx.Value(plannedOpKey{}).(string)
+	p.statements = append(p.statements, PlannedStatement{Op: method, SQL: query, Args: args})
+	return nil
+}
+
+// planned returns m recording its writes in a dry run. The hooks set on m
+// must be set before.
+func (a *Adapter) planned(m *gdb.Model, hook gdb.HookHandler) *gdb.Model {
+	if a.plan != nil {
+		hook = a.plan.hook(hook)
+	}
+	return m.Hook(hook)
+}
+
+// hook returns next recording the inserts, updates and deletes instead of
+// running them. next still prepares the statements, e.g. encodes the
+// values, as gdb builds them without sending them.
+func (p *dryRunPlan) hook(next gdb.HookHandler) gdb.HookHandler {
+	hook := next
+	hook.Insert = func(ctx context.Context, in *gdb.HookInsertInput) (sql.Result, error) {
+		if err := p.prepare(ctx, func(ctx context.Context) (sql.Result, error) {
+			if next.Insert != nil {
+				return next.Insert(ctx, in)
+			}
+			return in.Next(ctx)
+		}); err != nil {
+			return nil, err
+		}
+		return new(gdb.SqlResult), p.addInsert(ctx, in)
+	}
+	hook.Update = func(ctx context.Context, in *gdb.HookUpdateInput) (sql.Result, error) {
+		if err := p.prepare(ctx, func(ctx context.Context) (sql.Result, error) {
+			if next.Update != nil {
+				return next.Update(ctx, in)
+			}
+			return in.Next(ctx)
+		}); err != nil {
+			return nil, err
+		}
+		return new(gdb.SqlResult), p.addUpdate(ctx, in)
+	}
+	hook.Delete = func(ctx context.Context, in *gdb.HookDeleteInput) (sql.Result, error) {
+		if err := p.prepare(ctx, func(ctx context.Context) (sql.Result, error) {
+			if next.Delete != nil {
+				return next.Delete(ctx, in)
+			}
+			return in.Next(ctx)
+		}); err != nil {
+			return nil, err
+		}
+		table := p.db.GetCore().QuotePrefixTableName(in.Table)
+		return new(gdb.SqlResult), p.add(ctx, fmt.Sprintf("DELETE FROM %s%s", table, in.Condition), in.Args)
+	}
+	return hook
+}
+
+// prepare runs write with gdb building its statements without sending
+// them, as gdb.ToSQL does.
+func (p *dryRunPlan) prepare(ctx context.Context, write func(ctx context.Context) (sql.Result, error)) error {
+	_, err := gdb.ToSQL(ctx, func(ctx context.Context) error {
+		_, err := write(ctx)
+		return err
+	})
+	return err
+}
+
+// addInsert records the inserts of in, a statement per batch.
+func (p *dryRunPlan) addInsert(ctx context.Context, in *gdb.HookInsertInput) error {
+	if len(in.Data) == 0 {
+		return nil
+	}
+	keys := make([]string, 0, len(in.Data[0]))
+	for key := range in.Data[0] {
+		keys = append(keys, key)
+	}
+	keys, err := p.fieldsInSequence(ctx, in.Table, keys)
+	if err != nil {
+		return err
+	}
+	charL, charR := p.db.GetChars()
+	head := fmt.Sprintf("%s INTO %s(%s%s%s) VALUES",
+		gdb.GetInsertOperationByOption(in.Option.InsertOption), p.db.GetCore().QuotePrefixTableName(in.Table),
+		charL, strings.Join(keys, charR+","+charL), charR)
+	var upsert string
+	if in.Option.InsertOption == gdb.InsertOptionSave {
+		if upsert, err = p.db.FormatUpsert(keys, in.Data, in.Option); err != nil {
+			return fmt.Errorf("failed to plan statement: %w", err)
+		}
+	}
+	size := in.Option.BatchCount
+	if size <= 0 {
+		size = len(in.Data)
+	}
+	for i := 0; i < len(in.Data); i += size {
+		var holders []string
+		var args []any
+		for _, record := range in.Data[i:min(i+size, len(in.Data))] {
+			values := make([]string, 0, len(keys))
+			for _, key := range keys {
+				if raw, ok := record[key].(gdb.Raw); ok {
+					values = append(values, gconv.String(raw))
+					continue
+				}
+				values = append(values, "?")
+				args = append(args, record[key])
+			}
+			holders = append(holders, "("+strings.Join(values, ",")+")")
+		}
+		query := head + strings.Join(holders, ",")
+		if upsert != "" {
+			query += " " + upsert
+		}
+		if err := p.add(ctx, query, args); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// addUpdate records the update of in.
+func (p *dryRunPlan) addUpdate(ctx context.Context, in *gdb.HookUpdateInput) error {
+	updates := gconv.String(in.Data)
+	var args []any
+	if data, ok := in.Data.(map[string]any); ok {
+		keys := make([]string, 0, len(data))
+		for key := range data {
+			keys = append(keys, key)
+		}
+		keys, err := p.fieldsInSequence(ctx, in.Table, keys)
+		if err != nil {
+			return err
+		}
+		fields := make([]string, 0, len(keys))
+		for _, key := range keys {
+			if raw, ok := data[key].(gdb.Raw); ok {
+				fields = append(fields, p.db.GetCore().QuoteWord(key)+"="+gconv.String(raw))
+				continue
+			}
+			fields = append(fields, p.db.GetCore().QuoteWord(key)+"=?")
+			args = append(args, data[key])
+		}
+		updates = strings.Join(fields, ",")
+	}
+	table := p.db.GetCore().QuotePrefixTableName(in.Table)
+	return p.add(ctx, fmt.Sprintf("UPDATE %s SET %s%s", table, updates, in.Condition), append(args, in.Args...))
+}
+
+// fieldsInSequence orders keys like the columns of table, as gdb does.
+func (p *dryRunPlan) fieldsInSequence(ctx context.Context, table string, keys []string) ([]string, error) {
+	fields, err := p.db.TableFields(ctx, table)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get table fields: %w", err)
+	}
+	sort.Slice(keys, func(i, j int) bool {
+		fi, okI := fields[keys[i]]
+		fj, okJ := fields[keys[j]]
+		if okI != okJ || !okI {
+			return okI || !okJ && keys[i] < keys[j]
+		}
+		return fi.Index < fj.Index
+	})
+	return keys, nil
+}
@@ -0,0 +1,138 @@
+package adapter
+
+import (
+	"context"
+	"errors"
+	"slices"
+	"strings"
+	"testing"
+
+	"github.com/casbin/casbin/v2"
+	"github.com/casbin/casbin/v2/model"
+)
+
+func TestDryRun(t *testing.T) {
+	ctx := context.Background()
+	a := newSqliteAdapter(t, WithBatchSize(2))
+	initPolicy(t, a)
+	events := a.Events()
+
+	m, _ := model.NewModelFromFile("examples/rbac_model.conf")
+	for _, rule := range [][]string{{"alice", "data1", "read"}, {"bob", "data2", "write"}, {"carol", "data3", "read"}} {
+		m.AddPolicy("p", "p", rule)
+	}
+	var loaded int
+	plan, err := a.DryRun(ctx, func(a *Adapter) error {
+		if err := a.AddPolicy("p", "p", []string{"dave", "data1", "read"}); err != nil {
+			return err
+		}
+		if err := a.RemovePolicy("p", "p", []string{"alice", "data1", "read"}); err != nil {
+			return err
+		}
+		if err := a.SavePolicy(m); err != nil {
+			return err
+		}
+		// Loads run and don't see the planned changes.
+		e, _ := casbin.NewEnforcer("examples/rbac_model.conf")
+		if err := a.LoadPolicy(e.GetModel()); err != nil {
+			return err
+		}
+		loaded = len(e.GetModel()["p"]["p"].Policy)
+		return nil
+	})
+	if err != nil {
+		t.Fatalf("DryRun failed: %v", err)
+	}
+	if loaded != 4 {
+		t.Errorf("%d rules loaded in the dry run, supposed to be the 4 stored ones", loaded)
+	}
+
+	want := []struct{ op, verb string }{
+		{"AddPolicy", "INSERT"},
+		{"RemovePolicy", "DELETE"},
+		{"SavePolicy", "DELETE"},
+		{"SavePolicy", "INSERT"},
+		{"SavePolicy", "INSERT"},
+	}
+	if len(plan) != len(want) {
+		t.Fatalf("%d planned statements, supposed to be %d: %v", len(plan), len(want), plan)
+	}
+	for i, w := range want {
+		if plan[i].Op != w.op || !strings.HasPrefix(plan[i].SQL, w.verb) {
+			t.Errorf("statement %d is %s %q, supposed to be %s %s", i, plan[i].Op, plan[i].SQL, w.op, w.verb)
+		}
+	}
+	if !strings.Contains(plan[0].SQL, "?") || !slices.Contains(plan[0].Args, any("dave")) {
+		t.Errorf("planned insert %q with %v misses its rule", plan[0].SQL, plan[0].Args)
+	}
+	if !strings.Contains(plan[1].SQL, " WHERE ") || !slices.Contains(plan[1].Args, any("alice")) {
+		t.Errorf("planned delete %q with %v misses its condition", plan[1].SQL, plan[1].Args)
+	}
+
+	// Nothing was written and no event delivered.
+	e, _ := casbin.NewEnforcer("examples/rbac_model.conf", a)
+	testGetPolicyWithoutOrder(t, e, [][]string{{"alice", "data1", "read"}, {"bob", "data2", "write"}, {"data2_admin", "data2", "read"}, {"data2_admin", "data2", "write"}})
+	select {
+	case event := <-events:
+		t.Errorf("dry run delivered event %v", event)
+	default:
+	}
+
+	// The error of fn is returned with the statements planned until then.
+	failure := errors.New("failure")
+	plan, err = a.DryRun(ctx, func(a *Adapter) error {
+		if err := a.AddPolicy("p", "p", []string{"dave", "data1", "read"}); err != nil {
+			return err
+		}
+		return failure
+	})
+	if !errors.Is(err, failure) {
+		t.Errorf("DryRun returned %v, supposed to be %v", err, failure)
+	}
+	if len(plan) != 1 {
+		t.Errorf("%d planned statements, supposed to be 1", len(plan))
+	}
+}
+
+func TestDryRunUnsupported(t *testing.T) {
+	a := newSqliteAdapter(t, WithAllowDestructive())
+	initPolicy(t, a)
+
+	_, err := a.DryRun(context.Background(), func(a *Adapter) error {
+		if _, err := a.AddPoliciesReturningIDs("p", "p", [][]string{{"dave", "data1", "read"}}); !errors.Is(err, ErrDryRunUnsupported) {
+			t.Errorf("AddPoliciesReturningIDs error %v, supposed to be %v", err, ErrDryRunUnsupported)
+		}
+		if _, err := a.Backup(a.ctx, "dry"); !errors.Is(err, ErrDryRunUnsupported) {
+			t.Errorf("Backup error %v, supposed to be %v", err, ErrDryRunUnsupported)
+		}
+		if err := a.EnsureTable(a.ctx); !errors.Is(err, ErrDryRunUnsupported) {
+			t.Errorf("EnsureTable error %v, supposed to be %v", err, ErrDryRunUnsupported)
+		}
+		return a.DropTable(a.ctx)
+	})
+	if !errors.Is(err, ErrDryRunUnsupported) {
+		t.Errorf("DryRun error %v, supposed to be %v", err, ErrDryRunUnsupported)
+	}
+	if ids := storedIDs(t, a); len(ids) != 5 {
+		t.Errorf("stored ids %v, supposed to keep the 5 rules", ids)
+	}
+}
+
+func TestDryRunConflicts(t *testing.T) {
+	a := newSqliteAdapter(t, WithConflictPolicy(ConflictSkip), WithUniqueIndex())
+	initPolicy(t, a)
+
+	// The raw statements resolving the conflicts are planned too.
+	plan, err := a.DryRun(context.Background(), func(a *Adapter) error {
+		return a.AddPolicies("p", "p", [][]string{{"alice", "data1", "read"}, {"dave", "data1", "read"}})
+	})
+	if err != nil {
+		t.Fatalf("DryRun failed: %v", err)
+	}
+	if len(plan) != 1 || !strings.HasPrefix(plan[0].SQL, "INSERT") || len(plan[0].Args) == 0 {
+		t.Errorf("planned statements %v, supposed to be an insert", plan)
+	}
+	if ids := storedIDs(t, a); len(ids) != 5 {
+		t.Errorf("stored ids %v, supposed to keep the 5 rules", ids)
+	}
+}
@@ -0,0 +1,62 @@
+package adapter
+
+import (
+	"context"
+	"errors"
+	"fmt"
+
+	"github.com/casbin/casbin/v2/model"
+)
+
+// ErrRefusingEmptySave is returned by SavePolicy when the model holds no p
+// and g rules while the policy table holds rules, which saving would
+// delete, e.g. when SavePolicy is called before LoadPolicy, and by the
+// imports replacing the stored rules with no rules.
+var ErrRefusingEmptySave = errors.New("refusing to save an empty model over stored rules, use WithAllowEmptySave to save it anyway or RemoveFilteredPolicy to delete the rules")
+
+// WithAllowEmptySave lets SavePolicy save a model without rules, deleting
+// all the stored rules, and the imports of ImportOptions.Replace import no
+// rules. Such saves are refused by default with ErrRefusingEmptySave unless
+// the policy table is empty.
+func WithAllowEmptySave() AdapterOption {
+	return AdapterOption{apply: func(a *Adapter) {
+		a.allowEmptySave = true
+	}}
+}
+
+// checkEmptySave returns ErrRefusingEmptySave when m has no rules while
+// one of the policy tables of the adapter holds rules.
+func (a *Adapter) checkEmptySave(ctx context.Context, m model.Model) error {
+	if a.allowEmptySave || modelRuleCount(m) > 0 {
+		return nil
+	}
+	tables := []*Adapter{a}
+	if a.routed() {
+		tables = a.splitTables()
+	}
+	return checkEmptyTables(ctx, tables)
+}
+
+// checkEmptyReplace returns ErrRefusingEmptySave when an import replacing
+// the rules of the table of the adapter has no rules while it holds rules.
+func (a *Adapter) checkEmptyReplace(ctx context.Context, rules []Rule) error {
+	if a.allowEmptySave || len(rules) > 0 {
+		return nil
+	}
+	return checkEmptyTables(ctx, []*Adapter{a})
+}
+
+// checkEmptyTables returns ErrRefusingEmptySave when one of tables holds
+// rules.
+func checkEmptyTables(ctx context.Context, tables []*Adapter) error {
+	for _, table := range tables {
+		stored, err := table.modelCtx(ctx).Fields("id").One()
+		if err != nil {
+			return fmt.Errorf("failed to check stored rules: %w", err)
+		}
+		if !stored.IsEmpty() {
+			return ErrRefusingEmptySave
+		}
+	}
+	return nil
+}
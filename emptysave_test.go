@@ -0,0 +1,120 @@
+package adapter
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"testing"
+
+	"github.com/casbin/casbin/v2"
+	"github.com/casbin/casbin/v2/model"
+)
+
+// emptyModel returns the model of rbac_model.conf without rules.
+func emptyModel(t *testing.T) model.Model {
+	t.Helper()
+	m, err := model.NewModelFromFile("examples/rbac_model.conf")
+	if err != nil {
+		t.Fatalf("failed to read model: %v", err)
+	}
+	return m
+}
+
+func TestRefusingEmptySave(t *testing.T) {
+	ctx := context.Background()
+	a := newSqliteAdapter(t)
+	initPolicy(t, a)
+
+	// An enforcer saving before loading has an empty model.
+	e, _ := casbin.NewEnforcer("examples/rbac_model.conf", a)
+	e.ClearPolicy()
+	if err := e.SavePolicy(); !errors.Is(err, ErrRefusingEmptySave) {
+		t.Errorf("SavePolicy err: %v, supposed to refuse the empty save", err)
+	}
+	if count, err := a.CountPolicies(ctx, nil); err != nil || count != 5 {
+		t.Errorf("CountPolicies = %d, err: %v, supposed to keep the 5 rules", count, err)
+	}
+	// So are the imports replacing the rules with none.
+	if _, err := a.ImportPolicies(ctx, nil, "p", ImportOptions{Replace: true}); !errors.Is(err, ErrRefusingEmptySave) {
+		t.Errorf("ImportPolicies err: %v, supposed to refuse the empty import", err)
+	}
+	if _, err := a.ImportCSV(ctx, strings.NewReader("# no rules\n"), ImportOptions{Replace: true}); !errors.Is(err, ErrRefusingEmptySave) {
+		t.Errorf("ImportCSV err: %v, supposed to refuse the empty import", err)
+	}
+	if count, err := a.CountPolicies(ctx, nil); err != nil || count != 5 {
+		t.Errorf("CountPolicies = %d, err: %v, supposed to keep the 5 rules", count, err)
+	}
+
+	// A model holding rules is saved.
+	if _, err := e.AddPolicy("alice", "data1", "read"); err != nil {
+		t.Fatalf("AddPolicy failed: %v", err)
+	}
+	if err := e.SavePolicy(); err != nil {
+		t.Fatalf("SavePolicy failed: %v", err)
+	}
+	if count, err := a.CountPolicies(ctx, nil); err != nil || count != 1 {
+		t.Errorf("CountPolicies = %d, err: %v, supposed to be the saved rule", count, err)
+	}
+}
+
+func TestAllowEmptySave(t *testing.T) {
+	ctx := context.Background()
+	a := newSqliteAdapter(t, WithAllowEmptySave())
+	initPolicy(t, a)
+	if err := a.SavePolicy(emptyModel(t)); err != nil {
+		t.Fatalf("SavePolicy failed: %v", err)
+	}
+	if count, err := a.CountPolicies(ctx, nil); err != nil || count != 0 {
+		t.Errorf("CountPolicies = %d, err: %v, supposed to be 0", count, err)
+	}
+}
+
+func TestEmptySaveEmptyTable(t *testing.T) {
+	a := newSqliteAdapter(t)
+	if err := a.SavePolicy(emptyModel(t)); err != nil {
+		t.Errorf("SavePolicy of an empty table failed: %v", err)
+	}
+
+	// The rules of the other tenants aren't deleted by the save of a tenant.
+	tenants := newSqliteAdapter(t, WithTenantColumn("tenant_id"))
+	if err := tenants.ForTenant("t1").AddPolicy("p", "p", []string{"alice", "data1", "read"}); err != nil {
+		t.Fatalf("AddPolicy failed: %v", err)
+	}
+	if err := tenants.ForTenant("t2").SavePolicy(emptyModel(t)); err != nil {
+		t.Errorf("SavePolicy of an empty tenant failed: %v", err)
+	}
+	if err := tenants.ForTenant("t1").SavePolicy(emptyModel(t)); !errors.Is(err, ErrRefusingEmptySave) {
+		t.Errorf("SavePolicy err: %v, supposed to refuse the empty save", err)
+	}
+}
+
+// implicitTruncateDialect truncates before the transactions like MySQL,
+// whose truncation commits implicitly.
+type implicitTruncateDialect struct {
+	dialect
+}
+
+func (implicitTruncateDialect) transactionalTruncate() bool {
+	return false
+}
+
+func TestEmptySaveBumpsVersion(t *testing.T) {
+	ctx := context.Background()
+	a := newSqliteAdapter(t, WithAllowEmptySave(), WithVersionTable())
+	a.dialect = implicitTruncateDialect{a.dialect}
+	initPolicy(t, a)
+	before, err := a.db.Model(a.versionTable).Ctx(ctx).Where("id", versionRowID).Value("version")
+	if err != nil {
+		t.Fatalf("failed to read version: %v", err)
+	}
+	if err := a.SavePolicy(emptyModel(t)); err != nil {
+		t.Fatalf("SavePolicy failed: %v", err)
+	}
+	after, err := a.db.Model(a.versionTable).Ctx(ctx).Where("id", versionRowID).Value("version")
+	if err != nil {
+		t.Fatalf("failed to read version: %v", err)
+	}
+	if after.Int() != before.Int()+1 {
+		t.Errorf("version %d, supposed to be bumped from %d", after.Int(), before.Int())
+	}
+}
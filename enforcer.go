@@ -0,0 +1,135 @@
+package adapter
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/casbin/casbin/v2"
+	"github.com/casbin/casbin/v2/model"
+	"github.com/casbin/casbin/v2/persist"
+	"github.com/gogf/gf/v2/database/gdb"
+)
+
+var (
+	// ErrInvalidModel is wrapped by the errors of NewEnforcer reading the
+	// model.
+	ErrInvalidModel = errors.New("invalid casbin model")
+	// ErrAdapterSetup is wrapped by the errors of NewEnforcer creating the
+	// adapter, or setting up its watcher and reloads.
+	ErrAdapterSetup = errors.New("failed to set up adapter")
+	// ErrPolicyLoad is wrapped by the errors of NewEnforcer loading the
+	// policy.
+	ErrPolicyLoad = errors.New("failed to load policy")
+)
+
+// Option configures NewEnforcer.
+type Option struct {
+	apply func(c *enforcerConfig)
+}
+
+// enforcerConfig is the configuration of NewEnforcer.
+type enforcerConfig struct {
+	db          gdb.DB
+	dbGroupName string
+	tableName   string
+	adapterOpts []AdapterOption
+	watcher     persist.Watcher
+	reload      time.Duration
+}
+
+// WithDB stores the rules in db.
+func WithDB(db gdb.DB) Option {
+	return Option{apply: func(c *enforcerConfig) {
+		c.db = db
+	}}
+}
+
+// WithDBGroup stores the rules in the database of the configured group
+// name, when no database is given with WithDB.
+func WithDBGroup(name string) Option {
+	return Option{apply: func(c *enforcerConfig) {
+		c.dbGroupName = name
+	}}
+}
+
+// WithTableName stores the rules in the table name, casbin_rule by default.
+func WithTableName(name string) Option {
+	return Option{apply: func(c *enforcerConfig) {
+		c.tableName = name
+	}}
+}
+
+// WithAdapterOptions creates the adapter with opts.
+func WithAdapterOptions(opts ...AdapterOption) Option {
+	return Option{apply: func(c *enforcerConfig) {
+		c.adapterOpts = append(c.adapterOpts, opts...)
+	}}
+}
+
+// WithEnforcerWatcher sets w as the watcher of the enforcer, which then
+// publishes its changes and reloads the policy on the changes of the other
+// instances.
+func WithEnforcerWatcher(w persist.Watcher) Option {
+	return Option{apply: func(c *enforcerConfig) {
+		c.watcher = w
+	}}
+}
+
+// WithEnforcerAutoReload reloads the policy of the enforcer every interval,
+// see StartAutoReload, until the context of NewEnforcer is canceled, or the
+// adapter or its AutoReload is closed.
+func WithEnforcerAutoReload(interval time.Duration) Option {
+	return Option{apply: func(c *enforcerConfig) {
+		c.reload = interval
+	}}
+}
+
+// NewEnforcer returns an enforcer of the model file at modelPath with its
+// policy loaded, and its adapter created according to opts. The reloads of
+// the watcher and of WithEnforcerAutoReload replace the policy of the
+// enforcer while it is used, wrap the adapter in a casbin.SyncedEnforcer
+// instead when the enforcer must not be read during a reload. The errors
+// wrap ErrInvalidModel, ErrAdapterSetup or ErrPolicyLoad after the step that
+// failed, the adapter is closed when it was created.
+func NewEnforcer(ctx context.Context, modelPath string, opts ...Option) (*casbin.Enforcer, *Adapter, error) {
+	var c enforcerConfig
+	for _, opt := range opts {
+		opt.apply(&c)
+	}
+
+	m, err := model.NewModelFromFile(modelPath)
+	if err != nil {
+		return nil, nil, fmt.Errorf("%w: %w", ErrInvalidModel, err)
+	}
+	a, err := NewAdapter(ctx, c.dbGroupName, c.tableName, c.db, c.adapterOpts...)
+	if err != nil {
+		return nil, nil, fmt.Errorf("%w: %w", ErrAdapterSetup, err)
+	}
+	e, err := casbin.NewEnforcer(m, a)
+	if err != nil {
+		_ = a.Close()
+		return nil, nil, fmt.Errorf("%w: %w", ErrPolicyLoad, err)
+	}
+
+	if c.watcher != nil {
+		if err := e.SetWatcher(c.watcher); err != nil {
+			_ = a.Close()
+			return nil, nil, fmt.Errorf("%w: failed to set watcher: %w", ErrAdapterSetup, err)
+		}
+	}
+	if c.reload != 0 {
+		if a.autoReload, err = a.StartAutoReload(ctx, c.reload, e.LoadPolicy); err != nil {
+			_ = a.Close()
+			return nil, nil, fmt.Errorf("%w: %w", ErrAdapterSetup, err)
+		}
+	}
+	return e, a, nil
+}
+
+// AutoReload returns the reloads started by NewEnforcer with
+// WithEnforcerAutoReload, nil without them.
+func (a *Adapter) AutoReload() *AutoReload {
+	return a.autoReload
+}
@@ -0,0 +1,107 @@
+package adapter
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+)
+
+func TestNewEnforcer(t *testing.T) {
+	ctx := context.Background()
+	db := newSqliteDB(t)
+	seeded, err := NewAdapter(ctx, "", "rules", db)
+	if err != nil {
+		t.Fatalf("failed to create adapter: %v", err)
+	}
+	initPolicy(t, seeded)
+
+	e, a, err := NewEnforcer(ctx, "examples/rbac_model.conf", WithDB(db), WithTableName("rules"), WithAdapterOptions(WithBatchSize(10)))
+	if err != nil {
+		t.Fatalf("NewEnforcer failed: %v", err)
+	}
+	if a.batchSize != 10 {
+		t.Errorf("batch size %d, supposed to be 10", a.batchSize)
+	}
+	if a.AutoReload() != nil {
+		t.Error("the adapter reloads without WithEnforcerAutoReload")
+	}
+	if ok, _ := e.Enforce("alice", "data2", "read"); !ok {
+		t.Error("alice denied reading data2 through data2_admin")
+	}
+}
+
+func TestNewEnforcerAutoReload(t *testing.T) {
+	db := newSqliteDB(t)
+	e, a, err := NewEnforcer(context.Background(), "examples/rbac_model.conf", WithDB(db), WithEnforcerAutoReload(20*time.Millisecond))
+	if err != nil {
+		t.Fatalf("NewEnforcer failed: %v", err)
+	}
+	defer a.Close()
+	if a.AutoReload() == nil {
+		t.Fatal("the adapter holds no AutoReload")
+	}
+
+	other, err := NewAdapter(context.Background(), "", "", db)
+	if err != nil {
+		t.Fatalf("failed to create adapter: %v", err)
+	}
+	if err := other.AddPolicy("p", "p", []string{"alice", "data1", "read"}); err != nil {
+		t.Fatalf("AddPolicy failed: %v", err)
+	}
+	deadline := time.Now().Add(5 * time.Second)
+	for ok, _ := e.HasPolicy("alice", "data1", "read"); !ok; ok, _ = e.HasPolicy("alice", "data1", "read") {
+		if time.Now().After(deadline) {
+			t.Fatal("the enforcer didn't reload the added rule")
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+
+	// Once the adapter is closed, the policy isn't reloaded anymore.
+	_ = a.Close()
+	if err := other.AddPolicy("p", "p", []string{"bob", "data2", "read"}); err != nil {
+		t.Fatalf("AddPolicy failed: %v", err)
+	}
+	time.Sleep(100 * time.Millisecond)
+	if ok, _ := e.HasPolicy("bob", "data2", "read"); ok {
+		t.Error("the enforcer reloaded the policy after Close")
+	}
+}
+
+func TestNewEnforcerErrors(t *testing.T) {
+	ctx := context.Background()
+	db := newSqliteDB(t)
+	plain, err := NewAdapter(ctx, "", "", db)
+	if err != nil {
+		t.Fatalf("failed to create adapter: %v", err)
+	}
+	if err := plain.AddPolicy("p", "p", []string{"alice", "data1", "read"}); err != nil {
+		t.Fatalf("AddPolicy failed: %v", err)
+	}
+
+	tests := []struct {
+		name      string
+		modelPath string
+		opts      []Option
+		want      error
+	}{
+		{"model", "examples/missing.conf", []Option{WithDB(db)}, ErrInvalidModel},
+		{"adapter", "examples/rbac_model.conf", nil, ErrAdapterSetup},
+		// The stored rules aren't encrypted.
+		{"load", "examples/rbac_model.conf", []Option{WithDB(db), WithAdapterOptions(WithEncryption(newTestCodec(t, "k1", testKeys)))}, ErrPolicyLoad},
+		{"reload", "examples/rbac_model.conf", []Option{WithDB(db), WithEnforcerAutoReload(-time.Second)}, ErrAdapterSetup},
+		{"watcher", "examples/rbac_model.conf", []Option{WithDB(db), WithEnforcerWatcher(failingWatcher{})}, ErrAdapterSetup},
+	}
+	for _, tt := range tests {
+		if _, _, err := NewEnforcer(ctx, tt.modelPath, tt.opts...); !errors.Is(err, tt.want) {
+			t.Errorf("%s: err: %v, supposed to wrap %v", tt.name, err, tt.want)
+		}
+	}
+}
+
+// failingWatcher fails to take an update callback.
+type failingWatcher struct{}
+
+func (failingWatcher) SetUpdateCallback(func(string)) error { return errors.New("injected failure") }
+func (failingWatcher) Update() error                        { return nil }
+func (failingWatcher) Close()                               {}
@@ -0,0 +1,170 @@
+package adapter
+
+import (
+	"sync"
+	"time"
+)
+
+const (
+	// defaultEventBuffer is the default capacity of the channel of Events.
+	defaultEventBuffer = 100
+)
+
+// EventDropPolicy tells what happens to an event when the channel of Events
+// is full.
+type EventDropPolicy int
+
+const (
+	// EventsDropOldest discards the oldest buffered event to make room, so
+	// that a slow consumer never delays the writes but may miss events.
+	EventsDropOldest EventDropPolicy = iota
+	// EventsBlock makes the write delivering the event wait until the
+	// consumer made room, so that no event is lost.
+	EventsBlock
+)
+
+// PolicyEvent describes a change of the stored rules, delivered by Events.
+type PolicyEvent struct {
+	// Op is the name of the adapter method making the change, as in
+	// Operation.
+	Op    string
+	Sec   string
+	PType string
+	// Rules holds the rules added or removed, the old rules of an update
+	// and the rules written by SavePolicy, SyncPolicy and the imports.
+	Rules []Rule
+	// NewRules holds the new rules of an update.
+	NewRules  []Rule
+	Timestamp time.Time
+}
+
+// eventFeed holds the channel of Events, it is shared by the copies of an
+// adapter.
+type eventFeed struct {
+	size   int
+	policy EventDropPolicy
+
+	// mu serializes the sends and the closing of ch.
+	mu     sync.Mutex
+	ch     chan PolicyEvent
+	closed bool
+	// sending is held by the sends of EventsBlock waiting for the consumer
+	// outside of mu, Close waits for them before closing ch.
+	sending sync.RWMutex
+	// done is closed by Close to release the blocked sends.
+	done      chan struct{}
+	closeOnce sync.Once
+}
+
+// WithEventBuffer sets the capacity of the channel of Events, at least 1
+// and 100 by default, and what happens to the events when it is full.
+// Events are dropped oldest first by default.
+func WithEventBuffer(size int, policy EventDropPolicy) AdapterOption {
+	return AdapterOption{apply: func(a *Adapter) {
+		if size > 0 {
+			a.events.size = size
+		}
+		a.events.policy = policy
+	}}
+}
+
+// Events returns a channel receiving an event after each successful change
+// of the stored rules made through the adapter or its copies. The changes
+// made in Transaction are delivered once it committed, those of an adapter
+// bound to a transaction with WithTx as they are written. Every call returns
+// the same channel, it is closed by Close. Nothing is buffered before the
+// first call.
+func (a *Adapter) Events() <-chan PolicyEvent {
+	f := a.events
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	if f.ch == nil {
+		f.ch = make(chan PolicyEvent, f.size)
+		if f.closed {
+			close(f.ch)
+		}
+	}
+	return f.ch
+}
+
+// Close closes the channel of Events, releasing the writes blocked on it,
+// and stops the reloads of WithEnforcerAutoReload. The database is left
+// open, it belongs to the caller.
+func (a *Adapter) Close() error {
+	if a.autoReload != nil {
+		a.autoReload.Close()
+	}
+	f := a.events
+	f.closeOnce.Do(func() {
+		close(f.done)
+		f.mu.Lock()
+		f.closed = true
+		ch := f.ch
+		f.mu.Unlock()
+
+		// The blocked sends are released by done.
+		f.sending.Lock()
+		defer f.sending.Unlock()
+		if ch != nil {
+			close(ch)
+		}
+	})
+	return nil
+}
+
+// emitEvent delivers the event of the successful change op, or queues it
+// until the transaction of the adapter committed.
+func (a *Adapter) emitEvent(op Operation) {
+	f := a.events
+	f.mu.Lock()
+	subscribed := f.ch != nil && !f.closed
+	f.mu.Unlock()
+	if !subscribed {
+		return
+	}
+
+	rules := op.Rules
+	if op.Model != nil {
+		rules = a.modelRules(op.Model)
+	}
+	ev := PolicyEvent{Op: op.Method, Sec: op.Sec, PType: op.PType, Rules: rules, NewRules: op.NewRules, Timestamp: a.now()}
+	if a.pendingEvents != nil {
+		*a.pendingEvents = append(*a.pendingEvents, ev)
+		return
+	}
+	f.send(ev)
+}
+
+// send delivers ev according to the drop policy. A blocked send doesn't
+// hold mu, so that Events and the other writes don't wait for it.
+func (f *eventFeed) send(ev PolicyEvent) {
+	f.mu.Lock()
+	if f.closed {
+		f.mu.Unlock()
+		return
+	}
+	if f.policy == EventsBlock {
+		ch := f.ch
+		f.sending.RLock()
+		f.mu.Unlock()
+		defer f.sending.RUnlock()
+		select {
+		case ch <- ev:
+		case <-f.done:
+		}
+		return
+	}
+
+	defer f.mu.Unlock()
+	for {
+		select {
+		case f.ch <- ev:
+			return
+		default:
+		}
+		select {
+		case <-f.ch:
+		default:
+		}
+	}
+}
@@ -0,0 +1,217 @@
+package adapter
+
+import (
+	"context"
+	"errors"
+	"reflect"
+	"testing"
+	"time"
+
+	"github.com/casbin/casbin/v2/model"
+)
+
+// receiveEvent receives the next event of events.
+func receiveEvent(t *testing.T, events <-chan PolicyEvent) PolicyEvent {
+	t.Helper()
+	select {
+	case ev := <-events:
+		return ev
+	case <-time.After(5 * time.Second):
+		t.Fatal("no event received")
+		return PolicyEvent{}
+	}
+}
+
+// eventRules returns rules as slices led by their policy type.
+func eventRules(rules []Rule) [][]string {
+	var slices [][]string
+	for _, rule := range rules {
+		slices = append(slices, append([]string{rule.PType}, rule.toSlice()...))
+	}
+	return slices
+}
+
+func TestEvents(t *testing.T) {
+	a := newSqliteAdapter(t)
+	start := time.Now()
+	events := a.Events()
+
+	if err := a.AddPolicy("p", "p", []string{"alice", "data1", "read"}); err != nil {
+		t.Fatalf("AddPolicy failed: %v", err)
+	}
+	if err := a.RemoveFilteredPolicy("p", "p", 0, "alice"); err != nil {
+		t.Fatalf("RemoveFilteredPolicy failed: %v", err)
+	}
+	m, _ := model.NewModelFromFile("examples/rbac_model.conf")
+	m.AddPolicy("p", "p", []string{"bob", "data2", "write"})
+	m.AddPolicy("g", "g", []string{"bob", "admin"})
+	if err := a.SavePolicy(m); err != nil {
+		t.Fatalf("SavePolicy failed: %v", err)
+	}
+	// Failed changes emit nothing.
+	if err := a.RemoveFilteredPolicy("p", "p", -1); err == nil {
+		t.Fatal("expected an invalid field index to fail")
+	}
+
+	tests := []struct {
+		op    string
+		pType string
+		rules [][]string
+	}{
+		{"AddPolicy", "p", [][]string{{"p", "alice", "data1", "read"}}},
+		{"RemoveFilteredPolicy", "p", nil},
+		{"SavePolicy", "", [][]string{{"p", "bob", "data2", "write"}, {"g", "bob", "admin"}}},
+	}
+	for _, tt := range tests {
+		ev := receiveEvent(t, events)
+		if ev.Op != tt.op || ev.PType != tt.pType {
+			t.Errorf("event %s %q, supposed to be %s %q", ev.Op, ev.PType, tt.op, tt.pType)
+		}
+		if rules := eventRules(ev.Rules); !reflect.DeepEqual(rules, tt.rules) {
+			t.Errorf("%s: rules %v, supposed to be %v", tt.op, rules, tt.rules)
+		}
+		if ev.Timestamp.Before(start) || ev.Timestamp.After(time.Now()) {
+			t.Errorf("%s: timestamp %s outside of the test", tt.op, ev.Timestamp)
+		}
+	}
+	select {
+	case ev := <-events:
+		t.Errorf("unexpected event %s", ev.Op)
+	default:
+	}
+
+	if err := a.Close(); err != nil {
+		t.Fatalf("Close failed: %v", err)
+	}
+	if _, ok := <-events; ok {
+		t.Error("the channel is still open after Close")
+	}
+	if err := a.AddPolicy("p", "p", []string{"carol", "data1", "read"}); err != nil {
+		t.Errorf("AddPolicy after Close failed: %v", err)
+	}
+}
+
+func TestEventsTransaction(t *testing.T) {
+	a := newSqliteAdapter(t)
+	events := a.Events()
+	ctx := context.Background()
+
+	err := a.Transaction(ctx, func(tx *Adapter) error {
+		if err := tx.AddPolicy("p", "p", []string{"alice", "data1", "read"}); err != nil {
+			return err
+		}
+		return errors.New("rollback")
+	})
+	if err == nil {
+		t.Fatal("expected the transaction to fail")
+	}
+
+	err = a.Transaction(ctx, func(tx *Adapter) error {
+		if err := tx.AddPolicy("p", "p", []string{"bob", "data2", "write"}); err != nil {
+			return err
+		}
+		if len(events) != 0 {
+			t.Error("event delivered before the commit")
+		}
+		return tx.RemovePolicy("p", "p", []string{"bob", "data2", "write"})
+	})
+	if err != nil {
+		t.Fatalf("Transaction failed: %v", err)
+	}
+	for _, op := range []string{"AddPolicy", "RemovePolicy"} {
+		if ev := receiveEvent(t, events); ev.Op != op {
+			t.Errorf("event %s, supposed to be %s", ev.Op, op)
+		}
+	}
+	if len(events) != 0 {
+		t.Errorf("%d events left, the rolled back change emitted", len(events))
+	}
+}
+
+func TestEventsDropOldest(t *testing.T) {
+	a := newSqliteAdapter(t, WithEventBuffer(2, EventsDropOldest))
+	events := a.Events()
+	for _, user := range []string{"alice", "bob", "carol"} {
+		if err := a.AddPolicy("p", "p", []string{user, "data1", "read"}); err != nil {
+			t.Fatalf("AddPolicy failed: %v", err)
+		}
+	}
+	for _, user := range []string{"bob", "carol"} {
+		if ev := receiveEvent(t, events); ev.Rules[0].V0 != user {
+			t.Errorf("event of %s, supposed to be %s", ev.Rules[0].V0, user)
+		}
+	}
+}
+
+func TestEventsBlock(t *testing.T) {
+	a := newSqliteAdapter(t, WithEventBuffer(1, EventsBlock))
+	events := a.Events()
+	if err := a.AddPolicy("p", "p", []string{"alice", "data1", "read"}); err != nil {
+		t.Fatalf("AddPolicy failed: %v", err)
+	}
+
+	done := make(chan error, 1)
+	go func() {
+		done <- a.AddPolicy("p", "p", []string{"bob", "data1", "read"})
+	}()
+	select {
+	case <-done:
+		t.Fatal("the write didn't wait for the consumer")
+	case <-time.After(100 * time.Millisecond):
+	}
+	for _, user := range []string{"alice", "bob"} {
+		if ev := receiveEvent(t, events); ev.Rules[0].V0 != user {
+			t.Errorf("event of %s, supposed to be %s", ev.Rules[0].V0, user)
+		}
+	}
+	if err := <-done; err != nil {
+		t.Fatalf("AddPolicy failed: %v", err)
+	}
+
+	// Events doesn't wait for a blocked write.
+	if err := a.AddPolicy("p", "p", []string{"erin", "data1", "read"}); err != nil {
+		t.Fatalf("AddPolicy failed: %v", err)
+	}
+	go func() {
+		done <- a.AddPolicy("p", "p", []string{"frank", "data1", "read"})
+	}()
+	time.Sleep(50 * time.Millisecond)
+	got := make(chan (<-chan PolicyEvent), 1)
+	go func() {
+		got <- a.Events()
+	}()
+	select {
+	case again := <-got:
+		if again != events {
+			t.Error("Events returned another channel")
+		}
+	case <-time.After(5 * time.Second):
+		t.Fatal("Events waited for the blocked write")
+	}
+	for _, user := range []string{"erin", "frank"} {
+		if ev := receiveEvent(t, events); ev.Rules[0].V0 != user {
+			t.Errorf("event of %s, supposed to be %s", ev.Rules[0].V0, user)
+		}
+	}
+	if err := <-done; err != nil {
+		t.Fatalf("AddPolicy failed: %v", err)
+	}
+
+	// Close releases a blocked write.
+	if err := a.AddPolicy("p", "p", []string{"carol", "data1", "read"}); err != nil {
+		t.Fatalf("AddPolicy failed: %v", err)
+	}
+	go func() {
+		done <- a.AddPolicy("p", "p", []string{"dave", "data1", "read"})
+	}()
+	time.Sleep(50 * time.Millisecond)
+	_ = a.Close()
+	select {
+	case err := <-done:
+		if err != nil {
+			t.Errorf("AddPolicy failed: %v", err)
+		}
+	case <-time.After(5 * time.Second):
+		t.Fatal("Close didn't release the blocked write")
+	}
+}
@@ -0,0 +1,32 @@
+package adapter_test
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"path/filepath"
+
+	"github.com/gogf/gf/v2/database/gdb"
+
+	adapter "github.com/zcyc/gf-adapter/v2"
+
+	_ "github.com/gogf/gf/contrib/drivers/sqlite/v2"
+)
+
+func ExampleNewEnforcer() {
+	ctx := context.Background()
+	dir, _ := os.MkdirTemp("", "casbin-example-")
+	defer os.RemoveAll(dir)
+	db, _ := gdb.New(gdb.ConfigNode{Type: "sqlite", Name: filepath.Join(dir, "casbin.db")})
+	defer db.Close(ctx)
+
+	e, _, err := adapter.NewEnforcer(ctx, "examples/rbac_model.conf", adapter.WithDB(db))
+	if err != nil {
+		fmt.Println(err)
+		return
+	}
+	_, _ = e.AddPolicy("alice", "data1", "read")
+	ok, _ := e.Enforce("alice", "data1", "read")
+	fmt.Println(ok)
+	// Output: true
+}
@@ -0,0 +1,137 @@
+package adapter
+
+import (
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/gogf/gf/v2/database/gdb"
+	"github.com/gogf/gf/v2/os/gtime"
+)
+
+// createdAtColumn is the column of the time a rule was stored.
+const createdAtColumn = "created_at"
+
+// filterPage is the paging of the rules matching the filters.
+type filterPage struct {
+	limit, offset int
+	// column orders the rules, the ties by id. desc orders them backwards.
+	column string
+	desc   bool
+}
+
+// pageOf returns the paging of filters, set by the first filter only.
+func pageOf(filters []Filter) (filterPage, error) {
+	if len(filters) == 0 {
+		return filterPage{column: "id"}, nil
+	}
+	for _, filter := range filters[1:] {
+		if filter.Limit != 0 || filter.Offset != 0 || filter.OrderBy != "" {
+			return filterPage{}, fmt.Errorf("%w: only the first filter can set Limit, Offset and OrderBy", ErrInvalidFilter)
+		}
+	}
+
+	first := filters[0]
+	if first.Limit < 0 || first.Offset < 0 {
+		return filterPage{}, fmt.Errorf("%w: negative limit %d or offset %d", ErrInvalidFilter, first.Limit, first.Offset)
+	}
+	page := filterPage{limit: first.Limit, offset: first.Offset, column: "id"}
+	if first.OrderBy != "" {
+		fields := strings.Fields(strings.ToLower(first.OrderBy))
+		if len(fields) == 0 || len(fields) > 2 || fields[0] != "id" && fields[0] != createdAtColumn ||
+			len(fields) == 2 && fields[1] != "asc" && fields[1] != "desc" {
+			return filterPage{}, fmt.Errorf("%w order %q, supposed to be id or created_at, optionally followed by asc or desc", ErrInvalidFilter, first.OrderBy)
+		}
+		page.column = fields[0]
+		page.desc = len(fields) == 2 && fields[1] == "desc"
+	}
+	return page, nil
+}
+
+// order returns the ORDER BY clause of the page.
+func (p filterPage) order() string {
+	direction := " ASC"
+	if p.desc {
+		direction = " DESC"
+	}
+	if p.column == "id" {
+		return "id" + direction
+	}
+	return p.column + direction + ", id" + direction
+}
+
+// query restricts query to the rows of the page when it is the only query.
+// Otherwise it only leaves the rows up to the end of the page, which are
+// paged once merged.
+func (p filterPage) query(query *gdb.Model, only bool) *gdb.Model {
+	query = query.Order(p.order())
+	switch {
+	case p.limit == 0:
+	case only:
+		query = query.Limit(p.offset, p.limit)
+	default:
+		query = query.Limit(p.offset + p.limit)
+	}
+	return query
+}
+
+// less reports whether row i comes before row j.
+func (p filterPage) less(rows []pagedRow, i, j int) bool {
+	a, b := rows[i], rows[j]
+	if p.desc {
+		a, b = b, a
+	}
+	switch p.column {
+	case "id":
+	case createdAtColumn:
+		if at, bt := a.createdAt(), b.createdAt(); !at.Equal(bt) {
+			return at.Before(bt)
+		}
+	default:
+		if c := compareOrder(a.order, b.order, a.orderNull, b.orderNull); c != 0 {
+			return c < 0
+		}
+	}
+	return a.Id < b.Id
+}
+
+// slice returns the rows of the page from the merged rows.
+func (p filterPage) slice(rows []pagedRow) []pagedRow {
+	if p.offset >= len(rows) {
+		return nil
+	}
+	rows = rows[p.offset:]
+	if p.limit > 0 && p.limit < len(rows) {
+		rows = rows[:p.limit]
+	}
+	return rows
+}
+
+// count returns the number of rules in the page of total rules.
+func (p filterPage) count(total int64) int64 {
+	total = max(total-int64(p.offset), 0)
+	if p.limit > 0 {
+		total = min(total, int64(p.limit))
+	}
+	return total
+}
+
+// pagedRow is a stored rule together with its id and the time it was
+// stored, and the value of the column of WithLoadOrder ordering it.
+type pagedRow struct {
+	Id int64 `orm:"id"`
+	Rule
+	CreatedAt *gtime.Time `orm:"created_at"`
+
+	order     string
+	orderNull bool
+}
+
+// createdAt returns the time the rule was stored, the zero time when it is
+// unknown.
+func (r pagedRow) createdAt() time.Time {
+	if r.CreatedAt == nil {
+		return time.Time{}
+	}
+	return r.CreatedAt.Time
+}
@@ -0,0 +1,47 @@
+package adapter
+
+import "fmt"
+
+// FilterRaw filters the rules by an SQL condition, for the filters a Filter
+// can't express, such as "v1 LIKE ? AND created_at > ?". It is accepted
+// wherever a filter of LoadFilteredPolicy is. Where is added to the query
+// as is, and must only refer to the columns of the policy table by the
+// adapter's column names. Pass the values as Args, one per ? placeholder,
+// and never build Where from untrusted input. The values in Args aren't
+// normalized or encoded by WithNormalization, WithFieldHashing or
+// WithEncryption.
+type FilterRaw struct {
+	Where string
+	Args  []interface{}
+}
+
+// validate checks that f has a condition with a placeholder per argument.
+func (f FilterRaw) validate() error {
+	if f.Where == "" {
+		return fmt.Errorf("%w: empty raw condition", ErrInvalidFilter)
+	}
+	if n := placeholders(f.Where); n != len(f.Args) {
+		return fmt.Errorf("%w: %d placeholders in the raw condition for %d arguments", ErrInvalidFilter, n, len(f.Args))
+	}
+	return nil
+}
+
+// placeholders counts the ? placeholders of condition outside its quoted
+// strings and identifiers.
+func placeholders(condition string) int {
+	n := 0
+	var quote rune
+	for _, c := range condition {
+		switch {
+		case quote != 0:
+			if c == quote {
+				quote = 0
+			}
+		case c == '\'' || c == '"' || c == '`':
+			quote = c
+		case c == '?':
+			n++
+		}
+	}
+	return n
+}
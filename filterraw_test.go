@@ -0,0 +1,55 @@
+package adapter
+
+import (
+	"context"
+	"testing"
+
+	"github.com/casbin/casbin/v2"
+)
+
+func TestFilterRaw(t *testing.T) {
+	ctx := context.Background()
+	a := newSqliteAdapter(t)
+	if err := a.AddPolicies("p", "p", [][]string{
+		{"alice", "projects/42/docs", "read"},
+		{"bob", "projects/42/wiki", "write"},
+		{"carol", "projects/7/docs", "read"},
+	}); err != nil {
+		t.Fatalf("AddPolicies failed: %v", err)
+	}
+
+	filter := FilterRaw{Where: "v1 LIKE ? AND v2 <> 'w?'", Args: []interface{}{"projects/42/%"}}
+	e, _ := casbin.NewEnforcer("examples/rbac_model.conf", a)
+	if err := e.LoadFilteredPolicy(filter); err != nil {
+		t.Fatalf("LoadFilteredPolicy failed: %v", err)
+	}
+	testGetPolicy(t, e, [][]string{{"alice", "projects/42/docs", "read"}, {"bob", "projects/42/wiki", "write"}})
+
+	// The condition is grouped, so that its OR doesn't escape the filter.
+	or := []Filter{{V0: []string{"carol"}}}
+	if err := e.LoadFilteredPolicy(append(or, Filter{raw: FilterRaw{Where: "v0 = ? OR v0 = ?", Args: []interface{}{"alice", "bob"}}})); err != nil {
+		t.Fatalf("LoadFilteredPolicy failed: %v", err)
+	}
+	testGetPolicy(t, e, [][]string{{"alice", "projects/42/docs", "read"}, {"bob", "projects/42/wiki", "write"}, {"carol", "projects/7/docs", "read"}})
+	if count, err := a.CountPolicies(ctx, &FilterRaw{Where: "v2 = ? OR v2 = ?", Args: []interface{}{"read", "none"}}); err != nil || count != 2 {
+		t.Errorf("count %d, err: %v, supposed to be 2", count, err)
+	}
+	rules, err := a.GetFilteredPolicies(ctx, filter)
+	if err != nil || len(rules) != 2 {
+		t.Errorf("rules %v, err: %v, supposed to be the 2 rules of project 42", rules, err)
+	}
+
+	for _, invalid := range []FilterRaw{
+		{},
+		{Where: "v1 LIKE ?"},
+		{Where: "v1 LIKE 'projects/?'", Args: []interface{}{"x"}},
+		{Where: "v1 = ? AND v2 = ?", Args: []interface{}{"x"}},
+	} {
+		if err := e.LoadFilteredPolicy(invalid); err == nil {
+			t.Errorf("LoadFilteredPolicy of %+v succeeded", invalid)
+		}
+		if _, err := a.CountPolicies(ctx, invalid); err == nil {
+			t.Errorf("CountPolicies of %+v succeeded", invalid)
+		}
+	}
+}
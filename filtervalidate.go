@@ -0,0 +1,95 @@
+package adapter
+
+import (
+	"errors"
+	"fmt"
+	"slices"
+	"unicode/utf8"
+)
+
+const (
+	// maxPTypeLength and maxValueLength are the lengths of the p_type and
+	// value columns of the created policy tables, in characters.
+	maxPTypeLength = 10
+	maxValueLength = 256
+)
+
+// ErrInvalidFilter is wrapped by the errors of the filters that can't be
+// used, such as those rejected by Filter.Validate.
+var ErrInvalidFilter = errors.New("invalid filter")
+
+// Validate checks f for the mistakes that make a filter quietly match no or
+// all rules: no condition at all, unless AllowEmpty is set, values longer
+// than the columns of the created policy tables, empty values in the lists
+// of PType and V0 to V5, and a column with both a list and a negated list.
+// The errors wrap ErrInvalidFilter. LoadFilteredPolicy validates its
+// filters, against the column types of the adapter.
+func (f *Filter) Validate() error {
+	return f.validate(nil)
+}
+
+// validate validates f against the lengths of the value columns, 0 when
+// unbounded, the lengths of the created tables when lengths is nil.
+func (f *Filter) validate(lengths []int) error {
+	valueLength := func(i int) int {
+		if lengths == nil {
+			return maxValueLength
+		}
+		return lengths[i]
+	}
+	if !f.AllowEmpty && f.empty() {
+		return fmt.Errorf("%w: no condition set, set AllowEmpty to match all the rules", ErrInvalidFilter)
+	}
+
+	if slices.Contains(f.domains, "") {
+		return fmt.Errorf("%w: empty domain, which matches no rule", ErrInvalidFilter)
+	}
+
+	names := append([]string{"PType"}, filterFieldNames...)
+	lists := [][]string{f.PType, f.V0, f.V1, f.V2, f.V3, f.V4, f.V5}
+	negated := [][]string{f.NotPType, f.NotV0, f.NotV1, f.NotV2, f.NotV3, f.NotV4, f.NotV5}
+	for i, name := range names {
+		maxLength := maxPTypeLength
+		if i > 0 {
+			maxLength = valueLength(i - 1)
+		}
+		if slices.Contains(lists[i], "") {
+			return fmt.Errorf("%w: empty value in %s, which matches no rule", ErrInvalidFilter, name)
+		}
+		if len(lists[i]) > 0 && len(negated[i]) > 0 {
+			return fmt.Errorf("%w: both %s and Not%s set, leave the excluded values out of %s instead", ErrInvalidFilter, name, name, name)
+		}
+		for _, values := range [][]string{lists[i], negated[i]} {
+			for _, value := range values {
+				if maxLength > 0 && utf8.RuneCountInString(value) > maxLength {
+					return fmt.Errorf("%w: value %.20q... of %s longer than the %d characters of the column", ErrInvalidFilter, value, name, maxLength)
+				}
+			}
+		}
+	}
+	for i, prefixes := range filterPrefixes(*f) {
+		for _, prefix := range prefixes {
+			if maxLength := valueLength(i); maxLength > 0 && utf8.RuneCountInString(prefix) > maxLength {
+				return fmt.Errorf("%w: prefix %.20q... of %sPrefix longer than the %d characters of the column", ErrInvalidFilter, prefix, filterFieldNames[i], maxLength)
+			}
+		}
+	}
+	return nil
+}
+
+// filterFieldNames names the value fields of a Filter.
+var filterFieldNames = []string{"V0", "V1", "V2", "V3", "V4", "V5"}
+
+// empty reports whether f sets no condition.
+func (f *Filter) empty() bool {
+	for _, values := range [][]string{
+		f.PType, f.V0, f.V1, f.V2, f.V3, f.V4, f.V5,
+		f.NotPType, f.NotV0, f.NotV1, f.NotV2, f.NotV3, f.NotV4, f.NotV5,
+		f.V0Prefix, f.V1Prefix, f.V2Prefix, f.V3Prefix, f.V4Prefix, f.V5Prefix,
+	} {
+		if len(values) > 0 {
+			return false
+		}
+	}
+	return f.CreatedAfter.IsZero() && f.CreatedBefore.IsZero() && f.raw.Where == "" && len(f.domains) == 0
+}
@@ -0,0 +1,64 @@
+package adapter
+
+import (
+	"errors"
+	"strings"
+	"testing"
+
+	"github.com/casbin/casbin/v2"
+)
+
+func TestFilterValidate(t *testing.T) {
+	long := strings.Repeat("é", maxValueLength+1)
+	tests := []struct {
+		name   string
+		filter Filter
+		want   string
+	}{
+		{"empty", Filter{}, "no condition"},
+		{"paging only", Filter{Limit: 10}, "no condition"},
+		{"empty value", Filter{V1: []string{"data1", ""}}, "empty value in V1"},
+		{"empty policy type", Filter{PType: []string{""}}, "empty value in PType"},
+		{"long value", Filter{V0: []string{long}}, "longer than the 256 characters"},
+		{"long negated value", Filter{NotV3: []string{long}}, "of V3 longer"},
+		{"long policy type", Filter{PType: []string{"p12345678901"}}, "longer than the 10 characters"},
+		{"long prefix", Filter{V2Prefix: []string{long}}, "V2Prefix longer"},
+		{"negated and listed", Filter{V0: []string{"alice"}, NotV0: []string{"bob"}}, "both V0 and NotV0"},
+	}
+	for _, tt := range tests {
+		err := tt.filter.Validate()
+		if !errors.Is(err, ErrInvalidFilter) || !strings.Contains(err.Error(), tt.want) {
+			t.Errorf("%s: err: %v, supposed to be an invalid filter error containing %q", tt.name, err, tt.want)
+		}
+	}
+
+	for _, filter := range []Filter{
+		{AllowEmpty: true},
+		{V0: []string{"alice"}, NotV1: []string{""}},
+		{V0: []string{strings.Repeat("é", maxValueLength)}},
+		{V1Prefix: []string{"repo/"}},
+		{raw: FilterRaw{Where: "v0 <> ''"}},
+	} {
+		if err := filter.Validate(); err != nil {
+			t.Errorf("Validate of %+v failed: %v", filter, err)
+		}
+	}
+}
+
+func TestLoadFilteredPolicyValidates(t *testing.T) {
+	a := newSqliteAdapter(t)
+	initPolicy(t, a)
+	e, _ := casbin.NewEnforcer("examples/rbac_model.conf", a)
+
+	err := e.LoadFilteredPolicy([]Filter{{V0: []string{"alice"}}, {V0: []string{"bob", ""}}})
+	if !errors.Is(err, ErrInvalidFilter) {
+		t.Errorf("err: %v, supposed to be an invalid filter error", err)
+	}
+	if err := e.LoadFilteredPolicy(Filter{AllowEmpty: true}); err != nil {
+		t.Fatalf("LoadFilteredPolicy failed: %v", err)
+	}
+	testGetPolicy(t, e, [][]string{{"alice", "data1", "read"}, {"bob", "data2", "write"}, {"data2_admin", "data2", "read"}, {"data2_admin", "data2", "write"}})
+	if _, err := a.GetFilteredPolicies(a.ctx, "p"); !errors.Is(err, ErrInvalidFilter) {
+		t.Errorf("err: %v, supposed to be an invalid filter error", err)
+	}
+}
@@ -0,0 +1,49 @@
+package adapter
+
+import (
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"strings"
+)
+
+// saltedHashPrefix starts the values hashed by SaltedHasher.
+const saltedHashPrefix = "sha256:"
+
+// WithFieldHashing stores the values of the rule fields fieldIndexes, 0 for
+// v0, hashed with hasher, e.g. SaltedHasher. The values of the filters and
+// of the rules removed or updated are hashed as well, so that they find
+// the stored rules. The loads return the hashed values, so the application
+// hashes the values it enforces with the same hasher. As a loaded policy
+// is saved again, hasher must return the values it hashed unchanged.
+// Empty values aren't hashed.
+func WithFieldHashing(fieldIndexes []int, hasher func(string) string) AdapterOption {
+	return AdapterOption{apply: func(a *Adapter) {
+		a.hashedFields = fieldIndexes
+		a.hasher = hasher
+	}}
+}
+
+// SaltedHasher returns a hasher for WithFieldHashing hashing the values
+// with HMAC-SHA256 keyed by salt, in hex behind a sha256: prefix. The values
+// it hashed are returned unchanged.
+func SaltedHasher(salt []byte) func(string) string {
+	return func(value string) string {
+		if isSaltedHash(value) {
+			return value
+		}
+		mac := hmac.New(sha256.New, salt)
+		mac.Write([]byte(value))
+		return saltedHashPrefix + hex.EncodeToString(mac.Sum(nil))
+	}
+}
+
+// isSaltedHash reports whether value was hashed by SaltedHasher.
+func isSaltedHash(value string) bool {
+	digest, ok := strings.CutPrefix(value, saltedHashPrefix)
+	if !ok || len(digest) != 2*sha256.Size {
+		return false
+	}
+	_, err := hex.DecodeString(digest)
+	return err == nil
+}
@@ -0,0 +1,76 @@
+package adapter
+
+import (
+	"context"
+	"strings"
+	"testing"
+
+	"github.com/casbin/casbin/v2"
+)
+
+func TestSaltedHasher(t *testing.T) {
+	hash := SaltedHasher([]byte("salt"))
+	hashed := hash("alice")
+	if !strings.HasPrefix(hashed, "sha256:") || len(hashed) != len("sha256:")+64 {
+		t.Errorf("hashed value %q, supposed to be sha256: and 64 hex digits", hashed)
+	}
+	if again := hash(hashed); again != hashed {
+		t.Errorf("hashing %q again gave %q", hashed, again)
+	}
+	if other := SaltedHasher([]byte("pepper"))("alice"); other == hashed {
+		t.Error("the salt doesn't change the hash")
+	}
+}
+
+func TestFieldHashing(t *testing.T) {
+	ctx := context.Background()
+	hash := SaltedHasher([]byte("salt"))
+	db := newSqliteDB(t)
+	a, err := NewAdapter(ctx, "", "", db, WithFieldHashing([]int{0}, hash))
+	if err != nil {
+		t.Fatalf("failed to create adapter: %v", err)
+	}
+	if err := a.AddPolicies("p", "p", [][]string{{"alice", "data1", "read"}, {"bob", "data2", "write"}}); err != nil {
+		t.Fatalf("AddPolicies failed: %v", err)
+	}
+	if err := a.AddPolicy("g", "g", []string{"carol", "data2_admin"}); err != nil {
+		t.Fatalf("AddPolicy failed: %v", err)
+	}
+	if value, _ := db.Model(a.tableName).Ctx(ctx).OrderAsc("id").Value("v0"); value.String() != hash("alice") {
+		t.Errorf("stored subject %q, supposed to be the hash of alice", value)
+	}
+
+	// The application enforces with the hashed subject.
+	e, _ := casbin.NewEnforcer("examples/rbac_model.conf", a)
+	testGetPolicy(t, e, [][]string{{hash("alice"), "data1", "read"}, {hash("bob"), "data2", "write"}})
+	if ok, _ := e.Enforce(hash("alice"), "data1", "read"); !ok {
+		t.Error("the hashed subject isn't allowed")
+	}
+	if ok, _ := e.Enforce("alice", "data1", "read"); ok {
+		t.Error("the plain subject is allowed")
+	}
+
+	// Saving the loaded policy doesn't hash the subjects again.
+	if err := e.SavePolicy(); err != nil {
+		t.Fatalf("SavePolicy failed: %v", err)
+	}
+	_ = e.LoadPolicy()
+	testGetPolicy(t, e, [][]string{{hash("alice"), "data1", "read"}, {hash("bob"), "data2", "write"}})
+
+	// Filters and removals take the plain or hashed subjects.
+	if err := e.LoadFilteredPolicy(Filter{V0: []string{"alice"}}); err != nil {
+		t.Fatalf("LoadFilteredPolicy failed: %v", err)
+	}
+	testGetPolicy(t, e, [][]string{{hash("alice"), "data1", "read"}})
+	if roles, err := a.GetRolesForUser(ctx, "carol"); err != nil || len(roles) != 1 || roles[0] != "data2_admin" {
+		t.Errorf("roles %v, err: %v, supposed to be [data2_admin]", roles, err)
+	}
+	if err := a.RemoveFilteredPolicy("p", "p", 0, "alice"); err != nil {
+		t.Fatalf("RemoveFilteredPolicy failed: %v", err)
+	}
+	if err := a.RemovePolicy("p", "p", []string{hash("bob"), "data2", "write"}); err != nil {
+		t.Fatalf("RemovePolicy failed: %v", err)
+	}
+	_ = e.LoadPolicy()
+	testGetPolicy(t, e, [][]string{})
+}
@@ -0,0 +1,263 @@
+package adapter
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/casbin/casbin/v2/model"
+	"github.com/gogf/gf/v2/database/gdb"
+	"github.com/gogf/gf/v2/frame/g"
+)
+
+const (
+	// historyTableSuffix is appended to the policy table name to name the history table.
+	historyTableSuffix = "_history"
+
+	historyOpAdd    = "add"
+	historyOpRemove = "remove"
+	// historyOpReset marks that the whole rule set was replaced, e.g. by SavePolicy.
+	historyOpReset = "reset"
+)
+
+// HistoryRangeError is returned by LoadPolicyAt when the history table does
+// not reach back to the requested time.
+type HistoryRangeError struct {
+	// At is the requested point in time.
+	At time.Time
+	// Earliest is the oldest point in time that can be reconstructed,
+	// it is zero when no history has been recorded at all.
+	Earliest time.Time
+}
+
+func (e *HistoryRangeError) Error() string {
+	if e.Earliest.IsZero() {
+		return fmt.Sprintf("no policy history available for %s", e.At.Format(time.RFC3339))
+	}
+	return fmt.Sprintf("policy history starts at %s, cannot load policy as of %s",
+		e.Earliest.Format(time.RFC3339), e.At.Format(time.RFC3339))
+}
+
+// historyEntry is a row of the history table.
+type historyEntry struct {
+	Rule
+	Op string `orm:"op"`
+}
+
+// WithHistory records every policy change in a history table next to the
+// policy table, which makes it possible to load the policy as it was at an
+// earlier point in time with LoadPolicyAt.
+func WithHistory() AdapterOption {
+	return AdapterOption{apply: func(a *Adapter) {
+		a.history = true
+	}}
+}
+
+func (a *Adapter) historyModel(ctx context.Context) *gdb.Model {
+	if a.tx != nil {
+		return a.scoped(a.encoded(a.tx.Model(a.historyTable).Safe().Ctx(ctx)))
+	}
+	return a.scoped(a.encoded(a.db.Model(a.historyTable).Safe().Ctx(ctx)))
+}
+
+// createHistoryTable creates the history table when it doesn't exist.
+func (a *Adapter) createHistoryTable(ctx context.Context) error {
+	for _, sql := range a.dialect.createHistoryTableSql(a.historyTable, a.schema()) {
+		if _, err := a.db.Exec(ctx, sql); err != nil {
+			return fmt.Errorf("failed to create history table: %w", err)
+		}
+	}
+	return a.clearTableFields(ctx, a.historyTable)
+}
+
+// openHistory starts a fresh history table with a snapshot of the current
+// rules so that the rule set can be reconstructed from the moment history
+// was enabled.
+func (a *Adapter) openHistory() error {
+	count, err := a.db.Model(a.historyTable).Ctx(a.ctx).Count()
+	if err != nil {
+		return fmt.Errorf("failed to count history entries: %w", err)
+	}
+	if count > 0 {
+		return nil
+	}
+
+	return a.transaction(a.ctx, func(ctx context.Context, tx gdb.TX) error {
+		if err := a.recordHistory(ctx, historyOpReset, nil); err != nil {
+			return err
+		}
+
+		// The snapshot covers the rows of every tenant.
+		fields := []string{a.pTypeColumn, Columns.V0, Columns.V1, Columns.V2, Columns.V3, Columns.V4, Columns.V5}
+		if a.tenantColumn != "" {
+			fields = append(fields, a.tenantColumn)
+		}
+		records, err := a.db.Model(a.tableName).Ctx(ctx).Fields(fields).OrderAsc("id").All()
+		if err != nil {
+			return fmt.Errorf("failed to scan policy rules: %w", err)
+		}
+		if records.IsEmpty() {
+			return nil
+		}
+
+		entries := records.List()
+		changedAt := a.dbTime(a.now())
+		for _, entry := range entries {
+			a.historyColumns(entry)
+			entry["op"] = historyOpAdd
+			entry["changed_at"] = changedAt
+		}
+		if _, err := a.db.Model(a.historyTable).Ctx(ctx).Data(entries).Batch(a.rowsPerInsert(len(fields) + 2)).Insert(); err != nil {
+			return fmt.Errorf("failed to record policy history: %w", err)
+		}
+		return nil
+	})
+}
+
+// historyColumns renames the policy type column of record to the one of the
+// history table, which always follows the p_type convention.
+func (a *Adapter) historyColumns(record g.Map) {
+	if a.pTypeColumn != Columns.PType {
+		record[Columns.PType] = record[a.pTypeColumn]
+		delete(record, a.pTypeColumn)
+	}
+}
+
+// recordHistory appends the change to the history table, it does nothing
+// when history is disabled.
+func (a *Adapter) recordHistory(ctx context.Context, op string, rules []Rule) error {
+	if a.historyTable == "" {
+		return nil
+	}
+	if op == historyOpReset {
+		rules = []Rule{{}}
+	}
+	if len(rules) == 0 {
+		return nil
+	}
+
+	changedAt := a.dbTime(a.now())
+	entries := make(g.List, 0, len(rules))
+	for _, rule := range rules {
+		entry := a.ruleRecord(ctx, rule)
+		a.historyColumns(entry)
+		entry["op"] = op
+		entry["changed_at"] = changedAt
+		entries = append(entries, entry)
+	}
+
+	if _, err := a.historyModel(ctx).Data(entries).Batch(a.rowsPerInsert(a.insertColumns() + 2)).Insert(); err != nil {
+		return fmt.Errorf("failed to record policy history: %w", err)
+	}
+	return nil
+}
+
+// LoadPolicyAt loads the policy rules that were in effect at the given time
+// by replaying the history table of WithHistory. Without history, the
+// soft deleted rows of WithChangeTracking are read instead: the rules
+// created by then and not deleted yet, rules removed by truncating or
+// swapping the table aside. The adapter is marked as filtered afterwards so
+// that the reconstructed rules are never saved over the live table.
+func (a *Adapter) LoadPolicyAt(ctx context.Context, model model.Model, at time.Time) error {
+	if model == nil {
+		return errors.New("model cannot be nil")
+	}
+
+	if a.historyTable == "" {
+		if !a.softDelete {
+			return errors.New("neither history nor change tracking is enabled")
+		}
+		return a.loadPolicyAtDeleted(ctx, model, at)
+	}
+
+	// The oldest entry is the snapshot taken when history was enabled.
+	first, err := a.db.Model(a.historyTable).Ctx(ctx).
+		OrderAsc("id").
+		One()
+	if err != nil {
+		return fmt.Errorf("failed to query policy history: %w", err)
+	}
+	if first.IsEmpty() {
+		return &HistoryRangeError{At: at}
+	}
+	if earliest := a.dbTime(first["changed_at"].Time()); at.Before(earliest) {
+		return &HistoryRangeError{At: at, Earliest: earliest}
+	}
+
+	var entries []historyEntry
+	err = a.historyModel(ctx).
+		WhereLTE("changed_at", a.dbTime(at)).
+		OrderAsc("id").
+		Scan(&entries)
+	if err != nil {
+		return fmt.Errorf("failed to scan policy history: %w", err)
+	}
+
+	for _, rule := range replayHistory(entries) {
+		a.loadPolicyRule(rule, model)
+	}
+
+	a.isFiltered = true
+	return nil
+}
+
+// loadPolicyAtDeleted is LoadPolicyAt reading the rows created by at and
+// soft deleted after it, if ever. deleted_at is set by gdb, in the time zone
+// of the process.
+func (a *Adapter) loadPolicyAtDeleted(ctx context.Context, model model.Model, at time.Time) error {
+	query := a.loadModelCtx(ctx).Unscoped().
+		WhereLTE(createdAtColumn, a.dbTime(at)).
+		Where(fmt.Sprintf("(%s IS NULL OR %s > ?)", deletedAtColumn, deletedAtColumn), at)
+	err := a.scanPages(query, func(rows []ruleRow) error {
+		for _, row := range rows {
+			a.loadPolicyRule(row.Rule, model)
+		}
+		return nil
+	})
+	if err != nil {
+		return fmt.Errorf("failed to scan policy rules: %w", err)
+	}
+	a.isFiltered = true
+	return nil
+}
+
+// replayHistory applies the history entries in order and returns the rules
+// that remain, in the order they were added.
+func replayHistory(entries []historyEntry) []Rule {
+	var (
+		rules   []Rule
+		removed []bool
+		// positions holds the indexes of the live copies of each rule.
+		positions = make(map[ruleKey][]int)
+	)
+
+	for _, entry := range entries {
+		switch entry.Op {
+		case historyOpReset:
+			rules, removed = rules[:0], removed[:0]
+			positions = make(map[ruleKey][]int)
+
+		case historyOpAdd:
+			key := entry.Rule.key()
+			positions[key] = append(positions[key], len(rules))
+			rules = append(rules, entry.Rule)
+			removed = append(removed, false)
+
+		case historyOpRemove:
+			key := entry.Rule.key()
+			if indexes := positions[key]; len(indexes) > 0 {
+				removed[indexes[0]] = true
+				positions[key] = indexes[1:]
+			}
+		}
+	}
+
+	live := make([]Rule, 0, len(rules))
+	for i, rule := range rules {
+		if !removed[i] {
+			live = append(live, rule)
+		}
+	}
+	return live
+}
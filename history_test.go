@@ -0,0 +1,155 @@
+package adapter
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/casbin/casbin/v2"
+)
+
+// testClock is a manually advanced clock for the adapter.
+type testClock struct {
+	now time.Time
+}
+
+func (c *testClock) Now() time.Time {
+	return c.now
+}
+
+func withClock(c *testClock) AdapterOption {
+	return AdapterOption{apply: func(a *Adapter) {
+		a.now = c.Now
+	}}
+}
+
+func TestLoadPolicyAt(t *testing.T) {
+	start := time.Date(2024, 3, 5, 10, 0, 0, 0, time.Local)
+	clock := &testClock{now: start}
+	a := newSqliteAdapter(t, WithHistory(), withClock(clock))
+
+	var err error
+	logErr := func(action string) {
+		if err != nil {
+			t.Fatalf("test action[%s] failed, err: %v", action, err)
+		}
+	}
+
+	clock.now = start.Add(1 * time.Hour)
+	err = a.AddPolicy("p", "p", []string{"alice", "data1", "read"})
+	logErr("AddPolicy")
+
+	clock.now = start.Add(2 * time.Hour)
+	err = a.AddPolicies("p", "p", [][]string{{"bob", "data2", "write"}, {"alice", "data2", "read"}})
+	logErr("AddPolicies")
+
+	clock.now = start.Add(3 * time.Hour)
+	err = a.RemovePolicy("p", "p", []string{"alice", "data1", "read"})
+	logErr("RemovePolicy")
+
+	clock.now = start.Add(4 * time.Hour)
+	err = a.UpdatePolicy("p", "p", []string{"bob", "data2", "write"}, []string{"bob", "data2", "read"})
+	logErr("UpdatePolicy")
+
+	clock.now = start.Add(5 * time.Hour)
+	e, _ := casbin.NewEnforcer("examples/rbac_model.conf", "examples/rbac_policy.csv")
+	err = a.SavePolicy(e.GetModel())
+	logErr("SavePolicy")
+
+	clock.now = start.Add(6 * time.Hour)
+	err = a.RemoveFilteredPolicy("p", "p", 0, "data2_admin")
+	logErr("RemoveFilteredPolicy")
+
+	tests := []struct {
+		name string
+		at   time.Time
+		want [][]string
+	}{
+		{"before first change", start.Add(30 * time.Minute), [][]string{}},
+		{"after AddPolicy", start.Add(90 * time.Minute), [][]string{{"alice", "data1", "read"}}},
+		{"exactly at AddPolicies", start.Add(2 * time.Hour), [][]string{{"alice", "data1", "read"}, {"bob", "data2", "write"}, {"alice", "data2", "read"}}},
+		{"after RemovePolicy", start.Add(210 * time.Minute), [][]string{{"bob", "data2", "write"}, {"alice", "data2", "read"}}},
+		{"after UpdatePolicy", start.Add(270 * time.Minute), [][]string{{"alice", "data2", "read"}, {"bob", "data2", "read"}}},
+		{"after SavePolicy", start.Add(330 * time.Minute), [][]string{{"alice", "data1", "read"}, {"bob", "data2", "write"}, {"data2_admin", "data2", "read"}, {"data2_admin", "data2", "write"}}},
+		{"after RemoveFilteredPolicy", start.Add(7 * time.Hour), [][]string{{"alice", "data1", "read"}, {"bob", "data2", "write"}}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			e, _ := casbin.NewEnforcer("examples/rbac_model.conf")
+			if err := a.LoadPolicyAt(context.Background(), e.GetModel(), tt.at); err != nil {
+				t.Fatalf("LoadPolicyAt failed: %v", err)
+			}
+			testGetPolicyWithoutOrder(t, e, tt.want)
+			if !a.IsFiltered() {
+				t.Error("adapter should be marked as filtered after LoadPolicyAt")
+			}
+		})
+	}
+}
+
+func TestLoadPolicyAtBeforeHistory(t *testing.T) {
+	start := time.Date(2024, 3, 5, 10, 0, 0, 0, time.Local)
+	a := newSqliteAdapter(t, WithHistory(), withClock(&testClock{now: start}))
+
+	e, _ := casbin.NewEnforcer("examples/rbac_model.conf")
+	err := a.LoadPolicyAt(context.Background(), e.GetModel(), start.Add(-time.Minute))
+
+	var rangeErr *HistoryRangeError
+	if !errors.As(err, &rangeErr) {
+		t.Fatalf("expected HistoryRangeError, got %v", err)
+	}
+	if !rangeErr.Earliest.Equal(start) {
+		t.Errorf("earliest history = %v, supposed to be %v", rangeErr.Earliest, start)
+	}
+}
+
+func TestLoadPolicyAtSoftDeleted(t *testing.T) {
+	// created_at is set from the clock of the adapter, deleted_at by gdb
+	// at the current time.
+	now := time.Now()
+	clock := &testClock{now: now.Add(-time.Hour)}
+	a := newSqliteAdapter(t, WithChangeTracking(), withClock(clock))
+	initPolicy(t, a)
+	if err := a.RemovePolicy("p", "p", []string{"alice", "data1", "read"}); err != nil {
+		t.Fatalf("RemovePolicy failed: %v", err)
+	}
+	clock.now = now.Add(-30 * time.Minute)
+	if err := a.AddPolicy("p", "p", []string{"carol", "data3", "read"}); err != nil {
+		t.Fatalf("AddPolicy failed: %v", err)
+	}
+
+	stored := [][]string{{"bob", "data2", "write"}, {"data2_admin", "data2", "read"}, {"data2_admin", "data2", "write"}}
+	tests := []struct {
+		name string
+		at   time.Time
+		want [][]string
+	}{
+		{"before the rules", now.Add(-2 * time.Hour), [][]string{}},
+		{"before AddPolicy", now.Add(-45 * time.Minute), append([][]string{{"alice", "data1", "read"}}, stored...)},
+		{"before RemovePolicy", now.Add(-10 * time.Minute), append([][]string{{"alice", "data1", "read"}, {"carol", "data3", "read"}}, stored...)},
+		{"after RemovePolicy", now.Add(time.Minute), append([][]string{{"carol", "data3", "read"}}, stored...)},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			e, _ := casbin.NewEnforcer("examples/rbac_model.conf")
+			if err := a.LoadPolicyAt(context.Background(), e.GetModel(), tt.at); err != nil {
+				t.Fatalf("LoadPolicyAt failed: %v", err)
+			}
+			testGetPolicyWithoutOrder(t, e, tt.want)
+			if !a.IsFiltered() {
+				t.Error("adapter should be marked as filtered after LoadPolicyAt")
+			}
+		})
+	}
+}
+
+func TestLoadPolicyAtWithoutHistory(t *testing.T) {
+	a := newSqliteAdapter(t)
+
+	e, _ := casbin.NewEnforcer("examples/rbac_model.conf")
+	if err := a.LoadPolicyAt(context.Background(), e.GetModel(), time.Now()); err == nil {
+		t.Error("expected an error when history is not enabled")
+	}
+}
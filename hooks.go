@@ -0,0 +1,132 @@
+package adapter
+
+import (
+	"context"
+	"fmt"
+	"sync"
+
+	"github.com/casbin/casbin/v2/model"
+	"github.com/casbin/casbin/v2/persist"
+)
+
+// Operation describes a change of the stored rules handed to the hooks.
+type Operation struct {
+	// Method is the name of the adapter method making the change, e.g.
+	// AddPolicy.
+	Method string
+	Sec    string
+	PType  string
+	// Rules holds the rules added or removed, and the old rules of an
+	// update. It holds the rules read by the imports.
+	Rules []Rule
+	// NewRules holds the new rules of an update.
+	NewRules    []Rule
+	FieldIndex  int
+	FieldValues []string
+	// Model is the model written by SavePolicy and SyncPolicy.
+	Model model.Model
+}
+
+// Hook is called around every change of the stored rules made through the
+// adapter.
+type Hook interface {
+	// BeforeWrite is called before the change runs, an error aborts it
+	// before any statement is sent.
+	BeforeWrite(ctx context.Context, op Operation) error
+	// AfterWrite is called with the outcome once the change committed or
+	// failed, and when a hook aborted it.
+	AfterWrite(ctx context.Context, op Operation, err error)
+}
+
+// hookList holds the registered hooks, it is shared by the copies of an
+// adapter.
+type hookList struct {
+	mu    sync.RWMutex
+	hooks []Hook
+}
+
+// RegisterHook registers h to be called around every change of the stored
+// rules, by the adapter and by its tenant, table and transaction copies.
+// The hooks are called in registration order.
+func (a *Adapter) RegisterHook(h Hook) {
+	a.hooks.mu.Lock()
+	defer a.hooks.mu.Unlock()
+	a.hooks.hooks = append(a.hooks.hooks, h)
+}
+
+// registered returns the registered hooks.
+func (l *hookList) registered() []Hook {
+	l.mu.RLock()
+	defer l.mu.RUnlock()
+	return l.hooks
+}
+
+// mutate runs the change op with write, calling the hooks around it. When
+// dispatch is set the change is also handed to the dispatcher, or only to
+// it, as the dispatch mode asks.
+func (a *Adapter) mutate(ctx context.Context, op Operation, write func(ctx context.Context) error, dispatch func(d persist.Dispatcher) error) error {
+	ctx, m := a.startMeasure(ctx, op.Method)
+	total := int64(-1)
+	if op.Model != nil || op.Rules != nil {
+		total = int64(opRuleCount(op))
+	}
+	ctx = a.startProgress(ctx, op.Method, total)
+	if a.plan != nil {
+		write = a.plan.record(op.Method, write)
+	}
+	hooks := a.hooks.registered()
+	err := a.checkTx()
+	if err == nil {
+		err = a.beforeWrite(ctx, hooks, op)
+	}
+	if err == nil && a.partitioned && a.plan == nil {
+		err = a.EnsureTenantPartition(ctx, a.tenant)
+	}
+	if err == nil {
+		switch {
+		case dispatch != nil && a.dispatchOnly():
+			err = a.dispatch(dispatch)
+		case dispatch != nil:
+			if err = write(ctx); err == nil {
+				err = a.dispatch(dispatch)
+			}
+		default:
+			err = write(ctx)
+		}
+	}
+	// The dispatcher writes the dispatched-only changes through Local, which
+	// emits their events.
+	if err == nil && (dispatch == nil || !a.dispatchOnly()) {
+		a.emitEvent(op)
+	}
+	err = a.withContext(err)
+	for _, h := range hooks {
+		h.AfterWrite(ctx, op, err)
+	}
+	if err != nil {
+		a.stats.recordErr(err)
+	}
+	if m != nil {
+		a.finishMeasure(ctx, m, opRuleCount(op), err)
+	}
+	return err
+}
+
+// beforeWrite calls the BeforeWrite method of hooks until one fails.
+func (a *Adapter) beforeWrite(ctx context.Context, hooks []Hook, op Operation) error {
+	for _, h := range hooks {
+		if err := h.BeforeWrite(ctx, op); err != nil {
+			return fmt.Errorf("hook aborted %s: %w", op.Method, err)
+		}
+	}
+	return nil
+}
+
+// opRules converts the rules of an operation.
+func (a *Adapter) opRules(pType string, rules ...[]string) []Rule {
+	converted := make([]Rule, 0, len(rules))
+	for _, rule := range rules {
+		converted = append(converted, a.buildRule(pType, rule))
+	}
+	return converted
+}
@@ -0,0 +1,144 @@
+package adapter
+
+import (
+	"context"
+	"errors"
+	"reflect"
+	"testing"
+
+	"github.com/casbin/casbin/v2/model"
+)
+
+// recordingHook records the calls it receives in events, and fails the
+// BeforeWrite calls of the method named by abort.
+type recordingHook struct {
+	name   string
+	abort  string
+	events *[]string
+	errs   []error
+}
+
+func (h *recordingHook) BeforeWrite(ctx context.Context, op Operation) error {
+	*h.events = append(*h.events, h.name+" before "+op.Method)
+	if op.Method == h.abort {
+		return errors.New("denied")
+	}
+	return nil
+}
+
+func (h *recordingHook) AfterWrite(ctx context.Context, op Operation, err error) {
+	*h.events = append(*h.events, h.name+" after "+op.Method)
+	h.errs = append(h.errs, err)
+}
+
+func TestHooks(t *testing.T) {
+	a := newSqliteAdapter(t)
+	var events []string
+	first := &recordingHook{name: "first", events: &events}
+	second := &recordingHook{name: "second", abort: "RemovePolicy", events: &events}
+	a.RegisterHook(first)
+	a.RegisterHook(second)
+
+	if err := a.AddPolicy("p", "p", []string{"alice", "data1", "read"}); err != nil {
+		t.Fatalf("AddPolicy failed: %v", err)
+	}
+	want := []string{"first before AddPolicy", "second before AddPolicy", "first after AddPolicy", "second after AddPolicy"}
+	if !reflect.DeepEqual(events, want) {
+		t.Errorf("events %v, supposed to be %v", events, want)
+	}
+
+	// The abort skips the remaining BeforeWrite calls and the write, and
+	// every hook learns about it.
+	events = nil
+	err := a.RemovePolicy("p", "p", []string{"alice", "data1", "read"})
+	if err == nil {
+		t.Fatal("expected the hook to abort RemovePolicy")
+	}
+	want = []string{"first before RemovePolicy", "second before RemovePolicy", "first after RemovePolicy", "second after RemovePolicy"}
+	if !reflect.DeepEqual(events, want) {
+		t.Errorf("events %v, supposed to be %v", events, want)
+	}
+	if got := first.errs[len(first.errs)-1]; got != err {
+		t.Errorf("AfterWrite received %v, supposed to be %v", got, err)
+	}
+	if count, _ := a.model().Count(); count != 1 {
+		t.Errorf("%d rows after the aborted removal, supposed to be 1", count)
+	}
+
+	// A failing write reaches AfterWrite.
+	err = a.AddPolicies("p", "p", nil)
+	if got := first.errs[len(first.errs)-1]; got != err {
+		t.Errorf("AfterWrite received %v, supposed to be %v", got, err)
+	}
+}
+
+func TestHooksMethods(t *testing.T) {
+	a := newSqliteAdapter(t)
+	var events []string
+	hook := &recordingHook{name: "hook", events: &events}
+	a.RegisterHook(hook)
+
+	m, _ := model.NewModelFromFile("examples/rbac_model.conf")
+	m.AddPolicy("p", "p", []string{"alice", "data1", "read"})
+
+	changes := []struct {
+		method string
+		change func() error
+	}{
+		{"AddPolicies", func() error {
+			return a.AddPolicies("p", "p", [][]string{{"bob", "data2", "write"}})
+		}},
+		{"UpdatePolicy", func() error {
+			return a.UpdatePolicy("p", "p", []string{"bob", "data2", "write"}, []string{"bob", "data2", "read"})
+		}},
+		{"UpdatePolicies", func() error {
+			return a.UpdatePolicies("p", "p", [][]string{{"bob", "data2", "read"}}, [][]string{{"bob", "data3", "read"}})
+		}},
+		{"UpdateFilteredPolicies", func() error {
+			_, err := a.UpdateFilteredPolicies("p", "p", [][]string{{"bob", "data4", "read"}}, 0, "bob")
+			return err
+		}},
+		{"RemovePolicies", func() error {
+			return a.RemovePolicies("p", "p", [][]string{{"bob", "data4", "read"}})
+		}},
+		{"RemoveFilteredPolicy", func() error {
+			return a.RemoveFilteredPolicy("p", "p", 0, "bob")
+		}},
+		{"SavePolicy", func() error { return a.SavePolicy(m) }},
+		{"SyncPolicy", func() error { return a.SyncPolicy(m) }},
+		{"Deduplicate", func() error {
+			_, err := a.Deduplicate(context.Background())
+			return err
+		}},
+		{"AddPolicy", func() error {
+			return a.Transaction(context.Background(), func(tx *Adapter) error {
+				return tx.AddPolicy("p", "p", []string{"carol", "data1", "read"})
+			})
+		}},
+	}
+	for _, c := range changes {
+		events = nil
+		if err := c.change(); err != nil {
+			t.Fatalf("%s failed: %v", c.method, err)
+		}
+		want := []string{"hook before " + c.method, "hook after " + c.method}
+		if !reflect.DeepEqual(events, want) {
+			t.Errorf("%s: events %v, supposed to be %v", c.method, events, want)
+		}
+	}
+}
+
+func TestHooksSyncSave(t *testing.T) {
+	a := newSqliteAdapter(t, WithSyncSave())
+	var events []string
+	a.RegisterHook(&recordingHook{name: "hook", events: &events})
+
+	m, _ := model.NewModelFromFile("examples/rbac_model.conf")
+	if err := a.SavePolicy(m); err != nil {
+		t.Fatalf("SavePolicy failed: %v", err)
+	}
+	want := []string{"hook before SavePolicy", "hook after SavePolicy"}
+	if !reflect.DeepEqual(events, want) {
+		t.Errorf("events %v, supposed to be %v", events, want)
+	}
+}
@@ -0,0 +1,158 @@
+package adapter
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+	"unicode/utf8"
+)
+
+// ErrImportRejected is returned by ImportPolicies when rows were rejected
+// and nothing was written as BestEffort isn't set.
+var ErrImportRejected = errors.New("rows of the import were rejected")
+
+// ImportStatus is the outcome of a row of ImportPolicies.
+type ImportStatus string
+
+const (
+	// ImportInserted rows were inserted, or would be by a dry run.
+	ImportInserted ImportStatus = "inserted"
+	// ImportDuplicate rows were skipped as the rule is stored already or
+	// repeated by an earlier row.
+	ImportDuplicate ImportStatus = "duplicate"
+	// ImportRejected rows are invalid, see ImportRowResult.Err.
+	ImportRejected ImportStatus = "rejected"
+	// ImportAborted rows are valid but weren't written, as other rows were
+	// rejected.
+	ImportAborted ImportStatus = "aborted"
+)
+
+// ImportReport is the outcome of ImportPolicies, row by row.
+type ImportReport struct {
+	// Rows holds the result of every input row, in the input order.
+	Rows []ImportRowResult
+	// Inserted, Skipped and Rejected count the inserted, duplicate and
+	// rejected rows.
+	Inserted, Skipped, Rejected int
+}
+
+// ImportRowResult is the outcome of the input row Index.
+type ImportRowResult struct {
+	Index  int
+	Status ImportStatus
+	// Err is the reason of a rejected row.
+	Err error
+}
+
+// ImportPolicies inserts the rules of pType made of rows and reports the
+// outcome of every row. The rows with no or more than 6 values, with values
+// longer than their column or failing opts.Validator are rejected, and the
+// duplicate ones are always skipped. Unless opts.BestEffort is set, nothing
+// is written when a row is rejected and the error wraps ErrImportRejected,
+// otherwise the valid rows are inserted, and when their insert fails, they
+// are inserted one by one, rejecting the ones the database refuses.
+// opts.Replace and opts.DryRun apply like for ImportCSV.
+func (a *Adapter) ImportPolicies(ctx context.Context, rows [][]string, pType string, opts ImportOptions) (*ImportReport, error) {
+	if !strings.HasPrefix(pType, "p") && !strings.HasPrefix(pType, "g") || len(pType) > maxPTypeLength {
+		return nil, fmt.Errorf("invalid policy type: %q", pType)
+	}
+	if g := a.groupingFor(pType); g != nil {
+		return g.ImportPolicies(ctx, rows, pType, opts)
+	}
+
+	report := &ImportReport{Rows: make([]ImportRowResult, len(rows))}
+	valid := make([]int, 0, len(rows))
+	for i, values := range rows {
+		report.Rows[i].Index = i
+		if err := a.validateImported(values, opts.Validator); err != nil {
+			report.reject(i, err)
+			continue
+		}
+		valid = append(valid, i)
+	}
+	if report.Rejected > 0 && !opts.BestEffort {
+		for _, i := range valid {
+			report.Rows[i].Status = ImportAborted
+		}
+		return report, fmt.Errorf("%w: %d of %d rows", ErrImportRejected, report.Rejected, len(rows))
+	}
+
+	seen := make(map[ruleKey]struct{}, len(valid))
+	if !opts.Replace {
+		err := a.scanPages(a.modelCtx(ctx), func(rows []ruleRow) error {
+			for _, row := range rows {
+				seen[row.key()] = struct{}{}
+			}
+			return nil
+		})
+		if err != nil {
+			return nil, fmt.Errorf("failed to read stored rules: %w", err)
+		}
+	}
+	rules := make([]Rule, 0, len(valid))
+	indexes := make([]int, 0, len(valid))
+	for _, i := range valid {
+		rule := a.buildRule(pType, rows[i])
+		if _, ok := seen[rule.key()]; ok {
+			report.Rows[i].Status = ImportDuplicate
+			report.Skipped++
+			continue
+		}
+		seen[rule.key()] = struct{}{}
+		rules = append(rules, rule)
+		indexes = append(indexes, i)
+	}
+
+	err := a.mutate(ctx, Operation{Method: "ImportPolicies", PType: pType, Rules: rules}, func(ctx context.Context) error {
+		return a.importReported(ctx, rules, indexes, report, opts)
+	}, nil)
+	if err != nil {
+		return nil, err
+	}
+	return report, nil
+}
+
+// validateImported checks the values of an imported row.
+func (a *Adapter) validateImported(values []string, validator func([]string) error) error {
+	if len(values) == 0 || len(values) > maxFieldIndex+1 {
+		return fmt.Errorf("rule should have 1 to %d values, got %d", maxFieldIndex+1, len(values))
+	}
+	for i, value := range values {
+		value = a.normalizeValue(i, value)
+		if maxLength := a.valueLengths[i]; maxLength > 0 && utf8.RuneCountInString(value) > maxLength {
+			return fmt.Errorf("value %.20q... of %s longer than the %d characters of the column", value, valueColumns[i], maxLength)
+		}
+	}
+	if validator != nil {
+		return validator(values)
+	}
+	return nil
+}
+
+// importReported writes the rules of the input rows indexes and reports
+// them inserted. With BestEffort a failed insert is retried rule by rule.
+func (a *Adapter) importReported(ctx context.Context, rules []Rule, indexes []int, report *ImportReport, opts ImportOptions) error {
+	write := ImportOptions{Replace: opts.Replace, DryRun: opts.DryRun}
+	_, err := a.importRules(ctx, "ImportPolicies", rules, write)
+	if err != nil && (!opts.BestEffort || opts.Replace || opts.DryRun) {
+		return err
+	}
+	for n, rule := range rules {
+		if err != nil {
+			if _, rowErr := a.importRules(ctx, "ImportPolicies", []Rule{rule}, write); rowErr != nil {
+				report.reject(indexes[n], rowErr)
+				continue
+			}
+		}
+		report.Rows[indexes[n]].Status = ImportInserted
+		report.Inserted++
+	}
+	return nil
+}
+
+// reject reports the input row i rejected for err.
+func (r *ImportReport) reject(i int, err error) {
+	r.Rows[i].Status, r.Rows[i].Err = ImportRejected, err
+	r.Rejected++
+}
@@ -0,0 +1,112 @@
+package adapter
+
+import (
+	"context"
+	"errors"
+	"slices"
+	"strings"
+	"testing"
+)
+
+// importRows mixes valid, duplicate and invalid rows.
+var importRows = [][]string{
+	{"carol", "data3", "read"},
+	{"alice", "data1", "read"},
+	{"a", "b", "c", "d", "e", "f", "g"},
+	{"dave", strings.Repeat("x", maxValueLength+1), "read"},
+	{"carol", "data3", "read"},
+	{"mallory", "data1", "read"},
+	{"erin", "data3", "write"},
+}
+
+// noMallory rejects the rules of mallory.
+func noMallory(values []string) error {
+	if values[0] == "mallory" {
+		return errors.New("mallory isn't allowed")
+	}
+	return nil
+}
+
+func importStatuses(report *ImportReport) []ImportStatus {
+	statuses := make([]ImportStatus, len(report.Rows))
+	for i, row := range report.Rows {
+		statuses[i] = row.Status
+	}
+	return statuses
+}
+
+func TestImportPoliciesBestEffort(t *testing.T) {
+	a := newSqliteAdapter(t)
+	initPolicy(t, a)
+
+	report, err := a.ImportPolicies(context.Background(), importRows, "p", ImportOptions{BestEffort: true, Validator: noMallory})
+	if err != nil {
+		t.Fatalf("ImportPolicies failed: %v", err)
+	}
+	want := []ImportStatus{ImportInserted, ImportDuplicate, ImportRejected, ImportRejected, ImportDuplicate, ImportRejected, ImportInserted}
+	if statuses := importStatuses(report); !slices.Equal(statuses, want) {
+		t.Errorf("statuses %v, supposed to be %v", statuses, want)
+	}
+	if report.Inserted != 2 || report.Skipped != 2 || report.Rejected != 3 {
+		t.Errorf("report counts %d inserted, %d skipped and %d rejected", report.Inserted, report.Skipped, report.Rejected)
+	}
+	for i, want := range map[int]string{2: "1 to 6 values", 3: "longer than", 5: "mallory isn't allowed"} {
+		if err := report.Rows[i].Err; err == nil || !strings.Contains(err.Error(), want) {
+			t.Errorf("row %d rejected for %v, supposed to mention %q", i, err, want)
+		}
+	}
+	if count, _ := a.CountPolicies(a.ctx, nil); count != 7 {
+		t.Errorf("stored %d rules, supposed to be the 5 initial ones and 2 imported", count)
+	}
+}
+
+func TestImportPoliciesAllOrNothing(t *testing.T) {
+	a := newSqliteAdapter(t)
+	initPolicy(t, a)
+
+	report, err := a.ImportPolicies(context.Background(), importRows, "p", ImportOptions{Validator: noMallory})
+	if !errors.Is(err, ErrImportRejected) {
+		t.Fatalf("ImportPolicies err: %v, supposed to wrap ErrImportRejected", err)
+	}
+	want := []ImportStatus{ImportAborted, ImportAborted, ImportRejected, ImportRejected, ImportAborted, ImportRejected, ImportAborted}
+	if statuses := importStatuses(report); !slices.Equal(statuses, want) {
+		t.Errorf("statuses %v, supposed to be %v", statuses, want)
+	}
+	if ids := storedIDs(t, a); len(ids) != 5 {
+		t.Errorf("stored ids %v, supposed to be the 5 initial ones", ids)
+	}
+
+	// Without the invalid rows everything is inserted in one transaction.
+	rows := [][]string{importRows[0], importRows[1], importRows[6]}
+	report, err = a.ImportPolicies(context.Background(), rows, "p", ImportOptions{})
+	if err != nil {
+		t.Fatalf("ImportPolicies failed: %v", err)
+	}
+	if statuses := importStatuses(report); !slices.Equal(statuses, []ImportStatus{ImportInserted, ImportDuplicate, ImportInserted}) {
+		t.Errorf("statuses %v", statuses)
+	}
+	if ids := storedIDs(t, a); len(ids) != 7 {
+		t.Errorf("stored ids %v, supposed to be 7 rows", ids)
+	}
+}
+
+func TestImportPoliciesDriverErrors(t *testing.T) {
+	a := newSqliteAdapter(t)
+	if _, err := a.db.Exec(a.ctx, "CREATE TRIGGER no_bad BEFORE INSERT ON casbin_rule WHEN NEW.v1 = 'bad' BEGIN SELECT RAISE(ABORT, 'bad object'); END"); err != nil {
+		t.Fatalf("failed to create trigger: %v", err)
+	}
+
+	report, err := a.ImportPolicies(context.Background(), [][]string{{"alice", "data1"}, {"bob", "bad"}, {"carol", "data2"}}, "g", ImportOptions{BestEffort: true})
+	if err != nil {
+		t.Fatalf("ImportPolicies failed: %v", err)
+	}
+	if statuses := importStatuses(report); !slices.Equal(statuses, []ImportStatus{ImportInserted, ImportRejected, ImportInserted}) {
+		t.Errorf("statuses %v, supposed to reject the row refused by the database", statuses)
+	}
+	if ids := storedIDs(t, a); len(ids) != 2 {
+		t.Errorf("stored ids %v, supposed to be 2 rows", ids)
+	}
+	if _, err := a.ImportPolicies(context.Background(), nil, "x", ImportOptions{}); err == nil {
+		t.Error("expected an invalid policy type to fail the import")
+	}
+}
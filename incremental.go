@@ -0,0 +1,156 @@
+package adapter
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/casbin/casbin/v2/model"
+	"github.com/gogf/gf/v2/database/gdb"
+)
+
+const (
+	// updatedAtColumn and deletedAtColumn are maintained by gdb: it sets
+	// updated_at on every write and soft deletes the rows of tables with a
+	// deleted_at column, setting it instead of deleting them.
+	updatedAtColumn = "updated_at"
+	deletedAtColumn = "deleted_at"
+
+	// incrementalOverlap widens the window of LoadPolicyIncremental to catch
+	// the changes stamped by database clients whose clocks run behind, the
+	// changes read twice are applied once.
+	incrementalOverlap = 2 * time.Second
+)
+
+// trackingColumns are the columns of WithChangeTracking.
+var trackingColumns = []string{updatedAtColumn, deletedAtColumn}
+
+// WithChangeTracking adds updated_at and deleted_at columns to the policy
+// table when it is ensured, which makes gdb stamp every written row and
+// soft delete the removed ones, so that LoadPolicyIncremental can pick up
+// the changes since a previous load. SavePolicy deletes the rows instead
+// of truncating the table and doesn't write in parallel. The soft deleted
+// rows keep their place in the table until they are purged. It can't be
+// combined with WithUniqueIndex.
+func WithChangeTracking() AdapterOption {
+	return AdapterOption{apply: func(a *Adapter) {
+		a.changeTracking = true
+	}}
+}
+
+// ensureTrackingColumns adds the columns of WithChangeTracking to the
+// policy table when they are missing.
+func (a *Adapter) ensureTrackingColumns(ctx context.Context) error {
+	statements, err := a.missingColumnsSql(ctx, a.tableName, trackingColumns)
+	if err != nil {
+		return err
+	}
+	for _, sql := range statements {
+		if _, err := a.db.Exec(ctx, sql); err != nil && !isDuplicateColumnError(err) {
+			return fmt.Errorf("failed to add change tracking columns: %w", err)
+		}
+	}
+	a.softDelete = true
+	return a.clearTableFields(ctx, a.tableName)
+}
+
+// liveSql returns the condition restricting the rows of the policy table
+// referred to by table to the ones that aren't soft deleted, raw queries
+// don't get it from gdb. It is empty without soft deletes.
+func (a *Adapter) liveSql(table string) string {
+	if !a.softDelete {
+		return ""
+	}
+	return fmt.Sprintf("%s.%s IS NULL", table, deletedAtColumn)
+}
+
+// LoadPolicyIncremental applies the rules added and removed since the
+// watermark since to model, which holds the rules loaded up to since, and
+// returns the watermark of the next incremental load. The zero time loads
+// every rule. It requires the updated_at and deleted_at columns of
+// WithChangeTracking. The window starts slightly before since so that
+// clock skew between writers doesn't lose changes. A removed rule is kept
+// when another row still holds it. Changes made by truncating or swapping
+// the table are not seen, do a full load after them.
+func (a *Adapter) LoadPolicyIncremental(ctx context.Context, model model.Model, since time.Time) (time.Time, error) {
+	if model == nil {
+		return since, errors.New("model cannot be nil")
+	}
+
+	fields, err := a.db.TableFields(ctx, a.tableName)
+	if err != nil {
+		return since, fmt.Errorf("failed to get columns of %s: %w", a.tableName, err)
+	}
+	if fields[updatedAtColumn] == nil || fields[deletedAtColumn] == nil {
+		return since, fmt.Errorf("table %s has no %s and %s columns, use WithChangeTracking", a.tableName, updatedAtColumn, deletedAtColumn)
+	}
+
+	columns := append([]string{"id", a.pTypeColumn}, valueColumns...)
+	query := a.modelCtx(ctx).Unscoped().Fields(append(columns, trackingColumns...))
+	if !since.IsZero() {
+		from := since.Add(-incrementalOverlap)
+		query = query.Where(fmt.Sprintf("(%s > ? OR %s > ?)", updatedAtColumn, deletedAtColumn), from, from)
+	}
+
+	var (
+		added, removed []Rule
+		watermark      = since
+	)
+	err = a.scanRecordPages(query, func(records gdb.Result) error {
+		for _, record := range records {
+			var rule Rule
+			if err := record.Struct(&rule); err != nil {
+				return fmt.Errorf("failed to scan rule: %w", err)
+			}
+			for _, column := range trackingColumns {
+				if at := a.dbTime(record[column].Time()); at.After(watermark) {
+					watermark = at
+				}
+			}
+			if record[deletedAtColumn].IsNil() {
+				added = append(added, rule)
+			} else {
+				removed = append(removed, rule)
+			}
+		}
+		return nil
+	})
+	if err != nil {
+		return since, fmt.Errorf("failed to scan changed rules: %w", err)
+	}
+
+	if err := a.removeLoadedRules(ctx, model, removed); err != nil {
+		return since, err
+	}
+	for _, rule := range added {
+		a.loadPolicyRule(rule, model)
+	}
+	return watermark, nil
+}
+
+// removeLoadedRules removes rules, whose rows were deleted, from model
+// unless other rows still hold them.
+func (a *Adapter) removeLoadedRules(ctx context.Context, model model.Model, rules []Rule) error {
+	if len(rules) == 0 {
+		return nil
+	}
+	stored, err := a.storedRows(ctx, rules)
+	if err != nil {
+		return err
+	}
+	live := make(map[ruleKey]bool, len(stored))
+	for _, row := range stored {
+		live[row.key()] = true
+	}
+
+	for _, rule := range rules {
+		if live[rule.key()] || rule.PType == "" || model[rule.PType[:1]][rule.PType] == nil {
+			continue
+		}
+		if _, err := model.RemovePolicy(rule.PType[:1], rule.PType, rule.toSlice()); err != nil {
+			return fmt.Errorf("failed to remove rule: %w", err)
+		}
+	}
+	return nil
+}
@@ -0,0 +1,94 @@
+package adapter
+
+import (
+	"context"
+	"sort"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/casbin/casbin/v2/model"
+)
+
+// modelPolicy returns the rules of m as sorted lines.
+func modelPolicy(m model.Model) []string {
+	var lines []string
+	for _, sec := range []string{"p", "g"} {
+		for pType, ast := range m[sec] {
+			for _, rule := range ast.Policy {
+				lines = append(lines, pType+", "+strings.Join(rule, ", "))
+			}
+		}
+	}
+	sort.Strings(lines)
+	return lines
+}
+
+func TestLoadPolicyIncremental(t *testing.T) {
+	ctx := context.Background()
+	a := newSqliteAdapter(t, WithChangeTracking())
+	initPolicy(t, a)
+
+	m, err := model.NewModelFromFile("examples/rbac_model.conf")
+	if err != nil {
+		t.Fatalf("failed to load model: %v", err)
+	}
+	watermark, err := a.LoadPolicyIncremental(ctx, m, time.Time{})
+	if err != nil {
+		t.Fatalf("LoadPolicyIncremental failed: %v", err)
+	}
+	if watermark.IsZero() {
+		t.Fatal("the watermark didn't advance")
+	}
+
+	// Add, remove and update rules, and soft delete a duplicate row whose
+	// rule is still held by another row.
+	steps := []func() error{
+		func() error { return a.AddPolicy("p", "p", []string{"carol", "data3", "read"}) },
+		func() error { return a.RemovePolicy("p", "p", []string{"bob", "data2", "write"}) },
+		func() error {
+			return a.UpdatePolicy("p", "p", []string{"alice", "data1", "read"}, []string{"alice", "data3", "read"})
+		},
+		func() error { return a.AddPolicy("p", "p", []string{"data2_admin", "data2", "read"}) },
+		func() error {
+			ids := storedIDs(t, a)
+			_, err := a.model().Where("id", ids[len(ids)-1]).Delete()
+			return err
+		},
+		func() error { return a.RemovePolicy("g", "g", []string{"alice", "data2_admin"}) },
+	}
+	for i, step := range steps {
+		if err := step(); err != nil {
+			t.Fatalf("step %d failed: %v", i, err)
+		}
+		if watermark, err = a.LoadPolicyIncremental(ctx, m, watermark); err != nil {
+			t.Fatalf("step %d: LoadPolicyIncremental failed: %v", i, err)
+		}
+
+		full, _ := model.NewModelFromFile("examples/rbac_model.conf")
+		if err := a.LoadPolicy(full); err != nil {
+			t.Fatalf("LoadPolicy failed: %v", err)
+		}
+		got, want := modelPolicy(m), modelPolicy(full)
+		if strings.Join(got, "\n") != strings.Join(want, "\n") {
+			t.Errorf("step %d: incremental policy %q, supposed to be %q", i, got, want)
+		}
+	}
+
+	// The soft deleted rows remain in the table.
+	count, err := a.model().Unscoped().WhereNotNull(deletedAtColumn).Count()
+	if err != nil || count != 4 {
+		t.Errorf("%d soft deleted rows, err: %v, supposed to be 4", count, err)
+	}
+}
+
+func TestLoadPolicyIncrementalRequiresColumns(t *testing.T) {
+	a := newSqliteAdapter(t)
+	m, _ := model.NewModelFromFile("examples/rbac_model.conf")
+	if _, err := a.LoadPolicyIncremental(context.Background(), m, time.Time{}); err == nil {
+		t.Error("expected an error without the change tracking columns")
+	}
+	if _, err := NewAdapter(context.Background(), "", "", newSqliteDB(t), WithChangeTracking(), WithUniqueIndex()); err == nil {
+		t.Error("expected an error combining change tracking with a unique index")
+	}
+}
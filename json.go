@@ -0,0 +1,144 @@
+package adapter
+
+import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+)
+
+// jsonRule is the JSON representation of a rule used by ExportJSON and
+// ImportJSON.
+type jsonRule struct {
+	PType  string   `json:"ptype"`
+	Values []string `json:"values"`
+}
+
+// ImportOffsetError reports malformed JSON input of ImportJSON, Offset is
+// the byte offset in the input where the problem was found.
+type ImportOffsetError struct {
+	Offset int64
+	Err    error
+}
+
+func (e *ImportOffsetError) Error() string {
+	return fmt.Sprintf("offset %d: %v", e.Offset, e.Err)
+}
+
+func (e *ImportOffsetError) Unwrap() error {
+	return e.Err
+}
+
+// ExportJSON writes the stored rules to w as a JSON array of
+// {"ptype": "p", "values": [...]} objects, in the same order as ExportCSV.
+// A non-nil filter restricts the exported rules like LoadFilteredPolicy
+// does. The rules are encoded while they are read in pages of the page
+// size, so large tables are streamed.
+func (a *Adapter) ExportJSON(ctx context.Context, w io.Writer, filter *Filter) error {
+	bw := bufio.NewWriter(w)
+	if _, err := bw.WriteString("["); err != nil {
+		return fmt.Errorf("failed to write rules: %w", err)
+	}
+
+	first := true
+	for _, sec := range policySections {
+		query := a.modelCtx(ctx).WhereLike(a.pTypeColumn, sec+"%")
+		if filter != nil {
+			var err error
+			if query, err = a.filterQuery(query, *filter); err != nil {
+				return err
+			}
+		}
+
+		err := a.scanPages(query, func(rows []ruleRow) error {
+			for _, row := range rows {
+				values := row.toSlice()
+				if len(values) == 0 {
+					continue
+				}
+				data, err := json.Marshal(jsonRule{PType: row.PType, Values: values})
+				if err != nil {
+					return fmt.Errorf("failed to encode rule: %w", err)
+				}
+				if !first {
+					data = append([]byte(","), data...)
+				}
+				first = false
+				if _, err := bw.Write(data); err != nil {
+					return fmt.Errorf("failed to write rule: %w", err)
+				}
+			}
+			return nil
+		})
+		if err != nil {
+			return fmt.Errorf("failed to export %s rules: %w", sec, err)
+		}
+	}
+
+	if _, err := bw.WriteString("]\n"); err != nil {
+		return fmt.Errorf("failed to write rules: %w", err)
+	}
+	if err := bw.Flush(); err != nil {
+		return fmt.Errorf("failed to write rules: %w", err)
+	}
+	return nil
+}
+
+// ImportJSON reads rules in the format written by ExportJSON from r and
+// inserts them like ImportCSV does, returning the number of inserted rules.
+// The input is decoded as a stream and validated before anything is
+// written. The first problem found is returned as an *ImportOffsetError.
+func (a *Adapter) ImportJSON(ctx context.Context, r io.Reader, opts ImportOptions) (int, error) {
+	rules, err := parseJSON(r)
+	if err != nil {
+		return 0, err
+	}
+	return a.importHooked(ctx, "ImportJSON", rules, opts)
+}
+
+// parseJSON decodes and validates a JSON array of rules.
+func parseJSON(r io.Reader) ([]Rule, error) {
+	dec := json.NewDecoder(r)
+	offsetErr := func(err error) error {
+		var syntaxErr *json.SyntaxError
+		if errors.As(err, &syntaxErr) {
+			return &ImportOffsetError{Offset: syntaxErr.Offset, Err: err}
+		}
+		return &ImportOffsetError{Offset: dec.InputOffset(), Err: err}
+	}
+
+	token, err := dec.Token()
+	if err != nil {
+		return nil, offsetErr(err)
+	}
+	if delim, ok := token.(json.Delim); !ok || delim != '[' {
+		return nil, offsetErr(errors.New("rules should be a JSON array"))
+	}
+
+	var rules []Rule
+	for dec.More() {
+		var raw json.RawMessage
+		if err := dec.Decode(&raw); err != nil {
+			return nil, offsetErr(err)
+		}
+		// Offset of the first byte of the element.
+		offset := dec.InputOffset() - int64(len(raw))
+
+		var item jsonRule
+		if err := json.Unmarshal(raw, &item); err != nil {
+			return nil, &ImportOffsetError{Offset: offset, Err: err}
+		}
+		rule, err := importedRule(item.PType, item.Values)
+		if err != nil {
+			return nil, &ImportOffsetError{Offset: offset, Err: err}
+		}
+		rules = append(rules, rule)
+	}
+
+	if _, err := dec.Token(); err != nil {
+		return nil, offsetErr(err)
+	}
+	return rules, nil
+}
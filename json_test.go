@@ -0,0 +1,105 @@
+package adapter
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"strings"
+	"testing"
+
+	"github.com/casbin/casbin/v2"
+)
+
+func TestJSONRoundTrip(t *testing.T) {
+	src := newSqliteAdapter(t, WithPageSize(2))
+	initPolicy(t, src)
+	if err := src.AddPolicy("p", "p", []string{"carol", "data,3", `say "hi"`}); err != nil {
+		t.Fatalf("AddPolicy failed: %v", err)
+	}
+
+	var buf bytes.Buffer
+	if err := src.ExportJSON(context.Background(), &buf, nil); err != nil {
+		t.Fatalf("ExportJSON failed: %v", err)
+	}
+
+	dst := newSqliteAdapter(t)
+	inserted, err := dst.ImportJSON(context.Background(), &buf, ImportOptions{})
+	if err != nil {
+		t.Fatalf("ImportJSON failed: %v", err)
+	}
+	if inserted != 6 {
+		t.Errorf("inserted %d rules, supposed to be 6", inserted)
+	}
+
+	e, err := casbin.NewEnforcer("examples/rbac_model.conf", dst)
+	if err != nil {
+		t.Fatalf("NewEnforcer failed: %v", err)
+	}
+	testGetPolicy(t, e, [][]string{
+		{"alice", "data1", "read"},
+		{"bob", "data2", "write"},
+		{"data2_admin", "data2", "read"},
+		{"data2_admin", "data2", "write"},
+		{"carol", "data,3", `say "hi"`},
+	})
+	if res, _ := e.GetGroupingPolicy(); !arrayEqualsWithoutOrder(res, [][]string{{"alice", "data2_admin"}}) {
+		t.Errorf("grouping policy: %v, supposed to be [[alice data2_admin]]", res)
+	}
+
+	// Merging skips the rules already stored, replacing starts over.
+	in := `[{"ptype":"p","values":["alice","data1","read"]},{"ptype":"p","values":["erin","data4","read"]}]`
+	if inserted, err = dst.ImportJSON(context.Background(), strings.NewReader(in), ImportOptions{SkipDuplicates: true}); err != nil || inserted != 1 {
+		t.Errorf("merge inserted %d rules, err: %v, supposed to be 1", inserted, err)
+	}
+	if inserted, err = dst.ImportJSON(context.Background(), strings.NewReader(in), ImportOptions{Replace: true}); err != nil || inserted != 2 {
+		t.Errorf("replace inserted %d rules, err: %v, supposed to be 2", inserted, err)
+	}
+	if err := e.LoadPolicy(); err != nil {
+		t.Fatalf("LoadPolicy failed: %v", err)
+	}
+	testGetPolicy(t, e, [][]string{{"alice", "data1", "read"}, {"erin", "data4", "read"}})
+}
+
+func TestExportJSONEmpty(t *testing.T) {
+	a := newSqliteAdapter(t)
+
+	var buf bytes.Buffer
+	if err := a.ExportJSON(context.Background(), &buf, nil); err != nil {
+		t.Fatalf("ExportJSON failed: %v", err)
+	}
+	if buf.String() != "[]\n" {
+		t.Errorf("exported %q, supposed to be an empty array", buf.String())
+	}
+}
+
+func TestImportJSONMalformed(t *testing.T) {
+	a := newSqliteAdapter(t)
+	valid := `[{"ptype":"p","values":["alice","data1","read"]},`
+
+	tests := []struct {
+		name   string
+		in     string
+		offset int64
+	}{
+		{"syntax", valid + `{"ptype":"p","values":["bob",]}]`, int64(len(valid)) + 30},
+		{"too many values", valid + `{"ptype":"p","values":["a","b","c","d","e","f","g"]}]`, int64(len(valid))},
+		{"policy type", valid + `{"ptype":"x","values":["bob"]}]`, int64(len(valid))},
+		{"values type", valid + `{"ptype":"p","values":"bob"}]`, int64(len(valid))},
+		{"not an array", `{"ptype":"p"}`, 1},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := a.ImportJSON(context.Background(), strings.NewReader(tt.in), ImportOptions{})
+			var offsetErr *ImportOffsetError
+			if !errors.As(err, &offsetErr) {
+				t.Fatalf("expected an *ImportOffsetError, got %v", err)
+			}
+			if offsetErr.Offset != tt.offset {
+				t.Errorf("offset %d, supposed to be %d", offsetErr.Offset, tt.offset)
+			}
+		})
+	}
+
+	e, _ := casbin.NewEnforcer("examples/rbac_model.conf", a)
+	testGetPolicy(t, e, [][]string{})
+}
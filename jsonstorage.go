@@ -0,0 +1,125 @@
+package adapter
+
+import (
+	"encoding/json"
+	"fmt"
+	"strings"
+
+	"github.com/gogf/gf/v2/container/gvar"
+	"github.com/gogf/gf/v2/database/gdb"
+	"github.com/gogf/gf/v2/frame/g"
+)
+
+// jsonValuesColumn is the column of WithJSONStorage holding the values of
+// the rules.
+const jsonValuesColumn = "vals"
+
+// WithJSONStorage stores the values of each rule as a JSON array in the
+// vals column of the policy table, like ["alice","data1","read"], in place
+// of the v0 to v5 columns. The table created has the id, p_type, vals and
+// created_at columns, vals is a JSON column on MySQL, a jsonb column with a
+// GIN index on PostgreSQL and a text column holding JSON on the other
+// databases.
+//
+// The filters of the loads, GetFilteredPolicies and CountPolicies only
+// support PType and the values V0 to V5, which are compared in the database
+// by the JSON functions of MySQL, PostgreSQL, sqlite and SQL Server. On DM
+// the values are matched once the rules of the policy types are read. Like with
+// WithSingleRuleColumn, the other queries of the adapter return
+// ErrUnsupportedInPackedMode, and ClickHouse and the options adding columns
+// or encoding the values aren't supported.
+func WithJSONStorage() AdapterOption {
+	return AdapterOption{apply: func(a *Adapter) {
+		a.jsonStorage = true
+	}}
+}
+
+// jsonValues returns the JSON array of the values of rule, up to its last
+// non-empty one.
+func jsonValues(rule Rule) string {
+	values := []string{rule.V0, rule.V1, rule.V2, rule.V3, rule.V4, rule.V5}
+	for len(values) > 0 && values[len(values)-1] == "" {
+		values = values[:len(values)-1]
+	}
+	// Marshaling strings can't fail.
+	data, _ := json.Marshal(values)
+	return string(data)
+}
+
+// jsonValuesRecord returns the row of the JSON storage holding rule.
+func (a *Adapter) jsonValuesRecord(rule Rule) g.Map {
+	return g.Map{a.pTypeColumn: rule.PType, jsonValuesColumn: jsonValues(rule)}
+}
+
+// jsonValuesCondition returns the condition matching the stored rules of
+// rules exactly.
+func (a *Adapter) jsonValuesCondition(rules []Rule) (string, []interface{}) {
+	equal := a.dialect.jsonEqualSql(jsonValuesColumn)
+	condition := "(" + a.pTypeColumn + " = ? AND " + equal + ")"
+	binds := strings.Count(equal, "?")
+	conditions := make([]string, len(rules))
+	args := make([]interface{}, 0, (1+binds)*len(rules))
+	for i, rule := range rules {
+		conditions[i] = condition
+		args = append(args, rule.PType)
+		for range binds {
+			args = append(args, jsonValues(rule))
+		}
+	}
+	return "(" + strings.Join(conditions, " OR ") + ")", args
+}
+
+// parseJSONValues sets the value fields of the rules held by the JSON
+// arrays of records, in place.
+func (a *Adapter) parseJSONValues(records gdb.Result) error {
+	for _, record := range records {
+		data, ok := record[jsonValuesColumn]
+		if !ok {
+			continue
+		}
+		var values []string
+		if !data.IsNil() {
+			if err := json.Unmarshal(data.Bytes(), &values); err != nil {
+				return fmt.Errorf("failed to parse values %q: %w", data.String(), err)
+			}
+		}
+		if len(values) > len(valueColumns) {
+			return fmt.Errorf("failed to parse values %q: more than %d values", data.String(), len(valueColumns))
+		}
+		for i, column := range valueColumns {
+			var value string
+			if i < len(values) {
+				value = values[i]
+			}
+			record[column] = gvar.New(value)
+		}
+	}
+	return nil
+}
+
+// jsonFilterCondition returns the condition selecting the rules of the
+// policy types of filter holding one of the values of its fields, the
+// values being compared in the database when the dialect extracts them.
+func (a *Adapter) jsonFilterCondition(filter Filter) (string, []interface{}) {
+	var (
+		conditions []string
+		args       []interface{}
+	)
+	for i, field := range filterFields(&filter) {
+		if len(*field) == 0 {
+			continue
+		}
+		column := a.pTypeColumn
+		if i > 0 {
+			if column = a.dialect.jsonValueSql(jsonValuesColumn, i-1); column == "" {
+				continue
+			}
+			column = "COALESCE(" + column + ", '')"
+		}
+		conditions = append(conditions, column+" IN ("+strings.TrimSuffix(strings.Repeat("?,", len(*field)), ",")+")")
+		for _, value := range *field {
+			args = append(args, value)
+		}
+	}
+	return strings.Join(conditions, " AND "), args
+}
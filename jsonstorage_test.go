@@ -0,0 +1,129 @@
+package adapter
+
+import (
+	"context"
+	"errors"
+	"reflect"
+	"slices"
+	"testing"
+
+	"github.com/casbin/casbin/v2"
+)
+
+// storedValues returns the JSON values of the policy table, ordered by id.
+func storedValues(t *testing.T, a *Adapter) []string {
+	t.Helper()
+	values, err := a.db.Model(a.tableName).Fields(jsonValuesColumn).OrderAsc("id").Array()
+	if err != nil {
+		t.Fatalf("failed to read values column: %v", err)
+	}
+	stored := make([]string, len(values))
+	for i, value := range values {
+		stored[i] = value.String()
+	}
+	return stored
+}
+
+func TestJSONStorage(t *testing.T) {
+	ctx := context.Background()
+	a := newSqliteAdapter(t, WithJSONStorage())
+	fields, err := a.db.TableFields(ctx, a.tableName)
+	if err != nil {
+		t.Fatalf("TableFields failed: %v", err)
+	}
+	if len(fields) != 4 || fields["p_type"] == nil || fields["vals"] == nil {
+		t.Errorf("columns %v, supposed to be id, p_type, vals and created_at", fields)
+	}
+
+	runAdapterSuite(t, a)
+
+	if err := a.AddPolicies("p", "p", [][]string{{"erin", "", `say "hi"`}, {"frank", "data,4"}}); err != nil {
+		t.Fatalf("AddPolicies failed: %v", err)
+	}
+	stored := storedValues(t, a)
+	if want := []string{`["erin","","say \"hi\""]`, `["frank","data,4"]`}; !slices.Equal(stored[len(stored)-2:], want) {
+		t.Errorf("stored values %q, supposed to end with %q", stored, want)
+	}
+	rules, err := a.GetFilteredPolicies(ctx, Filter{V0: []string{"erin"}})
+	if err != nil {
+		t.Fatalf("GetFilteredPolicies failed: %v", err)
+	}
+	if want := []Rule{{PType: "p", V0: "erin", V2: `say "hi"`}}; !reflect.DeepEqual(rules, want) {
+		t.Errorf("GetFilteredPolicies = %v, supposed to be %v", rules, want)
+	}
+	if err := a.RemovePolicy("p", "p", []string{"erin", "", `say "hi"`}); err != nil {
+		t.Fatalf("RemovePolicy failed: %v", err)
+	}
+	if count, err := a.CountPolicies(ctx, Filter{V0: []string{"erin"}}); err != nil || count != 0 {
+		t.Errorf("CountPolicies = %d, err: %v, supposed to be 0", count, err)
+	}
+}
+
+func TestJSONStorageFilters(t *testing.T) {
+	testJSONStorageFilters(t, newSqliteAdapter(t, WithJSONStorage(), WithMaxTxRows(2)))
+}
+
+// testJSONStorageFilters checks the filters of a, storing the rules as JSON,
+// compared in the database.
+func testJSONStorageFilters(t *testing.T, a *Adapter) {
+	t.Helper()
+	ctx := context.Background()
+	initPolicy(t, a)
+
+	tests := []struct {
+		filter interface{}
+		want   []Rule
+	}{
+		{Filter{PType: []string{"g"}}, []Rule{{PType: "g", V0: "alice", V1: "data2_admin"}}},
+		{Filter{V1: []string{"data2"}, V2: []string{"write"}}, []Rule{
+			{PType: "p", V0: "bob", V1: "data2", V2: "write"}, {PType: "p", V0: "data2_admin", V1: "data2", V2: "write"},
+		}},
+		// The missing values of the arrays are empty.
+		{Filter{PType: []string{"g"}, V2: []string{""}}, []Rule{{PType: "g", V0: "alice", V1: "data2_admin"}}},
+		{[]Filter{{V0: []string{"bob"}}, {PType: []string{"g"}}}, []Rule{
+			{PType: "p", V0: "bob", V1: "data2", V2: "write"}, {PType: "g", V0: "alice", V1: "data2_admin"},
+		}},
+	}
+	for _, tt := range tests {
+		rules, err := a.GetFilteredPolicies(ctx, tt.filter)
+		if err != nil {
+			t.Fatalf("GetFilteredPolicies(%+v) failed: %v", tt.filter, err)
+		}
+		if !reflect.DeepEqual(rules, tt.want) {
+			t.Errorf("GetFilteredPolicies(%+v) = %v, supposed to be %v", tt.filter, rules, tt.want)
+		}
+	}
+
+	e, _ := casbin.NewEnforcer("examples/rbac_model.conf", a)
+	if err := e.LoadFilteredPolicy(Filter{V0Prefix: []string{"al"}}); !errors.Is(err, ErrInvalidFilter) {
+		t.Errorf("LoadFilteredPolicy err: %v, supposed to be an invalid filter", err)
+	}
+	if _, err := e.RemoveFilteredPolicy(1, "data2"); err != nil {
+		t.Fatalf("RemoveFilteredPolicy failed: %v", err)
+	}
+	if err := e.LoadPolicy(); err != nil {
+		t.Fatalf("LoadPolicy failed: %v", err)
+	}
+	testGetPolicy(t, e, [][]string{{"alice", "data1", "read"}})
+}
+
+func TestJSONStorageQueries(t *testing.T) {
+	testPackedQueries(t, newSqliteAdapter(t, WithJSONStorage()))
+}
+
+func TestJSONStorageOptions(t *testing.T) {
+	for name, opts := range map[string][]AdapterOption{
+		"rule column": {WithJSONStorage(), WithSingleRuleColumn("rule")},
+		"history":     {WithJSONStorage(), WithHistory()},
+		"tenant":      {WithJSONStorage(), WithTenantColumn("tenant_id")},
+	} {
+		if _, err := NewAdapter(context.Background(), "", "", newSqliteDB(t), opts...); err == nil {
+			t.Errorf("%s: expected an error", name)
+		}
+	}
+
+	db := newSqliteAdapter(t).db
+	if _, err := NewAdapter(context.Background(), "", "", db, WithJSONStorage()); !errors.Is(err, ErrIncompatibleSchema) {
+		t.Errorf("NewAdapter err: %v, supposed to be an incompatible schema", err)
+	}
+}
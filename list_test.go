@@ -0,0 +1,131 @@
+package adapter
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"reflect"
+	"testing"
+)
+
+func TestListPolicies(t *testing.T) {
+	ctx := context.Background()
+	a := newSqliteAdapter(t)
+	var rules [][]string
+	for i := 0; i < 25; i++ {
+		rules = append(rules, []string{fmt.Sprintf("user%02d", i), "data1", "read"})
+	}
+	if err := a.AddPolicies("p", "p", rules); err != nil {
+		t.Fatalf("AddPolicies failed: %v", err)
+	}
+	if err := a.AddPolicy("g", "g", []string{"user00", "admin"}); err != nil {
+		t.Fatalf("AddPolicy failed: %v", err)
+	}
+
+	for _, tc := range []struct {
+		page    int
+		orderBy string
+		desc    bool
+		want    []string
+	}{
+		{page: 1, orderBy: "id", want: []string{"user00", "user09"}},
+		{page: 3, orderBy: "id", want: []string{"user20", "user24"}},
+		{page: 4, orderBy: "id"},
+		{page: 1, orderBy: "id", desc: true, want: []string{"user24", "user15"}},
+		// The rules were stored within the same second, the ties are
+		// ordered by id.
+		{page: 3, orderBy: "created_at", desc: true, want: []string{"user04", "user00"}},
+	} {
+		rows, total, err := a.ListPolicies(ctx, Filter{PType: []string{"p"}}, tc.page, 10, tc.orderBy, tc.desc)
+		if err != nil {
+			t.Fatalf("page %d by %s: ListPolicies failed: %v", tc.page, tc.orderBy, err)
+		}
+		if total != 25 {
+			t.Errorf("page %d by %s: total %d, supposed to be 25", tc.page, tc.orderBy, total)
+		}
+		var bounds []string
+		if len(rows) > 0 {
+			bounds = []string{rows[0].V0, rows[len(rows)-1].V0}
+		}
+		if !reflect.DeepEqual(bounds, tc.want) {
+			t.Errorf("page %d by %s: rules from %v, supposed to be %v", tc.page, tc.orderBy, bounds, tc.want)
+		}
+	}
+
+	rows, total, err := a.ListPolicies(ctx, Filter{V0: []string{"user00"}}, 1, 10, "id", false)
+	if err != nil || total != 2 || len(rows) != 2 || rows[1].PType != "g" {
+		t.Errorf("rules %v of user00, total %d, err: %v, supposed to be its p and g rules", rows, total, err)
+	}
+
+	for name, tc := range map[string]struct {
+		filter  Filter
+		page    int
+		orderBy string
+	}{
+		"order":  {page: 1, orderBy: "v0; DROP TABLE casbin_rule"},
+		"page":   {page: 0, orderBy: "id"},
+		"paging": {filter: Filter{Limit: 5}, page: 1, orderBy: "id"},
+	} {
+		if _, _, err := a.ListPolicies(ctx, tc.filter, tc.page, 10, tc.orderBy, false); err == nil {
+			t.Errorf("expected an invalid %s to fail the listing", name)
+		}
+	}
+}
+
+func TestListPoliciesPageSizeCap(t *testing.T) {
+	a := newSqliteAdapter(t)
+	var rules [][]string
+	for i := 0; i < maxListPageSize+1; i++ {
+		rules = append(rules, []string{fmt.Sprintf("user%d", i), "data1", "read"})
+	}
+	if err := a.AddPolicies("p", "p", rules); err != nil {
+		t.Fatalf("AddPolicies failed: %v", err)
+	}
+	rows, total, err := a.ListPolicies(context.Background(), Filter{}, 1, 5000, "id", false)
+	if err != nil {
+		t.Fatalf("ListPolicies failed: %v", err)
+	}
+	if len(rows) != maxListPageSize || total != maxListPageSize+1 {
+		t.Errorf("listed %d of %d rules, supposed to be capped at %d", len(rows), total, maxListPageSize)
+	}
+}
+
+func TestListPoliciesTenant(t *testing.T) {
+	a := newSqliteAdapter(t, WithTenantColumn("tenant_id"))
+	t1, t2 := a.ForTenant("t1"), a.ForTenant("t2")
+	for i, tenant := range []*Adapter{t1, t2, t2} {
+		if err := tenant.AddPolicy("p", "p", []string{fmt.Sprintf("user%d", i), "data1", "read"}); err != nil {
+			t.Fatalf("AddPolicy failed: %v", err)
+		}
+	}
+	rows, total, err := t2.ListPolicies(context.Background(), Filter{}, 1, 10, "id", false)
+	if err != nil || total != 2 || len(rows) != 2 || rows[0].V0 != "user1" {
+		t.Errorf("rules %v of t2, total %d, err: %v, supposed to be its 2 rules", rows, total, err)
+	}
+}
+
+func TestListPoliciesSplitTables(t *testing.T) {
+	ctx := context.Background()
+	a := newSqliteAdapter(t, WithSplitTables("casbin_p", "casbin_g"))
+	initPolicy(t, a)
+
+	// The rules of one table are paged.
+	rows, total, err := a.ListPolicies(ctx, Filter{PType: []string{"p"}}, 2, 2, "id", false)
+	if err != nil {
+		t.Fatalf("ListPolicies failed: %v", err)
+	}
+	want := []Rule{{PType: "p", V0: "data2_admin", V1: "data2", V2: "read"}, {PType: "p", V0: "data2_admin", V1: "data2", V2: "write"}}
+	if total != 4 || !reflect.DeepEqual(rows, want) {
+		t.Errorf("ListPolicies = %v, %d, supposed to be %v, 4", rows, total, want)
+	}
+	if rows, total, err := a.ListPolicies(ctx, Filter{PType: []string{"g"}}, 1, 10, "id", false); err != nil || total != 1 || len(rows) != 1 {
+		t.Errorf("ListPolicies = %v, %d, err: %v, supposed to be the g rule", rows, total, err)
+	}
+
+	// The pages of several tables aren't merged.
+	for _, filter := range []Filter{{}, {PType: []string{"p", "g"}}, {V0: []string{"alice"}}} {
+		if _, _, err := a.ListPolicies(ctx, filter, 1, 10, "id", false); !errors.Is(err, ErrInvalidFilter) {
+			t.Errorf("ListPolicies(%+v) err: %v, supposed to be an invalid filter", filter, err)
+		}
+	}
+}
@@ -0,0 +1,359 @@
+package adapter
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"testing"
+
+	"github.com/casbin/casbin/v2"
+)
+
+// seedRules stores n distinct p rules, the i-th one being
+// ("user<i>", "data<i%10>", "read").
+func seedRules(tb testing.TB, a *Adapter, n int) {
+	tb.Helper()
+	rules := make([][]string, 0, n)
+	for i := 0; i < n; i++ {
+		rules = append(rules, []string{fmt.Sprintf("user%d", i), fmt.Sprintf("data%d", i%10), "read"})
+	}
+	if err := a.AddPolicies("p", "p", rules); err != nil {
+		tb.Fatalf("AddPolicies failed: %v", err)
+	}
+}
+
+func TestLoadPolicyPaged(t *testing.T) {
+	const n = 25000
+	a := newSqliteAdapter(t, WithPageSize(7000))
+	seedRules(t, a, n)
+
+	e, err := casbin.NewEnforcer("examples/rbac_model.conf", a)
+	if err != nil {
+		t.Fatalf("NewEnforcer failed: %v", err)
+	}
+	policy, _ := e.GetPolicy()
+	if len(policy) != n {
+		t.Fatalf("loaded %d rules, supposed to be %d", len(policy), n)
+	}
+	// The rules are loaded in the order they were added.
+	for i, rule := range policy {
+		if rule[0] != fmt.Sprintf("user%d", i) {
+			t.Fatalf("rule %d is %v, supposed to be for user%d", i, rule, i)
+		}
+	}
+
+	all, err := a.GetAllPolicies(a.ctx, nil)
+	if err != nil || len(all) != n || all[n-1].V0 != fmt.Sprintf("user%d", n-1) {
+		t.Errorf("GetAllPolicies returned %d rules, err: %v", len(all), err)
+	}
+}
+
+func BenchmarkLoadPolicy(b *testing.B) {
+	const n = 20000
+	db := newSqliteDB(b)
+	seed, err := NewAdapter(context.Background(), "", "", db)
+	if err != nil {
+		b.Fatalf("NewAdapter failed: %v", err)
+	}
+	seedRules(b, seed, n)
+
+	// A single page of all rows compared with the default and small pages.
+	for _, size := range []int{n, defaultPageSize, 1000} {
+		b.Run(fmt.Sprintf("page=%d", size), func(b *testing.B) {
+			a, err := NewAdapter(context.Background(), "", "", db, WithPageSize(size))
+			if err != nil {
+				b.Fatalf("NewAdapter failed: %v", err)
+			}
+			m, _ := casbin.NewEnforcer("examples/rbac_model.conf")
+			b.ReportAllocs()
+			b.ResetTimer()
+			for i := 0; i < b.N; i++ {
+				model := m.GetModel().Copy()
+				if err := a.LoadPolicy(model); err != nil {
+					b.Fatalf("LoadPolicy failed: %v", err)
+				}
+			}
+		})
+	}
+}
+
+func TestLoadPolicyIndexesRules(t *testing.T) {
+	a := newSqliteAdapter(t)
+	initPolicy(t, a)
+	// The duplicate row is loaded once.
+	if err := a.AddPolicy("p", "p", []string{"alice", "data1", "read"}); err != nil {
+		t.Fatalf("AddPolicy failed: %v", err)
+	}
+
+	e, err := casbin.NewEnforcer("examples/rbac_model.conf", a)
+	if err != nil {
+		t.Fatalf("NewEnforcer failed: %v", err)
+	}
+	testGetPolicy(t, e, [][]string{{"alice", "data1", "read"}, {"bob", "data2", "write"}, {"data2_admin", "data2", "read"}, {"data2_admin", "data2", "write"}})
+
+	// The enforcer finds the loaded rules in its policy map.
+	if ok, _ := e.HasPolicy("bob", "data2", "write"); !ok {
+		t.Error("the loaded rule is missing from the policy map")
+	}
+	if ok, err := e.RemovePolicy("bob", "data2", "write"); !ok || err != nil {
+		t.Errorf("RemovePolicy returned %v, err: %v, supposed to remove the rule", ok, err)
+	}
+	if err := e.LoadPolicy(); err != nil {
+		t.Fatalf("LoadPolicy failed: %v", err)
+	}
+	testGetPolicy(t, e, [][]string{{"alice", "data1", "read"}, {"data2_admin", "data2", "read"}, {"data2_admin", "data2", "write"}})
+}
+
+// BenchmarkLoadPolicyLarge compares LoadPolicy with loading the rules
+// scanned into structs, as LoadPolicy did before reading the records
+// directly.
+func BenchmarkLoadPolicyLarge(b *testing.B) {
+	const n = 500000
+	a, err := NewAdapter(context.Background(), "", "", newSqliteDB(b), WithBatchSize(2000))
+	if err != nil {
+		b.Fatalf("NewAdapter failed: %v", err)
+	}
+	seedRules(b, a, n)
+	m, _ := casbin.NewEnforcer("examples/rbac_model.conf")
+
+	b.Run("structs", func(b *testing.B) {
+		b.ReportAllocs()
+		for i := 0; i < b.N; i++ {
+			model := m.GetModel().Copy()
+			err := a.scanPages(a.model(), func(rows []ruleRow) error {
+				for _, row := range rows {
+					a.loadPolicyRule(row.Rule, model)
+				}
+				return nil
+			})
+			if err != nil {
+				b.Fatalf("failed to load rules: %v", err)
+			}
+		}
+	})
+	b.Run("records", func(b *testing.B) {
+		b.ReportAllocs()
+		for i := 0; i < b.N; i++ {
+			if err := a.LoadPolicy(m.GetModel().Copy()); err != nil {
+				b.Fatalf("LoadPolicy failed: %v", err)
+			}
+		}
+	})
+}
+
+func TestLoadFilteredPolicyLargeFilter(t *testing.T) {
+	a := newSqliteAdapter(t)
+	seedRules(t, a, 6000)
+
+	// Every other user of the first 10000, listed backwards: 5000 values
+	// split over 5 queries, 3000 of which are stored.
+	var users []string
+	for i := 9999; i >= 0; i -= 2 {
+		users = append(users, fmt.Sprintf("user%d", i))
+	}
+	if chunks := a.filterChunks(Filter{V0: users}); len(chunks) != 5 {
+		t.Errorf("filter split in %d chunks, supposed to be 5", len(chunks))
+	}
+
+	e, _ := casbin.NewEnforcer("examples/rbac_model.conf", a)
+	if err := e.LoadFilteredPolicy(Filter{PType: []string{"p"}, V0: users}); err != nil {
+		t.Fatalf("LoadFilteredPolicy failed: %v", err)
+	}
+	policy, _ := e.GetPolicy()
+	if len(policy) != 3000 {
+		t.Fatalf("loaded %d rules, supposed to be 3000", len(policy))
+	}
+	for i, rule := range policy {
+		if rule[0] != fmt.Sprintf("user%d", 2*i+1) {
+			t.Fatalf("rule %d is %v, supposed to be for user%d", i, rule, 2*i+1)
+		}
+	}
+}
+
+func TestFilterChunks(t *testing.T) {
+	a := newSqliteAdapter(t, WithFilterChunkSize(2))
+	seedRules(t, a, 30)
+
+	// Both lists are split, every combination of their chunks is queried.
+	filter := Filter{
+		V0: []string{"user1", "user2", "user11", "user12", "user21"},
+		V1: []string{"data1", "data2", "data3"},
+	}
+	if chunks := a.filterChunks(filter); len(chunks) != 6 {
+		t.Errorf("filter split in %d chunks, supposed to be 6", len(chunks))
+	}
+
+	e, _ := casbin.NewEnforcer("examples/rbac_model.conf", a)
+	if err := e.LoadFilteredPolicy(filter); err != nil {
+		t.Fatalf("LoadFilteredPolicy failed: %v", err)
+	}
+	testGetPolicy(t, e, [][]string{{"user1", "data1", "read"}, {"user2", "data2", "read"}, {"user11", "data1", "read"}, {"user12", "data2", "read"}, {"user21", "data1", "read"}})
+}
+
+func TestLoadFilteredPolicyAnyFilter(t *testing.T) {
+	a := newSqliteAdapter(t)
+	initPolicy(t, a)
+	e, _ := casbin.NewEnforcer("examples/rbac_model.conf", a)
+
+	filters := []Filter{{V0: []string{"alice"}}, {PType: []string{"p"}, V1: []string{"data2"}}}
+	if err := e.LoadFilteredPolicy(filters); err != nil {
+		t.Fatalf("LoadFilteredPolicy failed: %v", err)
+	}
+	testGetPolicy(t, e, [][]string{{"alice", "data1", "read"}, {"bob", "data2", "write"}, {"data2_admin", "data2", "read"}, {"data2_admin", "data2", "write"}})
+	if grouping, _ := e.GetGroupingPolicy(); len(grouping) != 1 {
+		t.Errorf("grouping policy %v, supposed to be the rule of alice", grouping)
+	}
+
+	// The rules matched by both filters are loaded once.
+	err := e.LoadFilteredPolicy([]*Filter{{V0: []string{"alice"}}, {PType: []string{"p"}, V1: []string{"data1"}}})
+	if err != nil {
+		t.Fatalf("LoadFilteredPolicy failed: %v", err)
+	}
+	testGetPolicy(t, e, [][]string{{"alice", "data1", "read"}})
+
+	for _, filter := range []interface{}{[]Filter{}, []*Filter{nil}} {
+		if err := a.LoadFilteredPolicy(e.GetModel(), filter); err == nil {
+			t.Errorf("LoadFilteredPolicy of %#v succeeded", filter)
+		}
+	}
+}
+
+func TestLoadFilteredPolicyPointer(t *testing.T) {
+	a := newSqliteAdapter(t)
+	initPolicy(t, a)
+	e, _ := casbin.NewEnforcer("examples/rbac_model.conf", a)
+
+	if err := e.LoadFilteredPolicy(&Filter{PType: []string{"p"}, V0: []string{"bob"}}); err != nil {
+		t.Fatalf("LoadFilteredPolicy failed: %v", err)
+	}
+	testGetPolicy(t, e, [][]string{{"bob", "data2", "write"}})
+	if !a.IsFiltered() {
+		t.Error("the adapter isn't filtered after a filtered load")
+	}
+
+	// A nil filter loads everything.
+	if err := e.LoadFilteredPolicy((*Filter)(nil)); err != nil {
+		t.Fatalf("LoadFilteredPolicy failed: %v", err)
+	}
+	testGetPolicy(t, e, [][]string{{"alice", "data1", "read"}, {"bob", "data2", "write"}, {"data2_admin", "data2", "read"}, {"data2_admin", "data2", "write"}})
+	if a.IsFiltered() {
+		t.Error("the adapter is filtered after loading with a nil filter")
+	}
+
+	err := a.LoadFilteredPolicy(e.GetModel(), "p")
+	if err == nil || !strings.Contains(err.Error(), "string") {
+		t.Errorf("err: %v, supposed to name the string type", err)
+	}
+}
+
+func TestLoadFilteredPolicyShapes(t *testing.T) {
+	a := newSqliteAdapter(t)
+	initPolicy(t, a)
+	e, _ := casbin.NewEnforcer("examples/rbac_model.conf", a)
+
+	tests := []struct {
+		name   string
+		filter interface{}
+		want   [][]string
+	}{
+		{"map", map[string][]string{"p_type": {"p"}, "v0": {"alice", "bob"}}, [][]string{{"alice", "data1", "read"}, {"bob", "data2", "write"}}},
+		{"patterns", [][]string{{"p", "", "data2", "read"}, {"p", "bob"}}, [][]string{{"bob", "data2", "write"}, {"data2_admin", "data2", "read"}}},
+		{"wildcard pattern", [][]string{{"", "", "", "write"}}, [][]string{{"bob", "data2", "write"}, {"data2_admin", "data2", "write"}}},
+	}
+	for _, tt := range tests {
+		if err := e.LoadFilteredPolicy(tt.filter); err != nil {
+			t.Fatalf("%s: LoadFilteredPolicy failed: %v", tt.name, err)
+		}
+		testGetPolicy(t, e, tt.want)
+	}
+
+	err := a.LoadFilteredPolicy(e.GetModel(), map[string][]string{"v0": {"alice"}, "domain": {"d1"}})
+	if err == nil || !strings.Contains(err.Error(), `"domain"`) || !strings.Contains(err.Error(), "p_type, v0") {
+		t.Errorf("err: %v, supposed to reject the key and list the valid ones", err)
+	}
+	if err := a.LoadFilteredPolicy(e.GetModel(), [][]string{make([]string, 8)}); err == nil {
+		t.Error("expected an error for a pattern with too many fields")
+	}
+}
+
+func TestLoadFilteredPolicyNegated(t *testing.T) {
+	a := newSqliteAdapter(t, WithFilterChunkSize(1))
+	initPolicy(t, a)
+	e, _ := casbin.NewEnforcer("examples/rbac_model.conf", a)
+
+	// The negated list isn't split in chunks.
+	filter := Filter{V0: []string{"alice", "bob", "data2_admin"}, NotV2: []string{"write", "delete"}}
+	if err := e.LoadFilteredPolicy(filter); err != nil {
+		t.Fatalf("LoadFilteredPolicy failed: %v", err)
+	}
+	testGetPolicy(t, e, [][]string{{"alice", "data1", "read"}, {"data2_admin", "data2", "read"}})
+	if grouping, _ := e.GetGroupingPolicy(); len(grouping) != 1 {
+		t.Errorf("grouping policy %v, supposed to be the rule of alice", grouping)
+	}
+
+	count, err := a.CountPolicies(context.Background(), Filter{NotPType: []string{"g"}, NotV0: []string{"data2_admin"}})
+	if err != nil || count != 2 {
+		t.Errorf("count %d, err: %v, supposed to be 2", count, err)
+	}
+}
+
+func TestLoadFilteredPolicyPrefix(t *testing.T) {
+	a := newSqliteAdapter(t)
+	if err := a.AddPolicies("p", "p", [][]string{
+		{"alice", "repo/org_1/proj2", "read"},
+		{"alice", "repo/orgX1/proj2", "read"},
+		{"bob", "repo/org_1/proj3", "write"},
+		{"alice", "repo/org_10/proj1", "read"},
+		{"alice", "100%/a", "read"},
+		{"alice", "1000/a", "read"},
+		{"alice", "x!_y", "read"},
+		{"alice", "x!zy", "read"},
+	}); err != nil {
+		t.Fatalf("AddPolicies failed: %v", err)
+	}
+	e, _ := casbin.NewEnforcer("examples/rbac_model.conf", a)
+
+	// The wildcards and the escape character of the prefixes are literal,
+	// and the prefixes are ANDed with the other columns.
+	filter := Filter{V0: []string{"alice"}, V1Prefix: []string{"repo/org_1/", "100%", "x!_"}}
+	if err := e.LoadFilteredPolicy(filter); err != nil {
+		t.Fatalf("LoadFilteredPolicy failed: %v", err)
+	}
+	testGetPolicy(t, e, [][]string{{"alice", "repo/org_1/proj2", "read"}, {"alice", "100%/a", "read"}, {"alice", "x!_y", "read"}})
+
+	encrypted := newSqliteAdapter(t, WithEncryption(newTestCodec(t, "k1", testKeys)))
+	if err := encrypted.LoadFilteredPolicy(e.GetModel(), Filter{V1Prefix: []string{"repo/"}}); err == nil {
+		t.Error("expected an error for a prefix of encrypted values")
+	}
+}
+
+func TestLoadIncrementalFilteredPolicy(t *testing.T) {
+	a := newSqliteAdapter(t)
+	initPolicy(t, a)
+	if err := a.AddPolicies("p", "p", [][]string{{"carol", "data3", "read"}, {"alice", "data3", "write"}}); err != nil {
+		t.Fatalf("AddPolicies failed: %v", err)
+	}
+	e, _ := casbin.NewEnforcer("examples/rbac_model.conf", a)
+
+	// The base rules of bob, then the rules of data2, repeating the rule of
+	// bob, and of data3.
+	if err := e.LoadFilteredPolicy(Filter{V0: []string{"bob"}}); err != nil {
+		t.Fatalf("LoadFilteredPolicy failed: %v", err)
+	}
+	m := e.GetModel()
+	for _, filter := range []Filter{{V1: []string{"data2"}}, {V1: []string{"data3"}}} {
+		if err := a.LoadIncrementalFilteredPolicy(m, filter); err != nil {
+			t.Fatalf("LoadIncrementalFilteredPolicy failed: %v", err)
+		}
+	}
+	testGetPolicy(t, e, [][]string{{"bob", "data2", "write"}, {"data2_admin", "data2", "read"}, {"data2_admin", "data2", "write"}, {"carol", "data3", "read"}, {"alice", "data3", "write"}})
+	if grouping, _ := e.GetGroupingPolicy(); len(grouping) != 0 {
+		t.Errorf("grouping policy %v, supposed to be empty", grouping)
+	}
+	if !a.IsFiltered() {
+		t.Error("the adapter isn't filtered after an incremental load")
+	}
+	if ok, _ := e.Enforce("carol", "data3", "read"); !ok {
+		t.Error("the incrementally loaded rule isn't enforced")
+	}
+}
@@ -0,0 +1,119 @@
+package adapter
+
+import (
+	"cmp"
+	"context"
+	"fmt"
+	"slices"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/gogf/gf/v2/database/gdb"
+)
+
+// WithLoadOrder sets the order the loads read the rules in, the order of
+// the rules in the model, which matters to the priority and firstMatch
+// effects. column is id, the default, or a column of the policy table
+// other than the ones of the rules, like created_at or a priority column
+// of its own, checked when the adapter is created. The ties are ordered by
+// id, in the same direction as column. The order applies to LoadPolicy and
+// to the filters without OrderBy of LoadFilteredPolicy and
+// GetFilteredPolicies. The loads ordered by another column than id read
+// the pages by offset, so a rule written during the load may be skipped
+// or read twice.
+func WithLoadOrder(column string, desc bool) AdapterOption {
+	return AdapterOption{apply: func(a *Adapter) {
+		a.loadOrder = filterPage{column: column, desc: desc}
+	}}
+}
+
+// ordered reports whether the loads have the order of WithLoadOrder rather
+// than the ascending ids.
+func (a *Adapter) ordered() bool {
+	return a.loadOrder.column != "" && (a.loadOrder.column != "id" || a.loadOrder.desc)
+}
+
+// validateLoadOrder checks that the column of WithLoadOrder is a column of
+// the policy table outside of the rule columns.
+func (a *Adapter) validateLoadOrder(ctx context.Context) error {
+	column := a.loadOrder.column
+	if column == "" || column == "id" {
+		return nil
+	}
+	if !isValidIdentifier(column) || slices.Contains(a.expectedColumns(), column) ||
+		column == ruleHashColumn || column == deletedAtColumn {
+		return fmt.Errorf("invalid load order column: %q", column)
+	}
+	fields, err := a.db.TableFields(ctx, a.tableName)
+	if err != nil {
+		return fmt.Errorf("failed to get columns of %s: %w", a.tableName, err)
+	}
+	if fields[column] == nil {
+		found := make([]string, 0, len(fields))
+		for name := range fields {
+			found = append(found, name)
+		}
+		sort.Strings(found)
+		return fmt.Errorf("load order column %s not found in table %s, found %v", column, a.tableName, found)
+	}
+	return nil
+}
+
+// pageOf returns the paging of filters like the function pageOf, ordered
+// by WithLoadOrder when the first filter has no OrderBy.
+func (a *Adapter) pageOf(filters []Filter) (filterPage, error) {
+	page, err := pageOf(filters)
+	if err != nil || !a.ordered() || len(filters) > 0 && filters[0].OrderBy != "" {
+		return page, err
+	}
+	page.column, page.desc = a.loadOrder.column, a.loadOrder.desc
+	return page, nil
+}
+
+// scanLoadPages is scanRecordPages for the loads, reading the pages in the
+// order of WithLoadOrder.
+func (a *Adapter) scanLoadPages(query *gdb.Model, fn func(records gdb.Result) error) error {
+	if !a.ordered() {
+		return a.scanRecordPages(query, fn)
+	}
+	for offset := 0; ; offset += a.pageSize {
+		if err := query.GetCtx().Err(); err != nil {
+			return err
+		}
+		records, err := query.Order(a.loadOrder.order()).Limit(offset, a.pageSize).All()
+		if err != nil {
+			return fmt.Errorf("failed to read rules page: %w", err)
+		}
+		if len(records) == 0 {
+			return nil
+		}
+		if err := fn(records); err != nil {
+			return err
+		}
+		if len(records) < a.pageSize {
+			return nil
+		}
+	}
+}
+
+// compareOrder compares the values of an order column, as numbers when
+// both are numeric. NULL comes first, like on MySQL and sqlite.
+func compareOrder(a, b string, aNull, bNull bool) int {
+	switch {
+	case aNull || bNull:
+		if aNull == bNull {
+			return 0
+		}
+		if aNull {
+			return -1
+		}
+		return 1
+	}
+	x, errX := strconv.ParseFloat(a, 64)
+	y, errY := strconv.ParseFloat(b, 64)
+	if errX == nil && errY == nil {
+		return cmp.Compare(x, y)
+	}
+	return strings.Compare(a, b)
+}
@@ -0,0 +1,103 @@
+package adapter
+
+import (
+	"context"
+	"testing"
+
+	"github.com/casbin/casbin/v2"
+	"github.com/casbin/casbin/v2/model"
+)
+
+// priorityModel allows or denies a request by the first matching rule.
+const priorityModel = `
+[request_definition]
+r = sub, obj, act
+
+[policy_definition]
+p = sub, obj, act, eft
+
+[policy_effect]
+e = priority(p.eft) || deny
+
+[matchers]
+m = r.sub == p.sub && r.obj == p.obj && r.act == p.act
+`
+
+// newPriorityAdapter creates an adapter ordering the loads by the priority
+// column of its table, holding rules stored in the reverse order of their
+// priorities.
+func newPriorityAdapter(t *testing.T, desc bool, opts ...AdapterOption) *Adapter {
+	t.Helper()
+	ctx := context.Background()
+	db := newSqliteAdapter(t).db
+	if _, err := db.Exec(ctx, "ALTER TABLE casbin_rule ADD COLUMN priority INTEGER"); err != nil {
+		t.Fatalf("failed to add priority column: %v", err)
+	}
+	a, err := NewAdapter(ctx, "", "", db, append(opts, WithLoadOrder("priority", desc))...)
+	if err != nil {
+		t.Fatalf("NewAdapter failed: %v", err)
+	}
+	rules := [][]string{{"alice", "data1", "read", "deny"}, {"bob", "data1", "read", "allow"}, {"alice", "data1", "read", "allow"}}
+	if err := a.AddPolicies("p", "p", rules); err != nil {
+		t.Fatalf("AddPolicies failed: %v", err)
+	}
+	for i, priority := range []int{3, 2, 1} {
+		if _, err := db.Model(a.tableName).Ctx(ctx).Data("priority", priority).Where("id", i+1).Update(); err != nil {
+			t.Fatalf("failed to set priority: %v", err)
+		}
+	}
+	return a
+}
+
+func TestLoadOrder(t *testing.T) {
+	m, _ := model.NewModelFromString(priorityModel)
+	for _, desc := range []bool{false, true} {
+		e, err := casbin.NewEnforcer(m, newPriorityAdapter(t, desc, WithFilterChunkSize(1)))
+		if err != nil {
+			t.Fatalf("NewEnforcer failed: %v", err)
+		}
+		want := [][]string{{"alice", "data1", "read", "allow"}, {"bob", "data1", "read", "allow"}, {"alice", "data1", "read", "deny"}}
+		if desc {
+			want = [][]string{want[2], want[1], want[0]}
+		}
+		testGetPolicy(t, e, want)
+
+		// The rules of the filters merged from several queries keep the
+		// order of LoadPolicy, run after run.
+		for run := 0; run < 3; run++ {
+			if err := e.LoadFilteredPolicy(Filter{V0: []string{"bob", "alice"}}); err != nil {
+				t.Fatalf("LoadFilteredPolicy failed: %v", err)
+			}
+			testGetPolicy(t, e, want)
+			if ok, _ := e.Enforce("alice", "data1", "read"); ok != !desc {
+				t.Errorf("desc %v: alice allowed %v, supposed to follow the first rule", desc, ok)
+			}
+		}
+	}
+
+	// An explicit OrderBy still wins over the load order.
+	a := newPriorityAdapter(t, false)
+	rules, err := a.GetFilteredPolicies(context.Background(), Filter{PType: []string{"p"}, OrderBy: "id desc"})
+	if err != nil {
+		t.Fatalf("GetFilteredPolicies failed: %v", err)
+	}
+	if len(rules) != 3 || rules[0].V3 != "allow" || rules[0].V0 != "alice" {
+		t.Errorf("rules %v, supposed to be ordered by descending id", rules)
+	}
+}
+
+func TestLoadOrderOptions(t *testing.T) {
+	for _, column := range []string{"v0", "p_type", "priority;", "missing"} {
+		if _, err := NewAdapter(context.Background(), "", "", newSqliteDB(t), WithLoadOrder(column, false)); err == nil {
+			t.Errorf("WithLoadOrder(%q): expected an error", column)
+		}
+	}
+	for _, column := range []string{"id", "created_at"} {
+		if _, err := NewAdapter(context.Background(), "", "", newSqliteDB(t), WithLoadOrder(column, true)); err != nil {
+			t.Errorf("WithLoadOrder(%q) failed: %v", column, err)
+		}
+	}
+	if _, err := NewAdapter(context.Background(), "", "", newSqliteDB(t), WithLoadOrder("created_at", false), WithJSONStorage()); err == nil {
+		t.Error("WithLoadOrder with JSON storage: expected an error")
+	}
+}
@@ -0,0 +1,248 @@
+package adapter
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"strings"
+	"sync/atomic"
+	"time"
+
+	"github.com/casbin/casbin/v2/model"
+	"github.com/gogf/gf/v2/database/gdb"
+	"github.com/gogf/gf/v2/os/glog"
+)
+
+// WithLogger logs every load and change of the stored rules to l, with the
+// operation, the table, the number of rules, the duration, the number of
+// rows affected and the error if any. Successes are logged at debug level,
+// failures at error level. Without it nothing is measured.
+func WithLogger(l *glog.Logger) AdapterOption {
+	return AdapterOption{apply: func(a *Adapter) {
+		a.logger = l
+	}}
+}
+
+// opMeasureKey is the context key of the operation measured with a context.
+type opMeasureKey struct{}
+
+// WithSlowThreshold logs the loads and changes of the stored rules taking
+// longer than d at warning level, with the operation, the duration, the
+// number of rules and of write statements and the filter of filtered loads.
+// The entries go to the logger of WithLogger, or to the logger of the
+// database. SlowOperations counts them.
+func WithSlowThreshold(d time.Duration) AdapterOption {
+	return AdapterOption{apply: func(a *Adapter) {
+		a.slowThreshold = d
+	}}
+}
+
+// SlowOperations returns the number of operations that took longer than
+// the threshold of WithSlowThreshold.
+func (a *Adapter) SlowOperations() int64 {
+	return a.stats.slowOps.Load()
+}
+
+// opMeasure measures an operation for the logger, the slow operation log,
+// the tracer and the metrics.
+type opMeasure struct {
+	method     string
+	start      time.Time
+	affected   atomic.Int64
+	statements atomic.Int64
+	// filter holds the filters of a filtered load.
+	filter []Filter
+}
+
+// measured reports whether the operations are measured, for a logger, a
+// slow threshold, tracing or metrics.
+func (a *Adapter) measured() bool {
+	return a.logger != nil || a.slowThreshold > 0 || a.tracerName != "" || a.metrics != nil
+}
+
+// startMeasure starts measuring the operation method, it returns a nil
+// opMeasure when the operations aren't measured.
+func (a *Adapter) startMeasure(ctx context.Context, method string) (context.Context, *opMeasure) {
+	if !a.measured() {
+		return ctx, nil
+	}
+	ctx = a.startSpan(ctx, method)
+	m := &opMeasure{method: method, start: time.Now()}
+	return context.WithValue(ctx, opMeasureKey{}, m), m
+}
+
+// finishMeasure reports the operation measured by l.
+func (a *Adapter) finishMeasure(ctx context.Context, l *opMeasure, rules int, err error) {
+	method := l.method
+	duration := time.Since(l.start)
+	a.endSpan(ctx, l, rules, err)
+	if a.metrics != nil {
+		a.metrics.record(ctx, a.tableName, method, rules, duration, err)
+	}
+	if a.slowThreshold > 0 && duration > a.slowThreshold {
+		a.logSlow(ctx, l, method, rules, duration)
+	}
+	if a.logger == nil {
+		return
+	}
+
+	const format = "casbin adapter: op=%s table=%s rules=%d duration=%s affected=%d"
+	if err != nil {
+		a.logger.Errorf(ctx, format+" error=%q", method, a.tableName, rules, duration, l.affected.Load(), err.Error())
+		return
+	}
+	a.logger.Debugf(ctx, format, method, a.tableName, rules, duration, l.affected.Load())
+}
+
+// logSlow logs the slow operation measured by l.
+func (a *Adapter) logSlow(ctx context.Context, l *opMeasure, method string, rules int, duration time.Duration) {
+	a.stats.slowOps.Add(1)
+	logger := a.warnLogger()
+	if logger == nil {
+		return
+	}
+
+	entry := fmt.Sprintf("casbin adapter: slow op=%s table=%s duration=%s threshold=%s rules=%d statements=%d",
+		method, a.tableName, duration, a.slowThreshold, rules, l.statements.Load())
+	if l.filter != nil {
+		entry += " filter=" + filtersSummary(l.filter)
+	}
+	logger.Warning(ctx, entry)
+}
+
+// warnLogger returns the logger of the warnings, the one of WithLogger or
+// else the logger of the database.
+func (a *Adapter) warnLogger() glog.ILogger {
+	if a.logger != nil {
+		return a.logger
+	}
+	return a.db.GetLogger()
+}
+
+// filtersSummary describes the filters of a load.
+func filtersSummary(filters []Filter) string {
+	parts := make([]string, len(filters))
+	for i, filter := range filters {
+		parts[i] = filterSummary(filter)
+	}
+	return strings.Join(parts, " OR ")
+}
+
+// filterSummary describes filter by its first values per column.
+func filterSummary(filter Filter) string {
+	const shown = 3
+	var parts []string
+	summarize := func(prefix string, lists [][]string) {
+		for i, values := range lists {
+			if len(values) == 0 {
+				continue
+			}
+			column := Columns.PType
+			if i > 0 {
+				column = fieldColumn(i - 1)
+			}
+			part := prefix + column + "=[" + strings.Join(values[:min(len(values), shown)], " ")
+			if len(values) > shown {
+				part += fmt.Sprintf(" +%d", len(values)-shown)
+			}
+			parts = append(parts, part+"]")
+		}
+	}
+	summarize("", [][]string{filter.PType, filter.V0, filter.V1, filter.V2, filter.V3, filter.V4, filter.V5})
+	summarize("!", [][]string{filter.NotPType, filter.NotV0, filter.NotV1, filter.NotV2, filter.NotV3, filter.NotV4, filter.NotV5})
+	summarize("^", append([][]string{nil}, filterPrefixes(filter)...))
+	if !filter.CreatedAfter.IsZero() || !filter.CreatedBefore.IsZero() {
+		parts = append(parts, fmt.Sprintf("created=[%s %s]", timeBound(filter.CreatedAfter), timeBound(filter.CreatedBefore)))
+	}
+	if filter.raw.Where != "" {
+		parts = append(parts, "where=["+filter.raw.Where+"]")
+	}
+	if len(filter.domains) > 0 {
+		parts = append(parts, "domain=["+strings.Join(filter.domains, " ")+"]")
+	}
+	if len(parts) == 0 {
+		return "none"
+	}
+	return strings.Join(parts, " ")
+}
+
+// timeBound describes a bound of a time window, "-" when it is open.
+func timeBound(t time.Time) string {
+	if t.IsZero() {
+		return "-"
+	}
+	return t.Format(time.RFC3339)
+}
+
+// addAffected counts n rows affected by the operation measured with ctx.
+func addAffected(ctx context.Context, n int64) {
+	if l, ok := ctx.Value(opMeasureKey{}).(*opMeasure); ok {
+		l.affected.Add(n)
+	}
+}
+
+// resetAffected forgets the rows affected so far, before a transaction is
+// run again.
+func resetAffected(ctx context.Context) {
+	if l, ok := ctx.Value(opMeasureKey{}).(*opMeasure); ok {
+		l.affected.Store(0)
+	}
+}
+
+// affectedRows returns the rows affected so far by the operation measured
+// with ctx.
+func affectedRows(ctx context.Context) int64 {
+	if l, ok := ctx.Value(opMeasureKey{}).(*opMeasure); ok {
+		return l.affected.Load()
+	}
+	return 0
+}
+
+// countAffected counts the statement and the rows affected by result.
+func countAffected(ctx context.Context, result sql.Result, err error) (sql.Result, error) {
+	if l, ok := ctx.Value(opMeasureKey{}).(*opMeasure); ok {
+		l.statements.Add(1)
+	}
+	if err == nil && result != nil {
+		if n, err := result.RowsAffected(); err == nil {
+			addAffected(ctx, n)
+		}
+	}
+	return result, err
+}
+
+// affectedHook counts the write statements of a model and the rows they
+// affect.
+var affectedHook = gdb.HookHandler{
+	Insert: func(ctx context.Context, in *gdb.HookInsertInput) (sql.Result, error) {
+		result, err := in.Next(ctx)
+		return countAffected(ctx, result, err)
+	},
+	Update: func(ctx context.Context, in *gdb.HookUpdateInput) (sql.Result, error) {
+		result, err := in.Next(ctx)
+		return countAffected(ctx, result, err)
+	},
+	Delete: func(ctx context.Context, in *gdb.HookDeleteInput) (sql.Result, error) {
+		result, err := in.Next(ctx)
+		return countAffected(ctx, result, err)
+	},
+}
+
+// opRuleCount returns the number of rules of op.
+func opRuleCount(op Operation) int {
+	if op.Model != nil {
+		return modelRuleCount(op.Model)
+	}
+	return len(op.Rules) + len(op.NewRules)
+}
+
+// modelRuleCount returns the number of policy rules of m.
+func modelRuleCount(m model.Model) int {
+	count := 0
+	for _, sec := range policySections {
+		for _, ast := range m[sec] {
+			count += len(ast.Policy)
+		}
+	}
+	return count
+}
@@ -0,0 +1,137 @@
+package adapter
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"regexp"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/casbin/casbin/v2"
+	"github.com/gogf/gf/v2/container/gvar"
+	"github.com/gogf/gf/v2/os/gcache"
+	"github.com/gogf/gf/v2/os/glog"
+)
+
+// newBufferLogger returns a logger writing every level without stack traces
+// into buf.
+func newBufferLogger(buf *bytes.Buffer) *glog.Logger {
+	logger := glog.New()
+	logger.SetWriter(buf)
+	logger.SetStdoutPrint(false)
+	logger.SetLevel(glog.LEVEL_ALL)
+	logger.SetStack(false)
+	return logger
+}
+
+func TestLogger(t *testing.T) {
+	var buf bytes.Buffer
+	a := newSqliteAdapter(t, WithLogger(newBufferLogger(&buf)))
+
+	if err := a.AddPolicies("p", "p", [][]string{{"alice", "data1", "read"}, {"bob", "data2", "write"}}); err != nil {
+		t.Fatalf("AddPolicies failed: %v", err)
+	}
+	line := buf.String()
+	want := regexp.MustCompile(`\[DEBU\] casbin adapter: op=AddPolicies table=casbin_rule rules=2 duration=\S+ affected=2\n$`)
+	if !want.MatchString(line) {
+		t.Errorf("logged %q, supposed to match %s", line, want)
+	}
+
+	e, _ := casbin.NewEnforcer("examples/rbac_model.conf", a)
+	buf.Reset()
+	if err := e.LoadPolicy(); err != nil {
+		t.Fatalf("LoadPolicy failed: %v", err)
+	}
+	if line := buf.String(); !strings.Contains(line, "op=LoadPolicy table=casbin_rule rules=2 ") {
+		t.Errorf("logged %q, supposed to log the load of 2 rules", line)
+	}
+
+	// A failing removal is logged with its error.
+	if _, err := a.db.Exec(context.Background(), fmt.Sprintf("DROP TABLE %s", a.tableName)); err != nil {
+		t.Fatalf("failed to drop table: %v", err)
+	}
+	buf.Reset()
+	if err := a.RemovePolicy("p", "p", []string{"alice", "data1", "read"}); err == nil {
+		t.Fatal("expected RemovePolicy to fail without the table")
+	}
+	line = buf.String()
+	want = regexp.MustCompile(`\[ERRO\] casbin adapter: op=RemovePolicy table=casbin_rule rules=1 duration=\S+ affected=0 error=".*no such table.*"\n$`)
+	if !want.MatchString(line) {
+		t.Errorf("logged %q, supposed to match %s", line, want)
+	}
+}
+
+func TestLoggerDisabled(t *testing.T) {
+	a := newSqliteAdapter(t)
+	ctx, m := a.startMeasure(context.Background(), "AddPolicy")
+	if m != nil || ctx.Value(opMeasureKey{}) != nil {
+		t.Error("an operation is measured without logger")
+	}
+}
+
+// sleepyHook delays every change.
+type sleepyHook struct {
+	delay time.Duration
+}
+
+func (h sleepyHook) BeforeWrite(ctx context.Context, op Operation) error {
+	time.Sleep(h.delay)
+	return nil
+}
+
+func (h sleepyHook) AfterWrite(ctx context.Context, op Operation, err error) {}
+
+// sleepyCache delays every read of the rule cache.
+type sleepyCache struct {
+	gcache.Adapter
+	delay time.Duration
+}
+
+func (c sleepyCache) Get(ctx context.Context, key interface{}) (*gvar.Var, error) {
+	time.Sleep(c.delay)
+	return c.Adapter.Get(ctx, key)
+}
+
+func TestSlowThreshold(t *testing.T) {
+	var buf bytes.Buffer
+	db := newSqliteDB(t)
+	db.SetLogger(newBufferLogger(&buf))
+	a, err := NewAdapter(context.Background(), "", "", db, WithSlowThreshold(20*time.Millisecond), WithCache(time.Minute))
+	if err != nil {
+		t.Fatalf("failed to create adapter: %v", err)
+	}
+
+	// Fast operations aren't logged.
+	if err := a.AddPolicies("p", "p", [][]string{{"alice", "data1", "read"}, {"bob", "data2", "write"}}); err != nil {
+		t.Fatalf("AddPolicies failed: %v", err)
+	}
+	if buf.Len() != 0 || a.SlowOperations() != 0 {
+		t.Errorf("logged %q for a fast operation", buf.String())
+	}
+
+	a.RegisterHook(sleepyHook{delay: 30 * time.Millisecond})
+	if err := a.RemovePolicies("p", "p", [][]string{{"alice", "data1", "read"}}); err != nil {
+		t.Fatalf("RemovePolicies failed: %v", err)
+	}
+	want := regexp.MustCompile(`\[WARN\] casbin adapter: slow op=RemovePolicies table=casbin_rule duration=\S+ threshold=20ms rules=1 statements=1\n$`)
+	if line := buf.String(); !want.MatchString(line) {
+		t.Errorf("logged %q, supposed to match %s", line, want)
+	}
+
+	e, _ := casbin.NewEnforcer("examples/rbac_model.conf", a)
+	buf.Reset()
+	a.cache.SetAdapter(sleepyCache{Adapter: gcache.NewAdapterMemory(), delay: 30 * time.Millisecond})
+	filter := Filter{PType: []string{"p"}, V0: []string{"alice", "bob", "carol", "dave"}}
+	if err := e.LoadFilteredPolicy(filter); err != nil {
+		t.Fatalf("LoadFilteredPolicy failed: %v", err)
+	}
+	want = regexp.MustCompile(`slow op=LoadFilteredPolicy .* rules=1 statements=0 filter=p_type=\[p\] v0=\[alice bob carol \+1\]\n$`)
+	if line := buf.String(); !want.MatchString(line) {
+		t.Errorf("logged %q, supposed to match %s", line, want)
+	}
+	if n := a.SlowOperations(); n != 2 {
+		t.Errorf("%d slow operations, supposed to be 2", n)
+	}
+}
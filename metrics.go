@@ -0,0 +1,101 @@
+package adapter
+
+import (
+	"context"
+	"math"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/gogf/gf/v2/os/gmetric"
+)
+
+// metricsInstrument names the gmetric meter of the adapter instruments.
+const metricsInstrument = "github.com/zcyc/gf-adapter/v2"
+
+// adapterMetrics holds the gmetric instruments shared by all the adapters,
+// which label their measurements with their table.
+type adapterMetrics struct {
+	operations gmetric.Counter
+	duration   gmetric.Histogram
+	loaded     gmetric.ObservableGauge
+
+	// rules holds the number of rules last loaded per table, as math.Float64bits.
+	rules sync.Map
+}
+
+var (
+	sharedMetrics     *adapterMetrics
+	sharedMetricsOnce sync.Once
+)
+
+// WithMetrics records the loads and changes of the stored rules with the
+// gmetric instruments casbin_adapter_operations_total, counting them by op
+// and status, casbin_adapter_operation_duration_seconds, their duration by
+// op, and casbin_adapter_rules_loaded, the number of rules last loaded. All
+// the measurements are labeled with the table. The instruments are created
+// once and shared by the adapters, they record nothing until a gmetric
+// provider is set.
+func WithMetrics() AdapterOption {
+	return AdapterOption{apply: func(a *Adapter) {
+		a.metrics = newAdapterMetrics()
+	}}
+}
+
+// newAdapterMetrics returns the shared instruments, creating them on the
+// first call.
+func newAdapterMetrics() *adapterMetrics {
+	sharedMetricsOnce.Do(func() {
+		m := &adapterMetrics{}
+		meter := gmetric.GetGlobalProvider().Meter(gmetric.MeterOption{Instrument: metricsInstrument})
+		m.operations = meter.MustCounter("casbin_adapter_operations_total", gmetric.MetricOption{
+			Help: "Number of the loads and changes of casbin rules.",
+		})
+		m.duration = meter.MustHistogram("casbin_adapter_operation_duration_seconds", gmetric.MetricOption{
+			Help: "Duration of the loads and changes of casbin rules.",
+			Unit: "s",
+		})
+		m.loaded = meter.MustObservableGauge("casbin_adapter_rules_loaded", gmetric.MetricOption{
+			Help:     "Number of casbin rules last loaded.",
+			Callback: m.observeLoaded,
+		})
+		sharedMetrics = m
+	})
+	return sharedMetrics
+}
+
+// observeLoaded observes the number of rules last loaded per table.
+func (m *adapterMetrics) observeLoaded(ctx context.Context, obs gmetric.MetricObserver) error {
+	m.rules.Range(func(table, rules any) bool {
+		obs.Observe(math.Float64frombits(rules.(*atomic.Uint64).Load()), tableAttributes(table.(string)))
+		return true
+	})
+	return nil
+}
+
+// record records an operation of table.
+func (m *adapterMetrics) record(ctx context.Context, table, method string, rules int, duration time.Duration, err error) {
+	status := "ok"
+	if err != nil {
+		status = "error"
+	}
+	m.operations.Inc(ctx, gmetric.Option{Attributes: gmetric.Attributes{
+		gmetric.NewAttribute("table", table),
+		gmetric.NewAttribute("op", method),
+		gmetric.NewAttribute("status", status),
+	}})
+	m.duration.Record(duration.Seconds(), gmetric.Option{Attributes: gmetric.Attributes{
+		gmetric.NewAttribute("table", table),
+		gmetric.NewAttribute("op", method),
+	}})
+
+	if err == nil && (method == "LoadPolicy" || method == "LoadFilteredPolicy") {
+		value, _ := m.rules.LoadOrStore(table, new(atomic.Uint64))
+		value.(*atomic.Uint64).Store(math.Float64bits(float64(rules)))
+	}
+}
+
+// tableAttributes returns the attributes labeling a measurement of table.
+func tableAttributes(table string) gmetric.Option {
+	return gmetric.Option{Attributes: gmetric.Attributes{gmetric.NewAttribute("table", table)}}
+}
@@ -0,0 +1,216 @@
+package adapter
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"sync"
+	"testing"
+
+	"github.com/casbin/casbin/v2"
+	"github.com/gogf/gf/v2/os/gmetric"
+)
+
+// testMetricProvider is a gmetric provider keeping the measurements in
+// memory, by metric name and attributes.
+type testMetricProvider struct {
+	mu        sync.Mutex
+	values    map[string]float64
+	callbacks map[string]gmetric.MetricCallback
+}
+
+var (
+	metricProvider     *testMetricProvider
+	metricProviderOnce sync.Once
+)
+
+// useMetricProvider sets the test provider as the global gmetric provider.
+func useMetricProvider() *testMetricProvider {
+	metricProviderOnce.Do(func() {
+		metricProvider = &testMetricProvider{
+			values:    make(map[string]float64),
+			callbacks: make(map[string]gmetric.MetricCallback),
+		}
+		metricProvider.SetAsGlobal()
+		for _, m := range gmetric.GetAllMetrics() {
+			if initializer, ok := m.(gmetric.MetricInitializer); ok {
+				_ = initializer.Init(metricProvider)
+			}
+		}
+	})
+	return metricProvider
+}
+
+// metricKey returns the key of the measurements of name with options.
+func metricKey(name string, options []gmetric.Option) string {
+	var attrs []string
+	for _, option := range options {
+		for _, attr := range option.Attributes {
+			attrs = append(attrs, fmt.Sprintf("%s=%v", attr.Key(), attr.Value()))
+		}
+	}
+	return name + "{" + strings.Join(attrs, ",") + "}"
+}
+
+func (p *testMetricProvider) add(name string, options []gmetric.Option, value float64) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	p.values[metricKey(name, options)] += value
+}
+
+// value returns the value of the metric name with attributes, after
+// running the callbacks of the observable metrics.
+func (p *testMetricProvider) value(name string, attrs ...string) float64 {
+	p.mu.Lock()
+	callbacks := make(map[string]gmetric.MetricCallback, len(p.callbacks))
+	for observed, callback := range p.callbacks {
+		callbacks[observed] = callback
+	}
+	p.mu.Unlock()
+	for observed, callback := range callbacks {
+		_ = callback(context.Background(), testObserver{provider: p, name: observed})
+	}
+
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	return p.values[name+"{"+strings.Join(attrs, ",")+"}"]
+}
+
+func (p *testMetricProvider) SetAsGlobal()                         { gmetric.SetGlobalProvider(p) }
+func (p *testMetricProvider) ForceFlush(ctx context.Context) error { return nil }
+func (p *testMetricProvider) Shutdown(ctx context.Context) error   { return nil }
+
+func (p *testMetricProvider) MeterPerformer(config gmetric.MeterOption) gmetric.MeterPerformer {
+	return p
+}
+
+func (p *testMetricProvider) CounterPerformer(name string, option gmetric.MetricOption) (gmetric.CounterPerformer, error) {
+	return testCounter{provider: p, name: name}, nil
+}
+
+func (p *testMetricProvider) UpDownCounterPerformer(name string, option gmetric.MetricOption) (gmetric.UpDownCounterPerformer, error) {
+	return testCounter{provider: p, name: name}, nil
+}
+
+func (p *testMetricProvider) HistogramPerformer(name string, option gmetric.MetricOption) (gmetric.HistogramPerformer, error) {
+	return testHistogram{provider: p, name: name}, nil
+}
+
+func (p *testMetricProvider) ObservableCounterPerformer(name string, option gmetric.MetricOption) (gmetric.ObservableCounterPerformer, error) {
+	return p.observable(name, option), nil
+}
+
+func (p *testMetricProvider) ObservableUpDownCounterPerformer(name string, option gmetric.MetricOption) (gmetric.ObservableUpDownCounterPerformer, error) {
+	return p.observable(name, option), nil
+}
+
+func (p *testMetricProvider) ObservableGaugePerformer(name string, option gmetric.MetricOption) (gmetric.ObservableGaugePerformer, error) {
+	return p.observable(name, option), nil
+}
+
+func (p *testMetricProvider) RegisterCallback(callback gmetric.Callback, canBeCallbackMetrics ...gmetric.ObservableMetric) error {
+	return nil
+}
+
+func (p *testMetricProvider) observable(name string, option gmetric.MetricOption) testObservable {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	if option.Callback != nil {
+		p.callbacks[name] = option.Callback
+	}
+	return testObservable{}
+}
+
+type testCounter struct {
+	provider *testMetricProvider
+	name     string
+}
+
+func (c testCounter) Inc(ctx context.Context, option ...gmetric.Option) {
+	c.provider.add(c.name, option, 1)
+}
+
+func (c testCounter) Dec(ctx context.Context, option ...gmetric.Option) {
+	c.provider.add(c.name, option, -1)
+}
+
+func (c testCounter) Add(ctx context.Context, increment float64, option ...gmetric.Option) {
+	c.provider.add(c.name, option, increment)
+}
+
+// testHistogram counts the recorded values.
+type testHistogram struct {
+	provider *testMetricProvider
+	name     string
+}
+
+func (h testHistogram) Record(increment float64, option ...gmetric.Option) {
+	h.provider.add(h.name+"_count", option, 1)
+}
+
+// testObservable is the performer of the observable metrics, whose values
+// are observed by the callbacks.
+type testObservable struct {
+	gmetric.ObservableMetric
+}
+
+// testObserver sets the observed values of the metric name.
+type testObserver struct {
+	provider *testMetricProvider
+	name     string
+}
+
+func (o testObserver) Observe(value float64, option ...gmetric.Option) {
+	o.provider.mu.Lock()
+	defer o.provider.mu.Unlock()
+	o.provider.values[metricKey(o.name, option)] = value
+}
+
+func TestMetrics(t *testing.T) {
+	provider := useMetricProvider()
+	const table = "metrics_rule"
+	a, err := NewAdapter(context.Background(), "", table, newSqliteDB(t), WithMetrics())
+	if err != nil {
+		t.Fatalf("failed to create adapter: %v", err)
+	}
+	// A second adapter shares the instruments.
+	other, err := NewAdapter(context.Background(), "", "other_metrics_rule", newSqliteDB(t), WithMetrics())
+	if err != nil {
+		t.Fatalf("failed to create adapter: %v", err)
+	}
+
+	operations := func(op, status string) float64 {
+		return provider.value("casbin_adapter_operations_total", "table="+table, "op="+op, "status="+status)
+	}
+	added, failed := operations("AddPolicy", "ok"), operations("AddPolicy", "error")
+	loads := provider.value("casbin_adapter_operation_duration_seconds_count", "table="+table, "op=LoadPolicy")
+
+	for _, user := range []string{"alice", "bob"} {
+		if err := a.AddPolicy("p", "p", []string{user, "data1", "read"}); err != nil {
+			t.Fatalf("AddPolicy failed: %v", err)
+		}
+	}
+	if err := other.AddPolicy("p", "p", []string{"carol", "data1", "read"}); err != nil {
+		t.Fatalf("AddPolicy failed: %v", err)
+	}
+	a.RegisterHook(&recordingHook{name: "deny", abort: "AddPolicy", events: new([]string)})
+	if err := a.AddPolicy("p", "p", []string{"dave", "data1", "read"}); err == nil {
+		t.Fatal("expected the hook to abort AddPolicy")
+	}
+	if _, err := casbin.NewEnforcer("examples/rbac_model.conf", a); err != nil {
+		t.Fatalf("NewEnforcer failed: %v", err)
+	}
+
+	if n := operations("AddPolicy", "ok") - added; n != 2 {
+		t.Errorf("%v successful additions counted, supposed to be 2", n)
+	}
+	if n := operations("AddPolicy", "error") - failed; n != 1 {
+		t.Errorf("%v failed additions counted, supposed to be 1", n)
+	}
+	if n := provider.value("casbin_adapter_operation_duration_seconds_count", "table="+table, "op=LoadPolicy") - loads; n != 1 {
+		t.Errorf("%v load durations recorded, supposed to be 1", n)
+	}
+	if n := provider.value("casbin_adapter_rules_loaded", "table="+table); n != 2 {
+		t.Errorf("%v rules loaded, supposed to be 2", n)
+	}
+}
@@ -0,0 +1,235 @@
+package adapter
+
+import (
+	"context"
+	"errors"
+	"fmt"
+
+	"github.com/gogf/gf/v2/database/gdb"
+)
+
+// ErrDestinationNotEmpty is returned by the migration helpers when the
+// policy table already holds rules and MigrateOptions.Force is not set.
+var ErrDestinationNotEmpty = errors.New("destination policy table is not empty, use MigrateOptions.Force to migrate anyway")
+
+// MigrateOptions configures the migration helpers.
+type MigrateOptions struct {
+	// Force migrates into a policy table that already holds rules. The
+	// stored rules are kept.
+	Force bool
+	// Deduplicate skips the rules that are repeated in the source table or
+	// already stored.
+	Deduplicate bool
+	// OnProgress is called with the number of source rows read so far every
+	// time another ProgressEvery rows are read, and once the migration
+	// completes. ProgressEvery defaults to the batch size.
+	OnProgress    func(rows int)
+	ProgressEvery int
+}
+
+// ColumnMapping names the columns of a source table holding the parts of a
+// rule. Empty value columns migrate as empty values.
+type ColumnMapping struct {
+	// ID is an optional column ordering the source rows, usually the
+	// primary key. Rows are read in pages by offset without it.
+	ID    string
+	PType string
+	V0    string
+	V1    string
+	V2    string
+	V3    string
+	V4    string
+	V5    string
+}
+
+// values returns the columns of the policy type and of v0 to v5.
+func (m ColumnMapping) values() []string {
+	return []string{m.PType, m.V0, m.V1, m.V2, m.V3, m.V4, m.V5}
+}
+
+// MigrateFromTable copies the rules of sourceTable into the policy table
+// and returns the number of migrated rules, for tables created by other
+// adapters such as xorm-adapter or by hand. mapping names the source
+// columns, which must exist, and NULL values are migrated as empty strings.
+// The migration runs in one transaction and refuses to write into a
+// non-empty policy table unless opts.Force is set. The source table is left
+// untouched.
+func (a *Adapter) MigrateFromTable(ctx context.Context, sourceTable string, mapping ColumnMapping, opts MigrateOptions) (int, error) {
+	if mapping.PType == "" {
+		return 0, errors.New("policy type column cannot be empty")
+	}
+
+	fields, err := a.db.TableFields(ctx, sourceTable)
+	if err != nil {
+		return 0, fmt.Errorf("failed to get columns of %s: %w", sourceTable, err)
+	}
+	for _, column := range append(mapping.values(), mapping.ID) {
+		if column == "" {
+			continue
+		}
+		if _, ok := fields[column]; !ok {
+			return 0, fmt.Errorf("table %s has no column %q", sourceTable, column)
+		}
+	}
+
+	return a.migrateHooked(ctx, "MigrateFromTable", sourceTable, mapping, opts)
+}
+
+// MigrateFromGormAdapter copies the rules of sourceTable, a table created by
+// casbin's gorm-adapter, into the policy table and returns the number of
+// migrated rules. The source table is read through the adapter's database
+// connection, its policy type column may be named "ptype" or "p_type", and
+// NULL values are migrated as empty strings. The migration runs in one
+// transaction and refuses to write into a non-empty policy table unless
+// opts.Force is set. The source table is left untouched.
+func (a *Adapter) MigrateFromGormAdapter(ctx context.Context, sourceTable string, opts ...MigrateOptions) (migrated int, err error) {
+	var opt MigrateOptions
+	if len(opts) > 0 {
+		opt = opts[0]
+	}
+
+	fields, err := a.db.TableFields(ctx, sourceTable)
+	if err != nil {
+		return 0, fmt.Errorf("failed to get columns of %s: %w", sourceTable, err)
+	}
+
+	mapping := ColumnMapping{ID: "id"}
+	for _, column := range []string{"ptype", Columns.PType} {
+		if _, ok := fields[column]; ok {
+			mapping.PType = column
+			break
+		}
+	}
+	if mapping.PType == "" {
+		return 0, fmt.Errorf("table %s has no ptype or p_type column", sourceTable)
+	}
+	if _, ok := fields[mapping.ID]; !ok {
+		return 0, fmt.Errorf("table %s has no id column", sourceTable)
+	}
+
+	values := []*string{&mapping.V0, &mapping.V1, &mapping.V2, &mapping.V3, &mapping.V4, &mapping.V5}
+	for i, value := range values {
+		if _, ok := fields[fieldColumn(i)]; ok {
+			*value = fieldColumn(i)
+		}
+	}
+
+	return a.migrateHooked(ctx, "MigrateFromGormAdapter", sourceTable, mapping, opt)
+}
+
+// migrateHooked migrates like migrate, calling the hooks around it.
+func (a *Adapter) migrateHooked(ctx context.Context, method, sourceTable string, mapping ColumnMapping, opts MigrateOptions) (migrated int, err error) {
+	err = a.mutate(ctx, Operation{Method: method}, func(ctx context.Context) (err error) {
+		migrated, err = a.migrate(ctx, sourceTable, mapping, opts)
+		return err
+	}, nil)
+	return migrated, err
+}
+
+// migrate copies the rows of sourceTable into the policy table. The source
+// rows are read in pages of the batch size, ordered by the ID column of
+// mapping when it is set.
+func (a *Adapter) migrate(ctx context.Context, sourceTable string, mapping ColumnMapping, opts MigrateOptions) (int, error) {
+	columns := mapping.values()
+	fields := make([]string, 0, len(columns)+1)
+	if mapping.ID != "" {
+		fields = append(fields, mapping.ID)
+	}
+	for _, column := range columns {
+		if column != "" {
+			fields = append(fields, column)
+		}
+	}
+
+	progressEvery := opts.ProgressEvery
+	if progressEvery <= 0 {
+		progressEvery = a.batchSize
+	}
+
+	migrated := 0
+	err := a.transaction(ctx, func(ctx context.Context, tx gdb.TX) error {
+		var seen map[ruleKey]struct{}
+		if opts.Deduplicate {
+			seen = make(map[ruleKey]struct{})
+		}
+
+		if !opts.Force || opts.Deduplicate {
+			err := a.scanPages(a.modelCtx(ctx), func(rows []ruleRow) error {
+				if !opts.Force {
+					return ErrDestinationNotEmpty
+				}
+				for _, row := range rows {
+					seen[row.key()] = struct{}{}
+				}
+				return nil
+			})
+			if err != nil {
+				return err
+			}
+		}
+
+		source := a.txModel(ctx, tx, sourceTable).Fields(fields)
+		var (
+			lastID   interface{}
+			read     int
+			reported int
+		)
+		for {
+			page := source.Limit(read, a.batchSize)
+			if mapping.ID != "" {
+				page = source.OrderAsc(mapping.ID).Limit(a.batchSize)
+				if lastID != nil {
+					page = page.WhereGT(mapping.ID, lastID)
+				}
+			}
+			records, err := page.All()
+			if err != nil {
+				return fmt.Errorf("failed to read %s: %w", sourceTable, err)
+			}
+
+			rules := make([]Rule, 0, len(records))
+			for _, record := range records {
+				values := make([]string, len(columns))
+				for i, column := range columns {
+					if column != "" {
+						values[i] = record[column].String()
+					}
+				}
+				rule := a.buildRule(values[0], values[1:])
+				if seen != nil {
+					if _, ok := seen[rule.key()]; ok {
+						continue
+					}
+					seen[rule.key()] = struct{}{}
+				}
+				rules = append(rules, rule)
+			}
+
+			if err := a.insertRules(ctx, rules); err != nil {
+				return err
+			}
+			migrated += len(rules)
+			read += len(records)
+
+			if opts.OnProgress != nil && read-reported >= progressEvery {
+				opts.OnProgress(read)
+				reported = read
+			}
+			if len(records) < a.batchSize {
+				break
+			}
+			if mapping.ID != "" {
+				lastID = records[len(records)-1][mapping.ID].Val()
+			}
+		}
+
+		if opts.OnProgress != nil && read != reported {
+			opts.OnProgress(read)
+		}
+		return nil
+	})
+	if err != nil {
+		return 0, fmt.Errorf("failed to migrate rules from %s: %w", sourceTable, err)
+	}
+	return migrated, nil
+}
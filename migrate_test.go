@@ -0,0 +1,182 @@
+package adapter
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"testing"
+
+	"github.com/casbin/casbin/v2"
+	"github.com/gogf/gf/v2/database/gdb"
+)
+
+// createGormTable creates a table laid out like the one of gorm-adapter,
+// with pTypeColumn as the policy type column, and fills it with the rules
+// of the example RBAC policy.
+func createGormTable(t *testing.T, db gdb.DB, table, pTypeColumn string) {
+	t.Helper()
+	ctx := context.Background()
+	stmts := []string{
+		fmt.Sprintf(`CREATE TABLE %s (
+			id INTEGER PRIMARY KEY AUTOINCREMENT,
+			%s varchar(100),
+			v0 varchar(100), v1 varchar(100), v2 varchar(100),
+			v3 varchar(100), v4 varchar(100), v5 varchar(100)
+		)`, table, pTypeColumn),
+		fmt.Sprintf(`INSERT INTO %s (%s, v0, v1, v2) VALUES
+			('p', 'alice', 'data1', 'read'),
+			('p', 'bob', 'data2', 'write'),
+			('p', 'data2_admin', 'data2', 'read'),
+			('p', 'data2_admin', 'data2', 'write'),
+			('p', 'alice', 'data1', 'read')`, table, pTypeColumn),
+		fmt.Sprintf(`INSERT INTO %s (%s, v0, v1) VALUES ('g', 'alice', 'data2_admin')`, table, pTypeColumn),
+	}
+	for _, stmt := range stmts {
+		if _, err := db.Exec(ctx, stmt); err != nil {
+			t.Fatalf("failed to create legacy table: %v", err)
+		}
+	}
+}
+
+// testSameDecisions checks that e decides like the example RBAC policy file.
+func testSameDecisions(t *testing.T, e *casbin.Enforcer) {
+	t.Helper()
+	want, err := casbin.NewEnforcer("examples/rbac_model.conf", "examples/rbac_policy.csv")
+	if err != nil {
+		t.Fatalf("failed to load policy file: %v", err)
+	}
+	for _, sub := range []string{"alice", "bob", "data2_admin", "carol"} {
+		for _, obj := range []string{"data1", "data2"} {
+			for _, act := range []string{"read", "write"} {
+				got, _ := e.Enforce(sub, obj, act)
+				exp, _ := want.Enforce(sub, obj, act)
+				if got != exp {
+					t.Errorf("Enforce(%s, %s, %s) = %v, supposed to be %v", sub, obj, act, got, exp)
+				}
+			}
+		}
+	}
+}
+
+func TestMigrateFromGormAdapter(t *testing.T) {
+	for _, column := range []string{"ptype", "p_type"} {
+		t.Run(column, func(t *testing.T) {
+			a := newSqliteAdapter(t, AdapterOption{BatchSize: 2})
+			createGormTable(t, a.db, "gorm_casbin_rule", column)
+
+			migrated, err := a.MigrateFromGormAdapter(context.Background(), "gorm_casbin_rule", MigrateOptions{Deduplicate: true})
+			if err != nil {
+				t.Fatalf("MigrateFromGormAdapter failed: %v", err)
+			}
+			if migrated != 5 {
+				t.Errorf("migrated %d rules, supposed to be 5", migrated)
+			}
+
+			e, err := casbin.NewEnforcer("examples/rbac_model.conf", a)
+			if err != nil {
+				t.Fatalf("NewEnforcer failed: %v", err)
+			}
+			testGetPolicy(t, e, [][]string{{"alice", "data1", "read"}, {"bob", "data2", "write"}, {"data2_admin", "data2", "read"}, {"data2_admin", "data2", "write"}})
+			testSameDecisions(t, e)
+		})
+	}
+}
+
+func TestMigrateFromGormAdapterNonEmpty(t *testing.T) {
+	a := newSqliteAdapter(t)
+	createGormTable(t, a.db, "gorm_casbin_rule", "ptype")
+	if err := a.AddPolicy("p", "p", []string{"alice", "data1", "read"}); err != nil {
+		t.Fatalf("AddPolicy failed: %v", err)
+	}
+
+	if _, err := a.MigrateFromGormAdapter(context.Background(), "gorm_casbin_rule"); !errors.Is(err, ErrDestinationNotEmpty) {
+		t.Fatalf("expected ErrDestinationNotEmpty, got %v", err)
+	}
+
+	// Forcing keeps the stored rules and skips those already stored.
+	migrated, err := a.MigrateFromGormAdapter(context.Background(), "gorm_casbin_rule", MigrateOptions{Force: true, Deduplicate: true})
+	if err != nil {
+		t.Fatalf("MigrateFromGormAdapter failed: %v", err)
+	}
+	if migrated != 4 {
+		t.Errorf("migrated %d rules, supposed to be 4", migrated)
+	}
+
+	e, _ := casbin.NewEnforcer("examples/rbac_model.conf", a)
+	testGetPolicy(t, e, [][]string{{"alice", "data1", "read"}, {"bob", "data2", "write"}, {"data2_admin", "data2", "read"}, {"data2_admin", "data2", "write"}})
+	testSameDecisions(t, e)
+}
+
+func TestMigrateFromGormAdapterInvalidTable(t *testing.T) {
+	a := newSqliteAdapter(t)
+	if _, err := a.db.Exec(context.Background(), "CREATE TABLE other (id INTEGER PRIMARY KEY, name varchar(10))"); err != nil {
+		t.Fatalf("failed to create table: %v", err)
+	}
+	if _, err := a.MigrateFromGormAdapter(context.Background(), "other"); err == nil {
+		t.Error("expected an error for a table without a policy type column")
+	}
+}
+
+func TestMigrateFromTable(t *testing.T) {
+	a := newSqliteAdapter(t, AdapterOption{BatchSize: 2})
+	ctx := context.Background()
+	stmts := []string{
+		`CREATE TABLE acl_entries (
+			entry_id INTEGER PRIMARY KEY,
+			kind varchar(10),
+			subject varchar(100),
+			resource varchar(100),
+			verb varchar(100)
+		)`,
+		`INSERT INTO acl_entries (entry_id, kind, subject, resource, verb) VALUES
+			(10, 'p', 'alice', 'data1', 'read'),
+			(20, 'p', 'bob', 'data2', 'write'),
+			(30, 'p', 'data2_admin', 'data2', 'read'),
+			(40, 'p', 'data2_admin', 'data2', 'write'),
+			(50, 'g', 'alice', 'data2_admin', NULL)`,
+	}
+	for _, stmt := range stmts {
+		if _, err := a.db.Exec(ctx, stmt); err != nil {
+			t.Fatalf("failed to create source table: %v", err)
+		}
+	}
+
+	mapping := ColumnMapping{ID: "entry_id", PType: "kind", V0: "subject", V1: "resource", V2: "verb"}
+	var progress []int
+	migrated, err := a.MigrateFromTable(ctx, "acl_entries", mapping, MigrateOptions{
+		ProgressEvery: 2,
+		OnProgress:    func(rows int) { progress = append(progress, rows) },
+	})
+	if err != nil {
+		t.Fatalf("MigrateFromTable failed: %v", err)
+	}
+	if migrated != 5 {
+		t.Errorf("migrated %d rules, supposed to be 5", migrated)
+	}
+	if fmt.Sprint(progress) != "[2 4 5]" {
+		t.Errorf("progress %v, supposed to be [2 4 5]", progress)
+	}
+
+	e, err := casbin.NewEnforcer("examples/rbac_model.conf", a)
+	if err != nil {
+		t.Fatalf("NewEnforcer failed: %v", err)
+	}
+	testGetPolicy(t, e, [][]string{{"alice", "data1", "read"}, {"bob", "data2", "write"}, {"data2_admin", "data2", "read"}, {"data2_admin", "data2", "write"}})
+	testSameDecisions(t, e)
+
+	// Without an id column the rows are paged by offset.
+	dst := newSqliteAdapter(t, AdapterOption{BatchSize: 2})
+	if _, err := dst.db.Exec(ctx, "CREATE TABLE acl_entries AS SELECT kind, subject, resource, verb FROM (SELECT 'p' AS kind, 'alice' AS subject, 'data1' AS resource, 'read' AS verb UNION ALL SELECT 'p', 'bob', 'data2', 'write' UNION ALL SELECT 'g', 'alice', 'data2_admin', NULL)"); err != nil {
+		t.Fatalf("failed to create source table: %v", err)
+	}
+	mapping.ID = ""
+	if migrated, err := dst.MigrateFromTable(ctx, "acl_entries", mapping, MigrateOptions{}); err != nil || migrated != 3 {
+		t.Errorf("migrated %d rules, err: %v, supposed to be 3", migrated, err)
+	}
+
+	// Unknown columns are rejected before anything is read.
+	mapping.V3 = "missing"
+	if _, err := a.MigrateFromTable(ctx, "acl_entries", mapping, MigrateOptions{Force: true}); err == nil {
+		t.Error("expected an error for an unknown column")
+	}
+}
@@ -0,0 +1,64 @@
+package adapter
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/gogf/gf/v2/database/gdb"
+)
+
+func TestModel(t *testing.T) {
+	base := newSqliteAdapter(t)
+	db, err := gdb.New(gdb.ConfigNode{
+		Type:   "sqlite",
+		Name:   base.db.GetConfig().Name,
+		Prefix: "app_",
+	})
+	if err != nil {
+		t.Fatalf("failed to create database connection: %v", err)
+	}
+	defer db.Close(context.Background())
+	a, err := NewAdapter(context.Background(), "", "", db, WithTenantColumn("tenant_id"))
+	if err != nil {
+		t.Fatalf("NewAdapter failed: %v", err)
+	}
+	if a.DB() != db {
+		t.Error("DB should return the database of the adapter")
+	}
+	if err := a.ForTenant("t1").AddPolicy("p", "p", []string{"alice", "data1", "read"}); err != nil {
+		t.Fatalf("AddPolicy failed: %v", err)
+	}
+	if err := base.AddPolicy("p", "p", []string{"bob", "data2", "write"}); err != nil {
+		t.Fatalf("AddPolicy failed: %v", err)
+	}
+
+	// The model targets the prefixed table, of every tenant.
+	ctx := context.Background()
+	values, err := a.ForTenant("t2").Model(ctx).Fields("v0").Array()
+	if err != nil {
+		t.Fatalf("failed to query the model: %v", err)
+	}
+	if len(values) != 1 || values[0].String() != "alice" {
+		t.Errorf("values %v, supposed to be the rule of app_casbin_rule", values)
+	}
+
+	// Inside a transaction the model joins it.
+	errRollback := errors.New("rollback")
+	err = db.Transaction(ctx, func(ctx context.Context, tx gdb.TX) error {
+		if _, err := a.WithTx(tx).Model(ctx).Where("v0", "alice").Delete(); err != nil {
+			return err
+		}
+		n, err := a.WithTx(tx).Model(ctx).Count()
+		if err != nil || n != 0 {
+			t.Errorf("count %d, err: %v, supposed to see the delete of the transaction", n, err)
+		}
+		return errRollback
+	})
+	if err != errRollback {
+		t.Fatalf("transaction err: %v", err)
+	}
+	if n, err := a.Model(ctx).Count(); err != nil || n != 1 {
+		t.Errorf("count %d, err: %v, supposed to keep the rule after the rollback", n, err)
+	}
+}
@@ -0,0 +1,282 @@
+package adapter
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+)
+
+const (
+	mssqlCreateTableSql = `
+IF OBJECT_ID(N'%s', N'U') IS NULL
+CREATE TABLE %s (
+  id bigint IDENTITY(1,1) NOT NULL PRIMARY KEY,
+  p_type nvarchar(10) NULL,
+  v0 nvarchar(256) NULL,
+  v1 nvarchar(256) NULL,
+  v2 nvarchar(256) NULL,
+  v3 nvarchar(256) NULL,
+  v4 nvarchar(256) NULL,
+  v5 nvarchar(256) NULL,
+%s  created_at datetime2 NULL DEFAULT SYSUTCDATETIME()
+)
+`
+	mssqlCreateHistoryTableSql = `
+IF OBJECT_ID(N'%s', N'U') IS NULL
+CREATE TABLE %s (
+  id bigint IDENTITY(1,1) NOT NULL PRIMARY KEY,
+  op nvarchar(10) NOT NULL,
+  p_type nvarchar(10) NULL,
+  v0 nvarchar(256) NULL,
+  v1 nvarchar(256) NULL,
+  v2 nvarchar(256) NULL,
+  v3 nvarchar(256) NULL,
+  v4 nvarchar(256) NULL,
+  v5 nvarchar(256) NULL,
+%s  changed_at datetime2 NOT NULL
+)
+`
+	mssqlCreateStagingTableSql = "IF OBJECT_ID(N'%s', N'U') IS NULL CREATE TABLE %s (\n  id bigint IDENTITY(1,1) NOT NULL PRIMARY KEY,\n  %s\n)"
+	mssqlTenantColumnSql       = "  %s nvarchar(64) NOT NULL DEFAULT '',\n"
+	mssqlActorColumnSql        = "%s nvarchar(255) NOT NULL DEFAULT ''"
+	mssqlTypedColumnSql        = "%s %s NULL"
+	mssqlCreateIndexSql        = `IF NOT EXISTS (SELECT 1 FROM sys.indexes WHERE name = N'%s' AND object_id = OBJECT_ID(N'%s')) CREATE %sINDEX %s ON %s (%s)`
+	mssqlAddColumnSql          = `ALTER TABLE %s ADD %s`
+	mssqlTruncateTableSql      = `TRUNCATE TABLE %s`
+	mssqlCreateLikeSql         = `SELECT * INTO %s FROM %s WHERE 1 = 0`
+	mssqlRenameTableSql        = `EXEC sp_rename N'%s', N'%s'`
+	mssqlVersionTableSql       = `IF OBJECT_ID(N'%s', N'U') IS NULL CREATE TABLE %s (id int NOT NULL PRIMARY KEY, version bigint NOT NULL DEFAULT 0)`
+	mssqlRuleColumnSql         = `IF OBJECT_ID(N'%s', N'U') IS NULL CREATE TABLE %s (id bigint IDENTITY(1,1) NOT NULL PRIMARY KEY, %s nvarchar(max) NULL, created_at datetime2 NULL DEFAULT SYSUTCDATETIME())`
+	mssqlJSONTableSql          = `IF OBJECT_ID(N'%s', N'U') IS NULL CREATE TABLE %s (id bigint IDENTITY(1,1) NOT NULL PRIMARY KEY, p_type nvarchar(32) NOT NULL DEFAULT '', vals nvarchar(max) NULL CHECK (ISJSON(vals) = 1), created_at datetime2 NULL DEFAULT SYSUTCDATETIME())`
+
+	// mssqlLockSql and mssqlUnlockSql take and release an application lock
+	// of the session, sp_getapplock returns 0 or 1 once the lock is taken.
+	mssqlLockSql = `DECLARE @result int;
+EXEC @result = sp_getapplock @Resource = @p1, @LockMode = 'Exclusive', @LockOwner = 'Session', @LockTimeout = @p2 * 1000;
+SELECT CASE WHEN @result >= 0 THEN 1 ELSE 0 END`
+	mssqlUnlockSql = `EXEC sp_releaseapplock @Resource = @p1, @LockOwner = 'Session';
+SELECT 1`
+
+	// mssqlReadPastHint skips the rows locked by writers.
+	mssqlReadPastHint = "WITH (READPAST)"
+	// mssqlOutputIdsSql selects the ids of the inserted rows.
+	mssqlOutputIdsSql = "OUTPUT INSERTED.id"
+
+	// mssqlMaxParams is the limit of the parameters of a statement.
+	mssqlMaxParams = 2100
+)
+
+// mssqlColumnSql defines the columns of the create statements, they are
+// used to add missing columns to existing tables.
+var mssqlColumnSql = map[string]string{
+	"p_type":     "p_type nvarchar(10) NULL",
+	"v0":         "v0 nvarchar(256) NULL",
+	"v1":         "v1 nvarchar(256) NULL",
+	"v2":         "v2 nvarchar(256) NULL",
+	"v3":         "v3 nvarchar(256) NULL",
+	"v4":         "v4 nvarchar(256) NULL",
+	"v5":         "v5 nvarchar(256) NULL",
+	"created_at": "created_at datetime2 NULL DEFAULT SYSUTCDATETIME()",
+	"updated_at": "updated_at datetime2 NULL",
+	"deleted_at": "deleted_at datetime2 NULL",
+	"rule_hash":  "rule_hash char(64) NULL",
+}
+
+// mssqlDialect generates the statements of SQL Server 2016 or later. The
+// gdb driver turns the LIMIT of the pages into OFFSET and FETCH itself.
+type mssqlDialect struct{}
+
+// createTableSql skips the comments of schema, SQL Server keeps them as
+// extended properties.
+func (d mssqlDialect) createTableSql(table string, schema tableSchema) []string {
+	var columns string
+	if schema.ruleHash {
+		columns = "  " + mssqlColumnSql[ruleHashColumn] + ",\n"
+	}
+	return d.withTenant(mssqlCreateTableSql, table, columns, schema)
+}
+
+func (d mssqlDialect) createHistoryTableSql(table string, schema tableSchema) []string {
+	statements := d.withTenant(mssqlCreateHistoryTableSql, table, "", schema)
+	return append(statements, mssqlIndexSql(table, "changed_at", false))
+}
+
+func (mssqlDialect) createVersionTableSql(table string) string {
+	return fmt.Sprintf(mssqlVersionTableSql, table, table)
+}
+
+func (mssqlDialect) createRuleColumnTableSql(table, column string) string {
+	return fmt.Sprintf(mssqlRuleColumnSql, table, table, column)
+}
+
+// createJSONTableSql stores the arrays in nvarchar(max), SQL Server has no
+// JSON type before 2025.
+func (mssqlDialect) createJSONTableSql(table string) []string {
+	return []string{fmt.Sprintf(mssqlJSONTableSql, table, table)}
+}
+
+func (mssqlDialect) jsonValueSql(column string, index int) string {
+	return fmt.Sprintf("JSON_VALUE(%s, '$[%d]')", column, index)
+}
+
+func (mssqlDialect) jsonEqualSql(column string) string {
+	return column + " = ?"
+}
+
+// withTenant renders the create statement with the optional columns, the
+// index of the tenant column is created by a separate statement.
+func (mssqlDialect) withTenant(createSql, table, columns string, schema tableSchema) []string {
+	if schema.tenantColumn == "" {
+		return []string{withColumnTypes(fmt.Sprintf(createSql, table, table, columns), schema, mssqlTypedColumnSql)}
+	}
+	columns = fmt.Sprintf(mssqlTenantColumnSql, schema.tenantColumn) + columns
+	return []string{
+		withColumnTypes(fmt.Sprintf(createSql, table, table, columns), schema, mssqlTypedColumnSql),
+		mssqlIndexSql(table, schema.tenantColumn, false),
+	}
+}
+
+// mssqlIndexSql returns the statement creating the index of table over
+// columns when it doesn't exist.
+func mssqlIndexSql(table, columns string, unique bool) string {
+	name := fmt.Sprintf("idx_%s_%s", table, strings.ReplaceAll(columns, ", ", "_"))
+	var kind string
+	if unique {
+		name, kind = fmt.Sprintf("uniq_%s_rule", table), "UNIQUE "
+	}
+	return fmt.Sprintf(mssqlCreateIndexSql, name, table, kind, name, table, columns)
+}
+
+func (mssqlDialect) truncateTableSql(table string) string {
+	return fmt.Sprintf(mssqlTruncateTableSql, table)
+}
+
+func (mssqlDialect) transactionalTruncate() bool {
+	return true
+}
+
+func (mssqlDialect) nullSafeEqualSql(left, right string) string {
+	return fmt.Sprintf(orNullEqualSql, left, right, left, right)
+}
+
+// createStagingTableSql creates a regular table, the temporary tables of
+// SQL Server need a name starting with #. It is created and dropped in the
+// transaction of the save, which holds it.
+func (mssqlDialect) createStagingTableSql(table string, schema tableSchema) string {
+	return fmt.Sprintf(mssqlCreateStagingTableSql, table, table, stagingColumnsSql(mssqlColumnSql, schema, mssqlTypedColumnSql))
+}
+
+func (mssqlDialect) dropStagingTableSql(table string) string {
+	return fmt.Sprintf(dropTableSql, table)
+}
+
+func (mssqlDialect) createTableLikeSql(table, like string, schema tableSchema) []string {
+	return []string{fmt.Sprintf(mssqlCreateLikeSql, table, like)}
+}
+
+// swapTablesSql renames the tables one by one, the transaction makes the
+// swap atomic.
+func (mssqlDialect) swapTablesSql(table, staging, old string) []string {
+	return []string{
+		fmt.Sprintf(mssqlRenameTableSql, table, old),
+		fmt.Sprintf(mssqlRenameTableSql, staging, table),
+	}
+}
+
+func (mssqlDialect) createUniqueIndexSql(table string, columns []string) string {
+	return mssqlIndexSql(table, strings.Join(columns, ", "), true)
+}
+
+func (mssqlDialect) maxWriters() int {
+	return 0
+}
+
+func (mssqlDialect) maxGroupedConditions() int {
+	return 0
+}
+
+func (mssqlDialect) lockSql() (lock, unlock string) {
+	return mssqlLockSql, mssqlUnlockSql
+}
+
+// addPartitionSql and truncatePartitionSql are empty, the partitions of SQL
+// Server are laid out by partition functions the adapter doesn't manage.
+func (mssqlDialect) addPartitionSql(table, partition, tenant string) string {
+	return ""
+}
+
+func (mssqlDialect) truncatePartitionSql(table, partition string) string {
+	return ""
+}
+
+func (mssqlDialect) optimizeTableSql(table string) string {
+	return ""
+}
+
+// insertedIds is nil, the driver doesn't tell the last insert id.
+func (mssqlDialect) insertedIds(last int64, n int) []int64 {
+	return nil
+}
+
+// returningSql outputs the ids, the driver doesn't tell the last insert
+// id.
+func (mssqlDialect) returningSql() (output, returning string) {
+	return mssqlOutputIdsSql, ""
+}
+
+func (mssqlDialect) readPastHint() string {
+	return mssqlReadPastHint
+}
+
+func (mssqlDialect) maxInsertRows() int {
+	return 0
+}
+
+func (mssqlDialect) maxParams() int {
+	return mssqlMaxParams
+}
+
+// resolvesConflicts is false, the driver has neither INSERT IGNORE nor
+// REPLACE.
+func (mssqlDialect) resolvesConflicts() bool {
+	return false
+}
+
+func (mssqlDialect) conflictSql(policy ConflictPolicy, unique, columns []string) string {
+	return ""
+}
+
+func (mssqlDialect) addColumnSql(table, column string, schema tableSchema) []string {
+	if column == schema.tenantColumn {
+		definition := strings.TrimSuffix(strings.TrimSpace(fmt.Sprintf(mssqlTenantColumnSql, column)), ",")
+		return []string{
+			fmt.Sprintf(mssqlAddColumnSql, table, definition),
+			mssqlIndexSql(table, column, false),
+		}
+	}
+	if column == schema.actorColumn {
+		return []string{fmt.Sprintf(mssqlAddColumnSql, table, fmt.Sprintf(mssqlActorColumnSql, column))}
+	}
+	statements := []string{fmt.Sprintf(mssqlAddColumnSql, table, mssqlColumnSql[column])}
+	if indexedColumns[column] {
+		statements = append(statements, mssqlIndexSql(table, column, false))
+	}
+	return statements
+}
+
+// WithReadPast makes the loads of the policy skip the rows locked by the
+// writers on SQL Server, with the READPAST table hint, instead of waiting
+// for them. A load may then miss the rules being changed at the time.
+func WithReadPast() AdapterOption {
+	return AdapterOption{apply: func(a *Adapter) {
+		a.loadHint = mssqlReadPastHint
+	}}
+}
+
+// validateReadPast checks that the database supports WithReadPast.
+func (a *Adapter) validateReadPast() error {
+	if a.loadHint != "" && a.dialect.readPastHint() == "" {
+		return errors.New("READPAST loads are only supported on SQL Server")
+	}
+	return nil
+}
@@ -0,0 +1,50 @@
+//go:build mssql
+
+package adapter
+
+import (
+	"context"
+	"testing"
+
+	"github.com/gogf/gf/v2/database/gdb"
+)
+
+// TestMSSQLAdapters needs a SQL Server, it runs with go test -tags mssql.
+func TestMSSQLAdapters(t *testing.T) {
+	db, err := gdb.New(gdb.ConfigNode{
+		Type:  "mssql",
+		Host:  "127.0.0.1",
+		Port:  "1433",
+		User:  "sa",
+		Pass:  "Casbin_Rule1",
+		Name:  "casbin",
+		Extra: "encrypt=disable",
+		Debug: true,
+	})
+	if err != nil {
+		t.Fatalf("failed to create database connection: %v", err)
+	}
+
+	a, err := NewAdapter(context.Background(), "", "", db, WithReadPast())
+	if err != nil {
+		t.Fatalf("failed to create adapter: %v", err)
+	}
+
+	runAdapterSuite(t, a)
+	t.Run("ConcurrentCreate", func(t *testing.T) {
+		testConcurrentCreate(t, db)
+	})
+	t.Run("ConcurrentSaves", func(t *testing.T) {
+		testConcurrentSaves(t, db)
+	})
+	t.Run("ReturningIDs", func(t *testing.T) {
+		returning, err := NewAdapter(context.Background(), "", "casbin_rule_ids", db, WithBatchSize(2))
+		if err != nil {
+			t.Fatalf("failed to create adapter: %v", err)
+		}
+		if err := returning.truncateTable(context.Background()); err != nil {
+			t.Fatalf("failed to truncate table: %v", err)
+		}
+		testReturningIDs(t, returning)
+	})
+}
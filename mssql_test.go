@@ -0,0 +1,67 @@
+package adapter
+
+import (
+	"context"
+	"strings"
+	"testing"
+
+	"github.com/casbin/casbin/v2"
+)
+
+func TestMSSQLDialect(t *testing.T) {
+	d := mssqlDialect{}
+	schema := tableSchema{tenantColumn: "tenant_id"}
+	statements := d.createTableSql("casbin_rule", schema)
+	if len(statements) != 2 {
+		t.Fatalf("create statements %q, supposed to be the table and the tenant index", statements)
+	}
+	for _, want := range []string{
+		"IF OBJECT_ID(N'casbin_rule', N'U') IS NULL\nCREATE TABLE casbin_rule (\n  id bigint IDENTITY(1,1) NOT NULL PRIMARY KEY,",
+		"  tenant_id nvarchar(64) NOT NULL DEFAULT '',\n  created_at datetime2",
+	} {
+		if !strings.Contains(statements[0], want) {
+			t.Errorf("create statement lacks %q:\n%s", want, statements[0])
+		}
+	}
+	want := "IF NOT EXISTS (SELECT 1 FROM sys.indexes WHERE name = N'idx_casbin_rule_tenant_id' AND object_id = OBJECT_ID(N'casbin_rule')) CREATE INDEX idx_casbin_rule_tenant_id ON casbin_rule (tenant_id)"
+	if statements[1] != want {
+		t.Errorf("tenant index statement %q, supposed to be %q", statements[1], want)
+	}
+	if sql := d.addColumnSql("casbin_rule", "tenant_id", schema)[0]; sql != "ALTER TABLE casbin_rule ADD tenant_id nvarchar(64) NOT NULL DEFAULT ''" {
+		t.Errorf("add tenant statement %q", sql)
+	}
+	if sql := d.createUniqueIndexSql("casbin_rule", []string{"p_type", "v0"}); !strings.HasSuffix(sql, "CREATE UNIQUE INDEX uniq_casbin_rule_rule ON casbin_rule (p_type, v0)") {
+		t.Errorf("unique index statement %q", sql)
+	}
+	if lock, unlock := d.lockSql(); !strings.Contains(lock, "sp_getapplock @Resource = @p1") || !strings.Contains(unlock, "sp_releaseapplock") {
+		t.Errorf("lock statements %q and %q, supposed to use application locks", lock, unlock)
+	}
+}
+
+// TestReadPast runs the hint of the loads against sqlite with an index hint
+// of sqlite, which fails the loads as the index doesn't exist.
+func TestReadPast(t *testing.T) {
+	if _, err := NewAdapter(context.Background(), "", "", newSqliteDB(t), WithReadPast()); err == nil {
+		t.Error("expected an error using READPAST on sqlite")
+	}
+
+	a := newSqliteAdapter(t)
+	e, err := casbin.NewEnforcer("examples/rbac_model.conf", a)
+	if err != nil {
+		t.Fatalf("NewEnforcer failed: %v", err)
+	}
+	a.loadHint = "INDEXED BY no_such_index"
+	if err := e.LoadPolicy(); err == nil || !strings.Contains(err.Error(), "no_such_index") {
+		t.Errorf("load err: %v, supposed to name the hinted index", err)
+	}
+
+	// The hint only applies to the loads.
+	if err := a.AddPolicy("p", "p", []string{"alice", "data1", "read"}); err != nil {
+		t.Fatalf("AddPolicy failed: %v", err)
+	}
+	a.loadHint = "NOT INDEXED"
+	if err := e.LoadPolicy(); err != nil {
+		t.Fatalf("LoadPolicy failed: %v", err)
+	}
+	testGetPolicy(t, e, [][]string{{"alice", "data1", "read"}})
+}
@@ -0,0 +1,102 @@
+package adapter
+
+import (
+	"slices"
+	"strings"
+)
+
+// NormalizeOptions selects the rule fields whose values are normalized, by
+// their index: 0 is v0 and 5 is v5.
+type NormalizeOptions struct {
+	// TrimSpace lists the fields whose values are trimmed of surrounding
+	// white space.
+	TrimSpace []int
+	// LowerCase lists the fields whose values are lowercased.
+	LowerCase []int
+}
+
+// WithNormalization normalizes the values of the rules written through the
+// adapter according to opts, as well as the values of the filters and of
+// the rules removed or updated, so that they find the stored rules. The
+// rules already stored aren't changed.
+func WithNormalization(opts NormalizeOptions) AdapterOption {
+	return AdapterOption{apply: func(a *Adapter) {
+		a.normalization = &opts
+	}}
+}
+
+// normalized reports whether the values are normalized or hashed.
+func (a *Adapter) normalized() bool {
+	return a.normalization != nil || a.hasher != nil
+}
+
+// normalizeValue normalizes value of the field index, and hashes it when
+// the field is hashed.
+func (a *Adapter) normalizeValue(index int, value string) string {
+	if a.normalization != nil {
+		if slices.Contains(a.normalization.TrimSpace, index) {
+			value = strings.TrimSpace(value)
+		}
+		if slices.Contains(a.normalization.LowerCase, index) {
+			value = strings.ToLower(value)
+		}
+	}
+	if a.hasher != nil && value != "" && slices.Contains(a.hashedFields, index) {
+		value = a.hasher(value)
+	}
+	return value
+}
+
+// normalizeValues returns values normalized as the values of the field
+// index.
+func (a *Adapter) normalizeValues(index int, values []string) []string {
+	if !a.normalized() {
+		return values
+	}
+	normalized := make([]string, len(values))
+	for i, value := range values {
+		normalized[i] = a.normalizeValue(index, value)
+	}
+	return normalized
+}
+
+// normalizeRule normalizes the values of rule.
+func (a *Adapter) normalizeRule(rule Rule) Rule {
+	if !a.normalized() {
+		return rule
+	}
+	for i, value := range []*string{&rule.V0, &rule.V1, &rule.V2, &rule.V3, &rule.V4, &rule.V5} {
+		*value = a.normalizeValue(i, *value)
+	}
+	return rule
+}
+
+// normalizeFilter normalizes the values of filter.
+func (a *Adapter) normalizeFilter(filter Filter) Filter {
+	if !a.normalized() {
+		return filter
+	}
+	for i, values := range []*[]string{&filter.V0, &filter.V1, &filter.V2, &filter.V3, &filter.V4, &filter.V5} {
+		if len(*values) > 0 {
+			*values = a.normalizeValues(i, *values)
+		}
+	}
+	for i, values := range []*[]string{&filter.NotV0, &filter.NotV1, &filter.NotV2, &filter.NotV3, &filter.NotV4, &filter.NotV5} {
+		if len(*values) > 0 {
+			*values = a.normalizeValues(i, *values)
+		}
+	}
+	// The prefixes are only lowercased, trimming would change the prefix.
+	if a.normalization != nil {
+		for i, prefixes := range []*[]string{&filter.V0Prefix, &filter.V1Prefix, &filter.V2Prefix, &filter.V3Prefix, &filter.V4Prefix, &filter.V5Prefix} {
+			if len(*prefixes) > 0 && slices.Contains(a.normalization.LowerCase, i) {
+				lowered := make([]string, len(*prefixes))
+				for j, prefix := range *prefixes {
+					lowered[j] = strings.ToLower(prefix)
+				}
+				*prefixes = lowered
+			}
+		}
+	}
+	return filter
+}
@@ -0,0 +1,47 @@
+package adapter
+
+import (
+	"testing"
+
+	"github.com/casbin/casbin/v2"
+)
+
+func TestNormalization(t *testing.T) {
+	a := newSqliteAdapter(t, WithNormalization(NormalizeOptions{
+		TrimSpace: []int{0, 1, 2},
+		LowerCase: []int{0},
+	}))
+
+	if err := a.AddPolicies("p", "p", [][]string{{" Alice ", " Data1", "read "}, {"BOB", "Data2", "write"}}); err != nil {
+		t.Fatalf("AddPolicies failed: %v", err)
+	}
+	e, _ := casbin.NewEnforcer("examples/rbac_model.conf", a)
+	testGetPolicyWithoutOrder(t, e, [][]string{{"alice", "Data1", "read"}, {"bob", "Data2", "write"}})
+
+	// Filters are normalized as the stored values.
+	if err := e.LoadFilteredPolicy(Filter{V0: []string{" ALICE"}}); err != nil {
+		t.Fatalf("LoadFilteredPolicy failed: %v", err)
+	}
+	testGetPolicyWithoutOrder(t, e, [][]string{{"alice", "Data1", "read"}})
+
+	if err := a.RemovePolicy("p", "p", []string{"alice", "Data1", "read"}); err != nil {
+		t.Fatalf("RemovePolicy failed: %v", err)
+	}
+	if err := a.RemoveFilteredPolicy("p", "p", 0, "Bob "); err != nil {
+		t.Fatalf("RemoveFilteredPolicy failed: %v", err)
+	}
+	_ = e.LoadPolicy()
+	testGetPolicyWithoutOrder(t, e, [][]string{})
+}
+
+func TestNormalizationDisabled(t *testing.T) {
+	a := newSqliteAdapter(t)
+	if err := a.AddPolicy("p", "p", []string{" Alice ", "data1", "read"}); err != nil {
+		t.Fatalf("AddPolicy failed: %v", err)
+	}
+	if err := a.RemoveFilteredPolicy("p", "p", 0, "alice"); err != nil {
+		t.Fatalf("RemoveFilteredPolicy failed: %v", err)
+	}
+	e, _ := casbin.NewEnforcer("examples/rbac_model.conf", a)
+	testGetPolicyWithoutOrder(t, e, [][]string{{" Alice ", "data1", "read"}})
+}
@@ -0,0 +1,188 @@
+package adapter
+
+import (
+	"context"
+	"errors"
+	"fmt"
+
+	"github.com/gogf/gf/v2/database/gdb"
+	"github.com/gogf/gf/v2/frame/g"
+	"golang.org/x/sync/errgroup"
+)
+
+const (
+	// swapTableSuffix and oldTableSuffix name the tables SavePolicy writes
+	// the rules into and moves the replaced rules to with parallel writes.
+	swapTableSuffix = "_swap"
+	oldTableSuffix  = "_old"
+)
+
+// WithParallelWrites makes SavePolicy write the batches of rules across n
+// goroutines, each batch on its own connection. The rules are written into
+// a new table, which replaces the policy table once every batch has been
+// written, so the policy table never holds a partial rule set and is left
+// untouched when a batch fails. The dialect may allow fewer writers, sqlite
+// a single one.
+//
+// The new table is filled and swapped holding the advisory lock of the
+// policy table, so the saves replacing it and the schema changes of other
+// adapters wait for each other. The other writes don't take the lock: the
+// rules added or removed by other writers while the new table is filled
+// are lost, have them wait for the save or keep the writers serial.
+//
+// Adapters scoped to a tenant, bound to a transaction, recording history,
+// soft deleting or using the gorm-adapter columns keep writing serially in
+// one transaction, as do values of n below 2.
+func WithParallelWrites(n int) AdapterOption {
+	return AdapterOption{apply: func(a *Adapter) {
+		a.writers = n
+	}}
+}
+
+// parallelWrites reports whether SavePolicy replaces the policy table.
+func (a *Adapter) parallelWrites() bool {
+	return a.writers > 1 && a.canReplaceTable()
+}
+
+// canReplaceTable reports whether the writes may replace the policy table
+// by a new one.
+func (a *Adapter) canReplaceTable() bool {
+	return a.tenantColumn == "" && a.tx == nil && a.historyTable == "" &&
+		a.pTypeColumn == Columns.PType && !a.softDelete
+}
+
+// parallelSavePolicy writes rules into a new table in parallel batches and
+// swaps it with the policy table.
+func (a *Adapter) parallelSavePolicy(ctx context.Context, rules []Rule) error {
+	return a.replaceTable(ctx, func(ctx context.Context, staging string) error {
+		return a.writeParallel(ctx, staging, rules)
+	})
+}
+
+// replaceTable creates a new table with the layout of the policy table,
+// fills it with fill and swaps it with the policy table, holding the
+// advisory lock of the policy table. The policy table is left untouched
+// when fill fails.
+func (a *Adapter) replaceTable(ctx context.Context, fill func(ctx context.Context, staging string) error) error {
+	return a.withDDLLock(ctx, func() error {
+		return a.replaceTableLocked(ctx, fill)
+	})
+}
+
+// replaceTableLocked is replaceTable once the lock is held.
+func (a *Adapter) replaceTableLocked(ctx context.Context, fill func(ctx context.Context, staging string) error) error {
+	staging := a.tableName + swapTableSuffix
+	old := a.tableName + oldTableSuffix
+
+	// Drop the tables left over by an interrupted save.
+	for _, table := range []string{staging, old} {
+		if err := a.dropTableNamed(ctx, table); err != nil {
+			return err
+		}
+	}
+	for _, sql := range a.dialect.createTableLikeSql(staging, a.tableName, a.schema()) {
+		if _, err := a.db.Exec(ctx, sql); err != nil {
+			return fmt.Errorf("failed to create table %s: %w", staging, err)
+		}
+	}
+	if err := a.clearTableFields(ctx, staging); err != nil {
+		return err
+	}
+	if a.actorColumn != "" {
+		if err := a.ensureActorColumn(ctx, staging); err != nil {
+			return a.abandonTable(ctx, staging, err)
+		}
+	}
+
+	if err := fill(ctx, staging); err != nil {
+		return a.abandonTable(ctx, staging, err)
+	}
+
+	defer a.invalidateCache()
+	err := a.runTransaction(ctx, func(ctx context.Context, tx gdb.TX) error {
+		for _, sql := range a.dialect.swapTablesSql(a.tableName, staging, old) {
+			if _, err := a.exec(ctx, tx, sql); err != nil {
+				return err
+			}
+		}
+		return a.bumpVersion(ctx)
+	})
+	if err != nil {
+		return a.abandonTable(ctx, staging, fmt.Errorf("failed to swap tables: %w", err))
+	}
+
+	for _, table := range []string{a.tableName, staging} {
+		if err := a.clearTableFields(ctx, table); err != nil {
+			return err
+		}
+	}
+	if err := a.dropTableNamed(ctx, old); err != nil {
+		return err
+	}
+	// The sqlite index names are global, the index moved along with the
+	// old table.
+	if a.uniqueIndex {
+		if err := a.createUniqueIndex(ctx); err != nil {
+			return err
+		}
+	}
+	return a.committed(ctx)
+}
+
+// writeParallel inserts rules into table in batches, as many at once as
+// the writers allow.
+func (a *Adapter) writeParallel(ctx context.Context, table string, rules []Rule) error {
+	writers := max(a.writers, 1)
+	if limit := a.dialect.maxWriters(); limit > 0 && limit < writers {
+		writers = limit
+	}
+
+	group, groupCtx := errgroup.WithContext(ctx)
+	group.SetLimit(writers)
+	// No more batches are started once one failed.
+	size := a.insertSize()
+	for i := 0; i < len(rules) && groupCtx.Err() == nil; i += size {
+		end := i + size
+		if end > len(rules) {
+			end = len(rules)
+		}
+		records := make(g.List, 0, end-i)
+		for _, rule := range rules[i:end] {
+			records = append(records, a.ruleRecord(ctx, rule))
+		}
+		group.Go(func() error {
+			if err := a.insertBatch(groupCtx, table, records); err != nil {
+				return err
+			}
+			reportProgress(groupCtx, len(records))
+			return nil
+		})
+	}
+	if err := group.Wait(); err != nil {
+		return fmt.Errorf("failed to insert rules batch: %w", err)
+	}
+	// The batches may have stopped early as ctx was canceled.
+	return ctx.Err()
+}
+
+// insertRecords inserts records into table outside of any transaction.
+func (a *Adapter) insertRecords(ctx context.Context, table string, records g.List) error {
+	_, err := a.encoded(a.db.Model(table).Safe().Ctx(ctx)).Insert(records)
+	return err
+}
+
+// abandonTable drops table after the save failed with err.
+func (a *Adapter) abandonTable(ctx context.Context, table string, err error) error {
+	if dropErr := a.dropTableNamed(ctx, table); dropErr != nil {
+		return errors.Join(err, dropErr)
+	}
+	return err
+}
+
+// dropTableNamed drops table if it exists.
+func (a *Adapter) dropTableNamed(ctx context.Context, table string) error {
+	if _, err := a.db.Exec(ctx, fmt.Sprintf(dropTableSql, table)); err != nil {
+		return fmt.Errorf("failed to drop table %s: %w", table, err)
+	}
+	return a.clearTableFields(ctx, table)
+}
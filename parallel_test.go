@@ -0,0 +1,166 @@
+package adapter
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sync"
+	"sync/atomic"
+	"testing"
+
+	"github.com/casbin/casbin/v2"
+	"github.com/casbin/casbin/v2/model"
+	"github.com/gogf/gf/v2/database/gdb"
+	"github.com/gogf/gf/v2/frame/g"
+)
+
+// concurrentDialect lifts the single writer limit of sqlite.
+type concurrentDialect struct {
+	dialect
+}
+
+func (concurrentDialect) maxWriters() int {
+	return 0
+}
+
+// policyModel returns a model holding the rules of the example policy and n
+// rules of seedRules.
+func policyModel(tb testing.TB, n int) model.Model {
+	tb.Helper()
+	e, err := casbin.NewEnforcer("examples/rbac_model.conf", "examples/rbac_policy.csv")
+	if err != nil {
+		tb.Fatalf("failed to load policy file: %v", err)
+	}
+	e.EnableAutoSave(false)
+	rules := make([][]string, 0, n)
+	for i := 0; i < n; i++ {
+		rules = append(rules, []string{fmt.Sprintf("user%d", i), fmt.Sprintf("data%d", i%10), "read"})
+	}
+	if _, err := e.AddPolicies(rules); err != nil {
+		tb.Fatalf("AddPolicies failed: %v", err)
+	}
+	return e.GetModel()
+}
+
+func TestParallelSavePolicy(t *testing.T) {
+	a := newSqliteAdapter(t, WithParallelWrites(4), WithBatchSize(100))
+	a.dialect = concurrentDialect{a.dialect}
+	initPolicy(t, a)
+
+	m := policyModel(t, 1000)
+	if err := a.SavePolicy(m); err != nil {
+		t.Fatalf("SavePolicy failed: %v", err)
+	}
+
+	serial := newSqliteAdapter(t)
+	if err := serial.SavePolicy(m); err != nil {
+		t.Fatalf("SavePolicy failed: %v", err)
+	}
+	report, err := a.CompareWith(a.ctx, serial)
+	if err != nil {
+		t.Fatalf("CompareWith failed: %v", err)
+	}
+	if !report.Equal() {
+		t.Errorf("parallel save differs from the serial one: %+v", report)
+	}
+
+	for _, table := range []string{a.tableName + swapTableSuffix, a.tableName + oldTableSuffix} {
+		if fields, _ := a.db.TableFields(a.ctx, table); len(fields) != 0 {
+			t.Errorf("table %s was left behind", table)
+		}
+	}
+}
+
+func TestParallelSavePolicyFailure(t *testing.T) {
+	a := newSqliteAdapter(t, WithParallelWrites(4), WithBatchSize(100))
+	initPolicy(t, a)
+
+	// The third batch fails, the policy table keeps the old rules while
+	// the batches are written.
+	var batches atomic.Int32
+	a.insertBatch = func(ctx context.Context, table string, records g.List) error {
+		count, err := a.db.Model(a.tableName).Ctx(context.Background()).Count()
+		if err != nil || count != 5 {
+			t.Errorf("policy table holds %d rules, err: %v, supposed to hold 5", count, err)
+		}
+		if batches.Add(1) == 3 {
+			return errors.New("injected failure")
+		}
+		return a.insertRecords(ctx, table, records)
+	}
+
+	if err := a.SavePolicy(policyModel(t, 1000)); err == nil {
+		t.Fatal("expected SavePolicy to fail")
+	}
+
+	if ids := storedIDs(t, a); len(ids) != 5 {
+		t.Errorf("stored ids %v, supposed to be the 5 initial ones", ids)
+	}
+	if fields, _ := a.db.TableFields(a.ctx, a.tableName+swapTableSuffix); len(fields) != 0 {
+		t.Error("the staging table was left behind")
+	}
+}
+
+// testConcurrentSaves replaces the policy table from many adapters on db at
+// once.
+func testConcurrentSaves(t *testing.T, db gdb.DB) {
+	ctx := context.Background()
+
+	// Every save replaces the whole table, the last one wins.
+	const replicas = 5
+	adapters := make([]*Adapter, replicas)
+	for i := range adapters {
+		a, err := NewAdapter(ctx, "", "casbin_rule_saves", db, WithParallelWrites(2), WithBatchSize(50))
+		if err != nil {
+			t.Fatalf("NewAdapter failed: %v", err)
+		}
+		adapters[i] = a
+	}
+	sizes := make(map[int]bool, replicas)
+	errs := make([]error, replicas)
+	var wg sync.WaitGroup
+	for i, a := range adapters {
+		m := policyModel(t, 100*(i+1))
+		sizes[len(m["p"]["p"].Policy)+len(m["g"]["g"].Policy)] = true
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			errs[i] = a.SavePolicy(m)
+		}()
+	}
+	wg.Wait()
+	for i, err := range errs {
+		if err != nil {
+			t.Fatalf("adapter %d failed: %v", i, err)
+		}
+	}
+
+	count, err := db.Model("casbin_rule_saves").Ctx(ctx).Count()
+	if err != nil {
+		t.Fatalf("Count failed: %v", err)
+	}
+	if !sizes[count] {
+		t.Errorf("policy table holds %d rules, supposed to hold the rules of one save", count)
+	}
+}
+
+func BenchmarkSavePolicy(b *testing.B) {
+	const n = 20000
+	m := policyModel(b, n)
+
+	for _, writers := range []int{1, 4} {
+		b.Run(fmt.Sprintf("writers=%d", writers), func(b *testing.B) {
+			a, err := NewAdapter(context.Background(), "", "", newSqliteDB(b), WithParallelWrites(writers))
+			if err != nil {
+				b.Fatalf("NewAdapter failed: %v", err)
+			}
+			a.dialect = concurrentDialect{a.dialect}
+			b.ResetTimer()
+			for i := 0; i < b.N; i++ {
+				if err := a.SavePolicy(m); err != nil {
+					b.Fatalf("SavePolicy failed: %v", err)
+				}
+			}
+		})
+	}
+}
@@ -0,0 +1,105 @@
+package adapter
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"strings"
+	"sync"
+
+	"github.com/gogf/gf/v2/database/gdb"
+)
+
+// defaultPartition is the partition of the default tenant "", created
+// along with the table.
+const defaultPartition = "p_default"
+
+// WithPartitioning creates the policy table partitioned by the tenant
+// column of WithTenantColumn or WithScope, one LIST partition per tenant,
+// so that SavePolicy of a tenant truncates its partition instead of
+// deleting its rows. The partition of a tenant is created by its first
+// write, or by EnsureTenantPartition. It is supported on MySQL and
+// PostgreSQL, where the primary key then covers the tenant column, and
+// applies to the tables the adapter creates.
+func WithPartitioning() AdapterOption {
+	return AdapterOption{apply: func(a *Adapter) {
+		a.partitioned = true
+		a.partitions = &sync.Map{}
+	}}
+}
+
+// validatePartitioning checks the options of partitioning.
+func (a *Adapter) validatePartitioning() error {
+	if !a.partitioned {
+		return nil
+	}
+	if a.tenantColumn == "" {
+		return errors.New("partitioning requires a tenant column")
+	}
+	if a.dialect.addPartitionSql(a.tableName, defaultPartition, "") == "" {
+		return errors.New("partitioning isn't supported by the database")
+	}
+	return nil
+}
+
+// partitionName returns the name of the partition of tenant, derived from
+// a hash as tenants needn't be valid identifiers.
+func partitionName(tenant string) string {
+	if tenant == "" {
+		return defaultPartition
+	}
+	sum := sha256.Sum256([]byte(tenant))
+	return "p_" + hex.EncodeToString(sum[:8])
+}
+
+// EnsureTenantPartition creates the partition of tenant in the policy table
+// of WithPartitioning when it doesn't exist. The writes of a tenant call it
+// themselves, the partitions created are remembered. MySQL commits the
+// transaction running a DDL statement, so the partitions aren't created in
+// one: Transaction creates the partition of its tenant before starting, and
+// a missing partition is an error in a transaction, e.g. the one of another
+// tenant.
+func (a *Adapter) EnsureTenantPartition(ctx context.Context, tenant string) error {
+	if !a.partitioned {
+		return errors.New("tenant partitions require the WithPartitioning option")
+	}
+	for _, table := range a.routedTableNames() {
+		key := table + "/" + tenant
+		if _, ok := a.partitions.Load(key); ok || tenant == "" {
+			continue
+		}
+		if err := a.checkDryRun(); err != nil {
+			return fmt.Errorf("failed to create partition of tenant %q: %w", tenant, err)
+		}
+		if a.tx != nil || gdb.TXFromCtx(ctx, a.db.GetGroup()) != nil {
+			return fmt.Errorf("failed to create partition of tenant %q: partitions can't be created in a transaction, call EnsureTenantPartition before it", tenant)
+		}
+		_, err := a.db.Exec(ctx, a.dialect.addPartitionSql(table, partitionName(tenant), tenant))
+		if err != nil && !isDuplicatePartitionError(err) {
+			return fmt.Errorf("failed to create partition of tenant %q: %w", tenant, err)
+		}
+		a.partitions.Store(key, true)
+	}
+	return nil
+}
+
+// isDuplicatePartitionError reports whether err was caused by adding a
+// partition that already exists.
+func isDuplicatePartitionError(err error) bool {
+	msg := strings.ToLower(err.Error())
+	return strings.Contains(msg, "duplicate partition name") || strings.Contains(msg, "multiple definition of same constant")
+}
+
+// truncatePartition empties the partition of the tenant of the adapter.
+func (a *Adapter) truncatePartition(ctx context.Context) error {
+	if err := a.EnsureTenantPartition(ctx, a.tenant); err != nil {
+		return err
+	}
+	defer a.invalidateCache()
+	if _, err := a.db.Exec(ctx, a.dialect.truncatePartitionSql(a.tableName, partitionName(a.tenant))); err != nil {
+		return fmt.Errorf("failed to truncate partition: %w", err)
+	}
+	return nil
+}
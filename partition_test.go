@@ -0,0 +1,135 @@
+package adapter
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+	"sync"
+	"testing"
+
+	"github.com/casbin/casbin/v2/model"
+)
+
+// partitionDialect logs the partition statements into the partition_log
+// table, sqlite has no partitions.
+type partitionDialect struct {
+	dialect
+}
+
+func (partitionDialect) addPartitionSql(table, partition, tenant string) string {
+	return fmt.Sprintf("INSERT INTO partition_log (statement) VALUES ('add %s %s')", table, partition)
+}
+
+func (partitionDialect) truncatePartitionSql(table, partition string) string {
+	return fmt.Sprintf("INSERT INTO partition_log (statement) VALUES ('truncate %s %s')", table, partition)
+}
+
+func TestMySQLPartitioning(t *testing.T) {
+	d := mysqlDialect{}
+	ddl := d.createTableSql("casbin_rule", tableSchema{tenantColumn: "tenant_id", partitioned: true})[0]
+	for _, want := range []string{
+		"PRIMARY KEY (id, tenant_id),\n  KEY idx_tenant_id (tenant_id)",
+		"COLLATE=utf8mb4_bin\nPARTITION BY LIST COLUMNS(tenant_id) (PARTITION p_default VALUES IN (''));",
+	} {
+		if !strings.Contains(ddl, want) {
+			t.Errorf("create statement lacks %q:\n%s", want, ddl)
+		}
+	}
+
+	partition := partitionName("o'brien")
+	if !isValidIdentifier(partition) || partition != partitionName("o'brien") || partition == partitionName("obrien") {
+		t.Errorf("partition name %q of o'brien isn't a stable identifier of its own", partition)
+	}
+	want := fmt.Sprintf("ALTER TABLE casbin_rule ADD PARTITION (PARTITION %s VALUES IN ('o''brien'))", partition)
+	if sql := d.addPartitionSql("casbin_rule", partition, "o'brien"); sql != want {
+		t.Errorf("add partition statement %q, supposed to be %q", sql, want)
+	}
+}
+
+func TestTenantPartitions(t *testing.T) {
+	ctx := context.Background()
+	a := newSqliteAdapter(t, WithTenantColumn("tenant_id"))
+	a.dialect = partitionDialect{a.dialect}
+	a.partitioned = true
+	a.partitions = &sync.Map{}
+	if _, err := a.db.Exec(ctx, "CREATE TABLE partition_log (id INTEGER PRIMARY KEY AUTOINCREMENT, statement varchar(256))"); err != nil {
+		t.Fatalf("failed to create partition log: %v", err)
+	}
+
+	t1, t2 := a.ForTenant("t1"), a.ForTenant("t2")
+	for _, tenant := range []*Adapter{t1, t2, t1} {
+		if err := tenant.AddPolicy("p", "p", []string{"alice", "data1", "read"}); err != nil {
+			t.Fatalf("AddPolicy failed: %v", err)
+		}
+	}
+	m, _ := model.NewModelFromFile("examples/rbac_model.conf")
+	m.AddPolicy("p", "p", []string{"bob", "data2", "write"})
+	if err := t1.SavePolicy(m); err != nil {
+		t.Fatalf("SavePolicy failed: %v", err)
+	}
+
+	statements, err := a.db.Model("partition_log").OrderAsc("id").Array("statement")
+	if err != nil {
+		t.Fatalf("failed to read partition log: %v", err)
+	}
+	want := []string{
+		"add casbin_rule " + partitionName("t1"),
+		"add casbin_rule " + partitionName("t2"),
+		"truncate casbin_rule " + partitionName("t1"),
+	}
+	if len(statements) != len(want) {
+		t.Fatalf("partition statements %v, supposed to be %v", statements, want)
+	}
+	for i, statement := range statements {
+		if statement.String() != want[i] {
+			t.Errorf("partition statement %d %q, supposed to be %q", i, statement, want[i])
+		}
+	}
+}
+
+func TestTenantPartitionsInTransaction(t *testing.T) {
+	ctx := context.Background()
+	a := newSqliteAdapter(t, WithTenantColumn("tenant_id"))
+	a.dialect = partitionDialect{a.dialect}
+	a.partitioned = true
+	a.partitions = &sync.Map{}
+	if _, err := a.db.Exec(ctx, "CREATE TABLE partition_log (id INTEGER PRIMARY KEY AUTOINCREMENT, statement varchar(256))"); err != nil {
+		t.Fatalf("failed to create partition log: %v", err)
+	}
+
+	// The partition of the tenant of the transaction is created before it,
+	// it outlives the rolled back transaction. The one of another tenant
+	// can't be created in it.
+	rollback := errors.New("rollback")
+	err := a.ForTenant("t1").Transaction(ctx, func(tx *Adapter) error {
+		if err := tx.AddPolicy("p", "p", []string{"alice", "data1", "read"}); err != nil {
+			return err
+		}
+		if err := tx.ForTenant("t2").AddPolicy("p", "p", []string{"bob", "data2", "write"}); err == nil || !strings.Contains(err.Error(), "in a transaction") {
+			t.Errorf("AddPolicy of another tenant err: %v, supposed to refuse to create its partition", err)
+		}
+		return rollback
+	})
+	if !errors.Is(err, rollback) {
+		t.Fatalf("Transaction err: %v, supposed to be rolled back", err)
+	}
+	statements, err := a.db.Model("partition_log").OrderAsc("id").Array("statement")
+	if err != nil {
+		t.Fatalf("failed to read partition log: %v", err)
+	}
+	if len(statements) != 1 || statements[0].String() != "add casbin_rule "+partitionName("t1") {
+		t.Errorf("partition statements %v, supposed to add the partition of t1", statements)
+	}
+}
+
+func TestPartitioningOptions(t *testing.T) {
+	for name, opts := range map[string][]AdapterOption{
+		"no tenant column": {WithPartitioning()},
+		"sqlite":           {WithTenantColumn("tenant_id"), WithPartitioning()},
+	} {
+		if _, err := NewAdapter(context.Background(), "", "", newSqliteDB(t), opts...); err == nil {
+			t.Errorf("%s: expected an error", name)
+		}
+	}
+}
@@ -0,0 +1,323 @@
+package adapter
+
+import (
+	"fmt"
+	"strings"
+)
+
+const (
+	pgsqlCreateTableSql = `
+CREATE TABLE IF NOT EXISTS %s (
+  id bigint GENERATED BY DEFAULT AS IDENTITY,
+  p_type varchar(10) DEFAULT NULL,
+  v0 varchar(256) DEFAULT NULL,
+  v1 varchar(256) DEFAULT NULL,
+  v2 varchar(256) DEFAULT NULL,
+  v3 varchar(256) DEFAULT NULL,
+  v4 varchar(256) DEFAULT NULL,
+  v5 varchar(256) DEFAULT NULL,
+%s  created_at timestamptz DEFAULT CURRENT_TIMESTAMP,
+  PRIMARY KEY (id)
+)%s
+`
+	pgsqlCreateHistoryTableSql = `
+CREATE TABLE IF NOT EXISTS %s (
+  id bigint GENERATED BY DEFAULT AS IDENTITY,
+  op varchar(10) NOT NULL,
+  p_type varchar(10) DEFAULT NULL,
+  v0 varchar(256) DEFAULT NULL,
+  v1 varchar(256) DEFAULT NULL,
+  v2 varchar(256) DEFAULT NULL,
+  v3 varchar(256) DEFAULT NULL,
+  v4 varchar(256) DEFAULT NULL,
+  v5 varchar(256) DEFAULT NULL,
+%s  changed_at timestamptz NOT NULL,
+  PRIMARY KEY (id)
+)%s
+`
+	pgsqlCreateStagingTableSql = "CREATE TEMPORARY TABLE IF NOT EXISTS %s (\n  id bigint GENERATED BY DEFAULT AS IDENTITY PRIMARY KEY,\n  %s\n)"
+	pgsqlDropStagingTableSql   = `DROP TABLE IF EXISTS %s`
+	pgsqlTenantColumnSql       = "  %s varchar(64) NOT NULL DEFAULT '',\n"
+	pgsqlActorColumnSql        = "%s varchar(255) NOT NULL DEFAULT ''"
+	pgsqlTypedColumnSql        = "%s %s DEFAULT NULL"
+	pgsqlAddColumnSql          = `ALTER TABLE %s ADD COLUMN IF NOT EXISTS %s`
+	pgsqlCreateIndexSql        = `CREATE INDEX IF NOT EXISTS idx_%s_%s ON %s (%s)`
+	pgsqlUniqueIndexSql        = `CREATE UNIQUE INDEX IF NOT EXISTS uniq_%s_rule ON %s (%s)`
+	pgsqlTruncateTableSql      = `TRUNCATE TABLE %s`
+	pgsqlCreateLikeSql         = `CREATE TABLE %s (LIKE %s INCLUDING ALL)`
+	pgsqlRenameTableSql        = `ALTER TABLE %s RENAME TO %s`
+	pgsqlVersionTableSql       = `CREATE TABLE IF NOT EXISTS %s (id int NOT NULL PRIMARY KEY, version bigint NOT NULL DEFAULT 0)`
+	pgsqlRuleColumnSql         = `CREATE TABLE IF NOT EXISTS %s (id bigint GENERATED BY DEFAULT AS IDENTITY PRIMARY KEY, %s text DEFAULT NULL, created_at timestamptz DEFAULT CURRENT_TIMESTAMP)`
+	pgsqlJSONTableSql          = `CREATE TABLE IF NOT EXISTS %s (id bigint GENERATED BY DEFAULT AS IDENTITY PRIMARY KEY, p_type varchar(32) NOT NULL DEFAULT '', vals jsonb DEFAULT NULL, created_at timestamptz DEFAULT CURRENT_TIMESTAMP)`
+	pgsqlJSONIndexSql          = `CREATE INDEX IF NOT EXISTS idx_%s_vals ON %s USING GIN (vals jsonb_path_ops)`
+	pgsqlPartitionSql          = "\nPARTITION BY LIST (%s)"
+	pgsqlAddPartitionSql       = `CREATE TABLE IF NOT EXISTS %s_%s PARTITION OF %s FOR VALUES IN (%s)`
+	pgsqlTruncatePartSql       = `TRUNCATE TABLE %s_%s`
+	pgsqlTableCommentSql       = `COMMENT ON TABLE %s IS %s`
+	pgsqlColumnCommentSql      = `COMMENT ON COLUMN %s.%s IS %s`
+	pgsqlReturningIdsSql       = `RETURNING id`
+	pgsqlSkipConflictSql       = `ON CONFLICT (%s) DO NOTHING`
+	pgsqlReplaceConflictSql    = `ON CONFLICT (%s) DO UPDATE SET %s`
+
+	// pgsqlLockSql and pgsqlUnlockSql take and release an advisory lock of
+	// the session, keyed by the hash of the name. The wait is bounded by the
+	// lock_timeout of the statement, which fails once it is over. The
+	// placeholders are the ones of the driver, as the statements run on a
+	// connection of database/sql.
+	pgsqlLockSql = `SELECT CASE WHEN set_config('lock_timeout', ($2::int * 1000)::text, true) IS NOT NULL
+THEN (SELECT 1 FROM pg_advisory_lock(hashtext($1))) END`
+	pgsqlUnlockSql = `SELECT CASE WHEN pg_advisory_unlock(hashtext($1)) THEN 1 ELSE 0 END`
+
+	// pgsqlMaxParams is the limit of the parameters of a statement of the
+	// PostgreSQL protocol.
+	pgsqlMaxParams = 65535
+)
+
+// pgsqlColumnSql defines the columns of the create statements, they are
+// used to add missing columns to existing tables.
+var pgsqlColumnSql = map[string]string{
+	"p_type":     "p_type varchar(10) DEFAULT NULL",
+	"v0":         "v0 varchar(256) DEFAULT NULL",
+	"v1":         "v1 varchar(256) DEFAULT NULL",
+	"v2":         "v2 varchar(256) DEFAULT NULL",
+	"v3":         "v3 varchar(256) DEFAULT NULL",
+	"v4":         "v4 varchar(256) DEFAULT NULL",
+	"v5":         "v5 varchar(256) DEFAULT NULL",
+	"created_at": "created_at timestamptz DEFAULT CURRENT_TIMESTAMP",
+	"updated_at": "updated_at timestamptz DEFAULT NULL",
+	"deleted_at": "deleted_at timestamptz DEFAULT NULL",
+	"rule_hash":  "rule_hash char(64) DEFAULT NULL",
+}
+
+// pgQuote returns s as a string literal of PostgreSQL, which doesn't treat
+// backslashes as escapes with standard_conforming_strings, the default.
+func pgQuote(s string) string {
+	return "'" + strings.ReplaceAll(s, "'", "''") + "'"
+}
+
+// pgsqlDialect generates the statements of PostgreSQL. The identifiers are
+// left unquoted, the names of the adapter are lower case.
+type pgsqlDialect struct{}
+
+// createTableSql creates the partitioned table along with the partition
+// of the default tenant, whose rows can't go elsewhere. The comments of
+// schema are set by COMMENT statements once the table exists, PostgreSQL
+// has no comment clause.
+func (d pgsqlDialect) createTableSql(table string, schema tableSchema) []string {
+	var columns, partitioning string
+	if schema.ruleHash {
+		columns = "  " + pgsqlColumnSql[ruleHashColumn] + ",\n"
+	}
+	if schema.partitioned {
+		partitioning = fmt.Sprintf(pgsqlPartitionSql, schema.tenantColumn)
+	}
+	statements := d.withTenant(pgsqlCreateTableSql, table, columns, partitioning, schema)
+	if schema.partitioned {
+		// The partitioning column must be part of the primary key.
+		statements[0] = strings.Replace(statements[0], "PRIMARY KEY (id)", fmt.Sprintf("PRIMARY KEY (id, %s)", schema.tenantColumn), 1)
+		statements = append(statements, d.addPartitionSql(table, defaultPartition, ""))
+	}
+	return append(statements, pgsqlComments(table, statements[0], schema)...)
+}
+
+// pgsqlComments returns the statements setting the table comment of schema
+// and the comments of the columns defined by ddl.
+func pgsqlComments(table, ddl string, schema tableSchema) []string {
+	var statements []string
+	if schema.tableComment != "" {
+		statements = append(statements, fmt.Sprintf(pgsqlTableCommentSql, table, pgQuote(schema.tableComment)))
+	}
+	if len(schema.columnComments) == 0 {
+		return statements
+	}
+	columnLines(ddl, func(column, definition string) string {
+		if comment := schema.columnComments[column]; comment != "" {
+			statements = append(statements, fmt.Sprintf(pgsqlColumnCommentSql, table, column, pgQuote(comment)))
+		}
+		return definition
+	})
+	return statements
+}
+
+func (d pgsqlDialect) createHistoryTableSql(table string, schema tableSchema) []string {
+	statements := d.withTenant(pgsqlCreateHistoryTableSql, table, "", "", schema)
+	return append(statements, fmt.Sprintf(pgsqlCreateIndexSql, table, "changed_at", table, "changed_at"))
+}
+
+func (pgsqlDialect) createVersionTableSql(table string) string {
+	return fmt.Sprintf(pgsqlVersionTableSql, table)
+}
+
+func (pgsqlDialect) createRuleColumnTableSql(table, column string) string {
+	return fmt.Sprintf(pgsqlRuleColumnSql, table, column)
+}
+
+// createJSONTableSql indexes the arrays for the containment of
+// jsonEqualSql.
+func (pgsqlDialect) createJSONTableSql(table string) []string {
+	return []string{
+		fmt.Sprintf(pgsqlJSONTableSql, table),
+		fmt.Sprintf(pgsqlJSONIndexSql, table, table),
+	}
+}
+
+func (pgsqlDialect) jsonValueSql(column string, index int) string {
+	return fmt.Sprintf("%s->>%d", column, index)
+}
+
+// jsonEqualSql looks up the arrays containing the bound one by the GIN
+// index, and compares them, as jsonb keeps the order of the arrays. The
+// bound array is cast rather than written ::jsonb, the driver turns the
+// placeholders following ::jsonb back into the ? operator.
+func (pgsqlDialect) jsonEqualSql(column string) string {
+	return fmt.Sprintf("%s @> CAST(? AS jsonb) AND %s = CAST(? AS jsonb)", column, column)
+}
+
+// withTenant renders the create statement with the optional columns, the
+// index of the tenant column is created by a separate statement.
+func (pgsqlDialect) withTenant(createSql, table, columns, suffix string, schema tableSchema) []string {
+	if schema.tenantColumn == "" {
+		return []string{withColumnTypes(fmt.Sprintf(createSql, table, columns, suffix), schema, pgsqlTypedColumnSql)}
+	}
+	columns = fmt.Sprintf(pgsqlTenantColumnSql, schema.tenantColumn) + columns
+	return []string{
+		withColumnTypes(fmt.Sprintf(createSql, table, columns, suffix), schema, pgsqlTypedColumnSql),
+		fmt.Sprintf(pgsqlCreateIndexSql, table, schema.tenantColumn, table, schema.tenantColumn),
+	}
+}
+
+func (pgsqlDialect) truncateTableSql(table string) string {
+	return fmt.Sprintf(pgsqlTruncateTableSql, table)
+}
+
+// transactionalTruncate is true, the truncation is rolled back with the
+// transaction like any other statement of PostgreSQL.
+func (pgsqlDialect) transactionalTruncate() bool {
+	return true
+}
+
+func (pgsqlDialect) nullSafeEqualSql(left, right string) string {
+	return fmt.Sprintf("%s IS NOT DISTINCT FROM %s", left, right)
+}
+
+func (pgsqlDialect) createStagingTableSql(table string, schema tableSchema) string {
+	return fmt.Sprintf(pgsqlCreateStagingTableSql, table, stagingColumnsSql(pgsqlColumnSql, schema, pgsqlTypedColumnSql))
+}
+
+func (pgsqlDialect) dropStagingTableSql(table string) string {
+	return fmt.Sprintf(pgsqlDropStagingTableSql, table)
+}
+
+// createTableLikeSql copies the columns, the indexes and the identity of
+// like, the table gets a sequence of its own.
+func (pgsqlDialect) createTableLikeSql(table, like string, schema tableSchema) []string {
+	return []string{fmt.Sprintf(pgsqlCreateLikeSql, table, like)}
+}
+
+func (pgsqlDialect) swapTablesSql(table, staging, old string) []string {
+	return []string{
+		fmt.Sprintf(pgsqlRenameTableSql, table, old),
+		fmt.Sprintf(pgsqlRenameTableSql, staging, table),
+	}
+}
+
+func (pgsqlDialect) createUniqueIndexSql(table string, columns []string) string {
+	return fmt.Sprintf(pgsqlUniqueIndexSql, table, table, strings.Join(columns, ", "))
+}
+
+func (pgsqlDialect) maxWriters() int {
+	return 0
+}
+
+func (pgsqlDialect) maxGroupedConditions() int {
+	return 0
+}
+
+func (pgsqlDialect) lockSql() (lock, unlock string) {
+	return pgsqlLockSql, pgsqlUnlockSql
+}
+
+// addPartitionSql creates the partition as a table of its own, named after
+// the partitioned table.
+func (pgsqlDialect) addPartitionSql(table, partition, tenant string) string {
+	return fmt.Sprintf(pgsqlAddPartitionSql, table, partition, table, pgQuote(tenant))
+}
+
+func (pgsqlDialect) truncatePartitionSql(table, partition string) string {
+	return fmt.Sprintf(pgsqlTruncatePartSql, table, partition)
+}
+
+func (pgsqlDialect) optimizeTableSql(table string) string {
+	return ""
+}
+
+// insertedIds only tells the id of a single row, the driver returns the
+// id of the last row of a statement.
+func (pgsqlDialect) insertedIds(last int64, n int) []int64 {
+	if n != 1 {
+		return nil
+	}
+	return []int64{last}
+}
+
+// returningSql returns the ids of every row, the driver only tells the one
+// of the last row.
+func (pgsqlDialect) returningSql() (output, returning string) {
+	return "", pgsqlReturningIdsSql
+}
+
+func (pgsqlDialect) readPastHint() string {
+	return ""
+}
+
+func (pgsqlDialect) maxInsertRows() int {
+	return 0
+}
+
+func (pgsqlDialect) maxParams() int {
+	return pgsqlMaxParams
+}
+
+// resolvesConflicts is true, the conflicts are resolved by the ON CONFLICT
+// clause of conflictSql.
+func (pgsqlDialect) resolvesConflicts() bool {
+	return true
+}
+
+// conflictSql skips the conflicting rows, or updates them as new rows with
+// the next id, the driver rejects REPLACE.
+func (pgsqlDialect) conflictSql(policy ConflictPolicy, unique, columns []string) string {
+	target := strings.Join(unique, ", ")
+	if policy != ConflictReplace {
+		return fmt.Sprintf(pgsqlSkipConflictSql, target)
+	}
+	updates := []string{"id = DEFAULT"}
+	for _, column := range columns {
+		updates = append(updates, fmt.Sprintf("%s = EXCLUDED.%s", column, column))
+	}
+	return fmt.Sprintf(pgsqlReplaceConflictSql, target, strings.Join(updates, ", "))
+}
+
+// addColumnSql sets the comment of column, when it has one, once added.
+func (pgsqlDialect) addColumnSql(table, column string, schema tableSchema) []string {
+	var statements []string
+	switch column {
+	case schema.tenantColumn:
+		definition := strings.TrimSuffix(strings.TrimSpace(fmt.Sprintf(pgsqlTenantColumnSql, column)), ",")
+		statements = []string{
+			fmt.Sprintf(pgsqlAddColumnSql, table, definition),
+			fmt.Sprintf(pgsqlCreateIndexSql, table, column, table, column),
+		}
+	case schema.actorColumn:
+		statements = []string{fmt.Sprintf(pgsqlAddColumnSql, table, fmt.Sprintf(pgsqlActorColumnSql, column))}
+	default:
+		statements = []string{fmt.Sprintf(pgsqlAddColumnSql, table, pgsqlColumnSql[column])}
+		if indexedColumns[column] {
+			statements = append(statements, fmt.Sprintf(pgsqlCreateIndexSql, table, column, table, column))
+		}
+	}
+	if comment := schema.columnComments[column]; comment != "" {
+		statements = append(statements, fmt.Sprintf(pgsqlColumnCommentSql, table, column, pgQuote(comment)))
+	}
+	return statements
+}
@@ -0,0 +1,155 @@
+//go:build pgsql
+
+package adapter
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/casbin/casbin/v2/model"
+	"github.com/gogf/gf/v2/database/gdb"
+)
+
+// newPgsqlDB connects to the PostgreSQL server of the pgsql tests.
+func newPgsqlDB(t *testing.T) gdb.DB {
+	t.Helper()
+	db, err := gdb.New(gdb.ConfigNode{
+		Type:  "pgsql",
+		Host:  "127.0.0.1",
+		Port:  "5432",
+		User:  "postgres",
+		Pass:  "postgres",
+		Name:  "casbin",
+		Debug: true,
+	})
+	if err != nil {
+		t.Fatalf("failed to create database connection: %v", err)
+	}
+	return db
+}
+
+// TestPgsqlAdapters needs a PostgreSQL server, it runs with go test -tags
+// pgsql.
+func TestPgsqlAdapters(t *testing.T) {
+	db := newPgsqlDB(t)
+	a, err := NewAdapter(context.Background(), "", "", db)
+	if err != nil {
+		t.Fatalf("failed to create adapter: %v", err)
+	}
+
+	runAdapterSuite(t, a)
+	t.Run("ConcurrentCreate", func(t *testing.T) {
+		testConcurrentCreate(t, db)
+	})
+	t.Run("ConcurrentSaves", func(t *testing.T) {
+		testConcurrentSaves(t, db)
+	})
+	t.Run("ReturningIDs", func(t *testing.T) {
+		returning, err := NewAdapter(context.Background(), "", "casbin_rule_ids", db, WithBatchSize(2))
+		if err != nil {
+			t.Fatalf("failed to create adapter: %v", err)
+		}
+		if err := returning.truncateTable(context.Background()); err != nil {
+			t.Fatalf("failed to truncate table: %v", err)
+		}
+		testReturningIDs(t, returning)
+	})
+	t.Run("JSONStorage", func(t *testing.T) {
+		ctx := context.Background()
+		_, _ = db.Exec(ctx, "DROP TABLE IF EXISTS casbin_rule_json")
+		stored, err := NewAdapter(ctx, "", "casbin_rule_json", db, WithJSONStorage())
+		if err != nil {
+			t.Fatalf("failed to create adapter: %v", err)
+		}
+		runAdapterSuite(t, stored)
+		testJSONStorageFilters(t, stored)
+		testPackedQueries(t, stored)
+	})
+	// The times are stored as instants, read alike in every time zone of
+	// the session.
+	t.Run("TimeZones", func(t *testing.T) {
+		ctx := context.Background()
+		_, _ = db.Exec(ctx, "DROP TABLE IF EXISTS casbin_rule_tz, casbin_rule_tz_history")
+		clock := tokyoClock()
+		zoned, err := NewAdapter(ctx, "", "casbin_rule_tz", db, WithHistory(), WithUTC(false), withClock(clock))
+		if err != nil {
+			t.Fatalf("failed to create adapter: %v", err)
+		}
+		if err := zoned.AddPolicy("p", "p", []string{"alice", "data1", "read"}); err != nil {
+			t.Fatalf("AddPolicy failed: %v", err)
+		}
+		for _, zone := range []string{"UTC", "Asia/Tokyo", "America/New_York"} {
+			err := db.Transaction(ctx, func(ctx context.Context, tx gdb.TX) error {
+				if _, err := tx.Exec("SET LOCAL TIME ZONE '" + zone + "'"); err != nil {
+					return err
+				}
+				var rows []pagedRow
+				if err := tx.Model("casbin_rule_tz").Ctx(ctx).Scan(&rows); err != nil {
+					return err
+				}
+				if len(rows) != 1 || !rows[0].createdAt().Equal(clock.now) {
+					t.Errorf("%s: rows %v, supposed to be created at %s", zone, rows, clock.now)
+				}
+				changedAt, err := tx.Model("casbin_rule_tz_history").Ctx(ctx).Value("changed_at")
+				if err != nil {
+					return err
+				}
+				if !changedAt.Time().Equal(clock.now) {
+					t.Errorf("%s: changed_at %s, supposed to be %s", zone, changedAt.Time(), clock.now)
+				}
+				return nil
+			})
+			if err != nil {
+				t.Fatalf("%s: failed to read the times: %v", zone, err)
+			}
+		}
+		rules, err := zoned.GetFilteredPolicies(ctx, Filter{CreatedAfter: clock.now.Add(-time.Minute), CreatedBefore: clock.now.Add(time.Minute)})
+		if err != nil || len(rules) != 1 {
+			t.Errorf("GetFilteredPolicies = %v, err: %v, supposed to be the rule of alice", rules, err)
+		}
+	})
+	t.Run("Conflicts", func(t *testing.T) {
+		ctx := context.Background()
+		_, _ = db.Exec(ctx, "DROP TABLE IF EXISTS casbin_rule_unique")
+		unique, err := NewAdapter(ctx, "", "casbin_rule_unique", db, WithUniqueIndex())
+		if err != nil {
+			t.Fatalf("failed to create adapter: %v", err)
+		}
+		rules := [][]string{{"alice", "data1", "read"}, {"bob", "data2", "write"}, {"bob", "data2", "write"}}
+		for _, policy := range []ConflictPolicy{ConflictSkip, ConflictReplace, ConflictSkip} {
+			if _, err := unique.AddPoliciesOnConflict("p", "p", rules, policy); err != nil {
+				t.Fatalf("AddPoliciesOnConflict %d failed: %v", policy, err)
+			}
+		}
+		if count, err := db.Model("casbin_rule_unique").Count(); err != nil || count != 2 {
+			t.Errorf("%d rules stored (%v), supposed to be 2", count, err)
+		}
+	})
+	t.Run("Partitioning", func(t *testing.T) {
+		ctx := context.Background()
+		_, _ = db.Exec(ctx, "DROP TABLE IF EXISTS casbin_rule_partitioned")
+		partitioned, err := NewAdapter(ctx, "", "casbin_rule_partitioned", db, WithTenantColumn("tenant_id"), WithPartitioning())
+		if err != nil {
+			t.Fatalf("failed to create adapter: %v", err)
+		}
+		t1, t2 := partitioned.ForTenant("t1"), partitioned.ForTenant("o'brien")
+		for _, tenant := range []*Adapter{t1, t2} {
+			if err := tenant.AddPolicy("p", "p", []string{"alice", "data1", "read"}); err != nil {
+				t.Fatalf("AddPolicy failed: %v", err)
+			}
+		}
+		m, _ := model.NewModelFromFile("examples/rbac_model.conf")
+		m.AddPolicy("p", "p", []string{"bob", "data2", "write"})
+		if err := t1.SavePolicy(m); err != nil {
+			t.Fatalf("SavePolicy failed: %v", err)
+		}
+		count, err := db.Model("casbin_rule_partitioned").Where("tenant_id", "o'brien").Count()
+		if err != nil {
+			t.Fatalf("failed to count the rules of o'brien: %v", err)
+		}
+		if count != 1 {
+			t.Errorf("%d rules of o'brien, the save of t1 is supposed to truncate its partition only", count)
+		}
+	})
+}
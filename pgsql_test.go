@@ -0,0 +1,140 @@
+package adapter
+
+import (
+	"fmt"
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/gogf/gf/v2/database/gdb"
+)
+
+func TestPgsqlDialect(t *testing.T) {
+	db, err := gdb.New(gdb.ConfigNode{Type: "pgsql", Host: "127.0.0.1", Port: "5432", Name: "casbin"})
+	if err != nil {
+		t.Fatalf("failed to create database connection: %v", err)
+	}
+	if _, ok := dialectFor(db).(pgsqlDialect); !ok {
+		t.Fatalf("dialect %T of pgsql, supposed to be pgsqlDialect", dialectFor(db))
+	}
+
+	d := pgsqlDialect{}
+	schema := tableSchema{tenantColumn: "tenant_id"}
+	statements := d.createTableSql("casbin_rule", schema)
+	if len(statements) != 2 {
+		t.Fatalf("create statements %q, supposed to be the table and the tenant index", statements)
+	}
+	for _, want := range []string{
+		"CREATE TABLE IF NOT EXISTS casbin_rule (\n  id bigint GENERATED BY DEFAULT AS IDENTITY,",
+		"  tenant_id varchar(64) NOT NULL DEFAULT '',\n  created_at timestamptz DEFAULT CURRENT_TIMESTAMP,\n  PRIMARY KEY (id)\n)",
+	} {
+		if !strings.Contains(statements[0], want) {
+			t.Errorf("create statement lacks %q:\n%s", want, statements[0])
+		}
+	}
+	if want := "CREATE INDEX IF NOT EXISTS idx_casbin_rule_tenant_id ON casbin_rule (tenant_id)"; statements[1] != want {
+		t.Errorf("tenant index statement %q, supposed to be %q", statements[1], want)
+	}
+	if sql := d.addColumnSql("casbin_rule", "tenant_id", schema)[0]; sql != "ALTER TABLE casbin_rule ADD COLUMN IF NOT EXISTS tenant_id varchar(64) NOT NULL DEFAULT ''" {
+		t.Errorf("add tenant statement %q", sql)
+	}
+	if sql := d.nullSafeEqualSql("t1.v0", "t2.v0"); sql != "t1.v0 IS NOT DISTINCT FROM t2.v0" {
+		t.Errorf("null-safe condition %q", sql)
+	}
+	if sql := d.createStagingTableSql("casbin_rule_staging", schema); !strings.HasPrefix(sql, "CREATE TEMPORARY TABLE IF NOT EXISTS casbin_rule_staging") {
+		t.Errorf("staging statement %q, supposed to create a temporary table", sql)
+	}
+	lock, unlock := d.lockSql()
+	if !strings.Contains(lock, "pg_advisory_lock(hashtext($1))") || !strings.Contains(lock, "$2") || unlock != "SELECT CASE WHEN pg_advisory_unlock(hashtext($1)) THEN 1 ELSE 0 END" {
+		t.Errorf("lock statements %q and %q, supposed to take the advisory lock of the hashed name", lock, unlock)
+	}
+	if !d.transactionalTruncate() {
+		t.Error("the truncation of PostgreSQL is supposed to be transactional")
+	}
+}
+
+func TestPgsqlPartitioning(t *testing.T) {
+	d := pgsqlDialect{}
+	statements := d.createTableSql("casbin_rule", tableSchema{tenantColumn: "tenant_id", partitioned: true})
+	if len(statements) != 3 {
+		t.Fatalf("create statements %q, supposed to be the table, the tenant index and the default partition", statements)
+	}
+	for _, want := range []string{
+		"PRIMARY KEY (id, tenant_id)\n)\nPARTITION BY LIST (tenant_id)",
+	} {
+		if !strings.Contains(statements[0], want) {
+			t.Errorf("create statement lacks %q:\n%s", want, statements[0])
+		}
+	}
+	if want := "CREATE TABLE IF NOT EXISTS casbin_rule_p_default PARTITION OF casbin_rule FOR VALUES IN ('')"; statements[2] != want {
+		t.Errorf("default partition statement %q, supposed to be %q", statements[2], want)
+	}
+
+	partition := partitionName("o'brien")
+	want := fmt.Sprintf("CREATE TABLE IF NOT EXISTS casbin_rule_%s PARTITION OF casbin_rule FOR VALUES IN ('o''brien')", partition)
+	if sql := d.addPartitionSql("casbin_rule", partition, "o'brien"); sql != want {
+		t.Errorf("add partition statement %q, supposed to be %q", sql, want)
+	}
+	if sql := d.truncatePartitionSql("casbin_rule", partition); sql != "TRUNCATE TABLE casbin_rule_"+partition {
+		t.Errorf("truncate partition statement %q", sql)
+	}
+}
+
+// pgsqlConflictDialect skips the conflicts by the ON CONFLICT clause of
+// PostgreSQL, which sqlite shares.
+type pgsqlConflictDialect struct {
+	dialect
+}
+
+func (pgsqlConflictDialect) conflictSql(policy ConflictPolicy, unique, columns []string) string {
+	return pgsqlDialect{}.conflictSql(policy, unique, columns)
+}
+
+func TestPgsqlConflicts(t *testing.T) {
+	d := pgsqlDialect{}
+	unique := []string{"p_type", "v0", "tenant_id"}
+	if sql := d.conflictSql(ConflictSkip, unique, []string{"p_type", "v0"}); sql != "ON CONFLICT (p_type, v0, tenant_id) DO NOTHING" {
+		t.Errorf("skip clause %q", sql)
+	}
+	if sql := d.conflictSql(ConflictReplace, unique, []string{"p_type", "v0"}); sql != "ON CONFLICT (p_type, v0, tenant_id) DO UPDATE SET id = DEFAULT, p_type = EXCLUDED.p_type, v0 = EXCLUDED.v0" {
+		t.Errorf("replace clause %q", sql)
+	}
+
+	a := newSqliteAdapter(t, WithUniqueIndex(), WithTenantColumn("tenant_id"), WithConflictPolicy(ConflictSkip))
+	a.dialect = pgsqlConflictDialect{a.dialect}
+	rules := [][]string{{"alice", "data1", "read"}, {"bob", "data2", "write"}, {"bob", "data2", "write"}}
+	for run, want := range []int64{2, 0} {
+		inserted, err := a.AddPoliciesOnConflict("p", "p", rules, ConflictSkip)
+		if err != nil {
+			t.Fatalf("run %d: AddPoliciesOnConflict failed: %v", run, err)
+		}
+		if inserted != want {
+			t.Errorf("run %d: inserted %d rules, supposed to be %d", run, inserted, want)
+		}
+	}
+	if ids := storedIDs(t, a); len(ids) != 2 {
+		t.Errorf("stored ids %v, supposed to be 2 rows", ids)
+	}
+}
+
+func TestPgsqlJSONStorage(t *testing.T) {
+	d := pgsqlDialect{}
+	statements := d.createJSONTableSql("casbin_rule")
+	if len(statements) != 2 || !strings.Contains(statements[0], "vals jsonb DEFAULT NULL") ||
+		statements[1] != "CREATE INDEX IF NOT EXISTS idx_casbin_rule_vals ON casbin_rule USING GIN (vals jsonb_path_ops)" {
+		t.Errorf("JSON table statements %q, supposed to create a jsonb column and its GIN index", statements)
+	}
+	if sql := d.jsonValueSql("vals", 2); sql != "vals->>2" {
+		t.Errorf("JSON value expression %q", sql)
+	}
+
+	a := newSqliteAdapter(t, WithJSONStorage())
+	a.dialect = d
+	query, args := a.jsonValuesCondition([]Rule{{PType: "p", V0: "alice", V1: "data1"}})
+	if want := "((p_type = ? AND vals @> CAST(? AS jsonb) AND vals = CAST(? AS jsonb)))"; query != want {
+		t.Errorf("JSON condition %q, supposed to be %q", query, want)
+	}
+	if want := []interface{}{"p", `["alice","data1"]`, `["alice","data1"]`}; !reflect.DeepEqual(args, want) {
+		t.Errorf("JSON condition args %v, supposed to be %v", args, want)
+	}
+}
@@ -0,0 +1,198 @@
+package adapter
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/casbin/casbin/v2/persist"
+	"github.com/gogf/gf/v2/database/gdb"
+	"github.com/gogf/gf/v2/frame/g"
+)
+
+const (
+	// versionTableSuffix is appended to the policy table name to name the
+	// version table.
+	versionTableSuffix = "_version"
+	// versionRowID is the id of the single row of the version table.
+	versionRowID = 1
+
+	// maxPollBackoff caps the pause of a polling watcher after failed polls,
+	// unless the poll interval is longer.
+	maxPollBackoff = time.Minute
+)
+
+var _ persist.Watcher = (*PollingWatcher)(nil)
+
+// WithVersionTable keeps a version counter in a single-row table next to
+// the policy table, which every change written through the adapter bumps
+// in its own transaction. A PollingWatcher polls the counter to learn about
+// the changes of the other instances without Redis.
+func WithVersionTable() AdapterOption {
+	return AdapterOption{apply: func(a *Adapter) {
+		a.versioned = true
+	}}
+}
+
+// createVersionTable creates the version table and its row.
+func (a *Adapter) createVersionTable(ctx context.Context) error {
+	if _, err := a.db.Exec(ctx, a.dialect.createVersionTableSql(a.versionTable)); err != nil {
+		return fmt.Errorf("failed to create version table: %w", err)
+	}
+	if err := a.clearTableFields(ctx, a.versionTable); err != nil {
+		return err
+	}
+	_, err := a.db.Model(a.versionTable).Ctx(ctx).Data(g.Map{"id": versionRowID, "version": 0}).InsertIgnore()
+	if err != nil {
+		return fmt.Errorf("failed to initialize version: %w", err)
+	}
+	return nil
+}
+
+// versionedFn returns fn followed by bumping the version in the same
+// transaction.
+func (a *Adapter) versionedFn(fn func(ctx context.Context, tx gdb.TX) error) func(ctx context.Context, tx gdb.TX) error {
+	return func(ctx context.Context, tx gdb.TX) error {
+		if err := fn(ctx, tx); err != nil {
+			return err
+		}
+		return a.bumpVersion(ctx)
+	}
+}
+
+// bumpVersion increments the version of the policy, it does nothing
+// without the version table.
+func (a *Adapter) bumpVersion(ctx context.Context) error {
+	if a.versionTable == "" {
+		return nil
+	}
+
+	m := a.db.Model(a.versionTable)
+	if a.tx != nil {
+		m = a.tx.Model(a.versionTable)
+	}
+	_, err := a.planned(m.Ctx(ctx), gdb.HookHandler{}).Data("version", gdb.Raw("version + 1")).Where("id", versionRowID).Update()
+	if err != nil {
+		return fmt.Errorf("failed to bump version: %w", err)
+	}
+	return nil
+}
+
+// PollingWatcher is a casbin persist.Watcher that polls the version table of
+// WithVersionTable and runs the update callback whenever the version
+// changed. It needs no other service than the database, at the price of
+// seeing the changes up to one poll interval late. The callback also runs
+// for the changes of the polling instance itself.
+type PollingWatcher struct {
+	db       gdb.DB
+	table    string
+	interval time.Duration
+
+	// stop is closed by Close, done once the polling goroutine returned.
+	stop      chan struct{}
+	done      chan struct{}
+	closeOnce sync.Once
+
+	mu       sync.RWMutex
+	callback func(string)
+}
+
+// NewPollingWatcher returns a watcher polling the version table of adapter
+// a, created with WithVersionTable, every interval. Close the watcher to stop
+// polling.
+func NewPollingWatcher(a *Adapter, interval time.Duration) (*PollingWatcher, error) {
+	if a.versionTable == "" {
+		return nil, errors.New("polling watcher requires an adapter created with WithVersionTable")
+	}
+	if interval <= 0 {
+		return nil, fmt.Errorf("invalid poll interval: %s", interval)
+	}
+
+	w := &PollingWatcher{
+		db:       a.db,
+		table:    a.versionTable,
+		interval: interval,
+		stop:     make(chan struct{}),
+		done:     make(chan struct{}),
+	}
+	version, err := w.version()
+	if err != nil {
+		return nil, err
+	}
+	go w.poll(version)
+	return w, nil
+}
+
+// version reads the current version.
+func (w *PollingWatcher) version() (int64, error) {
+	return readVersion(context.Background(), w.db, w.table)
+}
+
+// readVersion reads the version from the version table of db.
+func readVersion(ctx context.Context, db gdb.DB, table string) (int64, error) {
+	value, err := db.Model(table).Ctx(ctx).Where("id", versionRowID).Value("version")
+	if err != nil {
+		return 0, fmt.Errorf("failed to read version: %w", err)
+	}
+	return value.Int64(), nil
+}
+
+// poll polls the version until the watcher is closed. The pause doubles
+// after each failed poll up to maxPollBackoff.
+func (w *PollingWatcher) poll(version int64) {
+	defer close(w.done)
+	delay := w.interval
+	timer := time.NewTimer(delay)
+	defer timer.Stop()
+	for {
+		select {
+		case <-w.stop:
+			return
+		case <-timer.C:
+		}
+
+		current, err := w.version()
+		if err != nil {
+			delay = min(2*delay, max(maxPollBackoff, w.interval))
+			timer.Reset(delay)
+			continue
+		}
+		delay = w.interval
+		if current != version {
+			version = current
+			w.mu.RLock()
+			callback := w.callback
+			w.mu.RUnlock()
+			if callback != nil {
+				callback(strconv.FormatInt(version, 10))
+			}
+		}
+		timer.Reset(delay)
+	}
+}
+
+// SetUpdateCallback sets the function called when the version changed, it
+// receives the new version.
+func (w *PollingWatcher) SetUpdateCallback(callback func(string)) error {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	w.callback = callback
+	return nil
+}
+
+// Update does nothing, the changes written through the adapter bump the
+// version themselves.
+func (w *PollingWatcher) Update() error {
+	return nil
+}
+
+// Close stops polling and waits for the running callback to return.
+func (w *PollingWatcher) Close() {
+	w.closeOnce.Do(func() {
+		close(w.stop)
+	})
+	<-w.done
+}
@@ -0,0 +1,100 @@
+package adapter
+
+import (
+	"context"
+	"fmt"
+	"testing"
+	"time"
+
+	"github.com/casbin/casbin/v2"
+)
+
+// waitUpdate waits for an update of the watcher signaled on updates.
+func waitUpdate(t *testing.T, updates <-chan string) string {
+	t.Helper()
+	select {
+	case version := <-updates:
+		return version
+	case <-time.After(5 * time.Second):
+		t.Fatal("the watcher didn't see the change")
+		return ""
+	}
+}
+
+func TestPollingWatcher(t *testing.T) {
+	ctx := context.Background()
+	db := newSqliteDB(t)
+	a1, err := NewAdapter(ctx, "", "", db, WithVersionTable())
+	if err != nil {
+		t.Fatalf("failed to create adapter: %v", err)
+	}
+	a2, err := NewAdapter(ctx, "", "", db, WithVersionTable())
+	if err != nil {
+		t.Fatalf("failed to create adapter: %v", err)
+	}
+
+	w, err := NewPollingWatcher(a2, 10*time.Millisecond)
+	if err != nil {
+		t.Fatalf("NewPollingWatcher failed: %v", err)
+	}
+	defer w.Close()
+	e2, _ := casbin.NewEnforcer("examples/rbac_model.conf", a2)
+	if err := e2.SetWatcher(w); err != nil {
+		t.Fatalf("SetWatcher failed: %v", err)
+	}
+	updates := make(chan string, 10)
+	_ = w.SetUpdateCallback(func(version string) {
+		_ = e2.LoadPolicy()
+		updates <- version
+	})
+
+	if err := a1.AddPolicy("p", "p", []string{"alice", "data1", "read"}); err != nil {
+		t.Fatalf("AddPolicy failed: %v", err)
+	}
+	if version := waitUpdate(t, updates); version != "1" {
+		t.Errorf("version %s, supposed to be 1", version)
+	}
+	if ok, _ := e2.HasPolicy("alice", "data1", "read"); !ok {
+		t.Error("the second enforcer didn't load the added policy")
+	}
+
+	// A rolled back change doesn't bump the version.
+	err = a1.Transaction(ctx, func(tx *Adapter) error {
+		if err := tx.AddPolicy("p", "p", []string{"bob", "data2", "write"}); err != nil {
+			return err
+		}
+		return fmt.Errorf("rollback")
+	})
+	if err == nil {
+		t.Fatal("expected the transaction to fail")
+	}
+	if version, err := w.version(); err != nil || version != 1 {
+		t.Errorf("version %d, err: %v, supposed to be 1", version, err)
+	}
+
+	// The watcher survives failed polls while the version table is away.
+	moved := a1.versionTable + "_moved"
+	if _, err := db.Exec(ctx, fmt.Sprintf(sqliteRenameTableSql, a1.versionTable, moved)); err != nil {
+		t.Fatalf("failed to rename version table: %v", err)
+	}
+	time.Sleep(50 * time.Millisecond)
+	if _, err := db.Exec(ctx, fmt.Sprintf(sqliteRenameTableSql, moved, a1.versionTable)); err != nil {
+		t.Fatalf("failed to rename version table: %v", err)
+	}
+	if err := a1.RemovePolicy("p", "p", []string{"alice", "data1", "read"}); err != nil {
+		t.Fatalf("RemovePolicy failed: %v", err)
+	}
+	waitUpdate(t, updates)
+	if ok, _ := e2.HasPolicy("alice", "data1", "read"); ok {
+		t.Error("the second enforcer didn't load the removal")
+	}
+
+	w.Close()
+	w.Close()
+}
+
+func TestPollingWatcherRequiresVersionTable(t *testing.T) {
+	if _, err := NewPollingWatcher(newSqliteAdapter(t), time.Second); err == nil {
+		t.Error("expected an error without the version table")
+	}
+}
@@ -0,0 +1,62 @@
+package adapter
+
+import (
+	"context"
+	"sync/atomic"
+)
+
+// progressKey is the context key of the progress of an operation.
+type progressKey struct{}
+
+// opProgress counts the rules an operation processed for WithProgress.
+type opProgress struct {
+	fn    func(op string, done, total int64)
+	op    string
+	done  atomic.Int64
+	total atomic.Int64
+}
+
+// WithProgress sets a callback reporting the progress of the bulk
+// operations, called after each batch of rules written by SavePolicy,
+// AddPolicies, ImportCSV, ImportJSON, ImportPolicies and CopyTo, and after
+// each page read by LoadPolicy. done is the number of rules processed so far
+// and total the number the operation processes, -1 when unknown, as for
+// CopyTo and LoadPolicy. fn is called from the goroutine of the operation,
+// or from the writers of WithParallelWrites as their batches complete, and
+// a panic of fn doesn't affect the operation.
+func WithProgress(fn func(op string, done, total int64)) AdapterOption {
+	return AdapterOption{apply: func(a *Adapter) {
+		a.progress = fn
+	}}
+}
+
+// startProgress returns ctx reporting the progress of op processing total
+// rules, unless ctx already reports the progress of an operation.
+func (a *Adapter) startProgress(ctx context.Context, op string, total int64) context.Context {
+	if a.progress == nil || ctx.Value(progressKey{}) != nil {
+		return ctx
+	}
+	p := &opProgress{fn: a.progress, op: op}
+	p.total.Store(total)
+	return context.WithValue(ctx, progressKey{}, p)
+}
+
+// setProgressTotal sets the total of the operation of ctx, once known.
+func setProgressTotal(ctx context.Context, total int) {
+	if p, ok := ctx.Value(progressKey{}).(*opProgress); ok {
+		p.total.Store(int64(total))
+	}
+}
+
+// reportProgress reports that the operation of ctx processed n more rules.
+func reportProgress(ctx context.Context, n int) {
+	p, ok := ctx.Value(progressKey{}).(*opProgress)
+	if !ok || n == 0 {
+		return
+	}
+	done := p.done.Add(int64(n))
+	defer func() {
+		_ = recover()
+	}()
+	p.fn(p.op, done, p.total.Load())
+}
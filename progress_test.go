@@ -0,0 +1,111 @@
+package adapter
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"sync"
+	"testing"
+
+	"github.com/casbin/casbin/v2/model"
+)
+
+// progressCall is a call of the callback of WithProgress.
+type progressCall struct {
+	op          string
+	done, total int64
+}
+
+// progressCalls records the calls of the callback of WithProgress.
+type progressCalls struct {
+	mu    sync.Mutex
+	calls []progressCall
+}
+
+func (p *progressCalls) record(op string, done, total int64) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	p.calls = append(p.calls, progressCall{op, done, total})
+}
+
+// take returns the recorded calls and forgets them.
+func (p *progressCalls) take() []progressCall {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	calls := p.calls
+	p.calls = nil
+	return calls
+}
+
+// checkProgress checks that calls report the progress of op up to done of
+// total rules, in batches of size.
+func checkProgress(t *testing.T, calls []progressCall, op string, size, done, total int64) {
+	t.Helper()
+	if want := (done + size - 1) / size; int64(len(calls)) != want {
+		t.Fatalf("%s: %d progress calls %v, supposed to be %d", op, len(calls), calls, want)
+	}
+	var last int64
+	for _, call := range calls {
+		if call.op != op || call.total != total || call.done <= last {
+			t.Errorf("%s: progress call %+v after %d, supposed to be monotonic of total %d", op, call, last, total)
+		}
+		last = call.done
+	}
+	if last != done {
+		t.Errorf("%s: progress done %d, supposed to be %d", op, last, done)
+	}
+}
+
+func TestProgress(t *testing.T) {
+	ctx := context.Background()
+	progress := &progressCalls{}
+	a := newSqliteAdapter(t, WithBatchSize(10), WithPageSize(10), WithProgress(progress.record))
+
+	m, _ := model.NewModelFromFile("examples/rbac_model.conf")
+	for _, rule := range manyRules(25) {
+		m.AddPolicy("p", "p", rule)
+	}
+	if err := a.SavePolicy(m); err != nil {
+		t.Fatalf("SavePolicy failed: %v", err)
+	}
+	checkProgress(t, progress.take(), "SavePolicy", 10, 25, 25)
+
+	var rules [][]string
+	for i := 25; i < 40; i++ {
+		rules = append(rules, []string{fmt.Sprintf("user%d", i), "data2", "write"})
+	}
+	if err := a.AddPolicies("p", "p", rules); err != nil {
+		t.Fatalf("AddPolicies failed: %v", err)
+	}
+	checkProgress(t, progress.take(), "AddPolicies", 10, 15, 15)
+
+	// The total of an import skipping duplicates is the number of new rules.
+	csv := "p, user0, data1, read\np, user40, data1, read\np, user41, data1, read\n"
+	if _, err := a.ImportCSV(ctx, strings.NewReader(csv), ImportOptions{SkipDuplicates: true}); err != nil {
+		t.Fatalf("ImportCSV failed: %v", err)
+	}
+	checkProgress(t, progress.take(), "ImportCSV", 10, 2, 2)
+
+	if err := a.LoadPolicy(m); err != nil {
+		t.Fatalf("LoadPolicy failed: %v", err)
+	}
+	checkProgress(t, progress.take(), "LoadPolicy", 10, 42, -1)
+
+	dest := newSqliteAdapter(t, WithBatchSize(10))
+	if _, err := a.CopyTo(ctx, dest, nil, false); err != nil {
+		t.Fatalf("CopyTo failed: %v", err)
+	}
+	checkProgress(t, progress.take(), "CopyTo", 10, 42, -1)
+}
+
+func TestProgressPanic(t *testing.T) {
+	a := newSqliteAdapter(t, WithBatchSize(10), WithProgress(func(op string, done, total int64) {
+		panic("progress")
+	}))
+	if err := a.AddPolicies("p", "p", manyRules(25)); err != nil {
+		t.Fatalf("AddPolicies failed: %v", err)
+	}
+	if count, err := a.CountPolicies(context.Background(), nil); err != nil || count != 25 {
+		t.Errorf("CountPolicies = %d, err: %v, supposed to be 25", count, err)
+	}
+}
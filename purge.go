@@ -0,0 +1,142 @@
+package adapter
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sync"
+	"time"
+
+	"github.com/gogf/gf/v2/os/gtimer"
+)
+
+// defaultPurgeRetention is how long the soft deleted rows are kept by
+// default, so that the incremental loads see their removal.
+const defaultPurgeRetention = 7 * 24 * time.Hour
+
+// WithPurgeRetention sets how long PurgeExpired keeps the soft deleted rows
+// of WithChangeTracking, 7 days by default. LoadPolicyIncremental misses
+// the removals purged since the watermark it is given, so the retention
+// should exceed the time between the incremental loads.
+func WithPurgeRetention(retention time.Duration) AdapterOption {
+	return AdapterOption{apply: func(a *Adapter) {
+		if retention >= 0 {
+			a.purgeRetention = retention
+		}
+	}}
+}
+
+// PurgeExpired deletes the rows soft deleted longer than the purge
+// retention ago and returns their number. The rows are deleted in batches
+// of the batch size, one statement each, so that no pass holds its locks
+// long. The live rules are never touched, nor are the rows of the other
+// tenants. It does nothing without soft deletes.
+func (a *Adapter) PurgeExpired(ctx context.Context) (int64, error) {
+	if !a.softDelete {
+		return 0, nil
+	}
+
+	cutoff := a.now().Add(-a.purgeRetention)
+	var purged int64
+	size := a.idsSize()
+	for {
+		ids, err := a.modelCtx(ctx).Unscoped().
+			Fields("id").
+			WhereNotNull(deletedAtColumn).
+			WhereLT(deletedAtColumn, cutoff).
+			OrderAsc("id").
+			Limit(size).
+			Array()
+		if err != nil {
+			return purged, fmt.Errorf("failed to find expired rows: %w", err)
+		}
+		if len(ids) == 0 {
+			return purged, nil
+		}
+
+		result, err := a.modelCtx(ctx).Unscoped().WhereIn("id", ids).WhereNotNull(deletedAtColumn).Delete()
+		if err != nil {
+			return purged, fmt.Errorf("failed to purge expired rows: %w", err)
+		}
+		n, err := result.RowsAffected()
+		if err != nil {
+			return purged, fmt.Errorf("failed to purge expired rows: %w", err)
+		}
+		purged += n
+		if len(ids) < size {
+			return purged, nil
+		}
+	}
+}
+
+// Janitor purges the expired rows periodically, see StartJanitor.
+type Janitor struct {
+	adapter *Adapter
+	entry   *gtimer.Entry
+
+	// mu is held while a purge runs.
+	mu     sync.Mutex
+	closed bool
+
+	stop      chan struct{}
+	closeOnce sync.Once
+}
+
+// StartJanitor calls PurgeExpired every interval on a gtimer until ctx is
+// canceled or the returned Janitor is closed. A tick is skipped while the
+// previous purge is still running. The purged rows are counted in the logs
+// of WithLogger, or else of the database, along with the purge errors, and
+// the next tick tries again.
+func (a *Adapter) StartJanitor(ctx context.Context, interval time.Duration) (*Janitor, error) {
+	if interval <= 0 {
+		return nil, fmt.Errorf("invalid purge interval: %s", interval)
+	}
+	if !a.softDelete {
+		return nil, errors.New("janitor requires the deleted_at column of WithChangeTracking")
+	}
+
+	j := &Janitor{adapter: a, stop: make(chan struct{})}
+	j.entry = gtimer.AddSingleton(ctx, interval, j.tick)
+	go func() {
+		select {
+		case <-ctx.Done():
+			j.Close()
+		case <-j.stop:
+		}
+	}()
+	return j, nil
+}
+
+// tick runs a purge.
+func (j *Janitor) tick(ctx context.Context) {
+	j.mu.Lock()
+	defer j.mu.Unlock()
+	if j.closed {
+		return
+	}
+
+	a := j.adapter
+	purged, err := a.PurgeExpired(ctx)
+	logger := a.warnLogger()
+	if logger == nil {
+		return
+	}
+	if err != nil {
+		logger.Errorf(ctx, "casbin adapter: janitor: table=%s purged=%d error=%q", a.tableName, purged, err)
+		return
+	}
+	if purged > 0 {
+		logger.Infof(ctx, "casbin adapter: janitor: table=%s purged=%d", a.tableName, purged)
+	}
+}
+
+// Close stops the purges and waits for the running one to return.
+func (j *Janitor) Close() {
+	j.closeOnce.Do(func() {
+		j.entry.Close()
+		close(j.stop)
+	})
+	j.mu.Lock()
+	j.closed = true
+	j.mu.Unlock()
+}
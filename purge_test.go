@@ -0,0 +1,115 @@
+package adapter
+
+import (
+	"bytes"
+	"context"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/casbin/casbin/v2"
+)
+
+// ageDeletion moves the deletion of the soft deleted rows of v0 by age into
+// the past.
+func ageDeletion(t *testing.T, a *Adapter, v0 string, age time.Duration) {
+	t.Helper()
+	_, err := a.db.Model(a.tableName).Ctx(context.Background()).Unscoped().
+		Data(deletedAtColumn, time.Now().Add(-age)).
+		Where("v0", v0).
+		WhereNotNull(deletedAtColumn).
+		Update()
+	if err != nil {
+		t.Fatalf("failed to age deletion: %v", err)
+	}
+}
+
+// storedRowCount returns the number of rows of the policy table, soft
+// deleted ones included.
+func storedRowCount(t *testing.T, a *Adapter) int {
+	t.Helper()
+	count, err := a.db.Model(a.tableName).Ctx(context.Background()).Unscoped().Count()
+	if err != nil {
+		t.Fatalf("failed to count rows: %v", err)
+	}
+	return count
+}
+
+func TestPurgeExpired(t *testing.T) {
+	ctx := context.Background()
+	a := newSqliteAdapter(t, WithChangeTracking(), WithBatchSize(2), WithPurgeRetention(time.Hour))
+	rules := [][]string{{"alice", "data1", "read"}, {"bob", "data2", "write"}, {"carol", "data3", "read"}, {"dave", "data1", "read"}, {"erin", "data2", "read"}}
+	if err := a.AddPolicies("p", "p", rules); err != nil {
+		t.Fatalf("AddPolicies failed: %v", err)
+	}
+	if err := a.RemovePolicies("p", "p", rules[:4]); err != nil {
+		t.Fatalf("RemovePolicies failed: %v", err)
+	}
+
+	// The recent deletions are kept.
+	if purged, err := a.PurgeExpired(ctx); err != nil || purged != 0 {
+		t.Errorf("purged %d rows, err: %v, supposed to be 0", purged, err)
+	}
+
+	// The aged deletions are purged over several batches, the live rules
+	// and the recent deletion stay. A live row with an aged update isn't
+	// purged.
+	for _, v0 := range []string{"alice", "bob", "carol"} {
+		ageDeletion(t, a, v0, 2*time.Hour)
+	}
+	_, err := a.db.Model(a.tableName).Ctx(ctx).Data(updatedAtColumn, time.Now().Add(-48*time.Hour)).Where("v0", "erin").Update()
+	if err != nil {
+		t.Fatalf("failed to age update: %v", err)
+	}
+	if purged, err := a.PurgeExpired(ctx); err != nil || purged != 3 {
+		t.Errorf("purged %d rows, err: %v, supposed to be 3", purged, err)
+	}
+	if n := storedRowCount(t, a); n != 2 {
+		t.Errorf("%d rows left, supposed to be 2", n)
+	}
+	e, _ := casbin.NewEnforcer("examples/rbac_model.conf", a)
+	testGetPolicy(t, e, [][]string{{"erin", "data2", "read"}})
+
+	// Without soft deletes there is nothing to purge.
+	if purged, err := newSqliteAdapter(t).PurgeExpired(ctx); err != nil || purged != 0 {
+		t.Errorf("purged %d rows without soft deletes, err: %v, supposed to be 0", purged, err)
+	}
+}
+
+func TestJanitor(t *testing.T) {
+	var buf bytes.Buffer
+	a := newSqliteAdapter(t, WithChangeTracking(), WithLogger(newBufferLogger(&buf)))
+	if err := a.AddPolicies("p", "p", [][]string{{"alice", "data1", "read"}, {"bob", "data2", "write"}}); err != nil {
+		t.Fatalf("AddPolicies failed: %v", err)
+	}
+	if err := a.RemovePolicy("p", "p", []string{"alice", "data1", "read"}); err != nil {
+		t.Fatalf("RemovePolicy failed: %v", err)
+	}
+	ageDeletion(t, a, "alice", 30*24*time.Hour)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	j, err := a.StartJanitor(ctx, 20*time.Millisecond)
+	if err != nil {
+		t.Fatalf("StartJanitor failed: %v", err)
+	}
+	deadline := time.Now().Add(5 * time.Second)
+	for storedRowCount(t, a) != 1 {
+		if time.Now().After(deadline) {
+			t.Fatal("the janitor didn't purge the expired row")
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+	j.Close()
+	j.Close()
+	if !strings.Contains(buf.String(), "purged=1") {
+		t.Errorf("the purge wasn't logged: %s", buf.String())
+	}
+
+	if _, err := newSqliteAdapter(t).StartJanitor(ctx, time.Second); err == nil {
+		t.Error("expected an error without soft deletes")
+	}
+	if _, err := a.StartJanitor(ctx, 0); err == nil {
+		t.Error("expected an error for a zero interval")
+	}
+}
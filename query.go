@@ -0,0 +1,413 @@
+package adapter
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sort"
+
+	"github.com/gogf/gf/v2/database/gdb"
+)
+
+const (
+	// defaultPDomainIndex is the position of the domain in p rules of the
+	// rbac_with_domains model: p = sub, dom, obj, act.
+	defaultPDomainIndex = 1
+	// defaultGDomainIndex is the position of the domain in g rules of the
+	// rbac_with_domains model: g = _, _, _.
+	defaultGDomainIndex = 2
+
+	// maxListPageSize caps the page size of ListPolicies.
+	maxListPageSize = 1000
+)
+
+// WithDomainFieldIndex sets the positions of the domain in p and g rules,
+// used by the domain aware helpers such as GetAllDomains. The defaults match
+// casbin's rbac_with_domains model, v1 for p rules and v2 for g rules.
+func WithDomainFieldIndex(pIndex, gIndex int) AdapterOption {
+	return AdapterOption{apply: func(a *Adapter) {
+		a.pDomainIndex = pIndex
+		a.gDomainIndex = gIndex
+	}}
+}
+
+// FilterByDomain returns the filter of the p and g rules of the domains,
+// located by the domain field indexes of WithDomainFieldIndex, to pass to
+// LoadFilteredPolicy. Both policy types are read by one query.
+func FilterByDomain(domains ...string) Filter {
+	return Filter{domains: domains}
+}
+
+// domainCondition returns the condition matching the p and g rules of the
+// domains.
+func (a *Adapter) domainCondition(domains []string) (string, []interface{}, error) {
+	pDomains, err := a.encodeValues(a.normalizeValues(a.pDomainIndex, domains))
+	if err != nil {
+		return "", nil, err
+	}
+	gDomains, err := a.encodeValues(a.normalizeValues(a.gDomainIndex, domains))
+	if err != nil {
+		return "", nil, err
+	}
+	condition := fmt.Sprintf("(%s = ? AND %s IN(?)) OR (%s = ? AND %s IN(?))",
+		a.pTypeColumn, valueColumns[a.pDomainIndex], a.pTypeColumn, valueColumns[a.gDomainIndex])
+	return "(" + condition + ")", []interface{}{"p", pDomains, "g", gDomains}, nil
+}
+
+// GetAllDomains returns every domain used by the stored p and g rules,
+// sorted alphabetically. Unlike the enforcer it reads the database, so the
+// result is complete even when only a filtered policy is loaded.
+func (a *Adapter) GetAllDomains(ctx context.Context) ([]string, error) {
+	pDomains, err := a.distinctValues(ctx, "p", a.pDomainIndex, 0)
+	if err != nil {
+		return nil, err
+	}
+	gDomains, err := a.distinctValues(ctx, "g", a.gDomainIndex, 0)
+	if err != nil {
+		return nil, err
+	}
+
+	seen := make(map[string]struct{}, len(pDomains)+len(gDomains))
+	domains := make([]string, 0, len(pDomains)+len(gDomains))
+	for _, domain := range append(pDomains, gDomains...) {
+		if _, ok := seen[domain]; ok {
+			continue
+		}
+		seen[domain] = struct{}{}
+		domains = append(domains, domain)
+	}
+	sort.Strings(domains)
+
+	return domains, nil
+}
+
+// GetDistinctValues returns the distinct non-empty values stored at
+// fieldIndex of the rules of pType, sorted alphabetically. A positive limit
+// caps the number of returned values.
+func (a *Adapter) GetDistinctValues(ctx context.Context, pType string, fieldIndex int, limit int) ([]string, error) {
+	if !isValidFieldIndex(fieldIndex) {
+		return nil, fmt.Errorf("invalid field index: %d", fieldIndex)
+	}
+	return a.distinctValues(ctx, pType, fieldIndex, limit)
+}
+
+// GetAllSubjects returns the distinct subjects (v0) of the stored p rules.
+func (a *Adapter) GetAllSubjects(ctx context.Context) ([]string, error) {
+	return a.GetDistinctValues(ctx, "p", 0, 0)
+}
+
+// GetAllObjects returns the distinct objects (v1) of the stored p rules.
+func (a *Adapter) GetAllObjects(ctx context.Context) ([]string, error) {
+	return a.GetDistinctValues(ctx, "p", 1, 0)
+}
+
+// GetAllActions returns the distinct actions (v2) of the stored p rules.
+func (a *Adapter) GetAllActions(ctx context.Context) ([]string, error) {
+	return a.GetDistinctValues(ctx, "p", 2, 0)
+}
+
+// distinctValues returns the distinct non-empty values stored at fieldIndex
+// of the rules of pType, sorted alphabetically. The encoded values are
+// sorted and limited once decoded.
+func (a *Adapter) distinctValues(ctx context.Context, pType string, fieldIndex int, limit int) ([]string, error) {
+	if err := a.checkUnpacked(); err != nil {
+		return nil, err
+	}
+	column := fieldColumn(fieldIndex)
+	query := a.tableFor(pType).modelCtx(ctx).
+		Fields(column).
+		Distinct().
+		Where(a.pTypeColumn, pType).
+		WhereNot(column, "")
+	if a.codec == nil {
+		query = query.OrderAsc(column)
+		if limit > 0 {
+			query = query.Limit(limit)
+		}
+	}
+
+	values, err := query.Array()
+	if err != nil {
+		return nil, fmt.Errorf("failed to query distinct values of %s: %w", column, err)
+	}
+
+	res := make([]string, 0, len(values))
+	for _, value := range values {
+		res = append(res, value.String())
+	}
+	if a.codec != nil {
+		sort.Strings(res)
+		if limit > 0 && len(res) > limit {
+			res = res[:limit]
+		}
+	}
+	return res, nil
+}
+
+// GetAllPolicies returns the stored rules, p and g rules alike, in the order
+// they were added. Unlike the enforcer's policy the rules keep their empty
+// values. A non-nil filter restricts the rules like LoadFilteredPolicy does.
+func (a *Adapter) GetAllPolicies(ctx context.Context, filter *Filter) ([]Rule, error) {
+	if a.routed() {
+		var rules []Rule
+		for _, table := range a.splitTables() {
+			tableRules, err := table.GetAllPolicies(ctx, filter)
+			if err != nil {
+				return nil, err
+			}
+			rules = append(rules, tableRules...)
+		}
+		return rules, nil
+	}
+	if a.packed() && filter != nil {
+		return a.GetFilteredPolicies(ctx, *filter)
+	}
+	query := a.modelCtx(ctx)
+	if filter != nil {
+		var err error
+		if query, err = a.filterQuery(query, *filter); err != nil {
+			return nil, err
+		}
+	}
+
+	var rules []Rule
+	err := a.scanPages(query, func(rows []ruleRow) error {
+		for _, row := range rows {
+			rules = append(rules, row.Rule)
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to scan policy rules: %w", err)
+	}
+	return rules, nil
+}
+
+// GetFilteredPolicies returns the stored rules matching filter, any filter
+// of LoadFilteredPolicy, in the order they were added. The rules keep their
+// empty values like those of GetAllPolicies.
+func (a *Adapter) GetFilteredPolicies(ctx context.Context, filter interface{}) ([]Rule, error) {
+	filters, err := toFilters(filter)
+	if err != nil {
+		return nil, err
+	}
+	if filters == nil {
+		return a.GetAllPolicies(ctx, nil)
+	}
+
+	rows, err := a.filteredRows(ctx, filters)
+	if err != nil {
+		return nil, err
+	}
+	rules := make([]Rule, len(rows))
+	for i, row := range rows {
+		rules[i] = row.Rule
+	}
+	return rules, nil
+}
+
+// CountPolicies returns the number of stored rules matching filter, any
+// filter of LoadFilteredPolicy, or of all the stored rules when filter is
+// nil. A paged filter counts the rules of its page.
+func (a *Adapter) CountPolicies(ctx context.Context, filter interface{}) (int64, error) {
+	filters, err := toFilters(filter)
+	if err != nil {
+		return 0, err
+	}
+	if a.routed() {
+		return a.splitCount(ctx, filters)
+	}
+	if a.packed() && filters != nil {
+		rows, err := a.packedRows(ctx, filters)
+		return int64(len(rows)), err
+	}
+	page, err := pageOf(filters)
+	if err != nil {
+		return 0, err
+	}
+
+	var chunks []Filter
+	for _, filter := range filters {
+		chunks = append(chunks, a.filterChunks(filter)...)
+	}
+	if len(chunks) <= 1 {
+		query := a.modelCtx(ctx)
+		if len(chunks) == 1 {
+			if query, err = a.filterQuery(query, chunks[0]); err != nil {
+				return 0, err
+			}
+		}
+		count, err := query.Count()
+		if err != nil {
+			return 0, fmt.Errorf("failed to count policy rules: %w", err)
+		}
+		return page.count(int64(count)), nil
+	}
+
+	// The rules matching several chunks are counted once.
+	ids := make(map[int64]struct{})
+	for _, chunk := range chunks {
+		query, err := a.filterQuery(a.modelCtx(ctx), chunk)
+		if err != nil {
+			return 0, err
+		}
+		values, err := query.Array("id")
+		if err != nil {
+			return 0, fmt.Errorf("failed to count policy rules: %w", err)
+		}
+		for _, value := range values {
+			ids[value.Int64()] = struct{}{}
+		}
+	}
+	return page.count(int64(len(ids))), nil
+}
+
+// ListPolicies returns the page of the stored rules matching filter,
+// counting pages from 1, along with the number of the matching rules. The
+// rules are ordered by orderBy, id or created_at, backwards when desc is
+// true, the ties by id. pageSize is capped at 1000. filter can't page the
+// rules itself. With WithSplitTables or WithTableRouting the pages aren't
+// merged across tables: filter must select by PType the policy types of a
+// single table, otherwise ErrInvalidFilter is returned.
+func (a *Adapter) ListPolicies(ctx context.Context, filter Filter, page, pageSize int, orderBy string, desc bool) (rows []Rule, total int64, err error) {
+	if filter.Limit != 0 || filter.Offset != 0 || filter.OrderBy != "" {
+		return nil, 0, fmt.Errorf("%w: ListPolicies pages the rules itself", ErrInvalidFilter)
+	}
+	if page < 1 || pageSize < 1 {
+		return nil, 0, fmt.Errorf("invalid page %d of size %d", page, pageSize)
+	}
+	if orderBy != "id" && orderBy != createdAtColumn {
+		return nil, 0, fmt.Errorf("invalid order %q, supposed to be id or created_at", orderBy)
+	}
+	pageSize = min(pageSize, maxListPageSize)
+
+	if total, err = a.CountPolicies(ctx, filter); err != nil {
+		return nil, 0, err
+	}
+	filter.Limit, filter.Offset, filter.OrderBy = pageSize, (page-1)*pageSize, orderBy
+	if desc {
+		filter.OrderBy += " desc"
+	}
+	if rows, err = a.GetFilteredPolicies(ctx, filter); err != nil {
+		return nil, 0, err
+	}
+	return rows, total, nil
+}
+
+// GetRolesForUser returns the roles directly assigned to user by the stored
+// g rules, in the order they were granted. When a domain is given only the
+// grants in that domain are returned. Roles inherited through other roles
+// are not included, and no model needs to be loaded.
+func (a *Adapter) GetRolesForUser(ctx context.Context, user string, domain ...string) ([]string, error) {
+	query, err := a.groupingModel(ctx, domain)
+	if err != nil {
+		return nil, err
+	}
+	if query, err = a.fieldQuery(query, 0, user); err != nil {
+		return nil, err
+	}
+	return a.distinctByID(query, Columns.V1)
+}
+
+// GetUsersForRole returns the users directly assigned to role by the stored
+// g rules, in the order they were granted. When a domain is given only the
+// grants in that domain are returned. It reads the database, so the result
+// is complete even when the enforcer has only a filtered policy loaded.
+func (a *Adapter) GetUsersForRole(ctx context.Context, role string, domain ...string) ([]string, error) {
+	query, err := a.groupingModel(ctx, domain)
+	if err != nil {
+		return nil, err
+	}
+	if query, err = a.fieldQuery(query, 1, role); err != nil {
+		return nil, err
+	}
+	return a.distinctByID(query, Columns.V0)
+}
+
+// GetUsersForRolePage is like GetUsersForRole but returns at most limit
+// users, skipping the first offset ones, for roles with many members.
+func (a *Adapter) GetUsersForRolePage(ctx context.Context, role string, limit, offset int, domain ...string) ([]string, error) {
+	if limit <= 0 || offset < 0 {
+		return nil, fmt.Errorf("invalid page: limit=%d, offset=%d", limit, offset)
+	}
+
+	query, err := a.groupingModel(ctx, domain)
+	if err != nil {
+		return nil, err
+	}
+	if query, err = a.fieldQuery(query, 1, role); err != nil {
+		return nil, err
+	}
+	return a.distinctByID(query.Limit(offset, limit), Columns.V0)
+}
+
+// GetPermissionsForUser returns the p rules whose subject is subject, in
+// the order they were added. When a domain is given only the rules of that
+// domain are returned. It saves a full LoadPolicy when only the explicit
+// permissions of one subject are needed.
+func (a *Adapter) GetPermissionsForUser(ctx context.Context, subject string, domain ...string) ([][]string, error) {
+	if err := a.checkUnpacked(); err != nil {
+		return nil, err
+	}
+	query, err := a.fieldQuery(a.tableFor("p").modelCtx(ctx).Where(a.pTypeColumn, "p"), 0, subject)
+	if err != nil {
+		return nil, err
+	}
+	switch len(domain) {
+	case 0:
+	case 1:
+		if query, err = a.fieldQuery(query, a.pDomainIndex, domain[0]); err != nil {
+			return nil, err
+		}
+	default:
+		return nil, errors.New("domain should be 1 parameter")
+	}
+
+	var rules []Rule
+	if err := query.OrderAsc("id").Scan(&rules); err != nil {
+		return nil, fmt.Errorf("failed to scan permissions: %w", err)
+	}
+
+	permissions := make([][]string, 0, len(rules))
+	for _, rule := range rules {
+		permissions = append(permissions, rule.toSlice())
+	}
+	return permissions, nil
+}
+
+// groupingModel returns a model of the g rules, restricted to the domain
+// when one is given.
+func (a *Adapter) groupingModel(ctx context.Context, domain []string) (*gdb.Model, error) {
+	if err := a.checkUnpacked(); err != nil {
+		return nil, err
+	}
+	query := a.tableFor("g").modelCtx(ctx).Where(a.pTypeColumn, "g")
+	switch len(domain) {
+	case 0:
+	case 1:
+		return a.fieldQuery(query, a.gDomainIndex, domain[0])
+	default:
+		return nil, errors.New("domain should be 1 parameter")
+	}
+	return query, nil
+}
+
+// distinctByID returns the distinct non-empty values of column selected by
+// query, ordered by the id of their first occurrence.
+func (a *Adapter) distinctByID(query *gdb.Model, column string) ([]string, error) {
+	values, err := query.
+		Fields(column).
+		WhereNot(column, "").
+		Group(column).
+		Order("MIN(id)").
+		Array()
+	if err != nil {
+		return nil, fmt.Errorf("failed to query distinct values of %s: %w", column, err)
+	}
+
+	res := make([]string, 0, len(values))
+	for _, value := range values {
+		res = append(res, value.String())
+	}
+	return res, nil
+}
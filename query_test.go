@@ -0,0 +1,489 @@
+package adapter
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"reflect"
+	"testing"
+	"time"
+
+	"github.com/casbin/casbin/v2"
+)
+
+// seedPolicy replaces the stored policy with the rules of a policy file.
+func seedPolicy(t *testing.T, a *Adapter, modelPath, policyPath string) {
+	t.Helper()
+	e, err := casbin.NewEnforcer(modelPath, policyPath)
+	if err != nil {
+		t.Fatalf("failed to load policy file: %v", err)
+	}
+	if err := a.SavePolicy(e.GetModel()); err != nil {
+		t.Fatalf("failed to save policy: %v", err)
+	}
+}
+
+func TestGetAllDomains(t *testing.T) {
+	a := newSqliteAdapter(t)
+	seedPolicy(t, a, "examples/rbac_with_domains_model.conf", "examples/rbac_with_domains_policy.csv")
+	// A grouping rule in a domain without any permission, and one without a domain.
+	if err := a.AddPolicies("g", "g", [][]string{{"carol", "admin", "domain0"}, {"dave", "admin"}}); err != nil {
+		t.Fatalf("AddPolicies failed: %v", err)
+	}
+
+	domains, err := a.GetAllDomains(context.Background())
+	if err != nil {
+		t.Fatalf("GetAllDomains failed: %v", err)
+	}
+	want := []string{"domain0", "domain1", "domain2", "domain3"}
+	if !reflect.DeepEqual(domains, want) {
+		t.Errorf("domains = %v, supposed to be %v", domains, want)
+	}
+}
+
+func TestGetAllDomainsCustomFieldIndex(t *testing.T) {
+	a := newSqliteAdapter(t, WithDomainFieldIndex(0, 0))
+	if err := a.AddPolicies("p", "p", [][]string{{"tenant2", "alice", "data1", "read"}, {"tenant1", "bob", "data2", "read"}}); err != nil {
+		t.Fatalf("AddPolicies failed: %v", err)
+	}
+	if err := a.AddPolicy("g", "g", []string{"tenant1", "alice", "admin"}); err != nil {
+		t.Fatalf("AddPolicy failed: %v", err)
+	}
+
+	domains, err := a.GetAllDomains(context.Background())
+	if err != nil {
+		t.Fatalf("GetAllDomains failed: %v", err)
+	}
+	want := []string{"tenant1", "tenant2"}
+	if !reflect.DeepEqual(domains, want) {
+		t.Errorf("domains = %v, supposed to be %v", domains, want)
+	}
+}
+
+func TestInvalidDomainFieldIndex(t *testing.T) {
+	a := newSqliteAdapter(t)
+	if _, err := NewAdapter(a.ctx, "", "", a.db, WithDomainFieldIndex(1, 6)); err == nil {
+		t.Error("expected an error for an invalid domain field index")
+	}
+}
+
+func TestGetRolesForUser(t *testing.T) {
+	a := newSqliteAdapter(t)
+	seedPolicy(t, a, "examples/rbac_with_domains_model.conf", "examples/rbac_with_domains_policy.csv")
+	// alice is granted admin a second time in another domain.
+	if err := a.AddPolicy("g", "g", []string{"alice", "admin", "domain2"}); err != nil {
+		t.Fatalf("AddPolicy failed: %v", err)
+	}
+
+	tests := []struct {
+		name   string
+		user   string
+		domain []string
+		want   []string
+	}{
+		{"all domains", "alice", nil, []string{"admin", "auditor"}},
+		{"one domain", "alice", []string{"domain3"}, []string{"auditor"}},
+		{"domain without grants", "bob", []string{"domain1"}, []string{}},
+		{"user without roles", "nobody", nil, []string{}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			roles, err := a.GetRolesForUser(context.Background(), tt.user, tt.domain...)
+			if err != nil {
+				t.Fatalf("GetRolesForUser failed: %v", err)
+			}
+			if !reflect.DeepEqual(roles, tt.want) {
+				t.Errorf("roles = %v, supposed to be %v", roles, tt.want)
+			}
+		})
+	}
+
+	if _, err := a.GetRolesForUser(context.Background(), "alice", "domain1", "domain2"); err == nil {
+		t.Error("expected an error for more than one domain")
+	}
+}
+
+func TestGetRolesForUserWithoutDomains(t *testing.T) {
+	a := newSqliteAdapter(t)
+	seedPolicy(t, a, "examples/rbac_model.conf", "examples/rbac_policy.csv")
+
+	roles, err := a.GetRolesForUser(context.Background(), "alice")
+	if err != nil {
+		t.Fatalf("GetRolesForUser failed: %v", err)
+	}
+	if want := []string{"data2_admin"}; !reflect.DeepEqual(roles, want) {
+		t.Errorf("roles = %v, supposed to be %v", roles, want)
+	}
+}
+
+func TestGetUsersForRole(t *testing.T) {
+	a := newSqliteAdapter(t)
+	seedPolicy(t, a, "examples/rbac_with_domains_model.conf", "examples/rbac_with_domains_policy.csv")
+
+	tests := []struct {
+		name   string
+		role   string
+		domain []string
+		want   []string
+	}{
+		{"all domains", "admin", nil, []string{"alice", "bob"}},
+		{"one domain", "admin", []string{"domain2"}, []string{"bob"}},
+		{"role without members", "nobody", nil, []string{}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			users, err := a.GetUsersForRole(context.Background(), tt.role, tt.domain...)
+			if err != nil {
+				t.Fatalf("GetUsersForRole failed: %v", err)
+			}
+			if !reflect.DeepEqual(users, tt.want) {
+				t.Errorf("users = %v, supposed to be %v", users, tt.want)
+			}
+		})
+	}
+}
+
+func TestGetUsersForRoleLargeMembership(t *testing.T) {
+	a := newSqliteAdapter(t)
+
+	const members = 2500
+	rules := make([][]string, 0, members)
+	want := make([]string, 0, members)
+	for i := 0; i < members; i++ {
+		user := fmt.Sprintf("user%04d", members-i)
+		rules = append(rules, []string{user, "auditor", "domain1"})
+		want = append(want, user)
+	}
+	// Duplicated grants and grants of other roles must not show up.
+	rules = append(rules, []string{"user0001", "auditor", "domain2"}, []string{"someone", "admin", "domain1"})
+	if err := a.AddPolicies("g", "g", rules); err != nil {
+		t.Fatalf("AddPolicies failed: %v", err)
+	}
+
+	users, err := a.GetUsersForRole(context.Background(), "auditor")
+	if err != nil {
+		t.Fatalf("GetUsersForRole failed: %v", err)
+	}
+	if !reflect.DeepEqual(users, want) {
+		t.Errorf("got %d users, supposed to be %d users in grant order", len(users), len(want))
+	}
+
+	var paged []string
+	for offset := 0; ; offset += 1000 {
+		page, err := a.GetUsersForRolePage(context.Background(), "auditor", 1000, offset, "domain1")
+		if err != nil {
+			t.Fatalf("GetUsersForRolePage failed: %v", err)
+		}
+		if len(page) == 0 {
+			break
+		}
+		paged = append(paged, page...)
+	}
+	if !reflect.DeepEqual(paged, want) {
+		t.Errorf("got %d paged users, supposed to be %d users in grant order", len(paged), len(want))
+	}
+
+	if _, err := a.GetUsersForRolePage(context.Background(), "auditor", 0, 0); err == nil {
+		t.Error("expected an error for an empty page")
+	}
+}
+
+func TestGetPermissionsForUser(t *testing.T) {
+	a := newSqliteAdapter(t)
+	seedPolicy(t, a, "examples/rbac_with_domains_model.conf", "examples/rbac_with_domains_policy.csv")
+
+	e, err := casbin.NewEnforcer("examples/rbac_with_domains_model.conf", a)
+	if err != nil {
+		t.Fatalf("NewEnforcer failed: %v", err)
+	}
+
+	tests := []struct {
+		name        string
+		subject     string
+		domain      []string
+		fieldValues []string
+	}{
+		{"all domains", "admin", nil, []string{"admin"}},
+		{"one domain", "admin", []string{"domain2"}, []string{"admin", "domain2"}},
+		{"other subject", "auditor", nil, []string{"auditor"}},
+		{"subject without permissions", "alice", nil, []string{"alice"}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			permissions, err := a.GetPermissionsForUser(context.Background(), tt.subject, tt.domain...)
+			if err != nil {
+				t.Fatalf("GetPermissionsForUser failed: %v", err)
+			}
+			want, err := e.GetFilteredPolicy(0, tt.fieldValues...)
+			if err != nil {
+				t.Fatalf("GetFilteredPolicy failed: %v", err)
+			}
+			if len(want) == 0 {
+				want = [][]string{}
+			}
+			if !reflect.DeepEqual(permissions, want) {
+				t.Errorf("permissions = %v, supposed to be %v", permissions, want)
+			}
+		})
+	}
+
+	if _, err := a.GetPermissionsForUser(context.Background(), "admin", "domain1", "domain2"); err == nil {
+		t.Error("expected an error for more than one domain")
+	}
+
+	// The p rules of routed tables are read from their table.
+	routed, err := NewAdapter(context.Background(), "", "", newSqliteDB(t), WithTableRouting(map[string]string{"p": "pp", "g": "gg"}))
+	if err != nil {
+		t.Fatalf("NewAdapter failed: %v", err)
+	}
+	if err := routed.AddPolicy("p", "p", []string{"alice", "data1", "read"}); err != nil {
+		t.Fatalf("AddPolicy failed: %v", err)
+	}
+	permissions, err := routed.GetPermissionsForUser(context.Background(), "alice")
+	if err != nil {
+		t.Fatalf("GetPermissionsForUser failed: %v", err)
+	}
+	if want := [][]string{{"alice", "data1", "read"}}; !reflect.DeepEqual(permissions, want) {
+		t.Errorf("permissions = %v, supposed to be %v", permissions, want)
+	}
+}
+
+func TestGetDistinctValues(t *testing.T) {
+	a := newSqliteAdapter(t)
+	seedPolicy(t, a, "examples/rbac_model.conf", "examples/rbac_policy.csv")
+	// Overlapping rules and a rule with fewer values.
+	if err := a.AddPolicies("p", "p", [][]string{{"bob", "data1", "read"}, {"alice", "data2", "read"}, {"carol", "data3"}}); err != nil {
+		t.Fatalf("AddPolicies failed: %v", err)
+	}
+	ctx := context.Background()
+
+	tests := []struct {
+		name string
+		get  func() ([]string, error)
+		want []string
+	}{
+		{"subjects", func() ([]string, error) { return a.GetAllSubjects(ctx) }, []string{"alice", "bob", "carol", "data2_admin"}},
+		{"objects", func() ([]string, error) { return a.GetAllObjects(ctx) }, []string{"data1", "data2", "data3"}},
+		{"actions", func() ([]string, error) { return a.GetAllActions(ctx) }, []string{"read", "write"}},
+		{"limit", func() ([]string, error) { return a.GetDistinctValues(ctx, "p", 0, 2) }, []string{"alice", "bob"}},
+		{"grouping rules", func() ([]string, error) { return a.GetDistinctValues(ctx, "g", 1, 0) }, []string{"data2_admin"}},
+		{"unused field", func() ([]string, error) { return a.GetDistinctValues(ctx, "p", 5, 0) }, []string{}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			values, err := tt.get()
+			if err != nil {
+				t.Fatalf("failed to get distinct values: %v", err)
+			}
+			if !reflect.DeepEqual(values, tt.want) {
+				t.Errorf("values = %v, supposed to be %v", values, tt.want)
+			}
+		})
+	}
+
+	for _, fieldIndex := range []int{-1, 6} {
+		if _, err := a.GetDistinctValues(ctx, "p", fieldIndex, 0); err == nil {
+			t.Errorf("expected an error for field index %d", fieldIndex)
+		}
+	}
+}
+
+func TestGetFilteredPolicies(t *testing.T) {
+	ctx := context.Background()
+	a := newSqliteAdapter(t, WithFilterChunkSize(1))
+	initPolicy(t, a)
+
+	filters := []Filter{{V0: []string{"alice", "bob"}}, {PType: []string{"p"}, V1: []string{"data1"}}}
+	rules, err := a.GetFilteredPolicies(ctx, filters)
+	if err != nil {
+		t.Fatalf("GetFilteredPolicies failed: %v", err)
+	}
+	want := []Rule{
+		{PType: "p", V0: "alice", V1: "data1", V2: "read"},
+		{PType: "p", V0: "bob", V1: "data2", V2: "write"},
+		{PType: "g", V0: "alice", V1: "data2_admin"},
+	}
+	if !reflect.DeepEqual(rules, want) {
+		t.Errorf("rules %v, supposed to be %v", rules, want)
+	}
+
+	// The value lists split in chunks and the filters overlap.
+	count, err := a.CountPolicies(ctx, filters)
+	if err != nil || count != 3 {
+		t.Errorf("count %d, err: %v, supposed to be 3", count, err)
+	}
+	if count, err := a.CountPolicies(ctx, Filter{PType: []string{"p"}}); err != nil || count != 4 {
+		t.Errorf("count of p rules %d, err: %v, supposed to be 4", count, err)
+	}
+	if count, err := a.CountPolicies(ctx, nil); err != nil || count != 5 {
+		t.Errorf("count of all rules %d, err: %v, supposed to be 5", count, err)
+	}
+}
+
+func TestGetFilteredPoliciesPaged(t *testing.T) {
+	ctx := context.Background()
+	a := newSqliteAdapter(t, WithFilterChunkSize(4))
+	seedRules(t, a, 30)
+
+	var users []string
+	for i := 0; i < 30; i += 3 {
+		users = append(users, fmt.Sprintf("user%d", i))
+	}
+	tests := []struct {
+		name    string
+		filters []Filter
+		order   string
+		want    []string
+	}{
+		// A single query, paged by the database.
+		{"id", []Filter{{V1: []string{"data1", "data2"}}}, "", []string{"user1", "user2", "user11", "user12", "user21", "user22"}},
+		// Several queries merged before paging.
+		{"chunks", []Filter{{V0: users}, {V1: []string{"data1"}}}, "created_at DESC", []string{
+			"user27", "user24", "user21", "user18", "user15", "user12", "user11", "user9", "user6", "user3", "user1", "user0",
+		}},
+	}
+	for _, tt := range tests {
+		var got []string
+		for offset := 0; ; offset += 5 {
+			page := tt.filters[0]
+			page.Limit, page.Offset, page.OrderBy = 5, offset, tt.order
+			filters := append([]Filter{page}, tt.filters[1:]...)
+			rules, err := a.GetFilteredPolicies(ctx, filters)
+			if err != nil {
+				t.Fatalf("%s: GetFilteredPolicies failed: %v", tt.name, err)
+			}
+			count, err := a.CountPolicies(ctx, filters)
+			if err != nil || count != int64(len(rules)) {
+				t.Errorf("%s: count %d, err: %v, supposed to be %d", tt.name, count, err, len(rules))
+			}
+			for _, rule := range rules {
+				got = append(got, rule.V0)
+			}
+			if len(rules) < 5 {
+				break
+			}
+		}
+		if !reflect.DeepEqual(got, tt.want) {
+			t.Errorf("%s: pages of %v, supposed to be %v", tt.name, got, tt.want)
+		}
+	}
+
+	e, _ := casbin.NewEnforcer("examples/rbac_model.conf", a)
+	if err := e.LoadFilteredPolicy(Filter{V2: []string{"read"}, Limit: 3}); err != nil {
+		t.Fatalf("LoadFilteredPolicy failed: %v", err)
+	}
+	testGetPolicy(t, e, [][]string{{"user0", "data0", "read"}, {"user1", "data1", "read"}, {"user2", "data2", "read"}})
+	if !a.IsFiltered() {
+		t.Error("the adapter isn't filtered after a limited load")
+	}
+
+	for _, filters := range [][]Filter{
+		{{OrderBy: "v0"}},
+		{{OrderBy: "id sideways"}},
+		{{Limit: -1}},
+		{{}, {Limit: 1}},
+	} {
+		if _, err := a.GetFilteredPolicies(ctx, filters); err == nil {
+			t.Errorf("GetFilteredPolicies of %+v succeeded", filters)
+		}
+	}
+}
+
+func TestFilterCreatedWindow(t *testing.T) {
+	ctx := context.Background()
+	a := newSqliteAdapter(t)
+	now := time.Now().Truncate(time.Second)
+	ages := map[string]time.Duration{"alice": 10 * 24 * time.Hour, "bob": 5 * 24 * time.Hour, "carol": 2 * 24 * time.Hour, "dave": time.Hour}
+	for _, user := range []string{"alice", "bob", "carol", "dave"} {
+		if err := a.AddPolicy("p", "p", []string{user, "data1", "read"}); err != nil {
+			t.Fatalf("AddPolicy failed: %v", err)
+		}
+		_, err := a.db.Model(a.tableName).Ctx(ctx).Data(createdAtColumn, now.Add(-ages[user])).Where("v0", user).Update()
+		if err != nil {
+			t.Fatalf("failed to set the creation time: %v", err)
+		}
+	}
+
+	weekAgo, dayAgo := now.Add(-7*24*time.Hour), now.Add(-24*time.Hour)
+	tests := []struct {
+		filter Filter
+		want   []string
+	}{
+		{Filter{CreatedAfter: weekAgo, CreatedBefore: dayAgo}, []string{"bob", "carol"}},
+		{Filter{CreatedAfter: weekAgo}, []string{"bob", "carol", "dave"}},
+		{Filter{CreatedBefore: dayAgo}, []string{"alice", "bob", "carol"}},
+		{Filter{CreatedAfter: weekAgo, V0: []string{"alice", "carol"}}, []string{"carol"}},
+		{Filter{}, []string{"alice", "bob", "carol", "dave"}},
+	}
+	for _, tt := range tests {
+		rules, err := a.GetFilteredPolicies(ctx, tt.filter)
+		if err != nil {
+			t.Fatalf("GetFilteredPolicies failed: %v", err)
+		}
+		var got []string
+		for _, rule := range rules {
+			got = append(got, rule.V0)
+		}
+		if !reflect.DeepEqual(got, tt.want) {
+			t.Errorf("rules of %v, supposed to be %v", got, tt.want)
+		}
+		if count, err := a.CountPolicies(ctx, tt.filter); err != nil || count != int64(len(tt.want)) {
+			t.Errorf("count %d, err: %v, supposed to be %d", count, err, len(tt.want))
+		}
+	}
+
+	e, _ := casbin.NewEnforcer("examples/rbac_model.conf", a)
+	if err := e.LoadFilteredPolicy(Filter{CreatedAfter: dayAgo}); err != nil {
+		t.Fatalf("LoadFilteredPolicy failed: %v", err)
+	}
+	testGetPolicy(t, e, [][]string{{"dave", "data1", "read"}})
+}
+
+func TestFilterByDomain(t *testing.T) {
+	a := newSqliteAdapter(t)
+	seedPolicy(t, a, "examples/rbac_with_domains_model.conf", "examples/rbac_with_domains_policy.csv")
+	// A p rule whose object is named like a domain, and a g rule whose role
+	// is, neither is in domain1.
+	if err := a.AddPolicy("p", "p", []string{"admin", "domain2", "domain1", "read"}); err != nil {
+		t.Fatalf("AddPolicy failed: %v", err)
+	}
+	if err := a.AddPolicy("g", "g", []string{"carol", "domain1", "domain3"}); err != nil {
+		t.Fatalf("AddPolicy failed: %v", err)
+	}
+
+	e, _ := casbin.NewEnforcer("examples/rbac_with_domains_model.conf", a)
+	if err := e.LoadFilteredPolicy(FilterByDomain("domain1", "domain3")); err != nil {
+		t.Fatalf("LoadFilteredPolicy failed: %v", err)
+	}
+	testGetPolicy(t, e, [][]string{{"admin", "domain1", "data1", "read"}, {"admin", "domain1", "data1", "write"}, {"auditor", "domain3", "data3", "read"}})
+	grouping, _ := e.GetGroupingPolicy()
+	want := [][]string{{"alice", "admin", "domain1"}, {"alice", "auditor", "domain3"}, {"carol", "domain1", "domain3"}}
+	if !reflect.DeepEqual(grouping, want) {
+		t.Errorf("grouping policy %v, supposed to be %v", grouping, want)
+	}
+	if ok, _ := e.Enforce("alice", "domain1", "data1", "write"); !ok {
+		t.Error("alice denied in domain1")
+	}
+	if err := e.LoadFilteredPolicy(FilterByDomain()); !errors.Is(err, ErrInvalidFilter) {
+		t.Errorf("err: %v, supposed to be an invalid filter error", err)
+	}
+}
+
+func TestFilterByDomainCustomFieldIndex(t *testing.T) {
+	a := newSqliteAdapter(t, WithDomainFieldIndex(0, 0))
+	if err := a.AddPolicies("p", "p", [][]string{{"domain1", "alice", "data1", "read"}, {"domain2", "bob", "data2", "read"}}); err != nil {
+		t.Fatalf("AddPolicies failed: %v", err)
+	}
+	if err := a.AddPolicies("g", "g", [][]string{{"domain1", "alice", "admin"}, {"domain2", "bob", "admin"}}); err != nil {
+		t.Fatalf("AddPolicies failed: %v", err)
+	}
+
+	rules, err := a.GetFilteredPolicies(context.Background(), FilterByDomain("domain2"))
+	if err != nil {
+		t.Fatalf("GetFilteredPolicies failed: %v", err)
+	}
+	want := []Rule{{PType: "p", V0: "domain2", V1: "bob", V2: "data2", V3: "read"}, {PType: "g", V0: "domain2", V1: "bob", V2: "admin"}}
+	if !reflect.DeepEqual(rules, want) {
+		t.Errorf("rules %v, supposed to be %v", rules, want)
+	}
+}
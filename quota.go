@@ -0,0 +1,76 @@
+package adapter
+
+import (
+	"context"
+	"errors"
+	"fmt"
+)
+
+// quotaWarnRatio is the share of the rule quota above which the loads warn.
+const quotaWarnRatio = 0.9
+
+// ErrQuotaExceeded is matched by the *QuotaExceededError returned when a
+// write would take the stored rules over the quota of WithMaxRules.
+var ErrQuotaExceeded = errors.New("rule quota exceeded")
+
+// QuotaExceededError is returned by the writes refused by WithMaxRules.
+type QuotaExceededError struct {
+	Limit int64
+	// Current is the number of stored rules, Attempted the number the
+	// write would have left.
+	Current   int64
+	Attempted int64
+}
+
+func (e *QuotaExceededError) Error() string {
+	return fmt.Sprintf("rule quota exceeded: %d rules stored, %d after the write, limit %d", e.Current, e.Attempted, e.Limit)
+}
+
+func (e *QuotaExceededError) Is(target error) bool {
+	return target == ErrQuotaExceeded
+}
+
+// WithMaxRules limits the number of stored rules, of the tenant with
+// WithTenant, to n. AddPolicy, AddPolicies, AddPoliciesOnConflict,
+// SavePolicy and the imports count the stored rules first and fail with a
+// *QuotaExceededError, writing nothing, when the rules after the write
+// would exceed n. The added rules are all counted, even those the write
+// skips as duplicates. The loads log a warning when the loaded policy holds
+// more than 90% of n rules.
+func WithMaxRules(n int64) AdapterOption {
+	return AdapterOption{apply: func(a *Adapter) {
+		a.maxRules = n
+	}}
+}
+
+// checkQuota returns a *QuotaExceededError when writing rules would exceed
+// the rule quota. They are added to the stored rules, or replace them when
+// replace is set.
+func (a *Adapter) checkQuota(ctx context.Context, rules int, replace bool) error {
+	if a.maxRules <= 0 {
+		return nil
+	}
+
+	count, err := a.modelCtx(ctx).Count()
+	if err != nil {
+		return fmt.Errorf("failed to count rules: %w", err)
+	}
+	current, attempted := int64(count), int64(rules)
+	if !replace {
+		attempted += current
+	}
+	if attempted > a.maxRules {
+		return &QuotaExceededError{Limit: a.maxRules, Current: current, Attempted: attempted}
+	}
+	return nil
+}
+
+// warnQuota logs a warning when the loaded rules approach the rule quota.
+func (a *Adapter) warnQuota(ctx context.Context, rules int) {
+	if a.maxRules <= 0 || float64(rules) <= quotaWarnRatio*float64(a.maxRules) {
+		return
+	}
+	if logger := a.warnLogger(); logger != nil {
+		logger.Warningf(ctx, "casbin adapter: table=%s rules=%d near the quota of %d rules", a.tableName, rules, a.maxRules)
+	}
+}
@@ -0,0 +1,105 @@
+package adapter
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"strings"
+	"testing"
+
+	"github.com/casbin/casbin/v2"
+	"github.com/casbin/casbin/v2/model"
+)
+
+// wantQuotaExceeded fails unless err is a QuotaExceededError with the
+// given counts.
+func wantQuotaExceeded(t *testing.T, err error, current, attempted int64) {
+	t.Helper()
+	var quotaErr *QuotaExceededError
+	if !errors.Is(err, ErrQuotaExceeded) || !errors.As(err, &quotaErr) {
+		t.Fatalf("err: %v, supposed to be a QuotaExceededError", err)
+	}
+	if quotaErr.Current != current || quotaErr.Attempted != attempted || quotaErr.Limit != 3 {
+		t.Errorf("quota error %+v, supposed to have %d current and %d attempted rules of 3", *quotaErr, current, attempted)
+	}
+}
+
+func TestMaxRules(t *testing.T) {
+	a := newSqliteAdapter(t, WithMaxRules(3))
+
+	if err := a.AddPolicies("p", "p", [][]string{{"alice", "data1", "read"}, {"bob", "data2", "write"}}); err != nil {
+		t.Fatalf("AddPolicies failed: %v", err)
+	}
+	wantQuotaExceeded(t, a.AddPolicies("p", "p", [][]string{{"carol", "data1", "read"}, {"dave", "data2", "write"}}), 2, 4)
+	// Exactly at the limit.
+	if err := a.AddPolicy("g", "g", []string{"alice", "admin"}); err != nil {
+		t.Fatalf("AddPolicy failed: %v", err)
+	}
+	wantQuotaExceeded(t, a.AddPolicy("p", "p", []string{"carol", "data1", "read"}), 3, 4)
+	if _, err := a.AddPoliciesOnConflict("p", "p", [][]string{{"carol", "data1", "read"}}, ConflictSkip); !errors.Is(err, ErrQuotaExceeded) {
+		t.Errorf("AddPoliciesOnConflict err: %v, supposed to exceed the quota", err)
+	}
+	e, _ := casbin.NewEnforcer("examples/rbac_model.conf", a)
+	testGetPolicy(t, e, [][]string{{"alice", "data1", "read"}, {"bob", "data2", "write"}})
+
+	// Saving replaces the stored rules.
+	m, _ := model.NewModelFromFile("examples/rbac_model.conf")
+	m.AddPolicies("p", "p", [][]string{{"alice", "data1", "read"}, {"bob", "data2", "write"}, {"carol", "data1", "read"}})
+	if err := a.SavePolicy(m); err != nil {
+		t.Fatalf("SavePolicy at the limit failed: %v", err)
+	}
+	m.AddPolicy("p", "p", []string{"dave", "data2", "write"})
+	wantQuotaExceeded(t, a.SavePolicy(m), 3, 4)
+	_ = e.LoadPolicy()
+	testGetPolicy(t, e, [][]string{{"alice", "data1", "read"}, {"bob", "data2", "write"}, {"carol", "data1", "read"}})
+}
+
+func TestMaxRulesImport(t *testing.T) {
+	ctx := context.Background()
+	a := newSqliteAdapter(t, WithMaxRules(3))
+	if err := a.AddPolicy("p", "p", []string{"alice", "data1", "read"}); err != nil {
+		t.Fatalf("AddPolicy failed: %v", err)
+	}
+
+	// The stored duplicate is skipped before counting.
+	csv := "p, alice, data1, read\np, bob, data2, write\np, carol, data1, read\n"
+	if _, err := a.ImportCSV(ctx, strings.NewReader(csv), ImportOptions{SkipDuplicates: true}); err != nil {
+		t.Fatalf("ImportCSV at the limit failed: %v", err)
+	}
+	_, err := a.ImportCSV(ctx, strings.NewReader("p, dave, data2, write\n"), ImportOptions{})
+	wantQuotaExceeded(t, err, 3, 4)
+
+	if _, err := a.ImportCSV(ctx, strings.NewReader(csv), ImportOptions{Replace: true}); err != nil {
+		t.Fatalf("replacing ImportCSV at the limit failed: %v", err)
+	}
+	_, err = a.ImportCSV(ctx, strings.NewReader(csv+"p, dave, data2, write\n"), ImportOptions{Replace: true})
+	wantQuotaExceeded(t, err, 3, 4)
+}
+
+func TestMaxRulesLoadWarning(t *testing.T) {
+	var buf bytes.Buffer
+	a := newSqliteAdapter(t, WithMaxRules(10), WithLogger(newBufferLogger(&buf)))
+	e, _ := casbin.NewEnforcer("examples/rbac_model.conf", a)
+
+	rules := make([][]string, 9)
+	for i := range rules {
+		rules[i] = []string{"alice", "data" + string(rune('0'+i)), "read"}
+	}
+	if err := a.AddPolicies("p", "p", rules); err != nil {
+		t.Fatalf("AddPolicies failed: %v", err)
+	}
+	buf.Reset()
+	_ = e.LoadPolicy()
+	if strings.Contains(buf.String(), "near the quota") {
+		t.Errorf("load at 90%% of the quota warned: %s", buf.String())
+	}
+
+	if err := a.AddPolicy("p", "p", []string{"bob", "data1", "read"}); err != nil {
+		t.Fatalf("AddPolicy failed: %v", err)
+	}
+	buf.Reset()
+	_ = e.LoadPolicy()
+	if !strings.Contains(buf.String(), "rules=10 near the quota of 10 rules") {
+		t.Errorf("load above 90%% of the quota didn't warn: %s", buf.String())
+	}
+}
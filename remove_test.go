@@ -0,0 +1,106 @@
+package adapter
+
+import (
+	"context"
+	"fmt"
+	"reflect"
+	"testing"
+)
+
+// perRuleDialect disables grouped conditions, so rules are removed one
+// statement each.
+type perRuleDialect struct {
+	dialect
+}
+
+func (perRuleDialect) maxGroupedConditions() int {
+	return 1
+}
+
+func TestRemovePoliciesGrouped(t *testing.T) {
+	stored := [][]string{
+		{"alice", "data1", "read"},
+		{"alice", "data1", "write"},
+		{"alice", "data2", "read"},
+		{"bob", "data2", "write"},
+		{"carol", "data2", "write"},
+		{"alice", "data1", "read"},
+		{"dave", "data3", "read"},
+	}
+	// The rules overlap: the first one matches the rows of the second and
+	// the third, the fourth is repeated and bob's rule matches nothing.
+	remove := [][]string{
+		{"alice", "data1"},
+		{"alice", "data1", "read"},
+		{"dave", "data3", "read"},
+		{"dave", "data3", "read"},
+		{"", "data2", "write"},
+		{"bob", "data9", "write"},
+	}
+
+	results := make(map[string][]Rule)
+	for name, d := range map[string]dialect{"grouped": sqliteDialect{}, "per rule": perRuleDialect{sqliteDialect{}}} {
+		// A batch size of 4 splits the rules over two statements.
+		a := newSqliteAdapter(t, AdapterOption{BatchSize: 4}, WithHistory())
+		a.dialect = d
+		if err := a.AddPolicies("p", "p", stored); err != nil {
+			t.Fatalf("AddPolicies failed: %v", err)
+		}
+		if err := a.RemovePolicies("p", "p", remove); err != nil {
+			t.Fatalf("%s: RemovePolicies failed: %v", name, err)
+		}
+
+		rules, err := a.GetAllPolicies(a.ctx, nil)
+		if err != nil {
+			t.Fatalf("GetAllPolicies failed: %v", err)
+		}
+		results[name] = rules
+
+		removed, err := a.db.Model(a.historyTable).Where("op", historyOpRemove).Count()
+		if err != nil {
+			t.Fatalf("failed to count history entries: %v", err)
+		}
+		if removed != 6 {
+			t.Errorf("%s: recorded %d removals, supposed to be 6", name, removed)
+		}
+	}
+
+	want := []Rule{{PType: "p", V0: "alice", V1: "data2", V2: "read"}}
+	for name, rules := range results {
+		if !reflect.DeepEqual(rules, want) {
+			t.Errorf("%s: stored %v, supposed to be %v", name, rules, want)
+		}
+	}
+}
+
+func BenchmarkRemovePolicies(b *testing.B) {
+	const n = 5000
+	rules := make([][]string, 0, n)
+	for i := 0; i < n; i++ {
+		rules = append(rules, []string{fmt.Sprintf("user%d", i), fmt.Sprintf("data%d", i%10), "read"})
+	}
+
+	for name, d := range map[string]dialect{"grouped": sqliteDialect{}, "per rule": perRuleDialect{sqliteDialect{}}} {
+		b.Run(name, func(b *testing.B) {
+			a, err := NewAdapter(context.Background(), "", "", newSqliteDB(b))
+			if err != nil {
+				b.Fatalf("NewAdapter failed: %v", err)
+			}
+			a.dialect = d
+			b.ResetTimer()
+			for i := 0; i < b.N; i++ {
+				b.StopTimer()
+				if err := a.AddPolicies("p", "p", rules); err != nil {
+					b.Fatalf("AddPolicies failed: %v", err)
+				}
+				b.StartTimer()
+				if err := a.RemovePolicies("p", "p", rules); err != nil {
+					b.Fatalf("RemovePolicies failed: %v", err)
+				}
+			}
+			// Each statement is a round trip to the database.
+			size := d.maxGroupedConditions()
+			b.ReportMetric(float64((n+size-1)/size), "statements/op")
+		})
+	}
+}
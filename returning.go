@@ -0,0 +1,202 @@
+package adapter
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"slices"
+	"strings"
+
+	"github.com/gogf/gf/v2/database/gdb"
+	"github.com/gogf/gf/v2/frame/g"
+)
+
+// ErrIdsUnsupported is returned by AddPolicyReturningID and
+// AddPoliciesReturningIDs on the databases whose driver doesn't tell the ids
+// of the inserted rows, DM.
+var ErrIdsUnsupported = errors.New("the database doesn't tell the ids of the inserted rules")
+
+// mysqlAutoIncrementSql reads how MySQL gives ids to the rows of an insert.
+const mysqlAutoIncrementSql = "SELECT @@innodb_autoinc_lock_mode AS lock_mode, @@auto_increment_increment AS step"
+
+// AddPolicyReturningID adds a policy rule to the storage like AddPolicy and
+// returns the id of its row.
+func (a *Adapter) AddPolicyReturningID(sec string, pType string, rule []string) (int64, error) {
+	ids, err := a.addPoliciesReturningIDs("AddPolicyReturningID", WatcherAddPolicy, sec, pType, [][]string{rule})
+	if err != nil {
+		return 0, err
+	}
+	if len(ids) == 0 {
+		return 0, fmt.Errorf("failed to add policy: %w", ErrIdsUnsupported)
+	}
+	return ids[0], nil
+}
+
+// AddPoliciesReturningIDs adds policy rules to the storage like AddPolicies
+// and returns the ids of their rows, in the order of rules. Every rule is
+// inserted, whatever the conflict policy. The ids are told by the inserts,
+// by RETURNING on PostgreSQL and OUTPUT on SQL Server. On MySQL the rules
+// are inserted in batches when the rows of an insert get consecutive ids,
+// see detectAutoIncrement, and one statement per rule otherwise. It returns
+// ErrIdsUnsupported on DM, before inserting anything.
+func (a *Adapter) AddPoliciesReturningIDs(sec string, pType string, rules [][]string) ([]int64, error) {
+	return a.addPoliciesReturningIDs("AddPoliciesReturningIDs", WatcherAddPolicies, sec, pType, rules)
+}
+
+func (a *Adapter) addPoliciesReturningIDs(method string, watcherMethod WatcherMethod, sec string, pType string, rules [][]string) ([]int64, error) {
+	if g := a.groupingFor(pType); g != nil {
+		return g.addPoliciesReturningIDs(method, watcherMethod, sec, pType, rules)
+	}
+	if len(rules) == 0 {
+		return nil, nil
+	}
+	// The ids are only known once the rules are inserted.
+	if err := a.checkDryRun(); err != nil {
+		return nil, fmt.Errorf("failed to add policies: %w", err)
+	}
+	if !a.tombstones && !a.returnsIds() && a.dialect.insertedIds(1, 1) == nil {
+		return nil, fmt.Errorf("failed to add policies: %w", ErrIdsUnsupported)
+	}
+
+	dbRules := a.opRules(pType, rules...)
+	op := Operation{Method: method, Sec: sec, PType: pType, Rules: dbRules}
+
+	var ids []int64
+	err := a.mutate(a.ctx, op, func(ctx context.Context) error {
+		ctx = withWatcherMessage(ctx, &WatcherMessage{Method: watcherMethod, Sec: sec, PType: pType, Rules: rules}, nil)
+		return a.transaction(ctx, func(ctx context.Context, tx gdb.TX) error {
+			if err := a.checkQuota(ctx, len(dbRules), false); err != nil {
+				return err
+			}
+			var err error
+			ids, err = a.insertRulesReturningIDs(ctx, dbRules)
+			return err
+		})
+	}, nil)
+	if err != nil {
+		return nil, fmt.Errorf("failed to add policies: %w", err)
+	}
+	return ids, nil
+}
+
+// returnsIds reports whether the inserts of the dialect select the ids of
+// their rows.
+func (a *Adapter) returnsIds() bool {
+	output, returning := a.dialect.returningSql()
+	return output != "" || returning != ""
+}
+
+// detectAutoIncrement sets autoIncrementStep on MySQL when the rows of an
+// insert get consecutive ids. With innodb_autoinc_lock_mode 0 or 1 an insert
+// of a known number of rows reserves its ids at once, its last insert id
+// being the one of its first row. With 2, the default since MySQL 8.0, the
+// ids of concurrent inserts interleave, as they do on the servers without
+// the variables.
+func (a *Adapter) detectAutoIncrement(ctx context.Context) {
+	if _, ok := a.dialect.(mysqlDialect); !ok {
+		return
+	}
+	record, err := a.db.GetOne(ctx, mysqlAutoIncrementSql)
+	if err != nil || record.IsEmpty() || record["lock_mode"].Int() > 1 {
+		return
+	}
+	a.autoIncrementStep = max(record["step"].Int64(), 1)
+}
+
+// consecutiveIds returns the n ids counted from first by step.
+func consecutiveIds(first int64, n int, step int64) []int64 {
+	ids := make([]int64, n)
+	for i := range ids {
+		ids[i] = first + int64(i)*step
+	}
+	return ids
+}
+
+// insertRulesReturningIDs inserts rules like insertRules and returns the
+// ids of their rows. The batches are cut to single rules when the database
+// can't tell the ids of a batch.
+func (a *Adapter) insertRulesReturningIDs(ctx context.Context, rules []Rule) ([]int64, error) {
+	defer a.invalidateCache()
+	size := a.insertSize()
+	if !a.tombstones && !a.returnsIds() && a.autoIncrementStep == 0 && a.dialect.insertedIds(0, 2) == nil {
+		size = 1
+	}
+
+	ids := make([]int64, 0, len(rules))
+	for i := 0; i < len(rules); i += size {
+		if err := ctx.Err(); err != nil {
+			return nil, err
+		}
+		end := min(i+size, len(rules))
+		batch := make(g.List, 0, end-i)
+		for _, rule := range rules[i:end] {
+			batch = append(batch, a.ruleRecord(ctx, rule))
+		}
+		if !a.tombstones && a.returnsIds() {
+			returned, err := a.insertReturning(ctx, batch)
+			if err != nil {
+				return nil, fmt.Errorf("failed to insert rules batch: %w", err)
+			}
+			reportProgress(ctx, len(batch))
+			ids = append(ids, returned...)
+			continue
+		}
+		result, err := a.modelCtx(ctx).Insert(batch)
+		if err != nil {
+			return nil, fmt.Errorf("failed to insert rules batch: %w", err)
+		}
+		reportProgress(ctx, len(batch))
+
+		// The rows written with tombstones get their ids from ruleRecord.
+		if a.tombstones {
+			for _, record := range batch {
+				ids = append(ids, record["id"].(int64))
+			}
+			continue
+		}
+		last, err := result.LastInsertId()
+		if err != nil {
+			return nil, fmt.Errorf("failed to get inserted ids: %w", err)
+		}
+		if a.autoIncrementStep > 0 {
+			ids = append(ids, consecutiveIds(last, len(batch), a.autoIncrementStep)...)
+			continue
+		}
+		ids = append(ids, a.dialect.insertedIds(last, len(batch))...)
+	}
+	return ids, a.recordHistory(ctx, historyOpAdd, rules)
+}
+
+// insertReturning inserts batch by a statement selecting the ids of its
+// rows, which are returned in ascending order, the order of the rows as
+// the ids are given in sequence.
+func (a *Adapter) insertReturning(ctx context.Context, batch g.List) ([]int64, error) {
+	columns, values, args, err := a.insertValuesSql(batch)
+	if err != nil {
+		return nil, err
+	}
+	output, returning := a.dialect.returningSql()
+	query := fmt.Sprintf("INSERT INTO %s (%s)", a.tableName, strings.Join(columns, ", "))
+	for _, clause := range []string{output, "VALUES " + values, returning} {
+		if clause != "" {
+			query += " " + clause
+		}
+	}
+	result, err := a.getAll(ctx, a.tx, query, args...)
+	if a.measured() {
+		_, _ = countAffected(ctx, nil, err)
+		addAffected(ctx, int64(len(result)))
+	}
+	if err != nil {
+		return nil, err
+	}
+	ids := make([]int64, 0, len(result))
+	for _, record := range result {
+		ids = append(ids, record["id"].Int64())
+	}
+	if len(ids) != len(batch) {
+		return nil, fmt.Errorf("got %d ids of %d inserted rules", len(ids), len(batch))
+	}
+	slices.Sort(ids)
+	return ids, nil
+}
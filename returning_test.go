@@ -0,0 +1,123 @@
+package adapter
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"slices"
+	"testing"
+)
+
+func TestAddPoliciesReturningIDs(t *testing.T) {
+	testReturningIDs(t, newSqliteAdapter(t, WithBatchSize(2)))
+}
+
+// testReturningIDs checks the ids returned by the adds of a, whose table is
+// empty.
+func testReturningIDs(t *testing.T, a *Adapter) {
+	t.Helper()
+	if err := a.AddPolicy("p", "p", []string{"alice", "data1", "read"}); err != nil {
+		t.Fatalf("AddPolicy failed: %v", err)
+	}
+
+	var rules [][]string
+	for i := 0; i < 5; i++ {
+		rules = append(rules, []string{"bob", fmt.Sprintf("data%d", i), "read"})
+	}
+	ids, err := a.AddPoliciesReturningIDs("p", "p", rules)
+	if err != nil {
+		t.Fatalf("AddPoliciesReturningIDs failed: %v", err)
+	}
+	id, err := a.AddPolicyReturningID("p", "p", []string{"carol", "data1", "write"})
+	if err != nil {
+		t.Fatalf("AddPolicyReturningID failed: %v", err)
+	}
+	ids = append(ids, id)
+	if len(ids) != 6 || !slices.IsSorted(ids) || ids[0] <= 1 {
+		t.Fatalf("ids %v, supposed to be 6 increasing ids after the first row", ids)
+	}
+
+	// Every id is the one of the row of its rule.
+	rows, err := a.GetFilteredPolicies(context.Background(), Filter{V0: []string{"bob", "carol"}})
+	if err != nil {
+		t.Fatalf("GetFilteredPolicies failed: %v", err)
+	}
+	for i, rule := range append(rules, []string{"carol", "data1", "write"}) {
+		stored, err := a.db.Model(a.tableName).Where("v0", rule[0]).Where("v1", rule[1]).Value("id")
+		if err != nil || stored.Int64() != ids[i] {
+			t.Errorf("id %d of %v, stored %v, err: %v", ids[i], rule, stored, err)
+		}
+	}
+	if len(rows) != 6 {
+		t.Errorf("stored %d rules, supposed to be 6", len(rows))
+	}
+}
+
+func TestInsertedIds(t *testing.T) {
+	if ids := (mysqlDialect{}).insertedIds(7, 1); !slices.Equal(ids, []int64{7}) {
+		t.Errorf("MySQL ids %v of a single row", ids)
+	}
+	if ids := (mysqlDialect{}).insertedIds(7, 3); ids != nil {
+		t.Errorf("MySQL ids %v of a batch, supposed to be unknown", ids)
+	}
+	if ids := (sqliteDialect{}).insertedIds(9, 3); !slices.Equal(ids, []int64{7, 8, 9}) {
+		t.Errorf("sqlite ids %v of a batch ending at 9", ids)
+	}
+	// MySQL counts from the first row with consecutive ids.
+	if ids := consecutiveIds(7, 3, 2); !slices.Equal(ids, []int64{7, 9, 11}) {
+		t.Errorf("consecutive ids %v from 7 by 2", ids)
+	}
+}
+
+// returningDialect selects the inserted ids by the RETURNING clause of
+// PostgreSQL, which sqlite shares.
+type returningDialect struct {
+	dialect
+}
+
+func (returningDialect) returningSql() (output, returning string) {
+	return pgsqlDialect{}.returningSql()
+}
+
+func (returningDialect) insertedIds(last int64, n int) []int64 {
+	return nil
+}
+
+func TestAddPoliciesReturningIDsClause(t *testing.T) {
+	a := newSqliteAdapter(t, WithBatchSize(3))
+	a.dialect = returningDialect{a.dialect}
+	if err := a.AddPolicy("p", "p", []string{"alice", "data1", "read"}); err != nil {
+		t.Fatalf("AddPolicy failed: %v", err)
+	}
+	var rules [][]string
+	for i := 0; i < 5; i++ {
+		rules = append(rules, []string{"bob", fmt.Sprintf("data%d", i), "read"})
+	}
+	ids, err := a.AddPoliciesReturningIDs("p", "p", rules)
+	if err != nil {
+		t.Fatalf("AddPoliciesReturningIDs failed: %v", err)
+	}
+	for i, rule := range rules {
+		stored, err := a.db.Model(a.tableName).Where("v0", rule[0]).Where("v1", rule[1]).Value("id")
+		if err != nil || i >= len(ids) || stored.Int64() != ids[i] {
+			t.Errorf("ids %v, rule %v stored with id %v, err: %v", ids, rule, stored, err)
+		}
+	}
+}
+
+func TestAddPoliciesReturningIDsUnsupported(t *testing.T) {
+	// The dialects without ids refuse before inserting anything.
+	for _, dialect := range []dialect{dmDialect{}} {
+		a := newSqliteAdapter(t)
+		a.dialect = dialect
+		if _, err := a.AddPolicyReturningID("p", "p", []string{"alice", "data1", "read"}); !errors.Is(err, ErrIdsUnsupported) {
+			t.Errorf("%T: AddPolicyReturningID err: %v, supposed to be ErrIdsUnsupported", dialect, err)
+		}
+		if _, err := a.AddPoliciesReturningIDs("p", "p", [][]string{{"bob", "data2", "write"}}); !errors.Is(err, ErrIdsUnsupported) {
+			t.Errorf("%T: AddPoliciesReturningIDs err: %v, supposed to be ErrIdsUnsupported", dialect, err)
+		}
+		if count, err := a.db.Model(a.tableName).Count(); err != nil || count != 0 {
+			t.Errorf("%T: %d rules stored, err: %v, supposed to be none", dialect, count, err)
+		}
+	}
+}
@@ -0,0 +1,107 @@
+package adapter
+
+import (
+	"context"
+	"testing"
+
+	"github.com/casbin/casbin/v2"
+)
+
+func TestTableRouting(t *testing.T) {
+	ctx := context.Background()
+	a, err := NewAdapter(ctx, "", "", newSqliteDB(t), WithTableRouting(map[string]string{"p": "acl_policies", "g": "acl_groups"}))
+	if err != nil {
+		t.Fatalf("NewAdapter failed: %v", err)
+	}
+	initPolicy(t, a)
+	if p, g, d := tableCount(t, a, "acl_policies"), tableCount(t, a, "acl_groups"), tableCount(t, a, "casbin_rule"); p != 4 || g != 1 || d != 0 {
+		t.Errorf("saved %d, %d and %d rows, supposed to be 4 p, 1 g and none in the default table", p, g, d)
+	}
+
+	e, err := casbin.NewEnforcer("examples/rbac_model.conf", a)
+	if err != nil {
+		t.Fatalf("NewEnforcer failed: %v", err)
+	}
+	testGetPolicy(t, e, [][]string{{"alice", "data1", "read"}, {"bob", "data2", "write"}, {"data2_admin", "data2", "read"}, {"data2_admin", "data2", "write"}})
+	testGrouping(t, e, "g", [][]string{{"alice", "data2_admin"}})
+
+	if _, err := e.AddGroupingPolicy("bob", "data2_admin"); err != nil {
+		t.Fatalf("AddGroupingPolicy failed: %v", err)
+	}
+	if _, err := e.RemovePolicy("bob", "data2", "write"); err != nil {
+		t.Fatalf("RemovePolicy failed: %v", err)
+	}
+	if p, g := tableCount(t, a, "acl_policies"), tableCount(t, a, "acl_groups"); p != 3 || g != 2 {
+		t.Errorf("stored %d p and %d g rows, supposed to be 3 and 2", p, g)
+	}
+
+	// Saving replaces the rules of every table.
+	e.EnableAutoSave(false)
+	if _, err := e.RemoveGroupingPolicy("alice", "data2_admin"); err != nil {
+		t.Fatalf("RemoveGroupingPolicy failed: %v", err)
+	}
+	if err := e.SavePolicy(); err != nil {
+		t.Fatalf("SavePolicy failed: %v", err)
+	}
+	if p, g, d := tableCount(t, a, "acl_policies"), tableCount(t, a, "acl_groups"), tableCount(t, a, "casbin_rule"); p != 3 || g != 1 || d != 0 {
+		t.Errorf("saved %d, %d and %d rows, supposed to be 3 p, 1 g and none in the default table", p, g, d)
+	}
+
+	// A filtered load only queries the tables of its policy types.
+	if _, err := a.db.Exec(ctx, "DROP TABLE acl_groups"); err != nil {
+		t.Fatalf("failed to drop table: %v", err)
+	}
+	if err := e.LoadFilteredPolicy(Filter{PType: []string{"p"}}); err != nil {
+		t.Fatalf("LoadFilteredPolicy failed: %v", err)
+	}
+	testGetPolicy(t, e, [][]string{{"alice", "data1", "read"}, {"data2_admin", "data2", "read"}, {"data2_admin", "data2", "write"}})
+	if err := e.LoadPolicy(); err == nil {
+		t.Error("expected LoadPolicy to query the dropped table")
+	}
+}
+
+func TestTableRoutingFallback(t *testing.T) {
+	a, err := NewAdapter(context.Background(), "", "", newSqliteDB(t), WithTableRouting(map[string]string{"g2": "acl_resources"}))
+	if err != nil {
+		t.Fatalf("NewAdapter failed: %v", err)
+	}
+	seedPolicy(t, a, resourceRolesModel, resourceRolesPolicy)
+
+	// Only g2 is routed, p and g stay in the default table.
+	if r, d := tableCount(t, a, "acl_resources"), tableCount(t, a, "casbin_rule"); r != 2 || d != 4 {
+		t.Errorf("saved %d routed and %d default rows, supposed to be 2 and 4", r, d)
+	}
+	if err := a.AddPolicy("g", "g2", []string{"data3", "data_group"}); err != nil {
+		t.Fatalf("AddPolicy failed: %v", err)
+	}
+	if err := a.RemoveFilteredPolicy("g", "g", 0, "alice"); err != nil {
+		t.Fatalf("RemoveFilteredPolicy failed: %v", err)
+	}
+	if r, d := tableCount(t, a, "acl_resources"), tableCount(t, a, "casbin_rule"); r != 3 || d != 3 {
+		t.Errorf("stored %d routed and %d default rows, supposed to be 3 and 3", r, d)
+	}
+
+	e, err := casbin.NewEnforcer(resourceRolesModel, a)
+	if err != nil {
+		t.Fatalf("NewEnforcer failed: %v", err)
+	}
+	testGrouping(t, e, "g", nil)
+	testGrouping(t, e, "g2", [][]string{{"data1", "data_group"}, {"data2", "data_group"}, {"data3", "data_group"}})
+	if err := e.LoadFilteredPolicy(Filter{PType: []string{"g2"}, Limit: 2}); err != nil {
+		t.Fatalf("LoadFilteredPolicy of a paged routed type failed: %v", err)
+	}
+	testGrouping(t, e, "g2", [][]string{{"data1", "data_group"}, {"data2", "data_group"}})
+}
+
+func TestTableRoutingOptions(t *testing.T) {
+	for name, opts := range map[string][]AdapterOption{
+		"default table": {WithTableRouting(map[string]string{"g": "casbin_rule"})},
+		"empty table":   {WithTableRouting(map[string]string{"g": ""})},
+		"empty type":    {WithTableRouting(map[string]string{"": "acl_groups"})},
+		"history":       {WithTableRouting(map[string]string{"g": "acl_groups"}), WithHistory()},
+	} {
+		if _, err := NewAdapter(context.Background(), "", "", newSqliteDB(t), opts...); err == nil {
+			t.Errorf("%s: expected an error", name)
+		}
+	}
+}
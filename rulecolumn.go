@@ -0,0 +1,363 @@
+package adapter
+
+import (
+	"cmp"
+	"context"
+	"errors"
+	"fmt"
+	"reflect"
+	"slices"
+	"sort"
+	"strings"
+
+	"github.com/casbin/casbin/v2/model"
+	"github.com/gogf/gf/v2/container/gvar"
+	"github.com/gogf/gf/v2/database/gdb"
+	"github.com/gogf/gf/v2/frame/g"
+)
+
+// WithSingleRuleColumn stores each rule in the text column column of the
+// policy table, as one line of comma separated fields like
+// p,alice,data1,read, in place of the p_type and v0 to v5 columns. The
+// fields holding commas, quotes or line breaks, or surrounded by spaces,
+// are quoted like in a casbin policy file. The table created has the id,
+// column and created_at columns.
+//
+// The filters of the loads, GetFilteredPolicies and CountPolicies only
+// support PType and the values V0 to V5. The database selects the rules by
+// a LIKE prefix of their policy type and of the values leading the line,
+// the other values are matched once the rules are read: a filter without
+// PType reads the whole table, and RemoveFilteredPolicy all the rules of its
+// policy type when its field index isn't 0. The rules are removed and
+// updated by their exact line. The other queries of the adapter, like
+// SearchPolicies or GetAllDomains, need the value columns and return
+// ErrUnsupportedInPackedMode. ClickHouse and the options adding columns or
+// encoding the values aren't supported.
+func WithSingleRuleColumn(column string) AdapterOption {
+	return AdapterOption{apply: func(a *Adapter) {
+		a.ruleColumn = column
+	}}
+}
+
+// ErrUnsupportedInPackedMode is returned by the queries needing the value
+// columns, like SearchPolicies or GetAllDomains, with WithSingleRuleColumn
+// or WithJSONStorage.
+var ErrUnsupportedInPackedMode = errors.New("query isn't supported with a single rule column or the JSON storage")
+
+// packed reports whether the values of the rules are stored in a single
+// column, by WithSingleRuleColumn or WithJSONStorage.
+func (a *Adapter) packed() bool {
+	return a.ruleColumn != "" || a.jsonStorage
+}
+
+// checkUnpacked returns ErrUnsupportedInPackedMode when the values of the
+// rules are stored in a single column.
+func (a *Adapter) checkUnpacked() error {
+	if a.packed() {
+		return ErrUnsupportedInPackedMode
+	}
+	return nil
+}
+
+// validatePacked checks the options of a single rule column or of the JSON
+// storage.
+func (a *Adapter) validatePacked() error {
+	if !a.packed() {
+		return nil
+	}
+	mode := "JSON storage"
+	if a.ruleColumn != "" {
+		mode = "a single rule column"
+		if !isValidIdentifier(a.ruleColumn) {
+			return fmt.Errorf("invalid rule column name: %q", a.ruleColumn)
+		}
+	}
+	var option string
+	switch {
+	case a.tombstones:
+		return fmt.Errorf("%s isn't supported on ClickHouse", mode)
+	case a.ruleColumn != "" && a.jsonStorage:
+		option = "JSON storage"
+	case a.history:
+		option = "history"
+	case a.changeTracking:
+		option = "change tracking"
+	case a.uniqueIndex:
+		option = "a unique index"
+	case a.codec != nil:
+		option = "encryption"
+	case a.hasher != nil:
+		option = "field hashing"
+	case a.tenantColumn != "":
+		option = "a tenant column"
+	case a.partitioned:
+		option = "partitioning"
+	case a.routed():
+		option = "routed tables"
+	case len(a.columnTypes) > 0:
+		option = "column types"
+	case a.autoMigrate:
+		option = "schema migrations"
+	case a.writers > 1:
+		option = "parallel writes"
+	case a.syncSave:
+		option = "sync saves"
+	case a.stableSave:
+		option = "stable saves"
+	case a.ordered():
+		option = "a load order"
+	case a.actorColumn != "":
+		option = "an actor column"
+	}
+	if option != "" {
+		return fmt.Errorf("%s can't be combined with %s", mode, option)
+	}
+	return nil
+}
+
+// packedTableSql returns the statements creating table with the layout of a
+// single rule column or of the JSON storage.
+func (a *Adapter) packedTableSql(table string) []string {
+	if a.jsonStorage {
+		return a.dialect.createJSONTableSql(table)
+	}
+	return []string{a.dialect.createRuleColumnTableSql(table, a.ruleColumn)}
+}
+
+// detectPacked checks that the existing policy table has the columns of a
+// single rule column or of the JSON storage.
+func (a *Adapter) detectPacked(ctx context.Context) error {
+	fields, err := a.db.TableFields(ctx, a.tableName)
+	if err != nil {
+		return fmt.Errorf("failed to get columns of %s: %w", a.tableName, err)
+	}
+	expected := []string{"id", a.ruleColumn}
+	if a.jsonStorage {
+		expected = []string{"id", Columns.PType, jsonValuesColumn}
+	}
+	if len(fields) == 0 {
+		return nil
+	}
+	found := make([]string, 0, len(fields))
+	for name := range fields {
+		found = append(found, name)
+	}
+	sort.Strings(found)
+	for _, column := range expected {
+		if fields[column] == nil {
+			return &IncompatibleSchemaError{Table: a.tableName, Found: found, Expected: expected}
+		}
+	}
+	return nil
+}
+
+// ruleLine returns the line of rule, its policy type followed by its values
+// up to the last non-empty one.
+func ruleLine(rule Rule) string {
+	values := []string{rule.V0, rule.V1, rule.V2, rule.V3, rule.V4, rule.V5}
+	for len(values) > 0 && values[len(values)-1] == "" {
+		values = values[:len(values)-1]
+	}
+	return lineOf(append([]string{rule.PType}, values...))
+}
+
+// lineOf joins fields into a line, quoting them when needed.
+func lineOf(fields []string) string {
+	quoted := make([]string, len(fields))
+	for i, field := range fields {
+		quoted[i] = csvField(field)
+	}
+	return strings.Join(quoted, ",")
+}
+
+// ruleLineRecord returns the row of the rule column holding rule.
+func (a *Adapter) ruleLineRecord(rule Rule) g.Map {
+	return g.Map{a.ruleColumn: ruleLine(rule)}
+}
+
+// ruleLinesCondition returns the condition matching the stored lines of
+// rules exactly.
+func (a *Adapter) ruleLinesCondition(rules []Rule) (string, []interface{}) {
+	args := make([]interface{}, len(rules))
+	for i, rule := range rules {
+		args[i] = ruleLine(rule)
+	}
+	return a.ruleColumn + " IN (" + strings.TrimSuffix(strings.Repeat("?,", len(rules)), ",") + ")", args
+}
+
+// packedHook parses the rule column or the JSON values of the rows read by
+// a model into the p_type and v0 to v5 fields. When counted is set it also
+// counts the affected rows like affectedHook.
+func (a *Adapter) packedHook(counted bool) gdb.HookHandler {
+	var hook gdb.HookHandler
+	if counted {
+		hook = affectedHook
+	}
+	hook.Select = func(ctx context.Context, in *gdb.HookSelectInput) (gdb.Result, error) {
+		result, err := in.Next(ctx)
+		if err != nil {
+			return result, err
+		}
+		if a.jsonStorage {
+			return result, a.parseJSONValues(result)
+		}
+		return result, a.parseRuleLines(result)
+	}
+	return hook
+}
+
+// parseRuleLines sets the fields of the rules held by the rule column of
+// records, in place.
+func (a *Adapter) parseRuleLines(records gdb.Result) error {
+	for _, record := range records {
+		line, ok := record[a.ruleColumn]
+		if !ok || line.IsNil() {
+			continue
+		}
+		rule, err := parseCSVLine(line.String())
+		if err != nil {
+			return fmt.Errorf("failed to parse rule %q: %w", line.String(), err)
+		}
+		record[a.pTypeColumn] = gvar.New(rule.PType)
+		for i, value := range []string{rule.V0, rule.V1, rule.V2, rule.V3, rule.V4, rule.V5} {
+			record[valueColumns[i]] = gvar.New(value)
+		}
+	}
+	return nil
+}
+
+// loadPacked loads all the stored rules into model, page by page, and
+// caches them under key.
+func (a *Adapter) loadPacked(ctx context.Context, model model.Model, key string) error {
+	policy := a.newCachedPolicy()
+	err := a.scanPages(a.loadModelCtx(ctx), func(rows []ruleRow) error {
+		for _, row := range rows {
+			a.loadPolicyRule(row.Rule, model)
+			policy.add(row.PType, row.toSlice())
+		}
+		return nil
+	})
+	if err != nil {
+		return fmt.Errorf("failed to scan policy rules: %w", err)
+	}
+	a.isFiltered = false
+	return a.storeCache(key, policy)
+}
+
+// packedRows returns the rows matching any of filters ordered by id, a row
+// matching several filters once. The rows are selected by packedCondition
+// and matched by their values once read.
+func (a *Adapter) packedRows(ctx context.Context, filters []Filter) ([]ruleRow, error) {
+	var (
+		rows []ruleRow
+		read = make(map[int64]bool)
+	)
+	for _, filter := range filters {
+		supported := Filter{
+			PType: filter.PType, V0: filter.V0, V1: filter.V1, V2: filter.V2, V3: filter.V3, V4: filter.V4, V5: filter.V5,
+			AllowEmpty: filter.AllowEmpty,
+		}
+		if !reflect.DeepEqual(filter, supported) {
+			return nil, fmt.Errorf("%w: only PType and V0 to V5 are supported with the values stored in a single column", ErrInvalidFilter)
+		}
+		filter = a.normalizeFilter(filter)
+
+		query := a.modelCtx(ctx)
+		if condition, args := a.packedCondition(filter); condition != "" {
+			query = query.Where(condition, args...)
+		}
+		err := a.scanPages(query, func(page []ruleRow) error {
+			for _, row := range page {
+				if !read[row.Id] && filterMatches(filter, row.Rule) {
+					read[row.Id] = true
+					rows = append(rows, row)
+				}
+			}
+			return nil
+		})
+		if err != nil {
+			return nil, fmt.Errorf("failed to scan policy rules: %w", err)
+		}
+	}
+	if len(filters) > 1 {
+		slices.SortFunc(rows, func(x, y ruleRow) int { return cmp.Compare(x.Id, y.Id) })
+	}
+	return rows, nil
+}
+
+// packedCondition returns the condition selecting the rows that may match
+// filter, empty when the whole table is read.
+func (a *Adapter) packedCondition(filter Filter) (string, []interface{}) {
+	if a.jsonStorage {
+		return a.jsonFilterCondition(filter)
+	}
+	return a.linePrefixCondition(filter)
+}
+
+// linePrefixCondition returns the condition selecting the lines starting
+// with a policy type of filter, followed by the values of the leading
+// fields of filter having a single value. It is empty when filter has no
+// policy type.
+func (a *Adapter) linePrefixCondition(filter Filter) (string, []interface{}) {
+	fields := filterFields(&filter)
+	var leading []string
+	for _, field := range fields[1:] {
+		if len(*field) != 1 {
+			break
+		}
+		leading = append(leading, (*field)[0])
+	}
+
+	conditions := make([]string, 0, len(filter.PType))
+	args := make([]interface{}, 0, 2*len(filter.PType))
+	for _, pType := range filter.PType {
+		prefix := lineOf(append([]string{pType}, leading...))
+		conditions = append(conditions, a.ruleColumn+" = ? OR "+a.ruleColumn+" LIKE ? ESCAPE '"+likeEscape+"'")
+		args = append(args, prefix, likePrefix(prefix+","))
+	}
+	if len(conditions) == 0 {
+		return "", nil
+	}
+	return "(" + strings.Join(conditions, " OR ") + ")", args
+}
+
+// filterMatches reports whether rule has one of the values of each field
+// of filter, the fields without values matching any.
+func filterMatches(filter Filter, rule Rule) bool {
+	values := []string{rule.PType, rule.V0, rule.V1, rule.V2, rule.V3, rule.V4, rule.V5}
+	for i, field := range filterFields(&filter) {
+		if len(*field) > 0 && !slices.Contains(*field, values[i]) {
+			return false
+		}
+	}
+	return true
+}
+
+// packedFieldsQuery returns the query of the stored rules of pType
+// holding the non-empty values of fieldValues from fieldIndex on, by the
+// ids of the matching rows.
+func (a *Adapter) packedFieldsQuery(ctx context.Context, pType string, fieldIndex int, fieldValues []string) (*gdb.Model, error) {
+	filter := Filter{PType: []string{pType}}
+	fields := filterFields(&filter)
+	for i, value := range fieldValues {
+		if value == "" {
+			continue
+		}
+		if !isValidFieldIndex(fieldIndex + i) {
+			return nil, fmt.Errorf("invalid field index: %d", fieldIndex+i)
+		}
+		*fields[fieldIndex+i+1] = []string{value}
+	}
+	rows, err := a.packedRows(ctx, []Filter{filter})
+	if err != nil {
+		return nil, err
+	}
+	if len(rows) == 0 {
+		return a.modelCtx(ctx).Where("1=0"), nil
+	}
+	ids := make([]int64, len(rows))
+	for i, row := range rows {
+		ids[i] = row.Id
+	}
+	return a.modelCtx(ctx).WhereIn("id", ids), nil
+}
@@ -0,0 +1,230 @@
+package adapter
+
+import (
+	"context"
+	"errors"
+	"reflect"
+	"slices"
+	"testing"
+
+	"github.com/casbin/casbin/v2"
+)
+
+// storedLines returns the lines of the rule column, ordered by id.
+func storedLines(t *testing.T, a *Adapter) []string {
+	t.Helper()
+	values, err := a.db.Model(a.tableName).Fields(a.ruleColumn).OrderAsc("id").Array()
+	if err != nil {
+		t.Fatalf("failed to read rule column: %v", err)
+	}
+	lines := make([]string, len(values))
+	for i, value := range values {
+		lines[i] = value.String()
+	}
+	return lines
+}
+
+func TestSingleRuleColumn(t *testing.T) {
+	ctx := context.Background()
+	a := newSqliteAdapter(t, WithSingleRuleColumn("rule"))
+	fields, err := a.db.TableFields(ctx, a.tableName)
+	if err != nil {
+		t.Fatalf("TableFields failed: %v", err)
+	}
+	if len(fields) != 3 || fields["id"] == nil || fields["rule"] == nil || fields["created_at"] == nil {
+		t.Errorf("columns %v, supposed to be id, rule and created_at", fields)
+	}
+
+	rules := [][]string{
+		{"alice", "data1", "read"},
+		{"bob", "data,2", `say "hi"`},
+		{" carol", "line\nbreak", "#tag"},
+	}
+	if err := a.AddPolicies("p", "p", rules); err != nil {
+		t.Fatalf("AddPolicies failed: %v", err)
+	}
+	if err := a.AddPolicy("g", "g", []string{"alice", "admin"}); err != nil {
+		t.Fatalf("AddPolicy failed: %v", err)
+	}
+	want := []string{"p,alice,data1,read", `p,bob,"data,2","say ""hi"""`, "p,\" carol\",\"line\nbreak\",\"#tag\"", "g,alice,admin"}
+	if lines := storedLines(t, a); !slices.Equal(lines, want) {
+		t.Errorf("stored lines %q, supposed to be %q", lines, want)
+	}
+
+	e, err := casbin.NewEnforcer("examples/rbac_model.conf", a)
+	if err != nil {
+		t.Fatalf("NewEnforcer failed: %v", err)
+	}
+	testGetPolicy(t, e, rules)
+	testGrouping(t, e, "g", [][]string{{"alice", "admin"}})
+
+	// The rules are removed and updated by their line.
+	if _, err := e.RemovePolicy("bob", "data,2", `say "hi"`); err != nil {
+		t.Fatalf("RemovePolicy failed: %v", err)
+	}
+	if _, err := e.UpdatePolicy([]string{"alice", "data1", "read"}, []string{"alice", "data1", "write"}); err != nil {
+		t.Fatalf("UpdatePolicy failed: %v", err)
+	}
+	want = []string{"p,\" carol\",\"line\nbreak\",\"#tag\"", "g,alice,admin", "p,alice,data1,write"}
+	if lines := storedLines(t, a); !slices.Equal(lines, want) {
+		t.Errorf("stored lines %q, supposed to be %q", lines, want)
+	}
+	if _, err := e.RemoveFilteredPolicy(1, "line\nbreak"); err != nil {
+		t.Fatalf("RemoveFilteredPolicy failed: %v", err)
+	}
+	if lines := storedLines(t, a); !slices.Equal(lines, want[1:]) {
+		t.Errorf("stored lines %q, supposed to be %q", lines, want[1:])
+	}
+
+	// Saving replaces the lines, keeping the empty values between others.
+	e.EnableAutoSave(false)
+	if _, err := e.AddPolicy("dave", "data,3", "read"); err != nil {
+		t.Fatalf("AddPolicy failed: %v", err)
+	}
+	if err := e.SavePolicy(); err != nil {
+		t.Fatalf("SavePolicy failed: %v", err)
+	}
+	if err := e.LoadPolicy(); err != nil {
+		t.Fatalf("LoadPolicy failed: %v", err)
+	}
+	testGetPolicy(t, e, [][]string{{"alice", "data1", "write"}, {"dave", "data,3", "read"}})
+	if err := a.AddPolicy("p", "p", []string{"erin", "", "read"}); err != nil {
+		t.Fatalf("AddPolicy failed: %v", err)
+	}
+	// The staging table of a save split in transactions has the rule column.
+	b := newSqliteAdapter(t, WithSingleRuleColumn("rule"), WithMaxTxRows(2))
+	initPolicy(t, b)
+	if lines := storedLines(t, b); len(lines) != 5 || lines[4] != "g,alice,data2_admin" {
+		t.Errorf("stored lines %q, supposed to end with the grouping rule", lines)
+	}
+
+	stored, err := a.GetAllPolicies(ctx, nil)
+	if err != nil {
+		t.Fatalf("GetAllPolicies failed: %v", err)
+	}
+	if last := stored[len(stored)-1]; last != (Rule{PType: "p", V0: "erin", V2: "read"}) {
+		t.Errorf("last rule %+v, supposed to keep its empty v1", last)
+	}
+}
+
+func TestSingleRuleColumnFilters(t *testing.T) {
+	ctx := context.Background()
+	a := newSqliteAdapter(t, WithSingleRuleColumn("rule"))
+	initPolicy(t, a)
+	if err := a.AddPolicies("p", "p", [][]string{{"alice,x", "data1", "read"}, {"alicia", "data1", "read"}}); err != nil {
+		t.Fatalf("AddPolicies failed: %v", err)
+	}
+
+	tests := []struct {
+		filter interface{}
+		want   []Rule
+	}{
+		{Filter{PType: []string{"g"}}, []Rule{{PType: "g", V0: "alice", V1: "data2_admin"}}},
+		// The prefix of alice doesn't select the rules of alice,x and alicia.
+		{Filter{PType: []string{"p"}, V0: []string{"alice"}}, []Rule{{PType: "p", V0: "alice", V1: "data1", V2: "read"}}},
+		{Filter{PType: []string{"p"}, V0: []string{"alice,x"}}, []Rule{{PType: "p", V0: "alice,x", V1: "data1", V2: "read"}}},
+		// The values of V2 are matched once read.
+		{Filter{V2: []string{"write"}}, []Rule{{PType: "p", V0: "bob", V1: "data2", V2: "write"}, {PType: "p", V0: "data2_admin", V1: "data2", V2: "write"}}},
+		{[]Filter{{V0: []string{"bob"}}, {PType: []string{"g"}}, {V0: []string{"bob"}}}, []Rule{
+			{PType: "p", V0: "bob", V1: "data2", V2: "write"}, {PType: "g", V0: "alice", V1: "data2_admin"},
+		}},
+	}
+	for _, tt := range tests {
+		rules, err := a.GetFilteredPolicies(ctx, tt.filter)
+		if err != nil {
+			t.Fatalf("GetFilteredPolicies(%+v) failed: %v", tt.filter, err)
+		}
+		if !reflect.DeepEqual(rules, tt.want) {
+			t.Errorf("GetFilteredPolicies(%+v) = %v, supposed to be %v", tt.filter, rules, tt.want)
+		}
+		if count, err := a.CountPolicies(ctx, tt.filter); err != nil || count != int64(len(tt.want)) {
+			t.Errorf("CountPolicies(%+v) = %d, err: %v, supposed to be %d", tt.filter, count, err, len(tt.want))
+		}
+	}
+
+	e, _ := casbin.NewEnforcer("examples/rbac_model.conf", a)
+	if err := e.LoadFilteredPolicy(Filter{PType: []string{"p"}, V1: []string{"data1"}}); err != nil {
+		t.Fatalf("LoadFilteredPolicy failed: %v", err)
+	}
+	testGetPolicy(t, e, [][]string{{"alice", "data1", "read"}, {"alice,x", "data1", "read"}, {"alicia", "data1", "read"}})
+	if !e.GetAdapter().(*Adapter).IsFiltered() {
+		t.Error("the policy should be filtered")
+	}
+
+	for _, filter := range []Filter{{V0Prefix: []string{"al"}}, {NotV0: []string{"bob"}}, {PType: []string{"p"}, Limit: 1}} {
+		if err := e.LoadFilteredPolicy(filter); !errors.Is(err, ErrInvalidFilter) {
+			t.Errorf("LoadFilteredPolicy(%+v) err: %v, supposed to be an invalid filter", filter, err)
+		}
+	}
+}
+
+func TestSingleRuleColumnOptions(t *testing.T) {
+	for name, opts := range map[string][]AdapterOption{
+		"invalid column": {WithSingleRuleColumn("rule;")},
+		"history":        {WithSingleRuleColumn("rule"), WithHistory()},
+		"tenant":         {WithSingleRuleColumn("rule"), WithTenantColumn("tenant_id")},
+		"unique index":   {WithSingleRuleColumn("rule"), WithUniqueIndex()},
+		"routed tables":  {WithSingleRuleColumn("rule"), WithSplitTables("casbin_p", "casbin_g")},
+	} {
+		if _, err := NewAdapter(context.Background(), "", "", newSqliteDB(t), opts...); err == nil {
+			t.Errorf("%s: expected an error", name)
+		}
+	}
+
+	// The table of the value columns has no rule column.
+	db := newSqliteAdapter(t).db
+	if _, err := NewAdapter(context.Background(), "", "", db, WithSingleRuleColumn("rule")); !errors.Is(err, ErrIncompatibleSchema) {
+		t.Errorf("NewAdapter err: %v, supposed to be an incompatible schema", err)
+	}
+}
+
+// testPackedQueries checks that the queries needing the value columns
+// return ErrUnsupportedInPackedMode on the packed adapter a.
+func testPackedQueries(t *testing.T, a *Adapter) {
+	t.Helper()
+	ctx := context.Background()
+	initPolicy(t, a)
+	queries := map[string]func() error{
+		"GetAllDomains": func() error {
+			_, err := a.GetAllDomains(ctx)
+			return err
+		},
+		"GetAllSubjects": func() error {
+			_, err := a.GetAllSubjects(ctx)
+			return err
+		},
+		"GetDistinctValues": func() error {
+			_, err := a.GetDistinctValues(ctx, "p", 1, 10)
+			return err
+		},
+		"GetRolesForUser": func() error {
+			_, err := a.GetRolesForUser(ctx, "alice")
+			return err
+		},
+		"GetUsersForRole": func() error {
+			_, err := a.GetUsersForRole(ctx, "data2_admin")
+			return err
+		},
+		"GetUsersForRolePage": func() error {
+			_, err := a.GetUsersForRolePage(ctx, "data2_admin", 10, 0)
+			return err
+		},
+		"GetPermissionsForUser": func() error {
+			_, err := a.GetPermissionsForUser(ctx, "alice")
+			return err
+		},
+		"SearchPolicies": func() error {
+			_, err := a.SearchPolicies(ctx, "alice", 10)
+			return err
+		},
+	}
+	for name, query := range queries {
+		if err := query(); !errors.Is(err, ErrUnsupportedInPackedMode) {
+			t.Errorf("%s err: %v, supposed to be ErrUnsupportedInPackedMode", name, err)
+		}
+	}
+}
+
+func TestSingleRuleColumnQueries(t *testing.T) {
+	testPackedQueries(t, newSqliteAdapter(t, WithSingleRuleColumn("rule")))
+}
@@ -0,0 +1,215 @@
+package adapter
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sort"
+	"strings"
+
+	"github.com/gogf/gf/v2/database/gdb"
+)
+
+// legacyPTypeColumn is the policy type column of tables created by
+// gorm-adapter.
+const legacyPTypeColumn = "ptype"
+
+// ErrIncompatibleSchema is matched by the *IncompatibleSchemaError returned
+// when an existing policy table can't be used by the adapter.
+var ErrIncompatibleSchema = errors.New("incompatible policy table schema")
+
+// IncompatibleSchemaError is returned by NewAdapter when the existing policy
+// table doesn't follow a known column naming convention.
+type IncompatibleSchemaError struct {
+	Table string
+	// Found holds the columns of the table, Expected the columns the
+	// adapter requires.
+	Found    []string
+	Expected []string
+}
+
+func (e *IncompatibleSchemaError) Error() string {
+	return fmt.Sprintf("incompatible schema of table %s: found columns %v, expected %v", e.Table, e.Found, e.Expected)
+}
+
+func (e *IncompatibleSchemaError) Is(target error) bool {
+	return target == ErrIncompatibleSchema
+}
+
+// detectColumns inspects the columns of the existing policy table and picks
+// the naming convention of the policy type column, p_type as created by the
+// adapter or ptype as created by gorm-adapter. The table is expected to be
+// created with the adapter's schema when it doesn't exist yet.
+func (a *Adapter) detectColumns(ctx context.Context) error {
+	a.pTypeColumn = Columns.PType
+	a.softDelete = false
+	if a.packed() {
+		return a.detectPacked(ctx)
+	}
+
+	fields, err := a.db.TableFields(ctx, a.tableName)
+	if err != nil {
+		return fmt.Errorf("failed to get columns of %s: %w", a.tableName, err)
+	}
+	if len(fields) == 0 {
+		return nil
+	}
+
+	expected := a.expectedColumns()
+	legacy := fields[Columns.PType] == nil && fields[legacyPTypeColumn] != nil
+	if legacy {
+		expected[0] = legacyPTypeColumn
+	}
+
+	found := make([]string, 0, len(fields))
+	for name := range fields {
+		found = append(found, name)
+	}
+	sort.Strings(found)
+	for _, column := range expected {
+		if fields[column] == nil {
+			return &IncompatibleSchemaError{Table: a.tableName, Found: found, Expected: expected}
+		}
+	}
+
+	if fields[scopeColumn] != nil && a.tenantColumn != scopeColumn {
+		return fmt.Errorf("%w: table %s", ErrScopeRequired, a.tableName)
+	}
+
+	a.pTypeColumn = expected[0]
+	a.softDelete = fields[deletedAtColumn] != nil
+	if logger := a.db.GetLogger(); logger != nil && legacy {
+		logger.Infof(ctx, "casbin adapter: table %s uses the gorm-adapter column naming", a.tableName)
+	}
+	return nil
+}
+
+// expectedColumns returns the columns the adapter requires in the policy
+// table.
+func (a *Adapter) expectedColumns() []string {
+	columns := []string{Columns.PType, "id", Columns.V0, Columns.V1, Columns.V2, Columns.V3, Columns.V4, Columns.V5}
+	if a.tenantColumn != "" {
+		columns = append(columns, a.tenantColumn)
+	}
+	if a.tombstones {
+		columns = append(columns, versionColumn, tombstoneColumn)
+	}
+	return columns
+}
+
+// WithAutoMigrate adds the columns missing from existing policy and history
+// tables when the adapter is created, e.g. the tenant column after
+// upgrading a table created by an older version. Columns are only ever
+// added, never dropped or changed. Adapters starting at the same time may
+// race to add a column, the losers ignore the duplicate column errors. Use
+// PlanMigration to review the statements first.
+func WithAutoMigrate() AdapterOption {
+	return AdapterOption{apply: func(a *Adapter) {
+		a.autoMigrate = true
+	}}
+}
+
+// PlanMigration returns the statements WithAutoMigrate would run for an
+// adapter created with the same arguments, without executing them or
+// creating any table.
+func PlanMigration(ctx context.Context, dbGroupName, tableName string, db gdb.DB, opts ...AdapterOption) ([]string, error) {
+	if ctx == nil {
+		return nil, errors.New("context cannot be nil")
+	}
+
+	adp := newAdapter(ctx, dbGroupName, tableName, db, opts)
+	if err := adp.configure(); err != nil {
+		return nil, fmt.Errorf("failed to open adapter: %w", err)
+	}
+	return adp.migrationPlan(ctx)
+}
+
+// migrateSchema adds the missing columns of the policy and history tables.
+func (a *Adapter) migrateSchema(ctx context.Context) error {
+	statements, err := a.migrationPlan(ctx)
+	if err != nil {
+		return err
+	}
+	if len(statements) == 0 {
+		return nil
+	}
+
+	for _, sql := range statements {
+		if _, err := a.db.Exec(ctx, sql); err != nil && !isDuplicateColumnError(err) {
+			return fmt.Errorf("failed to migrate table: %w", err)
+		}
+	}
+
+	for _, table := range []string{a.tableName, a.historyTable} {
+		if table == "" {
+			continue
+		}
+		if err := a.clearTableFields(ctx, table); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// migrationPlan returns the statements adding the missing columns of the
+// existing policy and history tables.
+func (a *Adapter) migrationPlan(ctx context.Context) ([]string, error) {
+	values := []string{Columns.V0, Columns.V1, Columns.V2, Columns.V3, Columns.V4, Columns.V5}
+
+	policyColumns := append([]string{Columns.PType}, values...)
+	policyColumns = append(policyColumns, "created_at")
+	if a.changeTracking {
+		policyColumns = append(policyColumns, trackingColumns...)
+	}
+	historyColumns := append([]string{Columns.PType}, values...)
+	if a.tenantColumn != "" {
+		policyColumns = append(policyColumns, a.tenantColumn)
+		historyColumns = append(historyColumns, a.tenantColumn)
+	}
+
+	statements, err := a.missingColumnsSql(ctx, a.tableName, policyColumns)
+	if err != nil {
+		return nil, err
+	}
+	if a.historyTable != "" {
+		history, err := a.missingColumnsSql(ctx, a.historyTable, historyColumns)
+		if err != nil {
+			return nil, err
+		}
+		statements = append(statements, history...)
+	}
+	return statements, nil
+}
+
+// missingColumnsSql returns the statements adding the columns missing from
+// table, nothing when the table doesn't exist.
+func (a *Adapter) missingColumnsSql(ctx context.Context, table string, columns []string) ([]string, error) {
+	fields, err := a.db.TableFields(ctx, table)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get columns of %s: %w", table, err)
+	}
+	if len(fields) == 0 {
+		return nil, nil
+	}
+
+	var statements []string
+	for _, column := range columns {
+		if fields[column] != nil {
+			continue
+		}
+		// Tables created by gorm-adapter keep their own policy type column.
+		if column == Columns.PType && fields[legacyPTypeColumn] != nil {
+			continue
+		}
+		statements = append(statements, a.dialect.addColumnSql(table, column, a.schema())...)
+	}
+	return statements, nil
+}
+
+// isDuplicateColumnError reports whether err was caused by adding a column
+// or an index that already exists.
+func isDuplicateColumnError(err error) bool {
+	msg := strings.ToLower(err.Error())
+	return strings.Contains(msg, "duplicate column") || strings.Contains(msg, "duplicate key name") ||
+		strings.Contains(msg, "specified more than once")
+}
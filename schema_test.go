@@ -0,0 +1,181 @@
+package adapter
+
+import (
+	"context"
+	"errors"
+	"reflect"
+	"testing"
+
+	"github.com/casbin/casbin/v2"
+	"github.com/gogf/gf/v2/database/gdb"
+)
+
+func TestDetectGormColumns(t *testing.T) {
+	db := newSqliteDB(t)
+	createGormTable(t, db, defaultTableName, legacyPTypeColumn)
+
+	a, err := NewAdapter(context.Background(), "", "", db, WithHistory())
+	if err != nil {
+		t.Fatalf("NewAdapter failed: %v", err)
+	}
+	if a.pTypeColumn != legacyPTypeColumn {
+		t.Errorf("policy type column %q, supposed to be %q", a.pTypeColumn, legacyPTypeColumn)
+	}
+
+	e, err := casbin.NewEnforcer("examples/rbac_model.conf", a)
+	if err != nil {
+		t.Fatalf("NewEnforcer failed: %v", err)
+	}
+	testSameDecisions(t, e)
+
+	// Writes and filtered loads go through the legacy column as well.
+	if _, err := e.AddPolicy("carol", "data1", "read"); err != nil {
+		t.Fatalf("AddPolicy failed: %v", err)
+	}
+	if _, err := e.RemoveFilteredPolicy(0, "bob"); err != nil {
+		t.Fatalf("RemoveFilteredPolicy failed: %v", err)
+	}
+	if err := e.LoadFilteredPolicy(Filter{PType: []string{"p"}, V1: []string{"data1"}}); err != nil {
+		t.Fatalf("LoadFilteredPolicy failed: %v", err)
+	}
+	// The duplicate of alice's rule in the legacy table is loaded once.
+	testGetPolicyWithoutOrder(t, e, [][]string{{"alice", "data1", "read"}, {"carol", "data1", "read"}})
+
+	count, err := db.Model(defaultTableName).Where(legacyPTypeColumn, "p").Where("v0", "carol").Count()
+	if err != nil || count != 1 {
+		t.Errorf("stored %d rules for carol, err: %v, supposed to be 1", count, err)
+	}
+}
+
+func TestDetectStandardColumns(t *testing.T) {
+	db := newSqliteDB(t)
+	createGormTable(t, db, defaultTableName, Columns.PType)
+
+	a, err := NewAdapter(context.Background(), "", "", db, WithoutAutoCreate())
+	if err != nil {
+		t.Fatalf("NewAdapter failed: %v", err)
+	}
+	if a.pTypeColumn != Columns.PType {
+		t.Errorf("policy type column %q, supposed to be %q", a.pTypeColumn, Columns.PType)
+	}
+
+	e, err := casbin.NewEnforcer("examples/rbac_model.conf", a)
+	if err != nil {
+		t.Fatalf("NewEnforcer failed: %v", err)
+	}
+	testSameDecisions(t, e)
+}
+
+func TestDetectIncompatibleColumns(t *testing.T) {
+	db := newSqliteDB(t)
+	if _, err := db.Exec(context.Background(), "CREATE TABLE casbin_rule (id INTEGER PRIMARY KEY, kind varchar(10), subject varchar(100))"); err != nil {
+		t.Fatalf("failed to create table: %v", err)
+	}
+
+	_, err := NewAdapter(context.Background(), "", "", db)
+	if !errors.Is(err, ErrIncompatibleSchema) {
+		t.Fatalf("expected ErrIncompatibleSchema, got %v", err)
+	}
+	var schemaErr *IncompatibleSchemaError
+	if !errors.As(err, &schemaErr) {
+		t.Fatalf("expected an *IncompatibleSchemaError, got %T", err)
+	}
+	if len(schemaErr.Found) != 3 || schemaErr.Found[0] != "id" {
+		t.Errorf("found columns %v, supposed to be [id kind subject]", schemaErr.Found)
+	}
+	if len(schemaErr.Expected) == 0 || schemaErr.Expected[0] != Columns.PType {
+		t.Errorf("expected columns %v, supposed to start with p_type", schemaErr.Expected)
+	}
+}
+
+// createV0Table creates a policy table as created by early versions of the
+// adapter, without created_at, v4, v5 and tenant columns.
+func createV0Table(t *testing.T, db gdb.DB) {
+	t.Helper()
+	stmts := []string{
+		`CREATE TABLE casbin_rule (
+			id INTEGER PRIMARY KEY AUTOINCREMENT,
+			p_type varchar(10), v0 varchar(256), v1 varchar(256), v2 varchar(256), v3 varchar(256)
+		)`,
+		`INSERT INTO casbin_rule (p_type, v0, v1, v2) VALUES ('p', 'alice', 'data1', 'read')`,
+	}
+	for _, stmt := range stmts {
+		if _, err := db.Exec(context.Background(), stmt); err != nil {
+			t.Fatalf("failed to create v0 table: %v", err)
+		}
+	}
+}
+
+func TestAutoMigrate(t *testing.T) {
+	ctx := context.Background()
+	db := newSqliteDB(t)
+	createV0Table(t, db)
+
+	if _, err := NewAdapter(ctx, "", "", db, WithTenantColumn("tenant_id")); !errors.Is(err, ErrIncompatibleSchema) {
+		t.Fatalf("expected ErrIncompatibleSchema without auto-migrate, got %v", err)
+	}
+
+	plan, err := PlanMigration(ctx, "", "", db, WithTenantColumn("tenant_id"))
+	if err != nil {
+		t.Fatalf("PlanMigration failed: %v", err)
+	}
+	want := []string{
+		"ALTER TABLE casbin_rule ADD COLUMN v4 varchar(256) DEFAULT NULL",
+		"ALTER TABLE casbin_rule ADD COLUMN v5 varchar(256) DEFAULT NULL",
+		"ALTER TABLE casbin_rule ADD COLUMN created_at datetime DEFAULT NULL",
+		"ALTER TABLE casbin_rule ADD COLUMN tenant_id varchar(64) NOT NULL DEFAULT ''",
+		"CREATE INDEX IF NOT EXISTS idx_casbin_rule_tenant_id ON casbin_rule (tenant_id)",
+	}
+	if !reflect.DeepEqual(plan, want) {
+		t.Fatalf("plan %q, supposed to be %q", plan, want)
+	}
+
+	a, err := NewAdapter(ctx, "", "", db, WithTenantColumn("tenant_id"), WithAutoMigrate())
+	if err != nil {
+		t.Fatalf("NewAdapter failed: %v", err)
+	}
+
+	// The existing rows belong to the default tenant, new columns are written.
+	e, err := casbin.NewEnforcer("examples/rbac_model.conf", a)
+	if err != nil {
+		t.Fatalf("NewEnforcer failed: %v", err)
+	}
+	testGetPolicy(t, e, [][]string{{"alice", "data1", "read"}})
+	if err := a.ForTenant("t1").AddPolicy("p", "p", []string{"bob", "data2", "write", "v3", "v4", "v5"}); err != nil {
+		t.Fatalf("AddPolicy failed: %v", err)
+	}
+	count, err := db.Model("casbin_rule").Where("tenant_id", "t1").Where("v5", "v5").Count()
+	if err != nil || count != 1 {
+		t.Errorf("stored %d rules for t1, err: %v, supposed to be 1", count, err)
+	}
+
+	if plan, err := PlanMigration(ctx, "", "", db, WithTenantColumn("tenant_id")); err != nil || len(plan) != 0 {
+		t.Errorf("plan after migration %q, err: %v, supposed to be empty", plan, err)
+	}
+}
+
+func TestAutoMigrateConcurrent(t *testing.T) {
+	ctx := context.Background()
+	db := newSqliteDB(t)
+	createV0Table(t, db)
+
+	// Another replica adds the columns after this one planned the migration,
+	// the duplicate column errors are ignored.
+	plan, err := PlanMigration(ctx, "", "", db)
+	if err != nil {
+		t.Fatalf("PlanMigration failed: %v", err)
+	}
+	for _, sql := range plan {
+		if _, err := db.Exec(ctx, sql); err != nil {
+			t.Fatalf("failed to run %q: %v", sql, err)
+		}
+	}
+
+	a, err := NewAdapter(ctx, "", "", db, WithAutoMigrate())
+	if err != nil {
+		t.Fatalf("NewAdapter failed: %v", err)
+	}
+	if err := a.AddPolicy("p", "p", []string{"bob", "data2", "write"}); err != nil {
+		t.Fatalf("AddPolicy failed: %v", err)
+	}
+}
@@ -0,0 +1,44 @@
+package adapter
+
+import "errors"
+
+// scopeColumn is the column of the policy table holding the scope of
+// WithScope.
+const scopeColumn = "scope"
+
+// ErrScopeRequired is returned by NewAdapter for a policy table with a
+// scope column when the adapter was created without WithScope, which would
+// read and replace the rules of every scope.
+var ErrScopeRequired = errors.New("policy table is scoped, use WithScope to select a scope")
+
+// WithScope adds a scope column to the policy table and restricts every
+// read and write of the adapter, SavePolicy included, to the rules of
+// scope, so that separate policy sets such as "api" and "batch" can share
+// one table. It is a fixed tenant of the tenant column "scope", and can't
+// be combined with WithTenantColumn.
+func WithScope(scope string) AdapterOption {
+	return AdapterOption{apply: func(a *Adapter) {
+		a.scope = scope
+	}}
+}
+
+// configureScope scopes the adapter by the tenant column of its scope.
+func (a *Adapter) configureScope() error {
+	if a.scope == "" {
+		return nil
+	}
+	if a.tenantColumn != "" {
+		return errors.New("WithScope can't be combined with WithTenantColumn")
+	}
+	a.tenantColumn = scopeColumn
+	a.tenant = a.scope
+	return nil
+}
+
+// Scope returns the scope of the adapter, empty without WithScope.
+func (a *Adapter) Scope() string {
+	if a.tenantColumn != scopeColumn {
+		return ""
+	}
+	return a.tenant
+}
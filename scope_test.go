@@ -0,0 +1,95 @@
+package adapter
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sync"
+	"testing"
+
+	"github.com/casbin/casbin/v2"
+)
+
+func TestScopeIsolation(t *testing.T) {
+	ctx := context.Background()
+	db := newSqliteDB(t)
+	scopes := []string{"api", "batch"}
+	enforcers := make([]*casbin.Enforcer, len(scopes))
+	for i, scope := range scopes {
+		a, err := NewAdapter(ctx, "", "", db, WithScope(scope))
+		if err != nil {
+			t.Fatalf("NewAdapter failed: %v", err)
+		}
+		if a.Scope() != scope {
+			t.Errorf("scope %q, supposed to be %q", a.Scope(), scope)
+		}
+		if enforcers[i], err = casbin.NewEnforcer("examples/rbac_model.conf", a); err != nil {
+			t.Fatalf("NewEnforcer failed: %v", err)
+		}
+	}
+
+	// Both enforcers write the same rules at once, each saving its policy
+	// halfway, which only replaces the rules of its own scope.
+	var wg sync.WaitGroup
+	errs := make(chan error, len(scopes))
+	for i, scope := range scopes {
+		wg.Add(1)
+		go func(e *casbin.Enforcer, scope string) {
+			defer wg.Done()
+			for j := 0; j < 20; j++ {
+				if _, err := e.AddPolicy("alice", fmt.Sprintf("data%d", j), "read"); err != nil {
+					errs <- err
+					return
+				}
+				if j == 10 {
+					if _, err := e.AddPolicy(scope, "data", "write"); err != nil {
+						errs <- err
+						return
+					}
+					if err := e.SavePolicy(); err != nil {
+						errs <- err
+						return
+					}
+				}
+			}
+			errs <- nil
+		}(enforcers[i], scope)
+	}
+	wg.Wait()
+	for range scopes {
+		if err := <-errs; err != nil {
+			t.Fatalf("write failed: %v", err)
+		}
+	}
+
+	for i, scope := range scopes {
+		e := enforcers[i]
+		if err := e.LoadPolicy(); err != nil {
+			t.Fatalf("LoadPolicy failed: %v", err)
+		}
+		want := [][]string{{scope, "data", "write"}}
+		for j := 0; j < 20; j++ {
+			want = append(want, []string{"alice", fmt.Sprintf("data%d", j), "read"})
+		}
+		testGetPolicyWithoutOrder(t, e, want)
+	}
+	count, err := db.Model(defaultTableName).Count()
+	if err != nil || count != 42 {
+		t.Errorf("stored %d rules, err: %v, supposed to be 42", count, err)
+	}
+}
+
+func TestScopeRequired(t *testing.T) {
+	ctx := context.Background()
+	db := newSqliteDB(t)
+	if _, err := NewAdapter(ctx, "", "", db, WithScope("api")); err != nil {
+		t.Fatalf("NewAdapter failed: %v", err)
+	}
+
+	if _, err := NewAdapter(ctx, "", "", db); !errors.Is(err, ErrScopeRequired) {
+		t.Errorf("NewAdapter without scope err: %v, supposed to be ErrScopeRequired", err)
+	}
+	if _, err := NewAdapter(ctx, "", "", db, WithTenantColumn("tenant_id"), WithScope("api")); err == nil {
+		t.Error("expected an error combining a scope with a tenant column")
+	}
+}
@@ -0,0 +1,70 @@
+package adapter
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"slices"
+	"strings"
+)
+
+// SearchPolicies returns the stored rules with keyword in their policy type
+// or any of their values, at most limit of them ordered by id, limit being
+// capped at 1000. The keyword is matched literally, its % and _ aren't
+// wildcards, with the case sensitivity of the column collation. When
+// pTypes are given only the rules of these policy types are searched. The
+// search scans the table, it is canceled with ctx. The values encoded by a
+// codec or hashed aren't searched.
+func (a *Adapter) SearchPolicies(ctx context.Context, keyword string, limit int, pTypes ...string) ([]Rule, error) {
+	if keyword == "" {
+		return nil, errors.New("search keyword cannot be empty")
+	}
+	if err := a.checkUnpacked(); err != nil {
+		return nil, err
+	}
+	if limit < 1 {
+		return nil, fmt.Errorf("invalid search limit: %d", limit)
+	}
+	limit = min(limit, maxListPageSize)
+	if a.routed() {
+		var rules []Rule
+		for _, table := range a.splitTables() {
+			tableRules, err := table.SearchPolicies(ctx, keyword, limit-len(rules), pTypes...)
+			if err != nil {
+				return nil, err
+			}
+			if rules = append(rules, tableRules...); len(rules) == limit {
+				break
+			}
+		}
+		return rules, nil
+	}
+
+	pattern := "%" + likeEscaped(keyword) + "%"
+	columns := []string{a.pTypeColumn}
+	for i, column := range valueColumns {
+		if a.codec == nil && (a.hasher == nil || !slices.Contains(a.hashedFields, i)) {
+			columns = append(columns, column)
+		}
+	}
+	conditions := make([]string, len(columns))
+	args := make([]interface{}, len(columns))
+	for i, column := range columns {
+		conditions[i] = column + " LIKE ? ESCAPE '" + likeEscape + "'"
+		args[i] = pattern
+	}
+
+	query := a.modelCtx(ctx).Where("("+strings.Join(conditions, " OR ")+")", args...)
+	if len(pTypes) > 0 {
+		query = query.WhereIn(a.pTypeColumn, pTypes)
+	}
+	var rows []ruleRow
+	if err := query.OrderAsc("id").Limit(limit).Scan(&rows); err != nil {
+		return nil, fmt.Errorf("failed to search policy rules: %w", err)
+	}
+	rules := make([]Rule, len(rows))
+	for i, row := range rows {
+		rules[i] = row.Rule
+	}
+	return rules, nil
+}
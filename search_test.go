@@ -0,0 +1,59 @@
+package adapter
+
+import (
+	"context"
+	"slices"
+	"testing"
+	"time"
+)
+
+func TestSearchPolicies(t *testing.T) {
+	ctx := context.Background()
+	a := newSqliteAdapter(t)
+	initPolicy(t, a)
+	rules := [][]string{{"carol", "data_2", "read"}, {"dave", "100%", "read"}, {"erin", "data12", "read"}}
+	if err := a.AddPolicies("p", "p", rules); err != nil {
+		t.Fatalf("AddPolicies failed: %v", err)
+	}
+
+	for _, tc := range []struct {
+		keyword string
+		pTypes  []string
+		want    []string
+	}{
+		{keyword: "data2", want: []string{"bob", "data2_admin", "data2_admin", "alice"}},
+		{keyword: "data2", pTypes: []string{"g"}, want: []string{"alice"}},
+		// _ and % are matched literally.
+		{keyword: "data_", want: []string{"carol"}},
+		{keyword: "0%", want: []string{"dave"}},
+		{keyword: "%", want: []string{"dave"}},
+		{keyword: "nobody"},
+	} {
+		found, err := a.SearchPolicies(ctx, tc.keyword, 10, tc.pTypes...)
+		if err != nil {
+			t.Fatalf("%q: SearchPolicies failed: %v", tc.keyword, err)
+		}
+		var subjects []string
+		for _, rule := range found {
+			subjects = append(subjects, rule.V0)
+		}
+		if !slices.Equal(subjects, tc.want) {
+			t.Errorf("%q %v: found %v, supposed to be %v", tc.keyword, tc.pTypes, subjects, tc.want)
+		}
+	}
+
+	if found, err := a.SearchPolicies(ctx, "data", 2); err != nil || len(found) != 2 || found[0].V0 != "alice" {
+		t.Errorf("found %v, err: %v, supposed to be the first 2 rules", found, err)
+	}
+	if _, err := a.SearchPolicies(ctx, "", 10); err == nil {
+		t.Error("expected an empty keyword to fail the search")
+	}
+	if _, err := a.SearchPolicies(ctx, "data", 0); err == nil {
+		t.Error("expected a missing limit to fail the search")
+	}
+	expired, cancel := context.WithDeadline(ctx, time.Now().Add(-time.Second))
+	defer cancel()
+	if _, err := a.SearchPolicies(expired, "data", 10); err == nil {
+		t.Error("expected an expired deadline to fail the search")
+	}
+}
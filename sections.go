@@ -0,0 +1,52 @@
+package adapter
+
+import (
+	"errors"
+	"fmt"
+	"slices"
+	"sort"
+	"strings"
+
+	"github.com/casbin/casbin/v2/model"
+)
+
+// policySections are the sections of a model holding the stored rules, the
+// policy rules p, p2, ... and the grouping rules g, g2, ... Rules are
+// loaded into the section named by the first letter of their type.
+var policySections = []string{"p", "g"}
+
+// ErrUnknownSection is returned by SavePolicy for a model holding rules the
+// adapter can't store: rules outside the p and g sections, or of a type that
+// doesn't start with the letter of its section, which would be loaded into
+// another section.
+var ErrUnknownSection = errors.New("unknown policy section")
+
+// checkSections checks that every rule of m can be stored.
+func checkSections(m model.Model) error {
+	for sec, assertions := range m {
+		stored := slices.Contains(policySections, sec)
+		for pType, ast := range assertions {
+			if len(ast.Policy) == 0 {
+				continue
+			}
+			if !stored {
+				return fmt.Errorf("%w: section %q holds %d rules of %s", ErrUnknownSection, sec, len(ast.Policy), pType)
+			}
+			if !strings.HasPrefix(pType, sec) {
+				return fmt.Errorf("%w: policy type %s in section %q", ErrUnknownSection, pType, sec)
+			}
+		}
+	}
+	return nil
+}
+
+// sectionTypes returns the policy types of section sec of m in order, g
+// before g2.
+func sectionTypes(m model.Model, sec string) []string {
+	pTypes := make([]string, 0, len(m[sec]))
+	for pType := range m[sec] {
+		pTypes = append(pTypes, pType)
+	}
+	sort.Strings(pTypes)
+	return pTypes
+}
@@ -0,0 +1,89 @@
+package adapter
+
+import (
+	"errors"
+	"reflect"
+	"testing"
+
+	"github.com/casbin/casbin/v2"
+	"github.com/casbin/casbin/v2/model"
+)
+
+const (
+	resourceRolesModel  = "examples/rbac_with_resource_roles_model.conf"
+	resourceRolesPolicy = "examples/rbac_with_resource_roles_policy.csv"
+)
+
+// testGrouping checks the grouping rules of pType loaded by e.
+func testGrouping(t *testing.T, e *casbin.Enforcer, pType string, want [][]string) {
+	t.Helper()
+	rules, err := e.GetNamedGroupingPolicy(pType)
+	if err != nil {
+		t.Fatalf("GetNamedGroupingPolicy failed: %v", err)
+	}
+	if len(rules) == 0 && len(want) == 0 {
+		return
+	}
+	if !reflect.DeepEqual(rules, want) {
+		t.Errorf("%s rules %v, supposed to be %v", pType, rules, want)
+	}
+}
+
+func TestGroupingTypes(t *testing.T) {
+	a := newSqliteAdapter(t)
+	seedPolicy(t, a, resourceRolesModel, resourceRolesPolicy)
+
+	e, err := casbin.NewEnforcer(resourceRolesModel, a)
+	if err != nil {
+		t.Fatalf("NewEnforcer failed: %v", err)
+	}
+	testGetPolicy(t, e, [][]string{{"alice", "data1", "read"}, {"bob", "data2", "write"}, {"data_group_admin", "data_group", "write"}})
+	testGrouping(t, e, "g", [][]string{{"alice", "data_group_admin"}})
+	testGrouping(t, e, "g2", [][]string{{"data1", "data_group"}, {"data2", "data_group"}})
+	if ok, _ := e.Enforce("alice", "data2", "write"); !ok {
+		t.Error("alice can't write data2 through the resource role")
+	}
+
+	if err := e.LoadFilteredPolicy(Filter{PType: []string{"g2"}}); err != nil {
+		t.Fatalf("LoadFilteredPolicy failed: %v", err)
+	}
+	testGetPolicy(t, e, nil)
+	testGrouping(t, e, "g", nil)
+	testGrouping(t, e, "g2", [][]string{{"data1", "data_group"}, {"data2", "data_group"}})
+
+	if err := a.RemoveFilteredPolicy("g", "g2", 0, "data1"); err != nil {
+		t.Fatalf("RemoveFilteredPolicy failed: %v", err)
+	}
+	if err := e.LoadPolicy(); err != nil {
+		t.Fatalf("LoadPolicy failed: %v", err)
+	}
+	testGrouping(t, e, "g", [][]string{{"alice", "data_group_admin"}})
+	testGrouping(t, e, "g2", [][]string{{"data2", "data_group"}})
+	if ok, _ := e.Enforce("alice", "data1", "read"); !ok {
+		t.Error("alice lost the direct permission on data1")
+	}
+}
+
+func TestUnknownSection(t *testing.T) {
+	for name, add := range map[string]func(m model.Model){
+		"section": func(m model.Model) {
+			m["x"] = model.AssertionMap{"x": &model.Assertion{Key: "x", Policy: [][]string{{"alice", "data1"}}}}
+		},
+		"policy type": func(m model.Model) {
+			m["p"]["g3"] = &model.Assertion{Key: "g3", Policy: [][]string{{"alice", "admin"}}}
+		},
+	} {
+		a := newSqliteAdapter(t)
+		initPolicy(t, a)
+		m, _ := model.NewModelFromFile(resourceRolesModel)
+		add(m)
+		if err := a.SavePolicy(m); !errors.Is(err, ErrUnknownSection) {
+			t.Errorf("%s: SavePolicy err: %v, supposed to be ErrUnknownSection", name, err)
+		}
+		// The stored rules are kept.
+		e, _ := casbin.NewEnforcer("examples/rbac_model.conf", a)
+		if ok, _ := e.HasPolicy("alice", "data1", "read"); !ok {
+			t.Errorf("%s: the failed save removed the stored rules", name)
+		}
+	}
+}
@@ -0,0 +1,247 @@
+package adapter
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"slices"
+
+	"github.com/casbin/casbin/v2/model"
+	"github.com/gogf/gf/v2/database/gdb"
+)
+
+// WithSplitTables stores the policy rules, p, p2 and so on, in table pTable
+// and the grouping rules, g, g2 and so on, in table gTable, in place of the
+// table given to NewAdapter. Both names get the database prefix. It is the
+// table routing of WithTableRouting mapping the g section to gTable, with
+// pTable as the default table.
+func WithSplitTables(pTable, gTable string) AdapterOption {
+	return AdapterOption{apply: func(a *Adapter) {
+		a.tableName = pTable
+		a.addRoutes(map[string]string{"g": gTable})
+	}}
+}
+
+// WithTableRouting stores the rules of some policy types in tables of their
+// own, with the layout of the policy table. routes maps a policy type, like
+// p2, or a section, p or g, to a table name, which gets the database
+// prefix. A policy type is routed to its own table first, then to the one
+// of its section, and the unrouted types stay in the table given to
+// NewAdapter. Every rule is written to the table of its type. LoadPolicy
+// and SavePolicy cover all the tables, the default one first, and the
+// filters only query the tables their PType can match. A filter querying
+// several tables can't be paged. The quota of WithMaxRules applies to each
+// table. Routed tables can't be combined with WithHistory,
+// WithChangeTracking or WithParallelWrites.
+func WithTableRouting(routes map[string]string) AdapterOption {
+	return AdapterOption{apply: func(a *Adapter) {
+		a.addRoutes(routes)
+	}}
+}
+
+// addRoutes adds routes to the table routes of the adapter.
+func (a *Adapter) addRoutes(routes map[string]string) {
+	if a.routes == nil {
+		a.routes = make(map[string]string, len(routes))
+	}
+	for pType, table := range routes {
+		a.routes[pType] = table
+	}
+}
+
+// validateSplit checks the options of routed tables.
+func (a *Adapter) validateSplit() error {
+	if !a.routed() {
+		return nil
+	}
+	for pType, table := range a.routes {
+		switch {
+		case pType == "" || table == "":
+			return fmt.Errorf("invalid table route %q to %q", pType, table)
+		case table == a.tableName:
+			return fmt.Errorf("routed tables need tables of their own, %s is the default table", table)
+		}
+	}
+	switch {
+	case a.history:
+		return errors.New("routed tables can't be combined with history")
+	case a.changeTracking:
+		return errors.New("routed tables can't be combined with change tracking")
+	case a.writers > 1:
+		return errors.New("routed tables can't be combined with parallel writes")
+	}
+	return nil
+}
+
+// routed reports whether some rules are routed to other tables than the
+// default one.
+func (a *Adapter) routed() bool {
+	return len(a.routes) > 0
+}
+
+// routeOf returns the table storing the rules of pType.
+func (a *Adapter) routeOf(pType string) string {
+	if table, ok := a.routes[pType]; ok {
+		return table
+	}
+	if pType != "" {
+		if table, ok := a.routes[pType[:1]]; ok {
+			return table
+		}
+	}
+	return a.tableName
+}
+
+// routedTableNames returns the tables of the adapter, the default one
+// first, then the routed ones by name.
+func (a *Adapter) routedTableNames() []string {
+	tables := make([]string, 0, len(a.routes)+1)
+	for _, table := range a.routes {
+		if !slices.Contains(tables, table) {
+			tables = append(tables, table)
+		}
+	}
+	slices.Sort(tables)
+	return append([]string{a.tableName}, tables...)
+}
+
+// splitTables returns copies of the adapter for each of its tables, the
+// default one first.
+func (a *Adapter) splitTables() []*Adapter {
+	names := a.routedTableNames()
+	tables := make([]*Adapter, 0, len(names))
+	for _, name := range names {
+		tables = append(tables, a.onTable(name))
+	}
+	return tables
+}
+
+// onTable returns a copy of the adapter storing its rules in table, without
+// routes.
+func (a *Adapter) onTable(table string) *Adapter {
+	clone := *a
+	clone.tableName = table
+	clone.routes = nil
+	return &clone
+}
+
+// groupingFor returns the adapter of the routed table when the rules of
+// pType are stored there, nil when they are stored in the default table.
+func (a *Adapter) groupingFor(pType string) *Adapter {
+	if !a.routed() {
+		return nil
+	}
+	if table := a.routeOf(pType); table != a.tableName {
+		return a.onTable(table)
+	}
+	return nil
+}
+
+// tableFor returns the adapter storing the rules of pType.
+func (a *Adapter) tableFor(pType string) *Adapter {
+	if g := a.groupingFor(pType); g != nil {
+		return g
+	}
+	return a
+}
+
+// filterTables returns the adapters of the tables holding the rules
+// matching one of filters, all of them without filters.
+func (a *Adapter) filterTables(filters []Filter) ([]*Adapter, error) {
+	var names []string
+	for _, filter := range filters {
+		if len(filter.PType) == 0 {
+			names = nil
+			break
+		}
+		for _, pType := range filter.PType {
+			if table := a.routeOf(pType); !slices.Contains(names, table) {
+				names = append(names, table)
+			}
+		}
+	}
+	if len(names) == 1 {
+		return []*Adapter{a.onTable(names[0])}, nil
+	}
+	if len(filters) > 0 && (filters[0].Limit > 0 || filters[0].Offset > 0 || filters[0].OrderBy != "") {
+		return nil, fmt.Errorf("%w: a paged filter must only match the rules of one table with routed tables", ErrInvalidFilter)
+	}
+	if names == nil {
+		return a.splitTables(), nil
+	}
+	tables := make([]*Adapter, 0, len(names))
+	for _, table := range a.splitTables() {
+		if slices.Contains(names, table.tableName) {
+			tables = append(tables, table)
+		}
+	}
+	return tables, nil
+}
+
+// splitRows returns the rows of the tables matching filters, those of the
+// default table first.
+func (a *Adapter) splitRows(ctx context.Context, filters []Filter) ([]ruleRow, error) {
+	tables, err := a.filterTables(filters)
+	if err != nil {
+		return nil, err
+	}
+	var rows []ruleRow
+	for _, table := range tables {
+		tableRows, err := table.filteredRows(ctx, filters)
+		if err != nil {
+			return nil, err
+		}
+		rows = append(rows, tableRows...)
+	}
+	return rows, nil
+}
+
+// splitCount returns the number of rules of the tables matching filters.
+func (a *Adapter) splitCount(ctx context.Context, filters []Filter) (int64, error) {
+	tables, err := a.filterTables(filters)
+	if err != nil {
+		return 0, err
+	}
+	var count int64
+	for _, table := range tables {
+		var filter interface{}
+		if filters != nil {
+			filter = filters
+		}
+		n, err := table.CountPolicies(ctx, filter)
+		if err != nil {
+			return 0, err
+		}
+		count += n
+	}
+	return count, nil
+}
+
+// saveSplit runs save, savePolicy or syncPolicy, on each table with the
+// rules of its policy types, in one transaction.
+func (a *Adapter) saveSplit(ctx context.Context, m model.Model, save func(a *Adapter, ctx context.Context, m model.Model) error) error {
+	ctx = withWatcherMessage(ctx, &WatcherMessage{Method: WatcherSavePolicy}, m)
+	return a.transaction(ctx, func(ctx context.Context, tx gdb.TX) error {
+		for _, table := range a.splitTables() {
+			if err := save(table, ctx, a.routedModel(m, table.tableName)); err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+}
+
+// routedModel returns the p and g assertions of m whose rules are stored in
+// table.
+func (a *Adapter) routedModel(m model.Model, table string) model.Model {
+	routed := model.Model{}
+	for _, sec := range []string{"p", "g"} {
+		routed[sec] = model.AssertionMap{}
+		for pType, ast := range m[sec] {
+			if a.routeOf(pType) == table {
+				routed[sec][pType] = ast
+			}
+		}
+	}
+	return routed
+}
@@ -0,0 +1,114 @@
+package adapter
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/casbin/casbin/v2"
+)
+
+// tableCount returns the number of rows of table.
+func tableCount(t *testing.T, a *Adapter, table string) int {
+	t.Helper()
+	count, err := a.db.Model(table).Count()
+	if err != nil {
+		t.Fatalf("failed to count rows of %s: %v", table, err)
+	}
+	return count
+}
+
+func TestSplitTables(t *testing.T) {
+	ctx := context.Background()
+	a, err := NewAdapter(ctx, "", "", newSqliteDB(t), WithSplitTables("casbin_p", "casbin_g"))
+	if err != nil {
+		t.Fatalf("NewAdapter failed: %v", err)
+	}
+	seedPolicy(t, a, resourceRolesModel, resourceRolesPolicy)
+	if p, g := tableCount(t, a, "casbin_p"), tableCount(t, a, "casbin_g"); p != 3 || g != 3 {
+		t.Errorf("saved %d p and %d g rows, supposed to be 3 and 3", p, g)
+	}
+
+	e, err := casbin.NewEnforcer(resourceRolesModel, a)
+	if err != nil {
+		t.Fatalf("NewEnforcer failed: %v", err)
+	}
+	testGetPolicy(t, e, [][]string{{"alice", "data1", "read"}, {"bob", "data2", "write"}, {"data_group_admin", "data_group", "write"}})
+	testGrouping(t, e, "g", [][]string{{"alice", "data_group_admin"}})
+	testGrouping(t, e, "g2", [][]string{{"data1", "data_group"}, {"data2", "data_group"}})
+
+	// The changes are routed to the table of their section.
+	if _, err := e.AddGroupingPolicy("bob", "data_group_admin"); err != nil {
+		t.Fatalf("AddGroupingPolicy failed: %v", err)
+	}
+	if _, err := e.RemovePolicy("bob", "data2", "write"); err != nil {
+		t.Fatalf("RemovePolicy failed: %v", err)
+	}
+	if _, err := e.RemoveFilteredNamedGroupingPolicy("g2", 0, "data1"); err != nil {
+		t.Fatalf("RemoveFilteredNamedGroupingPolicy failed: %v", err)
+	}
+	if p, g := tableCount(t, a, "casbin_p"), tableCount(t, a, "casbin_g"); p != 2 || g != 3 {
+		t.Errorf("stored %d p and %d g rows, supposed to be 2 and 3", p, g)
+	}
+	roles, err := a.GetUsersForRole(ctx, "data_group_admin")
+	if err != nil || len(roles) != 2 {
+		t.Errorf("users %v of data_group_admin, err: %v, supposed to be alice and bob", roles, err)
+	}
+
+	// The filters query the tables of their policy types.
+	if err := e.LoadFilteredPolicy(Filter{PType: []string{"g2"}}); err != nil {
+		t.Fatalf("LoadFilteredPolicy failed: %v", err)
+	}
+	testGetPolicy(t, e, nil)
+	testGrouping(t, e, "g2", [][]string{{"data2", "data_group"}})
+	if err := e.LoadFilteredPolicy(Filter{V0: []string{"alice"}}); err != nil {
+		t.Fatalf("LoadFilteredPolicy failed: %v", err)
+	}
+	testGetPolicy(t, e, [][]string{{"alice", "data1", "read"}})
+	testGrouping(t, e, "g", [][]string{{"alice", "data_group_admin"}})
+
+	if _, err := a.GetFilteredPolicies(ctx, Filter{V0: []string{"alice"}, Limit: 1}); !errors.Is(err, ErrInvalidFilter) {
+		t.Errorf("paged filter of both tables err: %v, supposed to be ErrInvalidFilter", err)
+	}
+	rules, err := a.GetFilteredPolicies(ctx, Filter{PType: []string{"g"}, Limit: 1, Offset: 1})
+	if err != nil || len(rules) != 1 || rules[0].V0 != "bob" {
+		t.Errorf("paged g rules %v, err: %v, supposed to be bob's", rules, err)
+	}
+	if count, err := a.CountPolicies(ctx, nil); err != nil || count != 5 {
+		t.Errorf("counted %d rules, err: %v, supposed to be 5", count, err)
+	}
+
+	// Saving replaces the rules of both tables.
+	if err := e.LoadPolicy(); err != nil {
+		t.Fatalf("LoadPolicy failed: %v", err)
+	}
+	e.EnableAutoSave(false)
+	if _, err := e.RemoveGroupingPolicy("alice", "data_group_admin"); err != nil {
+		t.Fatalf("RemoveGroupingPolicy failed: %v", err)
+	}
+	if err := e.SavePolicy(); err != nil {
+		t.Fatalf("SavePolicy failed: %v", err)
+	}
+	if p, g := tableCount(t, a, "casbin_p"), tableCount(t, a, "casbin_g"); p != 2 || g != 2 {
+		t.Errorf("saved %d p and %d g rows, supposed to be 2 and 2", p, g)
+	}
+	if err := e.LoadPolicy(); err != nil {
+		t.Fatalf("LoadPolicy failed: %v", err)
+	}
+	testGrouping(t, e, "g", [][]string{{"bob", "data_group_admin"}})
+	if ok, _ := e.Enforce("bob", "data2", "write"); !ok {
+		t.Error("bob can't write data2 through the resource role")
+	}
+}
+
+func TestSplitTablesOptions(t *testing.T) {
+	for name, opts := range map[string][]AdapterOption{
+		"same table": {WithSplitTables("casbin_rule", "casbin_rule")},
+		"history":    {WithSplitTables("casbin_p", "casbin_g"), WithHistory()},
+		"parallel":   {WithSplitTables("casbin_p", "casbin_g"), WithParallelWrites(4)},
+	} {
+		if _, err := NewAdapter(context.Background(), "", "", newSqliteDB(t), opts...); err == nil {
+			t.Errorf("%s: expected an error", name)
+		}
+	}
+}
@@ -0,0 +1,86 @@
+package adapter
+
+import (
+	"context"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/casbin/casbin/v2/model"
+)
+
+// LoadInfo describes a successful load of the stored rules.
+type LoadInfo struct {
+	// Time is the time the load completed.
+	Time     time.Time
+	Duration time.Duration
+	// Rules is the number of rules in the loaded model.
+	Rules    int
+	Filtered bool
+}
+
+// adapterStats holds the statistics of an adapter, shared by its copies.
+type adapterStats struct {
+	slowOps atomic.Int64
+
+	mu       sync.RWMutex
+	lastLoad LoadInfo
+	loaded   bool
+	lastErr  error
+}
+
+// LastLoadInfo returns the last successful load of the adapter or its
+// copies, and false before the first one.
+func (a *Adapter) LastLoadInfo() (LoadInfo, bool) {
+	a.stats.mu.RLock()
+	defer a.stats.mu.RUnlock()
+	return a.stats.lastLoad, a.stats.loaded
+}
+
+// LastErr returns the error of the last failed load or change of the
+// adapter or its copies, nil when none failed. Later successes don't clear
+// it.
+func (a *Adapter) LastErr() error {
+	a.stats.mu.RLock()
+	defer a.stats.mu.RUnlock()
+	return a.stats.lastErr
+}
+
+// recordErr records err as the last error.
+func (s *adapterStats) recordErr(err error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.lastErr = err
+}
+
+// measureLoad runs the load method of model, recording it in the statistics
+// and measuring it.
+func (a *Adapter) measureLoad(ctx context.Context, method string, filter []Filter, model model.Model, load func(ctx context.Context) error) error {
+	start := time.Now()
+	ctx, m := a.startMeasure(ctx, method)
+	if m != nil {
+		m.filter = filter
+	}
+	err := a.checkTx()
+	if err == nil {
+		err = load(ctx)
+	}
+	err = a.withContext(err)
+	rules := modelRuleCount(model)
+
+	if err != nil {
+		a.stats.recordErr(err)
+	} else {
+		info := LoadInfo{Time: a.now(), Duration: time.Since(start), Rules: rules, Filtered: filter != nil}
+		a.stats.mu.Lock()
+		a.stats.lastLoad, a.stats.loaded = info, true
+		a.stats.mu.Unlock()
+		if filter == nil {
+			a.warnQuota(ctx, rules)
+		}
+	}
+	if m != nil {
+		a.finishMeasure(ctx, m, rules, err)
+	}
+	return err
+}
@@ -0,0 +1,63 @@
+package adapter
+
+import (
+	"context"
+	"fmt"
+	"testing"
+	"time"
+
+	"github.com/casbin/casbin/v2"
+)
+
+func TestLastLoadInfo(t *testing.T) {
+	now := time.Date(2024, 3, 5, 10, 0, 0, 0, time.UTC)
+	a := newSqliteAdapter(t, withClock(&testClock{now: now}))
+	if _, ok := a.LastLoadInfo(); ok {
+		t.Error("load info before the first load")
+	}
+	if err := a.LastErr(); err != nil {
+		t.Errorf("LastErr returned %v before any failure", err)
+	}
+
+	initPolicy(t, a)
+	e, err := casbin.NewEnforcer("examples/rbac_model.conf", a)
+	if err != nil {
+		t.Fatalf("NewEnforcer failed: %v", err)
+	}
+	info, ok := a.LastLoadInfo()
+	if !ok || !info.Time.Equal(now) || info.Rules != 5 || info.Filtered || info.Duration <= 0 {
+		t.Errorf("load info %+v, %v after LoadPolicy", info, ok)
+	}
+
+	if err := e.LoadFilteredPolicy(Filter{V0: []string{"alice"}}); err != nil {
+		t.Fatalf("LoadFilteredPolicy failed: %v", err)
+	}
+	if info, _ := a.LastLoadInfo(); info.Rules != 2 || !info.Filtered {
+		t.Errorf("load info %+v after LoadFilteredPolicy", info)
+	}
+
+	// A failed load keeps the info of the last successful one.
+	if _, err := a.db.Exec(context.Background(), fmt.Sprintf("DROP TABLE %s", a.tableName)); err != nil {
+		t.Fatalf("failed to drop table: %v", err)
+	}
+	err = e.LoadPolicy()
+	if err == nil {
+		t.Fatal("expected LoadPolicy to fail without the table")
+	}
+	if info, _ := a.LastLoadInfo(); info.Rules != 2 || !info.Filtered {
+		t.Errorf("load info %+v after a failed load", info)
+	}
+	if last := a.LastErr(); last == nil || last.Error() != err.Error() {
+		t.Errorf("LastErr returned %v, supposed to be %v", last, err)
+	}
+
+	// Failed changes are recorded too, by the copies of the adapter as
+	// well.
+	err = a.Local().AddPolicy("p", "p", []string{"carol", "data1", "read"})
+	if err == nil {
+		t.Fatal("expected AddPolicy to fail without the table")
+	}
+	if last := a.LastErr(); last == nil || last.Error() != err.Error() {
+		t.Errorf("LastErr returned %v, supposed to be %v", last, err)
+	}
+}
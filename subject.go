@@ -0,0 +1,75 @@
+package adapter
+
+import (
+	"context"
+	"errors"
+	"fmt"
+
+	"github.com/casbin/casbin/v2/model"
+)
+
+// maxRoleDepth is the number of role levels followed by
+// LoadPolicyForSubject, the default of the casbin role manager.
+const maxRoleDepth = 10
+
+// LoadPolicyForSubject loads the rules needed to enforce the requests of
+// subject: its g rules and those of the roles it inherits, and the p rules
+// of the subject and of these roles. When a domain is given only the rules
+// of that domain are loaded. It only follows the g policy type, up to 10
+// role levels, and fails for deeper role hierarchies. The adapter is
+// filtered afterwards.
+func (a *Adapter) LoadPolicyForSubject(ctx context.Context, model model.Model, subject string, domain ...string) error {
+	if model == nil {
+		return errors.New("model cannot be nil")
+	}
+	if len(domain) > 1 {
+		return errors.New("domain should be 1 parameter")
+	}
+
+	measured := []Filter{{V0: []string{subject}}}
+	return a.measureLoad(ctx, "LoadPolicyForSubject", measured, model, func(ctx context.Context) error {
+		closure, err := a.roleClosure(ctx, subject, domain)
+		if err != nil {
+			return err
+		}
+		return a.loadFilteredPolicy(ctx, model, []Filter{
+			a.domainFilter(Filter{PType: []string{"g"}, V0: closure}, a.gDomainIndex, domain),
+			a.domainFilter(Filter{PType: []string{"p"}, V0: closure}, a.pDomainIndex, domain),
+		})
+	})
+}
+
+// roleClosure returns subject followed by the roles it inherits through the
+// stored g rules, level by level.
+func (a *Adapter) roleClosure(ctx context.Context, subject string, domain []string) ([]string, error) {
+	closure := []string{subject}
+	seen := map[string]bool{subject: true}
+	for level, members := 1, closure; len(members) > 0; level++ {
+		filter := a.domainFilter(Filter{PType: []string{"g"}, V0: members}, a.gDomainIndex, domain)
+		rows, err := a.filteredRows(ctx, []Filter{filter})
+		if err != nil {
+			return nil, fmt.Errorf("failed to read roles: %w", err)
+		}
+		members = nil
+		for _, row := range rows {
+			if role := row.V1; role != "" && !seen[role] {
+				seen[role] = true
+				members = append(members, role)
+			}
+		}
+		if len(members) > 0 && level > maxRoleDepth {
+			return nil, fmt.Errorf("roles of %q nested deeper than %d levels", subject, maxRoleDepth)
+		}
+		closure = append(closure, members...)
+	}
+	return closure, nil
+}
+
+// domainFilter returns filter restricted to the domain at index, when a
+// domain is given.
+func (a *Adapter) domainFilter(filter Filter, index int, domain []string) Filter {
+	if len(domain) == 1 {
+		*filterFields(&filter)[index+1] = []string{domain[0]}
+	}
+	return filter
+}
@@ -0,0 +1,82 @@
+package adapter
+
+import (
+	"context"
+	"fmt"
+	"testing"
+
+	"github.com/casbin/casbin/v2"
+)
+
+func TestLoadPolicyForSubject(t *testing.T) {
+	ctx := context.Background()
+	a := newSqliteAdapter(t)
+	if err := a.AddPolicies("p", "p", [][]string{
+		{"viewer", "data1", "read"},
+		{"editor", "data1", "write"},
+		{"alice", "data2", "read"},
+		{"bob", "data3", "read"},
+	}); err != nil {
+		t.Fatalf("AddPolicies failed: %v", err)
+	}
+	// The roles of alice are two levels deep, and viewer loops back to
+	// editor.
+	if err := a.AddPolicies("g", "g", [][]string{{"alice", "editor"}, {"editor", "viewer"}, {"viewer", "editor"}, {"bob", "viewer"}}); err != nil {
+		t.Fatalf("AddPolicies failed: %v", err)
+	}
+
+	e, _ := casbin.NewEnforcer("examples/rbac_model.conf", a)
+	e.ClearPolicy()
+	if err := a.LoadPolicyForSubject(ctx, e.GetModel(), "alice"); err != nil {
+		t.Fatalf("LoadPolicyForSubject failed: %v", err)
+	}
+	if err := e.BuildRoleLinks(); err != nil {
+		t.Fatalf("BuildRoleLinks failed: %v", err)
+	}
+	testGetPolicy(t, e, [][]string{{"viewer", "data1", "read"}, {"editor", "data1", "write"}, {"alice", "data2", "read"}})
+	if grouping, _ := e.GetGroupingPolicy(); len(grouping) != 3 {
+		t.Errorf("grouping policy %v, supposed to be the 3 rules of alice and the inherited roles", grouping)
+	}
+	for _, request := range [][]interface{}{{"alice", "data1", "read"}, {"alice", "data1", "write"}, {"alice", "data2", "read"}} {
+		if ok, _ := e.Enforce(request...); !ok {
+			t.Errorf("%v denied, supposed to be allowed", request)
+		}
+	}
+	if !a.IsFiltered() {
+		t.Error("the adapter isn't filtered after loading a subject")
+	}
+}
+
+func TestLoadPolicyForSubjectDomain(t *testing.T) {
+	a := newSqliteAdapter(t)
+	seedPolicy(t, a, "examples/rbac_with_domains_model.conf", "examples/rbac_with_domains_policy.csv")
+
+	e, _ := casbin.NewEnforcer("examples/rbac_with_domains_model.conf", a)
+	e.ClearPolicy()
+	if err := a.LoadPolicyForSubject(context.Background(), e.GetModel(), "alice", "domain1"); err != nil {
+		t.Fatalf("LoadPolicyForSubject failed: %v", err)
+	}
+	testGetPolicy(t, e, [][]string{{"admin", "domain1", "data1", "read"}, {"admin", "domain1", "data1", "write"}})
+	if grouping, _ := e.GetGroupingPolicy(); len(grouping) != 1 {
+		t.Errorf("grouping policy %v, supposed to be the rule of alice in domain1", grouping)
+	}
+}
+
+func TestLoadPolicyForSubjectDepth(t *testing.T) {
+	a := newSqliteAdapter(t)
+	var chain [][]string
+	for i := 0; i <= maxRoleDepth; i++ {
+		chain = append(chain, []string{fmt.Sprintf("role%d", i), fmt.Sprintf("role%d", i+1)})
+	}
+	if err := a.AddPolicies("g", "g", chain); err != nil {
+		t.Fatalf("AddPolicies failed: %v", err)
+	}
+
+	e, _ := casbin.NewEnforcer("examples/rbac_model.conf", a)
+	if err := a.LoadPolicyForSubject(context.Background(), e.GetModel(), "role1"); err != nil {
+		t.Errorf("LoadPolicyForSubject of %d levels failed: %v", maxRoleDepth, err)
+	}
+	if err := a.LoadPolicyForSubject(context.Background(), e.GetModel(), "role0"); err == nil {
+		t.Errorf("expected an error for %d levels", maxRoleDepth+1)
+	}
+}
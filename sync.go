@@ -0,0 +1,209 @@
+package adapter
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+
+	"github.com/casbin/casbin/v2/model"
+	"github.com/gogf/gf/v2/database/gdb"
+	"github.com/gogf/gf/v2/frame/g"
+)
+
+// WithSyncSave makes SavePolicy call SyncPolicy, so that saving the
+// policy of an enforcer only writes the rules that changed.
+func WithSyncSave() AdapterOption {
+	return AdapterOption{apply: func(a *Adapter) {
+		a.syncSave = true
+	}}
+}
+
+// WithStableSave makes SavePolicy keep the rows, and so the ids and
+// created_at values, of the rules that are stored before and after the
+// save. The rules are staged in a temporary table and the database computes
+// which rows to delete and which rules to insert, comparing all values.
+// Duplicate rows are deleted as well. Without it SavePolicy replaces all rows.
+func WithStableSave() AdapterOption {
+	return AdapterOption{apply: func(a *Adapter) {
+		a.stableSave = true
+	}}
+}
+
+// SyncPolicy makes the stored rules match the policy rules of model like
+// SavePolicy, but only inserts the rules missing from the table and deletes
+// the extraneous ones, including duplicate rows, in one transaction. The
+// rows of unchanged rules are kept. Rules are compared by their non-empty
+// values, the way they are loaded into the model.
+func (a *Adapter) SyncPolicy(model model.Model) error {
+	if model == nil {
+		return errors.New("model cannot be nil")
+	}
+	op := Operation{Method: "SyncPolicy", Model: model}
+	return a.mutate(a.ctx, op, func(ctx context.Context) error { return a.syncPolicy(ctx, model) }, nil)
+}
+
+func (a *Adapter) syncPolicy(ctx context.Context, model model.Model) error {
+	if a.routed() {
+		return a.saveSplit(ctx, model, (*Adapter).syncPolicy)
+	}
+	wanted := a.modelRules(model)
+	missing := make(map[ruleKey]bool, len(wanted))
+	for _, rule := range wanted {
+		missing[syncKey(rule)] = true
+	}
+
+	ctx = withWatcherMessage(ctx, &WatcherMessage{Method: WatcherSavePolicy}, model)
+	err := a.transaction(ctx, func(ctx context.Context, tx gdb.TX) error {
+		var extraneous []int64
+		err := a.scanPages(a.modelCtx(ctx), func(rows []ruleRow) error {
+			for _, row := range rows {
+				key := syncKey(row.Rule)
+				if missing[key] {
+					missing[key] = false
+					continue
+				}
+				extraneous = append(extraneous, row.Id)
+			}
+			return nil
+		})
+		if err != nil {
+			return err
+		}
+
+		size := a.idsSize()
+		for i := 0; i < len(extraneous); i += size {
+			if err := ctx.Err(); err != nil {
+				return err
+			}
+			end := i + size
+			if end > len(extraneous) {
+				end = len(extraneous)
+			}
+			if err := a.deleteRules(ctx, a.modelCtx(ctx).WhereIn("id", extraneous[i:end])); err != nil {
+				return fmt.Errorf("failed to delete rules: %w", err)
+			}
+		}
+
+		inserts := make([]Rule, 0, len(wanted))
+		for _, rule := range wanted {
+			key := syncKey(rule)
+			if missing[key] {
+				// Repeated rules of the model are inserted once.
+				missing[key] = false
+				inserts = append(inserts, rule)
+			}
+		}
+		setProgressTotal(ctx, len(inserts))
+		return a.insertRules(ctx, inserts)
+	})
+	if err != nil {
+		return fmt.Errorf("failed to sync policy: %w", err)
+	}
+	return nil
+}
+
+// syncKey identifies rule by the values it is loaded into the model with.
+func syncKey(rule Rule) ruleKey {
+	loaded := newRule(rule.PType, rule.toSlice())
+	return loaded.key()
+}
+
+// stableSavePolicy saves the policy rules of model through a staging table,
+// keeping the rows of unchanged rules.
+func (a *Adapter) stableSavePolicy(ctx context.Context, model model.Model) error {
+	// The changes are computed from the rows written to the staging table.
+	if err := a.checkDryRun(); err != nil {
+		return fmt.Errorf("failed to save policy: %w", err)
+	}
+	staging := a.tableName + "_staging"
+	rules := a.modelRules(model)
+
+	ctx = withWatcherMessage(ctx, &WatcherMessage{Method: WatcherSavePolicy}, model)
+	err := a.transaction(ctx, func(ctx context.Context, tx gdb.TX) error {
+		// The temporary table is bound to the connection of the transaction.
+		for _, sql := range []string{a.dialect.createStagingTableSql(staging, a.schema()), fmt.Sprintf("DELETE FROM %s", staging)} {
+			if _, err := tx.Exec(sql); err != nil {
+				return fmt.Errorf("failed to prepare staging table: %w", err)
+			}
+		}
+		defer func() {
+			_, _ = tx.Exec(a.dialect.dropStagingTableSql(staging))
+		}()
+
+		size := a.insertSize()
+		for i := 0; i < len(rules); i += size {
+			if err := ctx.Err(); err != nil {
+				return err
+			}
+			end := i + size
+			if end > len(rules) {
+				end = len(rules)
+			}
+			batch := make(g.List, 0, end-i)
+			for _, rule := range rules[i:end] {
+				batch = append(batch, g.Map{
+					"p_type": rule.PType, "v0": rule.V0, "v1": rule.V1, "v2": rule.V2,
+					"v3": rule.V3, "v4": rule.V4, "v5": rule.V5,
+				})
+			}
+			if _, err := a.encoded(tx.Model(staging).Ctx(ctx)).Data(batch).Insert(); err != nil {
+				return fmt.Errorf("failed to stage rules: %w", err)
+			}
+			reportProgress(ctx, len(batch))
+		}
+
+		// Delete the rows of rules that are not staged, and the duplicates.
+		dupQuery, dupArgs := a.duplicateIdsSql()
+		where := fmt.Sprintf("NOT EXISTS (SELECT 1 FROM %s s WHERE %s) OR id IN (SELECT id FROM (%s) dup)",
+			staging, a.stagedMatchSql(a.tableName), dupQuery)
+		if err := a.deleteRules(ctx, a.modelCtx(ctx).Where(where, dupArgs...)); err != nil {
+			return fmt.Errorf("failed to delete rules: %w", err)
+		}
+
+		// Insert the staged rules that are not stored, in the model order.
+		conditions := a.stagedMatchSql("t")
+		if live := a.liveSql("t"); live != "" {
+			conditions = live + " AND " + conditions
+		}
+		var args []interface{}
+		if a.tenantColumn != "" {
+			conditions = fmt.Sprintf("t.%s = ? AND %s", a.tenantColumn, conditions)
+			args = append(args, a.tenant)
+		}
+		columns := "s." + strings.Join(stagingColumns, ", s.")
+		query := fmt.Sprintf("SELECT %s FROM %s s WHERE NOT EXISTS (SELECT 1 FROM %s t WHERE %s) GROUP BY %s ORDER BY MIN(s.id)",
+			columns, staging, a.tableName, conditions, columns)
+		records, err := tx.GetAll(query, args...)
+		if err != nil {
+			return fmt.Errorf("failed to find new rules: %w", err)
+		}
+		if err := a.decodeRecords(records); err != nil {
+			return fmt.Errorf("failed to find new rules: %w", err)
+		}
+
+		var inserts []Rule
+		if err := records.Structs(&inserts); err != nil {
+			return fmt.Errorf("failed to scan new rules: %w", err)
+		}
+		return a.insertRules(ctx, inserts)
+	})
+	if err != nil {
+		return fmt.Errorf("failed to save policy: %w", err)
+	}
+	return nil
+}
+
+// stagedMatchSql returns the condition matching the staged rule s with the
+// row of the policy table referred to by table.
+func (a *Adapter) stagedMatchSql(table string) string {
+	conditions := make([]string, 0, len(stagingColumns))
+	for _, column := range stagingColumns {
+		stored := column
+		if column == Columns.PType {
+			stored = a.pTypeColumn
+		}
+		conditions = append(conditions, fmt.Sprintf("COALESCE(%s.%s, '') = s.%s", table, stored, column))
+	}
+	return strings.Join(conditions, " AND ")
+}
@@ -0,0 +1,140 @@
+package adapter
+
+import (
+	"reflect"
+	"testing"
+
+	"github.com/casbin/casbin/v2"
+)
+
+// storedIDs returns the ids of the rows visible to the adapter.
+func storedIDs(t *testing.T, a *Adapter) []int64 {
+	t.Helper()
+	values, err := a.model().Fields("id").OrderAsc("id").Array()
+	if err != nil {
+		t.Fatalf("failed to query ids: %v", err)
+	}
+	ids := make([]int64, 0, len(values))
+	for _, value := range values {
+		ids = append(ids, value.Int64())
+	}
+	return ids
+}
+
+func TestSyncPolicy(t *testing.T) {
+	a := newSqliteAdapter(t, WithSyncSave())
+	initPolicy(t, a)
+	// Row 6 duplicates row 1.
+	if err := a.AddPolicy("p", "p", []string{"alice", "data1", "read"}); err != nil {
+		t.Fatalf("AddPolicy failed: %v", err)
+	}
+
+	e, err := casbin.NewEnforcer("examples/rbac_model.conf", "examples/rbac_policy.csv")
+	if err != nil {
+		t.Fatalf("failed to load policy file: %v", err)
+	}
+	e.EnableAutoSave(false)
+	if _, err := e.RemovePolicy("bob", "data2", "write"); err != nil {
+		t.Fatalf("RemovePolicy failed: %v", err)
+	}
+	if _, err := e.AddPolicy("carol", "data3", "read"); err != nil {
+		t.Fatalf("AddPolicy failed: %v", err)
+	}
+
+	// SavePolicy goes through SyncPolicy and only writes the delta.
+	if err := a.SavePolicy(e.GetModel()); err != nil {
+		t.Fatalf("SavePolicy failed: %v", err)
+	}
+	if ids := storedIDs(t, a); !reflect.DeepEqual(ids, []int64{1, 3, 4, 5, 7}) {
+		t.Errorf("stored ids %v, supposed to be [1 3 4 5 7]", ids)
+	}
+
+	// The result matches the truncate path.
+	full := newSqliteAdapter(t)
+	if err := full.SavePolicy(e.GetModel()); err != nil {
+		t.Fatalf("SavePolicy failed: %v", err)
+	}
+	report, err := a.CompareWith(a.ctx, full)
+	if err != nil {
+		t.Fatalf("CompareWith failed: %v", err)
+	}
+	if !report.Equal() || len(report.DuplicatesA) != 0 {
+		t.Errorf("synced table differs from the saved one: %+v", report)
+	}
+
+	// Syncing an unchanged model writes nothing.
+	if err := a.SyncPolicy(e.GetModel()); err != nil {
+		t.Fatalf("SyncPolicy failed: %v", err)
+	}
+	if ids := storedIDs(t, a); !reflect.DeepEqual(ids, []int64{1, 3, 4, 5, 7}) {
+		t.Errorf("stored ids %v after a no-op sync, supposed to be [1 3 4 5 7]", ids)
+	}
+}
+
+func TestSyncPolicyForTenant(t *testing.T) {
+	a := newSqliteAdapter(t, WithTenantColumn("tenant_id"))
+	t1, t2 := a.ForTenant("t1"), a.ForTenant("t2")
+	initPolicy(t, t1)
+	initPolicy(t, t2)
+
+	e, _ := casbin.NewEnforcer("examples/rbac_model.conf", "examples/rbac_policy.csv")
+	e.EnableAutoSave(false)
+	if _, err := e.RemoveGroupingPolicy("alice", "data2_admin"); err != nil {
+		t.Fatalf("RemoveGroupingPolicy failed: %v", err)
+	}
+	if err := t1.SyncPolicy(e.GetModel()); err != nil {
+		t.Fatalf("SyncPolicy failed: %v", err)
+	}
+
+	if count, _ := t1.model().Count(); count != 4 {
+		t.Errorf("tenant t1 has %d rows, supposed to be 4", count)
+	}
+	if count, _ := t2.model().Count(); count != 5 {
+		t.Errorf("tenant t2 has %d rows, supposed to be 5", count)
+	}
+}
+
+func TestStableSave(t *testing.T) {
+	a := newSqliteAdapter(t, WithStableSave(), WithHistory())
+	initPolicy(t, a)
+	if err := a.AddPolicy("p", "p", []string{"alice", "data1", "read"}); err != nil {
+		t.Fatalf("AddPolicy failed: %v", err)
+	}
+
+	e, err := casbin.NewEnforcer("examples/rbac_model.conf", "examples/rbac_policy.csv")
+	if err != nil {
+		t.Fatalf("failed to load policy file: %v", err)
+	}
+	e.EnableAutoSave(false)
+	if _, err := e.RemovePolicy("data2_admin", "data2", "read"); err != nil {
+		t.Fatalf("RemovePolicy failed: %v", err)
+	}
+	if _, err := e.AddPolicies([][]string{{"carol", "data3", "read"}, {"alice", "data3", "write"}}); err != nil {
+		t.Fatalf("AddPolicies failed: %v", err)
+	}
+	if err := a.SavePolicy(e.GetModel()); err != nil {
+		t.Fatalf("SavePolicy failed: %v", err)
+	}
+
+	// The unchanged rules keep their rows, the duplicate row 6 is gone.
+	if ids := storedIDs(t, a); !reflect.DeepEqual(ids, []int64{1, 2, 4, 5, 7, 8}) {
+		t.Errorf("stored ids %v, supposed to be [1 2 4 5 7 8]", ids)
+	}
+	loaded, _ := casbin.NewEnforcer("examples/rbac_model.conf", a)
+	testGetPolicy(t, loaded, [][]string{{"alice", "data1", "read"}, {"bob", "data2", "write"}, {"data2_admin", "data2", "write"}, {"carol", "data3", "read"}, {"alice", "data3", "write"}})
+
+	// The history follows the changes.
+	at, _ := casbin.NewEnforcer("examples/rbac_model.conf")
+	if err := a.LoadPolicyAt(a.ctx, at.GetModel(), a.now()); err != nil {
+		t.Fatalf("LoadPolicyAt failed: %v", err)
+	}
+	testGetPolicyWithoutOrder(t, at, [][]string{{"alice", "data1", "read"}, {"bob", "data2", "write"}, {"data2_admin", "data2", "write"}, {"carol", "data3", "read"}, {"alice", "data3", "write"}})
+
+	// Saving again is a no-op on the rows.
+	if err := a.SavePolicy(e.GetModel()); err != nil {
+		t.Fatalf("SavePolicy failed: %v", err)
+	}
+	if ids := storedIDs(t, a); !reflect.DeepEqual(ids, []int64{1, 2, 4, 5, 7, 8}) {
+		t.Errorf("stored ids %v after saving again, supposed to be [1 2 4 5 7 8]", ids)
+	}
+}
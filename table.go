@@ -0,0 +1,225 @@
+package adapter
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"slices"
+
+	"github.com/casbin/casbin/v2/persist"
+	"github.com/gogf/gf/v2/database/gdb"
+)
+
+// ErrDestructiveNotAllowed is returned by operations that wipe stored rules
+// when the adapter was created without WithAllowDestructive.
+var ErrDestructiveNotAllowed = errors.New("destructive operations are not allowed, use WithAllowDestructive to enable them")
+
+// WithAllowDestructive enables the operations that wipe stored rules at
+// once, such as ClearPolicy and DropTable. They are refused by default so
+// that they can't be called by accident.
+func WithAllowDestructive() AdapterOption {
+	return AdapterOption{apply: func(a *Adapter) {
+		a.allowDestructive = true
+	}}
+}
+
+// WithoutAutoCreate stops NewAdapter from creating the policy table, e.g.
+// when the table is managed by migrations. Use EnsureTable to create it.
+func WithoutAutoCreate() AdapterOption {
+	return AdapterOption{apply: func(a *Adapter) {
+		a.autoCreate = false
+	}}
+}
+
+// TableName returns the physical name of the policy table, including the
+// prefix of the database configuration.
+func (a *Adapter) TableName() string {
+	return a.tableName
+}
+
+// DB returns the database of the adapter, resolved from its group when
+// NewAdapter got no database.
+func (a *Adapter) DB() gdb.DB {
+	return a.db
+}
+
+// Model returns a safe model of the policy table, the default table with
+// WithTableRouting, bound to ctx and joining the transaction of WithTx.
+// It reads the stored rows as they are, without the tenant scope, the
+// decryption of WithEncryption or the soft deletes. The writes made through
+// it bypass the hooks, the history, the quota, the cache and the watcher of
+// the adapter.
+func (a *Adapter) Model(ctx context.Context) *gdb.Model {
+	if a.tx != nil {
+		return a.tx.Model(a.tableName).Safe().Ctx(ctx)
+	}
+	return a.db.Model(a.tableName).Safe().Ctx(ctx)
+}
+
+// WithTable returns a copy of the adapter that stores its rules in table
+// name, which gets the database prefix like the table given to NewAdapter.
+// The copy shares the database connection and options with the original
+// adapter, but has its own filtered state. The table is created when
+// auto-create is enabled.
+func (a *Adapter) WithTable(name string) (*Adapter, error) {
+	if name == "" {
+		return nil, errors.New("table name cannot be empty")
+	}
+	if a.routed() {
+		return nil, errors.New("WithTable can't be used with routed tables")
+	}
+
+	clone := *a
+	clone.tableName = a.db.GetPrefix() + name
+	clone.isFiltered = false
+	if clone.historyTable != "" {
+		clone.historyTable = clone.tableName + historyTableSuffix
+	}
+	if clone.versionTable != "" {
+		clone.versionTable = clone.tableName + versionTableSuffix
+	}
+
+	if err := clone.initTables(); err != nil {
+		return nil, fmt.Errorf("failed to open table %s: %w", clone.tableName, err)
+	}
+	return &clone, nil
+}
+
+// EnsureTable creates the policy table and its indexes when they don't
+// exist, as well as the history and version tables when they are enabled.
+// It is safe to call on an existing table.
+func (a *Adapter) EnsureTable(ctx context.Context) error {
+	if err := a.checkDryRun(); err != nil {
+		return fmt.Errorf("failed to create table: %w", err)
+	}
+	if err := a.chooseUTC(ctx); err != nil {
+		return err
+	}
+	if a.routed() {
+		for _, table := range a.splitTables() {
+			if err := table.EnsureTable(ctx); err != nil {
+				return err
+			}
+		}
+		return nil
+	}
+	if err := a.createTable(ctx); err != nil {
+		return err
+	}
+	if a.ruleHash {
+		if err := a.ensureRuleHashColumn(ctx); err != nil {
+			return err
+		}
+	}
+	if a.uniqueIndex {
+		if err := a.createUniqueIndex(ctx); err != nil {
+			return err
+		}
+	}
+	if a.actorColumn != "" {
+		if err := a.ensureActorColumn(ctx, a.tableName); err != nil {
+			return err
+		}
+	}
+	if a.changeTracking {
+		if err := a.ensureTrackingColumns(ctx); err != nil {
+			return err
+		}
+	}
+	if a.versionTable != "" {
+		if err := a.createVersionTable(ctx); err != nil {
+			return err
+		}
+	}
+	if a.historyTable != "" {
+		return a.createHistoryTable(ctx)
+	}
+	return nil
+}
+
+// errTenantDropTable is returned by DropTable on the adapters of a tenant
+// column or a scope, which would drop the rules of the other tenants.
+var errTenantDropTable = errors.New("dropping the tables isn't supported with a tenant column or a scope")
+
+// DropTable drops the policy table, the routed tables of WithTableRouting,
+// and the history and version tables when they are enabled. It requires the
+// adapter to be created with WithAllowDestructive, and isn't supported with
+// WithTenantColumn or WithScope, as the tables hold the rules of every
+// tenant.
+func (a *Adapter) DropTable(ctx context.Context) error {
+	if !a.allowDestructive {
+		return fmt.Errorf("failed to drop table: %w", ErrDestructiveNotAllowed)
+	}
+	if a.tenantColumn != "" {
+		return fmt.Errorf("failed to drop table: %w", errTenantDropTable)
+	}
+	if err := a.checkDryRun(); err != nil {
+		return fmt.Errorf("failed to drop table: %w", err)
+	}
+	if err := a.confirmTables(ctx, "DropTable", a.routedTableNames()); err != nil {
+		return fmt.Errorf("failed to drop table: %w", err)
+	}
+
+	if err := a.dropTable(ctx); err != nil {
+		return err
+	}
+	for _, table := range a.routedTableNames()[1:] {
+		if err := a.dropTableNamed(ctx, table); err != nil {
+			return err
+		}
+	}
+	if a.versionTable != "" {
+		if err := a.dropTableNamed(ctx, a.versionTable); err != nil {
+			return err
+		}
+	}
+	if a.historyTable != "" {
+		return a.dropTableNamed(ctx, a.historyTable)
+	}
+	return nil
+}
+
+// TableExists reports whether the policy table exists in the database, and
+// the routed tables of WithTableRouting.
+func (a *Adapter) TableExists(ctx context.Context) (bool, error) {
+	tables, err := a.db.Tables(ctx)
+	if err != nil {
+		return false, fmt.Errorf("failed to list tables: %w", err)
+	}
+	for _, table := range a.routedTableNames() {
+		if !slices.Contains(tables, table) {
+			return false, nil
+		}
+	}
+	return true, nil
+}
+
+// ClearPolicy deletes all stored rules visible to the adapter. A tenant
+// scoped adapter only deletes the rules of its tenant. It requires the
+// adapter to be created with WithAllowDestructive.
+func (a *Adapter) ClearPolicy(ctx context.Context) error {
+	if !a.allowDestructive {
+		return fmt.Errorf("failed to clear policy: %w", ErrDestructiveNotAllowed)
+	}
+	return a.mutate(ctx, Operation{Method: "ClearPolicy"}, func(ctx context.Context) error { return a.clearPolicy(ctx) }, func(d persist.Dispatcher) error {
+		return d.ClearPolicy()
+	})
+}
+
+func (a *Adapter) clearPolicy(ctx context.Context) error {
+	if err := a.confirmQuery(ctx, "ClearPolicy", nil); err != nil {
+		return fmt.Errorf("failed to clear policy: %w", err)
+	}
+	err := a.transaction(ctx, func(ctx context.Context, tx gdb.TX) error {
+		for _, table := range a.splitTables() {
+			if err := table.deleteRows(ctx, table.modelCtx(ctx).Where("1=1")); err != nil {
+				return fmt.Errorf("failed to delete rules: %w", err)
+			}
+		}
+		return a.recordHistory(ctx, historyOpReset, nil)
+	})
+	if err != nil {
+		return fmt.Errorf("failed to clear policy: %w", err)
+	}
+	return nil
+}
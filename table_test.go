@@ -0,0 +1,244 @@
+package adapter
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/casbin/casbin/v2"
+	"github.com/gogf/gf/v2/database/gdb"
+)
+
+func TestClearPolicy(t *testing.T) {
+	a := newSqliteAdapter(t, WithAllowDestructive())
+	initPolicy(t, a)
+
+	if err := a.ClearPolicy(context.Background()); err != nil {
+		t.Fatalf("ClearPolicy failed: %v", err)
+	}
+
+	e, err := casbin.NewEnforcer("examples/rbac_model.conf", a)
+	if err != nil {
+		t.Fatalf("NewEnforcer failed: %v", err)
+	}
+	testGetPolicyWithoutOrder(t, e, [][]string{})
+	if roles, _ := e.GetRolesForUser("alice"); len(roles) != 0 {
+		t.Errorf("roles = %v, supposed to be empty", roles)
+	}
+}
+
+func TestClearPolicyRequiresOptIn(t *testing.T) {
+	a := newSqliteAdapter(t)
+	initPolicy(t, a)
+
+	if err := a.ClearPolicy(context.Background()); !errors.Is(err, ErrDestructiveNotAllowed) {
+		t.Fatalf("expected ErrDestructiveNotAllowed, got %v", err)
+	}
+
+	e, _ := casbin.NewEnforcer("examples/rbac_model.conf", a)
+	testGetPolicyWithoutOrder(t, e, [][]string{{"alice", "data1", "read"}, {"bob", "data2", "write"}, {"data2_admin", "data2", "read"}, {"data2_admin", "data2", "write"}})
+}
+
+func TestClearPolicyForTenant(t *testing.T) {
+	a := newSqliteAdapter(t, WithTenantColumn("tenant_id"), WithAllowDestructive())
+	t1, t2 := a.ForTenant("t1"), a.ForTenant("t2")
+	initPolicy(t, t1)
+	initPolicy(t, t2)
+
+	if err := t1.ClearPolicy(context.Background()); err != nil {
+		t.Fatalf("ClearPolicy failed: %v", err)
+	}
+
+	e1, _ := casbin.NewEnforcer("examples/rbac_model.conf", t1)
+	testGetPolicyWithoutOrder(t, e1, [][]string{})
+	e2, _ := casbin.NewEnforcer("examples/rbac_model.conf", t2)
+	testGetPolicyWithoutOrder(t, e2, [][]string{{"alice", "data1", "read"}, {"bob", "data2", "write"}, {"data2_admin", "data2", "read"}, {"data2_admin", "data2", "write"}})
+}
+
+func TestTableManagement(t *testing.T) {
+	a := newSqliteAdapter(t, WithAllowDestructive())
+	ctx := context.Background()
+
+	assertExists := func(want bool) {
+		t.Helper()
+		exists, err := a.TableExists(ctx)
+		if err != nil {
+			t.Fatalf("TableExists failed: %v", err)
+		}
+		if exists != want {
+			t.Fatalf("TableExists = %v, supposed to be %v", exists, want)
+		}
+	}
+
+	assertExists(true)
+	if err := a.DropTable(ctx); err != nil {
+		t.Fatalf("DropTable failed: %v", err)
+	}
+	assertExists(false)
+
+	// Ensuring twice must be harmless.
+	for i := 0; i < 2; i++ {
+		if err := a.EnsureTable(ctx); err != nil {
+			t.Fatalf("EnsureTable failed: %v", err)
+		}
+	}
+	assertExists(true)
+
+	initPolicy(t, a)
+	if err := a.EnsureTable(ctx); err != nil {
+		t.Fatalf("EnsureTable failed: %v", err)
+	}
+	e, _ := casbin.NewEnforcer("examples/rbac_model.conf", a)
+	testGetPolicyWithoutOrder(t, e, [][]string{{"alice", "data1", "read"}, {"bob", "data2", "write"}, {"data2_admin", "data2", "read"}, {"data2_admin", "data2", "write"}})
+}
+
+func TestDropTableRequiresOptIn(t *testing.T) {
+	a := newSqliteAdapter(t)
+
+	if err := a.DropTable(context.Background()); !errors.Is(err, ErrDestructiveNotAllowed) {
+		t.Fatalf("expected ErrDestructiveNotAllowed, got %v", err)
+	}
+	if exists, _ := a.TableExists(context.Background()); !exists {
+		t.Error("table should still exist")
+	}
+}
+
+func TestWithoutAutoCreate(t *testing.T) {
+	base := newSqliteAdapter(t, WithAllowDestructive())
+	ctx := context.Background()
+	if err := base.DropTable(ctx); err != nil {
+		t.Fatalf("DropTable failed: %v", err)
+	}
+
+	a, err := NewAdapter(ctx, "", "", base.db, WithoutAutoCreate())
+	if err != nil {
+		t.Fatalf("NewAdapter failed: %v", err)
+	}
+	if exists, _ := a.TableExists(ctx); exists {
+		t.Fatal("NewAdapter must not create the table with WithoutAutoCreate")
+	}
+
+	if err := a.EnsureTable(ctx); err != nil {
+		t.Fatalf("EnsureTable failed: %v", err)
+	}
+	if exists, _ := a.TableExists(ctx); !exists {
+		t.Error("EnsureTable should have created the table")
+	}
+}
+
+func TestDropTableWithHistory(t *testing.T) {
+	a := newSqliteAdapter(t, WithHistory(), WithAllowDestructive())
+	ctx := context.Background()
+
+	if err := a.DropTable(ctx); err != nil {
+		t.Fatalf("DropTable failed: %v", err)
+	}
+	tables, err := a.db.Tables(ctx)
+	if err != nil {
+		t.Fatalf("Tables failed: %v", err)
+	}
+	for _, table := range tables {
+		if table == a.tableName || table == a.historyTable {
+			t.Errorf("table %s should have been dropped", table)
+		}
+	}
+
+	// The recreated tables are written with their new layout.
+	if err := a.EnsureTable(ctx); err != nil {
+		t.Fatalf("EnsureTable failed: %v", err)
+	}
+	if err := a.AddPolicy("p", "p", []string{"alice", "data1", "read"}); err != nil {
+		t.Fatalf("AddPolicy failed: %v", err)
+	}
+}
+
+func TestDropTableOfTenant(t *testing.T) {
+	ctx := context.Background()
+	for name, opts := range map[string][]AdapterOption{
+		"tenant": {WithTenantColumn("tenant_id"), WithAllowDestructive()},
+		"scope":  {WithScope("api"), WithAllowDestructive()},
+	} {
+		a := newSqliteAdapter(t, opts...)
+		if a.tenantColumn == "tenant_id" {
+			a = a.ForTenant("t1")
+		}
+		initPolicy(t, a)
+		if err := a.DropTable(ctx); !errors.Is(err, errTenantDropTable) {
+			t.Errorf("%s: DropTable err: %v, supposed to refuse the tenant", name, err)
+		}
+		if exists, _ := a.TableExists(ctx); !exists {
+			t.Errorf("%s: table should still exist", name)
+		}
+	}
+}
+
+func TestWithTable(t *testing.T) {
+	a := newSqliteAdapter(t)
+	if a.TableName() != defaultTableName {
+		t.Errorf("TableName = %s, supposed to be %s", a.TableName(), defaultTableName)
+	}
+
+	billing, err := a.WithTable("casbin_rule_billing")
+	if err != nil {
+		t.Fatalf("WithTable failed: %v", err)
+	}
+	docs, err := a.WithTable("casbin_rule_docs")
+	if err != nil {
+		t.Fatalf("WithTable failed: %v", err)
+	}
+	if billing.TableName() != "casbin_rule_billing" || docs.TableName() != "casbin_rule_docs" {
+		t.Fatalf("unexpected table names %s and %s", billing.TableName(), docs.TableName())
+	}
+	if billing.db != a.db {
+		t.Error("clones must share the database connection")
+	}
+
+	seedPolicy(t, billing, "examples/rbac_model.conf", "examples/rbac_policy.csv")
+	if err := docs.AddPolicy("p", "p", []string{"carol", "doc1", "read"}); err != nil {
+		t.Fatalf("AddPolicy failed: %v", err)
+	}
+
+	eBilling, _ := casbin.NewEnforcer("examples/rbac_model.conf", billing)
+	eDocs, _ := casbin.NewEnforcer("examples/rbac_model.conf", docs)
+	testGetPolicyWithoutOrder(t, eBilling, [][]string{{"alice", "data1", "read"}, {"bob", "data2", "write"}, {"data2_admin", "data2", "read"}, {"data2_admin", "data2", "write"}})
+	testGetPolicyWithoutOrder(t, eDocs, [][]string{{"carol", "doc1", "read"}})
+
+	if err := eDocs.LoadFilteredPolicy(Filter{V0: []string{"carol"}}); err != nil {
+		t.Fatalf("LoadFilteredPolicy failed: %v", err)
+	}
+	if !docs.IsFiltered() || billing.IsFiltered() || a.IsFiltered() {
+		t.Error("filtered state must not be shared between table clones")
+	}
+
+	if _, err := a.WithTable(""); err == nil {
+		t.Error("expected an error for an empty table name")
+	}
+}
+
+func TestWithTablePrefix(t *testing.T) {
+	base := newSqliteAdapter(t)
+	db, err := gdb.New(gdb.ConfigNode{
+		Type:   "sqlite",
+		Name:   base.db.GetConfig().Name,
+		Prefix: "app_",
+	})
+	if err != nil {
+		t.Fatalf("failed to create database connection: %v", err)
+	}
+	defer db.Close(context.Background())
+
+	a, err := NewAdapter(context.Background(), "", "", db)
+	if err != nil {
+		t.Fatalf("NewAdapter failed: %v", err)
+	}
+	clone, err := a.WithTable("casbin_rule_docs")
+	if err != nil {
+		t.Fatalf("WithTable failed: %v", err)
+	}
+	if a.TableName() != "app_casbin_rule" || clone.TableName() != "app_casbin_rule_docs" {
+		t.Errorf("unexpected table names %s and %s", a.TableName(), clone.TableName())
+	}
+	if exists, _ := clone.TableExists(context.Background()); !exists {
+		t.Error("WithTable should have created the table")
+	}
+}
@@ -0,0 +1,36 @@
+package adapter
+
+// WithTenantColumn adds a tenant column to the policy table so that many
+// tenants can share it. Every query of the adapter is restricted to the rows
+// of its tenant and every insert stores the tenant value. The adapter
+// returned by NewAdapter serves the default tenant "", use ForTenant to get
+// an adapter for another tenant.
+func WithTenantColumn(column string) AdapterOption {
+	return AdapterOption{apply: func(a *Adapter) {
+		a.tenantColumn = column
+	}}
+}
+
+// ForTenant returns a copy of the adapter scoped to tenant. The copy shares
+// the database connection and tables with the original adapter, but has its
+// own filtered state. It panics if the adapter was created without
+// WithTenantColumn, since the copy couldn't be isolated from other tenants,
+// or with WithScope, whose copies would read the rules of other scopes.
+func (a *Adapter) ForTenant(tenant string) *Adapter {
+	if a.scope != "" {
+		panic("adapter: ForTenant can't be used with the WithScope option")
+	}
+	if a.tenantColumn == "" {
+		panic("adapter: ForTenant requires the WithTenantColumn option")
+	}
+
+	clone := *a
+	clone.tenant = tenant
+	clone.isFiltered = false
+	return &clone
+}
+
+// Tenant returns the tenant the adapter is scoped to.
+func (a *Adapter) Tenant() string {
+	return a.tenant
+}
@@ -0,0 +1,117 @@
+package adapter
+
+import (
+	"testing"
+
+	"github.com/casbin/casbin/v2"
+)
+
+func TestTenantIsolation(t *testing.T) {
+	a := newSqliteAdapter(t, WithTenantColumn("tenant_id"))
+	t1 := a.ForTenant("t1")
+	t2 := a.ForTenant("t2")
+
+	var err error
+	logErr := func(action string) {
+		if err != nil {
+			t.Fatalf("test action[%s] failed, err: %v", action, err)
+		}
+	}
+
+	// Seed tenant t1 from the example policy and tenant t2 by hand.
+	initPolicy(t, t1)
+	err = t2.AddPolicies("p", "p", [][]string{{"carol", "data3", "read"}, {"dave", "data3", "write"}})
+	logErr("AddPolicies")
+
+	e1, err := casbin.NewEnforcer("examples/rbac_model.conf", t1)
+	logErr("NewEnforcer t1")
+	e2, err := casbin.NewEnforcer("examples/rbac_model.conf", t2)
+	logErr("NewEnforcer t2")
+
+	testGetPolicyWithoutOrder(t, e1, [][]string{{"alice", "data1", "read"}, {"bob", "data2", "write"}, {"data2_admin", "data2", "read"}, {"data2_admin", "data2", "write"}})
+	testGetPolicyWithoutOrder(t, e2, [][]string{{"carol", "data3", "read"}, {"dave", "data3", "write"}})
+
+	// The default tenant doesn't see the rules of the other tenants.
+	e0, err := casbin.NewEnforcer("examples/rbac_model.conf", a)
+	logErr("NewEnforcer default")
+	testGetPolicyWithoutOrder(t, e0, [][]string{})
+
+	// Filters compose with the tenant predicate.
+	err = e1.LoadFilteredPolicy(Filter{V1: []string{"data2", "data3"}})
+	logErr("LoadFilteredPolicy")
+	testGetPolicyWithoutOrder(t, e1, [][]string{{"bob", "data2", "write"}, {"data2_admin", "data2", "read"}, {"data2_admin", "data2", "write"}})
+
+	// Removing by filter only touches the own tenant.
+	err = t2.AddPolicy("p", "p", []string{"data2_admin", "data2", "read"})
+	logErr("AddPolicy")
+	_, err = e1.RemoveFilteredPolicy(0, "data2_admin")
+	logErr("RemoveFilteredPolicy")
+	err = e2.LoadPolicy()
+	logErr("LoadPolicy t2")
+	testGetPolicyWithoutOrder(t, e2, [][]string{{"carol", "data3", "read"}, {"dave", "data3", "write"}, {"data2_admin", "data2", "read"}})
+
+	// Saving a tenant replaces only the rows of that tenant.
+	err = t1.RemovePolicy("p", "p", []string{"alice", "data1", "read"})
+	logErr("RemovePolicy")
+	err = e1.LoadPolicy()
+	logErr("LoadPolicy t1")
+	e1.EnableAutoSave(false)
+	_, err = e1.AddPolicy("erin", "data1", "read")
+	logErr("AddPolicy in memory")
+	err = e1.SavePolicy()
+	logErr("SavePolicy")
+
+	err = e1.LoadPolicy()
+	logErr("LoadPolicy t1 after save")
+	testGetPolicyWithoutOrder(t, e1, [][]string{{"bob", "data2", "write"}, {"erin", "data1", "read"}})
+	err = e2.LoadPolicy()
+	logErr("LoadPolicy t2 after save")
+	testGetPolicyWithoutOrder(t, e2, [][]string{{"carol", "data3", "read"}, {"dave", "data3", "write"}, {"data2_admin", "data2", "read"}})
+
+	if t1.Tenant() != "t1" || a.Tenant() != "" {
+		t.Errorf("unexpected tenants %q and %q", t1.Tenant(), a.Tenant())
+	}
+}
+
+func TestTenantFilteredStateIsPerClone(t *testing.T) {
+	a := newSqliteAdapter(t, WithTenantColumn("tenant_id"))
+	t1 := a.ForTenant("t1")
+	t2 := a.ForTenant("t2")
+
+	e1, _ := casbin.NewEnforcer("examples/rbac_model.conf", t1)
+	if err := e1.LoadFilteredPolicy(Filter{V0: []string{"alice"}}); err != nil {
+		t.Fatalf("LoadFilteredPolicy failed: %v", err)
+	}
+	if !t1.IsFiltered() || t2.IsFiltered() || a.IsFiltered() {
+		t.Error("filtered state must not be shared between tenant adapters")
+	}
+}
+
+func TestForTenantRequiresTenantColumn(t *testing.T) {
+	a := newSqliteAdapter(t)
+
+	defer func() {
+		if recover() == nil {
+			t.Error("ForTenant should panic without a tenant column")
+		}
+	}()
+	a.ForTenant("t1")
+}
+
+func TestForTenantOfScope(t *testing.T) {
+	a := newSqliteAdapter(t, WithScope("api"))
+
+	defer func() {
+		if recover() == nil {
+			t.Error("ForTenant should panic on a scoped adapter")
+		}
+	}()
+	a.ForTenant("batch")
+}
+
+func TestInvalidTenantColumn(t *testing.T) {
+	a := newSqliteAdapter(t)
+	if _, err := NewAdapter(a.ctx, "", "", a.db, WithTenantColumn("tenant id")); err == nil {
+		t.Error("expected an error for an invalid tenant column name")
+	}
+}
@@ -0,0 +1,57 @@
+package adapter
+
+import (
+	"context"
+
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	"go.opentelemetry.io/otel/trace"
+)
+
+// spanPrefix is the prefix of the names of the operation spans.
+const spanPrefix = "casbin.adapter."
+
+// WithTracing wraps every load and change of the stored rules in a span of
+// the tracer tracerName of the global OpenTelemetry tracer provider, named
+// after the method, e.g. casbin.adapter.LoadPolicy. The spans carry the
+// table, the number of rules and of rows affected and whether the load is
+// filtered, and record the errors. The statements of the operation are
+// traced as children of its span. The spans are dropped while no tracer
+// provider is set.
+func WithTracing(tracerName string) AdapterOption {
+	return AdapterOption{apply: func(a *Adapter) {
+		a.tracerName = tracerName
+	}}
+}
+
+// startSpan starts the span of the operation method when tracing is
+// enabled.
+func (a *Adapter) startSpan(ctx context.Context, method string) context.Context {
+	if a.tracerName == "" {
+		return ctx
+	}
+	ctx, _ = otel.Tracer(a.tracerName).Start(ctx, spanPrefix+method,
+		trace.WithSpanKind(trace.SpanKindInternal),
+		trace.WithAttributes(attribute.String("casbin.adapter.table", a.tableName)),
+	)
+	return ctx
+}
+
+// endSpan ends the span of the operation measured by m.
+func (a *Adapter) endSpan(ctx context.Context, m *opMeasure, rules int, err error) {
+	if a.tracerName == "" {
+		return
+	}
+	span := trace.SpanFromContext(ctx)
+	span.SetAttributes(
+		attribute.Int("casbin.adapter.rules", rules),
+		attribute.Int64("casbin.adapter.rows_affected", m.affected.Load()),
+		attribute.Bool("casbin.adapter.filtered", m.filter != nil),
+	)
+	if err != nil {
+		span.RecordError(err)
+		span.SetStatus(codes.Error, err.Error())
+	}
+	span.End()
+}
@@ -0,0 +1,111 @@
+package adapter
+
+import (
+	"context"
+	"strings"
+	"testing"
+
+	"github.com/casbin/casbin/v2"
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	"go.opentelemetry.io/otel/sdk/trace/tracetest"
+)
+
+// recordSpans makes the global tracer provider record the spans in the
+// returned exporter until the end of the test.
+func recordSpans(t *testing.T) *tracetest.InMemoryExporter {
+	exporter := tracetest.NewInMemoryExporter()
+	provider := sdktrace.NewTracerProvider(sdktrace.WithSyncer(exporter))
+	previous := otel.GetTracerProvider()
+	otel.SetTracerProvider(provider)
+	t.Cleanup(func() {
+		otel.SetTracerProvider(previous)
+		_ = provider.Shutdown(context.Background())
+	})
+	return exporter
+}
+
+// findSpan returns the span named name.
+func findSpan(t *testing.T, spans tracetest.SpanStubs, name string) tracetest.SpanStub {
+	t.Helper()
+	for _, span := range spans {
+		if span.Name == name {
+			return span
+		}
+	}
+	t.Fatalf("no span %s", name)
+	return tracetest.SpanStub{}
+}
+
+// spanAttributes returns the attributes of span by key.
+func spanAttributes(span tracetest.SpanStub) map[attribute.Key]attribute.Value {
+	attrs := make(map[attribute.Key]attribute.Value, len(span.Attributes))
+	for _, attr := range span.Attributes {
+		attrs[attr.Key] = attr.Value
+	}
+	return attrs
+}
+
+func TestTracing(t *testing.T) {
+	exporter := recordSpans(t)
+	a := newSqliteAdapter(t, WithTracing("casbin-test"))
+
+	if err := a.AddPolicies("p", "p", [][]string{{"alice", "data1", "read"}, {"bob", "data2", "write"}}); err != nil {
+		t.Fatalf("AddPolicies failed: %v", err)
+	}
+	spans := exporter.GetSpans()
+	span := findSpan(t, spans, "casbin.adapter.AddPolicies")
+	attrs := spanAttributes(span)
+	if attrs["casbin.adapter.table"].AsString() != "casbin_rule" || attrs["casbin.adapter.rules"].AsInt64() != 2 ||
+		attrs["casbin.adapter.rows_affected"].AsInt64() != 2 || attrs["casbin.adapter.filtered"].AsBool() {
+		t.Errorf("attributes %v", span.Attributes)
+	}
+	// The statements are children of the operation span.
+	children := 0
+	for _, child := range spans {
+		if child.Parent.SpanID() == span.SpanContext.SpanID() {
+			children++
+		}
+	}
+	if children == 0 {
+		t.Error("the statements aren't traced in the operation span")
+	}
+
+	exporter.Reset()
+	e, _ := casbin.NewEnforcer("examples/rbac_model.conf", a)
+	if err := e.LoadFilteredPolicy(Filter{V0: []string{"alice"}}); err != nil {
+		t.Fatalf("LoadFilteredPolicy failed: %v", err)
+	}
+	span = findSpan(t, exporter.GetSpans(), "casbin.adapter.LoadFilteredPolicy")
+	attrs = spanAttributes(span)
+	if attrs["casbin.adapter.rules"].AsInt64() != 1 || !attrs["casbin.adapter.filtered"].AsBool() {
+		t.Errorf("attributes %v", span.Attributes)
+	}
+	findSpan(t, exporter.GetSpans(), "casbin.adapter.LoadPolicy")
+
+	// Errors are recorded.
+	exporter.Reset()
+	a.RegisterHook(&recordingHook{name: "deny", abort: "RemovePolicy", events: new([]string)})
+	if err := a.RemovePolicy("p", "p", []string{"alice", "data1", "read"}); err == nil {
+		t.Fatal("expected the hook to abort RemovePolicy")
+	}
+	span = findSpan(t, exporter.GetSpans(), "casbin.adapter.RemovePolicy")
+	if span.Status.Code != codes.Error || len(span.Events) == 0 || span.Events[0].Name != "exception" {
+		t.Errorf("status %v, events %v, supposed to record the error", span.Status, span.Events)
+	}
+}
+
+func TestTracingDisabled(t *testing.T) {
+	exporter := recordSpans(t)
+	a := newSqliteAdapter(t)
+	if err := a.AddPolicy("p", "p", []string{"alice", "data1", "read"}); err != nil {
+		t.Fatalf("AddPolicy failed: %v", err)
+	}
+	for _, span := range exporter.GetSpans() {
+		if strings.HasPrefix(span.Name, spanPrefix) {
+			t.Errorf("unexpected span %s without tracing", span.Name)
+		}
+	}
+}
@@ -0,0 +1,229 @@
+package adapter
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+
+	"github.com/gogf/gf/v2/database/gdb"
+)
+
+// ErrTxDone is returned by the writes and loads of an adapter bound to a
+// transaction that was already committed or rolled back.
+var ErrTxDone = errors.New("the transaction of the adapter is already committed or rolled back")
+
+// NewAdapterWithTx creates an adapter bound to the external transaction tx
+// from the start, like the copies of WithTx, on the database of tx. The
+// policy table must exist: the adapter runs no DDL, so the options creating
+// or migrating tables are ignored. The adapter doesn't open transactions of
+// its own, its batch operations run as plain statements in tx, and it can't
+// be used once tx is committed or rolled back.
+func NewAdapterWithTx(ctx context.Context, tx gdb.TX, tableName string, opts ...AdapterOption) (*Adapter, error) {
+	if ctx == nil {
+		return nil, errors.New("context cannot be nil")
+	}
+	if tx == nil {
+		return nil, errors.New("transaction cannot be nil")
+	}
+	if tx.IsClosed() {
+		return nil, ErrTxDone
+	}
+
+	adp := newAdapter(ctx, "", tableName, tx.GetDB(), opts)
+	adp.tx = tx
+	adp.autoCreate, adp.autoMigrate = false, false
+	if err := adp.openTx(); err != nil {
+		return nil, fmt.Errorf("failed to open %s: %w", adp, err)
+	}
+	return adp, nil
+}
+
+// openTx is open for the adapters bound to a transaction, which check the
+// layout of the existing tables without creating them.
+func (a *Adapter) openTx() error {
+	if err := a.configure(); err != nil {
+		return err
+	}
+	a.detectAutoIncrement(a.ctx)
+	for _, table := range append(a.routedTableNames(), a.historyTable, a.versionTable) {
+		if table == "" {
+			continue
+		}
+		if _, err := a.tx.Model(table).Ctx(a.ctx).Where("1=0").Count(); err != nil {
+			return fmt.Errorf("failed to read table %s: %w", table, err)
+		}
+	}
+	if err := a.detectColumns(a.ctx); err != nil {
+		return err
+	}
+	if err := a.validateLoadOrder(a.ctx); err != nil {
+		return err
+	}
+	if a.historyTable != "" {
+		return a.openHistory()
+	}
+	return nil
+}
+
+// WithoutTransactions makes the adapter run the statements of its writes
+// one by one, without opening transactions, for the databases and proxies
+// whose transactions misbehave. A write failing half way leaves the
+// statements run before it applied: a batch of rules may be partly added,
+// removed or updated. SavePolicy writes the rules into a new table that
+// replaces the policy table, like WithParallelWrites, and when the table
+// can't be replaced, e.g. scoped to a tenant, it inserts the new rules
+// before deleting the stored ones, so that the policy table is never
+// empty. With a unique index the stored rules are deleted first. It can't
+// be combined with history or stable saves, which rely on transactions.
+func WithoutTransactions() AdapterOption {
+	return AdapterOption{apply: func(a *Adapter) {
+		a.noTransactions = true
+	}}
+}
+
+// validateNoTransactions checks the options of WithoutTransactions.
+func (a *Adapter) validateNoTransactions() error {
+	if !a.noTransactions {
+		return nil
+	}
+	switch {
+	case a.history:
+		return errors.New("history can't be combined with WithoutTransactions")
+	case a.stableSave:
+		return errors.New("stable saves can't be combined with WithoutTransactions")
+	}
+	return nil
+}
+
+// sequentialSavePolicy replaces the stored rules by rules without a
+// transaction, see WithoutTransactions.
+func (a *Adapter) sequentialSavePolicy(ctx context.Context, rules []Rule) error {
+	if a.canReplaceTable() && gdb.TXFromCtx(ctx, a.db.GetGroup()) == nil {
+		return a.parallelSavePolicy(ctx, rules)
+	}
+	return a.transaction(ctx, func(ctx context.Context, tx gdb.TX) error {
+		if a.uniqueIndex {
+			if err := a.deleteRows(ctx, a.modelCtx(ctx).Where("1=1")); err != nil {
+				return fmt.Errorf("failed to delete rules: %w", err)
+			}
+			return a.insertRules(ctx, rules)
+		}
+		last, err := a.modelCtx(ctx).Max("id")
+		if err != nil {
+			return fmt.Errorf("failed to get last rule id: %w", err)
+		}
+		if err := a.insertRules(ctx, rules); err != nil {
+			return err
+		}
+		if err := a.deleteRows(ctx, a.modelCtx(ctx).WhereLTE("id", int64(last))); err != nil {
+			return fmt.Errorf("failed to delete rules: %w", err)
+		}
+		return nil
+	})
+}
+
+// exec runs query in tx, or on the database when tx is nil as the statements
+// run without a transaction. A dry run records query instead.
+func (a *Adapter) exec(ctx context.Context, tx gdb.TX, query string, args ...interface{}) (sql.Result, error) {
+	if a.plan != nil {
+		return new(gdb.SqlResult), a.plan.add(ctx, query, args)
+	}
+	if tx == nil {
+		return a.db.Exec(ctx, query, args...)
+	}
+	return tx.Exec(query, args...)
+}
+
+// getAll is exec for the queries.
+func (a *Adapter) getAll(ctx context.Context, tx gdb.TX, query string, args ...interface{}) (gdb.Result, error) {
+	if tx == nil {
+		return a.db.GetAll(ctx, query, args...)
+	}
+	return tx.GetAll(query, args...)
+}
+
+// txModel returns a safe model of table in tx, or on the database when tx
+// is nil.
+func (a *Adapter) txModel(ctx context.Context, tx gdb.TX, table string) *gdb.Model {
+	if tx == nil {
+		return a.db.Model(table).Safe().Ctx(ctx)
+	}
+	return tx.Model(table).Safe().Ctx(ctx)
+}
+
+// checkTx returns ErrTxDone when the transaction of the adapter is over.
+func (a *Adapter) checkTx() error {
+	if a.tx != nil && a.tx.IsClosed() {
+		return ErrTxDone
+	}
+	return nil
+}
+
+// WithTx returns a copy of the adapter that runs all its statements in the
+// external transaction tx, so that policy changes commit or roll back
+// together with the caller's own writes. The copy doesn't open transactions
+// of its own, and the caller owns the commit or rollback of tx.
+func (a *Adapter) WithTx(tx gdb.TX) *Adapter {
+	clone := *a
+	clone.tx = tx
+	clone.isFiltered = false
+	return &clone
+}
+
+// Transaction runs fn in a database transaction and hands it a copy of the
+// adapter bound to that transaction, so that several adapter calls apply as
+// one atomic change. The transaction commits when fn returns nil and rolls
+// back otherwise. Called on an adapter that is already bound to a
+// transaction, it reuses that transaction.
+func (a *Adapter) Transaction(ctx context.Context, fn func(txAdapter *Adapter) error) error {
+	if a.tx != nil {
+		return fn(a)
+	}
+	// The partition is created before the transaction, which its DDL
+	// would commit.
+	if a.partitioned && a.plan == nil {
+		if err := a.EnsureTenantPartition(ctx, a.tenant); err != nil {
+			return err
+		}
+	}
+
+	var events []PolicyEvent
+	err := a.db.Transaction(ctx, func(ctx context.Context, tx gdb.TX) error {
+		txAdapter := a.WithTx(tx)
+		txAdapter.ctx = ctx
+		txAdapter.pendingEvents = &events
+		return fn(txAdapter)
+	})
+	// The writes of fn emptied the cache before the commit, the loads of
+	// the other adapters may have cached the replaced rules in the meantime.
+	a.invalidateCache()
+	if err != nil {
+		return err
+	}
+	for _, ev := range events {
+		a.events.send(ev)
+	}
+	return a.notifyWatcher(ctx)
+}
+
+// transaction runs fn in a new transaction, or in the external transaction
+// when the adapter is bound to one.
+// The cache is emptied again once the transaction completed, as loads may
+// have cached the rules replaced by the transaction in the meantime. The
+// version is bumped in the transaction when the version table is enabled,
+// the watcher is notified once the outermost transaction committed. A new
+// transaction is retried when it deadlocked and WithDeadlockRetry allows.
+func (a *Adapter) transaction(ctx context.Context, fn func(ctx context.Context, tx gdb.TX) error) error {
+	defer a.invalidateCache()
+	if a.versionTable != "" {
+		fn = a.versionedFn(fn)
+	}
+	if a.tx != nil {
+		return fn(gdb.WithTX(ctx, a.tx), a.tx)
+	}
+	if err := a.runTransaction(ctx, fn); err != nil {
+		return err
+	}
+	return a.committed(ctx)
+}
@@ -0,0 +1,272 @@
+package adapter
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"slices"
+	"strings"
+	"testing"
+
+	"github.com/casbin/casbin/v2"
+	"github.com/gogf/gf/v2/database/gdb"
+)
+
+func TestWithTxRollback(t *testing.T) {
+	a := newSqliteAdapter(t)
+	ctx := context.Background()
+
+	err := a.db.Transaction(ctx, func(ctx context.Context, tx gdb.TX) error {
+		txa := a.WithTx(tx)
+		if err := txa.AddPolicies("p", "p", [][]string{{"alice", "data1", "read"}, {"bob", "data2", "write"}}); err != nil {
+			t.Fatalf("AddPolicies failed: %v", err)
+		}
+		if err := txa.AddPolicy("g", "g", []string{"alice", "admin"}); err != nil {
+			t.Fatalf("AddPolicy failed: %v", err)
+		}
+		if err := txa.RemovePolicy("p", "p", []string{"bob", "data2", "write"}); err != nil {
+			t.Fatalf("RemovePolicy failed: %v", err)
+		}
+
+		// The changes are visible inside the transaction.
+		e, _ := casbin.NewEnforcer("examples/rbac_model.conf", txa)
+		testGetPolicyWithoutOrder(t, e, [][]string{{"alice", "data1", "read"}})
+
+		// The business write fails, so everything rolls back.
+		return errBusiness
+	})
+	if !errors.Is(err, errBusiness) {
+		t.Fatalf("transaction error = %v, supposed to be %v", err, errBusiness)
+	}
+
+	count, err := a.model().Count()
+	if err != nil {
+		t.Fatalf("Count failed: %v", err)
+	}
+	if count != 0 {
+		t.Errorf("%d policy rows persisted after rollback", count)
+	}
+}
+
+func TestWithTxCommit(t *testing.T) {
+	a := newSqliteAdapter(t)
+	initPolicy(t, a)
+	ctx := context.Background()
+
+	tx, err := a.db.Begin(ctx)
+	if err != nil {
+		t.Fatalf("Begin failed: %v", err)
+	}
+	txa := a.WithTx(tx)
+
+	// SavePolicy must not truncate, which would commit the transaction on MySQL.
+	e, _ := casbin.NewEnforcer("examples/rbac_model.conf", txa)
+	e.EnableAutoSave(false)
+	if _, err := e.AddPolicy("carol", "data3", "read"); err != nil {
+		t.Fatalf("AddPolicy failed: %v", err)
+	}
+	if err := e.SavePolicy(); err != nil {
+		t.Fatalf("SavePolicy failed: %v", err)
+	}
+	if _, err := txa.UpdateFilteredPolicies("p", "p", [][]string{{"bob", "data3", "read"}}, 0, "bob"); err != nil {
+		t.Fatalf("UpdateFilteredPolicies failed: %v", err)
+	}
+	if err := tx.Commit(); err != nil {
+		t.Fatalf("Commit failed: %v", err)
+	}
+
+	e, _ = casbin.NewEnforcer("examples/rbac_model.conf", a)
+	testGetPolicyWithoutOrder(t, e, [][]string{{"alice", "data1", "read"}, {"data2_admin", "data2", "read"}, {"data2_admin", "data2", "write"}, {"carol", "data3", "read"}, {"bob", "data3", "read"}})
+}
+
+var errBusiness = errors.New("business insert failed")
+
+func TestTransactionRollback(t *testing.T) {
+	a := newSqliteAdapter(t)
+	initPolicy(t, a)
+
+	err := a.Transaction(context.Background(), func(txAdapter *Adapter) error {
+		if err := txAdapter.AddPolicies("p", "p", [][]string{{"carol", "data3", "read"}, {"carol", "data3", "write"}}); err != nil {
+			return err
+		}
+		if err := txAdapter.RemoveFilteredPolicy("p", "p", 0, "data2_admin"); err != nil {
+			return err
+		}
+		return errBusiness
+	})
+	if !errors.Is(err, errBusiness) {
+		t.Fatalf("Transaction error = %v, supposed to be %v", err, errBusiness)
+	}
+
+	e, _ := casbin.NewEnforcer("examples/rbac_model.conf", a)
+	testGetPolicyWithoutOrder(t, e, [][]string{{"alice", "data1", "read"}, {"bob", "data2", "write"}, {"data2_admin", "data2", "read"}, {"data2_admin", "data2", "write"}})
+}
+
+func TestTransactionCommit(t *testing.T) {
+	a := newSqliteAdapter(t)
+	initPolicy(t, a)
+
+	err := a.Transaction(context.Background(), func(txAdapter *Adapter) error {
+		if err := txAdapter.AddPolicies("p", "p", [][]string{{"carol", "data3", "read"}}); err != nil {
+			return err
+		}
+		// A nested call reuses the outer transaction.
+		return txAdapter.Transaction(context.Background(), func(nested *Adapter) error {
+			if nested.tx != txAdapter.tx {
+				t.Error("nested Transaction must reuse the outer transaction")
+			}
+			return nested.RemoveFilteredPolicy("p", "p", 0, "data2_admin")
+		})
+	})
+	if err != nil {
+		t.Fatalf("Transaction failed: %v", err)
+	}
+
+	e, _ := casbin.NewEnforcer("examples/rbac_model.conf", a)
+	testGetPolicyWithoutOrder(t, e, [][]string{{"alice", "data1", "read"}, {"bob", "data2", "write"}, {"carol", "data3", "read"}})
+}
+
+func TestNewAdapterWithTx(t *testing.T) {
+	base := newSqliteAdapter(t)
+	initPolicy(t, base)
+	ctx := context.Background()
+
+	tx, err := base.db.Begin(ctx)
+	if err != nil {
+		t.Fatalf("Begin failed: %v", err)
+	}
+	a, err := NewAdapterWithTx(ctx, tx, "")
+	if err != nil {
+		t.Fatalf("NewAdapterWithTx failed: %v", err)
+	}
+	if err := a.AddPolicies("p", "p", [][]string{{"carol", "data3", "read"}, {"carol", "data3", "write"}}); err != nil {
+		t.Fatalf("AddPolicies failed: %v", err)
+	}
+	e, _ := casbin.NewEnforcer("examples/rbac_model.conf", a)
+	testGetPolicyWithoutOrder(t, e, [][]string{{"alice", "data1", "read"}, {"bob", "data2", "write"}, {"data2_admin", "data2", "read"}, {"data2_admin", "data2", "write"}, {"carol", "data3", "read"}, {"carol", "data3", "write"}})
+	if err := tx.Rollback(); err != nil {
+		t.Fatalf("Rollback failed: %v", err)
+	}
+
+	// The writes rolled back with the transaction.
+	e, _ = casbin.NewEnforcer("examples/rbac_model.conf", base)
+	testGetPolicyWithoutOrder(t, e, [][]string{{"alice", "data1", "read"}, {"bob", "data2", "write"}, {"data2_admin", "data2", "read"}, {"data2_admin", "data2", "write"}})
+
+	if err := a.AddPolicy("p", "p", []string{"dave", "data4", "read"}); !errors.Is(err, ErrTxDone) {
+		t.Errorf("AddPolicy err: %v, supposed to be %v", err, ErrTxDone)
+	}
+	if err := a.LoadPolicy(e.GetModel()); !errors.Is(err, ErrTxDone) {
+		t.Errorf("LoadPolicy err: %v, supposed to be %v", err, ErrTxDone)
+	}
+	if _, err := NewAdapterWithTx(ctx, tx, ""); !errors.Is(err, ErrTxDone) {
+		t.Errorf("NewAdapterWithTx err: %v, supposed to be %v", err, ErrTxDone)
+	}
+}
+
+func TestNewAdapterWithTxMissingTable(t *testing.T) {
+	db := newSqliteAdapter(t).db
+	for _, tt := range []struct {
+		table string
+		opts  []AdapterOption
+	}{
+		{"missing_rule", nil},
+		{"", []AdapterOption{WithHistory()}},
+	} {
+		err := db.Transaction(context.Background(), func(ctx context.Context, tx gdb.TX) error {
+			_, err := NewAdapterWithTx(ctx, tx, tt.table, tt.opts...)
+			return err
+		})
+		if err == nil {
+			t.Errorf("expected an error for a missing table of %q", tt.table)
+		}
+	}
+	tables, _ := db.Tables(context.Background())
+	if slices.Contains(tables, "missing_rule") || slices.Contains(tables, "casbin_rule_history") {
+		t.Errorf("NewAdapterWithTx should not create tables, found %v", tables)
+	}
+}
+
+// logStatements logs the statements a runs from now on into buf.
+func logStatements(a *Adapter, buf *bytes.Buffer) {
+	a.db.SetLogger(newBufferLogger(buf))
+	a.db.SetDebug(true)
+}
+
+func TestWithoutTransactions(t *testing.T) {
+	// The writes run their batches in transactions by default.
+	var buf bytes.Buffer
+	a := newSqliteAdapter(t)
+	logStatements(a, &buf)
+	initPolicy(t, a)
+	if !strings.Contains(buf.String(), "BEGIN") {
+		t.Fatal("SavePolicy supposed to open a transaction")
+	}
+
+	buf.Reset()
+	a = newSqliteAdapter(t, WithoutTransactions())
+	logStatements(a, &buf)
+	initPolicy(t, a)
+	e, err := casbin.NewEnforcer("examples/rbac_model.conf", a)
+	if err != nil {
+		t.Fatalf("NewEnforcer failed: %v", err)
+	}
+	if _, err := e.AddPolicies([][]string{{"carol", "data3", "read"}, {"dave", "data3", "read"}}); err != nil {
+		t.Fatalf("AddPolicies failed: %v", err)
+	}
+	if _, err := e.RemovePolicies([][]string{{"dave", "data3", "read"}, {"bob", "data2", "write"}}); err != nil {
+		t.Fatalf("RemovePolicies failed: %v", err)
+	}
+	if _, err := e.UpdatePolicies([][]string{{"carol", "data3", "read"}}, [][]string{{"carol", "data3", "write"}}); err != nil {
+		t.Fatalf("UpdatePolicies failed: %v", err)
+	}
+	if _, err := e.UpdateFilteredPolicies([][]string{{"data2_admin", "data2", "read"}}, 0, "data2_admin", "data2", "read"); err != nil {
+		t.Fatalf("UpdateFilteredPolicies failed: %v", err)
+	}
+	if _, err := e.RemoveFilteredPolicy(0, "data2_admin", "data2", "write"); err != nil {
+		t.Fatalf("RemoveFilteredPolicy failed: %v", err)
+	}
+	if err := e.SavePolicy(); err != nil {
+		t.Fatalf("SavePolicy failed: %v", err)
+	}
+	if err := e.LoadPolicy(); err != nil {
+		t.Fatalf("LoadPolicy failed: %v", err)
+	}
+	testGetPolicy(t, e, [][]string{{"alice", "data1", "read"}, {"carol", "data3", "write"}, {"data2_admin", "data2", "read"}})
+	if strings.Contains(buf.String(), "BEGIN") {
+		t.Errorf("no transaction supposed to be opened, the statements were:\n%s", buf.String())
+	}
+
+	for name, opts := range map[string][]AdapterOption{
+		"history":     {WithoutTransactions(), WithHistory()},
+		"stable save": {WithoutTransactions(), WithStableSave()},
+	} {
+		if _, err := NewAdapter(context.Background(), "", "", newSqliteDB(t), opts...); err == nil {
+			t.Errorf("%s: expected an error", name)
+		}
+	}
+}
+
+func TestWithoutTransactionsTenant(t *testing.T) {
+	ctx := context.Background()
+	for _, opts := range [][]AdapterOption{{}, {WithUniqueIndex()}} {
+		var buf bytes.Buffer
+		base := newSqliteAdapter(t, append(opts, WithoutTransactions(), WithTenantColumn("tenant_id"))...)
+		a, other := base.ForTenant("t1"), base.ForTenant("t2")
+		if err := other.AddPolicy("p", "p", []string{"erin", "data9", "read"}); err != nil {
+			t.Fatalf("AddPolicy failed: %v", err)
+		}
+		logStatements(a, &buf)
+
+		// The stored rules of the tenant are replaced, the ones of others kept.
+		initPolicy(t, a)
+		initPolicy(t, a)
+		e, _ := casbin.NewEnforcer("examples/rbac_model.conf", a)
+		testGetPolicy(t, e, [][]string{{"alice", "data1", "read"}, {"bob", "data2", "write"}, {"data2_admin", "data2", "read"}, {"data2_admin", "data2", "write"}})
+		if n, err := other.CountPolicies(ctx, nil); err != nil || n != 1 {
+			t.Errorf("the other tenant has %d rules, err: %v, supposed to keep 1", n, err)
+		}
+		if strings.Contains(buf.String(), "BEGIN") {
+			t.Errorf("no transaction supposed to be opened, the statements were:\n%s", buf.String())
+		}
+	}
+}
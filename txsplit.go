@@ -0,0 +1,165 @@
+package adapter
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+
+	"github.com/gogf/gf/v2/database/gdb"
+)
+
+// PartialWriteError is returned by an AddPolicies split by WithMaxTxRows
+// whose transaction failed after others committed.
+type PartialWriteError struct {
+	// Committed is the number of transactions that committed, of Chunks
+	// transactions, and Rules the number of rules they wrote.
+	Committed int
+	Chunks    int
+	Rules     int
+	Err       error
+}
+
+func (e *PartialWriteError) Error() string {
+	return fmt.Sprintf("failed to write rules: %d of %d transactions committed, %d rules written: %v", e.Committed, e.Chunks, e.Rules, e.Err)
+}
+
+func (e *PartialWriteError) Unwrap() error {
+	return e.Err
+}
+
+// WithMaxTxRows splits the writes of AddPolicies of more than n rules into
+// transactions of at most n rules, run one after the other. A failing
+// transaction leaves the ones before it committed, and the write fails with
+// a *PartialWriteError telling how many did. The duplicates of the rules of
+// a committed transaction are handled by the conflict policy.
+//
+// SavePolicy of more than n rules writes them into a new table by
+// statements of at most n rows, which replaces the policy table once every
+// row has been written, so the policy table never holds a partial rule set
+// and is left untouched when a write fails. Rules added by other writers
+// while the new table is filled are lost. Adapters scoped to a tenant,
+// recording history, soft deleting or using the gorm-adapter columns save
+// in one transaction.
+//
+// Adapters bound to a transaction write in one transaction, as do the
+// writes in the transaction of ctx.
+func WithMaxTxRows(n int) AdapterOption {
+	return AdapterOption{apply: func(a *Adapter) {
+		a.maxTxRows = n
+	}}
+}
+
+// splitsWrite reports whether a write of rows rules is split by
+// WithMaxTxRows.
+func (a *Adapter) splitsWrite(ctx context.Context, rows int) bool {
+	return a.maxTxRows > 0 && rows > a.maxTxRows && a.tx == nil && gdb.TXFromCtx(ctx, a.db.GetGroup()) == nil
+}
+
+// splitsSave reports whether SavePolicy of rows rules replaces the policy
+// table by a table filled by WithMaxTxRows statements.
+func (a *Adapter) splitsSave(ctx context.Context, rows int) bool {
+	return a.splitsWrite(ctx, rows) && a.canReplaceTable() && !a.tombstones
+}
+
+// splitAddPolicies adds rules by transactions of at most maxTxRows rules.
+// The watcher is notified once, after the last transaction, or asked for a
+// full reload when some of them failed to commit.
+func (a *Adapter) splitAddPolicies(ctx context.Context, rules []Rule) error {
+	if err := a.checkQuota(ctx, len(rules), false); err != nil {
+		return err
+	}
+	defer a.invalidateCache()
+	chunks := (len(rules) + a.maxTxRows - 1) / a.maxTxRows
+	setProgressTotal(ctx, len(rules))
+
+	// Every transaction forgets the rows affected before it, the ones of
+	// the committed transactions are added up.
+	var committed, written int
+	var affected int64
+	for i := 0; i < len(rules); i += a.maxTxRows {
+		chunk := rules[i:min(i+a.maxTxRows, len(rules))]
+		fn := func(ctx context.Context, tx gdb.TX) error {
+			_, err := a.insertRulesOnConflict(ctx, chunk, a.conflictPolicy)
+			return err
+		}
+		if a.versionTable != "" {
+			fn = a.versionedFn(fn)
+		}
+		err := ctx.Err()
+		if err == nil {
+			err = a.runTransaction(ctx, fn)
+		}
+		if err != nil {
+			resetAffected(ctx)
+			addAffected(ctx, affected)
+			if committed == 0 {
+				return err
+			}
+			partial := &PartialWriteError{Committed: committed, Chunks: chunks, Rules: written, Err: err}
+			// The watcher can't be told which rules were added.
+			ctx = context.WithValue(ctx, watcherMessageKey{}, (*WatcherMessage)(nil))
+			if err := a.committed(ctx); err != nil {
+				return errors.Join(partial, err)
+			}
+			return partial
+		}
+		committed++
+		written += len(chunk)
+		affected += affectedRows(ctx)
+	}
+	resetAffected(ctx)
+	addAffected(ctx, affected)
+	return a.committed(ctx)
+}
+
+// copyRows copies the rows of table from into table to, the columns both
+// tables have. With WithMaxTxRows the rows are copied by statements of at
+// most maxTxRows rows in the order of the ids.
+func (a *Adapter) copyRows(ctx context.Context, from, to string) error {
+	fields, err := a.db.TableFields(ctx, to)
+	if err != nil {
+		return fmt.Errorf("failed to get table fields: %w", err)
+	}
+	stored, err := a.db.TableFields(ctx, from)
+	if err != nil {
+		return fmt.Errorf("failed to get table fields: %w", err)
+	}
+	columns := make([]string, 0, len(fields))
+	for name := range fields {
+		if _, ok := stored[name]; ok {
+			columns = append(columns, name)
+		}
+	}
+	list := strings.Join(columns, ", ")
+	copySql := fmt.Sprintf("INSERT INTO %s (%s) SELECT %s FROM %s", to, list, list, from)
+	if a.maxTxRows <= 0 {
+		if _, err := a.db.Exec(ctx, copySql); err != nil {
+			return fmt.Errorf("failed to copy rules: %w", err)
+		}
+		return nil
+	}
+
+	copySql += " WHERE id > ?"
+	var last int64
+	for {
+		// upper is the id of the last row of the chunk, none when the rows
+		// left fit in it.
+		upper, err := a.db.Model(from).Ctx(ctx).WhereGT("id", last).OrderAsc("id").Limit(a.maxTxRows-1, 1).Value("id")
+		if err != nil {
+			return fmt.Errorf("failed to read rules: %w", err)
+		}
+		if upper.IsNil() {
+			_, err = a.db.Exec(ctx, copySql, last)
+		} else {
+			_, err = a.db.Exec(ctx, copySql+" AND id <= ?", last, upper.Int64())
+		}
+		if err != nil {
+			return fmt.Errorf("failed to copy rules: %w", err)
+		}
+		if upper.IsNil() {
+			return nil
+		}
+		last = upper.Int64()
+	}
+}
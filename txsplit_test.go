@@ -0,0 +1,112 @@
+package adapter
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"reflect"
+	"testing"
+
+	"github.com/gogf/gf/v2/frame/g"
+)
+
+func TestMaxTxRows(t *testing.T) {
+	a := newSqliteAdapter(t, WithMaxTxRows(2))
+	testMaxTxRows(t, a)
+}
+
+func TestMaxTxRowsConflicts(t *testing.T) {
+	a := newSqliteAdapter(t, WithMaxTxRows(2), WithConflictPolicy(ConflictSkip))
+	initPolicy(t, a)
+
+	rules := [][]string{{"alice", "data1", "read"}, {"carol", "data3", "read"}, {"carol", "data3", "read"}}
+	if err := a.AddPolicies("p", "p", rules); err != nil {
+		t.Fatalf("AddPolicies failed: %v", err)
+	}
+	if ids := storedIDs(t, a); !reflect.DeepEqual(ids, []int64{1, 2, 3, 4, 5, 6}) {
+		t.Errorf("stored ids %v, supposed to add carol's rule once", ids)
+	}
+}
+
+func TestMaxTxRowsPartialWrite(t *testing.T) {
+	w := &countingWatcher{}
+	a := newSqliteAdapter(t, WithMaxTxRows(2), WithWatcher(w))
+	initPolicy(t, a)
+	ids := storedIDs(t, a)
+	_, err := a.db.Exec(a.ctx, fmt.Sprintf("CREATE TRIGGER fail_dave BEFORE INSERT ON %s WHEN NEW.v0 = 'dave' BEGIN SELECT RAISE(ABORT, 'injected failure'); END", a.tableName))
+	if err != nil {
+		t.Fatalf("failed to create trigger: %v", err)
+	}
+
+	// The second transaction fails and leaves the first one committed.
+	w.updates = 0
+	rules := [][]string{{"carol", "data3", "read"}, {"carol", "data3", "write"}, {"dave", "data3", "read"}, {"erin", "data3", "read"}}
+	err = a.AddPolicies("p", "p", rules)
+	var partial *PartialWriteError
+	if !errors.As(err, &partial) {
+		t.Fatalf("AddPolicies error %v, supposed to be a *PartialWriteError", err)
+	}
+	if partial.Committed != 1 || partial.Chunks != 2 || partial.Rules != 2 {
+		t.Errorf("%d of %d transactions committed with %d rules, supposed to be 1 of 2 with 2 rules", partial.Committed, partial.Chunks, partial.Rules)
+	}
+	if stored := storedIDs(t, a); len(stored) != 7 || !reflect.DeepEqual(stored[:5], ids) {
+		t.Errorf("stored ids %v after the failure, supposed to keep %v and add 2 rows", stored, ids)
+	}
+	if w.updates != 1 {
+		t.Errorf("%d updates after the failure, supposed to be 1", w.updates)
+	}
+
+	// A failure of the first transaction writes nothing.
+	w.updates = 0
+	if err := a.AddPolicies("p", "p", rules[2:]); err == nil || errors.As(err, &partial) {
+		t.Errorf("AddPolicies error %v, supposed to fail without a *PartialWriteError", err)
+	}
+	if stored := storedIDs(t, a); len(stored) != 7 {
+		t.Errorf("stored ids %v, supposed to be 7 rows", stored)
+	}
+	if w.updates != 0 {
+		t.Errorf("%d updates, supposed to be none", w.updates)
+	}
+}
+
+// testMaxTxRows checks the writes split by WithMaxTxRows(2) of a.
+func testMaxTxRows(t *testing.T, a *Adapter) {
+	// The policy table keeps the old rules while the rows of SavePolicy are
+	// written into the new table, by statements of at most 2 rows.
+	var batches int
+	a.insertBatch = func(ctx context.Context, table string, records g.List) error {
+		count, err := a.db.Model(a.tableName).Ctx(context.Background()).Count()
+		if err != nil || count != 0 {
+			t.Errorf("policy table holds %d rules, err: %v, supposed to be empty", count, err)
+		}
+		if len(records) > 2 {
+			t.Errorf("inserted %d rows at once, supposed to be at most 2", len(records))
+		}
+		batches++
+		return a.insertRecords(ctx, table, records)
+	}
+	initPolicy(t, a)
+	ids := storedIDs(t, a)
+	if len(ids) != 5 || batches != 3 {
+		t.Fatalf("stored ids %v by %d batches, supposed to be 5 rules in 3 batches", ids, batches)
+	}
+
+	// AddPolicies adds the rules to the stored ones, by a transaction per 2
+	// rules.
+	rules := [][]string{{"carol", "data3", "read"}, {"carol", "data3", "write"}, {"dave", "data3", "read"}, {"erin", "data3", "read"}}
+	if err := a.AddPolicies("p", "p", rules); err != nil {
+		t.Fatalf("AddPolicies failed: %v", err)
+	}
+	stored := storedIDs(t, a)
+	if len(stored) != 9 || !reflect.DeepEqual(stored[:5], ids) || batches != 3 {
+		t.Errorf("stored ids %v by %d batches, supposed to keep %v and add 4 rows in place", stored, batches, ids)
+	}
+
+	// A single rule is written in one transaction.
+	if err := a.AddPolicy("p", "p", []string{"frank", "data4", "read"}); err != nil {
+		t.Fatalf("AddPolicy failed: %v", err)
+	}
+	if stored := storedIDs(t, a); len(stored) != 10 {
+		t.Errorf("stored ids %v, supposed to be 10 rows", stored)
+	}
+}
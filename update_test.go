@@ -0,0 +1,82 @@
+package adapter
+
+import (
+	"errors"
+	"fmt"
+	"reflect"
+	"testing"
+)
+
+func TestUpdatePoliciesBatched(t *testing.T) {
+	const n = 3000
+	a := newSqliteAdapter(t, WithBatchSize(400), WithStrictUpdate())
+	seedRules(t, a, n)
+
+	// Every other rule is updated, the first two pairs swap their rules.
+	oldRules := [][]string{{"user0", "data0", "read"}, {"user1", "data1", "read"}}
+	newRules := [][]string{{"user1", "data1", "read"}, {"user0", "data0", "read"}}
+	for i := 2; i < n; i += 2 {
+		oldRules = append(oldRules, []string{fmt.Sprintf("user%d", i), fmt.Sprintf("data%d", i%10), "read"})
+		newRules = append(newRules, []string{fmt.Sprintf("user%d", i), fmt.Sprintf("data%d", i%10), "write"})
+	}
+	if err := a.UpdatePolicies("p", "p", oldRules, newRules); err != nil {
+		t.Fatalf("UpdatePolicies failed: %v", err)
+	}
+
+	rules, err := a.GetAllPolicies(a.ctx, nil)
+	if err != nil {
+		t.Fatalf("GetAllPolicies failed: %v", err)
+	}
+	if len(rules) != n {
+		t.Fatalf("stored %d rules, supposed to be %d", len(rules), n)
+	}
+	// The untouched rules keep their rows, the new rules follow them.
+	var want []Rule
+	for i := 3; i < n; i += 2 {
+		want = append(want, Rule{PType: "p", V0: fmt.Sprintf("user%d", i), V1: fmt.Sprintf("data%d", i%10), V2: "read"})
+	}
+	for _, rule := range newRules {
+		want = append(want, newRule("p", rule))
+	}
+	if !reflect.DeepEqual(rules, want) {
+		t.Errorf("stored rules differ from the expected ones, first stored %v", rules[0])
+	}
+}
+
+func TestUpdatePoliciesStrict(t *testing.T) {
+	a := newSqliteAdapter(t, WithStrictUpdate())
+	initPolicy(t, a)
+
+	tests := []struct {
+		name     string
+		oldRules [][]string
+		shared   bool
+	}{
+		{"several matches", [][]string{{"data2_admin", "data2"}}, false},
+		{"shared match", [][]string{{"alice", "data1", "read"}, {"alice", "data1"}}, true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			newRules := make([][]string, len(tt.oldRules))
+			for i := range newRules {
+				newRules[i] = []string{"carol", "data3", "read"}
+			}
+			err := a.UpdatePolicies("p", "p", tt.oldRules, newRules)
+			var ambiguous *AmbiguousRuleError
+			if !errors.As(err, &ambiguous) {
+				t.Fatalf("expected an *AmbiguousRuleError, got %v", err)
+			}
+			if ambiguous.Shared != tt.shared {
+				t.Errorf("shared is %v, supposed to be %v", ambiguous.Shared, tt.shared)
+			}
+		})
+	}
+
+	// Nothing was changed.
+	if ids := storedIDs(t, a); !reflect.DeepEqual(ids, []int64{1, 2, 3, 4, 5}) {
+		t.Errorf("stored ids %v, supposed to be [1 2 3 4 5]", ids)
+	}
+	if err := a.UpdatePolicy("p", "p", []string{"alice", "data1", "read"}, []string{"alice", "data3", "read"}); err != nil {
+		t.Errorf("UpdatePolicy failed: %v", err)
+	}
+}
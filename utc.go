@@ -0,0 +1,58 @@
+package adapter
+
+import (
+	"context"
+	"fmt"
+	"slices"
+	"time"
+
+	"github.com/gogf/gf/v2/frame/g"
+)
+
+// WithUTC sets whether the times the adapter writes, the created_at column
+// of the rules and the changed_at column of the history, are in UTC. They
+// are set from Go instead of by the database, which uses the time zone of
+// the session, and the times compared with them, like Filter.CreatedAfter,
+// are converted to UTC. The times returned by the loads, like the watermark
+// of LoadPolicyIncremental, are in UTC too. The updated_at and deleted_at
+// columns of WithChangeTracking are still set by gdb.
+//
+// By default the times are in UTC when the adapter creates the policy
+// table, and in the local time zone of the process when the table exists,
+// so that the tables holding the local times of an older version don't mix
+// time zones. The other adapters of a table created in UTC, in other
+// processes too, need WithUTC(true).
+func WithUTC(enabled bool) AdapterOption {
+	return AdapterOption{apply: func(a *Adapter) {
+		a.utc, a.utcChosen = enabled, true
+	}}
+}
+
+// chooseUTC writes the times in UTC when the policy table doesn't exist,
+// unless WithUTC chose otherwise.
+func (a *Adapter) chooseUTC(ctx context.Context) error {
+	if a.utcChosen {
+		return nil
+	}
+	tables, err := a.db.Tables(ctx)
+	if err != nil {
+		return fmt.Errorf("failed to list tables: %w", err)
+	}
+	a.utc, a.utcChosen = !slices.Contains(tables, a.tableName), true
+	return nil
+}
+
+// dbTime returns t as written to or compared with the stored times.
+func (a *Adapter) dbTime(t time.Time) time.Time {
+	if a.utc {
+		return t.UTC()
+	}
+	return t
+}
+
+// stamped sets the created_at column of record to the current time, in UTC
+// with WithUTC. gdb leaves it out of the tables without the column.
+func (a *Adapter) stamped(record g.Map) g.Map {
+	record[createdAtColumn] = a.dbTime(a.now())
+	return record
+}
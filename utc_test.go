@@ -0,0 +1,98 @@
+package adapter
+
+import (
+	"context"
+	"strings"
+	"testing"
+	"time"
+)
+
+// tokyoClock returns a clock of a time zone 9 hours ahead of UTC.
+func tokyoClock() *testClock {
+	return &testClock{now: time.Date(2024, 3, 5, 10, 0, 0, 0, time.FixedZone("JST", 9*60*60))}
+}
+
+// storedCreatedAt returns the created_at text of the rule of user.
+func storedCreatedAt(t *testing.T, a *Adapter, user string) string {
+	t.Helper()
+	value, err := a.db.GetValue(context.Background(), "SELECT created_at || '' FROM casbin_rule WHERE v0 = ?", user)
+	if err != nil {
+		t.Fatalf("failed to read created_at: %v", err)
+	}
+	return value.String()
+}
+
+func TestUTC(t *testing.T) {
+	ctx := context.Background()
+	clock := tokyoClock()
+	a := newSqliteAdapter(t, WithHistory(), withClock(clock))
+	before := clock.now
+	if err := a.AddPolicy("p", "p", []string{"alice", "data1", "read"}); err != nil {
+		t.Fatalf("AddPolicy failed: %v", err)
+	}
+
+	// The stored time is the UTC one, read back as the same instant.
+	if stored := storedCreatedAt(t, a, "alice"); stored != "2024-03-05 01:00:00+00:00" {
+		t.Errorf("created_at %q, supposed to be the UTC time", stored)
+	}
+	var rows []pagedRow
+	if err := a.db.Model(a.tableName).Ctx(ctx).Scan(&rows); err != nil {
+		t.Fatalf("failed to read rules: %v", err)
+	}
+	if at := rows[0].createdAt(); !at.Equal(before) {
+		t.Errorf("created_at %s, supposed to be about %s", at.UTC(), before.UTC())
+	}
+
+	// The local bounds of the filters are compared in UTC.
+	rules, err := a.GetFilteredPolicies(ctx, Filter{CreatedAfter: before.Add(-time.Minute), CreatedBefore: before.Add(time.Minute)})
+	if err != nil || len(rules) != 1 {
+		t.Errorf("GetFilteredPolicies = %v, err: %v, supposed to be the rule of alice", rules, err)
+	}
+	rules, err = a.GetFilteredPolicies(ctx, Filter{CreatedAfter: before.Add(time.Minute)})
+	if err != nil || len(rules) != 0 {
+		t.Errorf("GetFilteredPolicies = %v, err: %v, supposed to be empty", rules, err)
+	}
+	changedAt, err := a.db.Model(a.historyTable).Ctx(ctx).Fields("changed_at || ''").OrderDesc("id").Value()
+	if err != nil || !strings.HasSuffix(changedAt.String(), "+00:00") {
+		t.Errorf("changed_at %q, err: %v, supposed to be in UTC", changedAt, err)
+	}
+}
+
+func TestWithoutUTC(t *testing.T) {
+	a := newSqliteAdapter(t, WithUTC(false), withClock(tokyoClock()))
+	if err := a.AddPolicy("p", "p", []string{"alice", "data1", "read"}); err != nil {
+		t.Fatalf("AddPolicy failed: %v", err)
+	}
+	if stored := storedCreatedAt(t, a, "alice"); stored != "2024-03-05 10:00:00+09:00" {
+		t.Errorf("created_at %q, supposed to be the local time", stored)
+	}
+}
+
+func TestUTCExistingTable(t *testing.T) {
+	ctx := context.Background()
+	db := newSqliteAdapter(t).db
+	clock := tokyoClock()
+
+	// The rules added to an existing table keep the local times.
+	a, err := NewAdapter(ctx, "", "", db, withClock(clock))
+	if err != nil {
+		t.Fatalf("NewAdapter failed: %v", err)
+	}
+	if err := a.AddPolicy("p", "p", []string{"alice", "data1", "read"}); err != nil {
+		t.Fatalf("AddPolicy failed: %v", err)
+	}
+	if stored := storedCreatedAt(t, a, "alice"); stored != "2024-03-05 10:00:00+09:00" {
+		t.Errorf("created_at %q, supposed to be the local time", stored)
+	}
+
+	b, err := NewAdapter(ctx, "", "", db, withClock(clock), WithUTC(true))
+	if err != nil {
+		t.Fatalf("NewAdapter failed: %v", err)
+	}
+	if err := b.AddPolicy("p", "p", []string{"bob", "data1", "read"}); err != nil {
+		t.Fatalf("AddPolicy failed: %v", err)
+	}
+	if stored := storedCreatedAt(t, b, "bob"); stored != "2024-03-05 01:00:00+00:00" {
+		t.Errorf("created_at %q, supposed to be the UTC time", stored)
+	}
+}
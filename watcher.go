@@ -0,0 +1,255 @@
+package adapter
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"sync"
+	"time"
+
+	"github.com/casbin/casbin/v2/model"
+	"github.com/casbin/casbin/v2/persist"
+	"github.com/gogf/gf/v2/database/gdb"
+	"github.com/gogf/gf/v2/database/gredis"
+	"github.com/gogf/gf/v2/util/guid"
+)
+
+// DefaultWatcherChannel is the Redis channel of a watcher created without
+// a channel name.
+const DefaultWatcherChannel = "casbin:policy"
+
+// watcherRetryDelay is the pause before a watcher receives again after the
+// subscription failed.
+const watcherRetryDelay = time.Second
+
+var (
+	_ persist.WatcherEx        = (*Watcher)(nil)
+	_ persist.UpdatableWatcher = (*Watcher)(nil)
+)
+
+// Watcher is a casbin persist.WatcherEx publishing policy changes on a Redis
+// channel through GoFrame's gredis. Every instance of a deployment creates
+// its own watcher on the same channel; when one instance publishes a
+// change, the update callback runs on all the other instances with the
+// JSON encoded WatcherMessage describing it. The callback usually reloads
+// the policy, or applies the change with UpdateCallback. An instance
+// doesn't receive its own changes.
+type Watcher struct {
+	redis   *gredis.Redis
+	channel string
+	// id tells the messages of this watcher apart from those of the other
+	// instances.
+	id string
+
+	conn gredis.Conn
+	// stop is closed by Close, done once the receiving goroutine returned.
+	stop chan struct{}
+	done chan struct{}
+
+	mu       sync.RWMutex
+	callback func(string)
+	closed   bool
+}
+
+// NewWatcher subscribes to channel on redis and returns a watcher whose
+// callback runs for every update published by another instance. The
+// channel defaults to DefaultWatcherChannel. The gredis adapter must be
+// registered, e.g. by importing github.com/gogf/gf/contrib/nosql/redis/v2.
+// Close the watcher to end the subscription.
+func NewWatcher(ctx context.Context, redis *gredis.Redis, channel string) (*Watcher, error) {
+	if redis == nil {
+		return nil, errors.New("redis client cannot be nil")
+	}
+	if channel == "" {
+		channel = DefaultWatcherChannel
+	}
+
+	conn, _, err := redis.Subscribe(ctx, channel)
+	if err != nil {
+		return nil, fmt.Errorf("failed to subscribe to channel %s: %w", channel, err)
+	}
+	w := &Watcher{
+		redis:   redis,
+		channel: channel,
+		id:      guid.S(),
+		conn:    conn,
+		stop:    make(chan struct{}),
+		done:    make(chan struct{}),
+	}
+	go w.receive()
+	return w, nil
+}
+
+// receive runs the callback for the messages of the other instances until
+// the subscription is closed.
+func (w *Watcher) receive() {
+	defer close(w.done)
+	ctx := context.Background()
+	for {
+		reply, err := w.conn.Receive(ctx)
+		if err != nil {
+			// The subscription is restored on the next receive, after a
+			// pause so that an unreachable server isn't polled in a loop.
+			select {
+			case <-w.stop:
+				return
+			case <-time.After(watcherRetryDelay):
+			}
+			continue
+		}
+		// The subscription confirmations are skipped.
+		msg, ok := reply.Val().(*gredis.Message)
+		if !ok || w.isOwn(msg.Payload) {
+			continue
+		}
+
+		w.mu.RLock()
+		callback := w.callback
+		w.mu.RUnlock()
+		if callback != nil {
+			callback(msg.Payload)
+		}
+	}
+}
+
+// isOwn reports whether payload was published by this watcher.
+func (w *Watcher) isOwn(payload string) bool {
+	var msg WatcherMessage
+	return json.Unmarshal([]byte(payload), &msg) == nil && msg.ID == w.id
+}
+
+// SetUpdateCallback sets the function called when another instance
+// published a change, it receives the published payload.
+func (w *Watcher) SetUpdateCallback(callback func(string)) error {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	w.callback = callback
+	return nil
+}
+
+// Update publishes that the stored policy changed and has to be reloaded.
+func (w *Watcher) Update() error {
+	return w.publish(&WatcherMessage{Method: WatcherUpdate})
+}
+
+// UpdateForAddPolicy publishes that rule was added.
+func (w *Watcher) UpdateForAddPolicy(sec, ptype string, rule ...string) error {
+	return w.publish(&WatcherMessage{Method: WatcherAddPolicy, Sec: sec, PType: ptype, Rules: [][]string{rule}})
+}
+
+// UpdateForRemovePolicy publishes that rule was removed.
+func (w *Watcher) UpdateForRemovePolicy(sec, ptype string, rule ...string) error {
+	return w.publish(&WatcherMessage{Method: WatcherRemovePolicy, Sec: sec, PType: ptype, Rules: [][]string{rule}})
+}
+
+// UpdateForRemoveFilteredPolicy publishes that the rules matching the
+// filter were removed.
+func (w *Watcher) UpdateForRemoveFilteredPolicy(sec, ptype string, fieldIndex int, fieldValues ...string) error {
+	return w.publish(&WatcherMessage{
+		Method: WatcherRemoveFilteredPolicy, Sec: sec, PType: ptype, FieldIndex: fieldIndex, FieldValues: fieldValues,
+	})
+}
+
+// UpdateForSavePolicy publishes that the policy was replaced. The model
+// isn't published, the other instances reload.
+func (w *Watcher) UpdateForSavePolicy(model.Model) error {
+	return w.publish(&WatcherMessage{Method: WatcherSavePolicy})
+}
+
+// UpdateForAddPolicies publishes that rules were added.
+func (w *Watcher) UpdateForAddPolicies(sec string, ptype string, rules ...[]string) error {
+	return w.publish(&WatcherMessage{Method: WatcherAddPolicies, Sec: sec, PType: ptype, Rules: rules})
+}
+
+// UpdateForRemovePolicies publishes that rules were removed.
+func (w *Watcher) UpdateForRemovePolicies(sec string, ptype string, rules ...[]string) error {
+	return w.publish(&WatcherMessage{Method: WatcherRemovePolicies, Sec: sec, PType: ptype, Rules: rules})
+}
+
+// UpdateForUpdatePolicy publishes that oldRule was replaced by newRule.
+func (w *Watcher) UpdateForUpdatePolicy(sec string, ptype string, oldRule, newRule []string) error {
+	return w.publish(&WatcherMessage{
+		Method: WatcherUpdatePolicy, Sec: sec, PType: ptype, Rules: [][]string{oldRule}, NewRules: [][]string{newRule},
+	})
+}
+
+// UpdateForUpdatePolicies publishes that oldRules were replaced by
+// newRules.
+func (w *Watcher) UpdateForUpdatePolicies(sec string, ptype string, oldRules, newRules [][]string) error {
+	return w.publish(&WatcherMessage{Method: WatcherUpdatePolicies, Sec: sec, PType: ptype, Rules: oldRules, NewRules: newRules})
+}
+
+// publish publishes msg on the channel.
+func (w *Watcher) publish(msg *WatcherMessage) error {
+	w.mu.RLock()
+	closed := w.closed
+	w.mu.RUnlock()
+	if closed {
+		return errors.New("watcher is closed")
+	}
+
+	msg.ID = w.id
+	payload, err := json.Marshal(msg)
+	if err != nil {
+		return fmt.Errorf("failed to encode update: %w", err)
+	}
+	if _, err := w.redis.Publish(context.Background(), w.channel, string(payload)); err != nil {
+		return fmt.Errorf("failed to publish update: %w", err)
+	}
+	return nil
+}
+
+// Close ends the subscription and waits for the running callback to
+// return. The redis client stays open.
+func (w *Watcher) Close() {
+	w.mu.Lock()
+	if w.closed {
+		w.mu.Unlock()
+		return
+	}
+	w.closed = true
+	w.mu.Unlock()
+
+	close(w.stop)
+	_ = w.conn.Close(context.Background())
+	<-w.done
+}
+
+// WithWatcher makes the adapter call w.Update once a change written
+// through it committed, so that the other instances reload even when the
+// adapter is used without an enforcer. A persist.WatcherEx is handed the
+// change itself, e.g. UpdateForAddPolicy for AddPolicy, and the changes
+// it has no method for, such as imports, as Update. Changes made in
+// Transaction, or through an adapter of WithTx, are published by
+// Transaction after the commit; the caller of WithTx publishes the changes
+// of its own transaction. An enforcer given the same watcher with
+// SetWatcher publishes its changes too, disable that with
+// EnableAutoNotifyWatcher to publish every change once.
+func WithWatcher(w persist.Watcher) AdapterOption {
+	return AdapterOption{apply: func(a *Adapter) {
+		a.watcher = w
+	}}
+}
+
+// notifyWatcher tells the watcher of WithWatcher that the stored rules
+// changed, with the change ctx was given by withWatcherMessage.
+func (a *Adapter) notifyWatcher(ctx context.Context) error {
+	if a.watcher == nil {
+		return nil
+	}
+	msg, _ := ctx.Value(watcherMessageKey{}).(*WatcherMessage)
+	if err := publishMessage(a.watcher, msg); err != nil {
+		return fmt.Errorf("failed to notify watcher: %w", err)
+	}
+	return nil
+}
+
+// committed notifies the watcher of a change written with ctx, unless the
+// change is part of a transaction that hasn't committed yet.
+func (a *Adapter) committed(ctx context.Context) error {
+	if a.tx != nil || gdb.TXFromCtx(ctx, a.db.GetGroup()) != nil {
+		return nil
+	}
+	return a.notifyWatcher(ctx)
+}
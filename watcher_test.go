@@ -0,0 +1,138 @@
+package adapter
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/alicebob/miniredis/v2"
+	"github.com/casbin/casbin/v2"
+	_ "github.com/gogf/gf/contrib/nosql/redis/v2"
+	"github.com/gogf/gf/v2/database/gredis"
+)
+
+// newRedis returns a client of an in-memory Redis server.
+func newRedis(t *testing.T) *gredis.Redis {
+	t.Helper()
+	server := miniredis.RunT(t)
+	redis, err := gredis.New(&gredis.Config{Address: server.Addr()})
+	if err != nil {
+		t.Fatalf("failed to create redis client: %v", err)
+	}
+	t.Cleanup(func() {
+		_ = redis.Close(context.Background())
+	})
+	return redis
+}
+
+// newWatcher returns a watcher closed at the end of the test.
+func newWatcher(t *testing.T, redis *gredis.Redis) *Watcher {
+	t.Helper()
+	w, err := NewWatcher(context.Background(), redis, "")
+	if err != nil {
+		t.Fatalf("NewWatcher failed: %v", err)
+	}
+	t.Cleanup(w.Close)
+	return w
+}
+
+func TestWatcher(t *testing.T) {
+	ctx := context.Background()
+	redis := newRedis(t)
+	db := newSqliteDB(t)
+
+	w1, w2 := newWatcher(t, redis), newWatcher(t, redis)
+	a1, err := NewAdapter(ctx, "", "", db, WithWatcher(w1))
+	if err != nil {
+		t.Fatalf("failed to create adapter: %v", err)
+	}
+	a2, err := NewAdapter(ctx, "", "", db)
+	if err != nil {
+		t.Fatalf("failed to create adapter: %v", err)
+	}
+	initPolicy(t, a1)
+
+	e1, _ := casbin.NewEnforcer("examples/rbac_model.conf", a1)
+	e2, _ := casbin.NewEnforcer("examples/rbac_model.conf", a2)
+	if err := e2.SetWatcher(w2); err != nil {
+		t.Fatalf("SetWatcher failed: %v", err)
+	}
+	reloaded := make(chan struct{}, 1)
+	_ = w2.SetUpdateCallback(func(string) {
+		_ = e2.LoadPolicy()
+		reloaded <- struct{}{}
+	})
+	_ = w1.SetUpdateCallback(func(string) {
+		t.Error("the watcher received its own update")
+	})
+
+	// The first enforcer has no watcher, the adapter publishes the change.
+	if _, err := e1.AddPolicy("carol", "data3", "read"); err != nil {
+		t.Fatalf("AddPolicy failed: %v", err)
+	}
+	select {
+	case <-reloaded:
+	case <-time.After(5 * time.Second):
+		t.Fatal("the second enforcer didn't reload")
+	}
+	if ok, _ := e2.HasPolicy("carol", "data3", "read"); !ok {
+		t.Error("the second enforcer didn't load the added policy")
+	}
+
+	w1.Close()
+	if err := w1.Update(); err == nil {
+		t.Error("expected an error publishing on a closed watcher")
+	}
+}
+
+// countingWatcher counts its updates.
+type countingWatcher struct {
+	updates int
+}
+
+func (w *countingWatcher) SetUpdateCallback(func(string)) error { return nil }
+func (w *countingWatcher) Update() error                        { w.updates++; return nil }
+func (w *countingWatcher) Close()                               {}
+
+func TestWithWatcher(t *testing.T) {
+	w := &countingWatcher{}
+	a := newSqliteAdapter(t, WithWatcher(w))
+
+	if err := a.AddPolicies("p", "p", [][]string{{"alice", "data1", "read"}, {"bob", "data2", "write"}}); err != nil {
+		t.Fatalf("AddPolicies failed: %v", err)
+	}
+	if err := a.RemovePolicy("p", "p", []string{"bob", "data2", "write"}); err != nil {
+		t.Fatalf("RemovePolicy failed: %v", err)
+	}
+	if w.updates != 2 {
+		t.Errorf("%d updates, supposed to be 2", w.updates)
+	}
+
+	// The changes of a transaction are published once after the commit, and
+	// not at all when it rolled back.
+	w.updates = 0
+	err := a.Transaction(context.Background(), func(tx *Adapter) error {
+		if err := tx.AddPolicy("p", "p", []string{"carol", "data3", "read"}); err != nil {
+			return err
+		}
+		return tx.RemovePolicy("p", "p", []string{"alice", "data1", "read"})
+	})
+	if err != nil {
+		t.Fatalf("Transaction failed: %v", err)
+	}
+	if w.updates != 1 {
+		t.Errorf("%d updates, supposed to be 1", w.updates)
+	}
+	w.updates = 0
+	errRollback := errors.New("rollback")
+	err = a.Transaction(context.Background(), func(tx *Adapter) error {
+		if err := tx.AddPolicy("p", "p", []string{"dave", "data4", "read"}); err != nil {
+			return err
+		}
+		return errRollback
+	})
+	if !errors.Is(err, errRollback) || w.updates != 0 {
+		t.Errorf("%d updates, err: %v, supposed to be 0", w.updates, err)
+	}
+}
@@ -0,0 +1,142 @@
+package adapter
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+
+	"github.com/casbin/casbin/v2"
+	"github.com/casbin/casbin/v2/model"
+	"github.com/casbin/casbin/v2/persist"
+)
+
+// WatcherMethod names the change described by a WatcherMessage, after the
+// watcher method publishing it.
+type WatcherMethod string
+
+const (
+	// WatcherUpdate asks the other instances to reload their whole policy.
+	WatcherUpdate               WatcherMethod = "Update"
+	WatcherAddPolicy            WatcherMethod = "UpdateForAddPolicy"
+	WatcherRemovePolicy         WatcherMethod = "UpdateForRemovePolicy"
+	WatcherRemoveFilteredPolicy WatcherMethod = "UpdateForRemoveFilteredPolicy"
+	// WatcherSavePolicy is published after the policy was replaced, it
+	// carries no rules and the other instances reload.
+	WatcherSavePolicy     WatcherMethod = "UpdateForSavePolicy"
+	WatcherAddPolicies    WatcherMethod = "UpdateForAddPolicies"
+	WatcherRemovePolicies WatcherMethod = "UpdateForRemovePolicies"
+	WatcherUpdatePolicy   WatcherMethod = "UpdateForUpdatePolicy"
+	WatcherUpdatePolicies WatcherMethod = "UpdateForUpdatePolicies"
+)
+
+// WatcherMessage is the JSON payload published by Watcher for a policy
+// change.
+type WatcherMessage struct {
+	// ID identifies the publishing watcher.
+	ID     string        `json:"id"`
+	Method WatcherMethod `json:"method"`
+	Sec    string        `json:"sec,omitempty"`
+	PType  string        `json:"ptype,omitempty"`
+	// Rules holds the added or removed rules, and the old rules of an
+	// update.
+	Rules [][]string `json:"rules,omitempty"`
+	// NewRules holds the new rules of an update.
+	NewRules    [][]string `json:"new_rules,omitempty"`
+	FieldIndex  int        `json:"field_index,omitempty"`
+	FieldValues []string   `json:"field_values,omitempty"`
+
+	// model is the saved model handed to UpdateForSavePolicy, it isn't
+	// published.
+	model model.Model
+}
+
+// watcherMessageKey is the context key of the change written with a context.
+type watcherMessageKey struct{}
+
+// withWatcherMessage returns ctx carrying msg, the change the writes with
+// ctx make, and the model saved by it.
+func withWatcherMessage(ctx context.Context, msg *WatcherMessage, model model.Model) context.Context {
+	msg.model = model
+	return context.WithValue(ctx, watcherMessageKey{}, msg)
+}
+
+// publishMessage publishes the change msg on w. A watcher that doesn't
+// support the change, or a change without message, is asked for a full
+// reload.
+func publishMessage(w persist.Watcher, msg *WatcherMessage) error {
+	if msg == nil {
+		return w.Update()
+	}
+
+	if ex, ok := w.(persist.WatcherEx); ok {
+		switch msg.Method {
+		case WatcherAddPolicy:
+			return ex.UpdateForAddPolicy(msg.Sec, msg.PType, msg.Rules[0]...)
+		case WatcherAddPolicies:
+			return ex.UpdateForAddPolicies(msg.Sec, msg.PType, msg.Rules...)
+		case WatcherRemovePolicy:
+			return ex.UpdateForRemovePolicy(msg.Sec, msg.PType, msg.Rules[0]...)
+		case WatcherRemovePolicies:
+			return ex.UpdateForRemovePolicies(msg.Sec, msg.PType, msg.Rules...)
+		case WatcherRemoveFilteredPolicy:
+			return ex.UpdateForRemoveFilteredPolicy(msg.Sec, msg.PType, msg.FieldIndex, msg.FieldValues...)
+		case WatcherSavePolicy:
+			return ex.UpdateForSavePolicy(msg.model)
+		}
+	}
+	if updatable, ok := w.(persist.UpdatableWatcher); ok {
+		switch msg.Method {
+		case WatcherUpdatePolicy:
+			return updatable.UpdateForUpdatePolicy(msg.Sec, msg.PType, msg.Rules[0], msg.NewRules[0])
+		case WatcherUpdatePolicies:
+			return updatable.UpdateForUpdatePolicies(msg.Sec, msg.PType, msg.Rules, msg.NewRules)
+		}
+	}
+	return w.Update()
+}
+
+// UpdateCallback returns a watcher callback applying the changes published
+// by Watcher to the model of e, without writing them to the adapter again.
+// Updates, saves and the messages it can't decode or apply reload the whole
+// policy. Pass it to Watcher.SetUpdateCallback after SetWatcher, the
+// enforcer sets no callback of its own for a WatcherEx. A
+// casbin.DistributedEnforcer implements e.
+func UpdateCallback(e casbin.IDistributedEnforcer) func(string) {
+	return func(payload string) {
+		if err := applyMessage(e, payload); err != nil {
+			_ = e.LoadPolicy()
+		}
+	}
+}
+
+// errFullReload is returned by applyMessage for the changes needing a full
+// reload.
+var errFullReload = errors.New("full reload")
+
+// applyMessage applies the change of payload to the model of e.
+func applyMessage(e casbin.IDistributedEnforcer, payload string) error {
+	var msg WatcherMessage
+	if err := json.Unmarshal([]byte(payload), &msg); err != nil {
+		return err
+	}
+
+	// The change is stored already.
+	var err error
+	stored := func() bool { return false }
+	switch msg.Method {
+	case WatcherAddPolicy, WatcherAddPolicies:
+		_, err = e.AddPoliciesSelf(stored, msg.Sec, msg.PType, msg.Rules)
+	case WatcherRemovePolicy, WatcherRemovePolicies:
+		_, err = e.RemovePoliciesSelf(stored, msg.Sec, msg.PType, msg.Rules)
+	case WatcherRemoveFilteredPolicy:
+		_, err = e.RemoveFilteredPolicySelf(stored, msg.Sec, msg.PType, msg.FieldIndex, msg.FieldValues...)
+	case WatcherUpdatePolicy, WatcherUpdatePolicies:
+		if len(msg.Rules) != len(msg.NewRules) {
+			return errFullReload
+		}
+		_, err = e.UpdatePoliciesSelf(stored, msg.Sec, msg.PType, msg.Rules, msg.NewRules)
+	default:
+		return errFullReload
+	}
+	return err
+}
@@ -0,0 +1,109 @@
+package adapter
+
+import (
+	"context"
+	"encoding/json"
+	"testing"
+	"time"
+
+	"github.com/casbin/casbin/v2"
+	"github.com/casbin/casbin/v2/model"
+)
+
+func TestWatcherEx(t *testing.T) {
+	ctx := context.Background()
+	redis := newRedis(t)
+	w1, w2 := newWatcher(t, redis), newWatcher(t, redis)
+
+	// The instances use separate databases, so the second one only learns
+	// about the changes of the first from the messages, and a reload reads
+	// its own seed rule.
+	a1 := newSqliteAdapter(t, WithWatcher(w1))
+	a2 := newSqliteAdapter(t)
+	if err := a2.AddPolicy("p", "p", []string{"seed", "data0", "read"}); err != nil {
+		t.Fatalf("AddPolicy failed: %v", err)
+	}
+	e2, _ := casbin.NewDistributedEnforcer("examples/rbac_model.conf", a2)
+	if err := e2.SetWatcher(w2); err != nil {
+		t.Fatalf("SetWatcher failed: %v", err)
+	}
+	callback := UpdateCallback(e2)
+	methods := make(chan WatcherMethod, 1)
+	_ = w2.SetUpdateCallback(func(payload string) {
+		callback(payload)
+		var msg WatcherMessage
+		_ = json.Unmarshal([]byte(payload), &msg)
+		methods <- msg.Method
+	})
+	// The first instance only receives the messages published by the test.
+	received := make(chan string, 10)
+	_ = w1.SetUpdateCallback(func(payload string) {
+		received <- payload
+	})
+
+	seed := []string{"seed", "data0", "read"}
+	tests := []struct {
+		name   string
+		change func() error
+		method WatcherMethod
+		want   [][]string
+	}{
+		{"add", func() error {
+			return a1.AddPolicy("p", "p", []string{"alice", "data1", "read"})
+		}, WatcherAddPolicy, [][]string{seed, {"alice", "data1", "read"}}},
+		{"add several", func() error {
+			return a1.AddPolicies("p", "p", [][]string{{"alice", "data1", "read"}, {"bob", "data2", "write"}, {"bob", "data3", "read"}})
+		}, WatcherAddPolicies, [][]string{seed, {"alice", "data1", "read"}, {"bob", "data2", "write"}, {"bob", "data3", "read"}}},
+		{"remove", func() error {
+			return a1.RemovePolicy("p", "p", []string{"alice", "data1", "read"})
+		}, WatcherRemovePolicy, [][]string{seed, {"bob", "data2", "write"}, {"bob", "data3", "read"}}},
+		{"update", func() error {
+			return a1.UpdatePolicy("p", "p", []string{"bob", "data3", "read"}, []string{"bob", "data3", "write"})
+		}, WatcherUpdatePolicy, [][]string{seed, {"bob", "data2", "write"}, {"bob", "data3", "write"}}},
+		{"remove filtered", func() error {
+			return a1.RemoveFilteredPolicy("p", "p", 0, "bob")
+		}, WatcherRemoveFilteredPolicy, [][]string{seed}},
+		{"add again", func() error {
+			return a1.AddPolicy("p", "p", []string{"carol", "data1", "read"})
+		}, WatcherAddPolicy, [][]string{seed, {"carol", "data1", "read"}}},
+		{"save", func() error {
+			m, _ := model.NewModelFromFile("examples/rbac_model.conf")
+			m.AddPolicy("p", "p", []string{"dave", "data1", "read"})
+			return a1.SavePolicy(m)
+		}, WatcherSavePolicy, [][]string{seed}},
+		{"add before malformed", func() error {
+			return a1.AddPolicy("p", "p", []string{"erin", "data1", "read"})
+		}, WatcherAddPolicy, [][]string{seed, {"erin", "data1", "read"}}},
+		{"malformed", func() error {
+			_, err := redis.Publish(ctx, DefaultWatcherChannel, "{not json")
+			return err
+		}, "", [][]string{seed}},
+		{"unknown method", func() error {
+			if err := a1.AddPolicy("p", "p", []string{"frank", "data1", "read"}); err != nil {
+				return err
+			}
+			<-methods
+			_, err := redis.Publish(ctx, DefaultWatcherChannel, `{"id":"other","method":"UpdateForSomething"}`)
+			return err
+		}, "UpdateForSomething", [][]string{seed}},
+	}
+	for _, tt := range tests {
+		if err := tt.change(); err != nil {
+			t.Fatalf("%s: change failed: %v", tt.name, err)
+		}
+		select {
+		case method := <-methods:
+			if method != tt.method {
+				t.Errorf("%s: method %q, supposed to be %q", tt.name, method, tt.method)
+			}
+		case <-time.After(5 * time.Second):
+			t.Fatalf("%s: the second enforcer received no message", tt.name)
+		}
+		testGetPolicyWithoutOrder(t, e2.Enforcer, tt.want)
+	}
+	for _, want := range []string{"{not json", `{"id":"other","method":"UpdateForSomething"}`} {
+		if payload := <-received; payload != want {
+			t.Errorf("the first instance received %q, supposed to be %q", payload, want)
+		}
+	}
+}